@@ -55,6 +55,8 @@ pub enum SendMessageType {
     RawPcap = 12, // Enterprise Edition Feature: pcap
     Profile = 13,
     ProcEvents = 14,
+    Zipkin = 15,
+    SkyWalking = 16,
 }
 
 impl fmt::Display for SendMessageType {
@@ -75,6 +77,8 @@ impl fmt::Display for SendMessageType {
             Self::RawPcap => write!(f, "raw_pcap"), // Enterprise Edition Feature: pcap
             Self::Profile => write!(f, "profile"),
             Self::ProcEvents => write!(f, "proc_events"),
+            Self::Zipkin => write!(f, "zipkin"),
+            Self::SkyWalking => write!(f, "skywalking"),
         }
     }
 }
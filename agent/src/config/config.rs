@@ -363,6 +363,7 @@ pub struct YamlConfig {
     #[serde(with = "humantime_serde")]
     pub kubernetes_api_list_interval: Duration,
     pub kubernetes_api_memory_trim_percent: u8,
+    pub kubernetes_event_field_selector: String,
     pub external_metrics_sender_queue_size: usize,
     pub l7_protocol_inference_max_fail_count: usize,
     pub l7_protocol_inference_ttl: usize,
@@ -664,6 +665,7 @@ impl Default for YamlConfig {
             kubernetes_api_list_limit: 1000,
             kubernetes_api_list_interval: Duration::from_secs(600),
             kubernetes_api_memory_trim_percent: 100,
+            kubernetes_event_field_selector: "type=Warning".into(),
             external_metrics_sender_queue_size: 1 << 12,
             l7_protocol_inference_max_fail_count: L7_PROTOCOL_INFERENCE_MAX_FAIL_COUNT,
             l7_protocol_inference_ttl: L7_PROTOCOL_INFERENCE_TTL,
@@ -259,6 +259,7 @@ pub struct PlatformConfig {
     pub kubernetes_api_list_limit: u32,
     pub kubernetes_api_list_interval: Duration,
     pub kubernetes_api_memory_trim_percent: Option<u8>,
+    pub kubernetes_event_field_selector: String,
     pub max_memory: u64,
     pub namespace: Option<String>,
     pub thread_threshold: u32,
@@ -945,6 +946,10 @@ impl TryFrom<(Config, RuntimeConfig)> for ModuleConfig {
                 } else {
                     None
                 },
+                kubernetes_event_field_selector: conf
+                    .yaml_config
+                    .kubernetes_event_field_selector
+                    .clone(),
                 max_memory: conf.max_memory,
                 namespace: if conf.yaml_config.kubernetes_namespace.is_empty() {
                     None
@@ -1743,6 +1748,12 @@ impl ConfigHandler {
                     new_cfg.kubernetes_api_memory_trim_percent
                 );
             }
+            if old_cfg.kubernetes_event_field_selector != new_cfg.kubernetes_event_field_selector {
+                info!(
+                    "Kubernetes event field selector set to {}",
+                    new_cfg.kubernetes_event_field_selector
+                );
+            }
             if old_cfg.kubernetes_api_enabled != new_cfg.kubernetes_api_enabled {
                 info!(
                     "Kubernetes API enabled set to {}",
@@ -1775,6 +1786,8 @@ impl ConfigHandler {
                         != new_cfg.kubernetes_api_list_interval
                     || old_cfg.kubernetes_api_memory_trim_percent
                         != new_cfg.kubernetes_api_memory_trim_percent
+                    || old_cfg.kubernetes_event_field_selector
+                        != new_cfg.kubernetes_event_field_selector
                     || old_cfg.max_memory != new_cfg.max_memory);
 
             info!(
@@ -81,6 +81,8 @@ const OPEN_TELEMETRY: u32 = 20220607;
 const OPEN_TELEMETRY_COMPRESSED: u32 = 20221024;
 const PROMETHEUS: u32 = 20220613;
 const TELEGRAF: u32 = 20220613;
+const ZIPKIN: u32 = 20230710;
+const SKYWALKING: u32 = 20230710;
 
 // Otel的protobuf数据
 // ingester使用该proto https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/trace/v1/trace.proto进行解析
@@ -163,6 +165,48 @@ impl Sendable for TelegrafMetric {
     }
 }
 
+/// Zipkin v2的span列表, 格式是JSON数组或ListOfSpans的protobuf数据
+/// ingester先按JSON解析, 失败时再按protobuf解析
+#[derive(Debug, PartialEq)]
+pub struct ZipkinSpans(Vec<u8>);
+
+impl Sendable for ZipkinSpans {
+    fn encode(mut self, buf: &mut Vec<u8>) -> Result<usize, prost::EncodeError> {
+        let length = self.0.len();
+        buf.append(&mut self.0);
+        Ok(length)
+    }
+
+    fn message_type(&self) -> SendMessageType {
+        SendMessageType::Zipkin
+    }
+
+    fn version(&self) -> u32 {
+        ZIPKIN
+    }
+}
+
+/// SkyWalking HTTP接口上报的SegmentObject json数据, /v3/segments为数组, /v3/segment为单个对象
+/// 参考https://skywalking.apache.org/docs/main/latest/en/api/trace-data-protocol-v3/#http-api
+#[derive(Debug, PartialEq)]
+pub struct SkyWalkingSegment(Vec<u8>);
+
+impl Sendable for SkyWalkingSegment {
+    fn encode(mut self, buf: &mut Vec<u8>) -> Result<usize, prost::EncodeError> {
+        let length = self.0.len();
+        buf.append(&mut self.0);
+        Ok(length)
+    }
+
+    fn message_type(&self) -> SendMessageType {
+        SendMessageType::SkyWalking
+    }
+
+    fn version(&self) -> u32 {
+        SKYWALKING
+    }
+}
+
 /// java profile xxxx
 #[derive(Debug, PartialEq)]
 pub struct Profile(metric::Profile);
@@ -538,6 +582,8 @@ async fn handler(
     prometheus_sender: DebugSender<PrometheusMetric>,
     telegraf_sender: DebugSender<TelegrafMetric>,
     profile_sender: DebugSender<Profile>,
+    zipkin_sender: DebugSender<ZipkinSpans>,
+    skywalking_sender: DebugSender<SkyWalkingSegment>,
     exception_handler: ExceptionHandler,
     compressed: bool,
     counter: Arc<CompressedMetric>,
@@ -669,6 +715,45 @@ async fn handler(
 
             Ok(Response::builder().body(Body::empty()).unwrap())
         }
+        // Zipkin v2 integration
+        (&Method::POST, "/api/v2/spans") => {
+            let (part, body) = req.into_parts();
+            let whole_body = match aggregate_with_catch_exception(body, &exception_handler).await {
+                Ok(b) => b,
+                Err(e) => {
+                    return Ok(e);
+                }
+            };
+            let spans = decode_metric(whole_body, &part.headers)?;
+            if !spans.is_empty() {
+                if let Err(Error::Terminated(..)) = zipkin_sender.send(ZipkinSpans(spans)) {
+                    warn!("zipkin sender queue has terminated");
+                }
+            }
+            Ok(Response::builder()
+                .status(StatusCode::ACCEPTED)
+                .body(Body::empty())
+                .unwrap())
+        }
+        // SkyWalking HTTP integration
+        (&Method::POST, "/v3/segments") | (&Method::POST, "/v3/segment") => {
+            let (part, body) = req.into_parts();
+            let whole_body = match aggregate_with_catch_exception(body, &exception_handler).await {
+                Ok(b) => b,
+                Err(e) => {
+                    return Ok(e);
+                }
+            };
+            let segments = decode_metric(whole_body, &part.headers)?;
+            if !segments.is_empty() {
+                if let Err(Error::Terminated(..)) =
+                    skywalking_sender.send(SkyWalkingSegment(segments))
+                {
+                    warn!("skywalking sender queue has terminated");
+                }
+            }
+            Ok(Response::builder().body(Body::empty()).unwrap())
+        }
         // Return the 404 Not Found for other routes.
         _ => Ok(Response::builder()
             .status(StatusCode::NOT_FOUND)
@@ -768,6 +853,8 @@ pub struct MetricServer {
     prometheus_sender: DebugSender<PrometheusMetric>,
     telegraf_sender: DebugSender<TelegrafMetric>,
     profile_sender: DebugSender<Profile>,
+    zipkin_sender: DebugSender<ZipkinSpans>,
+    skywalking_sender: DebugSender<SkyWalkingSegment>,
     port: Arc<AtomicU16>,
     exception_handler: ExceptionHandler,
     server_shutdown_tx: Mutex<Option<mpsc::Sender<()>>>,
@@ -787,6 +874,8 @@ impl MetricServer {
         prometheus_sender: DebugSender<PrometheusMetric>,
         telegraf_sender: DebugSender<TelegrafMetric>,
         profile_sender: DebugSender<Profile>,
+        zipkin_sender: DebugSender<ZipkinSpans>,
+        skywalking_sender: DebugSender<SkyWalkingSegment>,
         port: u16,
         exception_handler: ExceptionHandler,
         compressed: bool,
@@ -807,6 +896,8 @@ impl MetricServer {
                 prometheus_sender,
                 telegraf_sender,
                 profile_sender,
+                zipkin_sender,
+                skywalking_sender,
                 port: Arc::new(AtomicU16::new(port)),
                 exception_handler,
                 server_shutdown_tx: Default::default(),
@@ -844,6 +935,8 @@ impl MetricServer {
         let prometheus_sender = self.prometheus_sender.clone();
         let telegraf_sender = self.telegraf_sender.clone();
         let profile_sender = self.profile_sender.clone();
+        let zipkin_sender = self.zipkin_sender.clone();
+        let skywalking_sender = self.skywalking_sender.clone();
         let port = self.port.clone();
         let monitor_port = Arc::new(AtomicU16::new(port.load(Ordering::Acquire)));
         let (mon_tx, mon_rx) = oneshot::channel();
@@ -908,6 +1001,8 @@ impl MetricServer {
                     let prometheus_sender = prometheus_sender.clone();
                     let telegraf_sender = telegraf_sender.clone();
                     let profile_sender = profile_sender.clone();
+                    let zipkin_sender = zipkin_sender.clone();
+                    let skywalking_sender = skywalking_sender.clone();
                     let exception_handler_inner = exception_handler.clone();
                     let counter = counter.clone();
                     let compressed = compressed.clone();
@@ -921,6 +1016,8 @@ impl MetricServer {
                         let prometheus_sender = prometheus_sender.clone();
                         let telegraf_sender = telegraf_sender.clone();
                         let profile_sender = profile_sender.clone();
+                        let zipkin_sender = zipkin_sender.clone();
+                        let skywalking_sender = skywalking_sender.clone();
                         let exception_handler = exception_handler_inner.clone();
                         let peer_addr = conn.remote_addr();
                         let counter = counter.clone();
@@ -939,6 +1036,8 @@ impl MetricServer {
                                     prometheus_sender.clone(),
                                     telegraf_sender.clone(),
                                     profile_sender.clone(),
+                                    zipkin_sender.clone(),
+                                    skywalking_sender.clone(),
                                     exception_handler.clone(),
                                     compressed.load(Ordering::Relaxed),
                                     counter.clone(),
@@ -62,7 +62,7 @@ use public::proto::{
  *     最新数据，此时进行一次全量同步。
  */
 
-const RESOURCES: [&str; 11] = [
+const RESOURCES: [&str; 16] = [
     "nodes",
     "namespaces",
     "services",
@@ -73,6 +73,11 @@ const RESOURCES: [&str; 11] = [
     "daemonsets",
     "replicationcontrollers",
     "replicasets",
+    "jobs",
+    "httproutes",
+    "grpcroutes",
+    "virtualservices",
+    "events",
     "ingresses",
 ];
 
@@ -80,7 +85,7 @@ const RESOURCES: [&str; 11] = [
     PB_RESOURCES 和 PB_INGRESS 用于打包发送k8s信息填写的资源类型，控制器根据类型作为key进行存储, 因为Route/Ingress 可以用Ingress一起表示，
     所以所有Ingress统一用*v1.Ingress。go里可以通过类型反射获取，然后控制器约定为key，rust还没好的方法获取，所以先手动填写，以后更新
 */
-const PB_RESOURCES: [&str; 11] = [
+const PB_RESOURCES: [&str; 16] = [
     "*v1.Node",
     "*v1.Namespace",
     "*v1.Service",
@@ -91,9 +96,27 @@ const PB_RESOURCES: [&str; 11] = [
     "*v1.DaemonSet",
     "*v1.ReplicationController",
     "*v1.ReplicaSet",
+    "*v1.Job",
+    "*v1beta1.HTTPRoute",
+    PB_GRPCROUTE,
+    "*v1beta1.VirtualService",
+    "*v1.Event",
     "*v1.Ingress",
 ];
+
+// jobs, gateway api, istio and event resources are optional
+const OPTIONAL_RESOURCES: [&str; 6] = [
+    "jobs",
+    "servicerules",
+    "httproutes",
+    "grpcroutes",
+    "virtualservices",
+    "events",
+];
 const PB_INGRESS: &str = "*v1.Ingress";
+const PB_GRPCROUTE: &str = "*v1.GRPCRoute";
+const PB_V1ALPHA2_GRPCROUTE: &str = "*v1alpha2.GRPCRoute";
+const GATEWAY_API_GROUP: &str = "gateway.networking.k8s.io";
 const PB_VERSION_INFO: &str = "*version.Info";
 
 struct Context {
@@ -294,6 +317,7 @@ impl ApiWatcher {
         let (mut watchers, mut task_handles) = (HashMap::new(), vec![]);
         let watcher_factory = ResourceWatcherFactory::new(client.clone(), runtime.handle().clone());
         let mut ingress_groups = vec![];
+        let mut grpcroute_groups = vec![];
         for version in api_version.versions {
             let core_resources = client
                 .list_core_api_resources(&version)
@@ -328,6 +352,25 @@ impl ApiWatcher {
         match client.list_api_groups().await {
             Ok(api_groups) => {
                 for group in api_groups.groups {
+                    // GRPCRoute 在 gateway api v1.1 之前只有 v1alpha2, 不在 preferred version (v1) 中
+                    if group.name == GATEWAY_API_GROUP {
+                        for version in group.versions.iter() {
+                            let Ok(api_resources) = client
+                                .list_api_group_resources(version.group_version.as_str())
+                                .await
+                            else {
+                                continue;
+                            };
+                            if api_resources
+                                .resources
+                                .iter()
+                                .any(|r| r.name == "grpcroutes")
+                            {
+                                info!("found grpcroutes api in group {}", version.group_version);
+                                grpcroute_groups.push(version.group_version.clone());
+                            }
+                        }
+                    }
                     let version = match group
                         .preferred_version
                         .as_ref()
@@ -362,7 +405,7 @@ impl ApiWatcher {
 
                     for api_resource in api_resources.unwrap().resources {
                         let resource_name = api_resource.name;
-                        if watchers.contains_key(&resource_name) {
+                        if watchers.contains_key(&resource_name) || resource_name == "grpcroutes" {
                             debug!(
                                 "found another {} api in group {}, skipped",
                                 resource_name.as_str(),
@@ -437,6 +480,34 @@ impl ApiWatcher {
                 } else {
                     None
                 };
+                let grpcroute_watcher = if grpcroute_groups
+                    .iter()
+                    .any(|g| g.as_str() == "gateway.networking.k8s.io/v1")
+                {
+                    watcher_factory.new_watcher(
+                        "v1grpcroutes",
+                        PB_GRPCROUTE,
+                        namespace,
+                        stats_collector,
+                        watcher_config,
+                    )
+                } else if grpcroute_groups
+                    .iter()
+                    .any(|g| g.as_str() == "gateway.networking.k8s.io/v1alpha2")
+                {
+                    watcher_factory.new_watcher(
+                        "v1alpha2grpcroutes",
+                        PB_V1ALPHA2_GRPCROUTE,
+                        namespace,
+                        stats_collector,
+                        watcher_config,
+                    )
+                } else {
+                    None
+                };
+                if let Some(watcher) = grpcroute_watcher {
+                    watchers.insert(String::from("grpcroutes"), watcher);
+                }
                 if let Some(watcher) = ingress_watcher {
                     // ingresses 排最后
                     watchers.insert(String::from(RESOURCES[RESOURCES.len() - 1]), watcher);
@@ -445,9 +516,9 @@ impl ApiWatcher {
                 {
                     let mut err_msgs_lock = err_msgs.lock().unwrap();
                     for &resource in RESOURCES[..RESOURCES.len() - 1].iter() {
-                        if resource == "servicerules" {
-                            // optional for pingan crd
-                            debug!("no service rules found");
+                        if OPTIONAL_RESOURCES.contains(&resource) {
+                            // optional for jobs, pingan crd, gateway api, istio and events
+                            debug!("no {} found", resource);
                             continue;
                         }
                         if !watchers.contains_key(resource) {
@@ -488,8 +559,13 @@ impl ApiWatcher {
                     if watchers.contains_key(resource) {
                         continue;
                     }
+                    let resource_name = if resource == "grpcroutes" {
+                        "v1grpcroutes"
+                    } else {
+                        resource
+                    };
                     if let Some(watcher) = watcher_factory.new_watcher(
-                        resource,
+                        resource_name,
                         PB_RESOURCES[index],
                         namespace,
                         stats_collector,
@@ -723,6 +799,7 @@ impl ApiWatcher {
             list_interval: config.kubernetes_api_list_interval,
             max_memory: config.max_memory,
             memory_trim_percent: config.kubernetes_api_memory_trim_percent,
+            event_field_selector: config.kubernetes_event_field_selector.clone(),
         };
 
         let (resource_watchers, task_handles) = loop {
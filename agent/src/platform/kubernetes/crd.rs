@@ -61,3 +61,125 @@ pub mod pingan {
         }
     }
 }
+
+pub mod gateway {
+    use super::*;
+
+    use serde_json::Value;
+
+    // only fields used to build ingress rules are kept, matches and backendRefs are left as json
+    #[derive(CustomResource, Clone, Debug, Serialize, Deserialize, JsonSchema)]
+    #[kube(
+        group = "gateway.networking.k8s.io",
+        version = "v1beta1",
+        kind = "HTTPRoute",
+        namespaced
+    )]
+    #[serde(rename_all = "camelCase")]
+    pub struct HTTPRouteSpec {
+        pub parent_refs: Option<Vec<Value>>,
+        pub hostnames: Option<Vec<String>>,
+        pub rules: Option<Vec<Value>>,
+    }
+
+    // GRPCRoute is served as v1 since gateway api v1.1, and only as v1alpha2 before
+    #[derive(CustomResource, Clone, Debug, Serialize, Deserialize, JsonSchema)]
+    #[kube(
+        group = "gateway.networking.k8s.io",
+        version = "v1",
+        kind = "GRPCRoute",
+        namespaced
+    )]
+    #[serde(rename_all = "camelCase")]
+    pub struct GRPCRouteSpec {
+        pub parent_refs: Option<Vec<Value>>,
+        pub hostnames: Option<Vec<String>>,
+        pub rules: Option<Vec<Value>>,
+    }
+
+    pub mod v1alpha2 {
+        use super::*;
+
+        #[derive(CustomResource, Clone, Debug, Serialize, Deserialize, JsonSchema)]
+        #[kube(
+            group = "gateway.networking.k8s.io",
+            version = "v1alpha2",
+            kind = "GRPCRoute",
+            namespaced
+        )]
+        #[serde(rename_all = "camelCase")]
+        pub struct GRPCRouteSpec {
+            pub parent_refs: Option<Vec<Value>>,
+            pub hostnames: Option<Vec<String>>,
+            pub rules: Option<Vec<Value>>,
+        }
+
+        impl Trimmable for GRPCRoute {
+            fn trim(mut self) -> Self {
+                let name = self.metadata.name.clone().unwrap_or_default();
+                let mut route = GRPCRoute::new(&name, self.spec);
+                route.metadata = trim_route_metadata(&mut self.metadata);
+                route
+            }
+        }
+    }
+
+    impl Trimmable for HTTPRoute {
+        fn trim(mut self) -> Self {
+            let name = self.metadata.name.clone().unwrap_or_default();
+            let mut route = HTTPRoute::new(&name, self.spec);
+            route.metadata = trim_route_metadata(&mut self.metadata);
+            route
+        }
+    }
+
+    impl Trimmable for GRPCRoute {
+        fn trim(mut self) -> Self {
+            let name = self.metadata.name.clone().unwrap_or_default();
+            let mut route = GRPCRoute::new(&name, self.spec);
+            route.metadata = trim_route_metadata(&mut self.metadata);
+            route
+        }
+    }
+
+    pub(super) fn trim_route_metadata(metadata: &mut ObjectMeta) -> ObjectMeta {
+        ObjectMeta {
+            uid: metadata.uid.take(),
+            name: metadata.name.take(),
+            namespace: metadata.namespace.take(),
+            labels: metadata.labels.take(),
+            ..Default::default()
+        }
+    }
+}
+
+pub mod istio {
+    use super::*;
+
+    use serde_json::Value;
+
+    #[derive(CustomResource, Clone, Debug, Serialize, Deserialize, JsonSchema)]
+    #[kube(
+        group = "networking.istio.io",
+        version = "v1beta1",
+        kind = "VirtualService",
+        namespaced
+    )]
+    #[serde(rename_all = "camelCase")]
+    pub struct VirtualServiceSpec {
+        pub hosts: Option<Vec<String>>,
+        pub gateways: Option<Vec<String>>,
+        pub http: Option<Vec<Value>>,
+        pub tls: Option<Vec<Value>>,
+        pub tcp: Option<Vec<Value>>,
+    }
+
+    impl Trimmable for VirtualService {
+        fn trim(mut self) -> Self {
+            let name = self.metadata.name.clone().unwrap_or_default();
+            let mut vs = VirtualService::new(&name, self.spec);
+            vs.metadata = gateway::trim_route_metadata(&mut self.metadata);
+            vs
+        }
+    }
+}
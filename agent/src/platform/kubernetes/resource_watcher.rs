@@ -34,9 +34,11 @@ use k8s_openapi::{
             DaemonSet, DaemonSetSpec, Deployment, DeploymentSpec, ReplicaSet, ReplicaSetSpec,
             StatefulSet, StatefulSetSpec,
         },
+        batch::v1::Job,
         core::v1::{
-            Container, ContainerStatus, Namespace, Node, NodeSpec, NodeStatus, Pod, PodSpec,
-            PodStatus, ReplicationController, ReplicationControllerSpec, Service, ServiceSpec,
+            Container, ContainerStatus, Event as CoreEvent, Namespace, Node, NodeSpec, NodeStatus,
+            Pod, PodSpec, PodStatus, ReplicationController, ReplicationControllerSpec, Service,
+            ServiceSpec,
         },
         extensions, networking,
     },
@@ -53,7 +55,11 @@ use serde::de::DeserializeOwned;
 use serde::ser::Serialize;
 use tokio::{runtime::Handle, sync::Mutex, task::JoinHandle, time};
 
-use super::crd::pingan::ServiceRule;
+use super::crd::{
+    gateway::{v1alpha2, GRPCRoute, HTTPRoute},
+    istio::VirtualService,
+    pingan::ServiceRule,
+};
 use crate::utils::stats::{
     self, Countable, Counter, CounterType, CounterValue, RefCountable, StatsOption,
 };
@@ -85,6 +91,12 @@ pub enum GenericResourceWatcher {
     DaemonSet(ResourceWatcher<DaemonSet>),
     ReplicationController(ResourceWatcher<ReplicationController>),
     ReplicaSet(ResourceWatcher<ReplicaSet>),
+    Job(ResourceWatcher<Job>),
+    HTTPRoute(ResourceWatcher<HTTPRoute>),
+    GRPCRoute(ResourceWatcher<GRPCRoute>),
+    V1alpha2GRPCRoute(ResourceWatcher<v1alpha2::GRPCRoute>),
+    VirtualService(ResourceWatcher<VirtualService>),
+    Event(ResourceWatcher<CoreEvent>),
     V1Ingress(ResourceWatcher<networking::v1::Ingress>),
     V1beta1Ingress(ResourceWatcher<networking::v1beta1::Ingress>),
     ExtV1beta1Ingress(ResourceWatcher<extensions::v1beta1::Ingress>),
@@ -156,6 +168,8 @@ pub struct WatcherConfig {
     pub list_interval: Duration,
     pub max_memory: u64,
     pub memory_trim_percent: Option<u8>,
+    // 事件数量较多，默认只同步Warning事件，为空时同步所有事件
+    pub event_field_selector: String,
 }
 
 // 发生错误，需要重新构造实例
@@ -170,6 +184,7 @@ pub struct ResourceWatcher<K> {
     ready: Arc<AtomicBool>,
     stats_counter: Arc<WatcherCounter>,
     config: WatcherConfig,
+    field_selector: Option<String>,
 
     listing: Arc<AtomicBool>,
 }
@@ -183,6 +198,7 @@ struct Context<K> {
     ready: Arc<AtomicBool>,
     stats_counter: Arc<WatcherCounter>,
     config: WatcherConfig,
+    field_selector: Option<String>,
     resource_version: Option<String>,
 
     listing: Arc<AtomicBool>,
@@ -202,6 +218,7 @@ where
             api: self.api.clone(),
             stats_counter: self.stats_counter.clone(),
             config: self.config.clone(),
+            field_selector: self.field_selector.clone(),
             resource_version: None,
             listing: self.listing.clone(),
         };
@@ -257,10 +274,26 @@ where
             ready: Default::default(),
             stats_counter: Default::default(),
             config: config.clone(),
+            field_selector: None,
             listing,
         }
     }
 
+    // 只list/watch满足field selector的资源
+    pub fn with_field_selector(mut self, field_selector: &str) -> Self {
+        if !field_selector.is_empty() {
+            self.field_selector = Some(field_selector.to_owned());
+        }
+        self
+    }
+
+    fn list_params(ctx: &Context<K>) -> ListParams {
+        match ctx.field_selector.as_ref() {
+            Some(field_selector) => ListParams::default().fields(field_selector),
+            None => ListParams::default(),
+        }
+    }
+
     async fn process(mut ctx: Context<K>) {
         let mut encoder = ZlibEncoder::new(Vec::new(), Compression::default());
         Self::serialized_get_list_entry(&mut ctx, &mut encoder).await;
@@ -268,7 +301,7 @@ where
         info!("{} watcher ready", ctx.kind);
 
         let mut last_update = SystemTime::now();
-        let mut stream = runtime::watcher(ctx.api.clone(), ListParams::default()).boxed();
+        let mut stream = runtime::watcher(ctx.api.clone(), Self::list_params(&ctx)).boxed();
 
         // If the watch is successful, keep updating the entry with the watch. If the watch is not successful,
         // update the entry with the full amount every 10 minutes.
@@ -369,7 +402,7 @@ where
         let mut all_entries = HashMap::new();
         let mut total_count = 0;
         let mut total_bytes = 0;
-        let mut params = ListParams::default().limit(ctx.config.list_limit);
+        let mut params = Self::list_params(ctx).limit(ctx.config.list_limit);
         loop {
             trace!("{} list with {:?}", ctx.kind, params);
             match ctx.api.list(&params).await {
@@ -646,6 +679,47 @@ impl Trimmable for ReplicaSet {
     }
 }
 
+impl Trimmable for Job {
+    fn trim(mut self) -> Self {
+        // only the owner of a job is needed, to find the cronjob of its pods
+        let mut trim_job = Job::default();
+        trim_job.metadata = ObjectMeta {
+            uid: self.metadata.uid.take(),
+            name: self.metadata.name.take(),
+            namespace: self.metadata.namespace.take(),
+            owner_references: self.metadata.owner_references.take(),
+            labels: self.metadata.labels.take(),
+            ..Default::default()
+        };
+        trim_job
+    }
+}
+
+impl Trimmable for CoreEvent {
+    fn trim(mut self) -> Self {
+        // keep what the controller needs to dedupe events and locate the involved object
+        CoreEvent {
+            metadata: ObjectMeta {
+                uid: self.metadata.uid.take(),
+                name: self.metadata.name.take(),
+                namespace: self.metadata.namespace.take(),
+                ..Default::default()
+            },
+            involved_object: self.involved_object,
+            reason: self.reason.take(),
+            message: self.message.take(),
+            type_: self.type_.take(),
+            count: self.count.take(),
+            first_timestamp: self.first_timestamp.take(),
+            last_timestamp: self.last_timestamp.take(),
+            event_time: self.event_time.take(),
+            source: self.source.take(),
+            reporting_component: self.reporting_component.take(),
+            ..Default::default()
+        }
+    }
+}
+
 impl Trimmable for ReplicationController {
     fn trim(mut self) -> Self {
         let mut trim_rc = ReplicationController::default();
@@ -941,6 +1015,34 @@ impl ResourceWatcherFactory {
                     namespace,
                     config,
                 )),
+                "jobs" => GenericResourceWatcher::Job(self.new_watcher_inner(
+                    kind,
+                    stats_collector,
+                    namespace,
+                    config,
+                )),
+                "httproutes" => GenericResourceWatcher::HTTPRoute(self.new_watcher_inner(
+                    kind,
+                    stats_collector,
+                    namespace,
+                    config,
+                )),
+                "v1grpcroutes" => GenericResourceWatcher::GRPCRoute(self.new_watcher_inner(
+                    kind,
+                    stats_collector,
+                    namespace,
+                    config,
+                )),
+                "v1alpha2grpcroutes" => GenericResourceWatcher::V1alpha2GRPCRoute(
+                    self.new_watcher_inner(kind, stats_collector, namespace, config),
+                ),
+                "virtualservices" => GenericResourceWatcher::VirtualService(
+                    self.new_watcher_inner(kind, stats_collector, namespace, config),
+                ),
+                "events" => GenericResourceWatcher::Event(
+                    self.new_watcher_inner(kind, stats_collector, namespace, config)
+                        .with_field_selector(&config.event_field_selector),
+                ),
                 "v1ingresses" => GenericResourceWatcher::V1Ingress(self.new_watcher_inner(
                     kind,
                     stats_collector,
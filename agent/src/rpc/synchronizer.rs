@@ -16,6 +16,7 @@
 
 use std::collections::HashMap;
 use std::fs::{self, File};
+use std::hash::Hash;
 use std::io::{BufWriter, Write};
 use std::mem;
 use std::net::IpAddr;
@@ -149,6 +150,10 @@ pub struct Status {
     pub cidrs: Vec<Arc<Cidr>>,
     pub ip_groups: Vec<Arc<IpGroupData>>,
     pub acls: Vec<Arc<Acl>>,
+
+    // 当前版本的原始数据，用于应用增量
+    platform_data: tp::PlatformData,
+    groups: tp::Groups,
 }
 
 impl Default for Status {
@@ -175,10 +180,107 @@ impl Default for Status {
             cidrs: Default::default(),
             ip_groups: Default::default(),
             acls: Default::default(),
+
+            platform_data: Default::default(),
+            groups: Default::default(),
         }
     }
 }
 
+// 增量中删除的条目按标识字段匹配，不依赖编码后的字节
+trait DeltaItem: PartialEq {
+    type Key: Hash + Eq;
+
+    fn delta_key(&self) -> Self::Key;
+}
+
+impl DeltaItem for tp::Interface {
+    type Key = (u32, u32, u32, u32, u64);
+
+    // WAN接口的id和mac都为0，同key有多个条目时由内容区分
+    fn delta_key(&self) -> Self::Key {
+        (
+            self.id(),
+            self.device_type(),
+            self.device_id(),
+            self.epc_id(),
+            self.mac(),
+        )
+    }
+}
+
+impl DeltaItem for tp::PeerConnection {
+    type Key = (u32, u32, u32);
+
+    // CEN生成的对等连接id为0，需要用两端的epc区分
+    fn delta_key(&self) -> Self::Key {
+        (self.id(), self.local_epc_id(), self.remote_epc_id())
+    }
+}
+
+impl DeltaItem for tp::Cidr {
+    type Key = (String, tp::CidrType, i32, u32);
+
+    fn delta_key(&self) -> Self::Key {
+        (
+            self.prefix().to_owned(),
+            self.r#type(),
+            self.epc_id(),
+            self.subnet_id(),
+        )
+    }
+}
+
+impl DeltaItem for tp::Group {
+    type Key = (tp::GroupType, u32);
+
+    fn delta_key(&self) -> Self::Key {
+        (self.r#type(), self.id())
+    }
+}
+
+impl DeltaItem for tp::ServiceInfo {
+    type Key = (tp::ServiceType, u32, u32, u32, u32, tp::ServiceProtocol);
+
+    fn delta_key(&self) -> Self::Key {
+        (
+            self.r#type(),
+            self.id(),
+            self.pod_cluster_id(),
+            self.pod_group_id(),
+            self.epc_id(),
+            self.protocol(),
+        )
+    }
+}
+
+// 按key删除dels中的条目，同key有多个条目时优先删除内容相同的，再追加adds
+fn apply_items_delta<T: DeltaItem + Clone>(items: &mut Vec<T>, adds: &[T], dels: &[T]) {
+    if !dels.is_empty() {
+        let mut key_indexes: HashMap<T::Key, Vec<usize>> = HashMap::new();
+        for (i, item) in items.iter().enumerate() {
+            key_indexes.entry(item.delta_key()).or_default().push(i);
+        }
+        let mut removed = vec![false; items.len()];
+        for item in dels {
+            let Some(indexes) = key_indexes.get_mut(&item.delta_key()) else {
+                continue;
+            };
+            if indexes.is_empty() {
+                continue;
+            }
+            let pos = indexes.iter().position(|&i| items[i] == *item).unwrap_or(0);
+            removed[indexes.remove(pos)] = true;
+        }
+        let mut i = 0;
+        items.retain(|_| {
+            i += 1;
+            !removed[i - 1]
+        });
+    }
+    items.extend_from_slice(adds);
+}
+
 impl Status {
     fn update_platform_data(
         &mut self,
@@ -239,41 +341,81 @@ impl Status {
             return false;
         }
 
-        if let Some(platform_compressed) = &resp.platform_data {
-            let platform = tp::PlatformData::decode(platform_compressed.as_slice());
-            if platform.is_ok() {
-                let platform = platform.unwrap();
-                let mut interfaces = Vec::new();
-                let mut peers = Vec::new();
-                let mut cidrs = Vec::new();
-                for item in &platform.interfaces {
-                    let result = VInterface::try_from(item);
-                    if result.is_ok() {
-                        interfaces.push(Arc::new(result.unwrap()));
-                    } else {
-                        warn!("{:?}: {}", item, result.unwrap_err());
-                    }
+        if let Some(delta_compressed) = &resp.platform_data_delta {
+            match tp::PlatformDataDelta::decode(delta_compressed.as_slice()) {
+                Ok(delta) if delta.base_version.unwrap_or(0) == current_version => {
+                    let platform = &mut self.platform_data;
+                    apply_items_delta(
+                        &mut platform.interfaces,
+                        &delta.add_interfaces,
+                        &delta.del_interfaces,
+                    );
+                    apply_items_delta(
+                        &mut platform.peer_connections,
+                        &delta.add_peer_connections,
+                        &delta.del_peer_connections,
+                    );
+                    apply_items_delta(&mut platform.cidrs, &delta.add_cidrs, &delta.del_cidrs);
+                    info!(
+                        "Apply PlatformData delta, interfaces +{} -{}, peer-connections +{} -{}, cidrs +{} -{}.",
+                        delta.add_interfaces.len(),
+                        delta.del_interfaces.len(),
+                        delta.add_peer_connections.len(),
+                        delta.del_peer_connections.len(),
+                        delta.add_cidrs.len(),
+                        delta.del_cidrs.len()
+                    );
                 }
-                for item in &platform.peer_connections {
-                    peers.push(Arc::new(PeerConnection::from(item)));
+                Ok(delta) => {
+                    warn!(
+                        "Ignore PlatformData delta, base version {} != current version {}.",
+                        delta.base_version.unwrap_or(0),
+                        current_version
+                    );
+                    return false;
                 }
-                for item in &platform.cidrs {
-                    let result = Cidr::try_from(item);
-                    if result.is_ok() {
-                        cidrs.push(Arc::new(result.unwrap()));
-                    } else {
-                        warn!("{:?}: {}", item, result.unwrap_err());
-                    }
+                Err(e) => {
+                    error!("Invalid platform data delta: {}.", e);
+                    return false;
+                }
+            }
+        } else if let Some(platform_compressed) = &resp.platform_data {
+            match tp::PlatformData::decode(platform_compressed.as_slice()) {
+                Ok(platform) => self.platform_data = platform,
+                Err(_) => {
+                    error!("Invalid platform data.");
+                    self.platform_data = Default::default();
                 }
+            }
+        } else {
+            self.platform_data = Default::default();
+        }
 
-                self.update_platform_data(version, interfaces, peers, cidrs);
+        let platform = &self.platform_data;
+        let mut interfaces = Vec::new();
+        let mut peers = Vec::new();
+        let mut cidrs = Vec::new();
+        for item in &platform.interfaces {
+            let result = VInterface::try_from(item);
+            if result.is_ok() {
+                interfaces.push(Arc::new(result.unwrap()));
             } else {
-                error!("Invalid platform data.");
-                self.update_platform_data(version, vec![], vec![], vec![]);
+                warn!("{:?}: {}", item, result.unwrap_err());
             }
-        } else {
-            self.update_platform_data(version, vec![], vec![], vec![]);
         }
+        for item in &platform.peer_connections {
+            peers.push(Arc::new(PeerConnection::from(item)));
+        }
+        for item in &platform.cidrs {
+            let result = Cidr::try_from(item);
+            if result.is_ok() {
+                cidrs.push(Arc::new(result.unwrap()));
+            } else {
+                warn!("{:?}: {}", item, result.unwrap_err());
+            }
+        }
+
+        self.update_platform_data(version, interfaces, peers, cidrs);
         return true;
     }
 
@@ -365,27 +507,56 @@ impl Status {
             return false;
         }
 
-        if let Some(groups_compressed) = &resp.groups {
-            let groups = tp::Groups::decode(groups_compressed.as_slice());
-            if groups.is_ok() {
-                let groups = groups.unwrap();
-                let mut ip_groups = Vec::new();
-                for item in &groups.groups {
-                    let result = IpGroupData::try_from(item);
-                    if result.is_ok() {
-                        ip_groups.push(Arc::new(result.unwrap()));
-                    } else {
-                        warn!("{}", result.unwrap_err());
-                    }
+        if let Some(delta_compressed) = &resp.groups_delta {
+            match tp::GroupsDelta::decode(delta_compressed.as_slice()) {
+                Ok(delta) if delta.base_version.unwrap_or(0) == self.version_groups => {
+                    apply_items_delta(
+                        &mut self.groups.groups,
+                        &delta.add_groups,
+                        &delta.del_groups,
+                    );
+                    apply_items_delta(&mut self.groups.svcs, &delta.add_svcs, &delta.del_svcs);
+                    info!(
+                        "Apply Groups delta, groups +{} -{}.",
+                        delta.add_groups.len(),
+                        delta.del_groups.len()
+                    );
+                }
+                Ok(delta) => {
+                    warn!(
+                        "Ignore Groups delta, base version {} != current version {}.",
+                        delta.base_version.unwrap_or(0),
+                        self.version_groups
+                    );
+                    return false;
+                }
+                Err(e) => {
+                    error!("Invalid ip groups delta: {}.", e);
+                    return false;
+                }
+            }
+        } else if let Some(groups_compressed) = &resp.groups {
+            match tp::Groups::decode(groups_compressed.as_slice()) {
+                Ok(groups) => self.groups = groups,
+                Err(_) => {
+                    error!("Invalid ip groups.");
+                    self.groups = Default::default();
                 }
-                self.update_ip_groups(version, ip_groups);
-            } else {
-                error!("Invalid ip groups.");
-                self.update_ip_groups(version, vec![]);
             }
         } else {
-            self.update_ip_groups(version, vec![]);
+            self.groups = Default::default();
         }
+
+        let mut ip_groups = Vec::new();
+        for item in &self.groups.groups {
+            let result = IpGroupData::try_from(item);
+            if result.is_ok() {
+                ip_groups.push(Arc::new(result.unwrap()));
+            } else {
+                warn!("{}", result.unwrap_err());
+            }
+        }
+        self.update_ip_groups(version, ip_groups);
         return true;
     }
 
@@ -565,6 +736,7 @@ impl Synchronizer {
             version_platform_data: Some(status.version_platform_data),
             version_acls: Some(status.version_acls),
             version_groups: Some(status.version_groups),
+            support_delta: Some(true),
             state: Some(tp::State::Running.into()),
             revision: Some(static_config.version_info.revision.to_owned()),
             exception: Some(exception_handler.take()),
@@ -1430,3 +1602,82 @@ impl stats::OwnedCountable for NtpCounter {
         self.0.strong_count() == 0
     }
 }
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn peer_connection(id: u32, local_epc_id: u32, remote_epc_id: u32) -> tp::PeerConnection {
+        tp::PeerConnection {
+            id: Some(id),
+            local_epc_id: Some(local_epc_id),
+            remote_epc_id: Some(remote_epc_id),
+        }
+    }
+
+    fn wan_interface(epc_id: u32, ip: &str) -> tp::Interface {
+        tp::Interface {
+            epc_id: Some(epc_id),
+            ip_resources: vec![tp::IpResource {
+                ip: Some(ip.to_owned()),
+                ..Default::default()
+            }],
+            ..Default::default()
+        }
+    }
+
+    #[test]
+    fn apply_items_delta_by_key() {
+        let mut items = vec![
+            peer_connection(1, 1, 2),
+            peer_connection(0, 1, 3),
+            peer_connection(0, 1, 4),
+        ];
+        // 未设置的字段与默认值编码不同，但标识相同
+        let del = tp::PeerConnection {
+            id: None,
+            ..peer_connection(0, 1, 3)
+        };
+        assert_ne!(del.encode_to_vec(), items[1].encode_to_vec());
+        apply_items_delta(
+            &mut items,
+            &[peer_connection(2, 2, 3)],
+            &[del, peer_connection(5, 5, 5)],
+        );
+        assert_eq!(
+            items,
+            vec![
+                peer_connection(1, 1, 2),
+                peer_connection(0, 1, 4),
+                peer_connection(2, 2, 3)
+            ]
+        );
+    }
+
+    #[test]
+    fn apply_items_delta_same_key() {
+        // WAN接口没有id和mac，同key时删除内容相同的条目
+        let mut items = vec![
+            wan_interface(1, "1.1.1.1"),
+            wan_interface(1, "2.2.2.2"),
+            wan_interface(1, "3.3.3.3"),
+        ];
+        apply_items_delta(&mut items, &[], &[wan_interface(1, "2.2.2.2")]);
+        assert_eq!(
+            items,
+            vec![wan_interface(1, "1.1.1.1"), wan_interface(1, "3.3.3.3")]
+        );
+
+        // 内容都不相同时删除同key的第一个条目，删除次数不超过条目数
+        apply_items_delta(
+            &mut items,
+            &[wan_interface(1, "4.4.4.4")],
+            &[
+                wan_interface(1, "5.5.5.5"),
+                wan_interface(1, "5.5.5.5"),
+                wan_interface(1, "5.5.5.5"),
+            ],
+        );
+        assert_eq!(items, vec![wan_interface(1, "4.4.4.4")]);
+    }
+}
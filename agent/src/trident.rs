@@ -73,7 +73,7 @@ use crate::{
     handler::{NpbBuilder, PacketHandlerBuilder},
     integration_collector::{
         MetricServer, OpenTelemetry, OpenTelemetryCompressed, Profile, PrometheusMetric,
-        TelegrafMetric,
+        SkyWalkingSegment, TelegrafMetric, ZipkinSpans,
     },
     metric::document::BoxedDocument,
     monitor::Monitor,
@@ -1027,6 +1027,8 @@ pub struct AgentComponents {
     pub prometheus_uniform_sender: UniformSenderThread<PrometheusMetric>,
     pub telegraf_uniform_sender: UniformSenderThread<TelegrafMetric>,
     pub profile_uniform_sender: UniformSenderThread<Profile>,
+    pub zipkin_uniform_sender: UniformSenderThread<ZipkinSpans>,
+    pub skywalking_uniform_sender: UniformSenderThread<SkyWalkingSegment>,
     pub packet_sequence_parsers: Vec<PacketSequenceParser>, // Enterprise Edition Feature: packet-sequence
     pub packet_sequence_uniform_sender: UniformSenderThread<BoxedPacketSequenceBlock>, // Enterprise Edition Feature: packet-sequence
     pub proc_event_uniform_sender: UniformSenderThread<BoxedProcEvents>,
@@ -2054,6 +2056,49 @@ impl AgentComponents {
             true,
         );
 
+        let zipkin_queue_name = "1-zipkin-to-sender";
+        let (zipkin_sender, zipkin_receiver, counter) = queue::bounded_with_debug(
+            yaml_config.external_metrics_sender_queue_size,
+            zipkin_queue_name,
+            &queue_debugger,
+        );
+        stats_collector.register_countable(
+            "queue",
+            Countable::Owned(Box::new(counter)),
+            vec![StatsOption::Tag("module", zipkin_queue_name.to_string())],
+        );
+        let zipkin_uniform_sender = UniformSenderThread::new(
+            zipkin_queue_name,
+            Arc::new(zipkin_receiver),
+            config_handler.sender(),
+            stats_collector.clone(),
+            exception_handler.clone(),
+            true,
+        );
+
+        let skywalking_queue_name = "1-skywalking-to-sender";
+        let (skywalking_sender, skywalking_receiver, counter) = queue::bounded_with_debug(
+            yaml_config.external_metrics_sender_queue_size,
+            skywalking_queue_name,
+            &queue_debugger,
+        );
+        stats_collector.register_countable(
+            "queue",
+            Countable::Owned(Box::new(counter)),
+            vec![StatsOption::Tag(
+                "module",
+                skywalking_queue_name.to_string(),
+            )],
+        );
+        let skywalking_uniform_sender = UniformSenderThread::new(
+            skywalking_queue_name,
+            Arc::new(skywalking_receiver),
+            config_handler.sender(),
+            stats_collector.clone(),
+            exception_handler.clone(),
+            true,
+        );
+
         let compressed_otel_queue_name = "1-compressed-otel-to-sender";
         let (compressed_otel_sender, compressed_otel_receiver, counter) = queue::bounded_with_debug(
             yaml_config.external_metrics_sender_queue_size,
@@ -2085,6 +2130,8 @@ impl AgentComponents {
             prometheus_sender,
             telegraf_sender,
             profile_sender,
+            zipkin_sender,
+            skywalking_sender,
             candidate_config.metric_server.port,
             exception_handler.clone(),
             candidate_config.metric_server.compressed,
@@ -2164,6 +2211,8 @@ impl AgentComponents {
             prometheus_uniform_sender,
             telegraf_uniform_sender,
             profile_uniform_sender,
+            zipkin_uniform_sender,
+            skywalking_uniform_sender,
             proc_event_uniform_sender,
             tap_mode: candidate_config.tap_mode,
             packet_sequence_uniform_sender, // Enterprise Edition Feature: packet-sequence
@@ -2251,6 +2300,8 @@ impl AgentComponents {
             self.prometheus_uniform_sender.start();
             self.telegraf_uniform_sender.start();
             self.profile_uniform_sender.start();
+            self.zipkin_uniform_sender.start();
+            self.skywalking_uniform_sender.start();
             self.proc_event_uniform_sender.start();
             if self.config.metric_server.enabled {
                 self.external_metrics_server.start();
@@ -2334,6 +2385,12 @@ impl AgentComponents {
         if let Some(h) = self.profile_uniform_sender.notify_stop() {
             join_handles.push(h);
         }
+        if let Some(h) = self.zipkin_uniform_sender.notify_stop() {
+            join_handles.push(h);
+        }
+        if let Some(h) = self.skywalking_uniform_sender.notify_stop() {
+            join_handles.push(h);
+        }
         if let Some(h) = self.proc_event_uniform_sender.notify_stop() {
             join_handles.push(h);
         }
@@ -19,8 +19,11 @@ package ctl
 import (
 	"fmt"
 	"io/ioutil"
+	"net/url"
 	"os"
+	"strings"
 
+	simplejson "github.com/bitly/go-simplejson"
 	"github.com/spf13/cobra"
 	"sigs.k8s.io/yaml"
 
@@ -32,7 +35,7 @@ func RegisterAgentGroupConfigCommand() *cobra.Command {
 		Use:   "agent-group-config",
 		Short: "agent-group config operation commands",
 		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Printf("please run with 'example | list | create | update | delete'.\n")
+			fmt.Printf("please run with 'example | list | create | update | delete | history | diff | rollback'.\n")
 		},
 	}
 
@@ -47,38 +50,83 @@ func RegisterAgentGroupConfigCommand() *cobra.Command {
 	}
 	list.Flags().StringVarP(&listOutput, "output", "o", "", "output format")
 
-	var createFilename string
+	var createFilename, createComment string
+	var createDryRun bool
 	create := &cobra.Command{
 		Use:     "create -f <filename>",
 		Short:   "create config",
 		Example: "deepflow-ctl agent-group-config create -f deepflow-config.yaml",
 		Run: func(cmd *cobra.Command, args []string) {
-			createAgentGroupConfig(cmd, args, createFilename)
+			createAgentGroupConfig(cmd, args, createFilename, createComment, createDryRun)
 		},
 	}
 	create.Flags().StringVarP(&createFilename, "filename", "f", "", "file to use create agent-group config")
+	create.Flags().StringVarP(&createComment, "comment", "m", "", "comment of the change, saved in config history")
+	create.Flags().BoolVarP(&createDryRun, "dry-run", "", false, "only validate config and show affected agents and changes")
 	create.MarkFlagRequired("filename")
 
-	var updateFilename string
+	var updateFilename, updateComment string
+	var updateDryRun bool
 	update := &cobra.Command{
 		Use:     "update -f <filename>",
 		Short:   "update agent-group config",
-		Example: "deepflow-ctl agent-group-config update -f deepflow-config.yaml",
+		Example: "deepflow-ctl agent-group-config update -f deepflow-config.yaml -m 'enable npb dedup'",
 		Run: func(cmd *cobra.Command, args []string) {
-			updateAgentGroupConfig(cmd, args, updateFilename)
+			updateAgentGroupConfig(cmd, args, updateFilename, updateComment, updateDryRun)
 		},
 	}
 	update.Flags().StringVarP(&updateFilename, "filename", "f", "", "file to use update agent-group config")
+	update.Flags().StringVarP(&updateComment, "comment", "m", "", "comment of the change, saved in config history")
+	update.Flags().BoolVarP(&updateDryRun, "dry-run", "", false, "only validate config and show affected agents and changes")
 	update.MarkFlagRequired("filename")
 
+	var deleteComment string
 	delete := &cobra.Command{
 		Use:     "delete [agent-group ID]",
 		Short:   "delete agent-group config",
 		Example: "deepflow-ctl agent-group-config delete g-xxxxxx",
 		Run: func(cmd *cobra.Command, args []string) {
-			deleteAgentGroupConfig(cmd, args)
+			deleteAgentGroupConfig(cmd, args, deleteComment)
 		},
 	}
+	delete.Flags().StringVarP(&deleteComment, "comment", "m", "", "comment of the change, saved in config history")
+
+	var historyRevision int
+	history := &cobra.Command{
+		Use:     "history [agent-group ID]",
+		Short:   "show agent-group config revisions",
+		Example: "deepflow-ctl agent-group-config history g-xxxxxx\ndeepflow-ctl agent-group-config history g-xxxxxx --revision 3",
+		Run: func(cmd *cobra.Command, args []string) {
+			historyAgentGroupConfig(cmd, args, historyRevision)
+		},
+	}
+	history.Flags().IntVarP(&historyRevision, "revision", "r", 0, "show config of the revision")
+
+	var diffFrom, diffTo int
+	diff := &cobra.Command{
+		Use:     "diff [agent-group ID]",
+		Short:   "show changes between agent-group config revisions",
+		Example: "deepflow-ctl agent-group-config diff g-xxxxxx --from 2 --to 4\n(compare the latest revision with the previous one by default)",
+		Run: func(cmd *cobra.Command, args []string) {
+			diffAgentGroupConfig(cmd, args, diffFrom, diffTo)
+		},
+	}
+	diff.Flags().IntVarP(&diffFrom, "from", "", 0, "revision to compare from, default is the previous revision of --to")
+	diff.Flags().IntVarP(&diffTo, "to", "", 0, "revision to compare to, default is the latest revision")
+
+	var rollbackRevision int
+	var rollbackComment string
+	rollback := &cobra.Command{
+		Use:     "rollback [agent-group ID]",
+		Short:   "roll back agent-group config to a revision",
+		Example: "deepflow-ctl agent-group-config rollback g-xxxxxx --revision 3",
+		Run: func(cmd *cobra.Command, args []string) {
+			rollbackAgentGroupConfig(cmd, args, rollbackRevision, rollbackComment)
+		},
+	}
+	rollback.Flags().IntVarP(&rollbackRevision, "revision", "r", 0, "revision to roll back to")
+	rollback.Flags().StringVarP(&rollbackComment, "comment", "m", "", "comment of the change, saved in config history")
+	rollback.MarkFlagRequired("revision")
 
 	example := &cobra.Command{
 		Use:   "example",
@@ -92,6 +140,9 @@ func RegisterAgentGroupConfigCommand() *cobra.Command {
 	agentGroupConfig.AddCommand(create)
 	agentGroupConfig.AddCommand(update)
 	agentGroupConfig.AddCommand(delete)
+	agentGroupConfig.AddCommand(history)
+	agentGroupConfig.AddCommand(diff)
+	agentGroupConfig.AddCommand(rollback)
 	return agentGroupConfig
 }
 
@@ -156,21 +207,57 @@ func listAgentGroupConfig(cmd *cobra.Command, args []string, output string) {
 	}
 }
 
-func createAgentGroupConfig(cmd *cobra.Command, args []string, createFilename string) {
+// query parameters saved in config history, the author is taken from the authenticated user by the server
+func getChangeQuery(comment string, dryRun bool) string {
+	values := url.Values{}
+	if dryRun {
+		values.Set("dry_run", "true")
+	} else if comment != "" {
+		values.Set("comment", comment)
+	}
+	return values.Encode()
+}
+
+func printValidation(response *simplejson.Json) {
+	data := response.Get("DATA")
+	if data.Get("VALID").MustBool() {
+		fmt.Println("config is valid")
+	} else {
+		fmt.Println("config is invalid:")
+		for _, e := range data.Get("ERRORS").MustStringArray() {
+			fmt.Printf("  %s\n", e)
+		}
+	}
+	vtaps := data.Get("AFFECTED_VTAPS").MustStringArray()
+	fmt.Printf("affected agents (%d) of agent-group %s:\n", len(vtaps), data.Get("VTAP_GROUP_ID").MustString())
+	if len(vtaps) > 0 {
+		fmt.Printf("  %s\n", strings.Join(vtaps, ", "))
+	}
+	if diff := data.Get("DIFF").MustString(); diff != "" {
+		fmt.Println(diff)
+	} else {
+		fmt.Println("no changes")
+	}
+}
+
+func createAgentGroupConfig(cmd *cobra.Command, args []string, createFilename, comment string, dryRun bool) {
 	server := common.GetServerInfo(cmd)
-	url := fmt.Sprintf("http://%s:%d/v1/vtap-group-configuration/advanced/", server.IP, server.Port)
+	url := fmt.Sprintf("http://%s:%d/v1/vtap-group-configuration/advanced/?%s", server.IP, server.Port, getChangeQuery(comment, dryRun))
 	yamlFile, err := ioutil.ReadFile(createFilename)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 	}
-	_, err = common.CURLPerform("POST", url, nil, string(yamlFile))
+	response, err := common.CURLPerform("POST", url, nil, string(yamlFile))
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return
 	}
+	if dryRun {
+		printValidation(response)
+	}
 }
 
-func updateAgentGroupConfig(cmd *cobra.Command, args []string, updateFilename string) {
+func updateAgentGroupConfig(cmd *cobra.Command, args []string, updateFilename, comment string, dryRun bool) {
 	yamlFile, err := ioutil.ReadFile(updateFilename)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -206,15 +293,18 @@ func updateAgentGroupConfig(cmd *cobra.Command, args []string, updateFilename st
 	lcuuid := group.Get("LCUUID").MustString()
 
 	// call vtap-group config update api
-	url = fmt.Sprintf("http://%s:%d/v1/vtap-group-configuration/advanced/%s/", server.IP, server.Port, lcuuid)
-	_, err = common.CURLPerform("PATCH", url, nil, string(yamlFile))
+	url = fmt.Sprintf("http://%s:%d/v1/vtap-group-configuration/advanced/%s/?%s", server.IP, server.Port, lcuuid, getChangeQuery(comment, dryRun))
+	response, err = common.CURLPerform("PATCH", url, nil, string(yamlFile))
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return
 	}
+	if dryRun {
+		printValidation(response)
+	}
 }
 
-func deleteAgentGroupConfig(cmd *cobra.Command, args []string) {
+func deleteAgentGroupConfig(cmd *cobra.Command, args []string, comment string) {
 	if len(args) == 0 {
 		fmt.Fprintln(os.Stderr, "must specify agent-group ID.\nExample: %s", cmd.Example)
 		return
@@ -222,8 +312,8 @@ func deleteAgentGroupConfig(cmd *cobra.Command, args []string) {
 
 	server := common.GetServerInfo(cmd)
 	url := fmt.Sprintf(
-		"http://%s:%d/v1/vtap-group-configuration/filter/?vtap_group_id=%s",
-		server.IP, server.Port, args[0],
+		"http://%s:%d/v1/vtap-group-configuration/filter/?vtap_group_id=%s&%s",
+		server.IP, server.Port, args[0], getChangeQuery(comment, false),
 	)
 	_, err := common.CURLPerform("DELETE", url, nil, "")
 	if err != nil {
@@ -231,3 +321,83 @@ func deleteAgentGroupConfig(cmd *cobra.Command, args []string) {
 		return
 	}
 }
+
+func historyAgentGroupConfig(cmd *cobra.Command, args []string, revision int) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "must specify agent-group ID.\nExample: %s\n", cmd.Example)
+		return
+	}
+
+	server := common.GetServerInfo(cmd)
+	url := fmt.Sprintf(
+		"http://%s:%d/v1/vtap-group-configuration/revision/?vtap_group_id=%s&revision=%d",
+		server.IP, server.Port, args[0], revision,
+	)
+	response, err := common.CURLPerform("GET", url, nil, "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	data := response.Get("DATA")
+	if revision > 0 {
+		if len(data.MustArray()) > 0 {
+			fmt.Println(data.GetIndex(0).Get("CONFIG").MustString())
+		}
+		return
+	}
+
+	cmdFormat := "%-8s %-9s %-19s %-16s %s\n"
+	fmt.Printf(cmdFormat, "REVISION", "OPERATION", "CREATED_AT", "AUTHOR", "COMMENT")
+	for i := range data.MustArray() {
+		r := data.GetIndex(i)
+		fmt.Printf(cmdFormat,
+			fmt.Sprint(r.Get("REVISION").MustInt()),
+			r.Get("OPERATION").MustString(),
+			r.Get("CREATED_AT").MustString(),
+			r.Get("AUTHOR").MustString(),
+			r.Get("COMMENT").MustString(),
+		)
+	}
+}
+
+func diffAgentGroupConfig(cmd *cobra.Command, args []string, from, to int) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "must specify agent-group ID.\nExample: %s\n", cmd.Example)
+		return
+	}
+
+	server := common.GetServerInfo(cmd)
+	url := fmt.Sprintf(
+		"http://%s:%d/v1/vtap-group-configuration/revision/diff/?vtap_group_id=%s&from=%d&to=%d",
+		server.IP, server.Port, args[0], from, to,
+	)
+	response, err := common.CURLPerform("GET", url, nil, "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	if diff := response.Get("DATA").MustString(); diff != "" {
+		fmt.Println(diff)
+	} else {
+		fmt.Println("no changes")
+	}
+}
+
+func rollbackAgentGroupConfig(cmd *cobra.Command, args []string, revision int, comment string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "must specify agent-group ID.\nExample: %s\n", cmd.Example)
+		return
+	}
+
+	server := common.GetServerInfo(cmd)
+	url := fmt.Sprintf(
+		"http://%s:%d/v1/vtap-group-configuration/revision/rollback/?vtap_group_id=%s&revision=%d&%s",
+		server.IP, server.Port, args[0], revision, getChangeQuery(comment, false),
+	)
+	_, err := common.CURLPerform("POST", url, nil, "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	fmt.Printf("agent-group %s config rolled back to revision %d\n", args[0], revision)
+}
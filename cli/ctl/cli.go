@@ -63,6 +63,7 @@ func Execute(version string) {
 	root.AddCommand(RegisterServerCommand())
 	root.AddCommand(RegisterRepoCommand())
 	root.AddCommand(RegisterPluginCommand())
+	root.AddCommand(RegisterEventCommand())
 	root.AddCommand(RegisterPrometheusCacheCommand())
 	root.AddCommand(RegisterPromQLCommand())
 
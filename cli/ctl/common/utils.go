@@ -64,6 +64,24 @@ func CURLPerform(method string, url string, body map[string]interface{}, strBody
 	return parseResponse(req)
 }
 
+// 功能：以Bearer token认证的方式调用API，token为空时不携带认证信息
+func CURLPerformWithToken(method string, url string, body map[string]interface{}, token string) (*simplejson.Json, error) {
+	bodyStr, _ := json.Marshal(&body)
+	req, err := http.NewRequest(method, url, bytes.NewReader(bodyStr))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/plain")
+	req.Header.Set("X-User-Id", "1")
+	req.Header.Set("X-User-Type", "1")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return parseResponse(req)
+}
+
 func parseResponse(req *http.Request) (*simplejson.Json, error) {
 	errResponse, _ := simplejson.NewJson([]byte("{}"))
 	// TODO: 通过配置文件获取API超时时间
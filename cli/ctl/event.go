@@ -0,0 +1,148 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ctl
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/deepflowio/deepflow/cli/ctl/common"
+	"github.com/deepflowio/deepflow/cli/ctl/common/jsonparser"
+	"github.com/spf13/cobra"
+)
+
+const CHANGE_EVENT_TOKEN_ENV = "DEEPFLOW_CHANGE_EVENT_TOKEN"
+
+type changeEventPush struct {
+	eventType    string
+	description  string
+	eventTime    string
+	podCluster   string
+	podNamespace string
+	podService   string
+	podGroup     string
+	pod          string
+	version      string
+	author       string
+	source       string
+	links        []string
+	attributes   map[string]string
+	token        string
+}
+
+func RegisterEventCommand() *cobra.Command {
+	event := &cobra.Command{
+		Use:   "event",
+		Short: "event operation commands",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Printf("please run with 'push'.\n")
+		},
+	}
+
+	var p changeEventPush
+	push := &cobra.Command{
+		Use:   "push",
+		Short: "push a change event (deployment, config change, feature flag ...) into deepflow",
+		Example: "deepflow-ctl event push --type deploy --namespace default --service productpage --version v1.2.0 --author tom --link https://ci.example.com/builds/42\n" +
+			"(token can also be specified by env " + CHANGE_EVENT_TOKEN_ENV + ")",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := pushChangeEvent(cmd, &p); err != nil {
+				fmt.Println(err)
+			}
+		},
+	}
+	push.Flags().StringVarP(&p.eventType, "type", "", "", "event type, e.g. deploy, config-change, feature-flag, rollback")
+	push.Flags().StringVarP(&p.description, "description", "", "", "event description")
+	push.Flags().StringVarP(&p.eventTime, "time", "", "", "event time, unix timestamp in seconds or RFC3339, default now")
+	push.Flags().StringVarP(&p.podCluster, "pod-cluster", "", "", "select resources in the pod cluster")
+	push.Flags().StringVarP(&p.podNamespace, "namespace", "", "", "select resources in the namespace")
+	push.Flags().StringVarP(&p.podService, "service", "", "", "select the service")
+	push.Flags().StringVarP(&p.podGroup, "workload", "", "", "select the workload (deployment, statefulset ...)")
+	push.Flags().StringVarP(&p.pod, "pod", "", "", "select the pod")
+	push.Flags().StringVarP(&p.version, "version", "", "", "version being deployed")
+	push.Flags().StringVarP(&p.author, "author", "", "", "author of the change")
+	push.Flags().StringVarP(&p.source, "source", "", "deepflow-ctl", "system reporting the change, e.g. jenkins, argocd")
+	push.Flags().StringSliceVarP(&p.links, "link", "", nil, "links of the change, separated by ','")
+	push.Flags().StringToStringVarP(&p.attributes, "attr", "", nil, "custom attributes, e.g. --attr commit=a1b2c3,env=prod")
+	push.Flags().StringVarP(&p.token, "token", "", "", "auth token configured in controller change-event.auth-tokens")
+	push.MarkFlagRequired("type")
+
+	event.AddCommand(push)
+	return event
+}
+
+func parseChangeEventTime(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if ts, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return ts, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time (%s), must be unix timestamp in seconds or RFC3339", value)
+	}
+	return t.Unix(), nil
+}
+
+func pushChangeEvent(cmd *cobra.Command, p *changeEventPush) error {
+	eventTime, err := parseChangeEventTime(p.eventTime)
+	if err != nil {
+		return err
+	}
+	token := p.token
+	if token == "" {
+		token = os.Getenv(CHANGE_EVENT_TOKEN_ENV)
+	}
+	body := map[string]interface{}{
+		"EVENT_TYPE":    p.eventType,
+		"DESCRIPTION":   p.description,
+		"TIME":          eventTime,
+		"POD_CLUSTER":   p.podCluster,
+		"POD_NAMESPACE": p.podNamespace,
+		"POD_SERVICE":   p.podService,
+		"POD_GROUP":     p.podGroup,
+		"POD":           p.pod,
+		"VERSION":       p.version,
+		"AUTHOR":        p.author,
+		"SOURCE":        p.source,
+		"LINKS":         p.links,
+		"ATTRIBUTES":    p.attributes,
+	}
+
+	server := common.GetServerInfo(cmd)
+	url := fmt.Sprintf("http://%s:%d/v1/change-events/", server.IP, server.Port)
+	response, err := common.CURLPerformWithToken("POST", url, body, token)
+	if err != nil {
+		return err
+	}
+	data := response.Get("DATA")
+	nameMaxSize := jsonparser.GetTheMaxSizeOfAttr(data, "INSTANCE_NAME")
+	cmdFormat := "%-13s %-11s %-*s\n"
+	fmt.Printf(cmdFormat, "INSTANCE_TYPE", "INSTANCE_ID", nameMaxSize, "INSTANCE_NAME")
+	for i := range data.MustArray() {
+		d := data.GetIndex(i)
+		fmt.Printf(cmdFormat,
+			strconv.Itoa(d.Get("INSTANCE_TYPE").MustInt()),
+			strconv.Itoa(d.Get("INSTANCE_ID").MustInt()),
+			nameMaxSize, d.Get("INSTANCE_NAME").MustString(),
+		)
+	}
+	return nil
+}
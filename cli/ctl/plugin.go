@@ -24,6 +24,8 @@ import (
 	"mime/multipart"
 	"os"
 	"path"
+	"strconv"
+	"strings"
 
 	"github.com/deepflowio/deepflow/cli/ctl/common"
 	"github.com/deepflowio/deepflow/cli/ctl/common/jsonparser"
@@ -42,11 +44,12 @@ func RegisterPluginCommand() *cobra.Command {
 		Use:   "plugin",
 		Short: "plugin operation commands",
 		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Printf("please run with 'list | create | delete.'\n")
+			fmt.Printf("please run with 'list | create | delete | versions | assign | rollout | rollback | agents.'\n")
 		},
 	}
 
 	var createType, image, name string
+	var noRollout bool
 	create := &cobra.Command{
 		Use:     "create",
 		Short:   "create plugin",
@@ -56,7 +59,7 @@ func RegisterPluginCommand() *cobra.Command {
 				fmt.Printf("file(%s) not found\n", image)
 				return
 			}
-			if err := createPlugin(cmd, createType, image, name); err != nil {
+			if err := createPlugin(cmd, createType, image, name, !noRollout); err != nil {
 				fmt.Println(err)
 			}
 		},
@@ -64,6 +67,7 @@ func RegisterPluginCommand() *cobra.Command {
 	create.Flags().StringVarP(&createType, "type", "", "", "type of image file, currently supports: wasm")
 	create.Flags().StringVarP(&image, "image", "", "", "plugin image to upload")
 	create.Flags().StringVarP(&name, "name", "", "", "specify a unique alias for image")
+	create.Flags().BoolVarP(&noRollout, "no-rollout", "", false, "only upload a new version, keep the default version unchanged")
 	create.MarkFlagsRequiredTogether("type", "image", "name")
 
 	list := &cobra.Command{
@@ -86,16 +90,87 @@ func RegisterPluginCommand() *cobra.Command {
 		},
 	}
 
+	versions := &cobra.Command{
+		Use:     "versions",
+		Short:   "list plugin versions",
+		Example: "deepflow-ctl plugin versions <name>",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := listPluginVersions(cmd, args); err != nil {
+				fmt.Println(err)
+			}
+		},
+	}
+
+	var assignVersion int
+	var agentGroupIDs []string
+	assign := &cobra.Command{
+		Use:     "assign",
+		Short:   "assign plugin version to agent groups",
+		Example: "deepflow-ctl plugin assign <name> --version 2 --agent-group-id g-xxxxxx\n(version 0 means following the default version)",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := assignPlugin(cmd, args, assignVersion, agentGroupIDs); err != nil {
+				fmt.Println(err)
+			}
+		},
+	}
+	assign.Flags().IntVarP(&assignVersion, "version", "", 0, "plugin version, 0 means following the default version")
+	assign.Flags().StringSliceVarP(&agentGroupIDs, "agent-group-id", "", nil, "agent group IDs, separated by ','")
+	assign.MarkFlagRequired("agent-group-id")
+
+	var rolloutVersion int
+	rollout := &cobra.Command{
+		Use:     "rollout",
+		Short:   "set the default version of plugin",
+		Example: "deepflow-ctl plugin rollout <name> --version 2",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := rolloutPlugin(cmd, args, "rollout", rolloutVersion); err != nil {
+				fmt.Println(err)
+			}
+		},
+	}
+	rollout.Flags().IntVarP(&rolloutVersion, "version", "", 0, "plugin version")
+	rollout.MarkFlagRequired("version")
+
+	var rollbackVersion int
+	rollback := &cobra.Command{
+		Use:     "rollback",
+		Short:   "roll back the default version of plugin",
+		Example: "deepflow-ctl plugin rollback <name> [--version 1]\n(roll back to the previous version if version is not specified)",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := rolloutPlugin(cmd, args, "rollback", rollbackVersion); err != nil {
+				fmt.Println(err)
+			}
+		},
+	}
+	rollback.Flags().IntVarP(&rollbackVersion, "version", "", 0, "plugin version")
+
+	agents := &cobra.Command{
+		Use:     "agents",
+		Short:   "list agents running plugin",
+		Example: "deepflow-ctl plugin agents <name>",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := listPluginAgents(cmd, args); err != nil {
+				fmt.Println(err)
+			}
+		},
+	}
+
 	plugin.AddCommand(create)
 	plugin.AddCommand(list)
 	plugin.AddCommand(delete)
+	plugin.AddCommand(versions)
+	plugin.AddCommand(assign)
+	plugin.AddCommand(rollout)
+	plugin.AddCommand(rollback)
+	plugin.AddCommand(agents)
 	return plugin
 }
 
-func createPlugin(cmd *cobra.Command, t, image, name string) error {
+func createPlugin(cmd *cobra.Command, t, image, name string, rollout bool) error {
 	bodyBuf := &bytes.Buffer{}
 	bodyWriter := multipart.NewWriter(bodyBuf)
 	bodyWriter.WriteField("NAME", name)
+	bodyWriter.WriteField("ROLLOUT", strconv.FormatBool(rollout))
 	if t == "wasm" {
 		bodyWriter.WriteField("TYPE", "1")
 	}
@@ -131,14 +206,15 @@ func listPlugin(cmd *cobra.Command) {
 		typeMaxSize = jsonparser.GetTheMaxSizeOfAttr(data, "TYPE")
 		nameMaxSize = jsonparser.GetTheMaxSizeOfAttr(data, "NAME")
 	)
-	cmdFormat := "%-*s %-*s %-19s\n"
-	fmt.Printf(cmdFormat, typeMaxSize, "TYPE", nameMaxSize, "NAME", "UPDATED_AT")
+	cmdFormat := "%-*s %-*s %-7s %-19s\n"
+	fmt.Printf(cmdFormat, typeMaxSize, "TYPE", nameMaxSize, "NAME", "VERSION", "UPDATED_AT")
 	for i := range data.MustArray() {
 		d := data.GetIndex(i)
 
 		fmt.Printf(cmdFormat,
 			typeMaxSize, PluginTypeIntToName[d.Get("TYPE").MustInt()],
 			nameMaxSize, d.Get("NAME").MustString(),
+			strconv.Itoa(d.Get("VERSION").MustInt()),
 			d.Get("UPDATED_AT").MustString(),
 		)
 	}
@@ -156,3 +232,107 @@ func deletePlugin(cmd *cobra.Command, args []string) error {
 	_, err := common.CURLPerform("DELETE", url, nil, "")
 	return err
 }
+
+func getPluginNameArg(cmd *cobra.Command, args []string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("must specify name\nExample: %s", cmd.Example)
+	} else if len(args) > 1 {
+		return "", fmt.Errorf("must specify one name\nExample: %s", cmd.Example)
+	}
+	return args[0], nil
+}
+
+func listPluginVersions(cmd *cobra.Command, args []string) error {
+	name, err := getPluginNameArg(cmd, args)
+	if err != nil {
+		return err
+	}
+	server := common.GetServerInfo(cmd)
+	url := fmt.Sprintf("http://%s:%d/v1/plugin/%s/version/", server.IP, server.Port, name)
+	response, err := common.CURLPerform("GET", url, nil, "")
+	if err != nil {
+		return err
+	}
+	data := response.Get("DATA")
+	cmdFormat := "%-7s %-7s %-64s %-10s %-19s %s\n"
+	fmt.Printf(cmdFormat, "VERSION", "DEFAULT", "DIGEST", "SIZE", "CREATED_AT", "AGENT_GROUP_IDS")
+	for i := range data.MustArray() {
+		d := data.GetIndex(i)
+		isDefault := ""
+		if d.Get("IS_DEFAULT").MustBool() {
+			isDefault = "*"
+		}
+		fmt.Printf(cmdFormat,
+			strconv.Itoa(d.Get("VERSION").MustInt()),
+			isDefault,
+			d.Get("DIGEST").MustString(),
+			strconv.Itoa(d.Get("SIZE").MustInt()),
+			d.Get("CREATED_AT").MustString(),
+			strings.Join(d.Get("VTAP_GROUP_IDS").MustStringArray(), ","),
+		)
+	}
+	return nil
+}
+
+func assignPlugin(cmd *cobra.Command, args []string, version int, agentGroupIDs []string) error {
+	name, err := getPluginNameArg(cmd, args)
+	if err != nil {
+		return err
+	}
+	server := common.GetServerInfo(cmd)
+	url := fmt.Sprintf("http://%s:%d/v1/plugin/%s/assign/", server.IP, server.Port, name)
+	body := map[string]interface{}{
+		"VERSION":        version,
+		"VTAP_GROUP_IDS": agentGroupIDs,
+	}
+	_, err = common.CURLPerform("POST", url, body, "")
+	return err
+}
+
+// operation is rollout or rollback
+func rolloutPlugin(cmd *cobra.Command, args []string, operation string, version int) error {
+	name, err := getPluginNameArg(cmd, args)
+	if err != nil {
+		return err
+	}
+	server := common.GetServerInfo(cmd)
+	url := fmt.Sprintf("http://%s:%d/v1/plugin/%s/%s/", server.IP, server.Port, name, operation)
+	response, err := common.CURLPerform("POST", url, map[string]interface{}{"VERSION": version}, "")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("plugin %s default version: %d\n", name, response.Get("DATA").Get("VERSION").MustInt())
+	return nil
+}
+
+func listPluginAgents(cmd *cobra.Command, args []string) error {
+	name, err := getPluginNameArg(cmd, args)
+	if err != nil {
+		return err
+	}
+	server := common.GetServerInfo(cmd)
+	url := fmt.Sprintf("http://%s:%d/v1/plugin/%s/agents/", server.IP, server.Port, name)
+	response, err := common.CURLPerform("GET", url, nil, "")
+	if err != nil {
+		return err
+	}
+	data := response.Get("DATA")
+	var (
+		nameMaxSize  = jsonparser.GetTheMaxSizeOfAttr(data, "VTAP_NAME")
+		groupMaxSize = jsonparser.GetTheMaxSizeOfAttr(data, "VTAP_GROUP_NAME")
+	)
+	cmdFormat := "%-*s %-*s %-14s %-7s %-6s %s\n"
+	fmt.Printf(cmdFormat, nameMaxSize, "AGENT", groupMaxSize, "AGENT_GROUP", "AGENT_GROUP_ID", "VERSION", "PINNED", "DIGEST")
+	for i := range data.MustArray() {
+		d := data.GetIndex(i)
+		fmt.Printf(cmdFormat,
+			nameMaxSize, d.Get("VTAP_NAME").MustString(),
+			groupMaxSize, d.Get("VTAP_GROUP_NAME").MustString(),
+			d.Get("VTAP_GROUP_ID").MustString(),
+			strconv.Itoa(d.Get("VERSION").MustInt()),
+			strconv.FormatBool(d.Get("PINNED").MustBool()),
+			d.Get("DIGEST").MustString(),
+		)
+	}
+	return nil
+}
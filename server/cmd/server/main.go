@@ -30,6 +30,7 @@ import (
 
 	"github.com/deepflowio/deepflow/server/common"
 	"github.com/deepflowio/deepflow/server/controller/controller"
+	"github.com/deepflowio/deepflow/server/controller/election"
 	"github.com/deepflowio/deepflow/server/controller/report"
 	"github.com/deepflowio/deepflow/server/controller/trisolaris/utils"
 	"github.com/deepflowio/deepflow/server/ingester/ingester"
@@ -129,6 +130,10 @@ func main() {
 	report.SetServerInfo(Branch, RevCount, Revision)
 
 	shared := common.NewControllerIngesterShared()
+	shared.IsMaster = func() bool {
+		isMaster, _ := election.IsMasterController()
+		return isMaster
+	}
 
 	go controller.Start(ctx, *configPath, cfg.LogFile, shared)
 
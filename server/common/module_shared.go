@@ -27,6 +27,8 @@ const QUEUE_SIZE = 1 << 16
 
 type ControllerIngesterShared struct {
 	ResourceEventQueue *queue.OverwriteQueue
+	// 当前deepflow-server是否为master, 只需在一个deepflow-server上执行的任务据此判断是否运行, 为nil时总是运行
+	IsMaster func() bool
 }
 
 func NewControllerIngesterShared() *ControllerIngesterShared {
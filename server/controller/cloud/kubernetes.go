@@ -125,6 +125,7 @@ func (c *Cloud) getKubernetesData() model.Resource {
 		PodIngressRules:        kubernetesGatherResource.PodIngressRules,
 		PodIngressRuleBackends: kubernetesGatherResource.PodIngressRuleBackends,
 		PrometheusTargets:      kubernetesGatherResource.PrometheusTargets,
+		KubernetesEvents:       kubernetesGatherResource.KubernetesEvents,
 		IPs:                    ips,
 		VMs:                    vms,
 		Regions:                regions,
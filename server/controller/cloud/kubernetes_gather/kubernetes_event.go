@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kubernetes_gather
+
+import (
+	"time"
+
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+
+	"github.com/bitly/go-simplejson"
+	uuid "github.com/satori/go.uuid"
+)
+
+// 事件为可选资源，解析失败只跳过该条，不影响其他资源的同步
+func (k *KubernetesGather) getKubernetesEvents(pods []model.Pod, podNodes []model.PodNode) []model.KubernetesEvent {
+	log.Debug("get kubernetes events starting")
+	kubernetesEvents := []model.KubernetesEvent{}
+	if len(k.k8sInfo["*v1.Event"]) == 0 {
+		return kubernetesEvents
+	}
+
+	namespaceLcuuidToName := map[string]string{}
+	for name, lcuuid := range k.namespaceToLcuuid {
+		namespaceLcuuidToName[lcuuid] = name
+	}
+	namespacePodNameToPod := map[string]model.Pod{}
+	for _, pod := range pods {
+		namespacePodNameToPod[namespaceLcuuidToName[pod.PodNamespaceLcuuid]+"/"+pod.Name] = pod
+	}
+	nodeNameToLcuuid := map[string]string{}
+	for _, node := range podNodes {
+		nodeNameToLcuuid[node.Name] = node.Lcuuid
+	}
+	podClusterLcuuid := common.GetUUID(k.UuidGenerate, uuid.Nil)
+
+	for _, e := range k.k8sInfo["*v1.Event"] {
+		eData, err := simplejson.NewJson([]byte(e))
+		if err != nil {
+			log.Errorf("kubernetes event initialization simplejson error: (%s)", err.Error())
+			continue
+		}
+		uID := eData.GetPath("metadata", "uid").MustString()
+		if uID == "" {
+			log.Debug("kubernetes event uid not found")
+			continue
+		}
+		reason := eData.Get("reason").MustString()
+		involvedObject := eData.Get("involvedObject")
+		kind := involvedObject.Get("kind").MustString()
+		name := involvedObject.Get("name").MustString()
+		if reason == "" || kind == "" || name == "" {
+			log.Debugf("kubernetes event (%s) reason or involved object not found", uID)
+			continue
+		}
+		namespace := involvedObject.Get("namespace").MustString()
+
+		kubernetesEvent := model.KubernetesEvent{
+			Lcuuid:             uID,
+			Reason:             reason,
+			Message:            eData.Get("message").MustString(),
+			Type:               eData.Get("type").MustString(),
+			Count:              eData.Get("count").MustInt(1),
+			InvolvedKind:       kind,
+			InvolvedName:       name,
+			InvolvedNamespace:  namespace,
+			FirstTimestamp:     parseKubernetesTime(eData.Get("firstTimestamp").MustString()),
+			LastTimestamp:      parseKubernetesTime(eData.Get("lastTimestamp").MustString()),
+			PodNamespaceLcuuid: k.namespaceToLcuuid[namespace],
+			PodClusterLcuuid:   podClusterLcuuid,
+		}
+		// events.k8s.io 上报的事件没有 first/lastTimestamp，使用 eventTime
+		if kubernetesEvent.LastTimestamp.IsZero() {
+			kubernetesEvent.LastTimestamp = parseKubernetesTime(eData.Get("eventTime").MustString())
+		}
+		if kubernetesEvent.FirstTimestamp.IsZero() {
+			kubernetesEvent.FirstTimestamp = kubernetesEvent.LastTimestamp
+		}
+
+		switch kind {
+		case "Pod":
+			if pod, ok := namespacePodNameToPod[namespace+"/"+name]; ok {
+				kubernetesEvent.PodLcuuid = pod.Lcuuid
+				kubernetesEvent.PodNodeLcuuid = pod.PodNodeLcuuid
+			}
+		case "Node":
+			kubernetesEvent.PodNodeLcuuid = nodeNameToLcuuid[name]
+		}
+		kubernetesEvents = append(kubernetesEvents, kubernetesEvent)
+	}
+	log.Debug("get kubernetes events complete")
+	return kubernetesEvents
+}
+
+func parseKubernetesTime(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		log.Debugf("kubernetes time (%s) parse error: (%s)", value, err.Error())
+		return time.Time{}
+	}
+	return t
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/deepflowio/deepflow/server/controller/statsd"
 
 	"regexp"
+	"strings"
 
 	simplejson "github.com/bitly/go-simplejson"
 	mapset "github.com/deckarep/golang-set"
@@ -58,6 +59,8 @@ type KubernetesGather struct {
 	nodeIPToLcuuid               map[string]string
 	namespaceToLcuuid            map[string]string
 	rsLcuuidToPodGroupLcuuid     map[string]string
+	ownerLcuuidToPodGroupLcuuid  map[string]string
+	ownerKindToPodGroupType      map[string]int
 	serviceLcuuidToIngressLcuuid map[string]string
 	k8sInfo                      map[string][]string
 	nsLabelToGroupLcuuids        map[string]mapset.Set
@@ -135,6 +138,20 @@ func NewKubernetesGather(domain *mysql.Domain, subDomain *mysql.SubDomain, cfg c
 		podNetIPv6CIDRMaxMask = common.K8S_POD_IPV6_NETMASK
 	}
 
+	ownerKindToPodGroupType := map[string]int{}
+	for kind, t := range DEFAULT_OWNER_KIND_TO_POD_GROUP_TYPE {
+		ownerKindToPodGroupType[kind] = t
+	}
+	for kind, t := range configJson.Get("pod_group_owner_kinds").MustMap() {
+		typeName, _ := t.(string)
+		typeID, ok := POD_GROUP_NAME_TO_TYPE[strings.ToLower(typeName)]
+		if !ok {
+			log.Warningf("pod group owner kind (%s) type (%v) not support", kind, t)
+			continue
+		}
+		ownerKindToPodGroupType[kind] = typeID
+	}
+
 	return &KubernetesGather{
 		// TODO: display_name后期需要修改为uuid_generate
 		Name:                  name,
@@ -158,6 +175,8 @@ func NewKubernetesGather(domain *mysql.Domain, subDomain *mysql.SubDomain, cfg c
 		nodeIPToLcuuid:               map[string]string{},
 		namespaceToLcuuid:            map[string]string{},
 		rsLcuuidToPodGroupLcuuid:     map[string]string{},
+		ownerLcuuidToPodGroupLcuuid:  map[string]string{},
+		ownerKindToPodGroupType:      ownerKindToPodGroupType,
 		serviceLcuuidToIngressLcuuid: map[string]string{},
 		k8sInfo:                      map[string][]string{},
 		nsLabelToGroupLcuuids:        map[string]mapset.Set{},
@@ -211,6 +230,7 @@ func (k *KubernetesGather) GetKubernetesGatherData() (model.KubernetesGatherReso
 	k.nodeIPToLcuuid = map[string]string{}
 	k.namespaceToLcuuid = map[string]string{}
 	k.rsLcuuidToPodGroupLcuuid = map[string]string{}
+	k.ownerLcuuidToPodGroupLcuuid = map[string]string{}
 	k.serviceLcuuidToIngressLcuuid = map[string]string{}
 	k.nsLabelToGroupLcuuids = map[string]mapset.Set{}
 	k.pgLcuuidTopodTargetPorts = map[string]map[string]int{}
@@ -294,6 +314,13 @@ func (k *KubernetesGather) GetKubernetesGatherData() (model.KubernetesGatherReso
 	if err != nil {
 		return model.KubernetesGatherResource{}, err
 	}
+	routes, routeRules, routeRuleBackends, err := k.getPodRoutes()
+	if err != nil {
+		return model.KubernetesGatherResource{}, err
+	}
+	ingresses = append(ingresses, routes...)
+	ingressRules = append(ingressRules, routeRules...)
+	ingressRuleBackends = append(ingressRuleBackends, routeRuleBackends...)
 	for index, s := range podServices {
 		if ingressLcuuid, ok := k.serviceLcuuidToIngressLcuuid[s.Lcuuid]; ok {
 			podServices[index].PodIngressLcuuid = ingressLcuuid
@@ -306,6 +333,8 @@ func (k *KubernetesGather) GetKubernetesGatherData() (model.KubernetesGatherReso
 	}
 	podNodes = append(podNodes, abstractNodes...)
 
+	kubernetesEvents := k.getKubernetesEvents(pods, podNodes)
+
 	nodeSubnets, podSubnets, nodeVInterfaces, podVInterfaces, nodeIPs, podIPs, err := k.getVInterfacesAndIPs()
 	if err != nil {
 		return model.KubernetesGatherResource{}, err
@@ -340,6 +369,7 @@ func (k *KubernetesGather) GetKubernetesGatherData() (model.KubernetesGatherReso
 		PodGroups:              podGroups,
 		Pods:                   pods,
 		PrometheusTargets:      prometheusTargets,
+		KubernetesEvents:       kubernetesEvents,
 	}
 
 	k.cloudStatsd.APICost["PrometheusTarget"] = []int{0}
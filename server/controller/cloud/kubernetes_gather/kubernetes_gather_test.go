@@ -123,3 +123,145 @@ func TestKubernetes(t *testing.T) {
 		})
 	})
 }
+
+func TestGetOwnerPodGroups(t *testing.T) {
+	Convey("TestGetOwnerPodGroups", t, func() {
+		k8sConfig := mysql.SubDomain{
+			Name:        "test_k8s",
+			DisplayName: "test_k8s",
+			ClusterID:   "d-01LMvvfQPZ",
+			Config:      fmt.Sprintf(`{"region_uuid": "%s", "vpc_uuid": ""}`, common.DEFAULT_REGION),
+		}
+		k8s := NewKubernetesGather(nil, &k8sConfig, cloudconfig.CloudConfig{}, false)
+
+		jsonData, _ := ioutil.ReadFile("./testfiles/owner-pod-groups.json")
+		var resources map[string][]json.RawMessage
+		json.Unmarshal(jsonData, &resources)
+		for key, items := range resources {
+			for _, item := range items {
+				k8s.k8sInfo[key] = append(k8s.k8sInfo[key], string(item))
+			}
+		}
+		k8s.namespaceToLcuuid["default"] = "ns-default"
+		// Deployment 由 agent 上报, 已生成工作负载
+		k8s.podGroupLcuuids.Add("deployment-1")
+
+		podGroups, err := k8s.getOwnerPodGroups()
+		lcuuidToPodGroup := map[string]model.PodGroup{}
+		for _, podGroup := range podGroups {
+			lcuuidToPodGroup[podGroup.Lcuuid] = podGroup
+		}
+
+		Convey("pods of jobs created by cronjob belong to the cronjob", func() {
+			So(err, ShouldBeNil)
+			cronJob, ok := lcuuidToPodGroup["cronjob-1"]
+			So(ok, ShouldBeTrue)
+			So(cronJob.Name, ShouldEqual, "backup")
+			So(cronJob.Type, ShouldEqual, common.POD_GROUP_CRONJOB)
+			So(cronJob.PodNum, ShouldEqual, 2)
+			So(cronJob.PodNamespaceLcuuid, ShouldEqual, "ns-default")
+			So(cronJob.Label, ShouldEqual, "job-name:backup-28100000")
+			So(k8s.ownerLcuuidToPodGroupLcuuid["job-1"], ShouldEqual, "cronjob-1")
+			_, ok = lcuuidToPodGroup["job-1"]
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("pods of standalone jobs belong to the job", func() {
+			job, ok := lcuuidToPodGroup["job-2"]
+			So(ok, ShouldBeTrue)
+			So(job.Name, ShouldEqual, "migrate")
+			So(job.Type, ShouldEqual, common.POD_GROUP_JOB)
+			So(job.PodNum, ShouldEqual, 1)
+			So(k8s.pgLcuuidTopodTargetPorts["job-2"], ShouldResemble, map[string]int{"metrics": 9090})
+		})
+
+		Convey("replicasets created by crd belong to the crd as deployment", func() {
+			rollout, ok := lcuuidToPodGroup["rollout-1"]
+			So(ok, ShouldBeTrue)
+			So(rollout.Name, ShouldEqual, "web")
+			So(rollout.Type, ShouldEqual, common.POD_GROUP_DEPLOYMENT)
+			So(rollout.PodNum, ShouldEqual, 3)
+			So(rollout.Label, ShouldEqual, "app:web")
+			So(k8s.pgLcuuidTopodTargetPorts["rollout-1"], ShouldResemble, map[string]int{"http": 8080})
+			So(k8s.nsLabelToGroupLcuuids["defaultapp_web"].Contains("rollout-1"), ShouldBeTrue)
+		})
+
+		Convey("reported deployments and unknown namespaces are skipped", func() {
+			So(len(podGroups), ShouldEqual, 3)
+			So(k8s.podGroupLcuuids.Contains("cronjob-1", "job-2", "rollout-1"), ShouldBeTrue)
+			_, ok := lcuuidToPodGroup["job-3"]
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
+
+func TestGetKubernetesEvents(t *testing.T) {
+	Convey("TestGetKubernetesEvents", t, func() {
+		k8sConfig := mysql.SubDomain{
+			Name:        "test_k8s",
+			DisplayName: "test_k8s",
+			ClusterID:   "d-01LMvvfQPZ",
+			Config:      fmt.Sprintf(`{"region_uuid": "%s", "vpc_uuid": ""}`, common.DEFAULT_REGION),
+		}
+		k8s := NewKubernetesGather(nil, &k8sConfig, cloudconfig.CloudConfig{}, false)
+
+		jsonData, _ := ioutil.ReadFile("./testfiles/kubernetes-events.json")
+		var resources map[string][]json.RawMessage
+		json.Unmarshal(jsonData, &resources)
+		for key, items := range resources {
+			for _, item := range items {
+				k8s.k8sInfo[key] = append(k8s.k8sInfo[key], string(item))
+			}
+		}
+		k8s.namespaceToLcuuid["default"] = "ns-default"
+		pods := []model.Pod{{Lcuuid: "pod-1", Name: "web-1", PodNamespaceLcuuid: "ns-default", PodNodeLcuuid: "node-lcuuid-1"}}
+		podNodes := []model.PodNode{{Lcuuid: "node-lcuuid-1", Name: "node-1"}}
+
+		events := k8s.getKubernetesEvents(pods, podNodes)
+		lcuuidToEvent := map[string]model.KubernetesEvent{}
+		for _, event := range events {
+			lcuuidToEvent[event.Lcuuid] = event
+		}
+
+		Convey("events without uid or reason are skipped", func() {
+			So(len(events), ShouldEqual, 3)
+			_, ok := lcuuidToEvent["event-5"]
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("pod events are attached to the pod and its node", func() {
+			event := lcuuidToEvent["event-1"]
+			So(event.Reason, ShouldEqual, "BackOff")
+			So(event.Message, ShouldEqual, "Back-off restarting failed container")
+			So(event.Type, ShouldEqual, "Warning")
+			So(event.Count, ShouldEqual, 3)
+			So(event.InvolvedKind, ShouldEqual, "Pod")
+			So(event.InvolvedName, ShouldEqual, "web-1")
+			So(event.InvolvedNamespace, ShouldEqual, "default")
+			So(event.FirstTimestamp.Unix(), ShouldEqual, 1680336000)
+			So(event.LastTimestamp.Unix(), ShouldEqual, 1680336300)
+			So(event.PodLcuuid, ShouldEqual, "pod-1")
+			So(event.PodNodeLcuuid, ShouldEqual, "node-lcuuid-1")
+			So(event.PodNamespaceLcuuid, ShouldEqual, "ns-default")
+			So(event.PodClusterLcuuid, ShouldNotBeEmpty)
+		})
+
+		Convey("node events use eventTime when first/lastTimestamp are missing", func() {
+			event := lcuuidToEvent["event-2"]
+			So(event.PodNodeLcuuid, ShouldEqual, "node-lcuuid-1")
+			So(event.PodLcuuid, ShouldBeEmpty)
+			So(event.PodNamespaceLcuuid, ShouldBeEmpty)
+			So(event.Count, ShouldEqual, 1)
+			So(event.LastTimestamp.UnixMilli(), ShouldEqual, 1680336600123)
+			So(event.FirstTimestamp.Equal(event.LastTimestamp), ShouldBeTrue)
+		})
+
+		Convey("events of unknown pods keep the involved object only", func() {
+			event := lcuuidToEvent["event-3"]
+			So(event.InvolvedName, ShouldEqual, "web-2")
+			So(event.PodLcuuid, ShouldBeEmpty)
+			So(event.PodNodeLcuuid, ShouldBeEmpty)
+			So(event.PodNamespaceLcuuid, ShouldEqual, "ns-default")
+		})
+	})
+}
@@ -52,6 +52,7 @@ type KubernetesGatherResource struct {
 	PodVInterfaces         []model.VInterface
 	PodIPs                 []model.IP
 	PrometheusTargets      []model.PrometheusTarget
+	KubernetesEvents       []model.KubernetesEvent
 }
 
 type KubernetesGatherBasicInfo struct {
@@ -108,7 +108,8 @@ func (k *KubernetesGather) getPods() (pods []model.Pod, nodes []model.PodNode, e
 			continue
 		}
 		kind := podGroups.GetIndex(0).Get("kind").MustString()
-		if _, ok := podTypesMap[kind]; !ok {
+		_, isOwnerKind := k.ownerKindToPodGroupType[kind]
+		if _, ok := podTypesMap[kind]; !ok && !isOwnerKind {
 			log.Infof("pod group (%s) type (%s) not support", name, kind)
 			continue
 		}
@@ -129,6 +130,8 @@ func (k *KubernetesGather) getPods() (pods []model.Pod, nodes []model.PodNode, e
 		if gLcuuid, ok := k.rsLcuuidToPodGroupLcuuid[ID]; ok {
 			podRSLcuuid = ID
 			podGroupLcuuid = gLcuuid
+		} else if gLcuuid, ok := k.ownerLcuuidToPodGroupLcuuid[ID]; ok {
+			podGroupLcuuid = gLcuuid
 		} else {
 			if !k.podGroupLcuuids.Contains(ID) {
 				log.Debugf("pod (%s) pod group not found", name)
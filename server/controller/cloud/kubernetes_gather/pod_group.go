@@ -199,6 +199,11 @@ func (k *KubernetesGather) getPodGroups() (podGroups []model.PodGroup, err error
 			k.pgLcuuidTopodTargetPorts[uID] = podTargetPorts
 		}
 	}
+	ownerPodGroups, err := k.getOwnerPodGroups()
+	if err != nil {
+		return
+	}
+	podGroups = append(podGroups, ownerPodGroups...)
 	log.Debug("get podgroups complete")
 	return
 }
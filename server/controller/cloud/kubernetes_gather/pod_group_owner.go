@@ -0,0 +1,202 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kubernetes_gather
+
+import (
+	"strings"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+
+	"github.com/bitly/go-simplejson"
+	mapset "github.com/deckarep/golang-set"
+	uuid "github.com/satori/go.uuid"
+)
+
+// 工作负载类型名称, 用于 domain 配置 pod_group_owner_kinds 中指定 CRD 对应的类型
+var POD_GROUP_NAME_TO_TYPE = map[string]int{
+	"deployment":            common.POD_GROUP_DEPLOYMENT,
+	"statefulset":           common.POD_GROUP_STATEFULSET,
+	"replicationcontroller": common.POD_GROUP_RC,
+	"daemonset":             common.POD_GROUP_DAEMON_SET,
+	"replicasetcontroller":  common.POD_GROUP_REPLICASET_CONTROLLER,
+	"job":                   common.POD_GROUP_JOB,
+	"cronjob":               common.POD_GROUP_CRONJOB,
+}
+
+// 不由 agent 上报, 根据 pod 和 replicaset 的 ownerReferences 生成的工作负载
+// 配置 pod_group_owner_kinds 可以覆盖或补充, 例如 {"Rollout": "deployment"}
+var DEFAULT_OWNER_KIND_TO_POD_GROUP_TYPE = map[string]int{
+	"Job":      common.POD_GROUP_JOB,
+	"CronJob":  common.POD_GROUP_CRONJOB,
+	"Rollout":  common.POD_GROUP_DEPLOYMENT,
+	"CloneSet": common.POD_GROUP_DEPLOYMENT,
+}
+
+type ownerPodGroup struct {
+	podGroup    model.PodGroup
+	namespace   string
+	labels      map[string]interface{}
+	targetPorts map[string]int
+}
+
+// getOwnerPodGroups 沿 ownerReferences 生成 Job, CronJob 及配置的 CRD 类型工作负载
+// 由 CronJob 创建的 Job 归属到 CronJob, 由 CRD 创建的 ReplicaSet 在之后归属到 CRD
+func (k *KubernetesGather) getOwnerPodGroups() (podGroups []model.PodGroup, err error) {
+	log.Debug("get owner podgroups starting")
+	// Job uid 到所属 CronJob 的 ownerReference
+	jobUIDToOwner := map[string]*simplejson.Json{}
+	for _, j := range k.k8sInfo["*v1.Job"] {
+		jData, jErr := simplejson.NewJson([]byte(j))
+		if jErr != nil {
+			err = jErr
+			log.Errorf("job initialization simplejson error: (%s)", jErr.Error())
+			return
+		}
+		jUID := jData.GetPath("metadata", "uid").MustString()
+		owner := jData.GetPath("metadata", "ownerReferences").GetIndex(0)
+		if jUID == "" || owner.Get("uid").MustString() == "" {
+			continue
+		}
+		if _, ok := k.ownerKindToPodGroupType[owner.Get("kind").MustString()]; ok {
+			jobUIDToOwner[jUID] = owner
+		}
+	}
+
+	ownerGroups := map[string]*ownerPodGroup{}
+	ownerGroupUIDs := []string{}
+	resources := [2][]string{k.k8sInfo["*v1.ReplicaSet"], k.k8sInfo["*v1.Pod"]}
+	for t, resource := range resources {
+		for _, r := range resource {
+			rData, rErr := simplejson.NewJson([]byte(r))
+			if rErr != nil {
+				err = rErr
+				log.Errorf("owner podgroup initialization simplejson error: (%s)", rErr.Error())
+				return
+			}
+			metaData, ok := rData.CheckGet("metadata")
+			if !ok {
+				continue
+			}
+			namespace := metaData.Get("namespace").MustString()
+			namespaceLcuuid, ok := k.namespaceToLcuuid[namespace]
+			if !ok {
+				continue
+			}
+			owner := metaData.Get("ownerReferences").GetIndex(0)
+			ownerUID := owner.Get("uid").MustString()
+			if ownerUID == "" || k.podGroupLcuuids.Contains(ownerUID) {
+				continue
+			}
+			kind := owner.Get("kind").MustString()
+			if _, ok := k.ownerKindToPodGroupType[kind]; !ok {
+				continue
+			}
+			groupUID := ownerUID
+			if jobOwner, ok := jobUIDToOwner[ownerUID]; ok {
+				owner = jobOwner
+				kind = owner.Get("kind").MustString()
+				groupUID = owner.Get("uid").MustString()
+				k.ownerLcuuidToPodGroupLcuuid[ownerUID] = groupUID
+			}
+
+			labels := metaData.Get("labels").MustMap()
+			targetPorts := map[string]int{}
+			var containers *simplejson.Json
+			if t == 0 {
+				labels = rData.GetPath("spec", "template", "metadata", "labels").MustMap()
+				containers = rData.GetPath("spec", "template", "spec", "containers")
+			} else {
+				containers = rData.GetPath("spec", "containers")
+			}
+			for i := range containers.MustArray() {
+				cPorts := containers.GetIndex(i).Get("ports")
+				for j := range cPorts.MustArray() {
+					cPort := cPorts.GetIndex(j)
+					cPortName, err := cPort.Get("name").String()
+					if err != nil {
+						continue
+					}
+					targetPorts[cPortName] = cPort.Get("containerPort").MustInt()
+				}
+			}
+
+			group, ok := ownerGroups[groupUID]
+			if !ok {
+				name := owner.Get("name").MustString()
+				group = &ownerPodGroup{
+					podGroup: model.PodGroup{
+						Lcuuid:             groupUID,
+						Name:               name,
+						Type:               k.ownerKindToPodGroupType[kind],
+						PodNamespaceLcuuid: namespaceLcuuid,
+						AZLcuuid:           k.azLcuuid,
+						RegionLcuuid:       k.RegionUuid,
+						PodClusterLcuuid:   common.GetUUID(k.UuidGenerate, uuid.Nil),
+					},
+					namespace:   namespace,
+					labels:      map[string]interface{}{},
+					targetPorts: map[string]int{},
+				}
+				label := strings.ToLower(kind) + ":" + namespace + ":" + name
+				k.addNSLabelGroupLcuuid(namespace+label, groupUID)
+				ownerGroups[groupUID] = group
+				ownerGroupUIDs = append(ownerGroupUIDs, groupUID)
+			}
+			if t == 0 {
+				group.podGroup.PodNum += rData.GetPath("spec", "replicas").MustInt()
+			} else {
+				group.podGroup.PodNum += 1
+			}
+			for key, v := range labels {
+				group.labels[key] = v
+			}
+			for key, v := range targetPorts {
+				group.targetPorts[key] = v
+			}
+		}
+	}
+
+	for _, uID := range ownerGroupUIDs {
+		group := ownerGroups[uID]
+		for key, v := range group.labels {
+			value, ok := v.(string)
+			if !ok {
+				continue
+			}
+			k.addNSLabelGroupLcuuid(group.namespace+key+"_"+value, uID)
+		}
+		labelSlice := cloudcommon.StringInterfaceMapKVs(group.labels, ":", 0)
+		group.podGroup.Label = strings.Join(labelSlice, ", ")
+		podGroups = append(podGroups, group.podGroup)
+		k.podGroupLcuuids.Add(uID)
+		k.pgLcuuidTopodTargetPorts[uID] = group.targetPorts
+	}
+	log.Debug("get owner podgroups complete")
+	return
+}
+
+func (k *KubernetesGather) addNSLabelGroupLcuuid(nsLabel, uID string) {
+	if groupLcuuids, ok := k.nsLabelToGroupLcuuids[nsLabel]; ok {
+		groupLcuuids.Add(uID)
+		return
+	}
+	groupLcuuids := mapset.NewSet()
+	groupLcuuids.Add(uID)
+	k.nsLabelToGroupLcuuids[nsLabel] = groupLcuuids
+}
@@ -64,9 +64,15 @@ func (k *KubernetesGather) getPodIngresses() (ingresses []model.PodIngress, ingr
 			log.Infof("ingress (%s) namespace not found", name)
 			continue
 		}
+		// openshift route is also reported as ingress by agent
+		ingressType := common.POD_INGRESS_TYPE_INGRESS
+		if _, ok := iData.Get("spec").CheckGet("to"); ok {
+			ingressType = common.POD_INGRESS_TYPE_ROUTE
+		}
 		ingress := model.PodIngress{
 			Lcuuid:             uID,
 			Name:               name,
+			Type:               ingressType,
 			PodNamespaceLcuuid: namespaceLcuuid,
 			AZLcuuid:           k.azLcuuid,
 			RegionLcuuid:       k.RegionUuid,
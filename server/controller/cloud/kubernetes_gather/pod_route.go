@@ -0,0 +1,287 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kubernetes_gather
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+
+	"github.com/bitly/go-simplejson"
+	uuid "github.com/satori/go.uuid"
+)
+
+// 路由后端, 对应 ingress 中 path 到 service 端口的一条转发
+type routeBackend struct {
+	path      string
+	namespace string
+	service   string
+	port      int
+	weight    int
+}
+
+// getPodRoutes 将 Gateway API HTTPRoute/GRPCRoute 和 Istio VirtualService 转换为 ingress
+// 每个 hostname 对应一条 ingress rule, 每个 path match 与 backendRef 的组合对应一个 rule backend
+func (k *KubernetesGather) getPodRoutes() (ingresses []model.PodIngress, ingressRules []model.PodIngressRule, ingressRuleBackends []model.PodIngressRuleBackend, err error) {
+	log.Debug("get routes starting")
+	routeInfos := []struct {
+		key         string
+		ingressType int
+	}{
+		{"*v1beta1.HTTPRoute", common.POD_INGRESS_TYPE_HTTPROUTE},
+		// agent 按集群提供的版本上报 v1 或 v1alpha2 的 GRPCRoute, 两者结构相同
+		{"*v1.GRPCRoute", common.POD_INGRESS_TYPE_GRPCROUTE},
+		{"*v1alpha2.GRPCRoute", common.POD_INGRESS_TYPE_GRPCROUTE},
+		{"*v1beta1.VirtualService", common.POD_INGRESS_TYPE_VIRTUALSERVICE},
+	}
+	for _, routeInfo := range routeInfos {
+		for _, r := range k.k8sInfo[routeInfo.key] {
+			rData, rErr := simplejson.NewJson([]byte(r))
+			if rErr != nil {
+				err = rErr
+				log.Errorf("route initialization simplejson error: (%s)", rErr.Error())
+				return
+			}
+			metaData, ok := rData.CheckGet("metadata")
+			if !ok {
+				log.Info("route metadata not found")
+				continue
+			}
+			uID := metaData.Get("uid").MustString()
+			if uID == "" {
+				log.Info("route uid not found")
+				continue
+			}
+			name := metaData.Get("name").MustString()
+			if name == "" {
+				log.Infof("route (%s) name not found", uID)
+				continue
+			}
+			namespace := metaData.Get("namespace").MustString()
+			namespaceLcuuid, ok := k.namespaceToLcuuid[namespace]
+			if !ok {
+				log.Infof("route (%s) namespace not found", name)
+				continue
+			}
+			ingresses = append(ingresses, model.PodIngress{
+				Lcuuid:             uID,
+				Name:               name,
+				Type:               routeInfo.ingressType,
+				PodNamespaceLcuuid: namespaceLcuuid,
+				AZLcuuid:           k.azLcuuid,
+				RegionLcuuid:       k.RegionUuid,
+				PodClusterLcuuid:   common.GetUUID(k.UuidGenerate, uuid.Nil),
+			})
+
+			spec := rData.Get("spec")
+			var hosts []string
+			protocolToBackends := map[string][]routeBackend{}
+			switch routeInfo.ingressType {
+			case common.POD_INGRESS_TYPE_HTTPROUTE:
+				hosts = spec.Get("hostnames").MustStringArray()
+				protocolToBackends["HTTP"] = getGatewayRouteBackends(spec.Get("rules"), namespace, false)
+			case common.POD_INGRESS_TYPE_GRPCROUTE:
+				hosts = spec.Get("hostnames").MustStringArray()
+				protocolToBackends["GRPC"] = getGatewayRouteBackends(spec.Get("rules"), namespace, true)
+			case common.POD_INGRESS_TYPE_VIRTUALSERVICE:
+				hosts = spec.Get("hosts").MustStringArray()
+				for _, protocol := range []string{"HTTP", "TLS", "TCP"} {
+					protocolToBackends[protocol] = getVirtualServiceBackends(spec.Get(strings.ToLower(protocol)), namespace)
+				}
+			}
+			if len(hosts) == 0 {
+				hosts = []string{""}
+			}
+
+			for _, protocol := range []string{"HTTP", "GRPC", "TLS", "TCP"} {
+				backends := protocolToBackends[protocol]
+				if len(backends) == 0 {
+					continue
+				}
+				for index, host := range hosts {
+					ruleLcuuid := common.GetUUID(uID+protocol+host+"_"+strconv.Itoa(index), uuid.Nil)
+					ingressRules = append(ingressRules, model.PodIngressRule{
+						Lcuuid:           ruleLcuuid,
+						Host:             host,
+						Protocol:         protocol,
+						PodIngressLcuuid: uID,
+					})
+					backendLcuuids := map[string]bool{}
+					for _, backend := range backends {
+						serviceLcuuid, port, ok := k.getRouteBackendService(uID, backend)
+						if !ok {
+							continue
+						}
+						// 不同 namespace 的同名 service 可能指向同一 path, 相同的后端只保留第一个
+						key := backend.namespace + "/" + backend.service + ":" + strconv.Itoa(port) + backend.path
+						backendLcuuid := common.GetUUID(ruleLcuuid+key, uuid.Nil)
+						if backendLcuuids[backendLcuuid] {
+							log.Debugf("route (%s) backend (%s) is duplicated", uID, key)
+							continue
+						}
+						backendLcuuids[backendLcuuid] = true
+						ingressRuleBackends = append(ingressRuleBackends, model.PodIngressRuleBackend{
+							Lcuuid:               backendLcuuid,
+							Path:                 backend.path,
+							Port:                 port,
+							Weight:               backend.weight,
+							PodServiceLcuuid:     serviceLcuuid,
+							PodIngressRuleLcuuid: ruleLcuuid,
+							PodIngressLcuuid:     uID,
+						})
+					}
+				}
+			}
+		}
+	}
+	log.Debug("get routes complete")
+	return
+}
+
+// getRouteBackendService 查找后端对应的 service, 未指定端口时使用 service 的端口
+func (k *KubernetesGather) getRouteBackendService(uID string, backend routeBackend) (string, int, bool) {
+	service, ok := k.nsServiceNameToService[backend.namespace+backend.service]
+	if !ok || len(service) == 0 {
+		log.Infof("route backend service (%s) not found", backend.service)
+		return "", 0, false
+	}
+	// 同名service只会有一个, 排序仅为保证结果稳定
+	serviceLcuuids := make([]string, 0, len(service))
+	for key := range service {
+		serviceLcuuids = append(serviceLcuuids, key)
+	}
+	sort.Strings(serviceLcuuids)
+	serviceLcuuid := serviceLcuuids[0]
+	ports := service[serviceLcuuid]
+	if ingressLcuuid, ok := k.serviceLcuuidToIngressLcuuid[serviceLcuuid]; ok && ingressLcuuid != uID {
+		log.Infof("ingress (%s) is already associated with the service (%s), and route (%s) cannot be associated", ingressLcuuid, serviceLcuuid, uID)
+	} else {
+		k.serviceLcuuidToIngressLcuuid[serviceLcuuid] = uID
+	}
+	// 未指定端口时使用service的最小端口
+	port := backend.port
+	if port == 0 {
+		for _, p := range ports {
+			if port == 0 || p < port {
+				port = p
+			}
+		}
+	}
+	if port == 0 {
+		log.Infof("route (%s) backend service (%s) no port", uID, backend.service)
+		return "", 0, false
+	}
+	return serviceLcuuid, port, true
+}
+
+// getGatewayRouteBackends 解析 HTTPRoute/GRPCRoute 的 rules, GRPCRoute 以 /service/method 作为 path
+func getGatewayRouteBackends(rules *simplejson.Json, namespace string, isGRPC bool) (backends []routeBackend) {
+	for i := range rules.MustArray() {
+		rule := rules.GetIndex(i)
+		paths := []string{}
+		matches := rule.Get("matches")
+		for j := range matches.MustArray() {
+			match := matches.GetIndex(j)
+			var path string
+			if isGRPC {
+				if service := match.GetPath("method", "service").MustString(); service != "" {
+					path = "/" + service + "/" + match.GetPath("method", "method").MustString()
+				}
+			} else {
+				path = match.GetPath("path", "value").MustString()
+			}
+			paths = append(paths, path)
+		}
+		if len(paths) == 0 {
+			paths = []string{""}
+		}
+		backendRefs := rule.Get("backendRefs")
+		for j := range backendRefs.MustArray() {
+			backendRef := backendRefs.GetIndex(j)
+			if kind := backendRef.Get("kind").MustString(); kind != "" && kind != "Service" {
+				continue
+			}
+			backendNamespace := backendRef.Get("namespace").MustString()
+			if backendNamespace == "" {
+				backendNamespace = namespace
+			}
+			for _, path := range paths {
+				backends = append(backends, routeBackend{
+					path:      path,
+					namespace: backendNamespace,
+					service:   backendRef.Get("name").MustString(),
+					port:      backendRef.Get("port").MustInt(),
+					weight:    backendRef.Get("weight").MustInt(1),
+				})
+			}
+		}
+	}
+	return
+}
+
+// getVirtualServiceBackends 解析 VirtualService 的 http/tls/tcp 路由, destination.host 可以是 service 短名称或 FQDN
+func getVirtualServiceBackends(routes *simplejson.Json, namespace string) (backends []routeBackend) {
+	for i := range routes.MustArray() {
+		route := routes.GetIndex(i)
+		paths := []string{}
+		matches := route.Get("match")
+		for j := range matches.MustArray() {
+			uri := matches.GetIndex(j).Get("uri")
+			path := uri.Get("prefix").MustString()
+			if path == "" {
+				path = uri.Get("exact").MustString()
+			}
+			if path == "" {
+				path = uri.Get("regex").MustString()
+			}
+			paths = append(paths, path)
+		}
+		if len(paths) == 0 {
+			paths = []string{""}
+		}
+		destinations := route.Get("route")
+		for j := range destinations.MustArray() {
+			destination := destinations.GetIndex(j)
+			host := destination.GetPath("destination", "host").MustString()
+			if host == "" {
+				continue
+			}
+			hostSlice := strings.Split(host, ".")
+			service, serviceNamespace := hostSlice[0], namespace
+			if len(hostSlice) > 1 {
+				serviceNamespace = hostSlice[1]
+			}
+			weight := destination.Get("weight").MustInt()
+			if weight == 0 && len(destinations.MustArray()) == 1 {
+				weight = 100
+			}
+			for _, path := range paths {
+				backends = append(backends, routeBackend{
+					path:      path,
+					namespace: serviceNamespace,
+					service:   service,
+					port:      destination.GetPath("destination", "port", "number").MustInt(),
+					weight:    weight,
+				})
+			}
+		}
+	}
+	return
+}
@@ -409,6 +409,7 @@ type PodGroupPort struct {
 type PodIngress struct {
 	Lcuuid             string `json:"lcuuid" binding:"required"`
 	Name               string `json:"name" binding:"required"`
+	Type               int    `json:"type"`
 	PodNamespaceLcuuid string `json:"pod_namespace_lcuuid" binding:"required"`
 	PodClusterLcuuid   string `json:"pod_cluster_lcuuid" binding:"required"`
 	AZLcuuid           string `json:"az_lcuuid" binding:"required"`
@@ -429,6 +430,7 @@ type PodIngressRuleBackend struct {
 	Lcuuid               string `json:"lcuuid" binding:"required"`
 	Path                 string `json:"path"`
 	Port                 int    `json:"port" binding:"required"`
+	Weight               int    `json:"weight"`
 	PodServiceLcuuid     string `json:"pod_service_lcuuid" binding:"required"`
 	PodIngressRuleLcuuid string `json:"pod_ingress_rule_lcuuid" binding:"required"`
 	PodIngressLcuuid     string `json:"pod_ingress_lcuuid" binding:"required"`
@@ -492,6 +494,24 @@ type PrometheusTarget struct {
 	SubDomainLcuuid string `json:"sub_domain_lcuuid"`
 }
 
+// KubernetesEvent 不落库，仅用于生成事件
+type KubernetesEvent struct {
+	Lcuuid             string    `json:"lcuuid" binding:"required"`
+	Reason             string    `json:"reason" binding:"required"`
+	Message            string    `json:"message"`
+	Type               string    `json:"type"`
+	Count              int       `json:"count"`
+	InvolvedKind       string    `json:"involved_kind" binding:"required"`
+	InvolvedName       string    `json:"involved_name" binding:"required"`
+	InvolvedNamespace  string    `json:"involved_namespace"`
+	FirstTimestamp     time.Time `json:"first_timestamp"`
+	LastTimestamp      time.Time `json:"last_timestamp"`
+	PodLcuuid          string    `json:"pod_lcuuid"`
+	PodNodeLcuuid      string    `json:"pod_node_lcuuid"`
+	PodNamespaceLcuuid string    `json:"pod_namespace_lcuuid"`
+	PodClusterLcuuid   string    `json:"pod_cluster_lcuuid"`
+}
+
 type SubDomainResource struct {
 	Verified               bool `json:"verified"`
 	ErrorState             int
@@ -515,6 +535,7 @@ type SubDomainResource struct {
 	Pods                   []Pod
 	Processes              []Process
 	PrometheusTargets      []PrometheusTarget
+	KubernetesEvents       []KubernetesEvent
 }
 
 type Resource struct {
@@ -565,6 +586,7 @@ type Resource struct {
 	PodIngressRuleBackends []PodIngressRuleBackend
 	Processes              []Process
 	PrometheusTargets      []PrometheusTarget
+	KubernetesEvents       []KubernetesEvent
 	SubDomainResources     map[string]SubDomainResource
 }
 
@@ -115,6 +115,7 @@ func (c *Cloud) getSubDomainData(cResource model.Resource) map[string]model.SubD
 			VInterfaces:            vinterfaces,
 			IPs:                    ips,
 			PrometheusTargets:      prometheusTargets,
+			KubernetesEvents:       kubernetesGatherResource.KubernetesEvents,
 		}
 		subDomainResources[lcuuid] = subDomainResource
 	}
@@ -286,6 +287,7 @@ func (c *Cloud) getSubDomainIngresses(
 		retPodIngresses = append(retPodIngresses, model.PodIngress{
 			Lcuuid:             podIngress.Lcuuid,
 			Name:               podIngress.Name,
+			Type:               podIngress.Type,
 			PodNamespaceLcuuid: podIngress.PodNamespaceLcuuid,
 			PodClusterLcuuid:   podIngress.PodClusterLcuuid,
 			AZLcuuid:           azLcuuid,
@@ -308,6 +310,7 @@ func (c *Cloud) getSubDomainIngresses(
 			Lcuuid:               podIngressRuleBackend.Lcuuid,
 			Path:                 podIngressRuleBackend.Path,
 			Port:                 podIngressRuleBackend.Port,
+			Weight:               podIngressRuleBackend.Weight,
 			PodServiceLcuuid:     podIngressRuleBackend.PodServiceLcuuid,
 			PodIngressLcuuid:     podIngressRuleBackend.PodIngressLcuuid,
 			PodIngressRuleLcuuid: podIngressRuleBackend.PodIngressRuleLcuuid,
@@ -493,6 +493,16 @@ const (
 	POD_GROUP_RC                    = 3
 	POD_GROUP_DAEMON_SET            = 4
 	POD_GROUP_REPLICASET_CONTROLLER = 5
+	POD_GROUP_JOB                   = 6
+	POD_GROUP_CRONJOB               = 7
+)
+
+const (
+	POD_INGRESS_TYPE_INGRESS        = 1
+	POD_INGRESS_TYPE_ROUTE          = 2
+	POD_INGRESS_TYPE_HTTPROUTE      = 3
+	POD_INGRESS_TYPE_GRPCROUTE      = 4
+	POD_INGRESS_TYPE_VIRTUALSERVICE = 5
 )
 
 const (
@@ -599,7 +609,10 @@ const (
 )
 
 const (
-	PROCESS_INSTANCE_TYPE = 120 // used in process event
+	PROCESS_INSTANCE_TYPE       = 120 // used in process event
+	POD_INGRESS_INSTANCE_TYPE   = 103 // used in pod ingress event
+	POD_CLUSTER_INSTANCE_TYPE   = 104 // used in change event
+	POD_NAMESPACE_INSTANCE_TYPE = 105 // used in change event
 )
 
 // plugin
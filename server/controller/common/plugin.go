@@ -0,0 +1,49 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	PLUGIN_VERSION_SEPARATOR = "@"
+	PLUGIN_VERSION_DEFAULT   = 0 // 未指定版本，使用插件的默认版本
+)
+
+// ParsePluginName 解析采集器组配置中wasm-plugins的插件名称，格式为name或name@version
+func ParsePluginName(s string) (name string, version int, err error) {
+	index := strings.LastIndex(s, PLUGIN_VERSION_SEPARATOR)
+	if index < 0 {
+		return s, PLUGIN_VERSION_DEFAULT, nil
+	}
+	name = s[:index]
+	version, err = strconv.Atoi(s[index+1:])
+	if err != nil || version <= 0 || name == "" {
+		return "", 0, fmt.Errorf("invalid plugin name(%s), expected name or name@version", s)
+	}
+	return name, version, nil
+}
+
+func FormatPluginName(name string, version int) string {
+	if version == PLUGIN_VERSION_DEFAULT {
+		return name
+	}
+	return fmt.Sprintf("%s%s%d", name, PLUGIN_VERSION_SEPARATOR, version)
+}
@@ -58,6 +58,11 @@ type DFWebService struct {
 	Timeout int    `default:"30" yaml:"timeout"`
 }
 
+type ChangeEvent struct {
+	AuthTokens []string `yaml:"auth-tokens"`
+	MaxTargets int      `default:"100" yaml:"max-targets"`
+}
+
 type ControllerConfig struct {
 	LogFile                        string `default:"/var/log/controller.log" yaml:"log-file"`
 	LogLevel                       string `default:"info" yaml:"log-level"`
@@ -75,6 +80,7 @@ type ControllerConfig struct {
 	PodClusterInternalIPToIngester int    `default:"0" yaml:"pod-cluster-internal-ip-to-ingester"`
 
 	DFWebService DFWebService `yaml:"df-web-service"`
+	ChangeEvent  ChangeEvent  `yaml:"change-event"`
 
 	MySqlCfg      mysql.MySqlConfig           `yaml:"mysql"`
 	RedisCfg      redis.RedisConfig           `yaml:"redis"`
@@ -164,6 +164,10 @@ func Start(ctx context.Context, configPath, serverLogFile string, shared *server
 	router.SetInitStageForHealthChecker("TagRecorder init")
 	tr := tagrecorder.NewTagRecorder(*cfg, ctx)
 	go checkAndStartAllRegionMasterFunctions(tr)
+	// recorder在各controller中运行，均需订阅资源变更以增量刷新ch表
+	if cfg.TagRecorderCfg.EventDrivenRefreshEnabled {
+		tagrecorder.NewResourceChangeSubscriber(tr, ctx).Start()
+	}
 
 	router.SetInitStageForHealthChecker("Master function init")
 	controllerCheck := monitor.NewControllerCheck(cfg, ctx)
@@ -175,7 +179,7 @@ func Start(ctx context.Context, configPath, serverLogFile string, shared *server
 	router.DebugRouter(r, m, g)
 	router.ControllerRouter(r, controllerCheck, cfg)
 	router.AnalyzerRouter(r, analyzerCheck, cfg)
-	router.VtapRouter(r)
+	router.VtapRouter(r, cfg)
 	router.VtapGroupRouter(r, cfg)
 	router.DataSourceRouter(r, cfg)
 	router.VTapGroupConfigRouter(r)
@@ -185,6 +189,8 @@ func Start(ctx context.Context, configPath, serverLogFile string, shared *server
 	registerResourceRouters(r, cfg)
 	router.VtapRepoRouter(r)
 	router.PluginRouter(r)
+	router.ChangeEventRouter(r, cfg, shared.ResourceEventQueue)
+	router.TagRecorderRouter(r)
 
 	grpcStart(ctx, cfg)
 
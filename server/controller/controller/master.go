@@ -90,7 +90,7 @@ func checkAndStartMasterFunctions(
 	}
 
 	vtapCheck := vtap.NewVTapCheck(cfg.MonitorCfg, ctx)
-	vtapRebalanceCheck := vtap.NewRebalanceCheck(cfg.MonitorCfg, ctx)
+	vtapRebalanceCheck := vtap.NewRebalanceCheck(cfg.MonitorCfg, cfg.ClickHouseCfg, ctx)
 	vtapLicenseAllocation := license.NewVTapLicenseAllocation(cfg.MonitorCfg, ctx)
 	resourceCleaner := recorder.NewResourceCleaner(&cfg.ManagerCfg.TaskCfg.RecorderCfg, ctx)
 	domainChecker := resoureservice.NewDomainCheck(ctx)
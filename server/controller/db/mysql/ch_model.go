@@ -16,6 +16,8 @@
 
 package mysql
 
+import "time"
+
 type ChRegion struct {
 	ID     int    `gorm:"primaryKey;column:id;type:int;not null" json:"ID"`
 	Name   string `gorm:"column:name;type:varchar(64);default:null" json:"NAME"`
@@ -47,6 +49,19 @@ type ChDevice struct {
 	UID        string `gorm:"column:uid;type:char(64);default:null" json:"UID"`
 }
 
+// ChDeviceHistory 记录 ch_device 名称的有效时间区间，valid_to 为 2106-01-01 00:00:00 表示当前仍有效
+type ChDeviceHistory struct {
+	DeviceType int       `gorm:"primaryKey;column:devicetype;type:int;not null" json:"DEVICETYPE"`
+	DeviceID   int       `gorm:"primaryKey;column:deviceid;type:int;not null" json:"DEVICEID"`
+	Name       string    `gorm:"column:name;type:varchar(256);default:null" json:"NAME"`
+	ValidFrom  time.Time `gorm:"primaryKey;column:valid_from;type:datetime;not null" json:"VALID_FROM"`
+	ValidTo    time.Time `gorm:"column:valid_to;type:datetime;not null" json:"VALID_TO"`
+}
+
+func (ChDeviceHistory) TableName() string {
+	return "ch_device_history"
+}
+
 type ChVTapPort struct {
 	VTapID     int    `gorm:"primaryKey;column:vtap_id;type:int;not null" json:"VTAP_ID"`
 	TapPort    int64  `gorm:"primaryKey;column:tap_port;type:bigint;not null" json:"TAP_PORT"`
@@ -222,9 +237,10 @@ type ChPodCluster struct {
 }
 
 type ChPodGroup struct {
-	ID     int    `gorm:"primaryKey;column:id;type:int;not null" json:"ID"`
-	Name   string `gorm:"column:name;type:varchar(256);not null" json:"NAME"`
-	IconID int    `gorm:"column:icon_id;type:int;default:null" json:"ICON_ID"`
+	ID           int    `gorm:"primaryKey;column:id;type:int;not null" json:"ID"`
+	Name         string `gorm:"column:name;type:varchar(256);not null" json:"NAME"`
+	PodGroupType int    `gorm:"column:pod_group_type;type:int;default:null" json:"POD_GROUP_TYPE"`
+	IconID       int    `gorm:"column:icon_id;type:int;default:null" json:"ICON_ID"`
 }
 
 type ChPodNamespace struct {
@@ -269,8 +285,9 @@ func (ChLBListener) TableName() string {
 }
 
 type ChPodIngress struct {
-	ID   int    `gorm:"primaryKey;column:id;type:int;not null" json:"ID"`
-	Name string `gorm:"column:name;type:varchar(256);not null" json:"NAME"`
+	ID             int    `gorm:"primaryKey;column:id;type:int;not null" json:"ID"`
+	Name           string `gorm:"column:name;type:varchar(256);not null" json:"NAME"`
+	PodIngressType int    `gorm:"column:pod_ingress_type;type:int;default:null" json:"POD_INGRESS_TYPE"`
 }
 
 type ChPodK8sLabel struct {
@@ -288,6 +305,17 @@ type ChPodK8sLabels struct {
 	PodNsID int    `gorm:"column:pod_ns_id;type:int;not null" json:"POD_NS_ID"`
 }
 
+type ChPodK8sLabelsHistory struct {
+	ID        int       `gorm:"primaryKey;column:id;type:int;not null" json:"ID"`
+	Labels    string    `gorm:"column:labels;type:text;default:null" json:"LABELS"`
+	ValidFrom time.Time `gorm:"primaryKey;column:valid_from;type:datetime;not null" json:"VALID_FROM"`
+	ValidTo   time.Time `gorm:"column:valid_to;type:datetime;not null" json:"VALID_TO"`
+}
+
+func (ChPodK8sLabelsHistory) TableName() string {
+	return "ch_pod_k8s_labels_history"
+}
+
 type ChPodServiceK8sLabel struct {
 	ID      int    `gorm:"primaryKey;column:id;type:int;not null" json:"ID"`
 	Key     string `gorm:"primaryKey;column:key;type:varchar(256);default:null" json:"KEY"`
@@ -0,0 +1,136 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	sqlite3 "github.com/mattn/go-sqlite3"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	. "github.com/deepflowio/deepflow/server/controller/db/mysql/config"
+)
+
+const (
+	SQLITE_DRIVER_NAME = "sqlite3_deepflow"
+	SQLITE_MEMORY_PATH = ":memory:"
+)
+
+func init() {
+	// 注册init.sql、issu及代码中用到的MySQL函数，SQLite没有对应的内置函数
+	sql.Register(SQLITE_DRIVER_NAME, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if err := conn.RegisterFunc("uuid", uuid.NewString, false); err != nil {
+				return err
+			}
+			if err := conn.RegisterFunc("concat", sqliteConcat, true); err != nil {
+				return err
+			}
+			return conn.RegisterFunc("now", func() string {
+				return time.Now().Format("2006-01-02 15:04:05")
+			}, false)
+		},
+	})
+}
+
+// MySQL的concat任一参数为NULL时结果为NULL，此处忽略NULL参数
+func sqliteConcat(args ...interface{}) string {
+	var b strings.Builder
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case nil:
+		case []byte:
+			b.Write(v)
+		default:
+			b.WriteString(fmt.Sprint(v))
+		}
+	}
+	return b.String()
+}
+
+func GetSQLiteDSN(cfg MySqlConfig) string {
+	busyTimeout := cfg.TimeOut * 1000
+	if cfg.SQLitePath == SQLITE_MEMORY_PATH {
+		return fmt.Sprintf("file::memory:?cache=shared&_busy_timeout=%d", busyTimeout)
+	}
+	// WAL模式下读写互不阻塞，写事务在开始时即获取写锁，避免并发事务升级写锁时直接返回busy
+	return fmt.Sprintf("file:%s?_busy_timeout=%d&_journal_mode=WAL&_txlock=immediate", cfg.SQLitePath, busyTimeout)
+}
+
+func GetSQLiteGormDB(cfg MySqlConfig) *gorm.DB {
+	if cfg.SQLitePath != SQLITE_MEMORY_PATH {
+		if err := os.MkdirAll(filepath.Dir(cfg.SQLitePath), 0755); err != nil {
+			log.Errorf("create sqlite directory failed with error: %v", err)
+			return nil
+		}
+	}
+	Db, err := gorm.Open(&sqlite.Dialector{DriverName: SQLITE_DRIVER_NAME, DSN: GetSQLiteDSN(cfg)}, getGormConfig())
+	if err != nil {
+		log.Errorf("SQLite Connection failed with error: %v", err.Error())
+		return nil
+	}
+
+	sqlDB, _ := Db.DB()
+	if cfg.SQLitePath == SQLITE_MEMORY_PATH {
+		// 共享缓存的内存数据库在连接全部关闭后被释放，且多连接并发写入时会返回locked
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+	} else {
+		sqlDB.SetMaxIdleConns(10)
+		sqlDB.SetMaxOpenConns(20)
+		sqlDB.SetConnMaxLifetime(time.Hour)
+	}
+	return Db
+}
+
+func IsSQLite(db *gorm.DB) bool {
+	return db.Dialector.Name() == "sqlite"
+}
+
+// ExecSQLiteScript 将MySQL语法的init.sql或issu转换为SQLite语句，在一个事务中逐条执行
+func ExecSQLiteScript(db *gorm.DB, script string) error {
+	t := newSQLiteTranslator()
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range SplitSQLStatements(script) {
+			translated, variable, err := t.translate(statement)
+			if err != nil {
+				return err
+			}
+			if variable != "" {
+				var value string
+				if err := tx.Raw(translated[0]).Row().Scan(&value); err != nil {
+					return fmt.Errorf("evaluate variable @%s failed: %v", variable, err)
+				}
+				t.variables[variable] = value
+				continue
+			}
+			for _, s := range translated {
+				if err := tx.Exec(s).Error; err != nil {
+					return fmt.Errorf("exec %q failed: %v", s, err)
+				}
+			}
+		}
+		return nil
+	})
+}
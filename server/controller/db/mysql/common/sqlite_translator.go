@@ -0,0 +1,422 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// init.sql和issu按MySQL语法编写，在SQLite上执行前逐条转换:
+//   - 建表时去掉ENGINE、CHARSET、COMMENT、UNSIGNED、ON UPDATE等SQLite不支持的属性，
+//     AUTO_INCREMENT列转换为INTEGER PRIMARY KEY AUTOINCREMENT，表内的INDEX转换为CREATE INDEX
+//   - 索引名在SQLite中全库唯一，统一加上表名前缀
+//   - SET @var语句在SQLite中求值后，以字面量替换后续语句中的@var
+//   - SQLite不强制列类型且不支持修改列定义，MODIFY/ALTER COLUMN直接跳过，CHANGE COLUMN仅处理重命名
+//   - 存储过程等无法转换的语句返回错误，此时需要在issu/sqlite目录下提供对应版本的SQLite语句
+//
+// ON UPDATE CURRENT_TIMESTAMP在SQLite中没有对应的列属性，updated_at由gorm在更新时赋值
+const SQLITE_LITERAL_MARK = "\x00"
+
+var (
+	sqliteLiteralRegexp     = regexp.MustCompile("\x00([0-9]+)\x00")
+	sqliteVariableRegexp    = regexp.MustCompile(`@([A-Za-z_][A-Za-z0-9_]*)`)
+	sqliteSetVariableRegexp = regexp.MustCompile(`(?is)^SET\s+@([A-Za-z_][A-Za-z0-9_]*)\s*:?=\s*(.+)$`)
+	sqliteSetRegexp         = regexp.MustCompile(`(?i)^SET\s`)
+	sqliteTransactionRegexp = regexp.MustCompile(`(?i)^(START\s+TRANSACTION|BEGIN|COMMIT|ROLLBACK)$`)
+	sqliteUnsupportedRegexp = regexp.MustCompile(`(?i)^(DELIMITER|CREATE\s+(DEFINER\s*=\s*\S+\s+)?(PROCEDURE|FUNCTION|TRIGGER)|DROP\s+(PROCEDURE|FUNCTION|TRIGGER)|CALL|DECLARE)\b`)
+	sqliteTruncateRegexp    = regexp.MustCompile(`(?i)^TRUNCATE\s+(TABLE\s+)?(\S+)$`)
+	sqliteCreateTableRegexp = regexp.MustCompile(`(?is)^CREATE\s+TABLE\s+(IF\s+NOT\s+EXISTS\s+)?(\S+?)\s*\((.*)\)(.*)$`)
+	sqliteCreateIndexRegexp = regexp.MustCompile(`(?is)^CREATE\s+(UNIQUE\s+)?INDEX\s+(\S+)\s+ON\s+(\S+?)\s*(\(.*\))$`)
+	sqliteDropIndexRegexp   = regexp.MustCompile(`(?i)^DROP\s+INDEX\s+(\S+)\s+ON\s+(\S+)$`)
+	sqliteAlterTableRegexp  = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+(\S+)\s+(.+)$`)
+	sqliteInsertIgnore      = regexp.MustCompile(`(?i)^INSERT\s+IGNORE\b`)
+	sqliteInsertValue       = regexp.MustCompile(`(?i)^(INSERT\s+(OR\s+IGNORE\s+)?INTO\s+\S+)\s+VALUE\s*\(`)
+	sqliteColumnsValue      = regexp.MustCompile(`(?i)\)\s*VALUE\s*\(`)
+
+	sqliteTableIndexRegexp   = regexp.MustCompile(`(?is)^(UNIQUE\s+)?(INDEX|KEY)\s+(\S+?)\s*(\(.*\))$`)
+	sqliteTablePrimaryRegexp = regexp.MustCompile(`(?is)^PRIMARY\s+KEY\s*\((.*)\)$`)
+	sqliteTableConstraint    = regexp.MustCompile(`(?i)^(CONSTRAINT|FOREIGN\s+KEY|CHECK|UNIQUE)\b`)
+	sqliteAutoIncrementStart = regexp.MustCompile(`(?i)\bAUTO_INCREMENT\s*=\s*([0-9]+)`)
+
+	sqliteAutoIncrementRegexp = regexp.MustCompile(`(?i)\bAUTO_INCREMENT\b`)
+	sqliteColumnAttrRegexp    = regexp.MustCompile("(?i)\\b(UNSIGNED|ZEROFILL)\\b|\\bON\\s+UPDATE\\s+CURRENT_TIMESTAMP(\\s*\\(\\s*[0-9]*\\s*\\))?|\\b(CHARACTER\\s+SET|CHARSET|COLLATE)\\s*=?\\s*\\w+|\\bCOMMENT\\s*=?\\s*\x00[0-9]+\x00")
+	sqliteEnumRegexp          = regexp.MustCompile(`(?i)\bENUM\s*\([^)]*\)`)
+	sqliteNotNullRegexp       = regexp.MustCompile(`(?i)\bNOT\s+NULL\b`)
+	sqliteDefaultRegexp       = regexp.MustCompile(`(?i)\bDEFAULT\b`)
+	sqliteDefaultNowRegexp    = regexp.MustCompile(`(?i)\bDEFAULT\s+(CURRENT_TIMESTAMP(\s*\(\s*[0-9]*\s*\))?|NOW\s*\(\s*\))`)
+	sqliteNumericTypeRegexp   = regexp.MustCompile(`(?i)^(TINYINT|SMALLINT|MEDIUMINT|INT|INTEGER|BIGINT|FLOAT|DOUBLE|DECIMAL|NUMERIC|BOOL|BOOLEAN|BIT)\b`)
+	sqliteIndexPrefixRegexp   = regexp.MustCompile("([\\w`])\\s*\\(\\s*[0-9]+\\s*\\)")
+
+	sqliteAlterAddIndexRegexp  = regexp.MustCompile(`(?is)^ADD\s+(UNIQUE\s+)?(INDEX|KEY)\s+(\S+?)\s*(\(.*\))$`)
+	sqliteAlterAddColumnRegexp = regexp.MustCompile(`(?is)^ADD\s+(COLUMN\s+)?(.+?)(\s+(AFTER\s+\S+|FIRST))?$`)
+	sqliteAlterDropIndexRegexp = regexp.MustCompile(`(?i)^DROP\s+(INDEX|KEY)\s+(\S+)$`)
+	sqliteAlterDropPrimary     = regexp.MustCompile(`(?i)^DROP\s+PRIMARY\s+KEY$`)
+	sqliteAlterDropColumn      = regexp.MustCompile(`(?i)^DROP\s+(COLUMN\s+)?(\S+)$`)
+	sqliteAlterChangeRegexp    = regexp.MustCompile(`(?is)^CHANGE\s+(COLUMN\s+)?(\S+)\s+(\S+)\s+.+$`)
+	sqliteAlterRenameColumn    = regexp.MustCompile(`(?i)^RENAME\s+COLUMN\s+(\S+)\s+TO\s+(\S+)$`)
+	sqliteAlterRenameTable     = regexp.MustCompile(`(?i)^RENAME\s+((TO|AS)\s+)?(\S+)$`)
+	sqliteAlterModifyRegexp    = regexp.MustCompile(`(?i)^(MODIFY|ALTER)\s`)
+)
+
+type sqliteTranslator struct {
+	variables map[string]string
+}
+
+func newSQLiteTranslator() *sqliteTranslator {
+	return &sqliteTranslator{variables: make(map[string]string)}
+}
+
+// SplitSQLStatements 去掉注释后按字符串和标识符之外的分号拆分语句
+func SplitSQLStatements(script string) []string {
+	statements := []string{}
+	var b strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(b.String()); s != "" {
+			statements = append(statements, s)
+		}
+		b.Reset()
+	}
+	var quote byte
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		if quote != 0 {
+			b.WriteByte(c)
+			if c == '\\' && quote != '`' && i+1 < len(script) {
+				i++
+				b.WriteByte(script[i])
+			} else if c == quote {
+				if i+1 < len(script) && script[i+1] == quote {
+					i++
+					b.WriteByte(script[i])
+				} else {
+					quote = 0
+				}
+			}
+			continue
+		}
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			b.WriteByte(c)
+		case c == '#' || (c == '-' && strings.HasPrefix(script[i:], "--")):
+			if end := strings.IndexByte(script[i:], '\n'); end >= 0 {
+				i += end
+				b.WriteByte('\n')
+			} else {
+				i = len(script)
+			}
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			if end := strings.Index(script[i+2:], "*/"); end >= 0 {
+				i += end + 3
+				b.WriteByte(' ')
+			} else {
+				i = len(script)
+			}
+		case c == ';':
+			flush()
+		default:
+			b.WriteByte(c)
+		}
+	}
+	flush()
+	return statements
+}
+
+// 将语句中的字符串字面量替换为占位符，返回的字面量已去掉MySQL的转义
+func extractLiterals(statement string) (string, []string) {
+	var code strings.Builder
+	literals := []string{}
+	for i := 0; i < len(statement); i++ {
+		c := statement[i]
+		if c == '`' {
+			end := strings.IndexByte(statement[i+1:], '`')
+			if end < 0 {
+				code.WriteString(statement[i:])
+				break
+			}
+			code.WriteString(statement[i : i+end+2])
+			i += end + 1
+			continue
+		}
+		if c != '\'' && c != '"' {
+			code.WriteByte(c)
+			continue
+		}
+		var literal strings.Builder
+		for i++; i < len(statement); i++ {
+			d := statement[i]
+			if d == '\\' && i+1 < len(statement) {
+				i++
+				switch e := statement[i]; e {
+				case 'n':
+					literal.WriteByte('\n')
+				case 'r':
+					literal.WriteByte('\r')
+				case 't':
+					literal.WriteByte('\t')
+				case '0':
+					literal.WriteByte(0)
+				case 'Z':
+					literal.WriteByte(26)
+				case 'b':
+					literal.WriteByte('\b')
+				case '%', '_':
+					literal.WriteByte('\\')
+					literal.WriteByte(e)
+				default:
+					literal.WriteByte(e)
+				}
+				continue
+			}
+			if d == c {
+				if i+1 < len(statement) && statement[i+1] == c {
+					i++
+					literal.WriteByte(c)
+					continue
+				}
+				break
+			}
+			literal.WriteByte(d)
+		}
+		code.WriteString(literalPlaceholder(len(literals)))
+		literals = append(literals, literal.String())
+	}
+	return code.String(), literals
+}
+
+func literalPlaceholder(index int) string {
+	return SQLITE_LITERAL_MARK + strconv.Itoa(index) + SQLITE_LITERAL_MARK
+}
+
+func restoreLiterals(code string, literals []string) string {
+	return sqliteLiteralRegexp.ReplaceAllStringFunc(code, func(s string) string {
+		index, _ := strconv.Atoi(strings.Trim(s, SQLITE_LITERAL_MARK))
+		return "'" + strings.ReplaceAll(literals[index], "'", "''") + "'"
+	})
+}
+
+func unquoteIdentifier(s string) string {
+	return strings.Trim(s, "`\"")
+}
+
+// MySQL的索引名在表内唯一，SQLite的索引名在库内唯一
+func sqliteIndexName(table, index string) string {
+	return fmt.Sprintf("`%s_%s`", unquoteIdentifier(table), unquoteIdentifier(index))
+}
+
+func sqliteCreateIndex(unique bool, table, index, columns string) string {
+	columns = sqliteIndexPrefixRegexp.ReplaceAllString(columns, "$1")
+	if unique {
+		return fmt.Sprintf("CREATE UNIQUE INDEX IF NOT EXISTS %s ON %s %s", sqliteIndexName(table, index), table, columns)
+	}
+	return fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s %s", sqliteIndexName(table, index), table, columns)
+}
+
+// 按括号和占位符之外的逗号拆分
+func splitTopLevel(s string) []string {
+	items := []string{}
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				items = append(items, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" {
+		items = append(items, last)
+	}
+	return items
+}
+
+// 转换列定义，返回的bool表示该列是否为自增列
+func translateColumnDefinition(definition string) (string, bool) {
+	definition = sqliteColumnAttrRegexp.ReplaceAllString(definition, "")
+	definition = sqliteEnumRegexp.ReplaceAllString(definition, "TEXT")
+	fields := strings.Fields(definition)
+	if sqliteAutoIncrementRegexp.MatchString(definition) {
+		return fields[0] + " INTEGER PRIMARY KEY AUTOINCREMENT", true
+	}
+	return strings.Join(fields, " "), false
+}
+
+func (t *sqliteTranslator) translate(statement string) ([]string, string, error) {
+	code, literals := extractLiterals(statement)
+	code = strings.TrimSpace(code)
+
+	if m := sqliteSetVariableRegexp.FindStringSubmatch(code); m != nil {
+		expression, literals := t.replaceVariables(m[2], literals)
+		return []string{restoreLiterals("SELECT "+expression, literals)}, strings.ToLower(m[1]), nil
+	}
+	if sqliteSetRegexp.MatchString(code) || sqliteTransactionRegexp.MatchString(code) {
+		return nil, "", nil
+	}
+	if sqliteUnsupportedRegexp.MatchString(code) {
+		return nil, "", fmt.Errorf("statement is not supported by sqlite: %s", statement)
+	}
+
+	code, literals = t.replaceVariables(code, literals)
+	var statements []string
+	var err error
+	if m := sqliteTruncateRegexp.FindStringSubmatch(code); m != nil {
+		statements = []string{"DELETE FROM " + m[2]}
+	} else if m := sqliteCreateTableRegexp.FindStringSubmatch(code); m != nil {
+		statements = translateCreateTable(m[1] != "", m[2], m[3], m[4])
+	} else if m := sqliteCreateIndexRegexp.FindStringSubmatch(code); m != nil {
+		statements = []string{sqliteCreateIndex(m[1] != "", m[3], m[2], m[4])}
+	} else if m := sqliteDropIndexRegexp.FindStringSubmatch(code); m != nil {
+		statements = []string{"DROP INDEX IF EXISTS " + sqliteIndexName(m[2], m[1])}
+	} else if m := sqliteAlterTableRegexp.FindStringSubmatch(code); m != nil {
+		statements, err = translateAlterTable(m[1], m[2])
+	} else {
+		code = sqliteInsertIgnore.ReplaceAllString(code, "INSERT OR IGNORE")
+		code = sqliteInsertValue.ReplaceAllString(code, "$1 VALUES (")
+		code = sqliteColumnsValue.ReplaceAllString(code, ") VALUES (")
+		statements = []string{code}
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("%v in statement: %s", err, statement)
+	}
+	for i := range statements {
+		statements[i] = restoreLiterals(statements[i], literals)
+	}
+	return statements, "", nil
+}
+
+// 未定义的变量与MySQL一致视为NULL
+func (t *sqliteTranslator) replaceVariables(code string, literals []string) (string, []string) {
+	code = sqliteVariableRegexp.ReplaceAllStringFunc(code, func(s string) string {
+		value, ok := t.variables[strings.ToLower(s[1:])]
+		if !ok {
+			return "NULL"
+		}
+		literals = append(literals, value)
+		return literalPlaceholder(len(literals) - 1)
+	})
+	return code, literals
+}
+
+func translateCreateTable(ifNotExists bool, table, body, options string) []string {
+	columns := []string{}
+	indexes := []string{}
+	var primaryKey []string
+	autoIncrementColumn := ""
+	for _, item := range splitTopLevel(body) {
+		if m := sqliteTableIndexRegexp.FindStringSubmatch(item); m != nil {
+			indexes = append(indexes, sqliteCreateIndex(m[1] != "", table, m[3], m[4]))
+		} else if m := sqliteTablePrimaryRegexp.FindStringSubmatch(item); m != nil {
+			primaryKey = splitTopLevel(m[1])
+		} else if sqliteTableConstraint.MatchString(item) {
+			columns = append(columns, strings.Join(strings.Fields(item), " "))
+		} else {
+			column, autoIncrement := translateColumnDefinition(item)
+			if autoIncrement {
+				autoIncrementColumn = unquoteIdentifier(strings.Fields(column)[0])
+			}
+			columns = append(columns, column)
+		}
+	}
+	// SQLite的自增列必须是单独的主键，原有的联合主键转换为唯一约束
+	if len(primaryKey) > 0 {
+		if autoIncrementColumn == "" {
+			columns = append(columns, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(primaryKey, ", ")))
+		} else if len(primaryKey) > 1 || unquoteIdentifier(primaryKey[0]) != autoIncrementColumn {
+			columns = append(columns, fmt.Sprintf("UNIQUE (%s)", strings.Join(primaryKey, ", ")))
+		}
+	}
+
+	create := "CREATE TABLE "
+	if ifNotExists {
+		create += "IF NOT EXISTS "
+	}
+	statements := []string{fmt.Sprintf("%s%s (\n    %s\n)", create, table, strings.Join(columns, ",\n    "))}
+	statements = append(statements, indexes...)
+	if m := sqliteAutoIncrementStart.FindStringSubmatch(options); m != nil && autoIncrementColumn != "" {
+		if start, _ := strconv.Atoi(m[1]); start > 1 {
+			name := unquoteIdentifier(table)
+			statements = append(statements, fmt.Sprintf(
+				"INSERT INTO sqlite_sequence (name, seq) SELECT '%s', %d WHERE NOT EXISTS (SELECT 1 FROM sqlite_sequence WHERE name = '%s')",
+				name, start-1, name,
+			))
+		}
+	}
+	return statements
+}
+
+func translateAlterTable(table, specification string) ([]string, error) {
+	statements := []string{}
+	for _, item := range splitTopLevel(specification) {
+		if m := sqliteAlterAddIndexRegexp.FindStringSubmatch(item); m != nil {
+			statements = append(statements, sqliteCreateIndex(m[1] != "", table, m[3], m[4]))
+		} else if m := sqliteAlterAddColumnRegexp.FindStringSubmatch(item); m != nil {
+			column, autoIncrement := translateColumnDefinition(m[2])
+			if autoIncrement {
+				return nil, fmt.Errorf("can not add auto increment column %s", strings.Fields(column)[0])
+			}
+			statements = append(statements, translateAddColumn(table, column)...)
+		} else if m := sqliteAlterDropIndexRegexp.FindStringSubmatch(item); m != nil {
+			statements = append(statements, "DROP INDEX IF EXISTS "+sqliteIndexName(table, m[2]))
+		} else if sqliteAlterDropPrimary.MatchString(item) {
+			return nil, fmt.Errorf("can not drop primary key")
+		} else if m := sqliteAlterDropColumn.FindStringSubmatch(item); m != nil {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, m[2]))
+		} else if m := sqliteAlterChangeRegexp.FindStringSubmatch(item); m != nil {
+			if unquoteIdentifier(m[2]) != unquoteIdentifier(m[3]) {
+				statements = append(statements, fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", table, m[2], m[3]))
+			}
+		} else if m := sqliteAlterRenameColumn.FindStringSubmatch(item); m != nil {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", table, m[1], m[2]))
+		} else if m := sqliteAlterRenameTable.FindStringSubmatch(item); m != nil {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s RENAME TO %s", table, m[3]))
+		} else if sqliteAlterModifyRegexp.MatchString(item) {
+			continue
+		} else {
+			return nil, fmt.Errorf("unsupported alter table specification %s", strings.ToUpper(strings.Fields(item)[0]))
+		}
+	}
+	return statements, nil
+}
+
+// SQLite增加的列不能以CURRENT_TIMESTAMP为默认值，NOT NULL的列必须有默认值
+func translateAddColumn(table, column string) []string {
+	name := strings.Fields(column)[0]
+	if sqliteDefaultNowRegexp.MatchString(column) {
+		column = sqliteDefaultNowRegexp.ReplaceAllString(column, "")
+		column = sqliteNotNullRegexp.ReplaceAllString(column, "")
+		return []string{
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", table, strings.Join(strings.Fields(column), " ")),
+			fmt.Sprintf("UPDATE %s SET %s = CURRENT_TIMESTAMP", table, name),
+		}
+	}
+	if sqliteNotNullRegexp.MatchString(column) && !sqliteDefaultRegexp.MatchString(column) {
+		if fields := strings.Fields(column); len(fields) > 1 && sqliteNumericTypeRegexp.MatchString(fields[1]) {
+			column += " DEFAULT 0"
+		} else {
+			column += " DEFAULT ''"
+		}
+	}
+	return []string{fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", table, column)}
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/deepflowio/deepflow/server/controller/db/mysql/config"
+)
+
+func TestSplitSQLStatements(t *testing.T) {
+	script := "-- modify start;\nINSERT INTO t VALUES ('a;b', \"c\\\";\"); # comment;\n/* x; */UPDATE t SET a=1;\n"
+	expected := []string{"INSERT INTO t VALUES ('a;b', \"c\\\";\")", "UPDATE t SET a=1"}
+	if got := SplitSQLStatements(script); !reflect.DeepEqual(got, expected) {
+		t.Errorf("SplitSQLStatements() = %q, want %q", got, expected)
+	}
+}
+
+func TestTranslateSQLite(t *testing.T) {
+	tests := []struct {
+		name      string
+		statement string
+		expected  []string
+		wantErr   bool
+	}{
+		{
+			name: "create table",
+			statement: "CREATE TABLE IF NOT EXISTS vm (\n" +
+				"    id INTEGER NOT NULL AUTO_INCREMENT,\n" +
+				"    name VARCHAR(256) CHARACTER SET utf8 COLLATE utf8_bin DEFAULT \"\" COMMENT 'vm name',\n" +
+				"    state INTEGER UNSIGNED NOT NULL,\n" +
+				"    updated_at DATETIME NOT NULL ON UPDATE CURRENT_TIMESTAMP DEFAULT CURRENT_TIMESTAMP,\n" +
+				"    PRIMARY KEY (id, domain),\n" +
+				"    INDEX state_index(state)\n" +
+				") ENGINE=innodb DEFAULT CHARSET=utf8 AUTO_INCREMENT=256",
+			expected: []string{
+				"CREATE TABLE IF NOT EXISTS vm (\n" +
+					"    id INTEGER PRIMARY KEY AUTOINCREMENT,\n" +
+					"    name VARCHAR(256) DEFAULT '',\n" +
+					"    state INTEGER NOT NULL,\n" +
+					"    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+					"    UNIQUE (id, domain)\n" +
+					")",
+				"CREATE INDEX IF NOT EXISTS `vm_state_index` ON vm (state)",
+				"INSERT INTO sqlite_sequence (name, seq) SELECT 'vm', 255 WHERE NOT EXISTS (SELECT 1 FROM sqlite_sequence WHERE name = 'vm')",
+			},
+		},
+		{
+			name:      "alter table",
+			statement: "ALTER TABLE plugin ADD COLUMN version INTEGER NOT NULL COMMENT 'version' AFTER image, ADD UNIQUE INDEX name_index(name), MODIFY COLUMN name VARCHAR(512), CHANGE old_name new_name INTEGER",
+			expected: []string{
+				"ALTER TABLE plugin ADD COLUMN version INTEGER NOT NULL DEFAULT 0",
+				"CREATE UNIQUE INDEX IF NOT EXISTS `plugin_name_index` ON plugin (name)",
+				"ALTER TABLE plugin RENAME COLUMN old_name TO new_name",
+			},
+		},
+		{
+			name:      "add timestamp column",
+			statement: "ALTER TABLE vtap ADD COLUMN synced_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP",
+			expected: []string{
+				"ALTER TABLE vtap ADD COLUMN synced_at DATETIME",
+				"UPDATE vtap SET synced_at = CURRENT_TIMESTAMP",
+			},
+		},
+		{
+			name:      "truncate",
+			statement: "TRUNCATE TABLE vm",
+			expected:  []string{"DELETE FROM vm"},
+		},
+		{
+			name:      "insert",
+			statement: "INSERT IGNORE INTO db_version (version) VALUE ('6.3.1.0')",
+			expected:  []string{"INSERT OR IGNORE INTO db_version (version) VALUES ('6.3.1.0')"},
+		},
+		{
+			name:      "transaction",
+			statement: "START TRANSACTION",
+		},
+		{
+			name:      "procedure",
+			statement: "CALL update_data_sources()",
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := newSQLiteTranslator().translate(tt.statement)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("translate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("translate() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExecSQLiteScript(t *testing.T) {
+	db := GetSQLiteGormDB(MySqlConfig{SQLitePath: SQLITE_MEMORY_PATH, TimeOut: 30})
+	if db == nil {
+		t.Fatal("open sqlite failed")
+	}
+	script := "CREATE TABLE vtap_group (\n" +
+		"    id INTEGER NOT NULL AUTO_INCREMENT PRIMARY KEY,\n" +
+		"    name VARCHAR(64) NOT NULL,\n" +
+		"    short_uuid CHAR(32),\n" +
+		"    lcuuid CHAR(64)\n" +
+		") ENGINE=innodb DEFAULT CHARSET=utf8;\n" +
+		"set @lcuuid = (select uuid());\n" +
+		"set @short_uuid = (select substr(replace(uuid(),'-',''), 1, 10));\n" +
+		"set @short_uuid = concat('g-', @short_uuid);\n" +
+		"INSERT INTO vtap_group(lcuuid, id, name, short_uuid) values(@lcuuid, 1, \"default\", @short_uuid);\n"
+	if err := ExecSQLiteScript(db, script); err != nil {
+		t.Fatal(err)
+	}
+	var result struct {
+		Name      string
+		ShortUUID string
+		Lcuuid    string
+	}
+	if err := db.Table("vtap_group").First(&result).Error; err != nil {
+		t.Fatal(err)
+	}
+	if result.Name != "default" || len(result.ShortUUID) != 12 || !strings.HasPrefix(result.ShortUUID, "g-") || len(result.Lcuuid) != 36 {
+		t.Errorf("unexpected vtap_group %+v", result)
+	}
+}
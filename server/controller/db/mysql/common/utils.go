@@ -43,11 +43,17 @@ var log = logging.MustGetLogger("db.mysql.common")
 var SQL_FILE_DIR = "/etc/mysql"
 
 func GetConnectionWithoutDatabase(cfg MySqlConfig) *gorm.DB {
+	if cfg.IsSQLite() {
+		return GetSQLiteGormDB(cfg)
+	}
 	dsn := GetDSN(cfg, "", cfg.TimeOut, false)
 	return GetGormDB(dsn)
 }
 
 func GetConnectionWithDatabase(cfg MySqlConfig) *gorm.DB {
+	if cfg.IsSQLite() {
+		return GetSQLiteGormDB(cfg)
+	}
 	// set multiStatements=true in dsn only when migrating MySQL
 	dsn := GetDSN(cfg, cfg.Database, cfg.TimeOut*2, true)
 	return GetGormDB(dsn)
@@ -77,17 +83,7 @@ func GetGormDB(dsn string) *gorm.DB {
 		DontSupportRenameIndex:    true,  // 重命名索引时采用删除并新建的方式，MySQL 5.7 之前的数据库和 MariaDB 不支持重命名索引
 		DontSupportRenameColumn:   true,  // 用 `change` 重命名列，MySQL 8 之前的数据库和 MariaDB 不支持重命名列
 		SkipInitializeWithVersion: false, // 根据当前 MySQL 版本自动配置
-	}), &gorm.Config{
-		NamingStrategy: schema.NamingStrategy{SingularTable: true}, // 设置全局表名禁用复数
-		Logger: logger.New(
-			l.New(os.Stdout, "\r\n", l.LstdFlags), // io writer
-			logger.Config{
-				SlowThreshold:             0,            // 慢SQL阈值,为0时不打印
-				LogLevel:                  logger.Error, // Log level
-				IgnoreRecordNotFoundError: false,        // 忽略ErrRecordNotFound（记录未找到）错误
-				Colorful:                  true,         // 是否彩色打印
-			}), // 配置log
-	})
+	}), getGormConfig())
 	if err != nil {
 		log.Errorf("Mysql Connection failed with error: %v", err.Error())
 		return nil
@@ -101,6 +97,20 @@ func GetGormDB(dsn string) *gorm.DB {
 	return Db
 }
 
+func getGormConfig() *gorm.Config {
+	return &gorm.Config{
+		NamingStrategy: schema.NamingStrategy{SingularTable: true}, // 设置全局表名禁用复数
+		Logger: logger.New(
+			l.New(os.Stdout, "\r\n", l.LstdFlags), // io writer
+			logger.Config{
+				SlowThreshold:             0,            // 慢SQL阈值,为0时不打印
+				LogLevel:                  logger.Error, // Log level
+				IgnoreRecordNotFoundError: false,        // 忽略ErrRecordNotFound（记录未找到）错误
+				Colorful:                  true,         // 是否彩色打印
+			}), // 配置log
+	}
+}
+
 func DropDatabase(db *gorm.DB, database string) error {
 	log.Infof("drop database %s", database)
 	return db.Exec(fmt.Sprintf("DROP DATABASE %s", database)).Error
@@ -142,12 +152,12 @@ func InitTables(db *gorm.DB) error {
 		log.Errorf("read sql file failed: %v", err)
 		return err
 	}
-	err = db.Exec(string(initSQL)).Error
+	err = execSQL(db, string(initSQL))
 	if err != nil {
 		log.Errorf("init db tables failed: %v", err)
 		return err
 	}
-	err = db.Exec(fmt.Sprintf("INSERT INTO db_version (version) VALUE ('%s')", migration.DB_VERSION_EXPECTED)).Error
+	err = db.Exec(fmt.Sprintf("INSERT INTO db_version (version) VALUES ('%s')", migration.DB_VERSION_EXPECTED)).Error
 	if err != nil {
 		log.Errorf("init db version failed: %v", err)
 		return err
@@ -156,7 +166,10 @@ func InitTables(db *gorm.DB) error {
 	return err
 }
 
-func ExecuteIssus(db *gorm.DB, curVersion string) error {
+// IssuCallback 完成无法用sql实现的数据迁移，在对应版本的issu执行后调用
+type IssuCallback func(db *gorm.DB) error
+
+func ExecuteIssus(db *gorm.DB, curVersion string, callbacks map[string]IssuCallback) error {
 	issus, err := ioutil.ReadDir(fmt.Sprintf("%s/issu", SQL_FILE_DIR))
 	if err != nil {
 		log.Errorf("read sql dir faild: %v", err)
@@ -169,12 +182,27 @@ func ExecuteIssus(db *gorm.DB, curVersion string) error {
 		if err != nil {
 			return err
 		}
+		if callback, ok := callbacks[nv]; ok {
+			if err = callback(db); err != nil {
+				log.Errorf("execute db issu (version: %s) callback failed: %v", nv, err)
+				return err
+			}
+			log.Infof("execute db issu (version: %s) callback success", nv)
+		}
 	}
 	return nil
 }
 
+// 执行SQLite的issu时，优先使用issu/sqlite目录下同版本的语句，不存在时由MySQL语句转换
 func executeIssu(db *gorm.DB, nextVersion string) error {
-	issuSQL, err := ioutil.ReadFile(fmt.Sprintf("%s/issu/%s.sql", SQL_FILE_DIR, nextVersion))
+	issuFile := fmt.Sprintf("%s/issu/%s.sql", SQL_FILE_DIR, nextVersion)
+	if IsSQLite(db) {
+		sqliteIssuFile := fmt.Sprintf("%s/issu/%s/%s.sql", SQL_FILE_DIR, DB_TYPE_SQLITE, nextVersion)
+		if _, err := os.Stat(sqliteIssuFile); err == nil {
+			issuFile = sqliteIssuFile
+		}
+	}
+	issuSQL, err := ioutil.ReadFile(issuFile)
 	if err != nil {
 		log.Errorf("read sql file (version: %s) failed: %v", nextVersion, err)
 		return err
@@ -183,7 +211,7 @@ func executeIssu(db *gorm.DB, nextVersion string) error {
 		log.Infof("issu with no content (version: %s)", nextVersion)
 		return nil
 	}
-	err = db.Exec(string(issuSQL)).Error
+	err = execSQL(db, string(issuSQL))
 	if err != nil {
 		log.Errorf("excute db issu (version: %s) failed: %v", nextVersion, err)
 		return err
@@ -192,9 +220,19 @@ func executeIssu(db *gorm.DB, nextVersion string) error {
 	return nil
 }
 
+func execSQL(db *gorm.DB, sql string) error {
+	if IsSQLite(db) {
+		return ExecSQLiteScript(db, sql)
+	}
+	return db.Exec(sql).Error
+}
+
 func getAscSortedNextVersions(files []fs.FileInfo, curVersion string) []string {
 	vs := []string{}
 	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
 		vs = append(vs, trimFilenameExt(f.Name()))
 	}
 	// asc sort: split version by ".", compare each number from first to end
@@ -16,7 +16,15 @@
 
 package config
 
+const (
+	DB_TYPE_MYSQL  = "mysql"
+	DB_TYPE_SQLITE = "sqlite"
+)
+
 type MySqlConfig struct {
+	// 元数据库类型，sqlite时使用SQLitePath指定的文件，Host等连接配置不生效
+	Type                   string `default:"mysql" yaml:"type"`
+	SQLitePath             string `default:"/var/lib/deepflow/deepflow.db" yaml:"sqlite-path"`
 	Database               string `default:"deepflow" yaml:"database"`
 	Host                   string `default:"mysql" yaml:"host"`
 	Port                   uint32 `default:"30130" yaml:"port"`
@@ -26,3 +34,7 @@ type MySqlConfig struct {
 	DropDatabaseEnabled    bool   `default:"false" yaml:"drop-database-enabled"`
 	AutoIncrementIncrement uint32 `default:"1" yaml:"auto_increment_increment"`
 }
+
+func (c MySqlConfig) IsSQLite() bool {
+	return c.Type == DB_TYPE_SQLITE
+}
@@ -50,6 +50,9 @@ func InitMySQL(cfg MySqlConfig) error {
 }
 
 func Gorm(cfg MySqlConfig) *gorm.DB {
+	if cfg.IsSQLite() {
+		return GetSQLiteGormDB(cfg)
+	}
 	dsn := GetDSN(cfg, cfg.Database, cfg.TimeOut, false)
 	return GetGormDB(dsn)
 }
@@ -10,12 +10,25 @@ CREATE TABLE IF NOT EXISTS plugin (
     name                VARCHAR(256) NOT NULL,
     type                INTEGER NOT NULL COMMENT '1: wasm',
     image               LONGBLOB NOT NULL,
+    version             INTEGER NOT NULL DEFAULT 0 COMMENT 'default version sent to agents without a pinned version, 0 means not versioned yet',
     created_at          DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at          DATETIME NOT NULL ON UPDATE CURRENT_TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     UNIQUE INDEX name_index(name)
 )ENGINE=innodb AUTO_INCREMENT=1 DEFAULT CHARSET=utf8 COMMENT='store plugins for sending to vtap';
 TRUNCATE TABLE plugin;
 
+CREATE TABLE IF NOT EXISTS plugin_version (
+    id                  INTEGER NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    plugin_name         VARCHAR(256) NOT NULL,
+    type                INTEGER NOT NULL COMMENT '1: wasm',
+    version             INTEGER NOT NULL,
+    digest              CHAR(64) NOT NULL COMMENT 'sha256 of decompressed image',
+    image               LONGBLOB NOT NULL,
+    created_at          DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE INDEX name_version_type_index(plugin_name, version, type)
+)ENGINE=innodb AUTO_INCREMENT=1 DEFAULT CHARSET=utf8 COMMENT='store immutable versions of plugins';
+TRUNCATE TABLE plugin_version;
+
 CREATE TABLE IF NOT EXISTS vtap_repo (
     id                  INTEGER NOT NULL AUTO_INCREMENT PRIMARY KEY,
     name                CHAR(64),
@@ -911,6 +924,7 @@ TRUNCATE TABLE pod_group_port;
 CREATE TABLE IF NOT EXISTS pod_ingress (
     id                  INTEGER NOT NULL AUTO_INCREMENT PRIMARY KEY,
     name                VARCHAR(256) DEFAULT '',
+    type                INTEGER DEFAULT 1 COMMENT '1: Ingress 2: Route 3: HTTPRoute 4: GRPCRoute 5: VirtualService',
     alias               CHAR(64) DEFAULT '',
     pod_namespace_id    INTEGER DEFAULT NULL,
     pod_cluster_id      INTEGER DEFAULT NULL,
@@ -940,6 +954,7 @@ CREATE TABLE IF NOT EXISTS pod_ingress_rule_backend (
     id                  INTEGER NOT NULL AUTO_INCREMENT PRIMARY KEY,
     path                TEXT,
     port                INTEGER,
+    weight              INTEGER DEFAULT 0,
     pod_service_id      INTEGER DEFAULT NULL,
     pod_ingress_rule_id INTEGER DEFAULT NULL,
     pod_ingress_id      INTEGER DEFAULT NULL,
@@ -1618,6 +1633,19 @@ CREATE TABLE IF NOT EXISTS vtap_group_configuration(
 ) ENGINE=innodb DEFAULT CHARSET=utf8 AUTO_INCREMENT=1;
 TRUNCATE TABLE vtap_group_configuration;
 
+CREATE TABLE IF NOT EXISTS vtap_group_configuration_revision (
+    id                  INTEGER NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    vtap_group_lcuuid   CHAR(64) NOT NULL,
+    revision            INTEGER NOT NULL,
+    operation           VARCHAR(16) NOT NULL COMMENT 'baseline, create, update, delete, rollback',
+    config              MEDIUMTEXT COMMENT 'yaml of the whole configuration after the change, empty after delete',
+    author              VARCHAR(64) DEFAULT '',
+    comment             VARCHAR(256) DEFAULT '',
+    created_at          DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE INDEX group_revision_index(vtap_group_lcuuid, revision)
+)ENGINE=innodb AUTO_INCREMENT=1 DEFAULT CHARSET=utf8 COMMENT='history of vtap group configuration';
+TRUNCATE TABLE vtap_group_configuration_revision;
+
 CREATE TABLE IF NOT EXISTS npb_tunnel (
     id                  INTEGER NOT NULL AUTO_INCREMENT PRIMARY KEY,
     name                CHAR(64) NOT NULL,
@@ -2006,6 +2034,7 @@ TRUNCATE TABLE ch_pod_ns;
 CREATE TABLE IF NOT EXISTS ch_pod_group (
     id                      INTEGER NOT NULL PRIMARY KEY,
     name                    VARCHAR(256),
+    pod_group_type          INTEGER DEFAULT NULL,
     icon_id                 INTEGER,
     updated_at              TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
 )ENGINE=innodb DEFAULT CHARSET=utf8;
@@ -2030,6 +2059,17 @@ CREATE TABLE IF NOT EXISTS ch_device (
 )ENGINE=innodb DEFAULT CHARSET=utf8;
 TRUNCATE TABLE ch_device;
 
+CREATE TABLE IF NOT EXISTS ch_device_history (
+    devicetype              INTEGER NOT NULL,
+    deviceid                INTEGER NOT NULL,
+    name                    VARCHAR(256),
+    valid_from              DATETIME NOT NULL,
+    valid_to                DATETIME NOT NULL DEFAULT '2106-01-01 00:00:00',
+    updated_at              TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (devicetype, deviceid, valid_from),
+    INDEX valid_to_index(valid_to)
+)ENGINE=innodb DEFAULT CHARSET=utf8;
+
 CREATE TABLE IF NOT EXISTS ch_vtap_port (
     vtap_id                 INTEGER NOT NULL,
     tap_port                BIGINT NOT NULL,
@@ -2082,6 +2122,16 @@ CREATE TABLE IF NOT EXISTS ch_pod_k8s_labels (
 )ENGINE=innodb DEFAULT CHARSET=utf8;
 TRUNCATE TABLE ch_pod_k8s_labels;
 
+CREATE TABLE IF NOT EXISTS ch_pod_k8s_labels_history (
+    `id`            INTEGER NOT NULL,
+    `labels`        TEXT,
+    `valid_from`    DATETIME NOT NULL,
+    `valid_to`      DATETIME NOT NULL DEFAULT '2106-01-01 00:00:00',
+    `updated_at`    TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`, `valid_from`),
+    INDEX valid_to_index(`valid_to`)
+)ENGINE=innodb DEFAULT CHARSET=utf8;
+
 CREATE TABLE IF NOT EXISTS ch_pod_node_port (
     id                      INTEGER NOT NULL,
     protocol                INTEGER NOT NULL,
@@ -2242,6 +2292,7 @@ TRUNCATE TABLE ch_lb_listener;
 CREATE TABLE IF NOT EXISTS ch_pod_ingress (
     id                      INTEGER NOT NULL PRIMARY KEY,
     name                    VARCHAR(256),
+    pod_ingress_type        INTEGER DEFAULT NULL,
     updated_at              TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
 )ENGINE=innodb DEFAULT CHARSET=utf8;
 TRUNCATE TABLE ch_pod_ingress;
@@ -0,0 +1,3 @@
+ALTER TABLE ch_pod_group ADD COLUMN pod_group_type INTEGER DEFAULT NULL AFTER `name`;
+
+UPDATE db_version SET version='6.3.1.23';
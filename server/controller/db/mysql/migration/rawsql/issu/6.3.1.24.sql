@@ -0,0 +1,5 @@
+ALTER TABLE pod_ingress ADD COLUMN type INTEGER DEFAULT 1 COMMENT '1: Ingress 2: Route 3: HTTPRoute 4: GRPCRoute 5: VirtualService' AFTER `name`;
+ALTER TABLE pod_ingress_rule_backend ADD COLUMN weight INTEGER DEFAULT 0 AFTER `port`;
+ALTER TABLE ch_pod_ingress ADD COLUMN pod_ingress_type INTEGER DEFAULT NULL AFTER `name`;
+
+UPDATE db_version SET version='6.3.1.24';
@@ -0,0 +1,25 @@
+-- modify start, add upgrade sql
+CREATE TABLE IF NOT EXISTS ch_device_history (
+    devicetype              INTEGER NOT NULL,
+    deviceid                INTEGER NOT NULL,
+    name                    VARCHAR(256),
+    valid_from              DATETIME NOT NULL,
+    valid_to                DATETIME NOT NULL DEFAULT '2106-01-01 00:00:00',
+    updated_at              TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (devicetype, deviceid, valid_from),
+    INDEX valid_to_index(valid_to)
+)ENGINE=innodb DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS ch_pod_k8s_labels_history (
+    `id`            INTEGER NOT NULL,
+    `labels`        TEXT,
+    `valid_from`    DATETIME NOT NULL,
+    `valid_to`      DATETIME NOT NULL DEFAULT '2106-01-01 00:00:00',
+    `updated_at`    TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`, `valid_from`),
+    INDEX valid_to_index(`valid_to`)
+)ENGINE=innodb DEFAULT CHARSET=utf8;
+
+-- update db_version to latest, remeber update DB_VERSION_EXPECT in migrate/version.go
+UPDATE db_version SET version='6.3.1.25';
+-- modify end
@@ -0,0 +1,20 @@
+-- modify start, add upgrade sql
+CREATE TABLE IF NOT EXISTS plugin_version (
+    id                  INTEGER NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    plugin_name         VARCHAR(256) NOT NULL,
+    type                INTEGER NOT NULL COMMENT '1: wasm',
+    version             INTEGER NOT NULL,
+    digest              CHAR(64) NOT NULL COMMENT 'sha256 of decompressed image',
+    image               LONGBLOB NOT NULL,
+    created_at          DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE INDEX name_version_type_index(plugin_name, version, type)
+)ENGINE=innodb AUTO_INCREMENT=1 DEFAULT CHARSET=utf8 COMMENT='store immutable versions of plugins';
+
+ALTER TABLE plugin ADD COLUMN version INTEGER NOT NULL DEFAULT 0 COMMENT 'default version sent to agents without a pinned version, 0 means not versioned yet' AFTER image;
+
+-- plugins uploaded before upgrade are recorded as version 1 by the controller after this file is executed,
+-- because the digest is calculated from the decompressed image
+
+-- update db_version to latest, remeber update DB_VERSION_EXPECT in migrate/version.go
+UPDATE db_version SET version='6.3.1.26';
+-- modify end
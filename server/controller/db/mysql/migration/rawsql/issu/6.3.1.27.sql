@@ -0,0 +1,16 @@
+-- modify start, add upgrade sql
+CREATE TABLE IF NOT EXISTS vtap_group_configuration_revision (
+    id                  INTEGER NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    vtap_group_lcuuid   CHAR(64) NOT NULL,
+    revision            INTEGER NOT NULL,
+    operation           VARCHAR(16) NOT NULL COMMENT 'baseline, create, update, delete, rollback',
+    config              MEDIUMTEXT COMMENT 'yaml of the whole configuration after the change, empty after delete',
+    author              VARCHAR(64) DEFAULT '',
+    comment             VARCHAR(256) DEFAULT '',
+    created_at          DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE INDEX group_revision_index(vtap_group_lcuuid, revision)
+)ENGINE=innodb AUTO_INCREMENT=1 DEFAULT CHARSET=utf8 COMMENT='history of vtap group configuration';
+
+-- update db_version to latest, remeber update DB_VERSION_EXPECT in migrate/version.go
+UPDATE db_version SET version='6.3.1.27';
+-- modify end
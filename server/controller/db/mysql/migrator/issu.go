@@ -0,0 +1,57 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package migrator
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	. "github.com/deepflowio/deepflow/server/controller/db/mysql/common"
+)
+
+// 各版本issu的sql执行后调用的数据迁移
+var issuCallbacks = map[string]IssuCallback{
+	"6.3.1.26": backfillPluginVersions,
+}
+
+// 升级前上传的插件记录为版本1，插件以压缩后的内容存储，摘要需要读取解压后的内容计算
+func backfillPluginVersions(db *gorm.DB) error {
+	var plugins []mysql.Plugin
+	if err := db.Where("version = ?", 0).Find(&plugins).Error; err != nil {
+		return err
+	}
+	for _, plugin := range plugins {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			pluginVersion := &mysql.PluginVersion{
+				PluginName: plugin.Name,
+				Type:       plugin.Type,
+				Version:    1,
+				Digest:     mysql.PluginDigest(plugin.Image),
+				Image:      plugin.Image,
+			}
+			if err := tx.Create(pluginVersion).Error; err != nil {
+				return err
+			}
+			return tx.Model(&mysql.Plugin{}).Where("id = ?", plugin.ID).Update("version", 1).Error
+		})
+		if err != nil {
+			return err
+		}
+		log.Infof("plugin (name: %s) is recorded as version 1", plugin.Name)
+	}
+	return nil
+}
@@ -38,6 +38,9 @@ var log = logging.MustGetLogger("db.migrator.mysql")
 //
 //	and upgrade based the result.
 func MigrateMySQL(cfg MySqlConfig) bool {
+	if cfg.IsSQLite() {
+		return MigrateSQLite(cfg)
+	}
 	db := GetConnectionWithoutDatabase(cfg)
 	if db == nil {
 		return false
@@ -69,6 +72,25 @@ func MigrateMySQL(cfg MySqlConfig) bool {
 	}
 }
 
+// sqlite文件不存在时自动创建，db_version表不存在时视为新部署，初始化所有表；否则按db_version升级
+// init.sql和issu在一个事务中执行，失败时不会留下部分创建的表
+func MigrateSQLite(cfg MySqlConfig) bool {
+	db := GetSQLiteGormDB(cfg)
+	if db == nil {
+		return false
+	}
+	var dbVersionTable string
+	err := db.Raw("SELECT name FROM sqlite_master WHERE type='table' AND name=?", migration.DB_VERSION_TABLE).Scan(&dbVersionTable).Error
+	if err != nil {
+		log.Errorf("check db_version table failed: %v", err)
+		return false
+	}
+	if dbVersionTable == "" {
+		return InitTablesWithoutRollBack(db, cfg.SQLitePath)
+	}
+	return UpgradeIfDBVersionNotLatest(db, cfg)
+}
+
 func InitTablesWithoutRollBack(db *gorm.DB, database string) bool {
 	log.Info("init db tables without rollback")
 	err := InitTables(db)
@@ -95,7 +117,7 @@ func UpgradeIfDBVersionNotLatest(db *gorm.DB, cfg MySqlConfig) bool {
 			return false
 		}
 	} else if version != migration.DB_VERSION_EXPECTED {
-		err = ExecuteIssus(db, version)
+		err = ExecuteIssus(db, version, issuCallbacks)
 		if err != nil {
 			return false
 		}
@@ -106,6 +128,9 @@ func UpgradeIfDBVersionNotLatest(db *gorm.DB, cfg MySqlConfig) bool {
 
 func RecreateDatabaseAndInitTables(db *gorm.DB, cfg MySqlConfig) bool {
 	log.Info("recreate database and init tables")
+	if cfg.IsSQLite() {
+		return RecreateSQLiteAndInitTables(db, cfg)
+	}
 	DropDatabase(db, cfg.Database)
 	db = GetConnectionWithoutDatabase(cfg)
 	if db == nil {
@@ -123,3 +148,19 @@ func RecreateDatabaseAndInitTables(db *gorm.DB, cfg MySqlConfig) bool {
 	}
 	return DropDatabaseIfInitTablesFailed(db, cfg.Database)
 }
+
+func RecreateSQLiteAndInitTables(db *gorm.DB, cfg MySqlConfig) bool {
+	var tables []string
+	err := db.Raw("SELECT name FROM sqlite_master WHERE type='table' AND name NOT LIKE 'sqlite_%'").Scan(&tables).Error
+	if err != nil {
+		log.Errorf("get sqlite tables failed: %v", err)
+		return false
+	}
+	for _, table := range tables {
+		if err = db.Exec(fmt.Sprintf("DROP TABLE `%s`", table)).Error; err != nil {
+			log.Errorf("drop table %s failed: %v", table, err)
+			return false
+		}
+	}
+	return InitTablesWithoutRollBack(db, cfg.SQLitePath)
+}
@@ -19,6 +19,7 @@ package mysql
 import (
 	"bytes"
 	"compress/zlib"
+	"crypto/sha256"
 	"database/sql/driver"
 	"errors"
 	"fmt"
@@ -674,6 +675,7 @@ type PodIngress struct {
 	Base           `gorm:"embedded"`
 	SoftDeleteBase `gorm:"embedded"`
 	Name           string `gorm:"column:name;type:varchar(256);default:''" json:"NAME"`
+	Type           int    `gorm:"column:type;type:int;default:1" json:"TYPE"` // 1: Ingress 2: Route 3: HTTPRoute 4: GRPCRoute 5: VirtualService
 	Alias          string `gorm:"column:alias;type:char(64);default:''" json:"ALIAS"`
 	PodNamespaceID int    `gorm:"column:pod_namespace_id;type:int;default:null" json:"POD_NAMESPACE_ID"`
 	PodClusterID   int    `gorm:"column:pod_cluster_id;type:int;default:null" json:"POD_CLUSTER_ID"`
@@ -696,6 +698,7 @@ type PodIngressRuleBackend struct {
 	Base             `gorm:"embedded"`
 	Path             string `gorm:"column:path;type:text;default:''" json:"PATH"`
 	Port             int    `gorm:"column:port;type:int;default:null" json:"PORT"`
+	Weight           int    `gorm:"column:weight;type:int;default:0" json:"WEIGHT"`
 	PodServiceID     int    `gorm:"column:pod_service_id;type:int;default:null" json:"POD_SERVICE_ID"`
 	PodIngressRuleID int    `gorm:"column:pod_ingress_rule_id;type:int;default:null" json:"POD_INGRESS_RULE_ID"`
 	PodIngressID     int    `gorm:"column:pod_ingress_id;type:int;default:null" json:"POD_INGRESS_ID"`
@@ -738,7 +741,7 @@ type PodGroup struct {
 	SoftDeleteBase `gorm:"embedded"`
 	Name           string `gorm:"column:name;type:varchar(256);default:''" json:"NAME"`
 	Alias          string `gorm:"column:alias;type:char(64);default:''" json:"ALIAS"`
-	Type           int    `gorm:"column:type;type:int;default:null" json:"TYPE"` // 1: Deployment 2: StatefulSet 3: ReplicationController
+	Type           int    `gorm:"column:type;type:int;default:null" json:"TYPE"` // 1: Deployment 2: StatefulSet 3: ReplicationController 4: DaemonSet 5: ReplicaSet 6: Job 7: CronJob
 	PodNum         int    `gorm:"column:pod_num;type:int;default:1" json:"POD_NUM"`
 	Label          string `gorm:"column:label;type:text;default:''" json:"LABEL"` // separated by ,
 	PodNamespaceID int    `gorm:"column:pod_namespace_id;type:int;default:null" json:"POD_NAMESPACE_ID"`
@@ -1090,6 +1093,22 @@ func (VTapGroupConfiguration) TableName() string {
 	return "vtap_group_configuration"
 }
 
+// VTapGroupConfigurationRevision 采集器组配置每次修改后的完整内容，用于查看历史、对比及回滚
+type VTapGroupConfigurationRevision struct {
+	ID              int       `gorm:"primaryKey;column:id;type:int;not null" json:"ID"`
+	VTapGroupLcuuid string    `gorm:"column:vtap_group_lcuuid;type:char(64);not null" json:"VTAP_GROUP_LCUUID"`
+	Revision        int       `gorm:"column:revision;type:int;not null" json:"REVISION"`
+	Operation       string    `gorm:"column:operation;type:varchar(16);not null" json:"OPERATION"` // baseline, create, update, delete, rollback
+	Config          string    `gorm:"column:config;type:mediumtext" json:"CONFIG"`                 // yaml, 删除后为空
+	Author          string    `gorm:"column:author;type:varchar(64);default:''" json:"AUTHOR"`
+	Comment         string    `gorm:"column:comment;type:varchar(256);default:''" json:"COMMENT"`
+	CreatedAt       time.Time `gorm:"autoCreateTime;column:created_at;type:datetime" json:"CREATED_AT"`
+}
+
+func (VTapGroupConfigurationRevision) TableName() string {
+	return "vtap_group_configuration_revision"
+}
+
 // VtapGroupConfiguration [...]
 type RVTapGroupConfiguration struct {
 	ID                            int    `gorm:"primaryKey;column:id;type:int;not null" json:"ID"`
@@ -1375,6 +1394,7 @@ type Plugin struct {
 	Name      string          `gorm:"column:name;type:varchar(256);not null" json:"NAME"`
 	Type      int             `gorm:"column:type;type:int" json:"TYPE"` // 1: wasm
 	Image     compressedBytes `gorm:"column:image;type:logblob;not null" json:"IMAGE"`
+	Version   int             `gorm:"column:version;type:int;not null;default:0" json:"VERSION"` // 默认版本，0表示尚未生成版本
 	CreatedAt time.Time       `gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"CREATED_AT"`
 	UpdatedAt time.Time       `gorm:"column:updated_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"UPDATED_AT"`
 }
@@ -1382,3 +1402,23 @@ type Plugin struct {
 func (Plugin) TableName() string {
 	return "plugin"
 }
+
+// PluginVersion 插件的历史版本，创建后不再修改
+type PluginVersion struct {
+	ID         int             `gorm:"primaryKey;column:id;type:int;not null" json:"ID"`
+	PluginName string          `gorm:"column:plugin_name;type:varchar(256);not null" json:"PLUGIN_NAME"`
+	Type       int             `gorm:"column:type;type:int" json:"TYPE"`
+	Version    int             `gorm:"column:version;type:int;not null" json:"VERSION"`
+	Digest     string          `gorm:"column:digest;type:char(64);not null" json:"DIGEST"`
+	Image      compressedBytes `gorm:"column:image;type:logblob;not null" json:"IMAGE"`
+	CreatedAt  time.Time       `gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"CREATED_AT"`
+}
+
+func (PluginVersion) TableName() string {
+	return "plugin_version"
+}
+
+// PluginDigest 返回插件内容的sha256，image为解压后的内容
+func PluginDigest(image []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(image))
+}
@@ -76,6 +76,7 @@ const (
 	PrometheusAPISync
 	GPIDSync
 	GetPrometheusLabelIDs
+	ServerPluginSync
 	MaxApiType
 )
 
@@ -90,6 +91,7 @@ var ApiTypeToName = map[ApiType]string{
 	PrometheusAPISync:      "PrometheusAPISync",
 	GetPrometheusLabelIDs:  "GetPrometheusLabelIDs",
 	GPIDSync:               "GPIDSync",
+	ServerPluginSync:       "ServerPluginSync",
 }
 
 var grpcCounters [MaxApiType]*GrpcCounter
@@ -161,3 +161,11 @@ func (s *service) GetPrometheusLabelIDs(ctx context.Context, in *api.PrometheusL
 func (s *service) Plugin(r *api.PluginRequest, in api.Synchronizer_PluginServer) error {
 	return s.pluginEvent.Plugin(r, in)
 }
+
+func (s *service) ServerPluginSync(ctx context.Context, in *api.ServerPluginSyncRequest) (*api.ServerPluginSyncResponse, error) {
+	startTime := time.Now()
+	defer func() {
+		statsd.AddGrpcCostStatsd(statsd.ServerPluginSync, int(time.Now().Sub(startTime).Milliseconds()))
+	}()
+	return s.pluginEvent.ServerPluginSync(ctx, in)
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package router
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/config"
+	. "github.com/deepflowio/deepflow/server/controller/http/router/common"
+	"github.com/deepflowio/deepflow/server/controller/http/service"
+	"github.com/deepflowio/deepflow/server/controller/model"
+	"github.com/deepflowio/deepflow/server/libs/queue"
+)
+
+func ChangeEventRouter(e *gin.Engine, cfg *config.ControllerConfig, eq *queue.OverwriteQueue) {
+	e.POST("/v1/change-events/", createChangeEvent(cfg, eq))
+}
+
+// 未配置token时拒绝所有请求
+func checkChangeEventToken(c *gin.Context, tokens []string) bool {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	for _, t := range tokens {
+		if t == "" {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func createChangeEvent(cfg *config.ControllerConfig, eq *queue.OverwriteQueue) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		if len(cfg.ChangeEvent.AuthTokens) == 0 {
+			HttpResponse(c, http.StatusUnauthorized, nil, common.FAIL, "change event intake is disabled, configure change-event.auth-tokens to enable it")
+			return
+		}
+		if !checkChangeEventToken(c, cfg.ChangeEvent.AuthTokens) {
+			HttpResponse(c, http.StatusUnauthorized, nil, common.FAIL, "invalid or missing token")
+			return
+		}
+		var changeEventCreate model.ChangeEventCreate
+		if err := c.ShouldBindBodyWith(&changeEventCreate, binding.JSON); err != nil {
+			BadRequestResponse(c, common.INVALID_PARAMETERS, err.Error())
+			return
+		}
+		data, err := service.CreateChangeEvents(&changeEventCreate, cfg.ChangeEvent.MaxTargets, eq)
+		JsonResponse(c, data, err)
+	})
+}
@@ -22,16 +22,24 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 
+	"github.com/deepflowio/deepflow/server/controller/common"
 	"github.com/deepflowio/deepflow/server/controller/db/mysql"
 	. "github.com/deepflowio/deepflow/server/controller/http/router/common"
 	"github.com/deepflowio/deepflow/server/controller/http/service"
+	"github.com/deepflowio/deepflow/server/controller/model"
 )
 
 func PluginRouter(e *gin.Engine) {
 	e.GET("/v1/plugin/", getPlugin)
 	e.POST("/v1/plugin/", createPlugin)
 	e.DELETE("/v1/plugin/:name/", deletePlugin)
+	e.GET("/v1/plugin/:name/version/", getPluginVersions)
+	e.GET("/v1/plugin/:name/agents/", getPluginAgents)
+	e.POST("/v1/plugin/:name/assign/", assignPlugin)
+	e.POST("/v1/plugin/:name/rollout/", rolloutPlugin)
+	e.POST("/v1/plugin/:name/rollback/", rollbackPlugin)
 }
 
 func getPlugin(c *gin.Context) {
@@ -65,7 +73,15 @@ func createPlugin(c *gin.Context) {
 	}
 	plugin.Image = buf.Bytes()
 
-	data, err := service.CreatePlugin(plugin)
+	// ROLLOUT为false时只上传版本，不修改默认版本
+	rollout := true
+	if value := c.PostForm("ROLLOUT"); value != "" {
+		if rollout, err = strconv.ParseBool(value); err != nil {
+			BadRequestResponse(c, common.INVALID_PARAMETERS, err.Error())
+			return
+		}
+	}
+	data, err := service.CreatePlugin(plugin, rollout)
 	JsonResponse(c, data, err)
 }
 
@@ -73,3 +89,48 @@ func deletePlugin(c *gin.Context) {
 	name := c.Param("name")
 	JsonResponse(c, nil, service.DeletePlugin(name))
 }
+
+func getPluginVersions(c *gin.Context) {
+	data, err := service.GetPluginVersions(c.Param("name"))
+	JsonResponse(c, data, err)
+}
+
+func getPluginAgents(c *gin.Context) {
+	data, err := service.GetPluginAgents(c.Param("name"))
+	JsonResponse(c, data, err)
+}
+
+func assignPlugin(c *gin.Context) {
+	var assign model.PluginAssign
+	if err := c.ShouldBindBodyWith(&assign, binding.JSON); err != nil {
+		BadRequestResponse(c, common.INVALID_PARAMETERS, err.Error())
+		return
+	}
+	JsonResponse(c, nil, service.AssignPlugin(c.Param("name"), &assign, getVTapGroupConfigChange(c)))
+}
+
+func rolloutPlugin(c *gin.Context) {
+	var rollout model.PluginRollout
+	if err := c.ShouldBindBodyWith(&rollout, binding.JSON); err != nil {
+		BadRequestResponse(c, common.INVALID_PARAMETERS, err.Error())
+		return
+	}
+	if rollout.Version <= 0 {
+		BadRequestResponse(c, common.INVALID_PARAMETERS, "VERSION must be specified")
+		return
+	}
+	data, err := service.RolloutPlugin(c.Param("name"), rollout.Version)
+	JsonResponse(c, data, err)
+}
+
+func rollbackPlugin(c *gin.Context) {
+	var rollback model.PluginRollout
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindBodyWith(&rollback, binding.JSON); err != nil {
+			BadRequestResponse(c, common.INVALID_PARAMETERS, err.Error())
+			return
+		}
+	}
+	data, err := service.RollbackPlugin(c.Param("name"), rollback.Version)
+	JsonResponse(c, data, err)
+}
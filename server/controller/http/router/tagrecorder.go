@@ -0,0 +1,46 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package router
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	. "github.com/deepflowio/deepflow/server/controller/http/router/common"
+	"github.com/deepflowio/deepflow/server/controller/http/service"
+)
+
+func TagRecorderRouter(e *gin.Engine) {
+	// ClickHouse字典的HTTP数据源，元数据库为SQLite时使用
+	e.GET("/v1/tagrecorder/dictionary/:table/", getTagRecorderDictionary)
+}
+
+func getTagRecorderDictionary(c *gin.Context) {
+	var columns []string
+	if value := c.Query("columns"); value != "" {
+		columns = strings.Split(value, ",")
+	}
+	var buffer bytes.Buffer
+	if err := service.WriteTagRecorderDictionary(&buffer, c.Param("table"), columns); err != nil {
+		JsonResponse(c, nil, err)
+		return
+	}
+	c.Data(http.StatusOK, "text/tab-separated-values; charset=utf-8", buffer.Bytes())
+}
@@ -24,6 +24,7 @@ import (
 	"io"
 	"net/url"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
@@ -31,12 +32,13 @@ import (
 	"github.com/gin-gonic/gin/binding"
 
 	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/config"
 	. "github.com/deepflowio/deepflow/server/controller/http/router/common"
 	"github.com/deepflowio/deepflow/server/controller/http/service"
 	"github.com/deepflowio/deepflow/server/controller/model"
 )
 
-func VtapRouter(e *gin.Engine) {
+func VtapRouter(e *gin.Engine, cfg *config.ControllerConfig) {
 	e.GET("/v1/vtaps/:lcuuid/", getVtap)
 	e.GET("/v1/vtaps/", getVtaps)
 	e.POST("/v1/vtaps/", createVtap)
@@ -46,7 +48,7 @@ func VtapRouter(e *gin.Engine) {
 	e.POST("/v1/vtaps/batch/", batchUpdateVtap)
 	e.DELETE("/v1/vtaps/batch/", batchDeleteVtap)
 
-	e.POST("/v1/rebalance-vtap/", rebalanceVtap)
+	e.POST("/v1/rebalance-vtap/", rebalanceVtap(cfg))
 
 	e.PATCH("/v1/vtaps-license-type/:lcuuid/", updateVtapLicenseType)
 	e.PATCH("/v1/vtaps-license-type/", batchUpdateVtapLicenseType)
@@ -214,27 +216,48 @@ func batchDeleteVtap(c *gin.Context) {
 	JsonResponse(c, data, err)
 }
 
-func rebalanceVtap(c *gin.Context) {
-	args := make(map[string]interface{})
-	args["check"] = false
-	if value, ok := c.GetQuery("check"); ok {
-		args["check"] = (strings.ToLower(value) == "true")
-	}
-	if value, ok := c.GetQuery("type"); ok {
-		args["type"] = value
-		if args["type"] != "controller" && args["type"] != "analyzer" {
-			BadRequestResponse(
-				c, common.INVALID_PARAMETERS,
-				fmt.Sprintf("type (%s) is not supported", args["type"]),
-			)
+func rebalanceVtap(cfg *config.ControllerConfig) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		args := make(map[string]interface{})
+		args["check"] = false
+		if value, ok := c.GetQuery("check"); ok {
+			args["check"] = (strings.ToLower(value) == "true")
+		}
+		if value, ok := c.GetQuery("type"); ok {
+			args["type"] = value
+			if args["type"] != "controller" && args["type"] != "analyzer" {
+				BadRequestResponse(
+					c, common.INVALID_PARAMETERS,
+					fmt.Sprintf("type (%s) is not supported", args["type"]),
+				)
+				return
+			}
+		} else {
+			BadRequestResponse(c, common.INVALID_PARAMETERS, "must specify type")
 			return
 		}
-	} else {
-		BadRequestResponse(c, common.INVALID_PARAMETERS, "must specify type")
-		return
-	}
-	data, err := service.VTapRebalance(args)
-	JsonResponse(c, data, err)
+		if value, ok := c.GetQuery("mode"); ok {
+			if value != service.VTAP_REBALANCE_MODE_AGENT_COUNT && value != service.VTAP_REBALANCE_MODE_TRAFFIC {
+				BadRequestResponse(c, common.INVALID_PARAMETERS, fmt.Sprintf("mode (%s) is not supported", value))
+				return
+			}
+			if value == service.VTAP_REBALANCE_MODE_TRAFFIC && args["type"] != "analyzer" {
+				BadRequestResponse(c, common.INVALID_PARAMETERS, "traffic mode only supports analyzer")
+				return
+			}
+			args["mode"] = value
+		}
+		if value, ok := c.GetQuery("max_moves"); ok {
+			maxMoves, err := strconv.Atoi(value)
+			if err != nil || maxMoves < 0 {
+				BadRequestResponse(c, common.INVALID_PARAMETERS, fmt.Sprintf("max_moves (%s) is invalid", value))
+				return
+			}
+			args["max_moves"] = maxMoves
+		}
+		data, err := service.VTapRebalance(args, cfg.MonitorCfg, cfg.ClickHouseCfg)
+		JsonResponse(c, data, err)
+	})
 }
 
 func batchUpdateVtapTapMode(c *gin.Context) {
@@ -18,10 +18,12 @@ package router
 
 import (
 	"io"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 
+	"github.com/deepflowio/deepflow/server/controller/common"
 	. "github.com/deepflowio/deepflow/server/controller/http/router/common"
 	"github.com/deepflowio/deepflow/server/controller/http/service"
 	"github.com/deepflowio/deepflow/server/controller/model"
@@ -41,13 +43,33 @@ func VTapGroupConfigRouter(e *gin.Engine) {
 
 	e.GET("/v1/vtap-group-configuration/filter/", getVTapGroupConfigByFilter)
 	e.DELETE("/v1/vtap-group-configuration/filter/", deleteVTapGroupConfigByFilter)
+
+	e.GET("/v1/vtap-group-configuration/revision/", getVTapGroupConfigRevisions)
+	e.GET("/v1/vtap-group-configuration/revision/diff/", diffVTapGroupConfigRevisions)
+	e.POST("/v1/vtap-group-configuration/revision/rollback/", rollbackVTapGroupConfig)
+}
+
+// 修改者取自认证后请求头中的用户ID，不接受客户端指定，说明通过query参数comment传入
+func getVTapGroupConfigChange(c *gin.Context) model.VTapGroupConfigChange {
+	return model.VTapGroupConfigChange{Author: c.GetHeader("X-User-Id"), Comment: c.Query("comment")}
+}
+
+func getIntQuery(c *gin.Context, key string) (int, error) {
+	value, ok := c.GetQuery(key)
+	if !ok || value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
 }
 
 func createVTapGroupConfig(c *gin.Context) {
 	vTapGroupConfig := &model.VTapGroupConfiguration{}
 	err := c.ShouldBindBodyWith(&vTapGroupConfig, binding.JSON)
-	if err == nil {
-		data, err := service.CreateVTapGroupConfig(vTapGroupConfig)
+	if err == nil && c.Query("dry_run") == "true" {
+		data, err := service.ValidateVTapGroupConfig("", vTapGroupConfig)
+		JsonResponse(c, data, err)
+	} else if err == nil {
+		data, err := service.CreateVTapGroupConfig(vTapGroupConfig, getVTapGroupConfigChange(c))
 		JsonResponse(c, data, err)
 	} else {
 		JsonResponse(c, nil, err)
@@ -56,7 +78,7 @@ func createVTapGroupConfig(c *gin.Context) {
 
 func deleteVTapGroupConfig(c *gin.Context) {
 	lcuuid := c.Param("lcuuid")
-	data, err := service.DeleteVTapGroupConfig(lcuuid)
+	data, err := service.DeleteVTapGroupConfig(lcuuid, getVTapGroupConfigChange(c))
 	JsonResponse(c, data, err)
 }
 
@@ -64,8 +86,11 @@ func updateVTapGroupConfig(c *gin.Context) {
 	lcuuid := c.Param("lcuuid")
 	vTapGroupConfig := &model.VTapGroupConfiguration{}
 	err := c.ShouldBindBodyWith(&vTapGroupConfig, binding.JSON)
-	if err == nil {
-		data, err := service.UpdateVTapGroupConfig(lcuuid, vTapGroupConfig)
+	if err == nil && c.Query("dry_run") == "true" {
+		data, err := service.ValidateVTapGroupConfig(lcuuid, vTapGroupConfig)
+		JsonResponse(c, data, err)
+	} else if err == nil {
+		data, err := service.UpdateVTapGroupConfig(lcuuid, vTapGroupConfig, getVTapGroupConfigChange(c))
 		JsonResponse(c, data, err)
 	} else {
 		JsonResponse(c, nil, err)
@@ -95,10 +120,14 @@ func getVTapGroupAdvancedConfig(c *gin.Context) {
 
 func updateVTapGroupAdvancedConfig(c *gin.Context) {
 	lcuuid := c.Param("lcuuid")
+	if c.Query("dry_run") == "true" {
+		validateVTapGroupAdvancedConfig(c, lcuuid)
+		return
+	}
 	vTapGroupConfig := &model.VTapGroupConfiguration{}
 	err := c.ShouldBindBodyWith(&vTapGroupConfig, binding.YAML)
 	if err == nil || err == io.EOF {
-		data, err := service.UpdateVTapGroupAdvancedConfig(lcuuid, vTapGroupConfig)
+		data, err := service.UpdateVTapGroupAdvancedConfig(lcuuid, vTapGroupConfig, getVTapGroupConfigChange(c))
 		JsonResponse(c, data, err)
 	} else {
 		JsonResponse(c, nil, err)
@@ -106,10 +135,14 @@ func updateVTapGroupAdvancedConfig(c *gin.Context) {
 }
 
 func createVTapGroupAdvancedConfig(c *gin.Context) {
+	if c.Query("dry_run") == "true" {
+		validateVTapGroupAdvancedConfig(c, "")
+		return
+	}
 	vTapGroupConfig := &model.VTapGroupConfiguration{}
 	err := c.ShouldBindBodyWith(&vTapGroupConfig, binding.YAML)
 	if err == nil {
-		data, err := service.CreateVTapGroupAdvancedConfig(vTapGroupConfig)
+		data, err := service.CreateVTapGroupAdvancedConfig(vTapGroupConfig, getVTapGroupConfigChange(c))
 		JsonResponse(c, data, err)
 	} else {
 		JsonResponse(c, nil, err)
@@ -130,7 +163,7 @@ func deleteVTapGroupConfigByFilter(c *gin.Context) {
 	if value, ok := c.GetQuery("vtap_group_id"); ok {
 		args["vtap_group_id"] = value
 	}
-	data, err := service.DeleteVTapGroupConfigByFilter(args)
+	data, err := service.DeleteVTapGroupConfigByFilter(args, getVTapGroupConfigChange(c))
 	JsonResponse(c, data, err)
 }
 
@@ -143,3 +176,49 @@ func getVTapGroupAdvancedConfigs(c *gin.Context) {
 	data, err := service.GetVTapGroupAdvancedConfigs()
 	JsonResponse(c, data, err)
 }
+
+// dry_run=true时只校验配置，返回校验结果、受影响的采集器及配置差异，基础配置接口同样支持
+func validateVTapGroupAdvancedConfig(c *gin.Context, lcuuid string) {
+	body, err := c.GetRawData()
+	if err != nil {
+		JsonResponse(c, nil, err)
+		return
+	}
+	data, err := service.ValidateVTapGroupAdvancedConfig(lcuuid, body)
+	JsonResponse(c, data, err)
+}
+
+func getVTapGroupConfigRevisions(c *gin.Context) {
+	revision, err := getIntQuery(c, "revision")
+	if err != nil {
+		BadRequestResponse(c, common.INVALID_PARAMETERS, err.Error())
+		return
+	}
+	data, err := service.GetVTapGroupConfigRevisions(c.Query("vtap_group_id"), revision)
+	JsonResponse(c, data, err)
+}
+
+func diffVTapGroupConfigRevisions(c *gin.Context) {
+	from, err := getIntQuery(c, "from")
+	if err != nil {
+		BadRequestResponse(c, common.INVALID_PARAMETERS, err.Error())
+		return
+	}
+	to, err := getIntQuery(c, "to")
+	if err != nil {
+		BadRequestResponse(c, common.INVALID_PARAMETERS, err.Error())
+		return
+	}
+	data, err := service.DiffVTapGroupConfigRevisions(c.Query("vtap_group_id"), from, to)
+	JsonResponse(c, data, err)
+}
+
+func rollbackVTapGroupConfig(c *gin.Context) {
+	revision, err := getIntQuery(c, "revision")
+	if err != nil {
+		BadRequestResponse(c, common.INVALID_PARAMETERS, err.Error())
+		return
+	}
+	data, err := service.RollbackVTapGroupConfig(c.Query("vtap_group_id"), revision, getVTapGroupConfigChange(c))
+	JsonResponse(c, data, err)
+}
@@ -0,0 +1,310 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	. "github.com/deepflowio/deepflow/server/controller/http/service/common"
+	"github.com/deepflowio/deepflow/server/controller/model"
+	"github.com/deepflowio/deepflow/server/libs/eventapi"
+	"github.com/deepflowio/deepflow/server/libs/queue"
+)
+
+// changeEventTarget 变更事件匹配到的资源，以及写入事件表的通用标签
+type changeEventTarget struct {
+	model.ChangeEventTarget
+	regionLcuuid string
+	azLcuuid     string
+	regionID     int
+	azID         int
+	vpcID        int
+	podClusterID int
+	podNSID      int
+	podServiceID int
+	podGroupID   int
+	podNodeID    int
+	podID        int
+}
+
+// CreateChangeEvents 将选择器解析为资源后，为每个资源生成一条变更事件并写入事件队列，由ingester写入event表
+func CreateChangeEvents(create *model.ChangeEventCreate, maxTargets int, eq *queue.OverwriteQueue) ([]model.ChangeEventTarget, error) {
+	if create.PodCluster == "" && create.PodNamespace == "" && create.PodService == "" && create.PodGroup == "" && create.Pod == "" {
+		return nil, NewError(common.INVALID_PARAMETERS, "at least one of POD_CLUSTER, POD_NAMESPACE, POD_SERVICE, POD_GROUP and POD must be specified")
+	}
+	targets, err := resolveChangeEventTargets(mysql.Db, create)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, NewError(common.RESOURCE_NOT_FOUND, "no resource matches the selectors")
+	}
+	if maxTargets > 0 && len(targets) > maxTargets {
+		return nil, NewError(common.SELECTED_RESOURCES_NUM_EXCEEDED, fmt.Sprintf("%d resources match the selectors, exceeds the limit %d", len(targets), maxTargets))
+	}
+	if err := fillChangeEventTargetRegionAndAZ(mysql.Db, targets); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result := make([]model.ChangeEventTarget, 0, len(targets))
+	for i := range targets {
+		event := newChangeEvent(create, &targets[i], now)
+		if err := eq.Put(event); err != nil {
+			log.Errorf("put change event (%+v) into queue failed: %s", event, err)
+			return result, NewError(common.SERVER_ERROR, err.Error())
+		}
+		result = append(result, targets[i].ChangeEventTarget)
+	}
+	log.Infof("change event (type: %s, source: %s) created for %d resources", create.EventType, create.Source, len(result))
+	return result, nil
+}
+
+func findPodResourceIDsByName[T any](db *gorm.DB, resourceType, name string, podClusterIDs, podNamespaceIDs []int) ([]T, error) {
+	var items []T
+	query := db.Where("name = ?", name)
+	if podClusterIDs != nil {
+		query = query.Where("pod_cluster_id IN ?", podClusterIDs)
+	}
+	if podNamespaceIDs != nil {
+		query = query.Where("pod_namespace_id IN ?", podNamespaceIDs)
+	}
+	if err := query.Find(&items).Error; err != nil {
+		return nil, NewError(common.SERVER_ERROR, fmt.Sprintf("query %s (name: %s) failed: %s", resourceType, name, err))
+	}
+	if len(items) == 0 {
+		return nil, NewError(common.RESOURCE_NOT_FOUND, fmt.Sprintf("%s (name: %s) not found", resourceType, name))
+	}
+	return items, nil
+}
+
+// resolveChangeEventTargets 集群、命名空间作为范围过滤，按 Pod > 服务 > 工作负载 > 命名空间 > 集群 的顺序取最细粒度的选择器匹配资源
+func resolveChangeEventTargets(db *gorm.DB, create *model.ChangeEventCreate) ([]changeEventTarget, error) {
+	var targets []changeEventTarget
+
+	var podClusterIDs []int
+	var podClusters []mysql.PodCluster
+	if create.PodCluster != "" {
+		var err error
+		podClusters, err = findPodResourceIDsByName[mysql.PodCluster](db, "pod_cluster", create.PodCluster, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		podClusterIDs = []int{}
+		for _, item := range podClusters {
+			podClusterIDs = append(podClusterIDs, item.ID)
+		}
+	}
+
+	var podNamespaceIDs []int
+	var podNamespaces []mysql.PodNamespace
+	if create.PodNamespace != "" {
+		var err error
+		podNamespaces, err = findPodResourceIDsByName[mysql.PodNamespace](db, "pod_namespace", create.PodNamespace, podClusterIDs, nil)
+		if err != nil {
+			return nil, err
+		}
+		podNamespaceIDs = []int{}
+		for _, item := range podNamespaces {
+			podNamespaceIDs = append(podNamespaceIDs, item.ID)
+		}
+	}
+
+	switch {
+	case create.Pod != "":
+		pods, err := findPodResourceIDsByName[mysql.Pod](db, "pod", create.Pod, podClusterIDs, podNamespaceIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range pods {
+			targets = append(targets, changeEventTarget{
+				ChangeEventTarget: model.ChangeEventTarget{InstanceType: common.VIF_DEVICE_TYPE_POD, InstanceID: item.ID, InstanceName: item.Name},
+				regionLcuuid:      item.Region,
+				azLcuuid:          item.AZ,
+				vpcID:             item.VPCID,
+				podClusterID:      item.PodClusterID,
+				podNSID:           item.PodNamespaceID,
+				podGroupID:        item.PodGroupID,
+				podNodeID:         item.PodNodeID,
+				podID:             item.ID,
+			})
+		}
+	case create.PodService != "":
+		podServices, err := findPodResourceIDsByName[mysql.PodService](db, "pod_service", create.PodService, podClusterIDs, podNamespaceIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range podServices {
+			targets = append(targets, changeEventTarget{
+				ChangeEventTarget: model.ChangeEventTarget{InstanceType: common.VIF_DEVICE_TYPE_POD_SERVICE, InstanceID: item.ID, InstanceName: item.Name},
+				regionLcuuid:      item.Region,
+				azLcuuid:          item.AZ,
+				vpcID:             item.VPCID,
+				podClusterID:      item.PodClusterID,
+				podNSID:           item.PodNamespaceID,
+				podServiceID:      item.ID,
+			})
+		}
+	case create.PodGroup != "":
+		podGroups, err := findPodResourceIDsByName[mysql.PodGroup](db, "pod_group", create.PodGroup, podClusterIDs, podNamespaceIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range podGroups {
+			targets = append(targets, changeEventTarget{
+				ChangeEventTarget: model.ChangeEventTarget{InstanceType: common.VIF_DEVICE_TYPE_POD_GROUP, InstanceID: item.ID, InstanceName: item.Name},
+				regionLcuuid:      item.Region,
+				azLcuuid:          item.AZ,
+				podClusterID:      item.PodClusterID,
+				podNSID:           item.PodNamespaceID,
+				podGroupID:        item.ID,
+			})
+		}
+	case create.PodNamespace != "":
+		for _, item := range podNamespaces {
+			targets = append(targets, changeEventTarget{
+				ChangeEventTarget: model.ChangeEventTarget{InstanceType: common.POD_NAMESPACE_INSTANCE_TYPE, InstanceID: item.ID, InstanceName: item.Name},
+				regionLcuuid:      item.Region,
+				azLcuuid:          item.AZ,
+				podClusterID:      item.PodClusterID,
+				podNSID:           item.ID,
+			})
+		}
+	default:
+		for _, item := range podClusters {
+			targets = append(targets, changeEventTarget{
+				ChangeEventTarget: model.ChangeEventTarget{InstanceType: common.POD_CLUSTER_INSTANCE_TYPE, InstanceID: item.ID, InstanceName: item.Name},
+				regionLcuuid:      item.Region,
+				azLcuuid:          item.AZ,
+				vpcID:             item.VPCID,
+				podClusterID:      item.ID,
+			})
+		}
+	}
+
+	// 工作负载、命名空间没有VPC信息，使用所属集群的VPC
+	var clusterIDs []int
+	for _, t := range targets {
+		if t.vpcID == 0 && t.podClusterID != 0 {
+			clusterIDs = append(clusterIDs, t.podClusterID)
+		}
+	}
+	if len(clusterIDs) > 0 {
+		var clusters []mysql.PodCluster
+		if err := db.Where("id IN ?", clusterIDs).Find(&clusters).Error; err != nil {
+			return nil, NewError(common.SERVER_ERROR, fmt.Sprintf("query pod_cluster failed: %s", err))
+		}
+		clusterIDToVPCID := make(map[int]int, len(clusters))
+		for _, c := range clusters {
+			clusterIDToVPCID[c.ID] = c.VPCID
+		}
+		for i := range targets {
+			if targets[i].vpcID == 0 {
+				targets[i].vpcID = clusterIDToVPCID[targets[i].podClusterID]
+			}
+		}
+	}
+	return targets, nil
+}
+
+func fillChangeEventTargetRegionAndAZ(db *gorm.DB, targets []changeEventTarget) error {
+	var regionLcuuids, azLcuuids []string
+	for _, t := range targets {
+		regionLcuuids = append(regionLcuuids, t.regionLcuuid)
+		azLcuuids = append(azLcuuids, t.azLcuuid)
+	}
+	var regions []mysql.Region
+	if err := db.Where("lcuuid IN ?", regionLcuuids).Find(&regions).Error; err != nil {
+		return NewError(common.SERVER_ERROR, fmt.Sprintf("query region failed: %s", err))
+	}
+	var azs []mysql.AZ
+	if err := db.Where("lcuuid IN ?", azLcuuids).Find(&azs).Error; err != nil {
+		return NewError(common.SERVER_ERROR, fmt.Sprintf("query az failed: %s", err))
+	}
+	regionLcuuidToID := make(map[string]int, len(regions))
+	for _, r := range regions {
+		regionLcuuidToID[r.Lcuuid] = r.ID
+	}
+	azLcuuidToID := make(map[string]int, len(azs))
+	for _, a := range azs {
+		azLcuuidToID[a.Lcuuid] = a.ID
+	}
+	for i := range targets {
+		targets[i].regionID = regionLcuuidToID[targets[i].regionLcuuid]
+		targets[i].azID = azLcuuidToID[targets[i].azLcuuid]
+	}
+	return nil
+}
+
+func newChangeEvent(create *model.ChangeEventCreate, target *changeEventTarget, now time.Time) *eventapi.ResourceEvent {
+	eventTime := now
+	if create.Time > 0 {
+		eventTime = time.Unix(create.Time, 0)
+	}
+	opts := []eventapi.TagFieldOption{
+		eventapi.TagDescription(create.Description),
+		eventapi.TagRegionID(target.regionID),
+		eventapi.TagAZID(target.azID),
+		eventapi.TagVPCID(target.vpcID),
+		eventapi.TagPodClusterID(target.podClusterID),
+		eventapi.TagPodNSID(target.podNSID),
+		eventapi.TagPodServiceID(target.podServiceID),
+		eventapi.TagPodGroupID(target.podGroupID),
+		eventapi.TagPodNodeID(target.podNodeID),
+		eventapi.TagPodID(target.podID),
+	}
+	for _, attr := range []struct{ name, value string }{
+		{"version", create.Version},
+		{"author", create.Author},
+		{"source", create.Source},
+		{"links", strings.Join(create.Links, ",")},
+	} {
+		if attr.value != "" {
+			opts = append(opts, eventapi.TagAttribute(attr.name, attr.value))
+		}
+	}
+	names := make([]string, 0, len(create.Attributes))
+	for name := range create.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		opts = append(opts, eventapi.TagAttribute(name, create.Attributes[name]))
+	}
+
+	// use interface in eventapi to create ResourceEvent instance which will be enqueued, because we need to manually free instance memory
+	event := eventapi.AcquireResourceEvent()
+	event.Time = eventTime.Unix()
+	event.TimeMilli = eventTime.UnixMilli()
+	event.Type = create.EventType
+	event.InstanceType = uint32(target.InstanceType)
+	event.InstanceID = uint32(target.InstanceID)
+	event.InstanceName = target.InstanceName
+	event.IsChangeEvent = true
+	event.IfNeedTagged = false
+	for _, option := range opts {
+		option(event)
+	}
+	return event
+}
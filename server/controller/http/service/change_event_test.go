@@ -0,0 +1,165 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	"github.com/deepflowio/deepflow/server/controller/model"
+	"github.com/deepflowio/deepflow/server/libs/eventapi"
+)
+
+func TestNewChangeEvent(t *testing.T) {
+	now := time.Unix(1690000000, 0)
+	create := &model.ChangeEventCreate{
+		EventType:   eventapi.CHANGE_EVENT_TYPE_DEPLOY,
+		Description: "deploy productpage",
+		PodService:  "productpage",
+		Version:     "v1.2.0",
+		Author:      "tom",
+		Links:       []string{"https://ci/1", "https://ci/2"},
+		Attributes:  map[string]string{"env": "prod", "commit": "a1b2c3"},
+	}
+	target := &changeEventTarget{
+		ChangeEventTarget: model.ChangeEventTarget{InstanceType: common.VIF_DEVICE_TYPE_POD_SERVICE, InstanceID: 3, InstanceName: "productpage"},
+		regionID:          1,
+		vpcID:             2,
+		podClusterID:      4,
+		podNSID:           5,
+		podServiceID:      3,
+	}
+
+	event := newChangeEvent(create, target, now)
+	defer event.Release()
+	if event.Time != now.Unix() || event.Type != eventapi.CHANGE_EVENT_TYPE_DEPLOY || !event.IsChangeEvent {
+		t.Errorf("newChangeEvent() = %+v", event)
+	}
+	if event.IfNeedTagged || event.PodServiceID != 3 || event.PodNSID != 5 || event.VPCID != 2 || event.InstanceType != common.VIF_DEVICE_TYPE_POD_SERVICE {
+		t.Errorf("newChangeEvent() tags = %+v", event)
+	}
+	wantNames := []string{"version", "author", "links", "commit", "env"}
+	wantValues := []string{"v1.2.0", "tom", "https://ci/1,https://ci/2", "a1b2c3", "prod"}
+	if !reflect.DeepEqual(event.AttributeNames, wantNames) || !reflect.DeepEqual(event.AttributeValues, wantValues) {
+		t.Errorf("newChangeEvent() attributes = %v %v, want %v %v", event.AttributeNames, event.AttributeValues, wantNames, wantValues)
+	}
+
+	create.Time = 1680000000
+	event2 := newChangeEvent(create, target, now)
+	defer event2.Release()
+	if event2.Time != create.Time {
+		t.Errorf("newChangeEvent() time = %d, want %d", event2.Time, create.Time)
+	}
+}
+
+func newChangeEventTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{NamingStrategy: schema.NamingStrategy{SingularTable: true}})
+	if err != nil {
+		t.Fatalf("create sqlite database failed: %s", err)
+	}
+	if err := db.AutoMigrate(&mysql.PodCluster{}, &mysql.PodNamespace{}, &mysql.PodService{}, &mysql.PodGroup{}, &mysql.Pod{}); err != nil {
+		t.Fatalf("migrate sqlite database failed: %s", err)
+	}
+	// 两个集群下有同名的命名空间和Pod
+	db.Create(&mysql.PodCluster{Base: mysql.Base{ID: 1, Lcuuid: "c1"}, Name: "prod", VPCID: 11})
+	db.Create(&mysql.PodCluster{Base: mysql.Base{ID: 2, Lcuuid: "c2"}, Name: "test", VPCID: 12})
+	db.Create(&mysql.PodNamespace{Base: mysql.Base{ID: 3, Lcuuid: "ns1"}, Name: "default", PodClusterID: 1})
+	db.Create(&mysql.PodNamespace{Base: mysql.Base{ID: 4, Lcuuid: "ns2"}, Name: "default", PodClusterID: 2})
+	db.Create(&mysql.PodGroup{Base: mysql.Base{ID: 5, Lcuuid: "pg1"}, Name: "web", PodNamespaceID: 3, PodClusterID: 1})
+	db.Create(&mysql.Pod{Base: mysql.Base{ID: 6, Lcuuid: "pod1"}, Name: "web-0", PodGroupID: 5, PodNamespaceID: 3, PodClusterID: 1, VPCID: 11})
+	db.Create(&mysql.Pod{Base: mysql.Base{ID: 7, Lcuuid: "pod2"}, Name: "web-0", PodNamespaceID: 4, PodClusterID: 2, VPCID: 12})
+	return db
+}
+
+func TestResolveChangeEventTargets(t *testing.T) {
+	db := newChangeEventTestDB(t)
+	tests := []struct {
+		name   string
+		create model.ChangeEventCreate
+		want   []model.ChangeEventTarget
+		vpcIDs []int
+	}{
+		{
+			name:   "pod in all clusters",
+			create: model.ChangeEventCreate{Pod: "web-0"},
+			want: []model.ChangeEventTarget{
+				{InstanceType: common.VIF_DEVICE_TYPE_POD, InstanceID: 6, InstanceName: "web-0"},
+				{InstanceType: common.VIF_DEVICE_TYPE_POD, InstanceID: 7, InstanceName: "web-0"},
+			},
+			vpcIDs: []int{11, 12},
+		},
+		{
+			name:   "pod scoped by cluster",
+			create: model.ChangeEventCreate{PodCluster: "test", Pod: "web-0"},
+			want:   []model.ChangeEventTarget{{InstanceType: common.VIF_DEVICE_TYPE_POD, InstanceID: 7, InstanceName: "web-0"}},
+			vpcIDs: []int{12},
+		},
+		{
+			name:   "pod group uses vpc of cluster",
+			create: model.ChangeEventCreate{PodNamespace: "default", PodGroup: "web"},
+			want:   []model.ChangeEventTarget{{InstanceType: common.VIF_DEVICE_TYPE_POD_GROUP, InstanceID: 5, InstanceName: "web"}},
+			vpcIDs: []int{11},
+		},
+		{
+			name:   "namespace scoped by cluster",
+			create: model.ChangeEventCreate{PodCluster: "prod", PodNamespace: "default"},
+			want:   []model.ChangeEventTarget{{InstanceType: common.POD_NAMESPACE_INSTANCE_TYPE, InstanceID: 3, InstanceName: "default"}},
+			vpcIDs: []int{11},
+		},
+		{
+			name:   "cluster",
+			create: model.ChangeEventCreate{PodCluster: "test"},
+			want:   []model.ChangeEventTarget{{InstanceType: common.POD_CLUSTER_INSTANCE_TYPE, InstanceID: 2, InstanceName: "test"}},
+			vpcIDs: []int{12},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets, err := resolveChangeEventTargets(db, &tt.create)
+			if err != nil {
+				t.Fatalf("resolveChangeEventTargets() error = %s", err)
+			}
+			var got []model.ChangeEventTarget
+			var vpcIDs []int
+			for _, target := range targets {
+				got = append(got, target.ChangeEventTarget)
+				vpcIDs = append(vpcIDs, target.vpcID)
+			}
+			if !reflect.DeepEqual(got, tt.want) || !reflect.DeepEqual(vpcIDs, tt.vpcIDs) {
+				t.Errorf("resolveChangeEventTargets() = %v %v, want %v %v", got, vpcIDs, tt.want, tt.vpcIDs)
+			}
+		})
+	}
+
+	// 范围内不存在的资源
+	for _, create := range []model.ChangeEventCreate{
+		{Pod: "web-1"},
+		{PodCluster: "test", PodGroup: "web"},
+		{PodCluster: "dev", Pod: "web-0"},
+	} {
+		if _, err := resolveChangeEventTargets(db, &create); err == nil {
+			t.Errorf("resolveChangeEventTargets(%+v) expected error", create)
+		}
+	}
+}
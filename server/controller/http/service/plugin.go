@@ -19,37 +19,89 @@ package service
 import (
 	"errors"
 	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v2"
+	"gorm.io/gorm"
 
 	"github.com/deepflowio/deepflow/server/controller/common"
 	"github.com/deepflowio/deepflow/server/controller/db/mysql"
 	. "github.com/deepflowio/deepflow/server/controller/http/service/common"
 	"github.com/deepflowio/deepflow/server/controller/model"
-	"gorm.io/gorm"
+	"github.com/deepflowio/deepflow/server/controller/trisolaris/refresh"
 )
 
-func CreatePlugin(pluginCreate *mysql.Plugin) (*model.Plugin, error) {
-	var pluginFirst mysql.Plugin
-	if err := mysql.Db.Where("name = ?", pluginCreate.Name).First(&pluginFirst).Error; err != nil {
-		if !errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, NewError(common.SERVER_ERROR,
+func getPluginByName(db *gorm.DB, name string) (*mysql.Plugin, error) {
+	var plugin mysql.Plugin
+	if err := db.Where("name = ?", name).First(&plugin).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, NewError(common.RESOURCE_NOT_FOUND, fmt.Sprintf("plugin (name: %s) not found", name))
+		}
+		return nil, NewError(common.SERVER_ERROR, fmt.Sprintf("fail to query plugin by name(%s), error: %s", name, err))
+	}
+	return &plugin, nil
+}
+
+// CreatePlugin 上传插件的新版本，内容与已有版本相同时复用该版本
+// rollout为false时只保存版本，由采集器组指定该版本进行灰度，之后再通过RolloutPlugin设为默认版本
+func CreatePlugin(pluginCreate *mysql.Plugin, rollout bool) (*model.Plugin, error) {
+	digest := mysql.PluginDigest(pluginCreate.Image)
+	err := mysql.Db.Transaction(func(tx *gorm.DB) error {
+		var pluginFirst mysql.Plugin
+		err := tx.Where("name = ?", pluginCreate.Name).First(&pluginFirst).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return NewError(common.SERVER_ERROR,
 				fmt.Sprintf("fail to query plugin by name(%s), error: %s", pluginCreate.Name, err))
 		}
+		exist := err == nil
 
-		if err = mysql.Db.Create(&pluginCreate).Error; err != nil {
-			return nil, err
+		var pluginVersion mysql.PluginVersion
+		err = tx.Where("plugin_name = ? AND type = ? AND digest = ?", pluginCreate.Name, pluginCreate.Type, digest).First(&pluginVersion).Error
+		if err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			var latest mysql.PluginVersion
+			if err := tx.Where("plugin_name = ?", pluginCreate.Name).Order("version DESC").
+				Limit(1).Find(&latest).Error; err != nil {
+				return err
+			}
+			pluginVersion = mysql.PluginVersion{
+				PluginName: pluginCreate.Name,
+				Type:       pluginCreate.Type,
+				Version:    latest.Version + 1,
+				Digest:     digest,
+				Image:      pluginCreate.Image,
+			}
+			if err := tx.Create(&pluginVersion).Error; err != nil {
+				return err
+			}
+			log.Infof("create plugin (name: %s) version %d, digest: %s", pluginCreate.Name, pluginVersion.Version, digest)
 		}
-		plugines, _ := GetPlugin(map[string]interface{}{"name": pluginCreate.Name})
-		return &plugines[0], nil
-	}
 
-	// update by name and type
-	if err := mysql.Db.Model(&mysql.Plugin{}).Where("name = ?", pluginCreate.Name).
-		Updates(pluginCreate).Error; err != nil {
+		// 新插件没有可回退的版本，总是使用上传的版本
+		if !exist {
+			pluginCreate.Version = pluginVersion.Version
+			return tx.Create(&pluginCreate).Error
+		}
+		if !rollout {
+			return nil
+		}
+		return updatePluginDefaultVersion(tx, &pluginVersion)
+	})
+	if err != nil {
 		return nil, err
 	}
+	refresh.RefreshCache([]string{common.VTAP_CHANGED})
+	return getPluginModel(pluginCreate.Name)
+}
 
-	plugins, _ := GetPlugin(map[string]interface{}{"name": pluginCreate.Name})
-	return &plugins[0], nil
+// plugin表保存默认版本的内容，未指定版本的采集器和数据节点使用该内容
+func updatePluginDefaultVersion(db *gorm.DB, pluginVersion *mysql.PluginVersion) error {
+	return db.Model(&mysql.Plugin{}).Where("name = ?", pluginVersion.PluginName).
+		Select("type", "image", "version").
+		Updates(&mysql.Plugin{Type: pluginVersion.Type, Image: pluginVersion.Image, Version: pluginVersion.Version}).Error
 }
 
 func GetPlugin(filter map[string]interface{}) ([]model.Plugin, error) {
@@ -61,29 +113,297 @@ func GetPlugin(filter map[string]interface{}) ([]model.Plugin, error) {
 	if _, ok := filter["type"]; ok {
 		db = db.Where("type = ?", filter["type"])
 	}
-	db.Order("updated_at DESC").Find(&plugins)
+	if err := db.Order("updated_at DESC").Find(&plugins).Error; err != nil {
+		return nil, NewError(common.SERVER_ERROR, fmt.Sprintf("query plugins failed, error: %s", err))
+	}
 
 	var resp []model.Plugin
 	for _, plugin := range plugins {
 		temp := model.Plugin{
 			Name:      plugin.Name,
 			Type:      plugin.Type,
+			Version:   plugin.Version,
 			UpdatedAt: plugin.UpdatedAt.Format(common.GO_BIRTHDAY),
 		}
 		resp = append(resp, temp)
 	}
 	return resp, nil
+}
 
+// getPluginModel 返回插件name的当前信息，插件已被删除时返回RESOURCE_NOT_FOUND
+func getPluginModel(name string) (*model.Plugin, error) {
+	plugins, err := GetPlugin(map[string]interface{}{"name": name})
+	if err != nil {
+		return nil, err
+	}
+	if len(plugins) == 0 {
+		return nil, NewError(common.RESOURCE_NOT_FOUND, fmt.Sprintf("plugin (name: %s) not found", name))
+	}
+	return &plugins[0], nil
 }
 
+// DeletePlugin 删除插件及其所有版本
 func DeletePlugin(name string) error {
 	var plugin model.Plugin
 	if err := mysql.Db.Where("name = ?", name).First(&plugin).Error; err != nil {
 		return NewError(common.RESOURCE_NOT_FOUND, fmt.Sprintf("plugin (name: %s) not found", name))
 	}
 
-	if err := mysql.Db.Where("name = ?", name).Delete(&mysql.Plugin{}).Error; err != nil {
-		return NewError(common.SERVER_ERROR, fmt.Sprintf("delete plugin (name: %s) failed, err: %v", name, err))
+	return mysql.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("name = ?", name).Delete(&mysql.Plugin{}).Error; err != nil {
+			return NewError(common.SERVER_ERROR, fmt.Sprintf("delete plugin (name: %s) failed, err: %v", name, err))
+		}
+		if err := tx.Where("plugin_name = ?", name).Delete(&mysql.PluginVersion{}).Error; err != nil {
+			return NewError(common.SERVER_ERROR, fmt.Sprintf("delete plugin (name: %s) versions failed, err: %v", name, err))
+		}
+		return nil
+	})
+}
+
+type pluginGroupConfig struct {
+	group        *mysql.VTapGroup
+	config       *mysql.VTapGroupConfiguration
+	staticConfig *model.StaticConfig
+}
+
+// 读取所有采集器组的高级配置，key为采集器组lcuuid
+func getPluginGroupConfigs() (map[string]*pluginGroupConfig, error) {
+	var groups []*mysql.VTapGroup
+	if err := mysql.Db.Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	var configs []*mysql.VTapGroupConfiguration
+	if err := mysql.Db.Find(&configs).Error; err != nil {
+		return nil, err
+	}
+	lcuuidToConfig := make(map[string]*mysql.VTapGroupConfiguration, len(configs))
+	for _, config := range configs {
+		if config.VTapGroupLcuuid != nil {
+			lcuuidToConfig[*config.VTapGroupLcuuid] = config
+		}
+	}
+	groupConfigs := make(map[string]*pluginGroupConfig, len(groups))
+	for _, group := range groups {
+		groupConfig := &pluginGroupConfig{
+			group:        group,
+			config:       lcuuidToConfig[group.Lcuuid],
+			staticConfig: &model.StaticConfig{},
+		}
+		if groupConfig.config != nil && groupConfig.config.YamlConfig != nil {
+			if err := yaml.Unmarshal([]byte(*groupConfig.config.YamlConfig), groupConfig.staticConfig); err != nil {
+				log.Warningf("unmarshal vtap group (short_uuid: %s) yaml config failed: %s", group.ShortUUID, err)
+			}
+		}
+		groupConfigs[group.Lcuuid] = groupConfig
+	}
+	return groupConfigs, nil
+}
+
+// 返回采集器组配置中插件name使用的版本，不存在时ok为false
+func lookupPluginVersion(wasmPlugins []string, name string) (version int, ok bool) {
+	for _, wasmPlugin := range wasmPlugins {
+		n, v, err := common.ParsePluginName(wasmPlugin)
+		if err == nil && n == name {
+			return v, true
+		}
 	}
+	return 0, false
+}
+
+// 将插件name在wasmPlugins中的版本设为version，不存在时追加
+func setPluginVersion(wasmPlugins []string, name string, version int) []string {
+	entry := common.FormatPluginName(name, version)
+	for i, wasmPlugin := range wasmPlugins {
+		if n, _, err := common.ParsePluginName(wasmPlugin); err == nil && n == name {
+			wasmPlugins[i] = entry
+			return wasmPlugins
+		}
+	}
+	return append(wasmPlugins, entry)
+}
+
+func GetPluginVersions(name string) ([]model.PluginVersion, error) {
+	plugin, err := getPluginByName(mysql.Db, name)
+	if err != nil {
+		return nil, err
+	}
+	var pluginVersions []mysql.PluginVersion
+	if err := mysql.Db.Where("plugin_name = ?", name).Order("version DESC").Find(&pluginVersions).Error; err != nil {
+		return nil, NewError(common.SERVER_ERROR, fmt.Sprintf("query plugin (name: %s) versions failed, err: %s", name, err))
+	}
+	groupConfigs, err := getPluginGroupConfigs()
+	if err != nil {
+		return nil, NewError(common.SERVER_ERROR, err.Error())
+	}
+	versionToGroupIDs := make(map[int][]string)
+	for _, groupConfig := range groupConfigs {
+		if version, ok := lookupPluginVersion(groupConfig.staticConfig.WasmPlugins, name); ok && version != common.PLUGIN_VERSION_DEFAULT {
+			versionToGroupIDs[version] = append(versionToGroupIDs[version], groupConfig.group.ShortUUID)
+		}
+	}
+
+	resp := make([]model.PluginVersion, 0, len(pluginVersions))
+	for _, pluginVersion := range pluginVersions {
+		groupIDs := versionToGroupIDs[pluginVersion.Version]
+		sort.Strings(groupIDs)
+		resp = append(resp, model.PluginVersion{
+			Name:         name,
+			Version:      pluginVersion.Version,
+			Digest:       pluginVersion.Digest,
+			Size:         len(pluginVersion.Image),
+			IsDefault:    pluginVersion.Version == plugin.Version,
+			VTapGroupIDs: groupIDs,
+			CreatedAt:    pluginVersion.CreatedAt.Format(common.GO_BIRTHDAY),
+		})
+	}
+	return resp, nil
+}
+
+// AssignPlugin 在采集器组的高级配置中启用插件，version为0时跟随默认版本，否则固定使用该版本
+func AssignPlugin(name string, assign *model.PluginAssign, change model.VTapGroupConfigChange) error {
+	if _, err := getPluginByName(mysql.Db, name); err != nil {
+		return err
+	}
+	if assign.Version != common.PLUGIN_VERSION_DEFAULT {
+		var pluginVersion mysql.PluginVersion
+		if err := mysql.Db.Where("plugin_name = ? AND version = ?", name, assign.Version).First(&pluginVersion).Error; err != nil {
+			return NewError(common.RESOURCE_NOT_FOUND, fmt.Sprintf("plugin (name: %s) version %d not found", name, assign.Version))
+		}
+	}
+
+	entry := common.FormatPluginName(name, assign.Version)
+	if change.Comment == "" {
+		change.Comment = fmt.Sprintf("assign plugin %s", entry)
+	}
+	// 所有采集器组的配置在同一事务中修改，任一采集器组失败时都不生效
+	err := mysql.Db.Transaction(func(tx *gorm.DB) error {
+		for _, groupID := range assign.VTapGroupIDs {
+			vtapGroup := &mysql.VTapGroup{}
+			if err := tx.Where("short_uuid = ?", groupID).First(vtapGroup).Error; err != nil {
+				return NewError(common.RESOURCE_NOT_FOUND, fmt.Sprintf("vtap group (short_uuid: %s) not found", groupID))
+			}
+			dbConfig := &mysql.VTapGroupConfiguration{}
+			err := tx.Where("vtap_group_lcuuid = ?", vtapGroup.Lcuuid).First(dbConfig).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return NewError(common.SERVER_ERROR, fmt.Sprintf("query vtap group (short_uuid: %s) configuration failed, err: %s", groupID, err))
+			}
+			before := vtapGroupConfigToYaml(dbConfig)
+			staticConfig := &model.StaticConfig{}
+			if dbConfig.YamlConfig != nil {
+				if err := yaml.Unmarshal([]byte(*dbConfig.YamlConfig), staticConfig); err != nil {
+					return NewError(common.SERVER_ERROR, fmt.Sprintf("unmarshal vtap group (short_uuid: %s) yaml config failed, err: %s", groupID, err))
+				}
+			}
+			staticConfig.WasmPlugins = setPluginVersion(staticConfig.WasmPlugins, name, assign.Version)
+			b, err := yaml.Marshal(staticConfig)
+			if err != nil {
+				return NewError(common.SERVER_ERROR, err.Error())
+			}
+			yamlConfig := string(b)
+			dbConfig.YamlConfig = &yamlConfig
+			if dbConfig.Lcuuid == nil {
+				lcuuid := uuid.New().String()
+				dbConfig.Lcuuid = &lcuuid
+				dbConfig.VTapGroupLcuuid = &vtapGroup.Lcuuid
+			}
+			if err := tx.Save(dbConfig).Error; err != nil {
+				return NewError(common.SERVER_ERROR, fmt.Sprintf("save vtap group (short_uuid: %s) configuration failed, err: %s", groupID, err))
+			}
+			if err := recordVTapGroupConfigRevision(tx, vtapGroup.Lcuuid, REVISION_OPERATION_UPDATE, before, vtapGroupConfigToYaml(dbConfig), change); err != nil {
+				return NewError(common.SERVER_ERROR, err.Error())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	log.Infof("assign plugin %s to vtap groups %v", entry, assign.VTapGroupIDs)
+	refresh.RefreshCache([]string{common.VTAP_CHANGED})
 	return nil
 }
+
+// RolloutPlugin 将插件的指定版本设为默认版本，未固定版本的采集器组会切换到该版本
+func RolloutPlugin(name string, version int) (*model.Plugin, error) {
+	plugin, err := getPluginByName(mysql.Db, name)
+	if err != nil {
+		return nil, err
+	}
+	var pluginVersion mysql.PluginVersion
+	if err := mysql.Db.Where("plugin_name = ? AND version = ?", name, version).First(&pluginVersion).Error; err != nil {
+		return nil, NewError(common.RESOURCE_NOT_FOUND, fmt.Sprintf("plugin (name: %s) version %d not found", name, version))
+	}
+	if err := updatePluginDefaultVersion(mysql.Db, &pluginVersion); err != nil {
+		return nil, NewError(common.SERVER_ERROR, fmt.Sprintf("rollout plugin (name: %s) version %d failed, err: %s", name, version, err))
+	}
+	log.Infof("plugin (name: %s) default version %d -> %d", name, plugin.Version, version)
+	refresh.RefreshCache([]string{common.VTAP_CHANGED})
+	return getPluginModel(name)
+}
+
+// RollbackPlugin 回退插件的默认版本，version为0时回退到当前默认版本的上一个版本
+func RollbackPlugin(name string, version int) (*model.Plugin, error) {
+	if version == common.PLUGIN_VERSION_DEFAULT {
+		plugin, err := getPluginByName(mysql.Db, name)
+		if err != nil {
+			return nil, err
+		}
+		var previous mysql.PluginVersion
+		if err := mysql.Db.Where("plugin_name = ? AND version < ?", name, plugin.Version).
+			Order("version DESC").First(&previous).Error; err != nil {
+			return nil, NewError(common.RESOURCE_NOT_FOUND,
+				fmt.Sprintf("plugin (name: %s) has no version before %d", name, plugin.Version))
+		}
+		version = previous.Version
+	}
+	return RolloutPlugin(name, version)
+}
+
+// GetPluginAgents 返回启用了插件的采集器及其使用的版本
+func GetPluginAgents(name string) ([]model.PluginAgent, error) {
+	plugin, err := getPluginByName(mysql.Db, name)
+	if err != nil {
+		return nil, err
+	}
+	var pluginVersions []mysql.PluginVersion
+	if err := mysql.Db.Select("version", "digest").Where("plugin_name = ?", name).Find(&pluginVersions).Error; err != nil {
+		return nil, NewError(common.SERVER_ERROR, fmt.Sprintf("query plugin (name: %s) versions failed, err: %s", name, err))
+	}
+	versionToDigest := make(map[int]string, len(pluginVersions))
+	for _, pluginVersion := range pluginVersions {
+		versionToDigest[pluginVersion.Version] = pluginVersion.Digest
+	}
+	groupConfigs, err := getPluginGroupConfigs()
+	if err != nil {
+		return nil, NewError(common.SERVER_ERROR, err.Error())
+	}
+	var vtaps []*mysql.VTap
+	if err := mysql.Db.Order("name").Find(&vtaps).Error; err != nil {
+		return nil, NewError(common.SERVER_ERROR, err.Error())
+	}
+
+	resp := []model.PluginAgent{}
+	for _, vtap := range vtaps {
+		groupConfig, ok := groupConfigs[vtap.VtapGroupLcuuid]
+		if !ok {
+			continue
+		}
+		version, ok := lookupPluginVersion(groupConfig.staticConfig.WasmPlugins, name)
+		if !ok {
+			continue
+		}
+		pinned := version != common.PLUGIN_VERSION_DEFAULT
+		if !pinned {
+			version = plugin.Version
+		}
+		resp = append(resp, model.PluginAgent{
+			VTapName:      vtap.Name,
+			VTapGroupID:   groupConfig.group.ShortUUID,
+			VTapGroupName: groupConfig.group.Name,
+			Version:       version,
+			Digest:        versionToDigest[version],
+			Pinned:        pinned,
+		})
+	}
+	return resp, nil
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetPluginVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		wasmPlugins []string
+		plugin      string
+		version     int
+		want        []string
+	}{
+		{
+			name:        "append",
+			wasmPlugins: []string{"a"},
+			plugin:      "b",
+			version:     2,
+			want:        []string{"a", "b@2"},
+		},
+		{
+			name:        "pin version",
+			wasmPlugins: []string{"a", "b"},
+			plugin:      "a",
+			version:     3,
+			want:        []string{"a@3", "b"},
+		},
+		{
+			name:        "follow default version",
+			wasmPlugins: []string{"a@3", "b"},
+			plugin:      "a",
+			version:     0,
+			want:        []string{"a", "b"},
+		},
+		{
+			name:        "name contains separator",
+			wasmPlugins: []string{"a@b@1"},
+			plugin:      "a@b",
+			version:     2,
+			want:        []string{"a@b@2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := setPluginVersion(tt.wasmPlugins, tt.plugin, tt.version); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("setPluginVersion() = %v, want %v", got, tt.want)
+			}
+			if version, ok := lookupPluginVersion(tt.want, tt.plugin); !ok || version != tt.version {
+				t.Errorf("lookupPluginVersion() = %d, %t, want %d", version, ok, tt.version)
+			}
+		})
+	}
+}
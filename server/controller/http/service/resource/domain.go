@@ -314,7 +314,7 @@ func CreateDomain(domainCreate model.DomainCreate, cfg *config.ControllerConfig)
 		}
 		createKubernetesRelatedResources(domain, regionLcuuid)
 	}
-	mysql.Db.Clauses(clause.Insert{Modifier: "IGNORE"}).Create(&domain)
+	mysql.Db.Clauses(clause.OnConflict{DoNothing: true}).Create(&domain)
 
 	response, _ := GetDomains(map[string]interface{}{"lcuuid": lcuuid})
 	return &response[0], nil
@@ -330,7 +330,7 @@ func createKubernetesRelatedResources(domain mysql.Domain, regionLcuuid string)
 	az.Domain = domain.Lcuuid
 	az.Region = regionLcuuid
 	az.CreateMethod = common.CREATE_METHOD_LEARN
-	err := mysql.Db.Clauses(clause.Insert{Modifier: "IGNORE"}).Create(&az).Error
+	err := mysql.Db.Clauses(clause.OnConflict{DoNothing: true}).Create(&az).Error
 	if err != nil {
 		log.Errorf("create az failed: %s", err)
 	}
@@ -340,7 +340,7 @@ func createKubernetesRelatedResources(domain mysql.Domain, regionLcuuid string)
 	vpc.Domain = domain.Lcuuid
 	vpc.Region = regionLcuuid
 	vpc.CreateMethod = common.CREATE_METHOD_LEARN
-	err = mysql.Db.Clauses(clause.Insert{Modifier: "IGNORE"}).Create(&vpc).Error
+	err = mysql.Db.Clauses(clause.OnConflict{DoNothing: true}).Create(&vpc).Error
 	if err != nil {
 		log.Errorf("create vpc failed: %s", err)
 	}
@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	. "github.com/deepflowio/deepflow/server/controller/http/service/common"
+)
+
+var (
+	dictionaryTableRegexp  = regexp.MustCompile(`^ch_[a-z0-9_]+$`)
+	dictionaryColumnRegexp = regexp.MustCompile(`^[a-z0-9_]+$`)
+	tsvEscaper             = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r")
+)
+
+// WriteTagRecorderDictionary 以TabSeparatedWithNames格式输出ch_*表，作为ClickHouse字典的HTTP数据源
+func WriteTagRecorderDictionary(w io.Writer, table string, columns []string) error {
+	if !dictionaryTableRegexp.MatchString(table) {
+		return NewError(common.INVALID_PARAMETERS, fmt.Sprintf("table (%s) is not a tagrecorder table", table))
+	}
+	for _, column := range columns {
+		if !dictionaryColumnRegexp.MatchString(column) {
+			return NewError(common.INVALID_PARAMETERS, fmt.Sprintf("column (%s) is invalid", column))
+		}
+	}
+	db := mysql.Db.Table(table)
+	if len(columns) > 0 {
+		db = db.Select(columns)
+	}
+	rows, err := db.Rows()
+	if err != nil {
+		return NewError(common.SERVER_ERROR, err.Error())
+	}
+	defer rows.Close()
+	names, err := rows.Columns()
+	if err != nil {
+		return NewError(common.SERVER_ERROR, err.Error())
+	}
+
+	writer := bufio.NewWriter(w)
+	writer.WriteString(strings.Join(names, "\t"))
+	writer.WriteByte('\n')
+	values := make([]interface{}, len(names))
+	pointers := make([]interface{}, len(names))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return NewError(common.SERVER_ERROR, err.Error())
+		}
+		for i, value := range values {
+			if i > 0 {
+				writer.WriteByte('\t')
+			}
+			switch v := value.(type) {
+			case nil:
+				writer.WriteString("\\N")
+			case []byte:
+				writer.WriteString(tsvEscaper.Replace(string(v)))
+			case string:
+				writer.WriteString(tsvEscaper.Replace(v))
+			case time.Time:
+				writer.WriteString(v.Format(common.GO_BIRTHDAY))
+			default:
+				writer.WriteString(fmt.Sprint(v))
+			}
+		}
+		writer.WriteByte('\n')
+	}
+	if err := rows.Err(); err != nil {
+		return NewError(common.SERVER_ERROR, err.Error())
+	}
+	return writer.Flush()
+}
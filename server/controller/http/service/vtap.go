@@ -28,9 +28,11 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/db/clickhouse"
 	"github.com/deepflowio/deepflow/server/controller/db/mysql"
 	. "github.com/deepflowio/deepflow/server/controller/http/service/common"
 	"github.com/deepflowio/deepflow/server/controller/model"
+	monitorconf "github.com/deepflowio/deepflow/server/controller/monitor/config"
 	"github.com/deepflowio/deepflow/server/controller/monitor/license"
 	"github.com/deepflowio/deepflow/server/controller/trisolaris/utils"
 	vtapop "github.com/deepflowio/deepflow/server/controller/trisolaris/vtap"
@@ -659,11 +661,11 @@ func vtapControllerRebalance(azs []mysql.AZ, ifCheck bool) (*model.VTapRebalance
 	return &response, nil
 }
 
-func vtapAnalyzerRebalance(azs []mysql.AZ, ifCheck bool) (*model.VTapRebalanceResult, error) {
+// getAZAnalyzerVTaps 获取各可用区的采集器及可分配的数据节点
+func getAZAnalyzerVTaps(azs []mysql.AZ) (map[string][]*mysql.VTap, map[string][]*mysql.Analyzer, error) {
 	var analyzers []mysql.Analyzer
 	var azAnalyzerConns []mysql.AZAnalyzerConnection
 	var vtaps []mysql.VTap
-	var response model.VTapRebalanceResult
 
 	mysql.Db.Find(&analyzers)
 	mysql.Db.Find(&azAnalyzerConns)
@@ -689,7 +691,7 @@ func vtapAnalyzerRebalance(azs []mysql.AZ, ifCheck bool) (*model.VTapRebalanceRe
 	}
 	if normalAnalyzerNum == 0 {
 		errMsg := "No available analyzers，Global equalization is not possible"
-		return nil, NewError(common.SERVER_ERROR, errMsg)
+		return nil, nil, NewError(common.SERVER_ERROR, errMsg)
 	}
 
 	azToAnalyzers := make(map[string][]*mysql.Analyzer)
@@ -710,6 +712,16 @@ func vtapAnalyzerRebalance(azs []mysql.AZ, ifCheck bool) (*model.VTapRebalanceRe
 			}
 		}
 	}
+	return azToVTaps, azToAnalyzers, nil
+}
+
+func vtapAnalyzerRebalance(azs []mysql.AZ, ifCheck bool) (*model.VTapRebalanceResult, error) {
+	var response model.VTapRebalanceResult
+
+	azToVTaps, azToAnalyzers, err := getAZAnalyzerVTaps(azs)
+	if err != nil {
+		return nil, err
+	}
 
 	// 遍历可用区，进行数据节点均衡
 	for _, az := range azs {
@@ -754,7 +766,7 @@ func vtapAnalyzerRebalance(azs []mysql.AZ, ifCheck bool) (*model.VTapRebalanceRe
 	return &response, nil
 }
 
-func VTapRebalance(args map[string]interface{}) (*model.VTapRebalanceResult, error) {
+func VTapRebalance(args map[string]interface{}, cfg monitorconf.MonitorConfig, chCfg clickhouse.ClickHouseConfig) (*model.VTapRebalanceResult, error) {
 	var azs []mysql.AZ
 
 	hostType := "controller"
@@ -767,9 +779,26 @@ func VTapRebalance(args map[string]interface{}) (*model.VTapRebalanceResult, err
 		ifCheck = argsCheck.(bool)
 	}
 
+	// 按流量均衡仅用于数据节点，未指定时使用配置文件中的方式
+	mode := cfg.RebalanceMode
+	if argsMode, ok := args["mode"]; ok {
+		mode = argsMode.(string)
+	}
+	maxMoves := cfg.RebalanceMaxMoves
+	if argsMaxMoves, ok := args["max_moves"]; ok {
+		maxMoves = argsMaxMoves.(int)
+	}
+
 	mysql.Db.Find(&azs)
 	if hostType == "controller" {
 		return vtapControllerRebalance(azs, ifCheck)
+	} else if mode == VTAP_REBALANCE_MODE_TRAFFIC {
+		vtapIDToTraffic, err := getVTapTraffic(chCfg, cfg.RebalanceTrafficWindow)
+		if err != nil {
+			log.Error(err)
+			return nil, NewError(common.SERVER_ERROR, fmt.Sprintf("get vtap traffic failed: %s", err.Error()))
+		}
+		return vtapAnalyzerTrafficRebalance(azs, ifCheck, cfg.RebalanceHysteresis, maxMoves, vtapIDToTraffic)
 	} else {
 		return vtapAnalyzerRebalance(azs, ifCheck)
 	}
@@ -24,6 +24,7 @@ import (
 
 	"github.com/google/uuid"
 	"gopkg.in/yaml.v2"
+	"gorm.io/gorm"
 
 	"github.com/deepflowio/deepflow/server/controller/common"
 	"github.com/deepflowio/deepflow/server/controller/db/mysql"
@@ -329,7 +330,7 @@ func convertToDb(sData *model.VTapGroupConfiguration, tData *mysql.VTapGroupConf
 	}
 }
 
-func CreateVTapGroupConfig(createData *model.VTapGroupConfiguration) (*mysql.VTapGroupConfiguration, error) {
+func CreateVTapGroupConfig(createData *model.VTapGroupConfiguration, change model.VTapGroupConfigChange) (*mysql.VTapGroupConfiguration, error) {
 	if createData.VTapGroupLcuuid == nil {
 		return nil, fmt.Errorf("vtap_group_lcuuid is emty")
 	}
@@ -351,12 +352,20 @@ func CreateVTapGroupConfig(createData *model.VTapGroupConfiguration) (*mysql.VTa
 	dbData.VTapGroupLcuuid = createData.VTapGroupLcuuid
 	lcuuid := uuid.New().String()
 	dbData.Lcuuid = &lcuuid
-	mysql.Db.Create(dbData)
+	err := mysql.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(dbData).Error; err != nil {
+			return fmt.Errorf("create config failed, %s", err)
+		}
+		return recordVTapGroupConfigRevision(tx, vTapGroupLcuuid, REVISION_OPERATION_CREATE, "", vtapGroupConfigToYaml(dbData), change)
+	})
+	if err != nil {
+		return nil, err
+	}
 	refresh.RefreshCache([]string{common.VTAP_CHANGED})
 	return dbData, nil
 }
 
-func DeleteVTapGroupConfig(lcuuid string) (*mysql.VTapGroupConfiguration, error) {
+func DeleteVTapGroupConfig(lcuuid string, change model.VTapGroupConfigChange) (*mysql.VTapGroupConfiguration, error) {
 	if lcuuid == "" {
 		return nil, fmt.Errorf("lcuuid is None")
 	}
@@ -367,12 +376,23 @@ func DeleteVTapGroupConfig(lcuuid string) (*mysql.VTapGroupConfiguration, error)
 	if ret.Error != nil {
 		return nil, fmt.Errorf("vtap group configuration(%s) not found", lcuuid)
 	}
-	db.Delete(dbConfig)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(dbConfig).Error; err != nil {
+			return fmt.Errorf("delete config failed, %s", err)
+		}
+		if dbConfig.VTapGroupLcuuid == nil {
+			return nil
+		}
+		return recordVTapGroupConfigRevision(tx, *dbConfig.VTapGroupLcuuid, REVISION_OPERATION_DELETE, vtapGroupConfigToYaml(dbConfig), "", change)
+	})
+	if err != nil {
+		return nil, err
+	}
 	refresh.RefreshCache([]string{common.VTAP_CHANGED})
 	return dbConfig, nil
 }
 
-func UpdateVTapGroupConfig(lcuuid string, updateData *model.VTapGroupConfiguration) (*mysql.VTapGroupConfiguration, error) {
+func UpdateVTapGroupConfig(lcuuid string, updateData *model.VTapGroupConfiguration, change model.VTapGroupConfigChange) (*mysql.VTapGroupConfiguration, error) {
 	if lcuuid == "" {
 		return nil, fmt.Errorf("lcuuid is None")
 	}
@@ -383,10 +403,19 @@ func UpdateVTapGroupConfig(lcuuid string, updateData *model.VTapGroupConfigurati
 	if ret.Error != nil {
 		return nil, fmt.Errorf("vtap group configuration(%s) not found", lcuuid)
 	}
+	before := vtapGroupConfigToYaml(dbConfig)
 	convertJsonToDb(updateData, dbConfig)
-	ret = db.Save(dbConfig)
-	if ret.Error != nil {
-		return nil, fmt.Errorf("save config failed, %s", ret.Error)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(dbConfig).Error; err != nil {
+			return fmt.Errorf("save config failed, %s", err)
+		}
+		if dbConfig.VTapGroupLcuuid == nil {
+			return nil
+		}
+		return recordVTapGroupConfigRevision(tx, *dbConfig.VTapGroupLcuuid, REVISION_OPERATION_UPDATE, before, vtapGroupConfigToYaml(dbConfig), change)
+	})
+	if err != nil {
+		return nil, err
 	}
 	refresh.RefreshCache([]string{common.VTAP_CHANGED})
 	return dbConfig, nil
@@ -579,18 +608,15 @@ func GetVTapGroupAdvancedConfigs() ([]string, error) {
 	return result, nil
 }
 
-func UpdateVTapGroupAdvancedConfig(lcuuid string, updateData *model.VTapGroupConfiguration) (string, error) {
+func UpdateVTapGroupAdvancedConfig(lcuuid string, updateData *model.VTapGroupConfiguration, change model.VTapGroupConfigChange) (string, error) {
 	db := mysql.Db
 	dbConfig := &mysql.VTapGroupConfiguration{}
 	ret := db.Where("lcuuid = ?", lcuuid).First(dbConfig)
 	if ret.Error != nil {
 		return "", fmt.Errorf("vtap group configuration(%s) not found", lcuuid)
 	}
+	before := vtapGroupConfigToYaml(dbConfig)
 	convertYamlToDb(updateData, dbConfig)
-	ret = db.Save(dbConfig)
-	if ret.Error != nil {
-		return "", fmt.Errorf("save config failed, %s", ret.Error)
-	}
 	response := &model.VTapGroupConfiguration{}
 	convertDBToYaml(dbConfig, response)
 	b, err := yaml.Marshal(response)
@@ -600,11 +626,23 @@ func UpdateVTapGroupAdvancedConfig(lcuuid string, updateData *model.VTapGroupCon
 	if string(b) == string(emptyData) {
 		b = nil
 	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(dbConfig).Error; err != nil {
+			return fmt.Errorf("save config failed, %s", err)
+		}
+		if dbConfig.VTapGroupLcuuid == nil {
+			return nil
+		}
+		return recordVTapGroupConfigRevision(tx, *dbConfig.VTapGroupLcuuid, REVISION_OPERATION_UPDATE, before, string(b), change)
+	})
+	if err != nil {
+		return "", err
+	}
 	refresh.RefreshCache([]string{common.VTAP_CHANGED})
 	return string(b), nil
 }
 
-func CreateVTapGroupAdvancedConfig(createData *model.VTapGroupConfiguration) (string, error) {
+func CreateVTapGroupAdvancedConfig(createData *model.VTapGroupConfiguration, change model.VTapGroupConfigChange) (string, error) {
 	if createData.VTapGroupID == nil {
 		return "", fmt.Errorf("vtap_group_id is None")
 	}
@@ -624,9 +662,14 @@ func CreateVTapGroupAdvancedConfig(createData *model.VTapGroupConfiguration) (st
 	dbConfig.VTapGroupLcuuid = &vtapGroup.Lcuuid
 	lcuuid := uuid.New().String()
 	dbConfig.Lcuuid = &lcuuid
-	ret = db.Save(dbConfig)
-	if ret.Error != nil {
-		return "", fmt.Errorf("save config failed, %s", ret.Error)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(dbConfig).Error; err != nil {
+			return fmt.Errorf("save config failed, %s", err)
+		}
+		return recordVTapGroupConfigRevision(tx, vtapGroup.Lcuuid, REVISION_OPERATION_CREATE, "", vtapGroupConfigToYaml(dbConfig), change)
+	})
+	if err != nil {
+		return "", err
 	}
 	response := &model.VTapGroupConfiguration{}
 	convertDBToYaml(dbConfig, response)
@@ -665,7 +708,7 @@ func GetVTapGroupConfigByFilter(args map[string]string) (string, error) {
 	return string(b), nil
 }
 
-func DeleteVTapGroupConfigByFilter(args map[string]string) (string, error) {
+func DeleteVTapGroupConfigByFilter(args map[string]string, change model.VTapGroupConfigChange) (string, error) {
 	shortUUID := args["vtap_group_id"]
 	if shortUUID == "" {
 		return "", fmt.Errorf("short uuid is None")
@@ -682,7 +725,15 @@ func DeleteVTapGroupConfigByFilter(args map[string]string) (string, error) {
 	if ret.Error != nil {
 		return "", fmt.Errorf("vtap group(short_uuid=%s) configuration not found", shortUUID)
 	}
-	db.Delete(dbConfig)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(dbConfig).Error; err != nil {
+			return fmt.Errorf("delete config failed, %s", err)
+		}
+		return recordVTapGroupConfigRevision(tx, vtapGroup.Lcuuid, REVISION_OPERATION_DELETE, vtapGroupConfigToYaml(dbConfig), "", change)
+	})
+	if err != nil {
+		return "", err
+	}
 	response := &model.VTapGroupConfiguration{}
 	convertDBToYaml(dbConfig, response)
 	response.VTapGroupID = &shortUUID
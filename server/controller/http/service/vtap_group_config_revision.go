@@ -0,0 +1,433 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pmezard/go-difflib/difflib"
+	"gopkg.in/yaml.v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	. "github.com/deepflowio/deepflow/server/controller/http/service/common"
+	"github.com/deepflowio/deepflow/server/controller/model"
+	"github.com/deepflowio/deepflow/server/controller/trisolaris/refresh"
+)
+
+const (
+	REVISION_OPERATION_BASELINE = "baseline" // 首次记录修订时，保存修改前的配置
+	REVISION_OPERATION_CREATE   = "create"
+	REVISION_OPERATION_UPDATE   = "update"
+	REVISION_OPERATION_DELETE   = "delete"
+	REVISION_OPERATION_ROLLBACK = "rollback"
+)
+
+var vtapGroupConfigLogLevels = []string{"DEBUG", "INFO", "WARNING", "ERROR"}
+
+// 配置的完整yaml，与高级配置接口返回的内容一致
+func vtapGroupConfigToYaml(dbConfig *mysql.VTapGroupConfiguration) string {
+	if dbConfig == nil {
+		return ""
+	}
+	response := &model.VTapGroupConfiguration{}
+	convertDBToYaml(dbConfig, response)
+	b, err := yaml.Marshal(response)
+	if err != nil {
+		log.Error(err)
+		return ""
+	}
+	if string(b) == string(emptyData) {
+		return ""
+	}
+	return string(b)
+}
+
+// 记录配置修改后的内容，before为修改前的配置，采集器组首次记录时作为基线保存
+// 需要与配置的修改在同一事务tx中执行，通过锁定采集器组保证并发修改时修订号连续
+func recordVTapGroupConfigRevision(tx *gorm.DB, vtapGroupLcuuid, operation, before, after string, change model.VTapGroupConfigChange) error {
+	if vtapGroupLcuuid == "" {
+		return nil
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("lcuuid = ?", vtapGroupLcuuid).
+		First(&mysql.VTapGroup{}).Error; err != nil {
+		return fmt.Errorf("lock vtap group (lcuuid: %s) failed: %s", vtapGroupLcuuid, err)
+	}
+	var latest mysql.VTapGroupConfigurationRevision
+	if err := tx.Where("vtap_group_lcuuid = ?", vtapGroupLcuuid).Order("revision DESC").Limit(1).Find(&latest).Error; err != nil {
+		return fmt.Errorf("query vtap group (lcuuid: %s) configuration revision failed: %s", vtapGroupLcuuid, err)
+	}
+	revision := latest.Revision
+	if revision == 0 && operation != REVISION_OPERATION_CREATE && before != "" {
+		baseline := &mysql.VTapGroupConfigurationRevision{
+			VTapGroupLcuuid: vtapGroupLcuuid,
+			Revision:        1,
+			Operation:       REVISION_OPERATION_BASELINE,
+			Config:          before,
+		}
+		if err := tx.Create(baseline).Error; err != nil {
+			return fmt.Errorf("create vtap group (lcuuid: %s) configuration baseline revision failed: %s", vtapGroupLcuuid, err)
+		}
+		revision = 1
+	} else if revision > 0 && operation != REVISION_OPERATION_DELETE && after == latest.Config &&
+		latest.Operation != REVISION_OPERATION_DELETE {
+		// 内容未变化
+		return nil
+	}
+	record := &mysql.VTapGroupConfigurationRevision{
+		VTapGroupLcuuid: vtapGroupLcuuid,
+		Revision:        revision + 1,
+		Operation:       operation,
+		Config:          after,
+		Author:          change.Author,
+		Comment:         change.Comment,
+	}
+	if err := tx.Create(record).Error; err != nil {
+		return fmt.Errorf("create vtap group (lcuuid: %s) configuration revision %d failed: %s", vtapGroupLcuuid, record.Revision, err)
+	}
+	log.Infof("vtap group (lcuuid: %s) configuration revision %d (%s) by %s: %s",
+		vtapGroupLcuuid, record.Revision, operation, change.Author, change.Comment)
+	return nil
+}
+
+func getVTapGroupByShortUUID(shortUUID string) (*mysql.VTapGroup, error) {
+	vtapGroup := &mysql.VTapGroup{}
+	if err := mysql.Db.Where("short_uuid = ?", shortUUID).First(vtapGroup).Error; err != nil {
+		return nil, NewError(common.RESOURCE_NOT_FOUND, fmt.Sprintf("vtap group(short_uuid=%s) not found", shortUUID))
+	}
+	return vtapGroup, nil
+}
+
+func getVTapGroupConfigRevision(vtapGroup *mysql.VTapGroup, revision int) (*mysql.VTapGroupConfigurationRevision, error) {
+	record := &mysql.VTapGroupConfigurationRevision{}
+	db := mysql.Db.Where("vtap_group_lcuuid = ?", vtapGroup.Lcuuid)
+	if revision > 0 {
+		db = db.Where("revision = ?", revision)
+	} else {
+		db = db.Order("revision DESC")
+	}
+	if err := db.First(record).Error; err != nil {
+		if revision > 0 {
+			return nil, NewError(common.RESOURCE_NOT_FOUND,
+				fmt.Sprintf("vtap group(short_uuid=%s) configuration revision %d not found", vtapGroup.ShortUUID, revision))
+		}
+		return nil, NewError(common.RESOURCE_NOT_FOUND,
+			fmt.Sprintf("vtap group(short_uuid=%s) has no configuration revision", vtapGroup.ShortUUID))
+	}
+	return record, nil
+}
+
+func convertRevisionToModel(vtapGroup *mysql.VTapGroup, record *mysql.VTapGroupConfigurationRevision, withConfig bool) model.VTapGroupConfigRevision {
+	revision := model.VTapGroupConfigRevision{
+		VTapGroupID: vtapGroup.ShortUUID,
+		Revision:    record.Revision,
+		Operation:   record.Operation,
+		Author:      record.Author,
+		Comment:     record.Comment,
+		CreatedAt:   record.CreatedAt.Format(common.GO_BIRTHDAY),
+	}
+	if withConfig {
+		revision.Config = record.Config
+	}
+	return revision
+}
+
+// GetVTapGroupConfigRevisions 返回采集器组配置的修订历史，指定revision时返回该修订及其配置内容
+func GetVTapGroupConfigRevisions(shortUUID string, revision int) ([]model.VTapGroupConfigRevision, error) {
+	vtapGroup, err := getVTapGroupByShortUUID(shortUUID)
+	if err != nil {
+		return nil, err
+	}
+	if revision > 0 {
+		record, err := getVTapGroupConfigRevision(vtapGroup, revision)
+		if err != nil {
+			return nil, err
+		}
+		return []model.VTapGroupConfigRevision{convertRevisionToModel(vtapGroup, record, true)}, nil
+	}
+
+	var records []*mysql.VTapGroupConfigurationRevision
+	if err := mysql.Db.Select("revision", "operation", "author", "comment", "created_at").
+		Where("vtap_group_lcuuid = ?", vtapGroup.Lcuuid).Order("revision DESC").Find(&records).Error; err != nil {
+		return nil, NewError(common.SERVER_ERROR, err.Error())
+	}
+	result := make([]model.VTapGroupConfigRevision, 0, len(records))
+	for _, record := range records {
+		result = append(result, convertRevisionToModel(vtapGroup, record, false))
+	}
+	return result, nil
+}
+
+func diffVTapGroupConfig(from, to, fromName, toName string) string {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(from),
+		B:        difflib.SplitLines(to),
+		FromFile: fromName,
+		ToFile:   toName,
+		Context:  3,
+	})
+	if err != nil {
+		log.Error(err)
+	}
+	return diff
+}
+
+// DiffVTapGroupConfigRevisions 对比两个修订的配置，to为0时使用最新的修订，from为0时使用to的上一个修订
+func DiffVTapGroupConfigRevisions(shortUUID string, from, to int) (string, error) {
+	vtapGroup, err := getVTapGroupByShortUUID(shortUUID)
+	if err != nil {
+		return "", err
+	}
+	toRecord, err := getVTapGroupConfigRevision(vtapGroup, to)
+	if err != nil {
+		return "", err
+	}
+	if from == 0 {
+		from = toRecord.Revision - 1
+	}
+	fromConfig := ""
+	if from > 0 {
+		fromRecord, err := getVTapGroupConfigRevision(vtapGroup, from)
+		if err != nil {
+			return "", err
+		}
+		fromConfig = fromRecord.Config
+	}
+	return diffVTapGroupConfig(fromConfig, toRecord.Config,
+		fmt.Sprintf("revision %d", from), fmt.Sprintf("revision %d", toRecord.Revision)), nil
+}
+
+// RollbackVTapGroupConfig 将采集器组配置恢复为指定修订的内容，并记录为新的修订
+func RollbackVTapGroupConfig(shortUUID string, revision int, change model.VTapGroupConfigChange) (string, error) {
+	vtapGroup, err := getVTapGroupByShortUUID(shortUUID)
+	if err != nil {
+		return "", err
+	}
+	if revision <= 0 {
+		return "", NewError(common.INVALID_PARAMETERS, "revision must be specified")
+	}
+	record, err := getVTapGroupConfigRevision(vtapGroup, revision)
+	if err != nil {
+		return "", err
+	}
+	if change.Comment == "" {
+		change.Comment = fmt.Sprintf("rollback to revision %d", revision)
+	}
+
+	data := &model.VTapGroupConfiguration{}
+	if record.Operation != REVISION_OPERATION_DELETE {
+		if err := yaml.Unmarshal([]byte(record.Config), data); err != nil {
+			return "", NewError(common.SERVER_ERROR,
+				fmt.Sprintf("unmarshal vtap group(short_uuid=%s) configuration revision %d failed, %s", shortUUID, revision, err))
+		}
+	}
+
+	after := ""
+	err = mysql.Db.Transaction(func(tx *gorm.DB) error {
+		dbConfig := &mysql.VTapGroupConfiguration{}
+		err := tx.Where("vtap_group_lcuuid = ?", vtapGroup.Lcuuid).First(dbConfig).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return NewError(common.SERVER_ERROR, err.Error())
+		}
+		exist := err == nil
+		before := ""
+		if exist {
+			before = vtapGroupConfigToYaml(dbConfig)
+		}
+
+		if record.Operation == REVISION_OPERATION_DELETE {
+			if exist {
+				if err := tx.Delete(dbConfig).Error; err != nil {
+					return NewError(common.SERVER_ERROR, fmt.Sprintf("delete config failed, %s", err))
+				}
+			}
+		} else {
+			convertYamlToDb(data, dbConfig)
+			if !exist {
+				lcuuid := uuid.New().String()
+				dbConfig.Lcuuid = &lcuuid
+				dbConfig.VTapGroupLcuuid = &vtapGroup.Lcuuid
+			}
+			if err := tx.Save(dbConfig).Error; err != nil {
+				return NewError(common.SERVER_ERROR, fmt.Sprintf("save config failed, %s", err))
+			}
+			after = vtapGroupConfigToYaml(dbConfig)
+		}
+		if err := recordVTapGroupConfigRevision(tx, vtapGroup.Lcuuid, REVISION_OPERATION_ROLLBACK, before, after, change); err != nil {
+			return NewError(common.SERVER_ERROR, err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	refresh.RefreshCache([]string{common.VTAP_CHANGED})
+	return after, nil
+}
+
+// 检查yaml中无法通过结构定义表达的约束
+func checkVTapGroupConfig(data *model.VTapGroupConfiguration) []string {
+	var errs []string
+	checkRegex := func(name string, value *string) {
+		if value == nil || *value == "" {
+			return
+		}
+		if _, err := regexp.Compile(*value); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: invalid regex %q, %s", name, *value, err))
+		}
+	}
+	checkRegex("tap_interface_regex", data.TapInterfaceRegex)
+	checkRegex("extra_netns_regex", data.ExtraNetnsRegex)
+	if data.YamlConfig != nil {
+		for i, osProcRegex := range data.YamlConfig.OsProcRegex {
+			if osProcRegex != nil {
+				checkRegex(fmt.Sprintf("static_config.os-proc-regex[%d].match-regex", i), osProcRegex.MatchRegex)
+			}
+		}
+		for i, wasmPlugin := range data.YamlConfig.WasmPlugins {
+			if _, _, err := common.ParsePluginName(wasmPlugin); err != nil {
+				errs = append(errs, fmt.Sprintf("static_config.wasm-plugins[%d]: %s", i, err))
+			}
+		}
+	}
+	if data.LogLevel != nil && !common.Contains(vtapGroupConfigLogLevels, *data.LogLevel) {
+		errs = append(errs, fmt.Sprintf("log_level: %q not in %v", *data.LogLevel, vtapGroupConfigLogLevels))
+	}
+
+	// 开关类配置只能为0或1
+	tv := reflect.ValueOf(data).Elem()
+	tt := tv.Type()
+	for i := 0; i < tt.NumField(); i++ {
+		name := strings.Split(tt.Field(i).Tag.Get("yaml"), ",")[0]
+		if !strings.HasSuffix(name, "_enabled") {
+			continue
+		}
+		if value, ok := tv.Field(i).Interface().(*int); ok && value != nil && *value != 0 && *value != 1 {
+			errs = append(errs, fmt.Sprintf("%s: %d not in [0, 1]", name, *value))
+		}
+	}
+	return errs
+}
+
+// ValidateVTapGroupAdvancedConfig 校验高级配置而不保存，返回校验错误、受影响的采集器及与当前配置的差异
+// lcuuid为配置的lcuuid，为空时根据yaml中的vtap_group_id查找采集器组
+func ValidateVTapGroupAdvancedConfig(lcuuid string, body []byte) (*model.VTapGroupConfigValidation, error) {
+	result := &model.VTapGroupConfigValidation{Errors: []string{}, AffectedVTaps: []string{}}
+	data := &model.VTapGroupConfiguration{}
+	if err := yaml.UnmarshalStrict(body, data); err != nil {
+		if typeErr, ok := err.(*yaml.TypeError); ok {
+			result.Errors = append(result.Errors, typeErr.Errors...)
+		} else {
+			result.Errors = append(result.Errors, err.Error())
+		}
+		return result, nil
+	}
+	result.Errors = append(result.Errors, checkVTapGroupConfig(data)...)
+
+	db := mysql.Db
+	var vtapGroup *mysql.VTapGroup
+	dbConfig := &mysql.VTapGroupConfiguration{}
+	if lcuuid != "" {
+		if err := db.Where("lcuuid = ?", lcuuid).First(dbConfig).Error; err != nil {
+			return nil, NewError(common.RESOURCE_NOT_FOUND, fmt.Sprintf("vtap group configuration(%s) not found", lcuuid))
+		}
+		vtapGroup = &mysql.VTapGroup{}
+		if dbConfig.VTapGroupLcuuid == nil || db.Where("lcuuid = ?", *dbConfig.VTapGroupLcuuid).First(vtapGroup).Error != nil {
+			return nil, NewError(common.RESOURCE_NOT_FOUND, fmt.Sprintf("vtap group of configuration(%s) not found", lcuuid))
+		}
+	} else {
+		if data.VTapGroupID == nil {
+			return nil, NewError(common.INVALID_PARAMETERS, "vtap_group_id is None")
+		}
+		var err error
+		if vtapGroup, err = getVTapGroupByShortUUID(*data.VTapGroupID); err != nil {
+			return nil, err
+		}
+		if db.Where("vtap_group_lcuuid = ?", vtapGroup.Lcuuid).First(dbConfig).Error != nil {
+			dbConfig = nil
+		}
+	}
+
+	proposedConfig := &mysql.VTapGroupConfiguration{}
+	convertYamlToDb(data, proposedConfig)
+	if err := fillVTapGroupConfigValidation(result, vtapGroup, dbConfig, proposedConfig); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// 填充校验结果中与当前配置的差异及受影响的采集器，dbConfig为nil表示当前没有配置
+func fillVTapGroupConfigValidation(result *model.VTapGroupConfigValidation, vtapGroup *mysql.VTapGroup, dbConfig, proposedConfig *mysql.VTapGroupConfiguration) error {
+	result.VTapGroupID = vtapGroup.ShortUUID
+	result.Diff = diffVTapGroupConfig(vtapGroupConfigToYaml(dbConfig), vtapGroupConfigToYaml(proposedConfig), "current", "proposed")
+
+	var vtaps []*mysql.VTap
+	if err := mysql.Db.Select("name").Where("vtap_group_lcuuid = ?", vtapGroup.Lcuuid).Order("name").Find(&vtaps).Error; err != nil {
+		return NewError(common.SERVER_ERROR, err.Error())
+	}
+	for _, vtap := range vtaps {
+		result.AffectedVTaps = append(result.AffectedVTaps, vtap.Name)
+	}
+	result.Valid = len(result.Errors) == 0
+	return nil
+}
+
+// ValidateVTapGroupConfig 校验基础配置接口的创建或修改而不保存，返回内容与ValidateVTapGroupAdvancedConfig一致
+// lcuuid为配置的lcuuid，修改时只覆盖data中指定的字段，为空时根据data中的vtap_group_lcuuid校验创建
+func ValidateVTapGroupConfig(lcuuid string, data *model.VTapGroupConfiguration) (*model.VTapGroupConfigValidation, error) {
+	result := &model.VTapGroupConfigValidation{Errors: []string{}, AffectedVTaps: []string{}}
+	result.Errors = append(result.Errors, checkVTapGroupConfig(data)...)
+
+	db := mysql.Db
+	vtapGroup := &mysql.VTapGroup{}
+	var dbConfig *mysql.VTapGroupConfiguration
+	proposedConfig := &mysql.VTapGroupConfiguration{}
+	if lcuuid != "" {
+		dbConfig = &mysql.VTapGroupConfiguration{}
+		if err := db.Where("lcuuid = ?", lcuuid).First(dbConfig).Error; err != nil {
+			return nil, NewError(common.RESOURCE_NOT_FOUND, fmt.Sprintf("vtap group configuration(%s) not found", lcuuid))
+		}
+		if dbConfig.VTapGroupLcuuid == nil || db.Where("lcuuid = ?", *dbConfig.VTapGroupLcuuid).First(vtapGroup).Error != nil {
+			return nil, NewError(common.RESOURCE_NOT_FOUND, fmt.Sprintf("vtap group of configuration(%s) not found", lcuuid))
+		}
+		*proposedConfig = *dbConfig
+	} else {
+		if data.VTapGroupLcuuid == nil {
+			return nil, NewError(common.INVALID_PARAMETERS, "vtap_group_lcuuid is None")
+		}
+		if err := db.Where("lcuuid = ?", *data.VTapGroupLcuuid).First(vtapGroup).Error; err != nil {
+			return nil, NewError(common.RESOURCE_NOT_FOUND, fmt.Sprintf("vtap group(%s) not found", *data.VTapGroupLcuuid))
+		}
+		existConfig := &mysql.VTapGroupConfiguration{}
+		if db.Where("vtap_group_lcuuid = ?", vtapGroup.Lcuuid).First(existConfig).Error == nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("vtap group(%s) configuration already exist", vtapGroup.Lcuuid))
+			dbConfig = existConfig
+		}
+	}
+	convertJsonToDb(data, proposedConfig)
+	if err := fillVTapGroupConfigValidation(result, vtapGroup, dbConfig, proposedConfig); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/deepflowio/deepflow/server/controller/model"
+)
+
+func TestCheckVTapGroupConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		errors int
+	}{
+		{
+			name:   "valid",
+			config: "tap_interface_regex: ^eth.*$\nnpb_dedup_enabled: 1\nlog_level: INFO\n",
+			errors: 0,
+		},
+		{
+			name:   "invalid regex",
+			config: "tap_interface_regex: ^(eth.*$\n",
+			errors: 1,
+		},
+		{
+			name:   "invalid switch and log level",
+			config: "npb_dedup_enabled: 2\nlog_level: TRACE\n",
+			errors: 2,
+		},
+		{
+			name:   "invalid static config",
+			config: "static_config:\n  os-proc-regex:\n  - match-regex: '[a-'\n  wasm-plugins:\n  - a@b\n",
+			errors: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := &model.VTapGroupConfiguration{}
+			if err := yaml.UnmarshalStrict([]byte(tt.config), data); err != nil {
+				t.Fatal(err)
+			}
+			if errs := checkVTapGroupConfig(data); len(errs) != tt.errors {
+				t.Errorf("checkVTapGroupConfig() = %v, want %d errors", errs, tt.errors)
+			}
+		})
+	}
+}
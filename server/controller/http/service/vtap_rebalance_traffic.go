@@ -0,0 +1,275 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/db/clickhouse"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	"github.com/deepflowio/deepflow/server/controller/model"
+)
+
+const (
+	VTAP_REBALANCE_MODE_AGENT_COUNT = "agent_count"
+	VTAP_REBALANCE_MODE_TRAFFIC     = "traffic"
+)
+
+// 数据节点receiver按采集器统计的接收字节数，见libs/receiver中的VTapCounter
+const vtapTrafficSQL = "SELECT tag_values[indexOf(tag_names, 'vtap_id')] AS vtap_id, " +
+	"sum(metrics_float_values[indexOf(metrics_float_names, 'rx_bytes')]) AS rx_bytes " +
+	"FROM deepflow_system.`deepflow_server.ingester.receiver_vtap` " +
+	"WHERE time >= now() - %d GROUP BY vtap_id"
+
+type vtapTraffic struct {
+	VTapID  string  `db:"vtap_id"`
+	RxBytes float64 `db:"rx_bytes"`
+}
+
+// getVTapTraffic 获取采集器在window时间内写入数据节点的平均流量，单位: byte/s
+func getVTapTraffic(chCfg clickhouse.ClickHouseConfig, window int) (map[int]float64, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("invalid traffic window (%d)", window)
+	}
+	connect, err := clickhouse.Connect(chCfg)
+	if err != nil {
+		return nil, err
+	}
+	defer connect.Close()
+
+	var traffics []vtapTraffic
+	if err := connect.Select(&traffics, fmt.Sprintf(vtapTrafficSQL, window)); err != nil {
+		return nil, err
+	}
+	vtapIDToTraffic := make(map[int]float64, len(traffics))
+	for _, traffic := range traffics {
+		vtapID, err := strconv.Atoi(traffic.VTapID)
+		if err != nil || vtapID == 0 {
+			continue
+		}
+		vtapIDToTraffic[vtapID] = traffic.RxBytes / float64(window)
+	}
+	return vtapIDToTraffic, nil
+}
+
+type trafficRebalanceHost struct {
+	analyzer *mysql.Analyzer
+	vtaps    []*mysql.VTap
+	traffic  float64
+	result   *model.HostVTapRebalanceResult
+}
+
+// execAZTrafficRebalance 按采集器流量在可用区内均衡数据节点，仅返回迁移方案，不修改数据库
+//
+// 当负载最高的数据节点超出平均负载的比例不大于hysteresis时不做调整，避免反复切换；
+// 每次从负载最高的数据节点选择一个采集器迁移至负载最低且未达到采集器上限的数据节点，
+// 选择流量最接近两者负载差一半的采集器，直至达到均衡或迁移个数达到maxMoves（0表示不限制）
+func execAZTrafficRebalance(
+	azLcuuid string, analyzers []*mysql.Analyzer, analyzerIPToVTaps map[string][]*mysql.VTap,
+	vtapIDToTraffic map[int]float64, hysteresis float64, maxMoves int,
+) (model.AZVTapRebalanceResult, []model.VTapRebalanceMove) {
+	response := model.AZVTapRebalanceResult{}
+	var moves []model.VTapRebalanceMove
+
+	// 没有流量数据的采集器按可用区内采集器的平均流量计算
+	knownTraffic, knownNum := 0.0, 0
+	for _, analyzer := range analyzers {
+		for _, vtap := range analyzerIPToVTaps[analyzer.IP] {
+			if traffic, ok := vtapIDToTraffic[vtap.ID]; ok {
+				knownTraffic += traffic
+				knownNum += 1
+			}
+		}
+	}
+	if knownNum == 0 || knownTraffic <= 0 {
+		return response, moves
+	}
+	defaultTraffic := knownTraffic / float64(knownNum)
+	getTraffic := func(vtap *mysql.VTap) float64 {
+		if traffic, ok := vtapIDToTraffic[vtap.ID]; ok {
+			return traffic
+		}
+		return defaultTraffic
+	}
+
+	hosts := []*trafficRebalanceHost{}
+	ipToHost := make(map[string]*trafficRebalanceHost)
+	totalTraffic := 0.0
+	for _, analyzer := range analyzers {
+		if _, ok := ipToHost[analyzer.IP]; ok {
+			continue
+		}
+		if analyzer.State != common.HOST_STATE_COMPLETE || analyzer.VTapMax <= 0 {
+			continue
+		}
+		host := &trafficRebalanceHost{analyzer: analyzer}
+		host.vtaps = append(host.vtaps, analyzerIPToVTaps[analyzer.IP]...)
+		for _, vtap := range host.vtaps {
+			host.traffic += getTraffic(vtap)
+		}
+		host.result = &model.HostVTapRebalanceResult{
+			IP:            analyzer.IP,
+			State:         analyzer.State,
+			AZ:            azLcuuid,
+			BeforeVTapNum: len(host.vtaps),
+			AfterVTapNum:  len(host.vtaps),
+			BeforeTraffic: host.traffic,
+		}
+		totalTraffic += host.traffic
+		hosts = append(hosts, host)
+		ipToHost[analyzer.IP] = host
+	}
+	if len(hosts) < 2 {
+		for _, host := range hosts {
+			host.result.AfterTraffic = host.traffic
+			response.Details = append(response.Details, *host.result)
+		}
+		return response, moves
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		return hosts[i].analyzer.IP < hosts[j].analyzer.IP
+	})
+	threshold := totalTraffic / float64(len(hosts)) * (1 + hysteresis)
+
+	for maxMoves <= 0 || len(moves) < maxMoves {
+		var src, dst *trafficRebalanceHost
+		for _, host := range hosts {
+			if src == nil || host.traffic > src.traffic {
+				src = host
+			}
+			if len(host.vtaps) >= host.analyzer.VTapMax {
+				continue
+			}
+			if dst == nil || host.traffic < dst.traffic {
+				dst = host
+			}
+		}
+		if src.traffic <= threshold || dst == nil || dst == src {
+			break
+		}
+
+		// 迁移后两个数据节点的最大负载需要下降，即采集器流量需小于两者负载差
+		gap := src.traffic - dst.traffic
+		vtapIndex, vtapTraffic := -1, 0.0
+		for i, vtap := range src.vtaps {
+			traffic := getTraffic(vtap)
+			if traffic <= 0 || traffic >= gap {
+				continue
+			}
+			if vtapIndex < 0 || abs(gap/2-traffic) < abs(gap/2-vtapTraffic) {
+				vtapIndex, vtapTraffic = i, traffic
+			}
+		}
+		if vtapIndex < 0 {
+			break
+		}
+
+		vtap := src.vtaps[vtapIndex]
+		src.vtaps = append(src.vtaps[:vtapIndex], src.vtaps[vtapIndex+1:]...)
+		dst.vtaps = append(dst.vtaps, vtap)
+		src.traffic -= vtapTraffic
+		dst.traffic += vtapTraffic
+		src.result.AfterVTapNum -= 1
+		src.result.SwitchVTapNum += 1
+		dst.result.AfterVTapNum += 1
+		dst.result.SwitchVTapNum += 1
+		response.TotalSwitchVTapNum += 1
+		moves = append(moves, model.VTapRebalanceMove{
+			VTapName: vtap.Name,
+			VTapID:   vtap.ID,
+			AZ:       azLcuuid,
+			FromIP:   src.analyzer.IP,
+			ToIP:     dst.analyzer.IP,
+			Traffic:  vtapTraffic,
+		})
+	}
+
+	for _, host := range hosts {
+		host.result.AfterTraffic = host.traffic
+		response.Details = append(response.Details, *host.result)
+	}
+	return response, moves
+}
+
+func abs(x float64) float64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// vtapAnalyzerTrafficRebalance 按采集器流量均衡数据节点，ifCheck为true时仅返回迁移方案
+func vtapAnalyzerTrafficRebalance(
+	azs []mysql.AZ, ifCheck bool, hysteresis float64, maxMoves int, vtapIDToTraffic map[int]float64,
+) (*model.VTapRebalanceResult, error) {
+	var response model.VTapRebalanceResult
+
+	azToVTaps, azToAnalyzers, err := getAZAnalyzerVTaps(azs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, az := range azs {
+		azVTaps, ok := azToVTaps[az.Lcuuid]
+		if !ok {
+			continue
+		}
+		azAnalyzers, ok := azToAnalyzers[az.Lcuuid]
+		if !ok {
+			continue
+		}
+		analyzerIPToVTaps := make(map[string][]*mysql.VTap)
+		for _, vtap := range azVTaps {
+			analyzerIPToVTaps[vtap.AnalyzerIP] = append(analyzerIPToVTaps[vtap.AnalyzerIP], vtap)
+		}
+
+		azMaxMoves := 0
+		if maxMoves > 0 {
+			azMaxMoves = maxMoves - len(response.Moves)
+			if azMaxMoves <= 0 {
+				break
+			}
+		}
+		azVTapRebalanceResult, moves := execAZTrafficRebalance(
+			az.Lcuuid, azAnalyzers, analyzerIPToVTaps, vtapIDToTraffic, hysteresis, azMaxMoves,
+		)
+		appliedMoves := moves[:0]
+		for _, move := range moves {
+			log.Infof(
+				"rebalance vtap (%s) analyzer_ip from (%s) to (%s) by traffic (%.0f byte/s)",
+				move.VTapName, move.FromIP, move.ToIP, move.Traffic,
+			)
+			if !ifCheck {
+				err := mysql.Db.Model(&mysql.VTap{}).Where("id = ?", move.VTapID).Update("analyzer_ip", move.ToIP).Error
+				if err != nil {
+					// 更新失败的采集器保持原数据节点, 不计入迁移结果
+					log.Errorf("rebalance vtap (%s) analyzer_ip to (%s) failed: %s", move.VTapName, move.ToIP, err)
+					azVTapRebalanceResult.TotalSwitchVTapNum--
+					continue
+				}
+			}
+			appliedMoves = append(appliedMoves, move)
+		}
+		response.TotalSwitchVTapNum += azVTapRebalanceResult.TotalSwitchVTapNum
+		response.Details = append(response.Details, azVTapRebalanceResult.Details...)
+		response.Moves = append(response.Moves, appliedMoves...)
+	}
+	return &response, nil
+}
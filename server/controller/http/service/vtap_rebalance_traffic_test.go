@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"testing"
+
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+)
+
+func TestExecAZTrafficRebalance(t *testing.T) {
+	analyzers := []*mysql.Analyzer{
+		{IP: "10.0.0.1", State: common.HOST_STATE_COMPLETE, VTapMax: 100},
+		{IP: "10.0.0.2", State: common.HOST_STATE_COMPLETE, VTapMax: 100},
+	}
+	vtaps := []*mysql.VTap{
+		{ID: 1, Name: "big", AnalyzerIP: "10.0.0.1"},
+		{ID: 2, Name: "medium", AnalyzerIP: "10.0.0.1"},
+		{ID: 3, Name: "small", AnalyzerIP: "10.0.0.1"},
+		{ID: 4, Name: "idle", AnalyzerIP: "10.0.0.2"},
+	}
+	analyzerIPToVTaps := map[string][]*mysql.VTap{}
+	for _, vtap := range vtaps {
+		analyzerIPToVTaps[vtap.AnalyzerIP] = append(analyzerIPToVTaps[vtap.AnalyzerIP], vtap)
+	}
+
+	tests := []struct {
+		name            string
+		vtapIDToTraffic map[int]float64
+		hysteresis      float64
+		maxMoves        int
+		wantMoves       []string
+	}{
+		{
+			name:            "move the agent closest to half of the gap",
+			vtapIDToTraffic: map[int]float64{1: 600, 2: 400, 3: 100, 4: 10},
+			hysteresis:      0.1,
+			wantMoves:       []string{"big"},
+		},
+		{
+			name:            "balanced within hysteresis",
+			vtapIDToTraffic: map[int]float64{1: 500, 2: 50, 3: 50, 4: 500},
+			hysteresis:      0.2,
+		},
+		{
+			name:            "limited by max moves",
+			vtapIDToTraffic: map[int]float64{1: 100, 2: 100, 3: 100, 4: 1},
+			hysteresis:      0,
+			maxMoves:        1,
+			wantMoves:       []string{"big"},
+		},
+		{
+			name:            "no traffic data",
+			vtapIDToTraffic: map[int]float64{},
+			hysteresis:      0.1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// execAZTrafficRebalance 会修改列表，每个用例使用副本
+			ipToVTaps := map[string][]*mysql.VTap{}
+			for ip, v := range analyzerIPToVTaps {
+				ipToVTaps[ip] = append([]*mysql.VTap{}, v...)
+			}
+			result, moves := execAZTrafficRebalance("az-1", analyzers, ipToVTaps, tt.vtapIDToTraffic, tt.hysteresis, tt.maxMoves)
+			if len(moves) != len(tt.wantMoves) || result.TotalSwitchVTapNum != len(tt.wantMoves) {
+				t.Fatalf("execAZTrafficRebalance() moves = %v, want %v", moves, tt.wantMoves)
+			}
+			for i, move := range moves {
+				if move.VTapName != tt.wantMoves[i] || move.ToIP != "10.0.0.2" {
+					t.Errorf("execAZTrafficRebalance() move %d = %v, want %s to 10.0.0.2", i, move, tt.wantMoves[i])
+				}
+			}
+		})
+	}
+}
@@ -186,12 +186,23 @@ type VtapRepo struct {
 }
 
 type HostVTapRebalanceResult struct {
-	IP            string `json:"IP"`
-	AZ            string `json:"AZ"`
-	State         int    `json:"STATE"`
-	BeforeVTapNum int    `json:"BEFORE_VTAP_NUM"`
-	AfterVTapNum  int    `json:"AFTER_VTAP_NUM"`
-	SwitchVTapNum int    `json:"SWITCH_VTAP_NUM"`
+	IP            string  `json:"IP"`
+	AZ            string  `json:"AZ"`
+	State         int     `json:"STATE"`
+	BeforeVTapNum int     `json:"BEFORE_VTAP_NUM"`
+	AfterVTapNum  int     `json:"AFTER_VTAP_NUM"`
+	SwitchVTapNum int     `json:"SWITCH_VTAP_NUM"`
+	BeforeTraffic float64 `json:"BEFORE_TRAFFIC,omitempty"` // unit: byte/s
+	AfterTraffic  float64 `json:"AFTER_TRAFFIC,omitempty"`  // unit: byte/s
+}
+
+type VTapRebalanceMove struct {
+	VTapName string  `json:"VTAP_NAME"`
+	VTapID   int     `json:"VTAP_ID"`
+	AZ       string  `json:"AZ"`
+	FromIP   string  `json:"FROM_IP"`
+	ToIP     string  `json:"TO_IP"`
+	Traffic  float64 `json:"TRAFFIC"` // unit: byte/s
 }
 
 type AZVTapRebalanceResult struct {
@@ -202,6 +213,7 @@ type AZVTapRebalanceResult struct {
 type VTapRebalanceResult struct {
 	TotalSwitchVTapNum int                       `json:"TOTAL_SWITCH_VTAP_NUM"`
 	Details            []HostVTapRebalanceResult `json:"DETAILS"`
+	Moves              []VTapRebalanceMove       `json:"MOVES,omitempty"`
 }
 
 type VtapGroup struct {
@@ -513,6 +525,30 @@ type VTapGroupConfiguration struct {
 	YamlConfig                    *StaticConfig `yaml:"static_config,omitempty"`
 }
 
+// VTapGroupConfigChange 记录配置修改的作者及说明，保存在配置的修订历史中
+type VTapGroupConfigChange struct {
+	Author  string
+	Comment string
+}
+
+type VTapGroupConfigRevision struct {
+	VTapGroupID string `json:"VTAP_GROUP_ID"`
+	Revision    int    `json:"REVISION"`
+	Operation   string `json:"OPERATION"`
+	Author      string `json:"AUTHOR"`
+	Comment     string `json:"COMMENT"`
+	Config      string `json:"CONFIG,omitempty"`
+	CreatedAt   string `json:"CREATED_AT"`
+}
+
+type VTapGroupConfigValidation struct {
+	Valid         bool     `json:"VALID"`
+	Errors        []string `json:"ERRORS"`
+	VTapGroupID   string   `json:"VTAP_GROUP_ID"`
+	AffectedVTaps []string `json:"AFFECTED_VTAPS"` // 使用该配置的采集器名称
+	Diff          string   `json:"DIFF"`           // 与当前配置的差异
+}
+
 type TypeInfo struct {
 	ID   int    `json:"ID"`
 	Name string `json:"NAME"`
@@ -809,5 +845,57 @@ type Plugin struct {
 	Name      string `json:"NAME" binding:"required"`
 	Type      int    `json:"TYPE" binding:"required"`
 	Image     []byte `json:"IMAGE,omitempty" binding:"required"`
+	Version   int    `json:"VERSION"`
 	UpdatedAt string `json:"UPDATED_AT"`
 }
+
+type PluginVersion struct {
+	Name         string   `json:"NAME"`
+	Version      int      `json:"VERSION"`
+	Digest       string   `json:"DIGEST"`
+	Size         int      `json:"SIZE"`
+	IsDefault    bool     `json:"IS_DEFAULT"`
+	VTapGroupIDs []string `json:"VTAP_GROUP_IDS"` // 固定使用该版本的采集器组
+	CreatedAt    string   `json:"CREATED_AT"`
+}
+
+type PluginAssign struct {
+	Version      int      `json:"VERSION"` // 0表示跟随默认版本
+	VTapGroupIDs []string `json:"VTAP_GROUP_IDS" binding:"required"`
+}
+
+type PluginRollout struct {
+	Version int `json:"VERSION"` // rollback时0表示回退到默认版本的上一个版本
+}
+
+type PluginAgent struct {
+	VTapName      string `json:"VTAP_NAME"`
+	VTapGroupID   string `json:"VTAP_GROUP_ID"`
+	VTapGroupName string `json:"VTAP_GROUP_NAME"`
+	Version       int    `json:"VERSION"`
+	Digest        string `json:"DIGEST"`
+	Pinned        bool   `json:"PINNED"`
+}
+
+// ChangeEventCreate 外部系统(CI/CD、配置中心、特性开关)上报的变更事件，选择器至少指定一个，按最细粒度的选择器匹配资源
+type ChangeEventCreate struct {
+	EventType    string            `json:"EVENT_TYPE" binding:"required"` // deploy/config-change/feature-flag/rollback 或自定义
+	Description  string            `json:"DESCRIPTION"`
+	Time         int64             `json:"TIME"` // unix时间戳(秒)，默认为当前时间
+	PodCluster   string            `json:"POD_CLUSTER"`
+	PodNamespace string            `json:"POD_NAMESPACE"`
+	PodService   string            `json:"POD_SERVICE"`
+	PodGroup     string            `json:"POD_GROUP"`
+	Pod          string            `json:"POD"`
+	Version      string            `json:"VERSION"`
+	Author       string            `json:"AUTHOR"`
+	Source       string            `json:"SOURCE"` // 上报系统，如 jenkins、argocd
+	Links        []string          `json:"LINKS"`
+	Attributes   map[string]string `json:"ATTRIBUTES"`
+}
+
+type ChangeEventTarget struct {
+	InstanceType int    `json:"INSTANCE_TYPE"`
+	InstanceID   int    `json:"INSTANCE_ID"`
+	InstanceName string `json:"INSTANCE_NAME"`
+}
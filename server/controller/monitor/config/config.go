@@ -30,7 +30,11 @@ type MonitorConfig struct {
 	VTapCheckInterval           int     `default:"60" yaml:"vtap_check_interval"`
 	ExceptionTimeFrame          int     `default:"3600" yaml:"exception_time_frame"`
 	AutoRebalanceVTap           bool    `default:"true" yaml:"auto_rebalance_vtap"`
-	RebalanceCheckInterval      int     `default:"300" yaml:"rebalance_check_interval"`   // unit: second
+	RebalanceCheckInterval      int     `default:"300" yaml:"rebalance_check_interval"`  // unit: second
+	RebalanceMode               string  `default:"agent_count" yaml:"rebalance_mode"`    // agent_count or traffic, traffic only applies to analyzer
+	RebalanceTrafficWindow      int     `default:"3600" yaml:"rebalance_traffic_window"` // unit: second
+	RebalanceHysteresis         float64 `default:"0.2" yaml:"rebalance_hysteresis"`
+	RebalanceMaxMoves           int     `default:"10" yaml:"rebalance_max_moves"`
 	VTapAutoDeleteInterval      int     `default:"3600" yaml:"vtap_auto_delete_interval"` // uint: second
 	Warrant                     Warrant `yaml:"warrant"`
 }
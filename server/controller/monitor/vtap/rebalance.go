@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/db/clickhouse"
 	"github.com/deepflowio/deepflow/server/controller/http/service"
 	"github.com/deepflowio/deepflow/server/controller/monitor/config"
 )
@@ -30,14 +31,16 @@ type RebalanceCheck struct {
 	vCtx    context.Context
 	vCancel context.CancelFunc
 	cfg     config.MonitorConfig
+	chCfg   clickhouse.ClickHouseConfig
 }
 
-func NewRebalanceCheck(cfg config.MonitorConfig, ctx context.Context) *RebalanceCheck {
+func NewRebalanceCheck(cfg config.MonitorConfig, chCfg clickhouse.ClickHouseConfig, ctx context.Context) *RebalanceCheck {
 	vCtx, vCancel := context.WithCancel(ctx)
 	return &RebalanceCheck{
 		vCtx:    vCtx,
 		vCancel: vCancel,
 		cfg:     cfg,
+		chCfg:   chCfg,
 	}
 }
 
@@ -77,7 +80,7 @@ func (r *RebalanceCheck) controllerRebalance() {
 				"check": false,
 				"type":  "controller",
 			}
-			if result, err := service.VTapRebalance(args); err != nil {
+			if result, err := service.VTapRebalance(args, r.cfg, r.chCfg); err != nil {
 				log.Error(err)
 			} else {
 				data, _ := json.Marshal(result)
@@ -89,6 +92,21 @@ func (r *RebalanceCheck) controllerRebalance() {
 }
 
 func (r *RebalanceCheck) analyzerRebalance() {
+	// 按流量均衡时每次检查都计算迁移方案，由rebalance_hysteresis和rebalance_max_moves控制切换频率
+	if r.cfg.RebalanceMode == service.VTAP_REBALANCE_MODE_TRAFFIC {
+		args := map[string]interface{}{
+			"check": false,
+			"type":  "analyzer",
+		}
+		if result, err := service.VTapRebalance(args, r.cfg, r.chCfg); err != nil {
+			log.Error(err)
+		} else if result.TotalSwitchVTapNum > 0 {
+			data, _ := json.Marshal(result)
+			log.Infof("exec traffic rebalance: %s", string(data))
+		}
+		return
+	}
+
 	// check if need rebalance
 	analyzers, err := service.GetAnalyzers(map[string]interface{}{})
 	if err != nil {
@@ -104,7 +122,7 @@ func (r *RebalanceCheck) analyzerRebalance() {
 				"check": false,
 				"type":  "analyzer",
 			}
-			if result, err := service.VTapRebalance(args); err != nil {
+			if result, err := service.VTapRebalance(args, r.cfg, r.chCfg); err != nil {
 				log.Error(err)
 			} else {
 				data, _ := json.Marshal(result)
@@ -29,6 +29,7 @@ func (b *DiffBaseDataSet) addPodIngress(dbItem *mysql.PodIngress, seq int) {
 			Lcuuid:   dbItem.Lcuuid,
 		},
 		Name:            dbItem.Name,
+		Type:            dbItem.Type,
 		RegionLcuuid:    dbItem.Region,
 		AZLcuuid:        dbItem.AZ,
 		SubDomainLcuuid: dbItem.SubDomain,
@@ -44,6 +45,7 @@ func (b *DiffBaseDataSet) deletePodIngress(lcuuid string) {
 type PodIngress struct {
 	DiffBase
 	Name            string `json:"name"`
+	Type            int    `json:"type"`
 	RegionLcuuid    string `json:"region_lcuuid"`
 	AZLcuuid        string `json:"az_lcuuid"`
 	SubDomainLcuuid string `json:"sub_domain_lcuu"`
@@ -51,6 +53,7 @@ type PodIngress struct {
 
 func (p *PodIngress) Update(cloudItem *cloudmodel.PodIngress) {
 	p.Name = cloudItem.Name
+	p.Type = cloudItem.Type
 	p.RegionLcuuid = cloudItem.RegionLcuuid
 	p.AZLcuuid = cloudItem.AZLcuuid
 	p.SubDomainLcuuid = cloudItem.SubDomainLcuuid
@@ -28,6 +28,7 @@ func (b *DiffBaseDataSet) addPodIngressRule(dbItem *mysql.PodIngressRule, seq in
 			Sequence: seq,
 			Lcuuid:   dbItem.Lcuuid,
 		},
+		PodIngressID:    dbItem.PodIngressID,
 		SubDomainLcuuid: dbItem.SubDomain,
 	}
 	b.GetLogFunc()(addDiffBase(RESOURCE_TYPE_POD_INGRESS_RULE_EN, b.PodIngressRules[dbItem.Lcuuid]))
@@ -40,6 +41,7 @@ func (b *DiffBaseDataSet) deletePodIngressRule(lcuuid string) {
 
 type PodIngressRule struct {
 	DiffBase
+	PodIngressID    int    `json:"pod_ingress_id"`
 	SubDomainLcuuid string `json:"sub_domain_lcuuid"`
 }
 
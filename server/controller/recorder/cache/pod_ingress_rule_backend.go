@@ -28,6 +28,8 @@ func (b *DiffBaseDataSet) addPodIngressRuleBackend(dbItem *mysql.PodIngressRuleB
 			Sequence: seq,
 			Lcuuid:   dbItem.Lcuuid,
 		},
+		PodIngressID:    dbItem.PodIngressID,
+		Weight:          dbItem.Weight,
 		SubDomainLcuuid: dbItem.SubDomain,
 	}
 	b.GetLogFunc()(addDiffBase(RESOURCE_TYPE_POD_INGRESS_RULE_BACKEND_EN, b.PodIngressRuleBackends[dbItem.Lcuuid]))
@@ -40,10 +42,13 @@ func (b *DiffBaseDataSet) deletePodIngressRuleBackend(lcuuid string) {
 
 type PodIngressRuleBackend struct {
 	DiffBase
+	PodIngressID    int    `json:"pod_ingress_id"`
+	Weight          int    `json:"weight"`
 	SubDomainLcuuid string `json:"sub_domain_lcuuid"`
 }
 
 func (p *PodIngressRuleBackend) Update(cloudItem *cloudmodel.PodIngressRuleBackend) {
+	p.Weight = cloudItem.Weight
 	p.SubDomainLcuuid = cloudItem.SubDomainLcuuid
 	log.Info(updateDiffBase(RESOURCE_TYPE_POD_INGRESS_RULE_BACKEND_EN, p))
 }
@@ -96,6 +96,7 @@ type ToolDataSet struct {
 
 	podIngressLcuuidToID     map[string]int
 	podIngressIDToLcuuid     map[int]string
+	podIngressIDToName       map[int]string
 	podIngressRuleLcuuidToID map[string]int
 
 	podServiceLcuuidToID map[string]int
@@ -174,6 +175,7 @@ func NewToolDataSet() ToolDataSet {
 
 		podIngressLcuuidToID:     make(map[string]int),
 		podIngressIDToLcuuid:     make(map[int]string),
+		podIngressIDToName:       make(map[int]string),
 		podIngressRuleLcuuidToID: make(map[string]int),
 
 		podServiceLcuuidToID: make(map[string]int),
@@ -837,12 +839,14 @@ func (t *ToolDataSet) deletePodNamespace(lcuuid string) {
 func (t *ToolDataSet) addPodIngress(item *mysql.PodIngress) {
 	t.podIngressLcuuidToID[item.Lcuuid] = item.ID
 	t.podIngressIDToLcuuid[item.ID] = item.Lcuuid
+	t.podIngressIDToName[item.ID] = item.Name
 	t.GetLogFunc()(addToToolMap(RESOURCE_TYPE_POD_INGRESS_EN, item.Lcuuid))
 }
 
 func (t *ToolDataSet) deletePodIngress(lcuuid string) {
 	id, _ := t.GetPodIngressIDByLcuuid(lcuuid)
 	delete(t.podIngressIDToLcuuid, id)
+	delete(t.podIngressIDToName, id)
 	delete(t.podIngressLcuuidToID, lcuuid)
 	log.Info(deleteFromToolMap(RESOURCE_TYPE_POD_INGRESS_EN, lcuuid))
 }
@@ -1582,6 +1586,23 @@ func (t *ToolDataSet) GetPodIngressLcuuidByID(id int) (string, bool) {
 	}
 }
 
+func (t *ToolDataSet) GetPodIngressNameByID(id int) (string, bool) {
+	name, exists := t.podIngressIDToName[id]
+	if exists {
+		return name, true
+	}
+	log.Warning(cacheNameByIDNotFound(RESOURCE_TYPE_POD_INGRESS_EN, id))
+	var podIngress mysql.PodIngress
+	result := mysql.Db.Where("id = ?", id).Find(&podIngress)
+	if result.RowsAffected == 1 {
+		t.addPodIngress(&podIngress)
+		return podIngress.Name, true
+	} else {
+		log.Error(dbResourceByIDNotFound(RESOURCE_TYPE_POD_INGRESS_EN, id))
+		return name, false
+	}
+}
+
 func (t *ToolDataSet) GetPodIngressRuleIDByLcuuid(lcuuid string) (int, bool) {
 	id, exists := t.podIngressRuleLcuuidToID[lcuuid]
 	if exists {
@@ -95,4 +95,7 @@ var DEVICE_TYPE_INT_TO_STR = map[int]string{
 	common.VIF_DEVICE_TYPE_POD_SERVICE:    RESOURCE_TYPE_POD_SERVICE_EN,
 	common.VIF_DEVICE_TYPE_POD:            RESOURCE_TYPE_POD_EN,
 	common.PROCESS_INSTANCE_TYPE:          RESOURCE_TYPE_PROCESS_EN,
+	common.POD_INGRESS_INSTANCE_TYPE:      RESOURCE_TYPE_POD_INGRESS_EN,
+	common.POD_CLUSTER_INSTANCE_TYPE:      RESOURCE_TYPE_POD_CLUSTER_EN,
+	common.POD_NAMESPACE_INSTANCE_TYPE:    RESOURCE_TYPE_POD_NAMESPACE_EN,
 }
@@ -0,0 +1,175 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package event
+
+import (
+	"strconv"
+	"time"
+
+	cloudmodel "github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/recorder/cache"
+	"github.com/deepflowio/deepflow/server/libs/eventapi"
+	"github.com/deepflowio/deepflow/server/libs/queue"
+)
+
+// 首次同步时只上报最近一段时间内发生的事件，避免 controller 重启后重复上报 apiserver 中残留的历史事件
+const KUBERNETES_EVENT_LOOKBACK = 10 * time.Minute
+
+// apiserver 默认保留事件 1 小时，已上报事件的状态保留更久一些，超时后再清理
+const KUBERNETES_EVENT_STATE_TTL = 2 * time.Hour
+
+type kubernetesEventState struct {
+	count         int
+	lastTimestamp time.Time
+}
+
+// KubernetesEvent 跨同步周期保存已上报事件的状态，按 involved object + reason 去重，count 或 lastTimestamp 变化时再次上报
+type KubernetesEvent struct {
+	EventManagerBase
+	startedAt  time.Time
+	keyToState map[string]kubernetesEventState
+	// 早于该时间的事件状态已被清理，再次出现时不重复上报
+	expiredBefore time.Time
+}
+
+func NewKubernetesEvent(eq *queue.OverwriteQueue) *KubernetesEvent {
+	return &KubernetesEvent{
+		EventManagerBase: EventManagerBase{
+			resourceType: "kubernetes_event",
+			Queue:        eq,
+		},
+		startedAt:  time.Now(),
+		keyToState: make(map[string]kubernetesEventState),
+	}
+}
+
+func kubernetesEventKey(item *cloudmodel.KubernetesEvent) string {
+	return item.InvolvedKind + "/" + item.InvolvedNamespace + "/" + item.InvolvedName + "/" + item.Reason
+}
+
+// 同一 key 可能同时存在多个事件对象（旧对象尚未过期），只保留 lastTimestamp 最新的一个
+func (k *KubernetesEvent) dedupe(items []cloudmodel.KubernetesEvent) map[string]*cloudmodel.KubernetesEvent {
+	keyToItem := make(map[string]*cloudmodel.KubernetesEvent, len(items))
+	for i := range items {
+		key := kubernetesEventKey(&items[i])
+		if item, ok := keyToItem[key]; !ok || items[i].LastTimestamp.After(item.LastTimestamp) {
+			keyToItem[key] = &items[i]
+		}
+	}
+	return keyToItem
+}
+
+func (k *KubernetesEvent) shouldProduce(key string, item *cloudmodel.KubernetesEvent) bool {
+	state, ok := k.keyToState[key]
+	if !ok {
+		since := k.startedAt.Add(-KUBERNETES_EVENT_LOOKBACK)
+		if k.expiredBefore.After(since) {
+			since = k.expiredBefore
+		}
+		return !item.LastTimestamp.Before(since)
+	}
+	return item.Count != state.count || item.LastTimestamp.After(state.lastTimestamp)
+}
+
+// ProduceByCloudItems 需在 updater 执行完成后调用，以便查询到本次同步新建的资源 ID
+func (k *KubernetesEvent) ProduceByCloudItems(toolDS *cache.ToolDataSet, items []cloudmodel.KubernetesEvent) {
+	k.ToolDataSet = toolDS
+	keyToItem := k.dedupe(items)
+	for key, item := range keyToItem {
+		if k.shouldProduce(key, item) {
+			k.produce(item)
+		}
+		// 旧的事件对象可能晚于新对象返回，只记录最新的状态
+		if state, ok := k.keyToState[key]; !ok || !item.LastTimestamp.Before(state.lastTimestamp) {
+			k.keyToState[key] = kubernetesEventState{count: item.Count, lastTimestamp: item.LastTimestamp}
+		}
+	}
+	// 某个周期未获取到事件（如 apiserver 请求失败）时保留已上报的状态，仅按时间清理
+	k.expireStates(time.Now())
+}
+
+func (k *KubernetesEvent) expireStates(now time.Time) {
+	k.expiredBefore = now.Add(-KUBERNETES_EVENT_STATE_TTL)
+	for key, state := range k.keyToState {
+		if state.lastTimestamp.Before(k.expiredBefore) {
+			delete(k.keyToState, key)
+		}
+	}
+}
+
+func (k *KubernetesEvent) produce(item *cloudmodel.KubernetesEvent) {
+	var (
+		instanceType int
+		instanceID   int
+		podNodeID    int
+		opts         []eventapi.TagFieldOption
+	)
+	if id, ok := k.ToolDataSet.GetPodClusterIDByLcuuid(item.PodClusterLcuuid); ok {
+		opts = append(opts, eventapi.TagPodClusterID(id))
+	}
+	if item.PodNamespaceLcuuid != "" {
+		if id, ok := k.ToolDataSet.GetPodNamespaceIDByLcuuid(item.PodNamespaceLcuuid); ok {
+			opts = append(opts, eventapi.TagPodNSID(id))
+		}
+	}
+	if item.PodNodeLcuuid != "" {
+		if id, ok := k.ToolDataSet.GetPodNodeIDByLcuuid(item.PodNodeLcuuid); ok {
+			podNodeID = id
+			opts = append(opts, eventapi.TagPodNodeID(id))
+			if info, err := k.ToolDataSet.GetPodNodeInfoByID(id); err == nil {
+				opts = append(opts, eventapi.TagRegionID(info.RegionID), eventapi.TagAZID(info.AZID), eventapi.TagVPCID(info.VPCID))
+			}
+			if l3DeviceOpts, ok := getL3DeviceOptionsByPodNodeID(k.ToolDataSet, id); ok {
+				opts = append(opts, l3DeviceOpts...)
+			}
+			instanceType, instanceID = common.VIF_DEVICE_TYPE_POD_NODE, id
+		}
+	}
+	if item.PodLcuuid != "" {
+		if id, ok := k.ToolDataSet.GetPodIDByLcuuid(item.PodLcuuid); ok {
+			opts = append(opts, eventapi.TagPodID(id))
+			if info, err := k.ToolDataSet.GetPodInfoByID(id); err == nil {
+				opts = append(opts, eventapi.TagPodGroupID(info.PodGroupID))
+				if podNodeID == 0 {
+					opts = append(opts, eventapi.TagRegionID(info.RegionID), eventapi.TagAZID(info.AZID), eventapi.TagVPCID(info.VPCID))
+				}
+			}
+			instanceType, instanceID = common.VIF_DEVICE_TYPE_POD, id
+		}
+	}
+	opts = append(opts, []eventapi.TagFieldOption{
+		eventapi.TagDescription(item.Message),
+		eventapi.TagAttribute("involved_kind", item.InvolvedKind),
+		eventapi.TagAttribute("involved_name", item.InvolvedName),
+		eventapi.TagAttribute("involved_namespace", item.InvolvedNamespace),
+		eventapi.TagAttribute("count", strconv.Itoa(item.Count)),
+	}...)
+
+	event := eventapi.AcquireResourceEvent()
+	k.fillEvent(event, item.Reason, item.InvolvedName, instanceType, instanceID, opts...)
+	if !item.LastTimestamp.IsZero() {
+		event.Time = item.LastTimestamp.Unix()
+		event.TimeMilli = item.LastTimestamp.UnixMilli()
+	}
+	event.IfNeedTagged = false
+	event.KubernetesType = item.Type
+	if event.KubernetesType == "" {
+		event.KubernetesType = eventapi.KUBERNETES_EVENT_TYPE_NORMAL
+	}
+	k.enqueue(item.Lcuuid, event)
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package event
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	cloudmodel "github.com/deepflowio/deepflow/server/controller/cloud/model"
+)
+
+func TestKubernetesEvent_dedupe(t *testing.T) {
+	now := time.Now()
+	em := NewKubernetesEvent(NewEventQueue())
+	items := []cloudmodel.KubernetesEvent{
+		{Lcuuid: RandLcuuid(), Reason: "BackOff", InvolvedKind: "Pod", InvolvedNamespace: "default", InvolvedName: "a", Count: 5, LastTimestamp: now.Add(-time.Hour)},
+		{Lcuuid: RandLcuuid(), Reason: "BackOff", InvolvedKind: "Pod", InvolvedNamespace: "default", InvolvedName: "a", Count: 1, LastTimestamp: now},
+		{Lcuuid: RandLcuuid(), Reason: "Unhealthy", InvolvedKind: "Pod", InvolvedNamespace: "default", InvolvedName: "a", Count: 1, LastTimestamp: now},
+	}
+	keyToItem := em.dedupe(items)
+	assert.Equal(t, 2, len(keyToItem))
+	assert.Equal(t, items[1].Lcuuid, keyToItem["Pod/default/a/BackOff"].Lcuuid)
+}
+
+func TestKubernetesEvent_shouldProduce(t *testing.T) {
+	now := time.Now()
+	em := NewKubernetesEvent(NewEventQueue())
+	item := &cloudmodel.KubernetesEvent{Reason: "OOMKilling", InvolvedKind: "Node", InvolvedName: "node-1", Count: 1, LastTimestamp: now}
+	key := kubernetesEventKey(item)
+	assert.True(t, em.shouldProduce(key, item))
+
+	// 首次同步忽略过旧的事件
+	old := &cloudmodel.KubernetesEvent{Reason: "OOMKilling", InvolvedKind: "Node", InvolvedName: "node-2", Count: 3, LastTimestamp: now.Add(-time.Hour)}
+	assert.False(t, em.shouldProduce(kubernetesEventKey(old), old))
+
+	em.keyToState[key] = kubernetesEventState{count: 1, lastTimestamp: now}
+	assert.False(t, em.shouldProduce(key, item))
+
+	repeated := *item
+	repeated.Count = 2
+	repeated.LastTimestamp = now.Add(time.Minute)
+	assert.True(t, em.shouldProduce(key, &repeated))
+}
+
+func TestKubernetesEvent_keepStateOnEmptyCycle(t *testing.T) {
+	now := time.Now()
+	em := NewKubernetesEvent(NewEventQueue())
+	item := &cloudmodel.KubernetesEvent{Reason: "BackOff", InvolvedKind: "Pod", InvolvedNamespace: "default", InvolvedName: "a", Count: 3, LastTimestamp: now}
+	key := kubernetesEventKey(item)
+	em.keyToState[key] = kubernetesEventState{count: 3, lastTimestamp: now}
+
+	// 未获取到事件的周期不清空状态，下个周期同一事件不重复上报
+	em.ProduceByCloudItems(nil, nil)
+	assert.Equal(t, 1, len(em.keyToState))
+	assert.False(t, em.shouldProduce(key, item))
+
+	// 状态超时后清理，且不再上报清理前的旧事件
+	stale := *item
+	stale.LastTimestamp = now.Add(-KUBERNETES_EVENT_STATE_TTL - time.Minute)
+	em.keyToState[key] = kubernetesEventState{count: 3, lastTimestamp: stale.LastTimestamp}
+	em.startedAt = now.Add(-3 * KUBERNETES_EVENT_STATE_TTL)
+	em.expireStates(now)
+	assert.Equal(t, 0, len(em.keyToState))
+	assert.False(t, em.shouldProduce(key, &stale))
+	assert.True(t, em.shouldProduce(key, item))
+}
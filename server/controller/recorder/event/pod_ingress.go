@@ -0,0 +1,156 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package event
+
+import (
+	"fmt"
+
+	cloudmodel "github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	"github.com/deepflowio/deepflow/server/controller/recorder/cache"
+	. "github.com/deepflowio/deepflow/server/controller/recorder/common"
+	"github.com/deepflowio/deepflow/server/libs/eventapi"
+	"github.com/deepflowio/deepflow/server/libs/queue"
+)
+
+var podIngressTypeToName = map[int]string{
+	common.POD_INGRESS_TYPE_INGRESS:        "Ingress",
+	common.POD_INGRESS_TYPE_ROUTE:          "Route",
+	common.POD_INGRESS_TYPE_HTTPROUTE:      "HTTPRoute",
+	common.POD_INGRESS_TYPE_GRPCROUTE:      "GRPCRoute",
+	common.POD_INGRESS_TYPE_VIRTUALSERVICE: "VirtualService",
+}
+
+type PodIngress struct {
+	EventManagerBase
+	deviceType int
+}
+
+func NewPodIngress(toolDS *cache.ToolDataSet, eq *queue.OverwriteQueue) *PodIngress {
+	mng := &PodIngress{
+		EventManagerBase{
+			resourceType: RESOURCE_TYPE_POD_INGRESS_EN,
+			ToolDataSet:  toolDS,
+			Queue:        eq,
+		},
+		common.POD_INGRESS_INSTANCE_TYPE,
+	}
+	return mng
+}
+
+func (p *PodIngress) ProduceByAdd(items []*mysql.PodIngress) {
+	for _, item := range items {
+		var opts []eventapi.TagFieldOption
+		if regionID, ok := p.ToolDataSet.GetRegionIDByLcuuid(item.Region); ok {
+			opts = append(opts, eventapi.TagRegionID(regionID))
+		}
+		if azID, ok := p.ToolDataSet.GetAZIDByLcuuid(item.AZ); ok {
+			opts = append(opts, eventapi.TagAZID(azID))
+		}
+		opts = append(opts, []eventapi.TagFieldOption{
+			eventapi.TagPodClusterID(item.PodClusterID),
+			eventapi.TagPodNSID(item.PodNamespaceID),
+			eventapi.TagDescription(fmt.Sprintf("%s %s created.", podIngressTypeToName[item.Type], item.Name)),
+		}...)
+
+		p.createAndEnqueue(
+			item.Lcuuid,
+			eventapi.RESOURCE_EVENT_TYPE_CREATE,
+			item.Name,
+			p.deviceType,
+			item.ID,
+			opts...,
+		)
+	}
+}
+
+// 名称或类型变化(如 HTTPRoute 改为 GRPCRoute)时产生配置变更事件, 返回是否产生了事件
+func (p *PodIngress) ProduceByUpdate(cloudItem *cloudmodel.PodIngress, diffBase *cache.PodIngress) bool {
+	if diffBase.Name == cloudItem.Name && diffBase.Type == cloudItem.Type {
+		return false
+	}
+	id, ok := p.ToolDataSet.GetPodIngressIDByLcuuid(diffBase.Lcuuid)
+	if !ok {
+		log.Error(idByLcuuidNotFound(p.resourceType, diffBase.Lcuuid))
+	}
+
+	var opts []eventapi.TagFieldOption
+	if regionID, ok := p.ToolDataSet.GetRegionIDByLcuuid(cloudItem.RegionLcuuid); ok {
+		opts = append(opts, eventapi.TagRegionID(regionID))
+	}
+	if azID, ok := p.ToolDataSet.GetAZIDByLcuuid(cloudItem.AZLcuuid); ok {
+		opts = append(opts, eventapi.TagAZID(azID))
+	}
+	if podClusterID, ok := p.ToolDataSet.GetPodClusterIDByLcuuid(cloudItem.PodClusterLcuuid); ok {
+		opts = append(opts, eventapi.TagPodClusterID(podClusterID))
+	}
+	if podNSID, ok := p.ToolDataSet.GetPodNamespaceIDByLcuuid(cloudItem.PodNamespaceLcuuid); ok {
+		opts = append(opts, eventapi.TagPodNSID(podNSID))
+	}
+	opts = append(opts, eventapi.TagDescription(fmt.Sprintf("%s %s changed to %s %s.",
+		podIngressTypeToName[diffBase.Type], diffBase.Name, podIngressTypeToName[cloudItem.Type], cloudItem.Name)))
+
+	p.createAndEnqueue(
+		diffBase.Lcuuid,
+		eventapi.RESOURCE_EVENT_TYPE_UPDATE_CONFIG,
+		cloudItem.Name,
+		p.deviceType,
+		id,
+		opts...,
+	)
+	return true
+}
+
+// 规则或后端(host, path, service, port, weight)变化时产生配置变更事件
+func (p *PodIngress) ProduceByRuleChange(id int, diffBase *cache.PodIngress) {
+	var opts []eventapi.TagFieldOption
+	if regionID, ok := p.ToolDataSet.GetRegionIDByLcuuid(diffBase.RegionLcuuid); ok {
+		opts = append(opts, eventapi.TagRegionID(regionID))
+	}
+	if azID, ok := p.ToolDataSet.GetAZIDByLcuuid(diffBase.AZLcuuid); ok {
+		opts = append(opts, eventapi.TagAZID(azID))
+	}
+	opts = append(opts, eventapi.TagDescription(fmt.Sprintf("%s %s rules changed.",
+		podIngressTypeToName[diffBase.Type], diffBase.Name)))
+
+	p.createAndEnqueue(
+		diffBase.Lcuuid,
+		eventapi.RESOURCE_EVENT_TYPE_UPDATE_CONFIG,
+		diffBase.Name,
+		p.deviceType,
+		id,
+		opts...,
+	)
+}
+
+func (p *PodIngress) ProduceByDelete(lcuuids []string) {
+	for _, lcuuid := range lcuuids {
+		var name string
+		id, ok := p.ToolDataSet.GetPodIngressIDByLcuuid(lcuuid)
+		if ok {
+			name, ok = p.ToolDataSet.GetPodIngressNameByID(id)
+			if !ok {
+				log.Error(nameByIDNotFound(p.resourceType, id))
+			}
+		} else {
+			log.Error(idByLcuuidNotFound(p.resourceType, lcuuid))
+		}
+
+		p.createAndEnqueue(lcuuid, eventapi.RESOURCE_EVENT_TYPE_DELETE, name, p.deviceType, id)
+	}
+}
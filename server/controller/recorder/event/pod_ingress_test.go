@@ -0,0 +1,70 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package event
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/stretchr/testify/assert"
+
+	cloudmodel "github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/recorder/cache"
+	"github.com/deepflowio/deepflow/server/libs/eventapi"
+)
+
+func TestUpdatePodIngress(t *testing.T) {
+	ds := cache.NewToolDataSet()
+	id := RandID()
+	monkey := gomonkey.ApplyPrivateMethod(reflect.TypeOf(&ds), "GetPodIngressIDByLcuuid", func(_ *cache.ToolDataSet, _ string) (int, bool) {
+		return id, true
+	})
+	defer monkey.Reset()
+
+	eq := NewEventQueue()
+	podIngress := NewPodIngress(&ds, eq)
+	name := RandName()
+	diffBase := &cache.PodIngress{DiffBase: cache.DiffBase{Lcuuid: RandLcuuid()}, Name: name, Type: common.POD_INGRESS_TYPE_HTTPROUTE}
+	assert.False(t, podIngress.ProduceByUpdate(&cloudmodel.PodIngress{Name: name, Type: common.POD_INGRESS_TYPE_HTTPROUTE}, diffBase))
+	assert.Equal(t, 0, eq.Len())
+
+	assert.True(t, podIngress.ProduceByUpdate(&cloudmodel.PodIngress{Name: name, Type: common.POD_INGRESS_TYPE_GRPCROUTE}, diffBase))
+	assert.Equal(t, 1, eq.Len())
+	e := eq.Get().(*eventapi.ResourceEvent)
+	assert.Equal(t, eventapi.RESOURCE_EVENT_TYPE_UPDATE_CONFIG, e.Type)
+	assert.Equal(t, uint32(common.POD_INGRESS_INSTANCE_TYPE), e.InstanceType)
+	assert.Equal(t, uint32(id), e.InstanceID)
+	assert.Equal(t, name, e.InstanceName)
+}
+
+func TestPodIngressRuleChange(t *testing.T) {
+	ds := cache.NewToolDataSet()
+	eq := NewEventQueue()
+	podIngress := NewPodIngress(&ds, eq)
+	id := RandID()
+	name := RandName()
+	diffBase := &cache.PodIngress{DiffBase: cache.DiffBase{Lcuuid: RandLcuuid()}, Name: name, Type: common.POD_INGRESS_TYPE_VIRTUALSERVICE}
+	podIngress.ProduceByRuleChange(id, diffBase)
+	assert.Equal(t, 1, eq.Len())
+	e := eq.Get().(*eventapi.ResourceEvent)
+	assert.Equal(t, eventapi.RESOURCE_EVENT_TYPE_UPDATE_CONFIG, e.Type)
+	assert.Equal(t, uint32(id), e.InstanceID)
+	assert.Equal(t, name, e.InstanceName)
+	assert.Equal(t, "VirtualService "+name+" rules changed.", e.Description)
+}
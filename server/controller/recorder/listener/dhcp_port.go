@@ -20,6 +20,7 @@ import (
 	cloudmodel "github.com/deepflowio/deepflow/server/controller/cloud/model"
 	"github.com/deepflowio/deepflow/server/controller/db/mysql"
 	"github.com/deepflowio/deepflow/server/controller/recorder/cache"
+	. "github.com/deepflowio/deepflow/server/controller/recorder/common"
 	"github.com/deepflowio/deepflow/server/controller/recorder/event"
 	"github.com/deepflowio/deepflow/server/libs/queue"
 )
@@ -40,15 +41,18 @@ func NewDHCPPort(c *cache.Cache, eq *queue.OverwriteQueue) *DHCPPort {
 func (p *DHCPPort) OnUpdaterAdded(addedDBItems []*mysql.DHCPPort) {
 	// p.cache.AddDHCPPorts(addedDBItems)
 	p.eventProducer.ProduceByAdd(addedDBItems)
+	publishByDBItems(RESOURCE_TYPE_DHCP_PORT_EN, addedDBItems)
 }
 
 func (p *DHCPPort) OnUpdaterUpdated(cloudItem *cloudmodel.DHCPPort, diffBase *cache.DHCPPort) {
 	p.eventProducer.ProduceByUpdate(cloudItem, diffBase)
+	publishByLcuuids(RESOURCE_TYPE_DHCP_PORT_EN, []string{diffBase.Lcuuid}, p.cache.ToolDataSet.GetDHCPPortIDByLcuuid)
 	// diffBase.Update(cloudItem)
 	// p.cache.UpdateDHCPPort(cloudItem)
 }
 
 func (p *DHCPPort) OnUpdaterDeleted(lcuuids []string) {
 	p.eventProducer.ProduceByDelete(lcuuids)
+	publishByLcuuids(RESOURCE_TYPE_DHCP_PORT_EN, lcuuids, p.cache.ToolDataSet.GetDHCPPortIDByLcuuid)
 	// p.cache.DeleteDHCPPorts(lcuuids)
 }
@@ -20,6 +20,7 @@ import (
 	cloudmodel "github.com/deepflowio/deepflow/server/controller/cloud/model"
 	"github.com/deepflowio/deepflow/server/controller/db/mysql"
 	"github.com/deepflowio/deepflow/server/controller/recorder/cache"
+	. "github.com/deepflowio/deepflow/server/controller/recorder/common"
 	"github.com/deepflowio/deepflow/server/controller/recorder/event"
 	"github.com/deepflowio/deepflow/server/libs/queue"
 )
@@ -40,15 +41,18 @@ func NewHost(c *cache.Cache, eq *queue.OverwriteQueue) *Host {
 func (p *Host) OnUpdaterAdded(addedDBItems []*mysql.Host) {
 	// p.cache.AddHosts(addedDBItems)
 	p.eventProducer.ProduceByAdd(addedDBItems)
+	publishByDBItems(RESOURCE_TYPE_HOST_EN, addedDBItems)
 }
 
 func (p *Host) OnUpdaterUpdated(cloudItem *cloudmodel.Host, diffBase *cache.Host) {
 	p.eventProducer.ProduceByUpdate(cloudItem, diffBase)
+	publishByLcuuids(RESOURCE_TYPE_HOST_EN, []string{diffBase.Lcuuid}, p.cache.ToolDataSet.GetHostIDByLcuuid)
 	// diffBase.Update(cloudItem)
 	// p.cache.UpdateHost(cloudItem)
 }
 
 func (p *Host) OnUpdaterDeleted(lcuuids []string) {
 	p.eventProducer.ProduceByDelete(lcuuids)
+	publishByLcuuids(RESOURCE_TYPE_HOST_EN, lcuuids, p.cache.ToolDataSet.GetHostIDByLcuuid)
 	// p.cache.DeleteHosts(lcuuids)
 }
@@ -20,6 +20,7 @@ import (
 	cloudmodel "github.com/deepflowio/deepflow/server/controller/cloud/model"
 	"github.com/deepflowio/deepflow/server/controller/db/mysql"
 	"github.com/deepflowio/deepflow/server/controller/recorder/cache"
+	. "github.com/deepflowio/deepflow/server/controller/recorder/common"
 	"github.com/deepflowio/deepflow/server/controller/recorder/event"
 	"github.com/deepflowio/deepflow/server/libs/queue"
 )
@@ -40,15 +41,18 @@ func NewLB(c *cache.Cache, eq *queue.OverwriteQueue) *LB {
 func (p *LB) OnUpdaterAdded(addedDBItems []*mysql.LB) {
 	// p.cache.AddLBs(addedDBItems)
 	p.eventProducer.ProduceByAdd(addedDBItems)
+	publishByDBItems(RESOURCE_TYPE_LB_EN, addedDBItems)
 }
 
 func (p *LB) OnUpdaterUpdated(cloudItem *cloudmodel.LB, diffBase *cache.LB) {
 	p.eventProducer.ProduceByUpdate(cloudItem, diffBase)
+	publishByLcuuids(RESOURCE_TYPE_LB_EN, []string{diffBase.Lcuuid}, p.cache.ToolDataSet.GetLBIDByLcuuid)
 	// diffBase.Update(cloudItem)
 	// p.cache.UpdateLB(cloudItem)
 }
 
 func (p *LB) OnUpdaterDeleted(lcuuids []string) {
 	p.eventProducer.ProduceByDelete(lcuuids)
+	publishByLcuuids(RESOURCE_TYPE_LB_EN, lcuuids, p.cache.ToolDataSet.GetLBIDByLcuuid)
 	// p.cache.DeleteLBs(lcuuids)
 }
@@ -20,6 +20,7 @@ import (
 	cloudmodel "github.com/deepflowio/deepflow/server/controller/cloud/model"
 	"github.com/deepflowio/deepflow/server/controller/db/mysql"
 	"github.com/deepflowio/deepflow/server/controller/recorder/cache"
+	. "github.com/deepflowio/deepflow/server/controller/recorder/common"
 	"github.com/deepflowio/deepflow/server/controller/recorder/event"
 	"github.com/deepflowio/deepflow/server/libs/queue"
 )
@@ -40,15 +41,18 @@ func NewNATGateway(c *cache.Cache, eq *queue.OverwriteQueue) *NATGateway {
 func (p *NATGateway) OnUpdaterAdded(addedDBItems []*mysql.NATGateway) {
 	// p.cache.AddNATGateways(addedDBItems)
 	p.eventProducer.ProduceByAdd(addedDBItems)
+	publishByDBItems(RESOURCE_TYPE_NAT_GATEWAY_EN, addedDBItems)
 }
 
 func (p *NATGateway) OnUpdaterUpdated(cloudItem *cloudmodel.NATGateway, diffBase *cache.NATGateway) {
 	p.eventProducer.ProduceByUpdate(cloudItem, diffBase)
+	publishByLcuuids(RESOURCE_TYPE_NAT_GATEWAY_EN, []string{diffBase.Lcuuid}, p.cache.ToolDataSet.GetNATGatewayIDByLcuuid)
 	// diffBase.Update(cloudItem)
 	// p.cache.UpdateNATGateway(cloudItem)
 }
 
 func (p *NATGateway) OnUpdaterDeleted(lcuuids []string) {
 	p.eventProducer.ProduceByDelete(lcuuids)
+	publishByLcuuids(RESOURCE_TYPE_NAT_GATEWAY_EN, lcuuids, p.cache.ToolDataSet.GetNATGatewayIDByLcuuid)
 	// p.cache.DeleteNATGateways(lcuuids)
 }
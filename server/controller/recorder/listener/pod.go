@@ -20,6 +20,7 @@ import (
 	cloudmodel "github.com/deepflowio/deepflow/server/controller/cloud/model"
 	"github.com/deepflowio/deepflow/server/controller/db/mysql"
 	"github.com/deepflowio/deepflow/server/controller/recorder/cache"
+	. "github.com/deepflowio/deepflow/server/controller/recorder/common"
 	"github.com/deepflowio/deepflow/server/controller/recorder/event"
 	"github.com/deepflowio/deepflow/server/libs/queue"
 )
@@ -40,15 +41,18 @@ func NewPod(c *cache.Cache, eq *queue.OverwriteQueue) *Pod {
 func (p *Pod) OnUpdaterAdded(addedDBItems []*mysql.Pod) {
 	// p.cache.AddPods(addedDBItems)
 	p.eventProducer.ProduceByAdd(addedDBItems)
+	publishByDBItems(RESOURCE_TYPE_POD_EN, addedDBItems)
 }
 
 func (p *Pod) OnUpdaterUpdated(cloudItem *cloudmodel.Pod, diffBase *cache.Pod) {
 	p.eventProducer.ProduceByUpdate(cloudItem, diffBase)
+	publishByLcuuids(RESOURCE_TYPE_POD_EN, []string{diffBase.Lcuuid}, p.cache.ToolDataSet.GetPodIDByLcuuid)
 	// diffBase.Update(cloudItem)
 	// p.cache.UpdatePod(cloudItem)
 }
 
 func (p *Pod) OnUpdaterDeleted(lcuuids []string) {
 	p.eventProducer.ProduceByDelete(lcuuids)
+	publishByLcuuids(RESOURCE_TYPE_POD_EN, lcuuids, p.cache.ToolDataSet.GetPodIDByLcuuid)
 	// p.cache.DeletePods(lcuuids)
 }
@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package listener
+
+import (
+	cloudmodel "github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	"github.com/deepflowio/deepflow/server/controller/recorder/cache"
+	"github.com/deepflowio/deepflow/server/controller/recorder/event"
+	"github.com/deepflowio/deepflow/server/libs/queue"
+)
+
+type PodIngress struct {
+	cache         *cache.Cache
+	eventProducer *event.PodIngress
+	// 本轮同步中已产生事件的 ingress, 其规则和后端的变化不再重复产生事件
+	changedIDs map[int]bool
+}
+
+func NewPodIngress(c *cache.Cache, eq *queue.OverwriteQueue) *PodIngress {
+	lisener := &PodIngress{
+		cache:         c,
+		eventProducer: event.NewPodIngress(&c.ToolDataSet, eq),
+		changedIDs:    make(map[int]bool),
+	}
+	return lisener
+}
+
+func (p *PodIngress) OnUpdaterAdded(addedDBItems []*mysql.PodIngress) {
+	for _, item := range addedDBItems {
+		p.changedIDs[item.ID] = true
+	}
+	p.eventProducer.ProduceByAdd(addedDBItems)
+}
+
+func (p *PodIngress) OnUpdaterUpdated(cloudItem *cloudmodel.PodIngress, diffBase *cache.PodIngress) {
+	if p.eventProducer.ProduceByUpdate(cloudItem, diffBase) {
+		if id, ok := p.cache.ToolDataSet.GetPodIngressIDByLcuuid(diffBase.Lcuuid); ok {
+			p.changedIDs[id] = true
+		}
+	}
+}
+
+// OnRulesChanged 由 ingress rule 和 rule backend 的增删改触发, 每个 ingress 每轮同步只产生一个配置变更事件,
+// 本轮将被删除的 ingress(规则先于 ingress 删除) 不产生事件
+func (p *PodIngress) OnRulesChanged(podIngressIDs ...int) {
+	for _, id := range podIngressIDs {
+		if p.changedIDs[id] {
+			continue
+		}
+		lcuuid, ok := p.cache.ToolDataSet.GetPodIngressLcuuidByID(id)
+		if !ok {
+			continue
+		}
+		diffBase, ok := p.cache.PodIngresses[lcuuid]
+		if !ok || diffBase.GetSequence() != p.cache.GetSequence() {
+			continue
+		}
+		p.changedIDs[id] = true
+		p.eventProducer.ProduceByRuleChange(id, diffBase)
+	}
+}
+
+func (p *PodIngress) OnUpdaterDeleted(lcuuids []string) {
+	p.eventProducer.ProduceByDelete(lcuuids)
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package listener
+
+import (
+	cloudmodel "github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	"github.com/deepflowio/deepflow/server/controller/recorder/cache"
+)
+
+// PodIngressRule 将规则的增删转换为所属 ingress 的配置变更事件
+type PodIngressRule struct {
+	cache              *cache.Cache
+	podIngressListener *PodIngress
+}
+
+func NewPodIngressRule(c *cache.Cache, podIngressListener *PodIngress) *PodIngressRule {
+	return &PodIngressRule{
+		cache:              c,
+		podIngressListener: podIngressListener,
+	}
+}
+
+func (r *PodIngressRule) OnUpdaterAdded(addedDBItems []*mysql.PodIngressRule) {
+	for _, item := range addedDBItems {
+		r.podIngressListener.OnRulesChanged(item.PodIngressID)
+	}
+}
+
+func (r *PodIngressRule) OnUpdaterUpdated(cloudItem *cloudmodel.PodIngressRule, diffBase *cache.PodIngressRule) {
+}
+
+func (r *PodIngressRule) OnUpdaterDeleted(lcuuids []string) {
+	for _, lcuuid := range lcuuids {
+		if diffBase, ok := r.cache.PodIngressRules[lcuuid]; ok {
+			r.podIngressListener.OnRulesChanged(diffBase.PodIngressID)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package listener
+
+import (
+	cloudmodel "github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	"github.com/deepflowio/deepflow/server/controller/recorder/cache"
+)
+
+// PodIngressRuleBackend 将后端的增删及权重变化转换为所属 ingress 的配置变更事件
+type PodIngressRuleBackend struct {
+	cache              *cache.Cache
+	podIngressListener *PodIngress
+}
+
+func NewPodIngressRuleBackend(c *cache.Cache, podIngressListener *PodIngress) *PodIngressRuleBackend {
+	return &PodIngressRuleBackend{
+		cache:              c,
+		podIngressListener: podIngressListener,
+	}
+}
+
+func (b *PodIngressRuleBackend) OnUpdaterAdded(addedDBItems []*mysql.PodIngressRuleBackend) {
+	for _, item := range addedDBItems {
+		b.podIngressListener.OnRulesChanged(item.PodIngressID)
+	}
+}
+
+func (b *PodIngressRuleBackend) OnUpdaterUpdated(cloudItem *cloudmodel.PodIngressRuleBackend, diffBase *cache.PodIngressRuleBackend) {
+	b.podIngressListener.OnRulesChanged(diffBase.PodIngressID)
+}
+
+func (b *PodIngressRuleBackend) OnUpdaterDeleted(lcuuids []string) {
+	for _, lcuuid := range lcuuids {
+		if diffBase, ok := b.cache.PodIngressRuleBackends[lcuuid]; ok {
+			b.podIngressListener.OnRulesChanged(diffBase.PodIngressID)
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 	cloudmodel "github.com/deepflowio/deepflow/server/controller/cloud/model"
 	"github.com/deepflowio/deepflow/server/controller/db/mysql"
 	"github.com/deepflowio/deepflow/server/controller/recorder/cache"
+	. "github.com/deepflowio/deepflow/server/controller/recorder/common"
 	"github.com/deepflowio/deepflow/server/controller/recorder/event"
 	"github.com/deepflowio/deepflow/server/libs/queue"
 )
@@ -40,15 +41,18 @@ func NewPodNode(c *cache.Cache, eq *queue.OverwriteQueue) *PodNode {
 func (p *PodNode) OnUpdaterAdded(addedDBItems []*mysql.PodNode) {
 	// p.cache.AddPodNodes(addedDBItems)
 	p.eventProducer.ProduceByAdd(addedDBItems)
+	publishByDBItems(RESOURCE_TYPE_POD_NODE_EN, addedDBItems)
 }
 
 func (p *PodNode) OnUpdaterUpdated(cloudItem *cloudmodel.PodNode, diffBase *cache.PodNode) {
 	p.eventProducer.ProduceByUpdate(cloudItem, diffBase)
+	publishByLcuuids(RESOURCE_TYPE_POD_NODE_EN, []string{diffBase.Lcuuid}, p.cache.ToolDataSet.GetPodNodeIDByLcuuid)
 	// diffBase.Update(cloudItem)
 	// p.cache.UpdatePodNode(cloudItem)
 }
 
 func (p *PodNode) OnUpdaterDeleted(lcuuids []string) {
 	p.eventProducer.ProduceByDelete(lcuuids)
+	publishByLcuuids(RESOURCE_TYPE_POD_NODE_EN, lcuuids, p.cache.ToolDataSet.GetPodNodeIDByLcuuid)
 	// p.cache.DeletePodNodes(lcuuids)
 }
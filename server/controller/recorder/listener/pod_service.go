@@ -20,6 +20,7 @@ import (
 	cloudmodel "github.com/deepflowio/deepflow/server/controller/cloud/model"
 	"github.com/deepflowio/deepflow/server/controller/db/mysql"
 	"github.com/deepflowio/deepflow/server/controller/recorder/cache"
+	. "github.com/deepflowio/deepflow/server/controller/recorder/common"
 	"github.com/deepflowio/deepflow/server/controller/recorder/event"
 	"github.com/deepflowio/deepflow/server/libs/queue"
 )
@@ -40,15 +41,18 @@ func NewPodService(c *cache.Cache, eq *queue.OverwriteQueue) *PodService {
 func (p *PodService) OnUpdaterAdded(addedDBItems []*mysql.PodService) {
 	// p.cache.AddPodServices(addedDBItems)
 	p.eventProducer.ProduceByAdd(addedDBItems)
+	publishByDBItems(RESOURCE_TYPE_POD_SERVICE_EN, addedDBItems)
 }
 
 func (p *PodService) OnUpdaterUpdated(cloudItem *cloudmodel.PodService, diffBase *cache.PodService) {
 	p.eventProducer.ProduceByUpdate(cloudItem, diffBase)
+	publishByLcuuids(RESOURCE_TYPE_POD_SERVICE_EN, []string{diffBase.Lcuuid}, p.cache.ToolDataSet.GetPodServiceIDByLcuuid)
 	// diffBase.Update(cloudItem)
 	// p.cache.UpdatePodService(cloudItem)
 }
 
 func (p *PodService) OnUpdaterDeleted(lcuuids []string) {
 	p.eventProducer.ProduceByDelete(lcuuids)
+	publishByLcuuids(RESOURCE_TYPE_POD_SERVICE_EN, lcuuids, p.cache.ToolDataSet.GetPodServiceIDByLcuuid)
 	// p.cache.DeletePodServices(lcuuids)
 }
@@ -20,6 +20,7 @@ import (
 	cloudmodel "github.com/deepflowio/deepflow/server/controller/cloud/model"
 	"github.com/deepflowio/deepflow/server/controller/db/mysql"
 	"github.com/deepflowio/deepflow/server/controller/recorder/cache"
+	. "github.com/deepflowio/deepflow/server/controller/recorder/common"
 	"github.com/deepflowio/deepflow/server/controller/recorder/event"
 	"github.com/deepflowio/deepflow/server/libs/queue"
 )
@@ -40,14 +41,17 @@ func NewRDSInstance(c *cache.Cache, eq *queue.OverwriteQueue) *RDSInstance {
 func (p *RDSInstance) OnUpdaterAdded(addedDBItems []*mysql.RDSInstance) {
 	// p.cache.AddRDSInstances(addedDBItems)
 	p.eventProducer.ProduceByAdd(addedDBItems)
+	publishByDBItems(RESOURCE_TYPE_RDS_INSTANCE_EN, addedDBItems)
 }
 
 func (p *RDSInstance) OnUpdaterUpdated(cloudItem *cloudmodel.RDSInstance, diffBase *cache.RDSInstance) {
+	publishByLcuuids(RESOURCE_TYPE_RDS_INSTANCE_EN, []string{diffBase.Lcuuid}, p.cache.ToolDataSet.GetRDSInstanceIDByLcuuid)
 	// diffBase.Update(cloudItem)
 	// p.cache.UpdateRDSInstance(cloudItem)
 }
 
 func (p *RDSInstance) OnUpdaterDeleted(lcuuids []string) {
 	p.eventProducer.ProduceByDelete(lcuuids)
+	publishByLcuuids(RESOURCE_TYPE_RDS_INSTANCE_EN, lcuuids, p.cache.ToolDataSet.GetRDSInstanceIDByLcuuid)
 	// p.cache.DeleteRDSInstances(lcuuids)
 }
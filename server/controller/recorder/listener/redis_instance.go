@@ -20,6 +20,7 @@ import (
 	cloudmodel "github.com/deepflowio/deepflow/server/controller/cloud/model"
 	"github.com/deepflowio/deepflow/server/controller/db/mysql"
 	"github.com/deepflowio/deepflow/server/controller/recorder/cache"
+	. "github.com/deepflowio/deepflow/server/controller/recorder/common"
 	"github.com/deepflowio/deepflow/server/controller/recorder/event"
 	"github.com/deepflowio/deepflow/server/libs/queue"
 )
@@ -40,14 +41,17 @@ func NewRedisInstance(c *cache.Cache, eq *queue.OverwriteQueue) *RedisInstance {
 func (p *RedisInstance) OnUpdaterAdded(addedDBItems []*mysql.RedisInstance) {
 	// p.cache.AddRedisInstances(addedDBItems)
 	p.eventProducer.ProduceByAdd(addedDBItems)
+	publishByDBItems(RESOURCE_TYPE_REDIS_INSTANCE_EN, addedDBItems)
 }
 
 func (p *RedisInstance) OnUpdaterUpdated(cloudItem *cloudmodel.RedisInstance, diffBase *cache.RedisInstance) {
+	publishByLcuuids(RESOURCE_TYPE_REDIS_INSTANCE_EN, []string{diffBase.Lcuuid}, p.cache.ToolDataSet.GetRedisInstanceIDByLcuuid)
 	// diffBase.Update(cloudItem)
 	// p.cache.UpdateRedisInstance(cloudItem)
 }
 
 func (p *RedisInstance) OnUpdaterDeleted(lcuuids []string) {
 	p.eventProducer.ProduceByDelete(lcuuids)
+	publishByLcuuids(RESOURCE_TYPE_REDIS_INSTANCE_EN, lcuuids, p.cache.ToolDataSet.GetRedisInstanceIDByLcuuid)
 	// p.cache.DeleteRedisInstances(lcuuids)
 }
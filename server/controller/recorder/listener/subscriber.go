@@ -0,0 +1,69 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package listener
+
+import (
+	"sync"
+
+	"github.com/deepflowio/deepflow/server/controller/recorder/constraint"
+)
+
+// ResourceChangeSubscriber 订阅recorder的资源变更，资源增删改写入db后回调
+// 回调在recorder刷新的goroutine中执行，实现方不应阻塞
+type ResourceChangeSubscriber interface {
+	OnResourceChanged(resourceType string, ids []int)
+}
+
+var (
+	subscribersLock sync.RWMutex
+	subscribers     []ResourceChangeSubscriber
+)
+
+func Subscribe(s ResourceChangeSubscriber) {
+	subscribersLock.Lock()
+	defer subscribersLock.Unlock()
+	subscribers = append(subscribers, s)
+}
+
+func publish(resourceType string, ids []int) {
+	if len(ids) == 0 {
+		return
+	}
+	subscribersLock.RLock()
+	defer subscribersLock.RUnlock()
+	for _, s := range subscribers {
+		s.OnResourceChanged(resourceType, ids)
+	}
+}
+
+func publishByLcuuids(resourceType string, lcuuids []string, getIDByLcuuid func(string) (int, bool)) {
+	ids := make([]int, 0, len(lcuuids))
+	for _, lcuuid := range lcuuids {
+		if id, ok := getIDByLcuuid(lcuuid); ok {
+			ids = append(ids, id)
+		}
+	}
+	publish(resourceType, ids)
+}
+
+func publishByDBItems[MT constraint.MySQLModel](resourceType string, dbItems []*MT) {
+	ids := make([]int, 0, len(dbItems))
+	for _, item := range dbItems {
+		ids = append(ids, (*item).GetID())
+	}
+	publish(resourceType, ids)
+}
@@ -20,6 +20,7 @@ import (
 	cloudmodel "github.com/deepflowio/deepflow/server/controller/cloud/model"
 	"github.com/deepflowio/deepflow/server/controller/db/mysql"
 	"github.com/deepflowio/deepflow/server/controller/recorder/cache"
+	. "github.com/deepflowio/deepflow/server/controller/recorder/common"
 	"github.com/deepflowio/deepflow/server/controller/recorder/event"
 	"github.com/deepflowio/deepflow/server/libs/queue"
 )
@@ -40,15 +41,18 @@ func NewVM(c *cache.Cache, eq *queue.OverwriteQueue) *VM {
 func (p *VM) OnUpdaterAdded(addedDBItems []*mysql.VM) {
 	// p.cache.AddVMs(addedDBItems)
 	p.eventProducer.ProduceByAdd(addedDBItems)
+	publishByDBItems(RESOURCE_TYPE_VM_EN, addedDBItems)
 }
 
 func (p *VM) OnUpdaterUpdated(cloudItem *cloudmodel.VM, diffBase *cache.VM) {
 	p.eventProducer.ProduceByUpdate(cloudItem, diffBase)
+	publishByLcuuids(RESOURCE_TYPE_VM_EN, []string{diffBase.Lcuuid}, p.cache.ToolDataSet.GetVMIDByLcuuid)
 	// diffBase.Update(cloudItem)
 	// p.cache.UpdateVM(cloudItem)
 }
 
 func (p *VM) OnUpdaterDeleted(lcuuids []string) {
 	p.eventProducer.ProduceByDelete(lcuuids)
+	publishByLcuuids(RESOURCE_TYPE_VM_EN, lcuuids, p.cache.ToolDataSet.GetVMIDByLcuuid)
 	// p.cache.DeleteVMs(lcuuids)
 }
@@ -20,6 +20,7 @@ import (
 	cloudmodel "github.com/deepflowio/deepflow/server/controller/cloud/model"
 	"github.com/deepflowio/deepflow/server/controller/db/mysql"
 	"github.com/deepflowio/deepflow/server/controller/recorder/cache"
+	. "github.com/deepflowio/deepflow/server/controller/recorder/common"
 	"github.com/deepflowio/deepflow/server/controller/recorder/event"
 	"github.com/deepflowio/deepflow/server/libs/queue"
 )
@@ -40,15 +41,18 @@ func NewVRouter(c *cache.Cache, eq *queue.OverwriteQueue) *VRouter {
 func (p *VRouter) OnUpdaterAdded(addedDBItems []*mysql.VRouter) {
 	// p.cache.AddVRouters(addedDBItems)
 	p.eventProducer.ProduceByAdd(addedDBItems)
+	publishByDBItems(RESOURCE_TYPE_VROUTER_EN, addedDBItems)
 }
 
 func (p *VRouter) OnUpdaterUpdated(cloudItem *cloudmodel.VRouter, diffBase *cache.VRouter) {
 	p.eventProducer.ProduceByUpdate(cloudItem, diffBase)
+	publishByLcuuids(RESOURCE_TYPE_VROUTER_EN, []string{diffBase.Lcuuid}, p.cache.ToolDataSet.GetVRouterIDByLcuuid)
 	// diffBase.Update(cloudItem)
 	// p.cache.UpdateVRouter(cloudItem)
 }
 
 func (p *VRouter) OnUpdaterDeleted(lcuuids []string) {
 	p.eventProducer.ProduceByDelete(lcuuids)
+	publishByLcuuids(RESOURCE_TYPE_VROUTER_EN, lcuuids, p.cache.ToolDataSet.GetVRouterIDByLcuuid)
 	// p.cache.DeleteVRouters(lcuuids)
 }
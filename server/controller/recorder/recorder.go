@@ -29,6 +29,7 @@ import (
 	"github.com/deepflowio/deepflow/server/controller/db/mysql"
 	"github.com/deepflowio/deepflow/server/controller/recorder/cache"
 	"github.com/deepflowio/deepflow/server/controller/recorder/config"
+	"github.com/deepflowio/deepflow/server/controller/recorder/event"
 	"github.com/deepflowio/deepflow/server/controller/recorder/listener"
 	"github.com/deepflowio/deepflow/server/controller/recorder/updater"
 	"github.com/deepflowio/deepflow/server/libs/queue"
@@ -44,6 +45,9 @@ type Recorder struct {
 	cacheMng     *cache.CacheManager
 	canRefresh   chan bool // 一个recorder中需要保证，同一时间只有一个goroutine在操作cache
 	eventQueue   *queue.OverwriteQueue
+
+	// 以 domain/sub_domain lcuuid 为 key，跨同步周期保留 Kubernetes 事件的去重状态
+	kubernetesEventProducers map[string]*event.KubernetesEvent
 }
 
 func NewRecorder(domainLcuuid string, cfg config.RecorderConfig, ctx context.Context, eventQueue *queue.OverwriteQueue) *Recorder {
@@ -54,6 +58,8 @@ func NewRecorder(domainLcuuid string, cfg config.RecorderConfig, ctx context.Con
 		cacheMng:     cache.NewCacheManager(domainLcuuid),
 		canRefresh:   make(chan bool, 1),
 		eventQueue:   eventQueue,
+
+		kubernetesEventProducers: make(map[string]*event.KubernetesEvent),
 	}
 }
 
@@ -168,6 +174,7 @@ func (r *Recorder) refreshDomain(cloudData cloudmodel.Resource) {
 	domainUpdatersInUpdateOrder := r.getDomainUpdatersInOrder(cloudData)
 	r.executeUpdators(domainUpdatersInUpdateOrder)
 	listener.OnUpdatersCompeleted()
+	r.produceKubernetesEvents(r.domainLcuuid, &r.cacheMng.DomainCache.ToolDataSet, cloudData.KubernetesEvents)
 	log.Infof("domain (lcuuid: %s, name: %s) refresh completed", r.domainLcuuid, r.domainName)
 }
 
@@ -181,6 +188,19 @@ func (r *Recorder) getDomainUpdatersInOrder(cloudData cloudmodel.Resource) []upd
 	podNode.RegisterCallbacks(
 		podNodeListener.OnUpdaterAdded, podNodeListener.OnUpdaterUpdated, podNodeListener.OnUpdaterDeleted)
 
+	podIngressListener := listener.NewPodIngress(r.cacheMng.DomainCache, r.eventQueue)
+	podIngress := updater.NewPodIngress(r.cacheMng.DomainCache, cloudData.PodIngresses)
+	podIngress.RegisterCallbacks(
+		podIngressListener.OnUpdaterAdded, podIngressListener.OnUpdaterUpdated, podIngressListener.OnUpdaterDeleted)
+	podIngressRuleListener := listener.NewPodIngressRule(r.cacheMng.DomainCache, podIngressListener)
+	podIngressRule := updater.NewPodIngressRule(r.cacheMng.DomainCache, cloudData.PodIngressRules)
+	podIngressRule.RegisterCallbacks(
+		podIngressRuleListener.OnUpdaterAdded, podIngressRuleListener.OnUpdaterUpdated, podIngressRuleListener.OnUpdaterDeleted)
+	podIngressRuleBackendListener := listener.NewPodIngressRuleBackend(r.cacheMng.DomainCache, podIngressListener)
+	podIngressRuleBackend := updater.NewPodIngressRuleBackend(r.cacheMng.DomainCache, cloudData.PodIngressRuleBackends)
+	podIngressRuleBackend.RegisterCallbacks(podIngressRuleBackendListener.OnUpdaterAdded,
+		podIngressRuleBackendListener.OnUpdaterUpdated, podIngressRuleBackendListener.OnUpdaterDeleted)
+
 	podServiceListener := listener.NewPodService(r.cacheMng.DomainCache, r.eventQueue)
 	podService := updater.NewPodService(r.cacheMng.DomainCache, cloudData.PodServices)
 	podService.RegisterCallbacks(
@@ -247,10 +267,10 @@ func (r *Recorder) getDomainUpdatersInOrder(cloudData cloudmodel.Resource) []upd
 		updater.NewPodCluster(r.cacheMng.DomainCache, cloudData.PodClusters),
 		podNode,
 		updater.NewPodNamespace(r.cacheMng.DomainCache, cloudData.PodNamespaces),
-		updater.NewPodIngress(r.cacheMng.DomainCache, cloudData.PodIngresses),
-		updater.NewPodIngressRule(r.cacheMng.DomainCache, cloudData.PodIngressRules),
+		podIngress,
+		podIngressRule,
 		podService,
-		updater.NewPodIngressRuleBackend(r.cacheMng.DomainCache, cloudData.PodIngressRuleBackends),
+		podIngressRuleBackend,
 		updater.NewPodServicePort(r.cacheMng.DomainCache, cloudData.PodServicePorts),
 		updater.NewPodGroup(r.cacheMng.DomainCache, cloudData.PodGroups),
 		updater.NewPodGroupPort(r.cacheMng.DomainCache, cloudData.PodGroupPorts),
@@ -311,6 +331,9 @@ func (r *Recorder) refreshSubDomains(cloudSubDomainResourceMap map[string]cloudm
 		subDomainUpdatersInUpdateOrder := r.getSubDomainUpdatersInOrder(subDomainLcuuid, subDomainResource, nil, nil)
 		r.executeUpdators(subDomainUpdatersInUpdateOrder)
 		listener.OnUpdatersCompeleted()
+		r.produceKubernetesEvents(
+			subDomainLcuuid, &r.cacheMng.CreateSubDomainCacheIfNotExists(subDomainLcuuid).ToolDataSet, subDomainResource.KubernetesEvents,
+		)
 
 		log.Infof("sub_domain (lcuuid: %s) sync refresh completed", subDomainLcuuid)
 	}
@@ -322,6 +345,7 @@ func (r *Recorder) refreshSubDomains(cloudSubDomainResourceMap map[string]cloudm
 			log.Infof("sub_domain (lcuuid: %s) clean refresh started", subDomainLcuuid)
 			subDomainUpdatersInUpdateOrder := r.getSubDomainUpdatersInOrder(subDomainLcuuid, cloudmodel.SubDomainResource{}, subDomainCache, &r.cacheMng.DomainCache.ToolDataSet)
 			r.executeUpdators(subDomainUpdatersInUpdateOrder)
+			delete(r.kubernetesEventProducers, subDomainLcuuid)
 			log.Infof("sub_domain (lcuuid: %s) clean refresh completed", subDomainLcuuid)
 		}
 	}
@@ -347,6 +371,19 @@ func (r *Recorder) getSubDomainUpdatersInOrder(subDomainLcuuid string, cloudData
 	podNode.RegisterCallbacks(
 		podNodeListener.OnUpdaterAdded, podNodeListener.OnUpdaterUpdated, podNodeListener.OnUpdaterDeleted)
 
+	podIngressListener := listener.NewPodIngress(subDomainCache, r.eventQueue)
+	podIngress := updater.NewPodIngress(subDomainCache, cloudData.PodIngresses)
+	podIngress.RegisterCallbacks(
+		podIngressListener.OnUpdaterAdded, podIngressListener.OnUpdaterUpdated, podIngressListener.OnUpdaterDeleted)
+	podIngressRuleListener := listener.NewPodIngressRule(subDomainCache, podIngressListener)
+	podIngressRule := updater.NewPodIngressRule(subDomainCache, cloudData.PodIngressRules)
+	podIngressRule.RegisterCallbacks(
+		podIngressRuleListener.OnUpdaterAdded, podIngressRuleListener.OnUpdaterUpdated, podIngressRuleListener.OnUpdaterDeleted)
+	podIngressRuleBackendListener := listener.NewPodIngressRuleBackend(subDomainCache, podIngressListener)
+	podIngressRuleBackend := updater.NewPodIngressRuleBackend(subDomainCache, cloudData.PodIngressRuleBackends)
+	podIngressRuleBackend.RegisterCallbacks(podIngressRuleBackendListener.OnUpdaterAdded,
+		podIngressRuleBackendListener.OnUpdaterUpdated, podIngressRuleBackendListener.OnUpdaterDeleted)
+
 	podServiceListener := listener.NewPodService(subDomainCache, r.eventQueue)
 	podService := updater.NewPodService(subDomainCache, cloudData.PodServices)
 	podService.RegisterCallbacks(
@@ -364,10 +401,10 @@ func (r *Recorder) getSubDomainUpdatersInOrder(subDomainLcuuid string, cloudData
 		updater.NewPodCluster(subDomainCache, cloudData.PodClusters),
 		podNode,
 		updater.NewPodNamespace(subDomainCache, cloudData.PodNamespaces),
-		updater.NewPodIngress(subDomainCache, cloudData.PodIngresses),
-		updater.NewPodIngressRule(subDomainCache, cloudData.PodIngressRules),
+		podIngress,
+		podIngressRule,
 		podService,
-		updater.NewPodIngressRuleBackend(subDomainCache, cloudData.PodIngressRuleBackends),
+		podIngressRuleBackend,
 		updater.NewPodServicePort(subDomainCache, cloudData.PodServicePorts),
 		updater.NewPodGroup(subDomainCache, cloudData.PodGroups),
 		updater.NewPodGroupPort(subDomainCache, cloudData.PodGroupPorts),
@@ -382,6 +419,18 @@ func (r *Recorder) getSubDomainUpdatersInOrder(subDomainLcuuid string, cloudData
 	}
 }
 
+func (r *Recorder) produceKubernetesEvents(lcuuid string, toolDS *cache.ToolDataSet, items []cloudmodel.KubernetesEvent) {
+	producer, ok := r.kubernetesEventProducers[lcuuid]
+	if !ok {
+		if len(items) == 0 {
+			return
+		}
+		producer = event.NewKubernetesEvent(r.eventQueue)
+		r.kubernetesEventProducers[lcuuid] = producer
+	}
+	producer.ProduceByCloudItems(toolDS, items)
+}
+
 func (r *Recorder) executeUpdators(updatersInUpdateOrder []updater.ResourceUpdater) {
 	for _, updater := range updatersInUpdateOrder {
 		updater.HandleAddAndUpdate()
@@ -66,6 +66,7 @@ func (i *PodIngress) generateDBItemToAdd(cloudItem *cloudmodel.PodIngress) (*mys
 	}
 	dbItem := &mysql.PodIngress{
 		Name:           cloudItem.Name,
+		Type:           cloudItem.Type,
 		PodNamespaceID: podNamespaceID,
 		PodClusterID:   podClusterID,
 		SubDomain:      cloudItem.SubDomainLcuuid,
@@ -82,6 +83,9 @@ func (i *PodIngress) generateUpdateInfo(diffBase *cache.PodIngress, cloudItem *c
 	if diffBase.Name != cloudItem.Name {
 		updateInfo["name"] = cloudItem.Name
 	}
+	if diffBase.Type != cloudItem.Type {
+		updateInfo["type"] = cloudItem.Type
+	}
 	if diffBase.RegionLcuuid != cloudItem.RegionLcuuid {
 		updateInfo["region"] = cloudItem.RegionLcuuid
 	}
@@ -76,6 +76,7 @@ func (b *PodIngressRuleBackend) generateDBItemToAdd(cloudItem *cloudmodel.PodIng
 	dbItem := &mysql.PodIngressRuleBackend{
 		Path:             cloudItem.Path,
 		Port:             cloudItem.Port,
+		Weight:           cloudItem.Weight,
 		PodServiceID:     podServiceID,
 		PodIngressID:     podIngressID,
 		PodIngressRuleID: podIngressRuleID,
@@ -85,8 +86,15 @@ func (b *PodIngressRuleBackend) generateDBItemToAdd(cloudItem *cloudmodel.PodIng
 	return dbItem, true
 }
 
-// 保留接口
 func (b *PodIngressRuleBackend) generateUpdateInfo(diffBase *cache.PodIngressRuleBackend, cloudItem *cloudmodel.PodIngressRuleBackend) (map[string]interface{}, bool) {
+	updateInfo := make(map[string]interface{})
+	if diffBase.Weight != cloudItem.Weight {
+		updateInfo["weight"] = cloudItem.Weight
+	}
+
+	if len(updateInfo) > 0 {
+		return updateInfo, true
+	}
 	return nil, false
 }
 
@@ -94,8 +102,8 @@ func (b *PodIngressRuleBackend) addCache(dbItems []*mysql.PodIngressRuleBackend)
 	b.cache.AddPodIngressRuleBackends(dbItems)
 }
 
-// 保留接口
 func (b *PodIngressRuleBackend) updateCache(cloudItem *cloudmodel.PodIngressRuleBackend, diffBase *cache.PodIngressRuleBackend) {
+	diffBase.Update(cloudItem)
 }
 
 func (b *PodIngressRuleBackend) deleteCache(lcuuids []string) {
@@ -19,6 +19,7 @@ package tagrecorder
 import (
 	"github.com/deepflowio/deepflow/server/controller/common"
 	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	rcommon "github.com/deepflowio/deepflow/server/controller/recorder/common"
 )
 
 type ChDevice struct {
@@ -30,65 +31,74 @@ func NewChDevice(resourceTypeToIconID map[IconKey]int) *ChDevice {
 	updater := &ChDevice{
 		UpdaterBase[mysql.ChDevice, DeviceKey]{
 			resourceTypeName: RESOURCE_TYPE_CH_DEVICE,
+			subscribedResourceTypes: []string{
+				rcommon.RESOURCE_TYPE_HOST_EN, rcommon.RESOURCE_TYPE_VM_EN, rcommon.RESOURCE_TYPE_VROUTER_EN,
+				rcommon.RESOURCE_TYPE_DHCP_PORT_EN, rcommon.RESOURCE_TYPE_NAT_GATEWAY_EN, rcommon.RESOURCE_TYPE_LB_EN,
+				rcommon.RESOURCE_TYPE_RDS_INSTANCE_EN, rcommon.RESOURCE_TYPE_REDIS_INSTANCE_EN,
+				rcommon.RESOURCE_TYPE_POD_SERVICE_EN, rcommon.RESOURCE_TYPE_POD_EN, rcommon.RESOURCE_TYPE_POD_NODE_EN,
+			},
+			// pod_group, gprocess, ip, internet 没有资源变更事件, 仍需周期全量刷新
+			hasUnsubscribedData: true,
 		},
 		resourceTypeToIconID,
 	}
 	updater.dataGenerator = updater
+	updater.incrementalDataGenerator = updater
 	return updater
 }
 
 func (d *ChDevice) generateNewData() (map[DeviceKey]mysql.ChDevice, bool) {
 	log.Infof("generate data for %s", d.resourceTypeName)
 	keyToItem := make(map[DeviceKey]mysql.ChDevice)
-	ok := d.generateHostData(keyToItem)
+	ok := d.generateHostData(keyToItem, nil)
 	if !ok {
 		return nil, false
 	}
-	ok = d.generateVMData(keyToItem)
+	ok = d.generateVMData(keyToItem, nil)
 	if !ok {
 		return nil, false
 	}
-	ok = d.generateVRouterData(keyToItem)
+	ok = d.generateVRouterData(keyToItem, nil)
 	if !ok {
 		return nil, false
 	}
-	ok = d.generateDHCPPortData(keyToItem)
+	ok = d.generateDHCPPortData(keyToItem, nil)
 	if !ok {
 		return nil, false
 	}
-	ok = d.generateNATGatewayData(keyToItem)
+	ok = d.generateNATGatewayData(keyToItem, nil)
 	if !ok {
 		return nil, false
 	}
-	ok = d.generateLBData(keyToItem)
+	ok = d.generateLBData(keyToItem, nil)
 	if !ok {
 		return nil, false
 	}
-	ok = d.generateRDSInstanceData(keyToItem)
+	ok = d.generateRDSInstanceData(keyToItem, nil)
 	if !ok {
 		return nil, false
 	}
-	ok = d.generateRedisInstanceData(keyToItem)
+	ok = d.generateRedisInstanceData(keyToItem, nil)
 	if !ok {
 		return nil, false
 	}
-	ok = d.generatePodServiceData(keyToItem)
+	ok = d.generatePodServiceData(keyToItem, nil)
 	if !ok {
 		return nil, false
 	}
-	ok = d.generatePodData(keyToItem)
+	ok = d.generatePodData(keyToItem, nil)
 	if !ok {
 		return nil, false
 	}
-	ok = d.generatePodGroupData(keyToItem)
+	ok = d.generatePodGroupData(keyToItem, nil)
 	if !ok {
 		return nil, false
 	}
-	ok = d.generatePodNodeData(keyToItem)
+	ok = d.generatePodNodeData(keyToItem, nil)
 	if !ok {
 		return nil, false
 	}
-	ok = d.generateProcessData(keyToItem)
+	ok = d.generateProcessData(keyToItem, nil)
 	if !ok {
 		return nil, false
 	}
@@ -97,6 +107,56 @@ func (d *ChDevice) generateNewData() (map[DeviceKey]mysql.ChDevice, bool) {
 	return keyToItem, true
 }
 
+func (d *ChDevice) generateNewDataByIDs(resourceType string, ids []int) (map[DeviceKey]mysql.ChDevice, bool) {
+	generate, _, ok := d.getIncrementalGenerator(resourceType)
+	if !ok {
+		return nil, false
+	}
+	keyToItem := make(map[DeviceKey]mysql.ChDevice)
+	if !generate(keyToItem, ids) {
+		return nil, false
+	}
+	return keyToItem, true
+}
+
+func (d *ChDevice) generateOldDataByIDs(resourceType string, ids []int) (map[DeviceKey]mysql.ChDevice, bool) {
+	_, deviceTypes, ok := d.getIncrementalGenerator(resourceType)
+	if !ok {
+		return nil, false
+	}
+	return d.generateOldDataWhere("devicetype IN ? AND deviceid IN ?", deviceTypes, ids)
+}
+
+// 返回recorder资源类型对应的数据生成方法及其生成的device type
+func (d *ChDevice) getIncrementalGenerator(resourceType string) (func(map[DeviceKey]mysql.ChDevice, []int) bool, []int, bool) {
+	switch resourceType {
+	case rcommon.RESOURCE_TYPE_HOST_EN:
+		return d.generateHostData, []int{common.VIF_DEVICE_TYPE_HOST}, true
+	case rcommon.RESOURCE_TYPE_VM_EN:
+		return d.generateVMData, []int{common.VIF_DEVICE_TYPE_VM}, true
+	case rcommon.RESOURCE_TYPE_VROUTER_EN:
+		return d.generateVRouterData, []int{common.VIF_DEVICE_TYPE_VROUTER}, true
+	case rcommon.RESOURCE_TYPE_DHCP_PORT_EN:
+		return d.generateDHCPPortData, []int{common.VIF_DEVICE_TYPE_DHCP_PORT}, true
+	case rcommon.RESOURCE_TYPE_NAT_GATEWAY_EN:
+		return d.generateNATGatewayData, []int{common.VIF_DEVICE_TYPE_NAT_GATEWAY}, true
+	case rcommon.RESOURCE_TYPE_LB_EN:
+		return d.generateLBData, []int{common.VIF_DEVICE_TYPE_LB}, true
+	case rcommon.RESOURCE_TYPE_RDS_INSTANCE_EN:
+		return d.generateRDSInstanceData, []int{common.VIF_DEVICE_TYPE_RDS_INSTANCE}, true
+	case rcommon.RESOURCE_TYPE_REDIS_INSTANCE_EN:
+		return d.generateRedisInstanceData, []int{common.VIF_DEVICE_TYPE_REDIS_INSTANCE}, true
+	case rcommon.RESOURCE_TYPE_POD_SERVICE_EN:
+		return d.generatePodServiceData, []int{common.VIF_DEVICE_TYPE_POD_SERVICE, CH_DEVICE_TYPE_SERVICE}, true
+	case rcommon.RESOURCE_TYPE_POD_EN:
+		return d.generatePodData, []int{common.VIF_DEVICE_TYPE_POD}, true
+	case rcommon.RESOURCE_TYPE_POD_NODE_EN:
+		return d.generatePodNodeData, []int{common.VIF_DEVICE_TYPE_POD_NODE}, true
+	}
+	log.Warningf("%s does not support incremental refresh of resource type: %s", d.resourceTypeName, resourceType)
+	return nil, nil, false
+}
+
 func (d *ChDevice) generateKey(dbItem mysql.ChDevice) DeviceKey {
 	return DeviceKey{
 		DeviceType: dbItem.DeviceType,
@@ -121,9 +181,9 @@ func (d *ChDevice) generateUpdateInfo(oldItem, newItem mysql.ChDevice) (map[stri
 	return nil, false
 }
 
-func (d *ChDevice) generateHostData(keyToItem map[DeviceKey]mysql.ChDevice) bool {
+func (d *ChDevice) generateHostData(keyToItem map[DeviceKey]mysql.ChDevice, ids []int) bool {
 	var hosts []mysql.Host
-	err := mysql.Db.Unscoped().Find(&hosts).Error
+	err := findResources(&hosts, ids)
 	if err != nil {
 		log.Errorf(dbQueryResourceFailed(d.resourceTypeName, err))
 		return false
@@ -154,9 +214,9 @@ func (d *ChDevice) generateHostData(keyToItem map[DeviceKey]mysql.ChDevice) bool
 	return true
 }
 
-func (d *ChDevice) generateVMData(keyToItem map[DeviceKey]mysql.ChDevice) bool {
+func (d *ChDevice) generateVMData(keyToItem map[DeviceKey]mysql.ChDevice, ids []int) bool {
 	var vms []mysql.VM
-	err := mysql.Db.Unscoped().Find(&vms).Error
+	err := findResources(&vms, ids)
 	if err != nil {
 		log.Errorf(dbQueryResourceFailed(d.resourceTypeName, err))
 		return false
@@ -189,9 +249,9 @@ func (d *ChDevice) generateVMData(keyToItem map[DeviceKey]mysql.ChDevice) bool {
 	return true
 }
 
-func (d *ChDevice) generateVRouterData(keyToItem map[DeviceKey]mysql.ChDevice) bool {
+func (d *ChDevice) generateVRouterData(keyToItem map[DeviceKey]mysql.ChDevice, ids []int) bool {
 	var vrouters []mysql.VRouter
-	err := mysql.Db.Unscoped().Find(&vrouters).Error
+	err := findResources(&vrouters, ids)
 	if err != nil {
 		log.Errorf(dbQueryResourceFailed(d.resourceTypeName, err))
 		return false
@@ -212,9 +272,9 @@ func (d *ChDevice) generateVRouterData(keyToItem map[DeviceKey]mysql.ChDevice) b
 	return true
 }
 
-func (d *ChDevice) generateDHCPPortData(keyToItem map[DeviceKey]mysql.ChDevice) bool {
+func (d *ChDevice) generateDHCPPortData(keyToItem map[DeviceKey]mysql.ChDevice, ids []int) bool {
 	var dhcpPorts []mysql.DHCPPort
-	err := mysql.Db.Unscoped().Find(&dhcpPorts).Error
+	err := findResources(&dhcpPorts, ids)
 	if err != nil {
 		log.Errorf(dbQueryResourceFailed(d.resourceTypeName, err))
 		return false
@@ -245,9 +305,9 @@ func (d *ChDevice) generateDHCPPortData(keyToItem map[DeviceKey]mysql.ChDevice)
 	return true
 }
 
-func (d *ChDevice) generateNATGatewayData(keyToItem map[DeviceKey]mysql.ChDevice) bool {
+func (d *ChDevice) generateNATGatewayData(keyToItem map[DeviceKey]mysql.ChDevice, ids []int) bool {
 	var natGateways []mysql.NATGateway
-	err := mysql.Db.Unscoped().Find(&natGateways).Error
+	err := findResources(&natGateways, ids)
 	if err != nil {
 		log.Errorf(dbQueryResourceFailed(d.resourceTypeName, err))
 		return false
@@ -280,9 +340,9 @@ func (d *ChDevice) generateNATGatewayData(keyToItem map[DeviceKey]mysql.ChDevice
 	return true
 }
 
-func (d *ChDevice) generateLBData(keyToItem map[DeviceKey]mysql.ChDevice) bool {
+func (d *ChDevice) generateLBData(keyToItem map[DeviceKey]mysql.ChDevice, ids []int) bool {
 	var lbs []mysql.LB
-	err := mysql.Db.Unscoped().Find(&lbs).Error
+	err := findResources(&lbs, ids)
 	if err != nil {
 		log.Errorf(dbQueryResourceFailed(d.resourceTypeName, err))
 		return false
@@ -315,9 +375,9 @@ func (d *ChDevice) generateLBData(keyToItem map[DeviceKey]mysql.ChDevice) bool {
 	return true
 }
 
-func (d *ChDevice) generateRDSInstanceData(keyToItem map[DeviceKey]mysql.ChDevice) bool {
+func (d *ChDevice) generateRDSInstanceData(keyToItem map[DeviceKey]mysql.ChDevice, ids []int) bool {
 	var rdsInstances []mysql.RDSInstance
-	err := mysql.Db.Unscoped().Find(&rdsInstances).Error
+	err := findResources(&rdsInstances, ids)
 	if err != nil {
 		log.Errorf(dbQueryResourceFailed(d.resourceTypeName, err))
 		return false
@@ -350,9 +410,9 @@ func (d *ChDevice) generateRDSInstanceData(keyToItem map[DeviceKey]mysql.ChDevic
 	return true
 }
 
-func (d *ChDevice) generateRedisInstanceData(keyToItem map[DeviceKey]mysql.ChDevice) bool {
+func (d *ChDevice) generateRedisInstanceData(keyToItem map[DeviceKey]mysql.ChDevice, ids []int) bool {
 	var redisInstances []mysql.RedisInstance
-	err := mysql.Db.Unscoped().Find(&redisInstances).Error
+	err := findResources(&redisInstances, ids)
 	if err != nil {
 		log.Errorf(dbQueryResourceFailed(d.resourceTypeName, err))
 		return false
@@ -385,9 +445,9 @@ func (d *ChDevice) generateRedisInstanceData(keyToItem map[DeviceKey]mysql.ChDev
 	return true
 }
 
-func (d *ChDevice) generatePodServiceData(keyToItem map[DeviceKey]mysql.ChDevice) bool {
+func (d *ChDevice) generatePodServiceData(keyToItem map[DeviceKey]mysql.ChDevice, ids []int) bool {
 	var podServices []mysql.PodService
-	err := mysql.Db.Unscoped().Find(&podServices).Error
+	err := findResources(&podServices, ids)
 	if err != nil {
 		log.Errorf(dbQueryResourceFailed(d.resourceTypeName, err))
 		return false
@@ -447,9 +507,9 @@ func (d *ChDevice) generatePodServiceData(keyToItem map[DeviceKey]mysql.ChDevice
 	return true
 }
 
-func (d *ChDevice) generatePodData(keyToItem map[DeviceKey]mysql.ChDevice) bool {
+func (d *ChDevice) generatePodData(keyToItem map[DeviceKey]mysql.ChDevice, ids []int) bool {
 	var pods []mysql.Pod
-	err := mysql.Db.Unscoped().Find(&pods).Error
+	err := findResources(&pods, ids)
 	if err != nil {
 		log.Errorf(dbQueryResourceFailed(d.resourceTypeName, err))
 		return false
@@ -479,9 +539,9 @@ func (d *ChDevice) generatePodData(keyToItem map[DeviceKey]mysql.ChDevice) bool
 	return true
 }
 
-func (d *ChDevice) generatePodGroupData(keyToItem map[DeviceKey]mysql.ChDevice) bool {
+func (d *ChDevice) generatePodGroupData(keyToItem map[DeviceKey]mysql.ChDevice, ids []int) bool {
 	var podGroups []mysql.PodGroup
-	err := mysql.Db.Unscoped().Find(&podGroups).Error
+	err := findResources(&podGroups, ids)
 	if err != nil {
 		log.Errorf(dbQueryResourceFailed(d.resourceTypeName, err))
 		return false
@@ -511,9 +571,9 @@ func (d *ChDevice) generatePodGroupData(keyToItem map[DeviceKey]mysql.ChDevice)
 	return true
 }
 
-func (d *ChDevice) generatePodNodeData(keyToItem map[DeviceKey]mysql.ChDevice) bool {
+func (d *ChDevice) generatePodNodeData(keyToItem map[DeviceKey]mysql.ChDevice, ids []int) bool {
 	var podNodes []mysql.PodNode
-	err := mysql.Db.Unscoped().Find(&podNodes).Error
+	err := findResources(&podNodes, ids)
 	if err != nil {
 		log.Errorf(dbQueryResourceFailed(d.resourceTypeName, err))
 		return false
@@ -568,9 +628,9 @@ func (d *ChDevice) generateInternetData(keyToItem map[DeviceKey]mysql.ChDevice)
 	}
 }
 
-func (d *ChDevice) generateProcessData(keyToItem map[DeviceKey]mysql.ChDevice) bool {
+func (d *ChDevice) generateProcessData(keyToItem map[DeviceKey]mysql.ChDevice, ids []int) bool {
 	var processes []mysql.Process
-	err := mysql.Db.Unscoped().Find(&processes).Error
+	err := findResources(&processes, ids)
 	if err != nil {
 		log.Errorf(dbQueryResourceFailed(d.resourceTypeName, err))
 		return false
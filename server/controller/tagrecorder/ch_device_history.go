@@ -0,0 +1,70 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tagrecorder
+
+import (
+	"time"
+
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+)
+
+// ChDeviceHistory 记录ch_device中资源名称的历史有效区间
+type ChDeviceHistory struct {
+	HistoryBase[mysql.ChDeviceHistory, DeviceKey]
+}
+
+func NewChDeviceHistory() *ChDeviceHistory {
+	recorder := &ChDeviceHistory{
+		HistoryBase[mysql.ChDeviceHistory, DeviceKey]{
+			resourceTypeName: RESOURCE_TYPE_CH_DEVICE_HISTORY,
+		},
+	}
+	recorder.dataGenerator = recorder
+	return recorder
+}
+
+func (d *ChDeviceHistory) generateCurrentData() (map[DeviceKey]string, bool) {
+	var devices []mysql.ChDevice
+	err := mysql.Db.Unscoped().Find(&devices).Error
+	if err != nil {
+		log.Errorf(dbQueryResourceFailed(d.resourceTypeName, err))
+		return nil, false
+	}
+	keyToName := make(map[DeviceKey]string, len(devices))
+	for _, device := range devices {
+		keyToName[DeviceKey{DeviceType: device.DeviceType, DeviceID: device.DeviceID}] = device.Name
+	}
+	return keyToName, true
+}
+
+func (d *ChDeviceHistory) generateKey(dbItem mysql.ChDeviceHistory) DeviceKey {
+	return DeviceKey{DeviceType: dbItem.DeviceType, DeviceID: dbItem.DeviceID}
+}
+
+func (d *ChDeviceHistory) generateValue(dbItem mysql.ChDeviceHistory) string {
+	return dbItem.Name
+}
+
+func (d *ChDeviceHistory) generateHistoryItem(key DeviceKey, name string, validFrom time.Time) mysql.ChDeviceHistory {
+	return mysql.ChDeviceHistory{
+		DeviceType: key.DeviceType,
+		DeviceID:   key.DeviceID,
+		Name:       name,
+		ValidFrom:  validFrom,
+		ValidTo:    CH_HISTORY_VALID_TO_MAX,
+	}
+}
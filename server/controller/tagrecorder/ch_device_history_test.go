@@ -0,0 +1,61 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tagrecorder
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	"github.com/deepflowio/deepflow/server/controller/tagrecorder/config"
+)
+
+func (t *SuiteTest) TestSyncChDeviceHistory() {
+	recorder := NewChDeviceHistory()
+	recorder.SetConfig(config.TagRecorderConfig{MySQLBatchSize: 1000, TagHistoryRetention: 30})
+	device := mysql.ChDevice{DeviceType: common.VIF_DEVICE_TYPE_POD, DeviceID: 1, Name: "pod-a"}
+	t.db.Create(&device)
+
+	recorder.Sync()
+	var items []mysql.ChDeviceHistory
+	t.db.Find(&items)
+	assert.Equal(t.T(), 1, len(items))
+	assert.Equal(t.T(), "pod-a", items[0].Name)
+	assert.True(t.T(), items[0].ValidFrom.Equal(CH_HISTORY_VALID_FROM_MIN))
+	assert.True(t.T(), items[0].ValidTo.Equal(CH_HISTORY_VALID_TO_MAX))
+
+	// 名称变化后，关闭旧记录并新增当前记录
+	t.db.Model(&device).Update("name", "pod-a (deleted)")
+	recorder.Sync()
+	var closedItems []mysql.ChDeviceHistory
+	t.db.Where("valid_to < ?", CH_HISTORY_VALID_TO_MAX).Find(&closedItems)
+	assert.Equal(t.T(), 1, len(closedItems))
+	assert.Equal(t.T(), "pod-a", closedItems[0].Name)
+	var openItems []mysql.ChDeviceHistory
+	t.db.Where("valid_to = ?", CH_HISTORY_VALID_TO_MAX).Find(&openItems)
+	assert.Equal(t.T(), 1, len(openItems))
+	assert.Equal(t.T(), "pod-a (deleted)", openItems[0].Name)
+
+	// 资源从ch_device中删除后，关闭仍有效的记录
+	t.db.Delete(&device)
+	recorder.Sync()
+	t.db.Where("valid_to = ?", CH_HISTORY_VALID_TO_MAX).Find(&openItems)
+	assert.Equal(t.T(), 0, len(openItems))
+
+	t.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&mysql.ChDeviceHistory{})
+}
@@ -18,6 +18,7 @@ package tagrecorder
 
 import (
 	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	rcommon "github.com/deepflowio/deepflow/server/controller/recorder/common"
 )
 
 type ChPod struct {
@@ -28,17 +29,23 @@ type ChPod struct {
 func NewChPod(resourceTypeToIconID map[IconKey]int) *ChPod {
 	updater := &ChPod{
 		UpdaterBase[mysql.ChPod, IDKey]{
-			resourceTypeName: RESOURCE_TYPE_CH_POD,
+			resourceTypeName:        RESOURCE_TYPE_CH_POD,
+			subscribedResourceTypes: []string{rcommon.RESOURCE_TYPE_POD_EN},
 		},
 		resourceTypeToIconID,
 	}
 	updater.dataGenerator = updater
+	updater.incrementalDataGenerator = updater
 	return updater
 }
 
 func (p *ChPod) generateNewData() (map[IDKey]mysql.ChPod, bool) {
+	return p.generateNewDataByIDs(rcommon.RESOURCE_TYPE_POD_EN, nil)
+}
+
+func (p *ChPod) generateNewDataByIDs(resourceType string, ids []int) (map[IDKey]mysql.ChPod, bool) {
 	var pods []mysql.Pod
-	err := mysql.Db.Unscoped().Find(&pods).Error
+	err := findResources(&pods, ids)
 	if err != nil {
 		log.Errorf(dbQueryResourceFailed(p.resourceTypeName, err))
 		return nil, false
@@ -63,6 +70,10 @@ func (p *ChPod) generateNewData() (map[IDKey]mysql.ChPod, bool) {
 	return keyToItem, true
 }
 
+func (p *ChPod) generateOldDataByIDs(resourceType string, ids []int) (map[IDKey]mysql.ChPod, bool) {
+	return p.generateOldDataWhere("id IN ?", ids)
+}
+
 func (p *ChPod) generateKey(dbItem mysql.ChPod) IDKey {
 	return IDKey{ID: dbItem.ID}
 }
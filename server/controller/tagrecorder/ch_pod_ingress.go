@@ -48,13 +48,15 @@ func (p *ChPodIngress) generateNewData() (map[IDKey]mysql.ChPodIngress, bool) {
 	for _, podIngress := range podIngresses {
 		if podIngress.DeletedAt.Valid {
 			keyToItem[IDKey{ID: podIngress.ID}] = mysql.ChPodIngress{
-				ID:   podIngress.ID,
-				Name: podIngress.Name + " (deleted)",
+				ID:             podIngress.ID,
+				Name:           podIngress.Name + " (deleted)",
+				PodIngressType: podIngress.Type,
 			}
 		} else {
 			keyToItem[IDKey{ID: podIngress.ID}] = mysql.ChPodIngress{
-				ID:   podIngress.ID,
-				Name: podIngress.Name,
+				ID:             podIngress.ID,
+				Name:           podIngress.Name,
+				PodIngressType: podIngress.Type,
 			}
 		}
 	}
@@ -70,6 +72,9 @@ func (p *ChPodIngress) generateUpdateInfo(oldItem, newItem mysql.ChPodIngress) (
 	if oldItem.Name != newItem.Name {
 		updateInfo["name"] = newItem.Name
 	}
+	if oldItem.PodIngressType != newItem.PodIngressType {
+		updateInfo["pod_ingress_type"] = newItem.PodIngressType
+	}
 	if len(updateInfo) > 0 {
 		return updateInfo, true
 	}
@@ -20,6 +20,7 @@ import (
 	"strings"
 
 	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	rcommon "github.com/deepflowio/deepflow/server/controller/recorder/common"
 )
 
 type ChPodK8sLabel struct {
@@ -29,18 +30,24 @@ type ChPodK8sLabel struct {
 func NewChPodK8sLabel() *ChPodK8sLabel {
 	updater := &ChPodK8sLabel{
 		UpdaterBase[mysql.ChPodK8sLabel, K8sLabelKey]{
-			resourceTypeName: RESOURCE_TYPE_CH_K8S_LABEL,
+			resourceTypeName:        RESOURCE_TYPE_CH_K8S_LABEL,
+			subscribedResourceTypes: []string{rcommon.RESOURCE_TYPE_POD_EN},
 		},
 	}
 	updater.dataGenerator = updater
+	updater.incrementalDataGenerator = updater
 	return updater
 }
 
 func (k *ChPodK8sLabel) generateNewData() (map[K8sLabelKey]mysql.ChPodK8sLabel, bool) {
+	return k.generateNewDataByIDs(rcommon.RESOURCE_TYPE_POD_EN, nil)
+}
+
+func (k *ChPodK8sLabel) generateNewDataByIDs(resourceType string, ids []int) (map[K8sLabelKey]mysql.ChPodK8sLabel, bool) {
 	var pods []mysql.Pod
 	var podGroups []mysql.PodGroup
 	var podClusters []mysql.PodCluster
-	err := mysql.Db.Unscoped().Find(&pods).Error
+	err := findResources(&pods, ids)
 	if err != nil {
 		log.Errorf(dbQueryResourceFailed(k.resourceTypeName, err))
 		return nil, false
@@ -83,6 +90,10 @@ func (k *ChPodK8sLabel) generateNewData() (map[K8sLabelKey]mysql.ChPodK8sLabel,
 	return keyToItem, true
 }
 
+func (k *ChPodK8sLabel) generateOldDataByIDs(resourceType string, ids []int) (map[K8sLabelKey]mysql.ChPodK8sLabel, bool) {
+	return k.generateOldDataWhere("id IN ?", ids)
+}
+
 func (k *ChPodK8sLabel) generateKey(dbItem mysql.ChPodK8sLabel) K8sLabelKey {
 	return K8sLabelKey{ID: dbItem.ID, Key: dbItem.Key}
 }
@@ -21,6 +21,7 @@ import (
 	"strings"
 
 	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	rcommon "github.com/deepflowio/deepflow/server/controller/recorder/common"
 )
 
 type ChPodK8sLabels struct {
@@ -30,16 +31,22 @@ type ChPodK8sLabels struct {
 func NewChPodK8sLabels() *ChPodK8sLabels {
 	updater := &ChPodK8sLabels{
 		UpdaterBase[mysql.ChPodK8sLabels, K8sLabelsKey]{
-			resourceTypeName: RESOURCE_TYPE_CH_K8S_LABELS,
+			resourceTypeName:        RESOURCE_TYPE_CH_K8S_LABELS,
+			subscribedResourceTypes: []string{rcommon.RESOURCE_TYPE_POD_EN},
 		},
 	}
 	updater.dataGenerator = updater
+	updater.incrementalDataGenerator = updater
 	return updater
 }
 
 func (k *ChPodK8sLabels) generateNewData() (map[K8sLabelsKey]mysql.ChPodK8sLabels, bool) {
+	return k.generateNewDataByIDs(rcommon.RESOURCE_TYPE_POD_EN, nil)
+}
+
+func (k *ChPodK8sLabels) generateNewDataByIDs(resourceType string, ids []int) (map[K8sLabelsKey]mysql.ChPodK8sLabels, bool) {
 	var pods []mysql.Pod
-	err := mysql.Db.Unscoped().Find(&pods).Error
+	err := findResources(&pods, ids)
 	if err != nil {
 		log.Errorf(dbQueryResourceFailed(k.resourceTypeName, err))
 		return nil, false
@@ -75,6 +82,10 @@ func (k *ChPodK8sLabels) generateNewData() (map[K8sLabelsKey]mysql.ChPodK8sLabel
 	return keyToItem, true
 }
 
+func (k *ChPodK8sLabels) generateOldDataByIDs(resourceType string, ids []int) (map[K8sLabelsKey]mysql.ChPodK8sLabels, bool) {
+	return k.generateOldDataWhere("id IN ?", ids)
+}
+
 func (k *ChPodK8sLabels) generateKey(dbItem mysql.ChPodK8sLabels) K8sLabelsKey {
 	return K8sLabelsKey{ID: dbItem.ID}
 }
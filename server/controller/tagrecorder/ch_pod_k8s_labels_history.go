@@ -0,0 +1,69 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tagrecorder
+
+import (
+	"time"
+
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+)
+
+// ChPodK8sLabelsHistory 记录ch_pod_k8s_labels中POD标签的历史有效区间
+type ChPodK8sLabelsHistory struct {
+	HistoryBase[mysql.ChPodK8sLabelsHistory, K8sLabelsKey]
+}
+
+func NewChPodK8sLabelsHistory() *ChPodK8sLabelsHistory {
+	recorder := &ChPodK8sLabelsHistory{
+		HistoryBase[mysql.ChPodK8sLabelsHistory, K8sLabelsKey]{
+			resourceTypeName: RESOURCE_TYPE_CH_K8S_LABELS_HISTORY,
+		},
+	}
+	recorder.dataGenerator = recorder
+	return recorder
+}
+
+func (k *ChPodK8sLabelsHistory) generateCurrentData() (map[K8sLabelsKey]string, bool) {
+	var podLabels []mysql.ChPodK8sLabels
+	err := mysql.Db.Unscoped().Find(&podLabels).Error
+	if err != nil {
+		log.Errorf(dbQueryResourceFailed(k.resourceTypeName, err))
+		return nil, false
+	}
+	keyToLabels := make(map[K8sLabelsKey]string, len(podLabels))
+	for _, item := range podLabels {
+		keyToLabels[K8sLabelsKey{ID: item.ID}] = item.Labels
+	}
+	return keyToLabels, true
+}
+
+func (k *ChPodK8sLabelsHistory) generateKey(dbItem mysql.ChPodK8sLabelsHistory) K8sLabelsKey {
+	return K8sLabelsKey{ID: dbItem.ID}
+}
+
+func (k *ChPodK8sLabelsHistory) generateValue(dbItem mysql.ChPodK8sLabelsHistory) string {
+	return dbItem.Labels
+}
+
+func (k *ChPodK8sLabelsHistory) generateHistoryItem(key K8sLabelsKey, labels string, validFrom time.Time) mysql.ChPodK8sLabelsHistory {
+	return mysql.ChPodK8sLabelsHistory{
+		ID:        key.ID,
+		Labels:    labels,
+		ValidFrom: validFrom,
+		ValidTo:   CH_HISTORY_VALID_TO_MAX,
+	}
+}
@@ -18,6 +18,7 @@ package tagrecorder
 
 import (
 	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	rcommon "github.com/deepflowio/deepflow/server/controller/recorder/common"
 )
 
 type ChPodNode struct {
@@ -28,17 +29,23 @@ type ChPodNode struct {
 func NewChPodNode(resourceTypeToIconID map[IconKey]int) *ChPodNode {
 	updater := &ChPodNode{
 		UpdaterBase[mysql.ChPodNode, IDKey]{
-			resourceTypeName: RESOURCE_TYPE_CH_POD_NODE,
+			resourceTypeName:        RESOURCE_TYPE_CH_POD_NODE,
+			subscribedResourceTypes: []string{rcommon.RESOURCE_TYPE_POD_NODE_EN},
 		},
 		resourceTypeToIconID,
 	}
 	updater.dataGenerator = updater
+	updater.incrementalDataGenerator = updater
 	return updater
 }
 
 func (p *ChPodNode) generateNewData() (map[IDKey]mysql.ChPodNode, bool) {
+	return p.generateNewDataByIDs(rcommon.RESOURCE_TYPE_POD_NODE_EN, nil)
+}
+
+func (p *ChPodNode) generateNewDataByIDs(resourceType string, ids []int) (map[IDKey]mysql.ChPodNode, bool) {
 	var podNodes []mysql.PodNode
-	err := mysql.Db.Unscoped().Find(&podNodes).Error
+	err := findResources(&podNodes, ids)
 	if err != nil {
 		log.Errorf(dbQueryResourceFailed(p.resourceTypeName, err))
 		return nil, false
@@ -63,6 +70,10 @@ func (p *ChPodNode) generateNewData() (map[IDKey]mysql.ChPodNode, bool) {
 	return keyToItem, true
 }
 
+func (p *ChPodNode) generateOldDataByIDs(resourceType string, ids []int) (map[IDKey]mysql.ChPodNode, bool) {
+	return p.generateOldDataWhere("id IN ?", ids)
+}
+
 func (p *ChPodNode) generateKey(dbItem mysql.ChPodNode) IDKey {
 	return IDKey{ID: dbItem.ID}
 }
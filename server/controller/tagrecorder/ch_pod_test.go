@@ -0,0 +1,56 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tagrecorder
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	rcommon "github.com/deepflowio/deepflow/server/controller/recorder/common"
+)
+
+func (t *SuiteTest) TestRefreshChPodByIDs() {
+	updater := NewChPod(map[IconKey]int{{NodeType: RESOURCE_TYPE_POD}: 1})
+	pod := newDBPod(0)
+	t.db.Create(&pod)
+	otherPod := newDBPod(0)
+	t.db.Create(&otherPod)
+
+	updater.RefreshByIDs(rcommon.RESOURCE_TYPE_POD_EN, []int{pod.ID})
+	var addedItems []mysql.ChPod
+	t.db.Unscoped().Find(&addedItems)
+	assert.Equal(t.T(), 1, len(addedItems))
+	assert.Equal(t.T(), pod.Name, addedItems[0].Name)
+
+	pod.Name = uuid.NewString()
+	t.db.Save(&pod)
+	updater.RefreshByIDs(rcommon.RESOURCE_TYPE_POD_EN, []int{pod.ID})
+	var updatedItem mysql.ChPod
+	t.db.Where("id = ?", pod.ID).Unscoped().Find(&updatedItem)
+	assert.Equal(t.T(), pod.Name, updatedItem.Name)
+
+	t.db.Where("id = ?", pod.ID).Delete(&mysql.Pod{})
+	updater.RefreshByIDs(rcommon.RESOURCE_TYPE_POD_EN, []int{pod.ID})
+	var deletedItem mysql.ChPod
+	t.db.Where("id = ?", pod.ID).Unscoped().Find(&deletedItem)
+	assert.Equal(t.T(), pod.Name+" (deleted)", deletedItem.Name)
+
+	t.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&mysql.Pod{})
+	t.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&mysql.ChPod{})
+}
@@ -20,4 +20,13 @@ type TagRecorderConfig struct {
 	Interval                  int `default:"60" yaml:"timeout"`
 	MySQLBatchSize            int `default:"1000" yaml:"mysql_batch_size"`
 	DictionaryRefreshInterval int `default:"60" yaml:"dictionary_refresh_interval"`
+	// 基于recorder资源变更事件增量刷新ch表，支持增量刷新的ch表仅按FullRefreshInterval全量对账
+	EventDrivenRefreshEnabled bool `default:"true" yaml:"event_driven_refresh_enabled"`
+	EventMergeInterval        int  `default:"2" yaml:"event_merge_interval"`
+	FullRefreshInterval       int  `default:"3600" yaml:"full_refresh_interval"`
+	// 维护资源名称和K8s标签的历史有效区间，供querier按数据时间解析，历史记录关闭后保留TagHistoryRetention天
+	TagHistoryEnabled   bool `default:"false" yaml:"tag_history_enabled"`
+	TagHistoryRetention int  `default:"30" yaml:"tag_history_retention"`
+	// 元数据库为SQLite时ClickHouse通过HTTP接口读取字典数据，为空时使用controller的POD IP
+	DictionarySourceHost string `default:"" yaml:"dictionary_source_host"`
 }
@@ -17,6 +17,8 @@
 package tagrecorder
 
 import (
+	"time"
+
 	"github.com/deepflowio/deepflow/server/controller/common"
 )
 
@@ -77,6 +79,9 @@ const (
 	RESOURCE_TYPE_CH_IP_RELATION       = "ch_ip_relation"
 	RESOURCE_TYPE_CH_IP_RESOURCE       = "ch_ip_resource"
 
+	RESOURCE_TYPE_CH_DEVICE_HISTORY     = "ch_device_history"
+	RESOURCE_TYPE_CH_K8S_LABELS_HISTORY = "ch_k8s_labels_history"
+
 	RESOURCE_TYPE_CH_POD_PORT       = "ch_pod_port"
 	RESOURCE_TYPE_CH_POD_NODE_PORT  = "ch_pod_node_port"
 	RESOURCE_TYPE_CH_POD_GROUP_PORT = "ch_pod_group_port"
@@ -133,6 +138,10 @@ const (
 	CH_DICTIONARY_POD_K8S_LABEL  = "pod_k8s_label_map"
 	CH_DICTIONARY_POD_K8S_LABELS = "pod_k8s_labels_map"
 
+	// 资源名称和K8s标签的历史有效区间，按数据时间查询
+	CH_DICTIONARY_DEVICE_HISTORY         = "device_history_map"
+	CH_DICTIONARY_POD_K8S_LABELS_HISTORY = "pod_k8s_labels_history_map"
+
 	CH_DICTIONARY_POD_SERVICE_K8S_LABEL  = "pod_service_k8s_label_map"
 	CH_DICTIONARY_POD_SERVICE_K8S_LABELS = "pod_service_k8s_labels_map"
 
@@ -167,6 +176,13 @@ const (
 	CH_PROMETHEUS_TARGET_LABEL_LAYOUT     = "prometheus_target_label_layout_map"
 )
 
+// 历史数据的有效区间：首次记录的资源从CH_HISTORY_VALID_FROM_MIN开始有效，
+// 仍有效的记录valid_to为CH_HISTORY_VALID_TO_MAX，二者均在ClickHouse DateTime可表示的范围内
+var (
+	CH_HISTORY_VALID_FROM_MIN = time.Date(1970, 1, 2, 0, 0, 0, 0, time.Local)
+	CH_HISTORY_VALID_TO_MAX   = time.Date(2106, 1, 1, 0, 0, 0, 0, time.Local)
+)
+
 const (
 	CH_DEVICE_TYPE_IP        = 64000
 	CH_DEVICE_TYPE_INTERNET  = 63999
@@ -201,6 +217,27 @@ const (
 		"SOURCE(MYSQL(PORT %s USER '%s' PASSWORD '%s' %s DB %s TABLE %s INVALIDATE_QUERY 'select(select updated_at from %s order by updated_at desc limit 1) as updated_at'))\n" +
 		"LIFETIME(MIN 0 MAX %d)\n" +
 		"LAYOUT(FLAT())"
+	CREATE_POD_GROUP_DICTIONARY_SQL = "CREATE DICTIONARY %s.%s\n" +
+		"(\n" +
+		"    `id` UInt64,\n" +
+		"    `name` String,\n" +
+		"    `pod_group_type` UInt64,\n" +
+		"    `icon_id` Int64\n" +
+		")\n" +
+		"PRIMARY KEY id\n" +
+		"SOURCE(MYSQL(PORT %s USER '%s' PASSWORD '%s' %s DB %s TABLE %s INVALIDATE_QUERY 'select(select updated_at from %s order by updated_at desc limit 1) as updated_at'))\n" +
+		"LIFETIME(MIN 0 MAX %d)\n" +
+		"LAYOUT(FLAT())"
+	CREATE_POD_INGRESS_DICTIONARY_SQL = "CREATE DICTIONARY %s.%s\n" +
+		"(\n" +
+		"    `id` UInt64,\n" +
+		"    `name` String,\n" +
+		"    `pod_ingress_type` UInt64\n" +
+		")\n" +
+		"PRIMARY KEY id\n" +
+		"SOURCE(MYSQL(PORT %s USER '%s' PASSWORD '%s' %s DB %s TABLE %s INVALIDATE_QUERY 'select(select updated_at from %s order by updated_at desc limit 1) as updated_at'))\n" +
+		"LIFETIME(MIN 0 MAX %d)\n" +
+		"LAYOUT(FLAT())"
 	CREATE_VPC_DICTIONARY_SQL = "CREATE DICTIONARY %s.%s\n" +
 		"(\n" +
 		"    `id` UInt64,\n" +
@@ -370,6 +407,31 @@ const (
 		"SOURCE(MYSQL(PORT %s USER '%s' PASSWORD '%s' %s DB %s TABLE %s INVALIDATE_QUERY 'select(select updated_at from %s order by updated_at desc limit 1) as updated_at'))\n" +
 		"LIFETIME(MIN 0 MAX %d)\n" +
 		"LAYOUT(FLAT())"
+	CREATE_DEVICE_HISTORY_DICTIONARY_SQL = "CREATE DICTIONARY %s.%s\n" +
+		"(\n" +
+		"    `devicetype` UInt64,\n" +
+		"    `deviceid` UInt64,\n" +
+		"    `name` String,\n" +
+		"    `valid_from` DateTime,\n" +
+		"    `valid_to` DateTime\n" +
+		")\n" +
+		"PRIMARY KEY devicetype, deviceid\n" +
+		"SOURCE(MYSQL(PORT %s USER '%s' PASSWORD '%s' %s DB %s TABLE %s INVALIDATE_QUERY 'select(select updated_at from %s order by updated_at desc limit 1) as updated_at'))\n" +
+		"LIFETIME(MIN 0 MAX %d)\n" +
+		"LAYOUT(COMPLEX_KEY_RANGE_HASHED())\n" +
+		"RANGE(MIN valid_from MAX valid_to)"
+	CREATE_K8S_LABELS_HISTORY_DICTIONARY_SQL = "CREATE DICTIONARY %s.%s\n" +
+		"(\n" +
+		"    `id` UInt64,\n" +
+		"    `labels` String,\n" +
+		"    `valid_from` DateTime,\n" +
+		"    `valid_to` DateTime\n" +
+		")\n" +
+		"PRIMARY KEY id\n" +
+		"SOURCE(MYSQL(PORT %s USER '%s' PASSWORD '%s' %s DB %s TABLE %s INVALIDATE_QUERY 'select(select updated_at from %s order by updated_at desc limit 1) as updated_at'))\n" +
+		"LIFETIME(MIN 0 MAX %d)\n" +
+		"LAYOUT(RANGE_HASHED())\n" +
+		"RANGE(MIN valid_from MAX valid_to)"
 	CREATE_IP_RESOURCE_DICTIONARY_SQL = "CREATE DICTIONARY %s.%s\n" +
 		"(\n" +
 		"    `ip` String,\n" +
@@ -660,7 +722,7 @@ var CREATE_SQL_MAP = map[string]string{
 	CH_DICTIONARY_POD_CLUSTER:            CREATE_DICTIONARY_SQL,
 	CH_DICTIONARY_POD_NAMESPACE:          CREATE_DICTIONARY_SQL,
 	CH_DICTIONARY_POD_NODE:               CREATE_DICTIONARY_SQL,
-	CH_DICTIONARY_POD_GROUP:              CREATE_DICTIONARY_SQL,
+	CH_DICTIONARY_POD_GROUP:              CREATE_POD_GROUP_DICTIONARY_SQL,
 	CH_DICTIONARY_POD:                    CREATE_DICTIONARY_SQL,
 	CH_DICTIONARY_DEVICE:                 CREATE_DEVICE_DICTIONARY_SQL,
 	CH_DICTIONARY_VTAP_PORT:              CREATE_VTAP_PORT_DICTIONARY_SQL,
@@ -674,9 +736,11 @@ var CREATE_SQL_MAP = map[string]string{
 	CH_DICTIONARY_SERVER_PORT:            CREATE_SERVER_PORT_DICTIONARY_SQL,
 	CH_DICTIONARY_IP_RELATION:            CREATE_IP_RELATION_DICTIONARY_SQL,
 	CH_DICTIONARY_LB_LISTENER:            CREATE_ID_NAME_DICTIONARY_SQL,
-	CH_DICTIONARY_POD_INGRESS:            CREATE_ID_NAME_DICTIONARY_SQL,
+	CH_DICTIONARY_POD_INGRESS:            CREATE_POD_INGRESS_DICTIONARY_SQL,
 	CH_DICTIONARY_POD_K8S_LABEL:          CREATE_K8S_LABEL_DICTIONARY_SQL,
 	CH_DICTIONARY_POD_K8S_LABELS:         CREATE_K8S_LABELS_DICTIONARY_SQL,
+	CH_DICTIONARY_DEVICE_HISTORY:         CREATE_DEVICE_HISTORY_DICTIONARY_SQL,
+	CH_DICTIONARY_POD_K8S_LABELS_HISTORY: CREATE_K8S_LABELS_HISTORY_DICTIONARY_SQL,
 	CH_DICTIONARY_IP_RESOURCE:            CREATE_IP_RESOURCE_DICTIONARY_SQL,
 	CH_DICTIONARY_NODE_TYPE:              CREATE_NODE_TYPE_DICTIONARY_SQL,
 	CH_STRING_DICTIONARY_ENUM:            CREATE_STRING_ENUM_SQL,
@@ -34,6 +34,11 @@ type MySQLChModel interface {
 		mysql.ChPodK8sEnv | mysql.ChPodK8sEnvs
 }
 
+// 资源历史有效区间的MySQL orm对象
+type MySQLChHistoryModel interface {
+	mysql.ChDeviceHistory | mysql.ChPodK8sLabelsHistory
+}
+
 // ch资源的组合key
 type ChModelKey interface {
 	PrometheusTargetLabelKey | PrometheusAPPLabelKey | OSAPPTagKey | OSAPPTagsKey | CloudTagsKey | CloudTagKey | IntEnumTagKey | StringEnumTagKey | VtapPortKey | IPResourceKey | K8sLabelKey | PortIDKey | PortIPKey | PortDeviceKey | IDKey | DeviceKey |
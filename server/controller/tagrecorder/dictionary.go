@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -34,6 +35,11 @@ import (
 	"github.com/deepflowio/deepflow/server/controller/db/clickhouse"
 )
 
+var (
+	dictionarySourceRegexp = regexp.MustCompile(`SOURCE\(MYSQL\(.*\)\)`)
+	dictionaryColumnRegexp = regexp.MustCompile("(?m)^\\s+`(\\w+)` ")
+)
+
 func (c *TagRecorder) UpdateChDictionary() {
 	log.Info("tagrecorder update ch dictionary")
 	kubeconfig := c.cfg.Kubeconfig
@@ -137,6 +143,7 @@ func (c *TagRecorder) UpdateChDictionary() {
 							CH_DICTIONARY_IP_RELATION,
 							CH_DICTIONARY_POD_K8S_LABEL,
 							CH_DICTIONARY_POD_K8S_LABELS,
+							CH_DICTIONARY_POD_K8S_LABELS_HISTORY,
 							CH_DICTIONARY_REGION,
 							CH_DICTIONARY_AZ,
 							CH_DICTIONARY_VPC,
@@ -147,6 +154,7 @@ func (c *TagRecorder) UpdateChDictionary() {
 							CH_DICTIONARY_POD_GROUP,
 							CH_DICTIONARY_POD,
 							CH_DICTIONARY_DEVICE,
+							CH_DICTIONARY_DEVICE_HISTORY,
 							CH_DICTIONARY_VTAP_PORT,
 							CH_DICTIONARY_TAP_TYPE,
 							CH_DICTIONARY_VTAP,
@@ -209,9 +217,7 @@ func (c *TagRecorder) UpdateChDictionary() {
 						for _, dict := range addDicts.ToSlice() {
 							dictName := dict.(string)
 							chTable := "ch_" + strings.TrimSuffix(dictName, "_map")
-							createSQL := CREATE_SQL_MAP[dictName]
-							mysqlPortStr := strconv.Itoa(int(c.cfg.MySqlCfg.Port))
-							createSQL = fmt.Sprintf(createSQL, c.cfg.ClickHouseCfg.Database, dictName, mysqlPortStr, c.cfg.MySqlCfg.UserName, c.cfg.MySqlCfg.UserPassword, replicaSQL, c.cfg.MySqlCfg.Database, chTable, chTable, c.cfg.TagRecorderCfg.DictionaryRefreshInterval)
+							createSQL := c.getDictionarySQL(dictName, chTable, replicaSQL)
 							log.Infof("create dictionary %s", dictName)
 							log.Info(createSQL)
 							_, err = connect.Exec(createSQL)
@@ -235,9 +241,7 @@ func (c *TagRecorder) UpdateChDictionary() {
 								connect.Close()
 								continue
 							}
-							createSQL := CREATE_SQL_MAP[dictName]
-							mysqlPortStr := strconv.Itoa(int(c.cfg.MySqlCfg.Port))
-							createSQL = fmt.Sprintf(createSQL, c.cfg.ClickHouseCfg.Database, dictName, mysqlPortStr, c.cfg.MySqlCfg.UserName, c.cfg.MySqlCfg.UserPassword, replicaSQL, c.cfg.MySqlCfg.Database, chTable, chTable, c.cfg.TagRecorderCfg.DictionaryRefreshInterval)
+							createSQL := c.getDictionarySQL(dictName, chTable, replicaSQL)
 							if createSQL == dictSQL[0] {
 								continue
 							}
@@ -358,3 +362,30 @@ func (c *TagRecorder) RefreshLiveView() {
 		}
 	}
 }
+
+// 元数据库为SQLite时ClickHouse无法直接读取，字典数据通过controller的HTTP接口获取
+func (c *TagRecorder) getDictionarySQL(dictName, chTable, replicaSQL string) string {
+	createSQL := CREATE_SQL_MAP[dictName]
+	mysqlPortStr := strconv.Itoa(int(c.cfg.MySqlCfg.Port))
+	createSQL = fmt.Sprintf(createSQL, c.cfg.ClickHouseCfg.Database, dictName, mysqlPortStr, c.cfg.MySqlCfg.UserName, c.cfg.MySqlCfg.UserPassword, replicaSQL, c.cfg.MySqlCfg.Database, chTable, chTable, c.cfg.TagRecorderCfg.DictionaryRefreshInterval)
+	if !c.cfg.MySqlCfg.IsSQLite() {
+		return createSQL
+	}
+
+	host := c.cfg.TagRecorderCfg.DictionarySourceHost
+	if host == "" {
+		host = common.GetPodIP()
+	}
+	if strings.Contains(host, ":") {
+		host = fmt.Sprintf("[%s]", host)
+	}
+	columns := []string{}
+	for _, m := range dictionaryColumnRegexp.FindAllStringSubmatch(createSQL, -1) {
+		columns = append(columns, m[1])
+	}
+	httpSource := fmt.Sprintf(
+		"SOURCE(HTTP(URL 'http://%s:%d/v1/tagrecorder/dictionary/%s/?columns=%s' FORMAT 'TabSeparatedWithNames'))",
+		host, c.cfg.ListenPort, chTable, strings.Join(columns, ","),
+	)
+	return dictionarySourceRegexp.ReplaceAllLiteralString(createSQL, httpSource)
+}
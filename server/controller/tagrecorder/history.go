@@ -0,0 +1,155 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tagrecorder
+
+import (
+	"time"
+
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	"github.com/deepflowio/deepflow/server/controller/tagrecorder/config"
+)
+
+type ChHistoryRecorder interface {
+	// 维护ch资源的历史有效区间
+	// 查询ch表的当前数据，及历史表中仍有效（valid_to为CH_HISTORY_VALID_TO_MAX）的记录
+	// 当前数据不在有效记录中，则新增；值有变化，则关闭有效记录并新增
+	// 有效记录不在当前数据中，则关闭
+	// 删除关闭时间超过保留时长的记录
+	Sync()
+	SetConfig(cfg config.TagRecorderConfig)
+}
+
+type HistoryDataGenerator[HT MySQLChHistoryModel, KT ChModelKey] interface {
+	// 查询ch表，构建需要记录历史的值
+	generateCurrentData() (map[KT]string, bool)
+	// 构建历史记录的结构体key，与对应ch资源的key相同
+	generateKey(HT) KT
+	// 历史记录中保存的值
+	generateValue(HT) string
+	// 根据ch资源的当前值，构建从validFrom开始有效的历史记录
+	generateHistoryItem(key KT, value string, validFrom time.Time) HT
+}
+
+type HistoryBase[HT MySQLChHistoryModel, KT ChModelKey] struct {
+	cfg              config.TagRecorderConfig
+	resourceTypeName string
+	dataGenerator    HistoryDataGenerator[HT, KT]
+}
+
+func (h *HistoryBase[HT, KT]) SetConfig(cfg config.TagRecorderConfig) {
+	h.cfg = cfg
+}
+
+func (h *HistoryBase[HT, KT]) Sync() {
+	keyToValue, ok := h.dataGenerator.generateCurrentData()
+	if !ok {
+		return
+	}
+	var openItems []HT
+	err := mysql.Db.Where("valid_to = ?", CH_HISTORY_VALID_TO_MAX).Find(&openItems).Error
+	if err != nil {
+		log.Errorf(dbQueryResourceFailed(h.resourceTypeName, err))
+		return
+	}
+	var count int64
+	err = mysql.Db.Model(new(HT)).Count(&count).Error
+	if err != nil {
+		log.Errorf(dbQueryResourceFailed(h.resourceTypeName, err))
+		return
+	}
+
+	now := time.Now().Truncate(time.Second)
+	// 历史表为空时，已有资源的当前值覆盖此前的全部时间
+	firstValidFrom := now
+	if count == 0 {
+		firstValidFrom = CH_HISTORY_VALID_FROM_MIN
+	}
+	keyToOpenItem := make(map[KT]HT, len(openItems))
+	for _, item := range openItems {
+		keyToOpenItem[h.dataGenerator.generateKey(item)] = item
+	}
+
+	itemsToAdd := []HT{}
+	itemsToClose := []HT{}
+	for key, value := range keyToValue {
+		openItem, exists := keyToOpenItem[key]
+		if !exists {
+			itemsToAdd = append(itemsToAdd, h.dataGenerator.generateHistoryItem(key, value, firstValidFrom))
+		} else if h.dataGenerator.generateValue(openItem) != value {
+			itemsToClose = append(itemsToClose, openItem)
+			itemsToAdd = append(itemsToAdd, h.dataGenerator.generateHistoryItem(key, value, now))
+		}
+	}
+	for key, openItem := range keyToOpenItem {
+		if _, exists := keyToValue[key]; !exists {
+			itemsToClose = append(itemsToClose, openItem)
+		}
+	}
+
+	// ClickHouse按闭区间匹配，关闭的记录截止到新记录开始前一秒
+	for _, item := range itemsToClose {
+		h.close(item, now.Add(-time.Second))
+	}
+	h.addBatch(itemsToAdd)
+	h.deleteExpired(now)
+}
+
+func (h *HistoryBase[HT, KT]) close(item HT, validTo time.Time) {
+	err := mysql.Db.Model(&item).Update("valid_to", validTo).Error
+	if err != nil {
+		log.Errorf("close %s (value: %+v) failed: %s", h.resourceTypeName, item, err.Error())
+		return
+	}
+	log.Infof("close %s (value: %+v, valid_to: %s) success", h.resourceTypeName, item, validTo.Format(common.GO_BIRTHDAY))
+}
+
+func (h *HistoryBase[HT, KT]) addBatch(items []HT) {
+	batchSize := h.cfg.MySQLBatchSize
+	if batchSize <= 0 {
+		batchSize = len(items)
+	}
+	for start := 0; start < len(items); start += batchSize {
+		end := start + batchSize
+		if end > len(items) {
+			end = len(items)
+		}
+		batch := items[start:end]
+		err := mysql.Db.Create(&batch).Error
+		if err != nil {
+			log.Errorf("add %s (count: %d) failed: %s", h.resourceTypeName, len(batch), err.Error())
+			continue
+		}
+		log.Infof("add %s (count: %d) success", h.resourceTypeName, len(batch))
+	}
+}
+
+// 删除关闭时间早于保留时长的记录，仍有效的记录不会被删除
+func (h *HistoryBase[HT, KT]) deleteExpired(now time.Time) {
+	if h.cfg.TagHistoryRetention <= 0 {
+		return
+	}
+	expiredAt := now.AddDate(0, 0, -h.cfg.TagHistoryRetention)
+	result := mysql.Db.Where("valid_to < ?", expiredAt).Delete(new(HT))
+	if result.Error != nil {
+		log.Errorf("delete %s expired before %s failed: %s", h.resourceTypeName, expiredAt.Format(common.GO_BIRTHDAY), result.Error.Error())
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Infof("delete %s expired before %s (count: %d) success", h.resourceTypeName, expiredAt.Format(common.GO_BIRTHDAY), result.RowsAffected)
+	}
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tagrecorder
+
+import (
+	"context"
+	"time"
+
+	"github.com/deepflowio/deepflow/server/controller/recorder/listener"
+)
+
+const RESOURCE_CHANGE_QUEUE_SIZE = 1 << 12
+
+type resourceChange struct {
+	resourceType string
+	ids          []int
+}
+
+// ResourceChangeSubscriber 订阅本controller中recorder的资源变更，增量刷新受影响的ch表
+// recorder运行在各个controller中，ch表存储于共享的MySQL，因此所有controller均需启动
+type ResourceChangeSubscriber struct {
+	ctx         context.Context
+	tagRecorder *TagRecorder
+	changeQueue chan resourceChange
+
+	resourceTypeToIconID map[IconKey]int
+	iconRefreshedAt      time.Time
+}
+
+func NewResourceChangeSubscriber(tr *TagRecorder, ctx context.Context) *ResourceChangeSubscriber {
+	return &ResourceChangeSubscriber{
+		ctx:         ctx,
+		tagRecorder: tr,
+		changeQueue: make(chan resourceChange, RESOURCE_CHANGE_QUEUE_SIZE),
+	}
+}
+
+func (s *ResourceChangeSubscriber) Start() {
+	listener.Subscribe(s)
+	go s.run()
+	log.Info("tagrecorder resource change subscriber started")
+}
+
+// 在recorder刷新的goroutine中回调，仅入队，队列满时丢弃，由全量刷新兜底
+func (s *ResourceChangeSubscriber) OnResourceChanged(resourceType string, ids []int) {
+	select {
+	case s.changeQueue <- resourceChange{resourceType: resourceType, ids: ids}:
+	default:
+		log.Warningf("resource change queue is full, drop %s changes (ids: %v)", resourceType, ids)
+	}
+}
+
+// 按EventMergeInterval合并同一资源的多次变更，批量刷新
+func (s *ResourceChangeSubscriber) run() {
+	cfg := s.tagRecorder.cfg.TagRecorderCfg
+	ticker := time.NewTicker(time.Duration(cfg.EventMergeInterval) * time.Second)
+	defer ticker.Stop()
+
+	resourceTypeToIDs := make(map[string]map[int]struct{})
+	for {
+		select {
+		case change := <-s.changeQueue:
+			ids, ok := resourceTypeToIDs[change.resourceType]
+			if !ok {
+				ids = make(map[int]struct{})
+				resourceTypeToIDs[change.resourceType] = ids
+			}
+			for _, id := range change.ids {
+				ids[id] = struct{}{}
+			}
+		case <-ticker.C:
+			if len(resourceTypeToIDs) == 0 {
+				continue
+			}
+			s.refresh(resourceTypeToIDs)
+			resourceTypeToIDs = make(map[string]map[int]struct{})
+		case <-s.ctx.Done():
+			log.Info("tagrecorder resource change subscriber stopped")
+			return
+		}
+	}
+}
+
+func (s *ResourceChangeSubscriber) refresh(resourceTypeToIDs map[string]map[int]struct{}) {
+	resourceTypeToIconID, ok := s.getResourceTypeToIconID()
+	if !ok {
+		log.Warning("icon info is not ready, resource changes will be applied by full refresh")
+		return
+	}
+	for _, updater := range newIncrementalUpdaters(resourceTypeToIconID) {
+		updater.SetConfig(s.tagRecorder.cfg.TagRecorderCfg)
+		for _, resourceType := range updater.SubscribedResourceTypes() {
+			idSet, ok := resourceTypeToIDs[resourceType]
+			if !ok {
+				continue
+			}
+			ids := make([]int, 0, len(idSet))
+			for id := range idSet {
+				ids = append(ids, id)
+			}
+			updater.RefreshByIDs(resourceType, ids)
+		}
+	}
+}
+
+// icon信息通过df-web接口获取，按全量刷新的间隔缓存；获取失败时沿用上一次的结果
+func (s *ResourceChangeSubscriber) getResourceTypeToIconID() (map[IconKey]int, bool) {
+	interval := time.Duration(s.tagRecorder.cfg.TagRecorderCfg.Interval) * time.Second
+	if s.resourceTypeToIconID == nil || time.Since(s.iconRefreshedAt) > interval {
+		_, resourceTypeToIconID, err := s.tagRecorder.UpdateIconInfo()
+		if err == nil {
+			s.resourceTypeToIconID = resourceTypeToIconID
+			s.iconRefreshedAt = time.Now()
+		}
+	}
+	return s.resourceTypeToIconID, s.resourceTypeToIconID != nil
+}
@@ -86,6 +86,7 @@ func getModels() []interface{} {
 		&mysql.NATVMConnection{}, &mysql.LB{}, &mysql.LBListener{}, &mysql.LBTargetServer{},
 		&mysql.LBVMConnection{}, &mysql.PodIngress{}, &mysql.PodService{}, mysql.PodGroup{},
 		&mysql.PodGroupPort{}, &mysql.Pod{},
-		&mysql.ChRegion{}, &mysql.ChAZ{}, &mysql.ChVPC{}, &mysql.ChIPRelation{},
+		&mysql.ChRegion{}, &mysql.ChAZ{}, &mysql.ChVPC{}, &mysql.ChIPRelation{}, &mysql.ChPod{},
+		&mysql.ChDevice{}, &mysql.ChDeviceHistory{},
 	}
 }
@@ -31,6 +31,8 @@ type TagRecorder struct {
 	tCtx    context.Context
 	tCancel context.CancelFunc
 	cfg     config.ControllerConfig
+
+	lastFullRefreshAt time.Time // 支持增量刷新的ch表上一次全量刷新的时间
 }
 
 func NewTagRecorder(cfg config.ControllerConfig, ctx context.Context) *TagRecorder {
@@ -49,6 +51,9 @@ func (c *TagRecorder) run() {
 	// 调用API获取资源对应的icon_id
 	domainToIconID, resourceToIconID, _ := c.UpdateIconInfo()
 	c.refresh(domainToIconID, resourceToIconID)
+	if c.cfg.TagRecorderCfg.TagHistoryEnabled {
+		c.syncHistory()
+	}
 }
 
 func (c *TagRecorder) StartChDictionaryUpdate() {
@@ -124,7 +129,19 @@ func (c *TagRecorder) refresh(domainLcuuidToIconID map[string]int, resourceTypeT
 	if c.cfg.RedisCfg.Enabled {
 		updaters = append(updaters, NewChIPResource(c.tCtx))
 	}
+	// 开启增量刷新时，数据来源全部有资源变更事件的ch表仅按FullRefreshInterval全量对账
+	skipIncremental := false
+	if c.cfg.TagRecorderCfg.EventDrivenRefreshEnabled {
+		if time.Since(c.lastFullRefreshAt) < time.Duration(c.cfg.TagRecorderCfg.FullRefreshInterval)*time.Second {
+			skipIncremental = true
+		} else {
+			c.lastFullRefreshAt = time.Now()
+		}
+	}
 	for _, updater := range updaters {
+		if skipIncremental && updater.FullySubscribed() {
+			continue
+		}
 		updater.SetConfig(c.cfg.TagRecorderCfg)
 		isUpdate := updater.Refresh()
 		if isUpdate {
@@ -135,3 +152,26 @@ func (c *TagRecorder) refresh(domainLcuuidToIconID map[string]int, resourceTypeT
 		}
 	}
 }
+
+// 根据刷新后的ch数据维护资源名称和标签的历史有效区间
+func (c *TagRecorder) syncHistory() {
+	recorders := []ChHistoryRecorder{
+		NewChDeviceHistory(),
+		NewChPodK8sLabelsHistory(),
+	}
+	for _, recorder := range recorders {
+		recorder.SetConfig(c.cfg.TagRecorderCfg)
+		recorder.Sync()
+	}
+}
+
+// 支持按recorder资源变更事件增量刷新的ch资源更新器
+func newIncrementalUpdaters(resourceTypeToIconID map[IconKey]int) []ChResourceUpdater {
+	return []ChResourceUpdater{
+		NewChDevice(resourceTypeToIconID),
+		NewChPod(resourceTypeToIconID),
+		NewChPodNode(resourceTypeToIconID),
+		NewChPodK8sLabel(),
+		NewChPodK8sLabels(),
+	}
+}
@@ -19,6 +19,8 @@ package tagrecorder
 import (
 	"time"
 
+	"gorm.io/gorm/clause"
+
 	"github.com/deepflowio/deepflow/server/controller/db/mysql"
 	"github.com/deepflowio/deepflow/server/controller/tagrecorder/config"
 )
@@ -31,6 +33,12 @@ type ChResourceUpdater interface {
 	// 遍历旧的ch数据，若key不在新的ch数据中，则删除
 	Refresh() bool
 	SetConfig(cfg config.TagRecorderConfig)
+	// 关注的recorder资源类型，为空时仅支持全量刷新
+	SubscribedResourceTypes() []string
+	// 所有数据来源都有资源变更事件时，周期刷新可以跳过，仅按FullRefreshInterval全量对账
+	FullySubscribed() bool
+	// 根据recorder资源变更事件，仅刷新变更资源对应的ch数据
+	RefreshByIDs(resourceType string, ids []int) bool
 }
 
 type DataGenerator[MT MySQLChModel, KT ChModelKey] interface {
@@ -42,10 +50,20 @@ type DataGenerator[MT MySQLChModel, KT ChModelKey] interface {
 	generateUpdateInfo(MT, MT) (map[string]interface{}, bool)
 }
 
+type IncrementalDataGenerator[MT MySQLChModel, KT ChModelKey] interface {
+	// 根据变更资源的ID，构建对应的最新ch资源数据
+	generateNewDataByIDs(resourceType string, ids []int) (map[KT]MT, bool)
+	// 根据变更资源的ID，查询ch表中对应的旧数据
+	generateOldDataByIDs(resourceType string, ids []int) (map[KT]MT, bool)
+}
+
 type UpdaterBase[MT MySQLChModel, KT ChModelKey] struct {
-	cfg              config.TagRecorderConfig
-	resourceTypeName string
-	dataGenerator    DataGenerator[MT, KT]
+	cfg                      config.TagRecorderConfig
+	resourceTypeName         string
+	dataGenerator            DataGenerator[MT, KT]
+	subscribedResourceTypes  []string                         // 关注的recorder资源类型，为空时不支持增量刷新
+	hasUnsubscribedData      bool                             // 部分数据来源没有资源变更事件，仍需周期全量刷新
+	incrementalDataGenerator IncrementalDataGenerator[MT, KT] // 提供增量刷新所需的数据生成方法
 }
 
 func (b *UpdaterBase[MT, KT]) SetConfig(cfg config.TagRecorderConfig) {
@@ -55,54 +73,80 @@ func (b *UpdaterBase[MT, KT]) SetConfig(cfg config.TagRecorderConfig) {
 func (b *UpdaterBase[MT, KT]) Refresh() bool {
 	newKeyToDBItem, newOK := b.dataGenerator.generateNewData()
 	oldKeyToDBItem, oldOK := b.generateOldData()
+	if newOK && oldOK {
+		return b.compareAndOperate(newKeyToDBItem, oldKeyToDBItem, b.add)
+	}
+	return false
+}
+
+func (b *UpdaterBase[MT, KT]) SubscribedResourceTypes() []string {
+	return b.subscribedResourceTypes
+}
+
+func (b *UpdaterBase[MT, KT]) FullySubscribed() bool {
+	return len(b.subscribedResourceTypes) > 0 && !b.hasUnsubscribedData
+}
+
+// 仅对比变更资源对应的新旧ch数据，新增数据使用upsert，避免与全量刷新并发写入时主键冲突
+func (b *UpdaterBase[MT, KT]) RefreshByIDs(resourceType string, ids []int) bool {
+	if b.incrementalDataGenerator == nil || len(ids) == 0 {
+		return false
+	}
+	newKeyToDBItem, newOK := b.incrementalDataGenerator.generateNewDataByIDs(resourceType, ids)
+	oldKeyToDBItem, oldOK := b.incrementalDataGenerator.generateOldDataByIDs(resourceType, ids)
+	if newOK && oldOK {
+		return b.compareAndOperate(newKeyToDBItem, oldKeyToDBItem, b.upsert)
+	}
+	return false
+}
+
+func (b *UpdaterBase[MT, KT]) compareAndOperate(newKeyToDBItem, oldKeyToDBItem map[KT]MT, addFunc func([]KT, []MT)) bool {
 	keysToAdd := []KT{}
 	itemsToAdd := []MT{}
 	keysToDelete := []KT{}
 	itemsToDelete := []MT{}
 	isUpdate := false
-	if newOK && oldOK {
-		for key, newDBItem := range newKeyToDBItem {
-			oldDBItem, exists := oldKeyToDBItem[key]
-			if !exists {
-				keysToAdd = append(keysToAdd, key)
-				itemsToAdd = append(itemsToAdd, newDBItem)
-			} else {
-				updateInfo, ok := b.dataGenerator.generateUpdateInfo(oldDBItem, newDBItem)
-				if ok {
-					b.update(oldDBItem, updateInfo, key)
-					isUpdate = true
-				}
+	for key, newDBItem := range newKeyToDBItem {
+		oldDBItem, exists := oldKeyToDBItem[key]
+		if !exists {
+			keysToAdd = append(keysToAdd, key)
+			itemsToAdd = append(itemsToAdd, newDBItem)
+		} else {
+			updateInfo, ok := b.dataGenerator.generateUpdateInfo(oldDBItem, newDBItem)
+			if ok {
+				b.update(oldDBItem, updateInfo, key)
+				isUpdate = true
 			}
 		}
-		if len(itemsToAdd) > 0 {
-			b.operateBatch(keysToAdd, itemsToAdd, b.add)
-		}
+	}
+	if len(itemsToAdd) > 0 {
+		b.operateBatch(keysToAdd, itemsToAdd, addFunc)
+	}
 
-		for key, oldDBItem := range oldKeyToDBItem {
-			_, exists := newKeyToDBItem[key]
-			if !exists {
-				keysToDelete = append(keysToDelete, key)
-				itemsToDelete = append(itemsToDelete, oldDBItem)
-			}
-		}
-		if len(itemsToDelete) > 0 {
-			b.operateBatch(keysToDelete, itemsToDelete, b.delete)
+	for key, oldDBItem := range oldKeyToDBItem {
+		_, exists := newKeyToDBItem[key]
+		if !exists {
+			keysToDelete = append(keysToDelete, key)
+			itemsToDelete = append(itemsToDelete, oldDBItem)
 		}
+	}
+	if len(itemsToDelete) > 0 {
+		b.operateBatch(keysToDelete, itemsToDelete, b.delete)
+	}
 
-		if len(itemsToDelete) > 0 && len(itemsToAdd) == 0 && !isUpdate {
-			updateDBItem, updateOK := b.generateOneData()
-			if updateOK {
-				for key, updateDBItem := range updateDBItem {
-					updateTimeInfo := make(map[string]interface{})
-					now := time.Now()
-					updateTimeInfo["updated_at"] = now.Format("2006-01-02 15:04:05")
-					b.update(updateDBItem, updateTimeInfo, key)
-				}
+	if len(itemsToDelete) > 0 && len(itemsToAdd) == 0 && !isUpdate {
+		updateDBItem, updateOK := b.generateOneData()
+		if updateOK {
+			for key, updateDBItem := range updateDBItem {
+				updateTimeInfo := make(map[string]interface{})
+				now := time.Now()
+				updateTimeInfo["updated_at"] = now.Format("2006-01-02 15:04:05")
+				b.update(updateDBItem, updateTimeInfo, key)
 			}
 		}
-		if (isUpdate || len(itemsToDelete) > 0 || len(itemsToAdd) > 0) && (b.resourceTypeName == RESOURCE_TYPE_CH_APP_LABEL || b.resourceTypeName == RESOURCE_TYPE_CH_TARGET_LABEL) {
-			return true
-		}
+	}
+	if (isUpdate || len(itemsToDelete) > 0 || len(itemsToAdd) > 0) && (b.resourceTypeName == RESOURCE_TYPE_CH_APP_LABEL || b.resourceTypeName == RESOURCE_TYPE_CH_TARGET_LABEL) {
+		return true
 	}
 	return false
 }
@@ -121,6 +165,20 @@ func (b *UpdaterBase[MT, KT]) generateOldData() (map[KT]MT, bool) {
 	return idToItem, true
 }
 
+func (b *UpdaterBase[MT, KT]) generateOldDataWhere(query string, args ...interface{}) (map[KT]MT, bool) {
+	var items []MT
+	err := mysql.Db.Unscoped().Where(query, args...).Find(&items).Error
+	if err != nil {
+		log.Errorf(dbQueryResourceFailed(b.resourceTypeName, err))
+		return nil, false
+	}
+	idToItem := make(map[KT]MT)
+	for _, item := range items {
+		idToItem[b.dataGenerator.generateKey(item)] = item
+	}
+	return idToItem, true
+}
+
 func (b *UpdaterBase[MT, KT]) generateOneData() (map[KT]MT, bool) {
 	var items []MT
 	err := mysql.Db.Unscoped().First(&items).Error
@@ -167,6 +225,19 @@ func (b *UpdaterBase[MT, KT]) add(keys []KT, dbItems []MT) {
 	}
 }
 
+func (b *UpdaterBase[MT, KT]) upsert(keys []KT, dbItems []MT) {
+	err := mysql.Db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&dbItems).Error
+	if err != nil {
+		for i := range keys {
+			log.Errorf("upsert %s (key: %+v value: %+v) failed: %s", b.resourceTypeName, keys[i], dbItems[i], err.Error())
+		}
+		return
+	}
+	for i := range keys {
+		log.Infof("upsert %s (key: %+v value: %+v) success", b.resourceTypeName, keys[i], dbItems[i])
+	}
+}
+
 func (b *UpdaterBase[MT, KT]) update(oldDBItem MT, updateInfo map[string]interface{}, key KT) {
 	err := mysql.Db.Model(&oldDBItem).Updates(updateInfo).Error
 	if err != nil {
@@ -188,3 +259,12 @@ func (b *UpdaterBase[MT, KT]) delete(keys []KT, dbItems []MT) {
 		log.Infof("delete %s (key: %+v value: %+v) success", b.resourceTypeName, keys[i], dbItems[i])
 	}
 }
+
+// ids为nil时查询全部资源，否则仅查询指定ID的资源，用于增量刷新
+func findResources(items interface{}, ids []int) error {
+	db := mysql.Db.Unscoped()
+	if ids != nil {
+		db = db.Where("id IN ?", ids)
+	}
+	return db.Find(items).Error
+}
@@ -53,6 +53,7 @@ type Config struct {
 	VTapAutoRegister               bool `default:"true" yaml:"vtap-auto-register"`
 	DomainAutoRegister             bool `default:"true" yaml:"domain-auto-register"`
 	DefaultTapMode                 int  `yaml:"default-tap-mode"`
+	PlatformDataHistorySize        int  `default:"8" yaml:"platform-data-history-size"`
 	BillingMethod                  string
 	GrpcPort                       int
 	IngesterPort                   int
@@ -0,0 +1,276 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metadata
+
+import (
+	"hash/fnv"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/deepflowio/deepflow/message/trident"
+)
+
+// deltaSnapshot 某个版本的数据，用于计算与历史版本之间的增量
+type deltaSnapshot interface {
+	hash() uint64
+	size() int
+	// delta 返回从old到当前版本的序列化增量
+	delta(old deltaSnapshot, oldVersion uint64) ([]byte, error)
+}
+
+// versionHistory 保留最近size个版本的数据，客户端版本仍在历史中时下发增量，否则下发全量
+type versionHistory struct {
+	sync.Mutex
+	name      string
+	size      int
+	versions  []uint64
+	snapshots map[uint64]deltaSnapshot
+	deltas    map[[2]uint64][]byte // (旧版本, 新版本) -> 增量，nil表示增量不小于全量
+}
+
+func newVersionHistory(name string, size int) *versionHistory {
+	return &versionHistory{
+		name:      name,
+		size:      size,
+		snapshots: make(map[uint64]deltaSnapshot),
+		deltas:    make(map[[2]uint64][]byte),
+	}
+}
+
+func (h *versionHistory) add(version uint64, snapshot deltaSnapshot) {
+	if old, ok := h.snapshots[version]; ok {
+		if old.hash() == snapshot.hash() {
+			return
+		}
+		// 相同版本号的数据发生变化，丢弃基于该版本的增量
+		h.remove(version)
+	}
+	h.versions = append(h.versions, version)
+	h.snapshots[version] = snapshot
+	for len(h.versions) > h.size {
+		h.remove(h.versions[0])
+	}
+}
+
+func (h *versionHistory) remove(version uint64) {
+	for i, v := range h.versions {
+		if v == version {
+			h.versions = append(h.versions[:i], h.versions[i+1:]...)
+			break
+		}
+	}
+	delete(h.snapshots, version)
+	for key := range h.deltas {
+		if key[0] == version || key[1] == version {
+			delete(h.deltas, key)
+		}
+	}
+}
+
+// getDelta 返回从oldVersion到version的增量，不支持增量时返回nil，调用方需下发全量数据
+func (h *versionHistory) getDelta(oldVersion, version uint64, current deltaSnapshot) []byte {
+	if h == nil || h.size <= 0 || current == nil || version == 0 {
+		return nil
+	}
+	h.Lock()
+	defer h.Unlock()
+
+	h.add(version, current)
+	if oldVersion == 0 || oldVersion == version {
+		return nil
+	}
+	key := [2]uint64{oldVersion, version}
+	if delta, ok := h.deltas[key]; ok {
+		return delta
+	}
+	old, ok := h.snapshots[oldVersion]
+	if !ok {
+		return nil
+	}
+	delta, err := current.delta(old, oldVersion)
+	if err != nil {
+		log.Errorf("generate %s delta (%d -> %d) failed: %s", h.name, oldVersion, version, err)
+		return nil
+	}
+	if len(delta) >= current.size() {
+		delta = nil
+	}
+	h.deltas[key] = delta
+	log.Infof("generate %s delta (%d -> %d), delta size: %d, full size: %d", h.name, oldVersion, version, len(delta), current.size())
+	return delta
+}
+
+type protoItem interface {
+	Marshal() ([]byte, error)
+}
+
+// itemHashes 计算各条目序列化内容的hash，作为条目的标识
+func itemHashes[T protoItem](items []T) ([]uint64, map[uint64]struct{}) {
+	hashes := make([]uint64, 0, len(items))
+	hashSet := make(map[uint64]struct{}, len(items))
+	for _, item := range items {
+		b, err := item.Marshal()
+		if err != nil {
+			log.Error(err)
+		}
+		h64 := fnv.New64()
+		h64.Write(b)
+		hash := h64.Sum64()
+		hashes = append(hashes, hash)
+		hashSet[hash] = struct{}{}
+	}
+	return hashes, hashSet
+}
+
+// diffItems 比较新旧两个版本的条目，返回新增和删除的条目
+func diffItems[T protoItem](oldItems []T, oldHashes []uint64, oldHashSet map[uint64]struct{},
+	newItems []T, newHashes []uint64, newHashSet map[uint64]struct{}) (adds []T, dels []T) {
+	for i, hash := range newHashes {
+		if _, ok := oldHashSet[hash]; !ok {
+			adds = append(adds, newItems[i])
+		}
+	}
+	for i, hash := range oldHashes {
+		if _, ok := newHashSet[hash]; !ok {
+			dels = append(dels, oldItems[i])
+		}
+	}
+	return
+}
+
+type platformDataItemHashes struct {
+	interfaceHashes  []uint64
+	interfaceHashSet map[uint64]struct{}
+	peerConnHashes   []uint64
+	peerConnHashSet  map[uint64]struct{}
+	cidrHashes       []uint64
+	cidrHashSet      map[uint64]struct{}
+}
+
+// getItemHashes 条目hash在首次计算增量时生成，多个采集器共用同一份平台数据时只计算一次
+func (f *PlatformData) getItemHashes() *platformDataItemHashes {
+	f.itemHashesOnce.Do(func() {
+		h := &platformDataItemHashes{}
+		h.interfaceHashes, h.interfaceHashSet = itemHashes(f.interfaceProtos)
+		h.peerConnHashes, h.peerConnHashSet = itemHashes(f.peerConnProtos)
+		h.cidrHashes, h.cidrHashSet = itemHashes(f.cidrProtos)
+		f.itemHashes = h
+	})
+	return f.itemHashes
+}
+
+type platformDataSnapshot struct {
+	data *PlatformData
+}
+
+func (s *platformDataSnapshot) hash() uint64 {
+	return s.data.platformDataHash
+}
+
+func (s *platformDataSnapshot) size() int {
+	return len(s.data.platformDataStr)
+}
+
+func (s *platformDataSnapshot) delta(old deltaSnapshot, oldVersion uint64) ([]byte, error) {
+	o := old.(*platformDataSnapshot).data
+	oh, nh := o.getItemHashes(), s.data.getItemHashes()
+	delta := &trident.PlatformDataDelta{BaseVersion: proto.Uint64(oldVersion)}
+	delta.AddInterfaces, delta.DelInterfaces = diffItems(
+		o.interfaceProtos, oh.interfaceHashes, oh.interfaceHashSet,
+		s.data.interfaceProtos, nh.interfaceHashes, nh.interfaceHashSet)
+	delta.AddPeerConnections, delta.DelPeerConnections = diffItems(
+		o.peerConnProtos, oh.peerConnHashes, oh.peerConnHashSet,
+		s.data.peerConnProtos, nh.peerConnHashes, nh.peerConnHashSet)
+	delta.AddCidrs, delta.DelCidrs = diffItems(
+		o.cidrProtos, oh.cidrHashes, oh.cidrHashSet,
+		s.data.cidrProtos, nh.cidrHashes, nh.cidrHashSet)
+	return delta.Marshal()
+}
+
+// PlatformDataHistory 平台数据的版本历史，同一个历史中相同版本号对应相同的数据来源
+type PlatformDataHistory struct {
+	history *versionHistory
+}
+
+func NewPlatformDataHistory(name string, size int) *PlatformDataHistory {
+	return &PlatformDataHistory{
+		history: newVersionHistory(name, size),
+	}
+}
+
+// GetDelta 返回从oldVersion到data的增量，返回nil时需下发全量数据
+func (h *PlatformDataHistory) GetDelta(oldVersion uint64, data *PlatformData) []byte {
+	if h == nil || data == nil {
+		return nil
+	}
+	return h.history.getDelta(oldVersion, data.GetVersion(), &platformDataSnapshot{data: data})
+}
+
+type groupsSnapshot struct {
+	version   uint64
+	groups    *trident.Groups
+	groupHash uint64
+	groupSize int
+
+	hashed       bool
+	groupHashes  []uint64
+	groupHashSet map[uint64]struct{}
+	svcHashes    []uint64
+	svcHashSet   map[uint64]struct{}
+}
+
+func newGroupsSnapshot(version uint64, groups *trident.Groups, groupHash uint64, groupSize int) *groupsSnapshot {
+	return &groupsSnapshot{
+		version:   version,
+		groups:    groups,
+		groupHash: groupHash,
+		groupSize: groupSize,
+	}
+}
+
+func (s *groupsSnapshot) hash() uint64 {
+	return s.groupHash
+}
+
+func (s *groupsSnapshot) size() int {
+	return s.groupSize
+}
+
+// 条目hash在首次计算增量时生成，由versionHistory的锁保护
+func (s *groupsSnapshot) generateHashes() {
+	if s.hashed {
+		return
+	}
+	s.groupHashes, s.groupHashSet = itemHashes(s.groups.GetGroups())
+	s.svcHashes, s.svcHashSet = itemHashes(s.groups.GetSvcs())
+	s.hashed = true
+}
+
+func (s *groupsSnapshot) delta(old deltaSnapshot, oldVersion uint64) ([]byte, error) {
+	o := old.(*groupsSnapshot)
+	o.generateHashes()
+	s.generateHashes()
+	delta := &trident.GroupsDelta{BaseVersion: proto.Uint64(oldVersion)}
+	delta.AddGroups, delta.DelGroups = diffItems(
+		o.groups.GetGroups(), o.groupHashes, o.groupHashSet,
+		s.groups.GetGroups(), s.groupHashes, s.groupHashSet)
+	delta.AddSvcs, delta.DelSvcs = diffItems(
+		o.groups.GetSvcs(), o.svcHashes, o.svcHashSet,
+		s.groups.GetSvcs(), s.svcHashes, s.svcHashSet)
+	return delta.Marshal()
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metadata
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/deepflowio/deepflow/message/trident"
+)
+
+type testItem string
+
+func (i testItem) Marshal() ([]byte, error) {
+	return []byte(i), nil
+}
+
+// testSnapshot 的增量格式为 +新增条目,-删除条目
+type testSnapshot struct {
+	items      []testItem
+	itemsHash  uint64
+	itemsSize  int
+	deltaCount *int
+}
+
+func (s *testSnapshot) hash() uint64 {
+	return s.itemsHash
+}
+
+func (s *testSnapshot) size() int {
+	return s.itemsSize
+}
+
+func (s *testSnapshot) delta(old deltaSnapshot, oldVersion uint64) ([]byte, error) {
+	*s.deltaCount++
+	o := old.(*testSnapshot)
+	oldHashes, oldHashSet := itemHashes(o.items)
+	newHashes, newHashSet := itemHashes(s.items)
+	adds, dels := diffItems(o.items, oldHashes, oldHashSet, s.items, newHashes, newHashSet)
+	var parts []string
+	for _, item := range adds {
+		parts = append(parts, "+"+string(item))
+	}
+	for _, item := range dels {
+		parts = append(parts, "-"+string(item))
+	}
+	return []byte(strings.Join(parts, ",")), nil
+}
+
+func TestDiffItems(t *testing.T) {
+	oldItems := []testItem{"a", "b", "c"}
+	newItems := []testItem{"b", "c", "d", "e"}
+	oldHashes, oldHashSet := itemHashes(oldItems)
+	newHashes, newHashSet := itemHashes(newItems)
+	adds, dels := diffItems(oldItems, oldHashes, oldHashSet, newItems, newHashes, newHashSet)
+	if !reflect.DeepEqual(adds, []testItem{"d", "e"}) {
+		t.Errorf("diffItems() adds = %v, want [d e]", adds)
+	}
+	if !reflect.DeepEqual(dels, []testItem{"a"}) {
+		t.Errorf("diffItems() dels = %v, want [a]", dels)
+	}
+
+	adds, dels = diffItems(oldItems, oldHashes, oldHashSet, oldItems, oldHashes, oldHashSet)
+	if len(adds) != 0 || len(dels) != 0 {
+		t.Errorf("diffItems() of same items = %v, %v, want empty", adds, dels)
+	}
+}
+
+func TestVersionHistoryGetDelta(t *testing.T) {
+	deltaCount := 0
+	snapshot := func(hash uint64, items ...testItem) *testSnapshot {
+		return &testSnapshot{items: items, itemsHash: hash, itemsSize: 100, deltaCount: &deltaCount}
+	}
+	h := newVersionHistory("test", 2)
+
+	if delta := h.getDelta(0, 1, snapshot(1, "a", "b")); delta != nil {
+		t.Errorf("getDelta() without client version = %s, want nil", delta)
+	}
+	if delta := h.getDelta(1, 1, snapshot(1, "a", "b")); delta != nil {
+		t.Errorf("getDelta() of current version = %s, want nil", delta)
+	}
+	if delta := h.getDelta(1, 2, snapshot(2, "b", "c")); string(delta) != "+c,-a" {
+		t.Errorf("getDelta(1, 2) = %s, want +c,-a", delta)
+	}
+	// 相同的增量只生成一次
+	if delta := h.getDelta(1, 2, snapshot(2, "b", "c")); string(delta) != "+c,-a" || deltaCount != 1 {
+		t.Errorf("getDelta(1, 2) = %s, generated %d times, want +c,-a generated once", delta, deltaCount)
+	}
+
+	// 版本1超出历史长度被淘汰，相关的增量一并删除
+	if delta := h.getDelta(1, 3, snapshot(3, "c")); delta != nil {
+		t.Errorf("getDelta() of evicted version = %s, want nil", delta)
+	}
+	if !reflect.DeepEqual(h.versions, []uint64{2, 3}) || len(h.deltas) != 0 {
+		t.Errorf("history versions = %v, deltas = %v, want [2 3] and no deltas", h.versions, h.deltas)
+	}
+	if delta := h.getDelta(2, 3, snapshot(3, "c")); string(delta) != "-b" {
+		t.Errorf("getDelta(2, 3) = %s, want -b", delta)
+	}
+
+	// 相同版本号的数据发生变化，丢弃基于旧数据的增量
+	if delta := h.getDelta(2, 3, snapshot(4, "c", "d")); string(delta) != "+d,-b" {
+		t.Errorf("getDelta(2, 3) after data changed = %s, want +d,-b", delta)
+	}
+
+	// 增量不小于全量时下发全量
+	big := snapshot(5, "e")
+	big.itemsSize = 1
+	if delta := h.getDelta(3, 5, big); delta != nil {
+		t.Errorf("getDelta() larger than full data = %s, want nil", delta)
+	}
+	if delta, ok := h.deltas[[2]uint64{3, 5}]; !ok || delta != nil {
+		t.Errorf("oversized delta cached as %v, %v, want nil, true", delta, ok)
+	}
+
+	var disabled *versionHistory
+	if delta := disabled.getDelta(1, 2, snapshot(2, "b")); delta != nil {
+		t.Errorf("getDelta() of nil history = %s, want nil", delta)
+	}
+}
+
+func TestGroupsSnapshotDelta(t *testing.T) {
+	group := func(id uint32, ips ...string) *trident.Group {
+		return &trident.Group{Id: proto.Uint32(id), Ips: ips}
+	}
+	oldGroups := &trident.Groups{Groups: []*trident.Group{group(1, "10.0.0.1"), group(2, "10.0.0.2")}}
+	newGroups := &trident.Groups{Groups: []*trident.Group{group(1, "10.0.0.1"), group(2, "10.0.0.3")}}
+	old := newGroupsSnapshot(1, oldGroups, 1, 1000)
+	current := newGroupsSnapshot(2, newGroups, 2, 1000)
+
+	b, err := current.delta(old, 1)
+	if err != nil {
+		t.Fatalf("delta() failed: %s", err)
+	}
+	delta := &trident.GroupsDelta{}
+	if err := delta.Unmarshal(b); err != nil {
+		t.Fatalf("unmarshal delta failed: %s", err)
+	}
+	if delta.GetBaseVersion() != 1 {
+		t.Errorf("delta base version = %d, want 1", delta.GetBaseVersion())
+	}
+	if len(delta.GetAddGroups()) != 1 || !proto.Equal(delta.GetAddGroups()[0], group(2, "10.0.0.3")) {
+		t.Errorf("delta add groups = %v, want group 2 with 10.0.0.3", delta.GetAddGroups())
+	}
+	if len(delta.GetDelGroups()) != 1 || !proto.Equal(delta.GetDelGroups()[0], group(2, "10.0.0.2")) {
+		t.Errorf("delta del groups = %v, want group 2 with 10.0.0.2", delta.GetDelGroups())
+	}
+}
@@ -58,15 +58,21 @@ type GroupProto struct {
 	groupVersion uint64
 	groups       *atomic.Value // []byte
 	groupHash    uint64
+	snapshot     *atomic.Value // *groupsSnapshot
+	history      *versionHistory
 }
 
-func newGroupProto() *GroupProto {
+func newGroupProto(name string, historySize int) *GroupProto {
 	groups := &atomic.Value{}
 	groups.Store([]byte{})
+	snapshot := &atomic.Value{}
+	snapshot.Store((*groupsSnapshot)(nil))
 	return &GroupProto{
 		groupVersion: 0,
 		groups:       groups,
 		groupHash:    0,
+		snapshot:     snapshot,
+		history:      newVersionHistory(name, historySize),
 	}
 }
 
@@ -99,6 +105,15 @@ func (g *GroupProto) updateGroups(groups []byte) {
 	g.groups.Store(groups)
 }
 
+// getGroupsDelta 返回从oldVersion到version的增量，version不是当前版本时返回nil
+func (g *GroupProto) getGroupsDelta(oldVersion, version uint64) []byte {
+	snapshot := g.snapshot.Load().(*groupsSnapshot)
+	if snapshot == nil || snapshot.version != version {
+		return nil
+	}
+	return g.history.getDelta(oldVersion, version, snapshot)
+}
+
 func (g *GroupProto) generateGroupProto(groupsProto []*trident.Group, svcs []*trident.ServiceInfo) {
 	groups := &trident.Groups{
 		Groups: groupsProto,
@@ -109,7 +124,9 @@ func (g *GroupProto) generateGroupProto(groupsProto []*trident.Group, svcs []*tr
 		g.updateGroups(groupBytes)
 		h64 := fnv.New64()
 		h64.Write(groupBytes)
-		g.checkVersion(h64.Sum64())
+		groupHash := h64.Sum64()
+		g.checkVersion(groupHash)
+		g.snapshot.Store(newGroupsSnapshot(g.getVersion(), groups, groupHash, len(groupBytes)))
 	} else {
 		log.Error(err)
 	}
@@ -128,8 +145,8 @@ func newGroupDataOP(metaData *MetaData) *GroupDataOP {
 		groupRawData:      newGroupRawData(),
 		serviceDataOP:     newServiceDataOP(metaData),
 		metaData:          metaData,
-		tridentGroupProto: newGroupProto(),
-		dropletGroupProto: newGroupProto(),
+		tridentGroupProto: newGroupProto("trident groups", metaData.config.PlatformDataHistorySize),
+		dropletGroupProto: newGroupProto("droplet groups", metaData.config.PlatformDataHistorySize),
 	}
 }
 
@@ -156,6 +173,14 @@ func (g *GroupDataOP) getTridentGroupsVersion() uint64 {
 	return g.tridentGroupProto.getVersion()
 }
 
+func (g *GroupDataOP) getTridentGroupsDelta(oldVersion, version uint64) []byte {
+	return g.tridentGroupProto.getGroupsDelta(oldVersion, version)
+}
+
+func (g *GroupDataOP) getDropletGroupsDelta(oldVersion, version uint64) []byte {
+	return g.dropletGroupProto.getGroupsDelta(oldVersion, version)
+}
+
 func (g *GroupDataOP) getDropletGroups() []byte {
 	return g.dropletGroupProto.getGroups()
 }
@@ -126,6 +126,14 @@ func (m *MetaData) GetTridentGroupsVersion() uint64 {
 	return m.groupDataOP.getTridentGroupsVersion()
 }
 
+func (m *MetaData) GetTridentGroupsDelta(oldVersion, version uint64) []byte {
+	return m.groupDataOP.getTridentGroupsDelta(oldVersion, version)
+}
+
+func (m *MetaData) GetDropletGroupsDelta(oldVersion, version uint64) []byte {
+	return m.groupDataOP.getDropletGroupsDelta(oldVersion, version)
+}
+
 func (m *MetaData) GetDropletGroups() []byte {
 	return m.groupDataOP.getDropletGroups()
 }
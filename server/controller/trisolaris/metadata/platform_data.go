@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"hash/fnv"
 	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/deepflowio/deepflow/message/trident"
@@ -40,6 +41,9 @@ type PlatformData struct {
 	version            uint64
 	mergeDomains       []string
 	dataType           uint32
+
+	itemHashesOnce sync.Once
+	itemHashes     *platformDataItemHashes
 }
 
 func NewPlatformData(domain string, lcuuid string, version uint64, dataType uint32) *PlatformData {
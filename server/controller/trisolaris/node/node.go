@@ -50,6 +50,7 @@ type NodeInfo struct {
 	controllerToPodIP       map[string]string
 	localServers            *atomic.Value // []*trident.DeepFlowServerInstanceInfo
 	platformData            *atomic.Value // *metaData.PlatformData
+	platformDataHistory     *metadata.PlatformDataHistory
 	localRegion             *string
 	localAZs                []string
 	sysConfigurationToValue map[string]string
@@ -77,6 +78,7 @@ func NewNodeInfo(db *gorm.DB, metaData *metadata.MetaData, cfg *config.Config) *
 		controllerToPodIP:       make(map[string]string),
 		localServers:            localServers,
 		platformData:            platformData,
+		platformDataHistory:     metadata.NewPlatformDataHistory("tsdb platform data", cfg.PlatformDataHistorySize),
 		sysConfigurationToValue: make(map[string]string),
 		metaData:                metaData,
 		tsdbRegister:            newTSDBDiscovery(),
@@ -100,6 +102,15 @@ func (n *NodeInfo) GetPlatformDataStr() []byte {
 	return n.getPlatformData().GetPlatformDataStr()
 }
 
+// GetPlatformDataDelta 返回从oldVersion到version的平台数据增量，返回nil时需下发全量数据
+func (n *NodeInfo) GetPlatformDataDelta(oldVersion, version uint64) []byte {
+	platformData := n.getPlatformData()
+	if platformData.GetPlatformDataVersion() != version {
+		return nil
+	}
+	return n.platformDataHistory.GetDelta(oldVersion, platformData)
+}
+
 func (n *NodeInfo) GetPodIPs() []*trident.PodIp {
 	return n.metaData.GetPlatformDataOP().GetPodIPs()
 }
@@ -607,6 +618,10 @@ func (n *NodeInfo) GetGroupsVersion() uint64 {
 	return n.metaData.GetDropletGroupsVersion()
 }
 
+func (n *NodeInfo) GetGroupsDelta(oldVersion, version uint64) []byte {
+	return n.metaData.GetDropletGroupsDelta(oldVersion, version)
+}
+
 func (n *NodeInfo) GetPolicy() []byte {
 	return n.metaData.GetDropletPolicyStr()
 }
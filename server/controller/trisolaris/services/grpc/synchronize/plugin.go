@@ -18,12 +18,18 @@ package synchronize
 
 import (
 	"crypto/md5"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"math"
+	"sort"
 
 	"github.com/golang/protobuf/proto"
+	context "golang.org/x/net/context"
+	"gorm.io/gorm"
 
 	api "github.com/deepflowio/deepflow/message/trident"
+	"github.com/deepflowio/deepflow/server/controller/common"
 	models "github.com/deepflowio/deepflow/server/controller/db/mysql"
 	"github.com/deepflowio/deepflow/server/controller/trisolaris"
 	"github.com/deepflowio/deepflow/server/controller/trisolaris/dbmgr"
@@ -43,21 +49,42 @@ func NewPluginEvent() *PluginEvent {
 	return &PluginEvent{}
 }
 
+// 插件名称为name@version时返回该版本的内容，否则返回默认版本的内容
+func getPluginImage(pluginType int, pluginName string) ([]byte, error) {
+	name, version, err := common.ParsePluginName(pluginName)
+	if err != nil {
+		return nil, err
+	}
+	if version == common.PLUGIN_VERSION_DEFAULT {
+		pluginDbMgr := dbmgr.DBMgr[models.Plugin](trisolaris.GetDB())
+		plugin, err := pluginDbMgr.GetByOption(
+			pluginDbMgr.WithName(name),
+			pluginDbMgr.WithType(pluginType),
+		)
+		if err != nil {
+			return nil, err
+		}
+		return plugin.Image, nil
+	}
+	pluginVersion := &models.PluginVersion{}
+	err = trisolaris.GetDB().Where("plugin_name = ? AND type = ? AND version = ?", name, pluginType, version).
+		First(pluginVersion).Error
+	if err != nil {
+		return nil, err
+	}
+	return pluginVersion.Image, nil
+}
+
 func (p *PluginEvent) GetPluginData(r *api.PluginRequest) (*PluginData, error) {
 	if r.GetPluginType() == 0 || r.GetPluginName() == "" {
 		return nil, fmt.Errorf("the plugin request data type(%d) or name(%s) is empty",
 			r.GetPluginType(), r.GetPluginName())
 	}
-	pluginDbMgr := dbmgr.DBMgr[models.Plugin](trisolaris.GetDB())
-	plugin, err := pluginDbMgr.GetByOption(
-		pluginDbMgr.WithName(r.GetPluginName()),
-		pluginDbMgr.WithType(int(r.GetPluginType())),
-	)
+	content, err := getPluginImage(int(r.GetPluginType()), r.GetPluginName())
 	if err != nil {
 		return nil, fmt.Errorf("get plugin(type=%s, name=%s) from db failed, %s",
 			r.GetPluginType(), r.GetPluginName(), err)
 	}
-	content := plugin.Image
 	totalLen := uint64(len(content))
 	step := uint64(1024 * 1024)
 	pktCount := uint32(math.Ceil(float64(totalLen) / float64(step)))
@@ -116,3 +143,86 @@ func (p *PluginEvent) Plugin(r *api.PluginRequest, in api.Synchronizer_PluginSer
 	log.Infof("sending plugin data to agent(%s) completed", vtapCacheKey)
 	return err
 }
+
+// getPluginDigest 返回数据库中记录的插件版本摘要，不读取插件内容。
+// 默认版本尚未生成时返回空字符串，需读取内容计算
+func getPluginDigest(pluginType int, pluginName string) (string, error) {
+	name, version, err := common.ParsePluginName(pluginName)
+	if err != nil {
+		return "", err
+	}
+	db := trisolaris.GetDB()
+	if version == common.PLUGIN_VERSION_DEFAULT {
+		plugin := &models.Plugin{}
+		err = db.Select("version").Where("name = ? AND type = ?", name, pluginType).First(plugin).Error
+		if err != nil || plugin.Version == 0 {
+			return "", err
+		}
+		version = plugin.Version
+	}
+	pluginVersion := &models.PluginVersion{}
+	err = db.Select("digest").Where("plugin_name = ? AND type = ? AND version = ?", name, pluginType, version).
+		First(pluginVersion).Error
+	return pluginVersion.Digest, err
+}
+
+func (p *PluginEvent) ServerPluginSync(ctx context.Context, r *api.ServerPluginSyncRequest) (*api.ServerPluginSyncResponse, error) {
+	if r.GetPluginType() == 0 || len(r.GetPluginNames()) == 0 {
+		return &api.ServerPluginSyncResponse{Status: &STATUS_SUCCESS, Version: proto.Uint64(0)}, nil
+	}
+	pluginType := int(r.GetPluginType())
+	names := make([]string, len(r.GetPluginNames()))
+	copy(names, r.GetPluginNames())
+	sort.Strings(names)
+
+	// version由插件名称及摘要计算，插件增删或内容更新时变化
+	// 名称可以是name@version，返回的插件名称与请求中的一致
+	// 先用数据库中的摘要计算version，version未变化时不读取插件内容
+	serverPlugins := make([]*api.ServerPlugin, 0, len(names))
+	hash := fnv.New64a()
+	for _, name := range names {
+		digest, err := getPluginDigest(pluginType, name)
+		var image []byte
+		if err == nil && digest == "" {
+			if image, err = getPluginImage(pluginType, name); err == nil {
+				digest = models.PluginDigest(image)
+			}
+		}
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			log.Errorf("get server plugin(type=%d, name=%s) from db failed, %s", pluginType, name, err)
+			return &api.ServerPluginSyncResponse{Status: &STATUS_FAILED}, nil
+		}
+		hash.Write([]byte(name))
+		hash.Write([]byte(digest))
+		serverPlugins = append(serverPlugins, &api.ServerPlugin{
+			Name:    proto.String(name),
+			Content: image,
+			Digest:  proto.String(digest),
+		})
+	}
+	version := hash.Sum64()
+	if version == r.GetVersion() {
+		return &api.ServerPluginSyncResponse{Status: &STATUS_SUCCESS, Version: proto.Uint64(version)}, nil
+	}
+	for _, plugin := range serverPlugins {
+		if plugin.Content != nil {
+			continue
+		}
+		image, err := getPluginImage(pluginType, plugin.GetName())
+		if err != nil {
+			// 插件在两次查询之间被删除或更新，ingester在下次同步时重试
+			log.Errorf("get server plugin(type=%d, name=%s) from db failed, %s", pluginType, plugin.GetName(), err)
+			return &api.ServerPluginSyncResponse{Status: &STATUS_FAILED}, nil
+		}
+		plugin.Content = image
+	}
+	log.Infof("send server plugins(%d) to ingester(%s), version %d -> %d", len(serverPlugins), r.GetCtrlIp(), r.GetVersion(), version)
+	return &api.ServerPluginSyncResponse{
+		Status:  &STATUS_SUCCESS,
+		Version: proto.Uint64(version),
+		Plugins: serverPlugins,
+	}, nil
+}
@@ -103,12 +103,24 @@ func (e *TSDBEvent) AnalyzerSync(ctx context.Context, in *api.SyncRequest) (*api
 
 	configure := e.generateConfig(tsdbIP)
 	platformData := []byte{}
+	var platformDataDelta []byte
 	if versionPlatformData != in.GetVersionPlatformData() {
-		platformData = nodeInfo.GetPlatformDataStr()
+		if in.GetSupportDelta() {
+			platformDataDelta = nodeInfo.GetPlatformDataDelta(in.GetVersionPlatformData(), versionPlatformData)
+		}
+		if platformDataDelta == nil {
+			platformData = nodeInfo.GetPlatformDataStr()
+		}
 	}
 	groups := []byte{}
+	var groupsDelta []byte
 	if versionGroups != in.GetVersionGroups() {
-		groups = nodeInfo.GetGroups()
+		if in.GetSupportDelta() {
+			groupsDelta = nodeInfo.GetGroupsDelta(in.GetVersionGroups(), versionGroups)
+		}
+		if groupsDelta == nil {
+			groups = nodeInfo.GetGroups()
+		}
 	}
 	acls := []byte{}
 	if versionPolicy != in.GetVersionAcls() {
@@ -120,7 +132,9 @@ func (e *TSDBEvent) AnalyzerSync(ctx context.Context, in *api.SyncRequest) (*api
 	return &api.SyncResponse{
 		Status:                  &STATUS_SUCCESS,
 		PlatformData:            platformData,
+		PlatformDataDelta:       platformDataDelta,
 		Groups:                  groups,
+		GroupsDelta:             groupsDelta,
 		FlowAcls:                acls,
 		PodIps:                  podIPs,
 		VtapIps:                 vTapIPs,
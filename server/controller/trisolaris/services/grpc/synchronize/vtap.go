@@ -366,12 +366,24 @@ func (e *VTapEvent) Sync(ctx context.Context, in *api.SyncRequest) (*api.SyncRes
 		vtapCache.UpdatePushVersionPolicy(versionPolicy)
 	}
 	platformData := []byte{}
+	var platformDataDelta []byte
 	if versionPlatformData != in.GetVersionPlatformData() {
-		platformData = vtapCache.GetSimplePlatformDataStr()
+		if in.GetSupportDelta() {
+			platformDataDelta = vtapCache.GetSimplePlatformDataDelta(in.GetVersionPlatformData(), versionPlatformData)
+		}
+		if platformDataDelta == nil {
+			platformData = vtapCache.GetSimplePlatformDataStr()
+		}
 	}
 	groups := []byte{}
+	var groupsDelta []byte
 	if versionGroups != in.GetVersionGroups() {
-		groups = gVTapInfo.GetGroupData()
+		if in.GetSupportDelta() {
+			groupsDelta = gVTapInfo.GetGroupDataDelta(in.GetVersionGroups(), versionGroups)
+		}
+		if groupsDelta == nil {
+			groups = gVTapInfo.GetGroupData()
+		}
 	}
 	acls := []byte{}
 	if versionPolicy != in.GetVersionAcls() {
@@ -405,7 +417,9 @@ func (e *VTapEvent) Sync(ctx context.Context, in *api.SyncRequest) (*api.SyncRes
 		RemoteSegments:      remoteSegments,
 		Config:              configInfo,
 		PlatformData:        platformData,
+		PlatformDataDelta:   platformDataDelta,
 		Groups:              groups,
+		GroupsDelta:         groupsDelta,
 		FlowAcls:            acls,
 		VersionPlatformData: proto.Uint64(versionPlatformData),
 		VersionGroups:       proto.Uint64(versionGroups),
@@ -649,17 +663,34 @@ func (e *VTapEvent) pushResponse(in *api.SyncRequest) (*api.SyncResponse, error)
 	}
 
 	platformData := []byte{}
+	var platformDataDelta []byte
 	if versionPlatformData != pushVersionPlatformData {
-		platformData = vtapCache.GetSimplePlatformDataStr()
+		if in.GetSupportDelta() {
+			platformDataDelta = vtapCache.GetSimplePlatformDataDelta(pushVersionPlatformData, versionPlatformData)
+		}
+		if platformDataDelta == nil {
+			platformData = vtapCache.GetSimplePlatformDataStr()
+		}
 	}
 	groups := []byte{}
+	var groupsDelta []byte
 	if versionGroups != pushVersionGroups {
-		groups = gVTapInfo.GetGroupData()
+		if in.GetSupportDelta() {
+			groupsDelta = gVTapInfo.GetGroupDataDelta(pushVersionGroups, versionGroups)
+		}
+		if groupsDelta == nil {
+			groups = gVTapInfo.GetGroupData()
+		}
 	}
 	acls := []byte{}
 	if versionPolicy != in.GetVersionAcls() {
 		acls = gVTapInfo.GetVTapPolicyData(vtapID, functions)
 	}
+	// 支持增量的采集器按推送的版本计算下一次增量，避免重复下发
+	if in.GetSupportDelta() {
+		vtapCache.UpdatePushVersionPlatformData(versionPlatformData)
+		vtapCache.UpdatePushVersionGroups(versionGroups)
+	}
 
 	// 只有专属采集器下发tap_types
 	tapTypes := []*api.TapType{}
@@ -687,9 +718,11 @@ func (e *VTapEvent) pushResponse(in *api.SyncRequest) (*api.SyncResponse, error)
 		RemoteSegments:      remoteSegments,
 		Config:              configInfo,
 		PlatformData:        platformData,
+		PlatformDataDelta:   platformDataDelta,
 		SkipInterface:       skipInterface,
 		VersionPlatformData: proto.Uint64(versionPlatformData),
 		Groups:              groups,
+		GroupsDelta:         groupsDelta,
 		VersionGroups:       proto.Uint64(versionGroups),
 		FlowAcls:            acls,
 		VersionAcls:         proto.Uint64(versionPolicy),
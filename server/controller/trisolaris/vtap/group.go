@@ -37,3 +37,7 @@ func (g *GroupData) getGroupData() []byte {
 func (g *GroupData) getGroupDataVersion() uint64 {
 	return g.metaData.GetTridentGroupsVersion()
 }
+
+func (g *GroupData) getGroupDataDelta(oldVersion, version uint64) []byte {
+	return g.metaData.GetTridentGroupsDelta(oldVersion, version)
+}
@@ -26,6 +26,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"gopkg.in/yaml.v2"
 	"gorm.io/gorm"
 
 	mapset "github.com/deckarep/golang-set"
@@ -36,6 +37,7 @@ import (
 	"github.com/deepflowio/deepflow/server/controller/common"
 	. "github.com/deepflowio/deepflow/server/controller/common"
 	models "github.com/deepflowio/deepflow/server/controller/db/mysql"
+	cmodel "github.com/deepflowio/deepflow/server/controller/model"
 	. "github.com/deepflowio/deepflow/server/controller/trisolaris/common"
 	"github.com/deepflowio/deepflow/server/controller/trisolaris/config"
 	"github.com/deepflowio/deepflow/server/controller/trisolaris/dbmgr"
@@ -372,7 +374,47 @@ func (v *VTapInfo) loadConfigData() {
 	v.realDefaultConfig = NewVTapConfig(deafaultConfiguration)
 	dbDataCache := v.metaData.GetDBDataCache()
 	configs := dbDataCache.GetVTapGroupConfigurationsFromDB(v.db)
-	v.convertConfig(configs)
+	v.convertConfig(configs, v.loadPluginVersions())
+}
+
+// 返回插件名称到默认版本的映射
+func (v *VTapInfo) loadPluginVersions() map[string]int {
+	var plugins []*models.Plugin
+	if err := v.db.Select("name", "version").Find(&plugins).Error; err != nil {
+		log.Error(err)
+		return nil
+	}
+	pluginVersions := make(map[string]int, len(plugins))
+	for _, plugin := range plugins {
+		pluginVersions[plugin.Name] = plugin.Version
+	}
+	return pluginVersions
+}
+
+// 将未指定版本的wasm-plugins改写为name@默认版本，默认版本变化时采集器的插件列表随之变化，从而重新获取插件
+func pinPluginVersions(yamlConfig string, pluginVersions map[string]int) string {
+	staticConfig := &cmodel.StaticConfig{}
+	if err := yaml.Unmarshal([]byte(yamlConfig), staticConfig); err != nil || len(staticConfig.WasmPlugins) == 0 {
+		return yamlConfig
+	}
+	changed := false
+	for i, wasmPlugin := range staticConfig.WasmPlugins {
+		name, version, err := ParsePluginName(wasmPlugin)
+		if err != nil || version != PLUGIN_VERSION_DEFAULT || pluginVersions[name] == PLUGIN_VERSION_DEFAULT {
+			continue
+		}
+		staticConfig.WasmPlugins[i] = FormatPluginName(name, pluginVersions[name])
+		changed = true
+	}
+	if !changed {
+		return yamlConfig
+	}
+	b, err := yaml.Marshal(staticConfig)
+	if err != nil {
+		log.Error(err)
+		return yamlConfig
+	}
+	return string(b)
 }
 
 func (v *VTapInfo) loadKubernetesCluster() {
@@ -446,7 +488,7 @@ func DefaultFieldNone(filed string) bool {
 	return false
 }
 
-func (v *VTapInfo) convertConfig(configs []*models.VTapGroupConfiguration) {
+func (v *VTapInfo) convertConfig(configs []*models.VTapGroupConfiguration, pluginVersions map[string]int) {
 	if configs == nil {
 		log.Error("no vtap configs data")
 		return
@@ -460,7 +502,7 @@ func (v *VTapInfo) convertConfig(configs []*models.VTapGroupConfiguration) {
 			continue
 		}
 		if config.YamlConfig != nil {
-			vtapGroupLcuuidToLocalConfig[*config.VTapGroupLcuuid] = *config.YamlConfig
+			vtapGroupLcuuidToLocalConfig[*config.VTapGroupLcuuid] = pinPluginVersions(*config.YamlConfig, pluginVersions)
 		} else {
 			vtapGroupLcuuidToLocalConfig[*config.VTapGroupLcuuid] = ""
 		}
@@ -573,6 +615,10 @@ func (v *VTapInfo) GetGroupDataVersion() uint64 {
 	return v.groupData.getGroupDataVersion()
 }
 
+func (v *VTapInfo) GetGroupDataDelta(oldVersion, version uint64) []byte {
+	return v.groupData.getGroupDataDelta(oldVersion, version)
+}
+
 func (v *VTapInfo) GetVTapPolicyData(vtapID int, functions mapset.Set) []byte {
 	return v.vTapPolicyData.getVTapPolicyData(vtapID, functions)
 }
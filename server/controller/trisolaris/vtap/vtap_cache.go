@@ -17,6 +17,7 @@
 package vtap
 
 import (
+	"fmt"
 	"sort"
 	"strconv"
 	"strings"
@@ -161,6 +162,8 @@ type VTapCache struct {
 	VPCID int
 	// vtap platform data
 	PlatformData *atomic.Value //*PlatformData
+	// 采集器平台数据的版本历史，用于下发增量
+	platformDataHistory *metadata.PlatformDataHistory
 }
 
 func NewVTapCache(vtap *models.VTap) *VTapCache {
@@ -391,6 +394,15 @@ func (c *VTapCache) GetSimplePlatformDataVersion() uint64 {
 	return platformData.GetVersion()
 }
 
+// GetSimplePlatformDataDelta 返回从oldVersion到version的平台数据增量，返回nil时需下发全量数据
+func (c *VTapCache) GetSimplePlatformDataDelta(oldVersion, version uint64) []byte {
+	platformData := c.GetVTapPlatformData()
+	if platformData == nil || platformData.GetVersion() != version {
+		return nil
+	}
+	return c.platformDataHistory.GetDelta(oldVersion, platformData)
+}
+
 func (c *VTapCache) GetSimplePlatformDataStr() []byte {
 	platformData := c.GetVTapPlatformData()
 	if platformData == nil {
@@ -768,6 +780,8 @@ func (c *VTapCache) updateCtrlMacFromDB(ctrlMac string) {
 }
 
 func (c *VTapCache) init(v *VTapInfo) {
+	c.platformDataHistory = metadata.NewPlatformDataHistory(
+		fmt.Sprintf("vtap(%s) platform data", c.GetVTapHost()), v.config.PlatformDataHistorySize)
 	c.modifyVTapCache(v)
 	c.initVTapPodDomains(v)
 	c.initVTapConfig(v)
@@ -781,6 +781,12 @@ var ColumnAdd626 = []*ColumnAdds{
 		ColumnNames: []string{"acl_gids"},
 		ColumnType:  ckdb.ArrayUInt16,
 	},
+	&ColumnAdds{
+		Dbs:         []string{"flow_metrics"},
+		Tables:      append(append([]string{"vtap_acl.1m", "vtap_acl.1m_local"}, flowMetricsTables...), flowMetricsEdgeTables...),
+		ColumnNames: []string{"_backfill"},
+		ColumnType:  ckdb.UInt8,
+	},
 }
 
 var TableRenames626 = []*TableRename{
@@ -1571,6 +1577,54 @@ func (i *Issu) Start() error {
 	return nil
 }
 
+// RunTTLRules 已存在的表创建时未带配置文件中的TTL规则, 对未设置过规则的表应用配置的规则,
+// 之后规则通过datasource接口修改
+func (i *Issu) RunTTLRules(ds *datasource.DatasourceManager) error {
+	if len(i.cfg.TTLRules) == 0 {
+		return nil
+	}
+	if len(i.Connections) == 0 {
+		return fmt.Errorf("connections is nil")
+	}
+	connect := i.Connections[0]
+	for _, setting := range i.cfg.TTLRules {
+		tables := setting.Tables
+		if len(tables) == 0 {
+			localTables, err := getTables(connect, setting.Db, "")
+			if err != nil {
+				log.Warningf("get tables of db(%s) failed: %s", setting.Db, err)
+				continue
+			}
+			for _, table := range localTables {
+				if strings.HasSuffix(table, ckdb.LOCAL_SUBFFIX) {
+					tables = append(tables, strings.TrimSuffix(table, ckdb.LOCAL_SUBFFIX))
+				}
+			}
+		}
+		for _, table := range tables {
+			isSet, err := datasource.IsTTLRulesSet(connect, setting.Db, table)
+			if err != nil {
+				log.Warningf("db: %s, table: %s get ttl rules failed: %s", setting.Db, table, err)
+				continue
+			}
+			if isSet {
+				continue
+			}
+			rules := ckdb.GetTTLRules(i.cfg.GetCKDBTTLRules(), setting.Db, table)
+			if err := ds.ModTTLRules(i.Connections, setting.Db, table, rules); err != nil {
+				if strings.Contains(err.Error(), "doesn't exist") {
+					log.Infof("db: %s, table: %s info: %s", setting.Db, table, err)
+				} else {
+					log.Warningf("db: %s, table: %s apply ttl rules failed: %s", setting.Db, table, err)
+				}
+				continue
+			}
+			log.Infof("db: %s, table: %s apply ttl rules: %+v", setting.Db, table, rules)
+		}
+	}
+	return nil
+}
+
 func (i *Issu) Close() error {
 	if len(i.Connections) == 0 {
 		return nil
@@ -0,0 +1,613 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ckmonitor
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deepflowio/deepflow/server/ingester/common"
+	"github.com/deepflowio/deepflow/server/ingester/config"
+	"github.com/deepflowio/deepflow/server/libs/ckdb"
+)
+
+// 分区归档: 通过clickhouse的file/s3表函数将分区导出为Parquet文件, 并写入manifest文件描述分区信息.
+// 'file' 类型的文件保存在各clickhouse节点的user_files_path下, 's3' 类型保存在S3兼容的存储中.
+// 目录结构: <path>/<database>/<table>/<partition_id>_<node>_<archived_at>.{parquet,manifest.json}
+
+const (
+	ARCHIVE_STORAGE_FILE = "file"
+	ARCHIVE_STORAGE_S3   = "s3"
+
+	PARQUET_SUFFIX  = ".parquet"
+	MANIFEST_SUFFIX = ".manifest.json"
+
+	RESTORE_PREFIX = "restore_"
+)
+
+const manifestStructure = "database String, table String, partition String, partition_id String, " +
+	"time_key String, min_time UInt32, max_time UInt32, rows UInt64, schema_version String, " +
+	"structure String, node String, data_file String, archived_at UInt32"
+
+var (
+	nameRegexp         = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+	tableNameRegexp    = regexp.MustCompile(`^[a-zA-Z0-9_.]+$`)
+	partitionKeyRegexp = regexp.MustCompile(`\((\w+)\)`)
+	nodeNameRegexp     = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+)
+
+type Manifest struct {
+	Database      string
+	Table         string // 全局表名
+	Partition     string
+	PartitionID   string
+	TimeKey       string
+	MinTime       uint32
+	MaxTime       uint32
+	Rows          uint64
+	SchemaVersion string // 时间字段的注释, 即表的版本
+	Structure     string // 导出时的列结构, 恢复时使用
+	Node          string // 导出数据的clickhouse节点
+	DataFile      string
+	ArchivedAt    uint32
+}
+
+type RestoreTask struct {
+	Database, Table, Name string
+	Start, End            uint32
+	Total, Done           int
+	Rows                  uint64
+	Err                   error
+	Finished              bool
+	StartedAt             time.Time
+}
+
+type Archiver struct {
+	cfg      *config.CKDBArchive
+	cluster  string
+	addrs    []string
+	username string
+	password string
+
+	restoreLock  sync.Mutex
+	restoreTasks map[string]*RestoreTask
+
+	exit bool
+}
+
+func NewArchiver(cfg *config.Config) *Archiver {
+	return &Archiver{
+		cfg:          &cfg.Archive,
+		cluster:      cfg.CKDB.ClusterName,
+		addrs:        cfg.CKDB.ActualAddrs,
+		username:     cfg.CKDBAuth.Username,
+		password:     cfg.CKDBAuth.Password,
+		restoreTasks: make(map[string]*RestoreTask),
+	}
+}
+
+func escapeString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return strings.ReplaceAll(s, `'`, `\'`)
+}
+
+func (a *Archiver) isArchiveTable(database, table string) bool {
+	for _, t := range a.cfg.Tables {
+		if database == t.Database && (t.TablesContain == "" || strings.Contains(table, t.TablesContain)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Archiver) filePath(database, table, name string) string {
+	return strings.TrimSuffix(a.cfg.Path, "/") + "/" + database + "/" + table + "/" + name
+}
+
+// 生成表函数, path可以包含通配符
+func (a *Archiver) tableFunction(path, format, structure string) string {
+	if a.cfg.StorageType == ARCHIVE_STORAGE_S3 {
+		return fmt.Sprintf("s3('%s', '%s', '%s', '%s', '%s')", escapeString(path),
+			escapeString(a.cfg.S3AccessKeyID), escapeString(a.cfg.S3SecretAccessKey), format, escapeString(structure))
+	}
+	return fmt.Sprintf("file('%s', '%s', '%s')", escapeString(path), format, escapeString(structure))
+}
+
+func queryNode(connect *sql.DB) (string, error) {
+	var node string
+	if err := connect.QueryRow("SELECT hostName()").Scan(&node); err != nil {
+		return "", err
+	}
+	return nodeNameRegexp.ReplaceAllString(node, "_"), nil
+}
+
+// 返回导出的列结构, 如: 'time DateTime, ip4 IPv4', 不包含MATERIALIZED和ALIAS列
+func queryStructure(connect *sql.DB, database, localTable string) (string, error) {
+	rows, err := connect.Query(fmt.Sprintf("SELECT name, type FROM system.columns WHERE database='%s' AND table='%s' AND default_kind NOT IN ('MATERIALIZED', 'ALIAS') ORDER BY position",
+		escapeString(database), escapeString(localTable)))
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	columns := []string{}
+	var name, columnType string
+	for rows.Next() {
+		if err := rows.Scan(&name, &columnType); err != nil {
+			return "", err
+		}
+		columns = append(columns, fmt.Sprintf("`%s` %s", name, columnType))
+	}
+	if len(columns) == 0 {
+		return "", fmt.Errorf("table %s.%s doesn't exist", database, localTable)
+	}
+	return strings.Join(columns, ", "), nil
+}
+
+// 返回表的时间字段和版本, 时间字段从partition_key中获取, 如: toStartOfHour(time)
+func queryTimeKeyAndVersion(connect *sql.DB, database, localTable string) (string, string, error) {
+	var partitionKey string
+	if err := connect.QueryRow(fmt.Sprintf("SELECT partition_key FROM system.tables WHERE database='%s' AND name='%s'",
+		escapeString(database), escapeString(localTable))).Scan(&partitionKey); err != nil {
+		return "", "", err
+	}
+	matches := partitionKeyRegexp.FindStringSubmatch(partitionKey)
+	if len(matches) != 2 {
+		return "", "", fmt.Errorf("table %s.%s unknown partition key '%s'", database, localTable, partitionKey)
+	}
+	timeKey := matches[1]
+	var version string
+	if err := connect.QueryRow(fmt.Sprintf("SELECT comment FROM system.columns WHERE database='%s' AND table='%s' AND name='%s'",
+		escapeString(database), escapeString(localTable), timeKey)).Scan(&version); err != nil {
+		return "", "", err
+	}
+	return timeKey, version, nil
+}
+
+func (a *Archiver) newManifest(connect *sql.DB, database, localTable, partition string) (*Manifest, error) {
+	m := &Manifest{
+		Database:   database,
+		Table:      strings.TrimSuffix(localTable, ckdb.LOCAL_SUBFFIX),
+		Partition:  partition,
+		ArchivedAt: uint32(time.Now().Unix()),
+	}
+	var minTime, maxTime time.Time
+	if err := connect.QueryRow(fmt.Sprintf("SELECT partition_id, min(min_time), max(max_time), sum(rows) FROM system.parts WHERE database='%s' AND table='%s' AND partition='%s' AND active=1 GROUP BY partition_id",
+		escapeString(database), escapeString(localTable), escapeString(partition))).Scan(&m.PartitionID, &minTime, &maxTime, &m.Rows); err != nil {
+		return nil, fmt.Errorf("get partition(%s) of %s.%s failed: %s", partition, database, localTable, err)
+	}
+	m.MinTime, m.MaxTime = uint32(minTime.Unix()), uint32(maxTime.Unix())
+
+	var err error
+	if m.TimeKey, m.SchemaVersion, err = queryTimeKeyAndVersion(connect, database, localTable); err != nil {
+		return nil, err
+	}
+	if m.Structure, err = queryStructure(connect, database, localTable); err != nil {
+		return nil, err
+	}
+	if m.Node, err = queryNode(connect); err != nil {
+		return nil, err
+	}
+	m.DataFile = fmt.Sprintf("%s_%s_%d%s", nodeNameRegexp.ReplaceAllString(m.PartitionID, "_"), m.Node, m.ArchivedAt, PARQUET_SUFFIX)
+	return m, nil
+}
+
+func (a *Archiver) writeManifest(connect *sql.DB, m *Manifest) error {
+	path := a.filePath(m.Database, m.Table, strings.TrimSuffix(m.DataFile, PARQUET_SUFFIX)+MANIFEST_SUFFIX)
+	sql := fmt.Sprintf("INSERT INTO FUNCTION %s VALUES ('%s', '%s', '%s', '%s', '%s', %d, %d, %d, '%s', '%s', '%s', '%s', %d)",
+		a.tableFunction(path, "JSONEachRow", manifestStructure),
+		escapeString(m.Database), escapeString(m.Table), escapeString(m.Partition), escapeString(m.PartitionID),
+		escapeString(m.TimeKey), m.MinTime, m.MaxTime, m.Rows, escapeString(m.SchemaVersion),
+		escapeString(m.Structure), escapeString(m.Node), escapeString(m.DataFile), m.ArchivedAt)
+	_, err := connect.Exec(sql)
+	return err
+}
+
+// ArchivePartition 导出分区数据, 数据文件写完后再写manifest, 有manifest的归档才是完整的
+func (a *Archiver) ArchivePartition(connect *sql.DB, database, localTable, partition string) (*Manifest, error) {
+	m, err := a.newManifest(connect, database, localTable, partition)
+	if err != nil {
+		return nil, err
+	}
+	sql := fmt.Sprintf("INSERT INTO FUNCTION %s SELECT * FROM %s.`%s` WHERE _partition_id='%s'",
+		a.tableFunction(a.filePath(m.Database, m.Table, m.DataFile), "Parquet", m.Structure),
+		database, localTable, escapeString(m.PartitionID))
+	log.Infof("archive partition: %s, database: %s, table: %s, minTime: %d, maxTime: %d, rows: %d, file: %s",
+		partition, database, localTable, m.MinTime, m.MaxTime, m.Rows, m.DataFile)
+	if _, err := connect.Exec(sql); err != nil {
+		return nil, fmt.Errorf("archive partition(%s) of %s.%s failed: %s", partition, database, localTable, err)
+	}
+	if err := a.writeManifest(connect, m); err != nil {
+		return nil, fmt.Errorf("write manifest of %s failed: %s", m.DataFile, err)
+	}
+	return m, nil
+}
+
+// 删除分区前归档, 返回是否可以删除分区
+func (a *Archiver) archiveBeforeDrop(connect *sql.DB, database, localTable, partition string) bool {
+	if !a.cfg.BeforeDrop || !a.isArchiveTable(database, localTable) {
+		return true
+	}
+	if _, err := a.ArchivePartition(connect, database, localTable, partition); err != nil {
+		log.Error(err)
+		return a.cfg.DropOnFailure
+	}
+	return true
+}
+
+func (a *Archiver) queryManifests(connect *sql.DB, database, table string) ([]*Manifest, error) {
+	if database == "" {
+		database = "*"
+	}
+	if table == "" {
+		table = "*"
+	}
+	rows, err := connect.Query(fmt.Sprintf("SELECT * FROM %s",
+		a.tableFunction(a.filePath(database, table, "*"+MANIFEST_SUFFIX), "JSONEachRow", manifestStructure)))
+	if err != nil {
+		// 还没有归档文件
+		if strings.Contains(err.Error(), "No such file") || strings.Contains(err.Error(), "CANNOT_STAT") ||
+			strings.Contains(err.Error(), "Cannot stat") || strings.Contains(err.Error(), "NoSuchKey") {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer rows.Close()
+	manifests := []*Manifest{}
+	for rows.Next() {
+		m := &Manifest{}
+		if err := rows.Scan(&m.Database, &m.Table, &m.Partition, &m.PartitionID, &m.TimeKey, &m.MinTime, &m.MaxTime,
+			&m.Rows, &m.SchemaVersion, &m.Structure, &m.Node, &m.DataFile, &m.ArchivedAt); err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, m)
+	}
+	return manifests, nil
+}
+
+// ListManifests 'file' 类型需要查询所有节点, 's3' 类型查询一个节点即可
+func (a *Archiver) ListManifests(conns common.DBs, database, table string) ([]*Manifest, error) {
+	manifests := []*Manifest{}
+	for _, connect := range conns {
+		ms, err := a.queryManifests(connect, database, table)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, ms...)
+		if a.cfg.StorageType == ARCHIVE_STORAGE_S3 {
+			break
+		}
+	}
+	sort.Slice(manifests, func(i, j int) bool {
+		if manifests[i].Table != manifests[j].Table {
+			return manifests[i].Table < manifests[j].Table
+		}
+		return manifests[i].MinTime < manifests[j].MinTime
+	})
+	return manifests, nil
+}
+
+// 定时归档, 归档数据时间早于schedule-min-age且未归档过的分区
+func (a *Archiver) archiveSchedule() {
+	conns, err := common.NewCKConnections(a.addrs, a.username, a.password)
+	if err != nil {
+		log.Warning(err)
+		return
+	}
+	defer conns.Close()
+	deadline := time.Now().Add(-time.Duration(a.cfg.ScheduleMinAge) * time.Hour)
+	for _, connect := range conns {
+		node, err := queryNode(connect)
+		if err != nil {
+			log.Warning(err)
+			continue
+		}
+		rows, err := connect.Query(fmt.Sprintf("SELECT database, table, partition, partition_id FROM system.parts WHERE active=1 AND endsWith(table, '%s') GROUP BY database, table, partition, partition_id HAVING max(max_time) < toDateTime(%d) ORDER BY database, table, partition_id",
+			ckdb.LOCAL_SUBFFIX, deadline.Unix()))
+		if err != nil {
+			log.Warning(err)
+			continue
+		}
+		type partition struct{ database, table, partition, partitionID string }
+		partitions := []partition{}
+		for rows.Next() {
+			var p partition
+			if err := rows.Scan(&p.database, &p.table, &p.partition, &p.partitionID); err != nil {
+				log.Warning(err)
+				break
+			}
+			if a.isArchiveTable(p.database, p.table) {
+				partitions = append(partitions, p)
+			}
+		}
+		rows.Close()
+
+		archived := make(map[string]bool)
+		for _, p := range partitions {
+			table := strings.TrimSuffix(p.table, ckdb.LOCAL_SUBFFIX)
+			if _, ok := archived[p.database+"."+table]; !ok {
+				manifests, err := a.queryManifests(connect, p.database, table)
+				if err != nil {
+					log.Warning(err)
+				}
+				archived[p.database+"."+table] = true
+				for _, m := range manifests {
+					archived[m.Database+"."+m.Table+"/"+m.PartitionID+"/"+m.Node] = true
+				}
+			}
+			if archived[p.database+"."+table+"/"+p.partitionID+"/"+node] {
+				continue
+			}
+			if _, err := a.ArchivePartition(connect, p.database, p.table, p.partition); err != nil {
+				log.Warning(err)
+			}
+			if a.exit {
+				return
+			}
+		}
+	}
+}
+
+func (a *Archiver) Start() {
+	if a.cfg.ScheduleInterval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		counter := 0
+		for !a.exit {
+			<-ticker.C
+			counter++
+			if counter%a.cfg.ScheduleInterval != 0 {
+				continue
+			}
+			a.archiveSchedule()
+		}
+	}()
+}
+
+func (a *Archiver) Close() {
+	a.exit = true
+}
+
+// 恢复后的表名为 '<table>.<name>', 查询时指定 data_precision 为 <name> 即可
+func restoreTableName(table, name string) string {
+	return table + "." + name
+}
+
+func (a *Archiver) createRestoreTable(conns common.DBs, database, table, name string) error {
+	restoreTable := restoreTableName(table, name)
+	// 恢复的表不设置TTL, 避免数据被立即删除
+	if _, err := conns.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s.`%s` AS %s.`%s` ENGINE = MergeTree ORDER BY tuple()",
+		database, restoreTable+ckdb.LOCAL_SUBFFIX, database, table+ckdb.LOCAL_SUBFFIX)); err != nil {
+		return err
+	}
+	_, err := conns.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s.`%s` AS %s.`%s` ENGINE = %s",
+		database, restoreTable, database, restoreTable+ckdb.LOCAL_SUBFFIX,
+		fmt.Sprintf(ckdb.Distributed.String(), a.cluster, database, restoreTable+ckdb.LOCAL_SUBFFIX)))
+	return err
+}
+
+// 恢复时只导入当前表结构中仍存在的列
+func restoreColumns(structure string, columns []string) []string {
+	exists := make(map[string]bool, len(columns))
+	for _, c := range columns {
+		exists[c] = true
+	}
+	restores := []string{}
+	for _, column := range strings.Split(structure, ", `") {
+		name := strings.SplitN(strings.TrimPrefix(column, "`"), "`", 2)[0]
+		if exists[name] {
+			restores = append(restores, "`"+name+"`")
+		}
+	}
+	return restores
+}
+
+func queryColumns(connect *sql.DB, database, table string) ([]string, error) {
+	rows, err := connect.Query(fmt.Sprintf("SELECT name FROM system.columns WHERE database='%s' AND table='%s'",
+		escapeString(database), escapeString(table)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns := []string{}
+	var name string
+	for rows.Next() {
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns = append(columns, name)
+	}
+	return columns, nil
+}
+
+func countRows(connect *sql.DB, database, table string) (uint64, error) {
+	var count uint64
+	err := connect.QueryRow(fmt.Sprintf("SELECT count() FROM %s.`%s`", database, table)).Scan(&count)
+	return count, err
+}
+
+// 返回实际导入的行数, 只导入时间范围内的数据, 因此可能少于归档的行数
+func (a *Archiver) restoreManifest(conns common.DBs, nodes []string, m *Manifest, task *RestoreTask) (uint64, error) {
+	// 'file' 类型只能在导出数据的节点上恢复
+	var connect *sql.DB
+	for i, node := range nodes {
+		if node == m.Node {
+			connect = conns[i]
+			break
+		}
+	}
+	if connect == nil {
+		if a.cfg.StorageType == ARCHIVE_STORAGE_FILE {
+			return 0, fmt.Errorf("node %s of %s is not found", m.Node, m.DataFile)
+		}
+		connect = conns[0]
+	}
+	restoreTable := restoreTableName(m.Table, task.Name) + ckdb.LOCAL_SUBFFIX
+	tableColumns, err := queryColumns(connect, m.Database, restoreTable)
+	if err != nil {
+		return 0, err
+	}
+	// 同一任务的数据按顺序导入, 导入前后的行数差即为导入的行数
+	before, err := countRows(connect, m.Database, restoreTable)
+	if err != nil {
+		return 0, err
+	}
+	columns := strings.Join(restoreColumns(m.Structure, tableColumns), ", ")
+	sql := fmt.Sprintf("INSERT INTO %s.`%s` (%s) SELECT %s FROM %s WHERE `%s` >= %d AND `%s` <= %d",
+		m.Database, restoreTable, columns, columns,
+		a.tableFunction(a.filePath(m.Database, m.Table, m.DataFile), "Parquet", m.Structure),
+		m.TimeKey, task.Start, m.TimeKey, task.End)
+	if _, err := connect.Exec(sql); err != nil {
+		return 0, fmt.Errorf("restore %s failed: %s", m.DataFile, err)
+	}
+	after, err := countRows(connect, m.Database, restoreTable)
+	if err != nil {
+		return 0, err
+	}
+	if after < before {
+		return 0, nil
+	}
+	return after - before, nil
+}
+
+func (a *Archiver) setTaskError(task *RestoreTask, err error) {
+	log.Warning(err)
+	a.restoreLock.Lock()
+	task.Err = err
+	a.restoreLock.Unlock()
+}
+
+func (a *Archiver) restore(task *RestoreTask, manifests []*Manifest) {
+	defer func() {
+		a.restoreLock.Lock()
+		task.Finished = true
+		a.restoreLock.Unlock()
+	}()
+	conns, err := common.NewCKConnections(a.addrs, a.username, a.password)
+	if err != nil {
+		a.setTaskError(task, err)
+		return
+	}
+	defer conns.Close()
+	nodes := make([]string, len(conns))
+	for i, connect := range conns {
+		if nodes[i], err = queryNode(connect); err != nil {
+			a.setTaskError(task, err)
+			return
+		}
+	}
+	if err := a.createRestoreTable(conns, task.Database, task.Table, task.Name); err != nil {
+		a.setTaskError(task, err)
+		return
+	}
+	for _, m := range manifests {
+		rows, err := a.restoreManifest(conns, nodes, m, task)
+		if err != nil {
+			a.setTaskError(task, err)
+			continue
+		}
+		a.restoreLock.Lock()
+		task.Done++
+		task.Rows += rows
+		a.restoreLock.Unlock()
+		log.Infof("restore %s to %s.%s done", m.DataFile, task.Database, restoreTableName(task.Table, task.Name))
+	}
+}
+
+// Restore 异步将时间范围内的归档数据导入到临时表 '<database>.<table>.<name>'
+func (a *Archiver) Restore(database, table string, start, end uint32, name string) (*RestoreTask, error) {
+	if !tableNameRegexp.MatchString(database) || !tableNameRegexp.MatchString(table) {
+		return nil, fmt.Errorf("invalid database(%s) or table(%s)", database, table)
+	}
+	if name == "" {
+		name = RESTORE_PREFIX + time.Now().Format("20060102150405")
+	}
+	if !nameRegexp.MatchString(name) {
+		return nil, fmt.Errorf("invalid restore name(%s)", name)
+	}
+	if start > end {
+		return nil, fmt.Errorf("start time(%d) is bigger than end time(%d)", start, end)
+	}
+
+	conns, err := common.NewCKConnections(a.addrs, a.username, a.password)
+	if err != nil {
+		return nil, err
+	}
+	allManifests, err := a.ListManifests(conns, database, table)
+	conns.Close()
+	if err != nil {
+		return nil, err
+	}
+	manifests := []*Manifest{}
+	for _, m := range allManifests {
+		if m.MaxTime >= start && m.MinTime <= end {
+			manifests = append(manifests, m)
+		}
+	}
+	if len(manifests) == 0 {
+		return nil, fmt.Errorf("no archive of %s.%s between %d and %d", database, table, start, end)
+	}
+
+	a.restoreLock.Lock()
+	if t, ok := a.restoreTasks[name]; ok && !t.Finished {
+		a.restoreLock.Unlock()
+		return nil, fmt.Errorf("restore task %s is running", name)
+	}
+	task := &RestoreTask{
+		Database:  database,
+		Table:     table,
+		Name:      name,
+		Start:     start,
+		End:       end,
+		Total:     len(manifests),
+		StartedAt: time.Now(),
+	}
+	a.restoreTasks[name] = task
+	a.restoreLock.Unlock()
+
+	go a.restore(task, manifests)
+	return task, nil
+}
+
+// DropRestore 删除恢复的临时表
+func (a *Archiver) DropRestore(database, table, name string) error {
+	if !tableNameRegexp.MatchString(database) || !tableNameRegexp.MatchString(table) || !nameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid database(%s), table(%s) or name(%s)", database, table, name)
+	}
+	conns, err := common.NewCKConnections(a.addrs, a.username, a.password)
+	if err != nil {
+		return err
+	}
+	defer conns.Close()
+	restoreTable := restoreTableName(table, name)
+	for _, t := range []string{restoreTable, restoreTable + ckdb.LOCAL_SUBFFIX} {
+		if _, err := conns.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s.`%s`", database, t)); err != nil {
+			return err
+		}
+	}
+	a.restoreLock.Lock()
+	delete(a.restoreTasks, name)
+	a.restoreLock.Unlock()
+	return nil
+}
@@ -0,0 +1,205 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ckmonitor
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/deepflowio/deepflow/server/ingester/common"
+	"github.com/deepflowio/deepflow/server/ingester/ingesterctl"
+	"github.com/deepflowio/deepflow/server/libs/debug"
+)
+
+const (
+	ARCHIVE_CMD_LIST = iota
+	ARCHIVE_CMD_RESTORE
+	ARCHIVE_CMD_STATUS
+	ARCHIVE_CMD_DROP
+)
+
+const (
+	timeFormat         = "2006-01-02 15:04:05"
+	localISOTimeFormat = "2006-01-02T15:04:05"
+)
+
+// 支持unix时间戳, '2006-01-02 15:04:05', '2006-01-02T15:04:05' 和 RFC3339 格式, 不带时区时使用本地时区
+func parseArchiveTime(value string) (uint32, error) {
+	if ts, err := strconv.ParseUint(value, 10, 32); err == nil {
+		return uint32(ts), nil
+	}
+	for _, layout := range []string{timeFormat, localISOTimeFormat} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return uint32(t.Unix()), nil
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return uint32(t.Unix()), nil
+	}
+	return 0, fmt.Errorf("invalid time '%s', should be unix timestamp, '%s', '%s' or RFC3339", value, timeFormat, localISOTimeFormat)
+}
+
+func formatUnix(ts uint32) string {
+	return time.Unix(int64(ts), 0).Format(timeFormat)
+}
+
+func (a *Archiver) listCommand(args []string) string {
+	database, table := "", ""
+	if len(args) > 0 {
+		database = args[0]
+	}
+	if len(args) > 1 {
+		table = args[1]
+	}
+	conns, err := common.NewCKConnections(a.addrs, a.username, a.password)
+	if err != nil {
+		return err.Error()
+	}
+	defer conns.Close()
+	manifests, err := a.ListManifests(conns, database, table)
+	if err != nil {
+		return err.Error()
+	}
+	sb := &bytes.Buffer{}
+	fmt.Fprintf(sb, "%-12s %-24s %-19s %-19s %-12s %-10s %-16s %s\n", "DATABASE", "TABLE", "MIN_TIME", "MAX_TIME", "ROWS", "VERSION", "NODE", "FILE")
+	for _, m := range manifests {
+		fmt.Fprintf(sb, "%-12s %-24s %-19s %-19s %-12d %-10s %-16s %s\n", m.Database, m.Table, formatUnix(m.MinTime), formatUnix(m.MaxTime),
+			m.Rows, m.SchemaVersion, m.Node, m.DataFile)
+	}
+	return sb.String()
+}
+
+func (a *Archiver) restoreCommand(args []string) string {
+	if len(args) < 4 {
+		return "usage: restore <database> <table> <start time> <end time> [name]"
+	}
+	start, err := parseArchiveTime(args[2])
+	if err != nil {
+		return err.Error()
+	}
+	end, err := parseArchiveTime(args[3])
+	if err != nil {
+		return err.Error()
+	}
+	name := ""
+	if len(args) > 4 {
+		name = args[4]
+	}
+	task, err := a.Restore(args[0], args[1], start, end, name)
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("restoring %d archives into %s.`%s`, run 'status' to check progress.\nquery it with querier parameter 'data_precision=%s'",
+		task.Total, task.Database, restoreTableName(task.Table, task.Name), task.Name)
+}
+
+func (a *Archiver) statusCommand() string {
+	a.restoreLock.Lock()
+	defer a.restoreLock.Unlock()
+	names := make([]string, 0, len(a.restoreTasks))
+	for name := range a.restoreTasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sb := &bytes.Buffer{}
+	fmt.Fprintf(sb, "%-24s %-36s %-19s %-19s %-10s %-12s %-9s %s\n", "NAME", "TABLE", "START", "END", "PROGRESS", "ROWS", "STATUS", "ERROR")
+	for _, name := range names {
+		t := a.restoreTasks[name]
+		status := "running"
+		if t.Finished {
+			status = "finished"
+		}
+		errString := ""
+		if t.Err != nil {
+			errString = t.Err.Error()
+		}
+		fmt.Fprintf(sb, "%-24s %-36s %-19s %-19s %-10s %-12d %-9s %s\n", t.Name, t.Database+"."+restoreTableName(t.Table, t.Name),
+			formatUnix(t.Start), formatUnix(t.End), fmt.Sprintf("%d/%d", t.Done, t.Total), t.Rows, status, errString)
+	}
+	return sb.String()
+}
+
+func (a *Archiver) dropCommand(args []string) string {
+	if len(args) < 3 {
+		return "usage: drop <database> <table> <name>"
+	}
+	if err := a.DropRestore(args[0], args[1], args[2]); err != nil {
+		return err.Error()
+	}
+	return "success"
+}
+
+func (a *Archiver) HandleSimpleCommand(operate uint16, arg string) string {
+	args := strings.Fields(arg)
+	switch operate {
+	case ARCHIVE_CMD_LIST:
+		return a.listCommand(args)
+	case ARCHIVE_CMD_RESTORE:
+		return a.restoreCommand(args)
+	case ARCHIVE_CMD_STATUS:
+		return a.statusCommand()
+	case ARCHIVE_CMD_DROP:
+		return a.dropCommand(args)
+	}
+	return fmt.Sprintf("unknown operate %d", operate)
+}
+
+func RegisterArchiveCommand() *cobra.Command {
+	archive := &cobra.Command{
+		Use:   "archive",
+		Short: "list, restore archived clickhouse partitions",
+	}
+	operates := []struct {
+		operate int
+		use     string
+		short   string
+		example string
+	}{
+		{ARCHIVE_CMD_LIST, "list [database] [table]", "list archived partitions", "archive list flow_log l7_flow_log"},
+		{ARCHIVE_CMD_RESTORE, "restore <database> <table> <start time> <end time> [name]", "restore archived partitions in the time range into table '<table>.<name>'",
+			"archive restore flow_log l7_flow_log '2023-07-01 00:00:00' '2023-07-02 00:00:00' audit_0701"},
+		{ARCHIVE_CMD_STATUS, "status", "show restore tasks", "archive status"},
+		{ARCHIVE_CMD_DROP, "drop <database> <table> <name>", "drop restored table '<table>.<name>'", "archive drop flow_log l7_flow_log audit_0701"},
+	}
+	for _, o := range operates {
+		operate := o.operate
+		archive.AddCommand(&cobra.Command{
+			Use:     o.use,
+			Short:   o.short,
+			Example: o.example,
+			Run: func(cmd *cobra.Command, args []string) {
+				// 时间参数可能包含空格, 转换为 'T' 分隔以便服务端按空格切分参数
+				for i := range args {
+					args[i] = strings.ReplaceAll(strings.TrimSpace(args[i]), " ", "T")
+				}
+				result, err := debug.CommmandGetResult(ingesterctl.CMD_CK_ARCHIVE, operate, strings.Join(args, " "))
+				if err != nil {
+					fmt.Println("Get result failed", err)
+					return
+				}
+				fmt.Println(result)
+			},
+		})
+	}
+	return archive
+}
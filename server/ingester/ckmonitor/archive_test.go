@@ -0,0 +1,49 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ckmonitor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRestoreColumns(t *testing.T) {
+	structure := "`time` DateTime, `ip4_0` IPv4, `removed` UInt8, `l7_protocol` UInt8"
+	columns := []string{"time", "ip4_0", "l7_protocol", "added"}
+	expect := []string{"`time`", "`ip4_0`", "`l7_protocol`"}
+	if got := restoreColumns(structure, columns); !reflect.DeepEqual(got, expect) {
+		t.Errorf("restoreColumns() = %v, want %v", got, expect)
+	}
+}
+
+func TestParseArchiveTime(t *testing.T) {
+	for _, value := range []string{"1688169600", "2023-07-01T00:00:00Z"} {
+		if ts, err := parseArchiveTime(value); err != nil || ts != 1688169600 {
+			t.Errorf("parseArchiveTime(%s) = %d, %v", value, ts, err)
+		}
+	}
+	local := uint32(time.Date(2023, 7, 1, 0, 0, 0, 0, time.Local).Unix())
+	for _, value := range []string{"2023-07-01 00:00:00", "2023-07-01T00:00:00"} {
+		if ts, err := parseArchiveTime(value); err != nil || ts != local {
+			t.Errorf("parseArchiveTime(%s) = %d, %v, want %d", value, ts, err, local)
+		}
+	}
+	if _, err := parseArchiveTime("yesterday"); err == nil {
+		t.Error("parseArchiveTime(yesterday) should fail")
+	}
+}
@@ -27,6 +27,8 @@ import (
 
 	"github.com/deepflowio/deepflow/server/ingester/common"
 	"github.com/deepflowio/deepflow/server/ingester/config"
+	"github.com/deepflowio/deepflow/server/ingester/ingesterctl"
+	"github.com/deepflowio/deepflow/server/libs/debug"
 )
 
 var log = logging.MustGetLogger("monitor")
@@ -38,6 +40,7 @@ type Monitor struct {
 	Conns              common.DBs
 	Addrs              []string
 	username, password string
+	archiver           *Archiver
 	exit               bool
 }
 
@@ -65,6 +68,10 @@ func NewCKMonitor(cfg *config.Config) (*Monitor, error) {
 	if err != nil {
 		return nil, err
 	}
+	if cfg.Archive.Enabled {
+		m.archiver = NewArchiver(cfg)
+		debug.ServerRegisterSimple(ingesterctl.CMD_CK_ARCHIVE, m.archiver)
+	}
 
 	return m, nil
 }
@@ -217,6 +224,10 @@ func (m *Monitor) dropMinPartitions(connect *sql.DB, diskInfo *DiskInfo) error {
 	}
 
 	for _, p := range partitions {
+		// 删除前先归档, 归档失败且配置不允许删除时跳过该分区
+		if m.archiver != nil && !m.archiver.archiveBeforeDrop(connect, p.database, p.table, p.partition) {
+			continue
+		}
 		sql := fmt.Sprintf("ALTER TABLE %s.`%s` DROP PARTITION '%s'", p.database, p.table, p.partition)
 		log.Warningf("drop partition: %s, database: %s, table: %s, minTime: %s, maxTime: %s, rows: %d, bytesOnDisk: %d", p.partition, p.database, p.table, p.minTime, p.maxTime, p.rows, p.bytesOnDisk)
 		_, err := connect.Exec(sql)
@@ -244,6 +255,9 @@ func (m *Monitor) moveMinPartitions(connect *sql.DB, diskInfo *DiskInfo) error {
 }
 
 func (m *Monitor) Start() {
+	if m.archiver != nil {
+		m.archiver.Start()
+	}
 	go m.start()
 }
 
@@ -281,5 +295,8 @@ func (m *Monitor) start() {
 
 func (m *Monitor) Close() error {
 	m.exit = true
+	if m.archiver != nil {
+		m.archiver.Close()
+	}
 	return nil
 }
@@ -54,6 +54,9 @@ const (
 	DefaultStatsInterval            = 10      // s
 	DefaultFlowTagCacheFlushTimeout = 1800    // s
 	DefaultFlowTagCacheMaxSize      = 1 << 18 // 256k
+	DefaultWasmPluginTimeout        = 10      // ms
+	DefaultArchivePath              = "deepflow-archive"
+	DefaultArchiveScheduleMinAge    = 24 // hour
 )
 
 type DatabaseTable struct {
@@ -91,6 +94,26 @@ type CKDBColdStorage struct {
 	Settings []StorageSetting `yaml:"settings,flow"`
 }
 
+type CKDBArchive struct {
+	Enabled           bool            `yaml:"enabled"`
+	StorageType       string          `yaml:"storage-type"` // 'file' or 's3'
+	Path              string          `yaml:"path"`
+	FileSeparateDisk  bool            `yaml:"file-separate-disk"` // 'file' 类型的路径是否在ClickHouse数据盘之外的磁盘上
+	S3AccessKeyID     string          `yaml:"s3-access-key-id"`
+	S3SecretAccessKey string          `yaml:"s3-secret-access-key"`
+	Tables            []DatabaseTable `yaml:"tables"`
+	BeforeDrop        bool            `yaml:"before-drop"`
+	DropOnFailure     bool            `yaml:"drop-on-failure"`
+	ScheduleInterval  int             `yaml:"schedule-interval"` // hour, 0 means disabled
+	ScheduleMinAge    int             `yaml:"schedule-min-age"`  // hour
+}
+
+type TTLRuleSetting struct {
+	Db     string         `yaml:"db"`
+	Tables []string       `yaml:"tables,flow"`
+	Rules  []ckdb.TTLRule `yaml:"rules"`
+}
+
 type HostPort struct {
 	Host string `yaml:"host"`
 	Port string `yaml:"port"`
@@ -120,6 +143,12 @@ type CKDB struct {
 	TimeZone            string `yaml:"time-zone"`
 }
 
+type WasmPlugin struct {
+	Name      string `yaml:"name"`
+	Timeout   int    `yaml:"timeout"`    // ms, 单次调用的超时时间
+	CPUBudget int    `yaml:"cpu-budget"` // ms, 每秒内插件可执行的总时长, 0表示不限制
+}
+
 type Config struct {
 	ListenPort               uint16          `yaml:"listen-port"`
 	CKDB                     CKDB            `yaml:"ckdb"`
@@ -135,12 +164,16 @@ type Config struct {
 	CKDiskMonitor            CKDiskMonitor   `yaml:"ck-disk-monitor"`
 	ColdStorage              CKDBColdStorage `yaml:"ckdb-cold-storage"`
 	ckdbColdStorages         map[string]*ckdb.ColdStorage
-	NodeIP                   string `yaml:"node-ip"`
-	GrpcBufferSize           int    `yaml:"grpc-buffer-size"`
-	ServiceLabelerLruCap     int    `yaml:"service-labeler-lru-cap"`
-	StatsInterval            int    `yaml:"stats-interval"`
-	FlowTagCacheFlushTimeout uint32 `yaml:"flow-tag-cache-flush-timeout"`
-	FlowTagCacheMaxSize      uint32 `yaml:"flow-tag-cache-max-size"`
+	TTLRules                 []TTLRuleSetting `yaml:"ckdb-ttl-rules"`
+	Archive                  CKDBArchive      `yaml:"ckdb-archive"`
+	ckdbTTLRules             map[string][]ckdb.TTLRule
+	NodeIP                   string       `yaml:"node-ip"`
+	GrpcBufferSize           int          `yaml:"grpc-buffer-size"`
+	ServiceLabelerLruCap     int          `yaml:"service-labeler-lru-cap"`
+	StatsInterval            int          `yaml:"stats-interval"`
+	FlowTagCacheFlushTimeout uint32       `yaml:"flow-tag-cache-flush-timeout"`
+	FlowTagCacheMaxSize      uint32       `yaml:"flow-tag-cache-max-size"`
+	WasmPlugins              []WasmPlugin `yaml:"wasm-plugins"`
 	LogFile                  string
 	LogLevel                 string
 	MyNodeName               string
@@ -174,6 +207,14 @@ func (c *Config) Validate() error {
 	if c.FlowTagCacheFlushTimeout == 0 {
 		c.FlowTagCacheFlushTimeout = DefaultFlowTagCacheFlushTimeout
 	}
+	for i := range c.WasmPlugins {
+		if c.WasmPlugins[i].Name == "" {
+			return errors.New("wasm-plugins name is empty")
+		}
+		if c.WasmPlugins[i].Timeout <= 0 {
+			c.WasmPlugins[i].Timeout = DefaultWasmPluginTimeout
+		}
+	}
 
 	// should get node ip from ENV
 	if c.NodeIP == "" && c.ControllerIPs[0] == DefaultContrallerIP {
@@ -297,9 +338,67 @@ func (c *Config) Validate() error {
 		c.StatsInterval = DefaultStatsInterval
 	}
 
+	if err := c.ValidateAndSetckdbTTLRules(); err != nil {
+		return err
+	}
+	if err := c.ValidateArchive(); err != nil {
+		return err
+	}
 	return c.ValidateAndSetckdbColdStorages()
 }
 
+func (c *Config) ValidateArchive() error {
+	if !c.Archive.Enabled {
+		return nil
+	}
+	if c.Archive.StorageType != "file" && c.Archive.StorageType != "s3" {
+		return fmt.Errorf("'ingester.ckdb-archive.storage-type' is '%s', should be 'file' or 's3'", c.Archive.StorageType)
+	}
+	if c.Archive.Path == "" {
+		return errors.New("'ingester.ckdb-archive.path' is empty")
+	}
+	// 磁盘满时归档到同一磁盘上无法释放空间, 反而可能写满磁盘
+	if c.Archive.BeforeDrop && c.Archive.StorageType == "file" && !c.Archive.FileSeparateDisk {
+		return errors.New("'ingester.ckdb-archive.before-drop' requires 's3' storage-type, or 'file' storage-type with 'file-separate-disk' set to true when 'user_files_path' of ClickHouse is on a different disk from data")
+	}
+	if c.Archive.ScheduleInterval < 0 {
+		c.Archive.ScheduleInterval = 0
+	}
+	if c.Archive.ScheduleMinAge <= 0 {
+		c.Archive.ScheduleMinAge = DefaultArchiveScheduleMinAge
+	}
+	return nil
+}
+
+func (c *Config) ValidateAndSetckdbTTLRules() error {
+	c.ckdbTTLRules = make(map[string][]ckdb.TTLRule)
+	for i, setting := range c.TTLRules {
+		if setting.Db == "" {
+			return fmt.Errorf("'ingester.ckdb-ttl-rules[%d].db' is empty", i)
+		}
+		if setting.Db == ckdb.METRICS_DB {
+			return fmt.Errorf("'ingester.ckdb-ttl-rules[%d].db' is '%s', which is not supported", i, setting.Db)
+		}
+		for j := range setting.Rules {
+			if err := setting.Rules[j].Validate(); err != nil {
+				return fmt.Errorf("'ingester.ckdb-ttl-rules[%d].rules[%d]' is invalid: %s", i, j, err)
+			}
+		}
+		// If 'tables' is not configured, then the rules apply to all tables under db
+		if len(setting.Tables) == 0 {
+			c.ckdbTTLRules[setting.Db] = setting.Rules
+		}
+		for _, table := range setting.Tables {
+			c.ckdbTTLRules[setting.Db+table] = setting.Rules
+		}
+	}
+	return nil
+}
+
+func (c *Config) GetCKDBTTLRules() map[string][]ckdb.TTLRule {
+	return c.ckdbTTLRules
+}
+
 func (c *Config) ValidateAndSetckdbColdStorages() error {
 	c.ckdbColdStorages = make(map[string]*ckdb.ColdStorage)
 	if !c.ColdStorage.Enabled {
@@ -382,6 +481,14 @@ func Load(path string) *Config {
 			StatsInterval:            DefaultStatsInterval,
 			FlowTagCacheFlushTimeout: DefaultFlowTagCacheFlushTimeout,
 			FlowTagCacheMaxSize:      DefaultFlowTagCacheMaxSize,
+			Archive: CKDBArchive{
+				StorageType:    "file",
+				Path:           DefaultArchivePath,
+				Tables:         []DatabaseTable{{"flow_log", ""}},
+				BeforeDrop:     true,
+				DropOnFailure:  true,
+				ScheduleMinAge: DefaultArchiveScheduleMinAge,
+			},
 		},
 	}
 	if err != nil {
@@ -23,6 +23,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/deepflowio/deepflow/server/ingester/config"
@@ -44,8 +45,12 @@ type DatasourceManager struct {
 	readTimeout      int
 	replicaEnabled   bool
 	ckdbColdStorages map[string]*ckdb.ColdStorage
+	ckdbTTLRules     map[string][]ckdb.TTLRule
 	isModifyingFlags []bool
 
+	ttlRulesLock      sync.Mutex
+	ttlRulesModifying map[string]bool
+
 	ckdbCluster       string
 	ckdbStoragePolicy string
 
@@ -61,7 +66,9 @@ func NewDatasourceManager(cfg *config.Config, readTimeout int) *DatasourceManage
 		ckdbCluster:       cfg.CKDB.ClusterName,
 		ckdbStoragePolicy: cfg.CKDB.StoragePolicy,
 		ckdbColdStorages:  cfg.GetCKDBColdStorages(),
+		ckdbTTLRules:      cfg.GetCKDBTTLRules(),
 		isModifyingFlags:  make([]bool, 16),
+		ttlRulesModifying: make(map[string]bool),
 		server: &http.Server{
 			Addr:    ":" + strconv.Itoa(DATASOURCE_PORT),
 			Handler: mux.NewRouter(),
@@ -200,6 +207,8 @@ func (m *DatasourceManager) RegisterHandlers() {
 	router.HandleFunc("/v1/rpadd/", m.rpAdd).Methods("POST")
 	router.HandleFunc("/v1/rpmod/", m.rpMod).Methods("PATCH")
 	router.HandleFunc("/v1/rpdel/", m.rpDel).Methods("DELETE")
+	router.HandleFunc("/v1/ttlrules/", m.ttlRulesGet).Methods("GET")
+	router.HandleFunc("/v1/ttlrules/", m.ttlRulesMod).Methods("POST")
 }
 
 func (m *DatasourceManager) Start() {
@@ -233,13 +233,9 @@ func stringSliceHas(items []string, item string) bool {
 }
 
 func (m *DatasourceManager) makeTTLString(timeKey, db, table string, duration int) string {
-	coldStorage := ckdb.GetColdStorage(m.ckdbColdStorages, db, table)
-	if coldStorage.Enabled {
-		return fmt.Sprintf("%s + toIntervalHour(%d), %s +  toIntervalHour(%d) TO %s '%s'",
-			timeKey, duration,
-			timeKey, coldStorage.TTLToMove, coldStorage.Type, coldStorage.Name)
-	}
-	return fmt.Sprintf("%s + toIntervalHour(%d)", timeKey, duration)
+	// 无规则时不会失败
+	ttl, _ := ckdb.MakeTTLString(timeKey, duration, nil, nil, ckdb.GetColdStorage(m.ckdbColdStorages, db, table))
+	return ttl
 }
 
 func (m *DatasourceManager) makeAggTableCreateSQL(t *ckdb.Table, dstTable, aggrSummable, aggrUnsummable string, partitionTime ckdb.TimeFuncType, duration int) string {
@@ -429,7 +425,7 @@ func (m *DatasourceManager) modFlowLogLocalTable(cks basecommon.DBs, tableID com
 	timeKey := tableID.TimeKey()
 	tableLocal := fmt.Sprintf("%s.%s_%s", common.FLOW_LOG_DB, tableID.String(), LOCAL)
 	modTable := fmt.Sprintf("ALTER TABLE %s MODIFY TTL %s",
-		tableLocal, m.makeTTLString(timeKey, common.FLOW_LOG_DB, tableID.String(), duration))
+		tableLocal, m.makeTableTTLString(cks[0], timeKey, common.FLOW_LOG_DB, tableID.String(), duration))
 	_, err := cks.ExecParallel(modTable)
 	return err
 }
@@ -467,7 +463,7 @@ func (m *DatasourceManager) modDeepflowSystemTables(cks basecommon.DBs, duration
 		for _, tableName := range tableNames {
 			tableLocal := fmt.Sprintf("%s.`%s`", DEEPFLOW_SYSTEM, tableName)
 			modTable := fmt.Sprintf("ALTER TABLE %s MODIFY TTL %s",
-				tableLocal, m.makeTTLString("time", DEEPFLOW_SYSTEM, tableName, duration))
+				tableLocal, m.makeTableTTLString(ck, "time", DEEPFLOW_SYSTEM, strings.TrimSuffix(tableName, ckdb.LOCAL_SUBFFIX), duration))
 			log.Infof("modify deepflow_system table TTL: %s", modTable)
 			_, err := ck.Exec(modTable)
 			if err != nil {
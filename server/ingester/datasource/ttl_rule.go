@@ -0,0 +1,355 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package datasource
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	basecommon "github.com/deepflowio/deepflow/server/ingester/common"
+	"github.com/deepflowio/deepflow/server/libs/ckdb"
+)
+
+const (
+	// 通过接口设置的规则保存在clickhouse中, 修改保留时长时需要保留这些规则
+	TTL_RULES_DB    = "flow_tag"
+	TTL_RULES_TABLE = "ttl_rules"
+
+	ERR_TTL_RULES_MODIFYING = "Modifying the ttl rules of %s.%s, please try again later"
+)
+
+var (
+	tableNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_.]+$`)
+	// 默认TTL总是在第一个, 见 ckdb.MakeTTLString
+	tableTTLRegexp = regexp.MustCompile(`TTL (\w+) \+ toIntervalHour\((\d+)\)`)
+)
+
+type TTLRulesBody struct {
+	DB    string         `json:"db"`
+	Name  string         `json:"name"`
+	Rules []ckdb.TTLRule `json:"rules"`
+}
+
+type TTLRulesResp struct {
+	OptStatus   string         `json:"OPT_STATUS"`
+	Description string         `json:"DESCRIPTION,omitempty"`
+	Data        []TTLRulesBody `json:"DATA"`
+}
+
+func escapeString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return strings.ReplaceAll(s, `'`, `\'`)
+}
+
+func createTTLRulesTable(cks basecommon.DBs) error {
+	if _, err := cks.Exec(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s", TTL_RULES_DB)); err != nil {
+		return err
+	}
+	_, err := cks.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s.%s
+(db String, table_name String, rules String, updated_at DateTime)
+ENGINE = ReplacingMergeTree(updated_at)
+ORDER BY (db, table_name)`, TTL_RULES_DB, TTL_RULES_TABLE))
+	return err
+}
+
+// 查询通过接口设置的规则, 第二个返回值表示是否设置过
+func queryTTLRules(ck *sql.DB, db, table string) ([]ckdb.TTLRule, bool, error) {
+	rows, err := ck.Query(fmt.Sprintf("SELECT rules FROM %s.%s FINAL WHERE db='%s' AND table_name='%s'",
+		TTL_RULES_DB, TTL_RULES_TABLE, escapeString(db), escapeString(table)))
+	if err != nil {
+		// 表不存在时表示未设置过
+		if strings.Contains(err.Error(), "doesn't exist") {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	defer rows.Close()
+	var rulesJson string
+	found := false
+	for rows.Next() {
+		if err := rows.Scan(&rulesJson); err != nil {
+			return nil, false, err
+		}
+		found = true
+	}
+	if !found {
+		return nil, false, nil
+	}
+	rules := []ckdb.TTLRule{}
+	if err := json.Unmarshal([]byte(rulesJson), &rules); err != nil {
+		return nil, false, err
+	}
+	return rules, true, nil
+}
+
+func queryAllTTLRules(ck *sql.DB) ([]TTLRulesBody, error) {
+	rows, err := ck.Query(fmt.Sprintf("SELECT db, table_name, rules FROM %s.%s FINAL ORDER BY db, table_name",
+		TTL_RULES_DB, TTL_RULES_TABLE))
+	if err != nil {
+		if strings.Contains(err.Error(), "doesn't exist") {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer rows.Close()
+	bodies := []TTLRulesBody{}
+	var db, table, rulesJson string
+	for rows.Next() {
+		if err := rows.Scan(&db, &table, &rulesJson); err != nil {
+			return nil, err
+		}
+		body := TTLRulesBody{DB: db, Name: table}
+		if err := json.Unmarshal([]byte(rulesJson), &body.Rules); err != nil {
+			return nil, err
+		}
+		bodies = append(bodies, body)
+	}
+	return bodies, nil
+}
+
+func saveTTLRules(cks basecommon.DBs, db, table string, rules []ckdb.TTLRule) error {
+	if err := createTTLRulesTable(cks); err != nil {
+		return err
+	}
+	if rules == nil {
+		rules = []ckdb.TTLRule{}
+	}
+	rulesJson, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+	_, err = cks.Exec(fmt.Sprintf("INSERT INTO %s.%s (db, table_name, rules, updated_at) VALUES ('%s', '%s', '%s', now())",
+		TTL_RULES_DB, TTL_RULES_TABLE, escapeString(db), escapeString(table), escapeString(string(rulesJson))))
+	return err
+}
+
+// 返回表当前的时间字段和默认保留时长
+func getTableTTL(ck *sql.DB, db, localTable string) (string, int, error) {
+	rows, err := ck.Query(fmt.Sprintf("SELECT engine_full FROM system.tables WHERE database='%s' AND name='%s'",
+		escapeString(db), escapeString(localTable)))
+	if err != nil {
+		return "", 0, err
+	}
+	defer rows.Close()
+	var engineFull string
+	found := false
+	for rows.Next() {
+		if err := rows.Scan(&engineFull); err != nil {
+			return "", 0, err
+		}
+		found = true
+	}
+	if !found {
+		return "", 0, fmt.Errorf("table %s.%s doesn't exist", db, localTable)
+	}
+	matches := tableTTLRegexp.FindStringSubmatch(engineFull)
+	if len(matches) != 3 {
+		return "", 0, fmt.Errorf("table %s.%s has no ttl", db, localTable)
+	}
+	ttl, _ := strconv.Atoi(matches[2])
+	return matches[1], ttl, nil
+}
+
+func getTableColumns(ck *sql.DB, db, localTable string) ([]string, error) {
+	rows, err := ck.Query(fmt.Sprintf("SELECT name FROM system.columns WHERE database='%s' AND table='%s'",
+		escapeString(db), escapeString(localTable)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns := []string{}
+	var column string
+	for rows.Next() {
+		if err := rows.Scan(&column); err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// IsTTLRulesSet 是否通过接口或ckissu设置过规则
+func IsTTLRulesSet(ck *sql.DB, db, table string) (bool, error) {
+	_, ok, err := queryTTLRules(ck, db, table)
+	return ok, err
+}
+
+// 优先使用接口设置的规则, 未设置时使用配置文件中的规则
+func (m *DatasourceManager) getTTLRules(ck *sql.DB, db, table string) ([]ckdb.TTLRule, error) {
+	rules, ok, err := queryTTLRules(ck, db, table)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return rules, nil
+	}
+	return ckdb.GetTTLRules(m.ckdbTTLRules, db, table), nil
+}
+
+func (m *DatasourceManager) makeTTLStringWithRules(ck *sql.DB, timeKey, db, table string, duration int, rules []ckdb.TTLRule) (string, error) {
+	columns := []string{}
+	if len(rules) > 0 {
+		var err error
+		if columns, err = getTableColumns(ck, db, table+ckdb.LOCAL_SUBFFIX); err != nil {
+			return "", err
+		}
+	}
+	return ckdb.MakeTTLString(timeKey, duration, rules, columns, ckdb.GetColdStorage(m.ckdbColdStorages, db, table))
+}
+
+// 修改保留时长时, 保留已设置的规则. 规则无法生效时(如列被删除)忽略规则
+func (m *DatasourceManager) makeTableTTLString(ck *sql.DB, timeKey, db, table string, duration int) string {
+	rules, err := m.getTTLRules(ck, db, table)
+	if err != nil {
+		log.Warningf("get ttl rules of %s.%s failed: %s", db, table, err)
+	}
+	ttl, err := m.makeTTLStringWithRules(ck, timeKey, db, table, duration, rules)
+	if err != nil {
+		log.Warningf("table %s.%s ignore ttl rules: %s", db, table, err)
+		return m.makeTTLString(timeKey, db, table, duration)
+	}
+	return ttl
+}
+
+func checkTTLRulesTable(db, table string) error {
+	if !tableNameRegexp.MatchString(db) || !tableNameRegexp.MatchString(table) {
+		return fmt.Errorf("invalid db(%s) or table(%s)", db, table)
+	}
+	// flow_metrics的表由数据源管理, 修改保留时长时会重建TTL
+	if db == ckdb.METRICS_DB {
+		return fmt.Errorf("ttl rules of db %s are not supported", db)
+	}
+	return nil
+}
+
+// ModTTLRules 修改表的TTL规则并保存, 表的默认保留时长不变. rules为空表示删除所有规则
+func (m *DatasourceManager) ModTTLRules(cks basecommon.DBs, db, table string, rules []ckdb.TTLRule) error {
+	if len(cks) == 0 {
+		return fmt.Errorf("ck connections is empty")
+	}
+	if err := checkTTLRulesTable(db, table); err != nil {
+		return err
+	}
+	localTable := table + ckdb.LOCAL_SUBFFIX
+	timeKey, duration, err := getTableTTL(cks[0], db, localTable)
+	if err != nil {
+		return err
+	}
+	ttl, err := m.makeTTLStringWithRules(cks[0], timeKey, db, table, duration, rules)
+	if err != nil {
+		return err
+	}
+	// 先修改表的TTL, 成功后再保存规则, 任一步失败都恢复为修改前的TTL, 避免保存的规则与表不一致
+	oldTTL := m.makeTableTTLString(cks[0], timeKey, db, table, duration)
+	if err := alterTableTTL(cks, db, localTable, ttl); err != nil {
+		rollbackTableTTL(cks, db, localTable, oldTTL)
+		return err
+	}
+	if err := saveTTLRules(cks, db, table, rules); err != nil {
+		rollbackTableTTL(cks, db, localTable, oldTTL)
+		return err
+	}
+	return nil
+}
+
+func alterTableTTL(cks basecommon.DBs, db, localTable, ttl string) error {
+	_, err := cks.ExecParallel(fmt.Sprintf("ALTER TABLE %s.`%s` MODIFY TTL %s", db, localTable, ttl))
+	return err
+}
+
+// 多个ck节点时可能部分节点已修改成功, 所有节点都恢复
+func rollbackTableTTL(cks basecommon.DBs, db, localTable, ttl string) {
+	if err := alterTableTTL(cks, db, localTable, ttl); err != nil {
+		log.Errorf("rollback ttl of %s.%s failed: %s", db, localTable, err)
+	}
+}
+
+func (m *DatasourceManager) ttlRulesGet(w http.ResponseWriter, r *http.Request) {
+	db, table := r.URL.Query().Get("db"), r.URL.Query().Get("name")
+	resp := TTLRulesResp{OptStatus: "SUCCESS"}
+	cks, err := basecommon.NewCKConnections(m.ckAddrs, m.user, m.password)
+	if err == nil && len(cks) == 0 {
+		err = fmt.Errorf("ck addrs is empty")
+	}
+	if err == nil {
+		defer cks.Close()
+		if db != "" && table != "" {
+			var rules []ckdb.TTLRule
+			if rules, err = m.getTTLRules(cks[0], db, table); err == nil {
+				resp.Data = []TTLRulesBody{{DB: db, Name: table, Rules: rules}}
+			}
+		} else {
+			resp.Data, err = queryAllTTLRules(cks[0])
+		}
+	}
+	if err != nil {
+		resp.OptStatus = "FAILED"
+		resp.Description = err.Error()
+		log.Warningf("get ttl rules failed: %s", err)
+	}
+	body, _ := json.Marshal(resp)
+	w.Write(body)
+}
+
+func (m *DatasourceManager) ttlRulesMod(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Errorf("read body err, %v", err)
+		respFailed(w, err.Error())
+		return
+	}
+	var b TTLRulesBody
+	if err = json.Unmarshal(body, &b); err != nil {
+		log.Errorf("Unmarshal err, %v", err)
+		respFailed(w, err.Error())
+		return
+	}
+	log.Infof("receive ttl rules request: %+v", b)
+
+	key := b.DB + "." + b.Name
+	m.ttlRulesLock.Lock()
+	if m.ttlRulesModifying[key] {
+		m.ttlRulesLock.Unlock()
+		respPending(w, fmt.Sprintf(ERR_TTL_RULES_MODIFYING, b.DB, b.Name))
+		return
+	}
+	m.ttlRulesModifying[key] = true
+	m.ttlRulesLock.Unlock()
+	defer func() {
+		m.ttlRulesLock.Lock()
+		delete(m.ttlRulesModifying, key)
+		m.ttlRulesLock.Unlock()
+	}()
+
+	cks, err := basecommon.NewCKConnections(m.ckAddrs, m.user, m.password)
+	if err != nil {
+		respFailed(w, err.Error())
+		return
+	}
+	defer cks.Close()
+	if err := m.ModTTLRules(cks, b.DB, b.Name, b.Rules); err != nil {
+		respFailed(w, err.Error())
+		return
+	}
+	respSuccess(w)
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package anomaly
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/deepflowio/deepflow/server/ingester/event/config"
+)
+
+// MAD换算为正态分布标准差的系数
+const MAD_SCALE = 1.4826
+
+// 一个时段的历史样本, 写满后覆盖最旧的样本
+type ring struct {
+	samples []float32
+	next    int
+	full    bool
+}
+
+func (r *ring) add(v float64, size int) {
+	if r.samples == nil {
+		r.samples = make([]float32, 0, size)
+	}
+	if !r.full {
+		r.samples = append(r.samples, float32(v))
+		r.full = len(r.samples) == size
+		return
+	}
+	r.samples[r.next] = float32(v)
+	r.next = (r.next + 1) % size
+}
+
+// Baseline 按季节性时段分别保存一个指标的历史样本, 以中位数和MAD作为基线.
+// 每个时段在一个季节周期内只有一个样本, 即该小时内各检测窗口的均值,
+// 否则一个小时的检测窗口就会占满历史样本, 基线只反映最近一两天的情况
+type Baseline struct {
+	buckets []ring
+
+	// 当前小时内已检测窗口的累计值, 进入下一个小时后作为pendingBucket的样本
+	pendingBucket int
+	pendingStart  uint32
+	pendingSum    float64
+	pendingCount  int
+}
+
+func NewBaseline(bucketCount int) *Baseline {
+	return &Baseline{buckets: make([]ring, bucketCount)}
+}
+
+type Result struct {
+	Baseline    float64
+	Deviation   float64
+	Score       float64
+	SampleCount int
+}
+
+func (b *Baseline) flush(historySize int) {
+	if b.pendingCount == 0 {
+		return
+	}
+	b.buckets[b.pendingBucket].add(b.pendingSum/float64(b.pendingCount), historySize)
+	b.pendingSum, b.pendingCount = 0, 0
+}
+
+// Observe 用时段内以前周期的样本评估start开始的检测窗口的值v, 再将v计入本周期的样本.
+// 样本数不足minSamples时ok为false
+func (b *Baseline) Observe(bucket int, start uint32, v, minDeviation float64, historySize, minSamples int, buffer []float64) (result Result, ok bool) {
+	if b.pendingCount > 0 && (bucket != b.pendingBucket || start >= b.pendingStart+3600) {
+		b.flush(historySize)
+	}
+	r := &b.buckets[bucket]
+	if len(r.samples) >= minSamples {
+		result = Evaluate(r.samples, v, minDeviation, buffer)
+		ok = true
+	}
+	if b.pendingCount == 0 {
+		b.pendingBucket, b.pendingStart = bucket, start
+	}
+	b.pendingSum += v
+	b.pendingCount++
+	return
+}
+
+// Learn 直接加入时段一个周期的样本, 用于启动时从历史数据学习基线
+func (b *Baseline) Learn(bucket int, v float64, historySize int) {
+	b.buckets[bucket].add(v, historySize)
+}
+
+// Evaluate 计算v相对samples的鲁棒z-score: (v - median) / (1.4826 * MAD).
+// 样本几乎不变时MAD为0, 此时以minDeviation作为偏差的下限, 避免微小波动被放大
+func Evaluate(samples []float32, v, minDeviation float64, buffer []float64) Result {
+	buffer = buffer[:0]
+	for _, s := range samples {
+		buffer = append(buffer, float64(s))
+	}
+	median := Median(buffer)
+	for i := range buffer {
+		buffer[i] = math.Abs(buffer[i] - median)
+	}
+	deviation := MAD_SCALE * Median(buffer)
+	if deviation < minDeviation {
+		deviation = minDeviation
+	}
+	result := Result{Baseline: median, Deviation: deviation, SampleCount: len(samples)}
+	if deviation > 0 {
+		result.Score = (v - median) / deviation
+	} else if v != median {
+		result.Score = math.Copysign(math.MaxFloat32, v-median)
+	}
+	return result
+}
+
+// Median 会修改values的顺序
+func Median(values []float64) float64 {
+	n := len(values)
+	if n == 0 {
+		return 0
+	}
+	sort.Float64s(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
+
+// SeasonalBucket 返回t所属的季节性时段
+func SeasonalBucket(t time.Time, seasonality string) int {
+	switch seasonality {
+	case config.SEASONALITY_DAY:
+		return t.Hour()
+	case config.SEASONALITY_WEEK:
+		return int(t.Weekday())*24 + t.Hour()
+	default:
+		return 0
+	}
+}
+
+func SeasonalBucketCount(seasonality string) int {
+	switch seasonality {
+	case config.SEASONALITY_DAY:
+		return 24
+	case config.SEASONALITY_WEEK:
+		return 7 * 24
+	default:
+		return 1
+	}
+}
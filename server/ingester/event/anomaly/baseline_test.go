@@ -0,0 +1,136 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package anomaly
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/deepflowio/deepflow/server/ingester/event/config"
+)
+
+func TestMedian(t *testing.T) {
+	if m := Median([]float64{5, 1, 3}); m != 3 {
+		t.Errorf("Median() = %v, want 3", m)
+	}
+	if m := Median([]float64{4, 1, 3, 2}); m != 2.5 {
+		t.Errorf("Median() = %v, want 2.5", m)
+	}
+	if m := Median(nil); m != 0 {
+		t.Errorf("Median() = %v, want 0", m)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	// 离群样本不影响中位数和MAD
+	samples := []float32{10, 11, 9, 10, 12, 8, 10, 1000}
+	r := Evaluate(samples, 20, 0, nil)
+	if r.Baseline != 10 {
+		t.Errorf("baseline = %v, want 10", r.Baseline)
+	}
+	if math.Abs(r.Deviation-MAD_SCALE) > 1e-9 {
+		t.Errorf("deviation = %v, want %v", r.Deviation, MAD_SCALE)
+	}
+	if math.Abs(r.Score-10/MAD_SCALE) > 1e-9 {
+		t.Errorf("score = %v, want %v", r.Score, 10/MAD_SCALE)
+	}
+
+	// 样本不变时使用偏差下限
+	r = Evaluate([]float32{0, 0, 0, 0}, 0.02, 0.01, nil)
+	if r.Deviation != 0.01 || math.Abs(r.Score-2) > 1e-9 {
+		t.Errorf("unexpected result %+v", r)
+	}
+	r = Evaluate([]float32{0, 0, 0, 0}, 0.02, 0, nil)
+	if r.Score != math.MaxFloat32 {
+		t.Errorf("score = %v, want %v", r.Score, math.MaxFloat32)
+	}
+}
+
+func TestBaselineObserve(t *testing.T) {
+	b := NewBaseline(2)
+	// 每个周期的同一时段只产生一个样本, 为该小时内各窗口的均值
+	for day := uint32(0); day < 5; day++ {
+		start := day * 2 * 3600
+		for minute := uint32(0); minute < 60; minute++ {
+			if _, ok := b.Observe(0, start+minute*60, 90+float64(minute%2)*20, 1, 5, 5, nil); ok {
+				t.Fatalf("day %d minute %d should be learned only", day, minute)
+			}
+		}
+		// 其他时段的样本不参与计算
+		if _, ok := b.Observe(1, start+3600, 1000, 1, 5, 5, nil); ok {
+			t.Fatal("bucket 1 should be learned only")
+		}
+	}
+	if n := len(b.buckets[0].samples); n != 5 || b.buckets[0].samples[0] != 100 {
+		t.Fatalf("unexpected samples %v", b.buckets[0].samples)
+	}
+	r, ok := b.Observe(0, 5*2*3600, 110, 1, 5, 5, nil)
+	if !ok || r.Baseline != 100 || r.Score != 10 || r.SampleCount != 5 {
+		t.Errorf("unexpected result %+v, %v", r, ok)
+	}
+
+	// 同一时段间隔一个周期再次出现时也作为新的样本
+	b = NewBaseline(1)
+	b.Observe(0, 0, 100, 1, 5, 5, nil)
+	b.Observe(0, 3600, 200, 1, 5, 5, nil)
+	if n := len(b.buckets[0].samples); n != 1 || b.buckets[0].samples[0] != 100 {
+		t.Errorf("unexpected samples %v", b.buckets[0].samples)
+	}
+
+	// 样本数达到上限后覆盖最旧的样本
+	b = NewBaseline(1)
+	for i := 0; i < 6; i++ {
+		b.Learn(0, float64(i), 5)
+	}
+	if n := len(b.buckets[0].samples); n != 5 || b.buckets[0].samples[0] != 5 {
+		t.Errorf("unexpected samples %v", b.buckets[0].samples)
+	}
+}
+
+func TestSeasonalBucket(t *testing.T) {
+	ts := time.Date(2023, 6, 6, 13, 30, 0, 0, time.Local) // Tuesday
+	if b := SeasonalBucket(ts, config.SEASONALITY_DAY); b != 13 {
+		t.Errorf("day bucket = %d, want 13", b)
+	}
+	if b := SeasonalBucket(ts, config.SEASONALITY_WEEK); b != 2*24+13 {
+		t.Errorf("week bucket = %d, want %d", b, 2*24+13)
+	}
+	if b := SeasonalBucket(ts, config.SEASONALITY_NONE); b != 0 {
+		t.Errorf("none bucket = %d, want 0", b)
+	}
+}
+
+func TestWarmupSQL(t *testing.T) {
+	sql := warmupSQL(source{"vtap_flow_port.1m", false, false}, 0, 3600, 10)
+	expected := "SELECT l3_epc_id, auto_service_type, auto_service_id, toUInt32(toStartOfHour(time)) AS hour, toFloat64(sum(packet)), toFloat64(sum(retrans)) FROM flow_metrics.`vtap_flow_port.1m` " +
+		"WHERE time>=0 AND time<3600 AND auto_service_type NOT IN (0,255) AND direction='s2c' AND protocol=6 " +
+		"GROUP BY l3_epc_id, auto_service_type, auto_service_id, hour ORDER BY hour, toFloat64(sum(packet)) DESC LIMIT 10 BY hour"
+	if sql != expected {
+		t.Errorf("warmupSQL() = %s\nwant %s", sql, expected)
+	}
+}
+
+func TestQuerySQL(t *testing.T) {
+	sql := querySQL(source{"vtap_flow_port.1m", false, false}, 60, 120, 10)
+	expected := "SELECT l3_epc_id, auto_service_type, auto_service_id, toFloat64(sum(packet)), toFloat64(sum(retrans)) FROM flow_metrics.`vtap_flow_port.1m` " +
+		"WHERE time>=60 AND time<120 AND auto_service_type NOT IN (0,255) AND direction='s2c' AND protocol=6 " +
+		"GROUP BY l3_epc_id, auto_service_type, auto_service_id ORDER BY toFloat64(sum(packet)) DESC LIMIT 10"
+	if sql != expected {
+		t.Errorf("querySQL() = %s\nwant %s", sql, expected)
+	}
+}
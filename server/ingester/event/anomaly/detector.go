@@ -0,0 +1,479 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package anomaly
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	logging "github.com/op/go-logging"
+
+	"github.com/deepflowio/deepflow/server/ingester/common"
+	"github.com/deepflowio/deepflow/server/ingester/event/config"
+	"github.com/deepflowio/deepflow/server/ingester/event/dbwriter"
+	"github.com/deepflowio/deepflow/server/ingester/pkg/ckwriter"
+	"github.com/deepflowio/deepflow/server/libs/utils"
+)
+
+var log = logging.MustGetLogger("event.anomaly")
+
+const (
+	FLOW_METRICS_DB = "flow_metrics"
+	CHECK_INTERVAL  = 10 * time.Second
+)
+
+type Metric uint8
+
+const (
+	REQUEST_RATE  Metric = iota // 每秒请求数
+	ERROR_RATIO                 // 异常数/响应数
+	RRT                         // 平均响应时延, 单位: 微秒
+	RETRANS_RATIO               // 重传包数/总包数
+	METRIC_MAX
+)
+
+var metricNames = [METRIC_MAX]string{
+	REQUEST_RATE:  "request_rate",
+	ERROR_RATIO:   "error_ratio",
+	RRT:           "rrt",
+	RETRANS_RATIO: "retrans_ratio",
+}
+
+// 偏差的下限, 避免历史样本几乎不变时微小的波动被判定为异常
+var metricMinDeviations = [METRIC_MAX]float64{
+	REQUEST_RATE:  1,
+	ERROR_RATIO:   0.01,
+	RRT:           1000,
+	RETRANS_RATIO: 0.001,
+}
+
+func (m Metric) String() string {
+	if m < METRIC_MAX {
+		return metricNames[m]
+	}
+	return "unknown"
+}
+
+// 除请求速率外, 指标低于基线不认为是异常
+func (m Metric) isAnomaly(score, threshold float64) bool {
+	if m == REQUEST_RATE {
+		return score >= threshold || score <= -threshold
+	}
+	return score >= threshold
+}
+
+// Key 服务只有1侧的tag, 服务路径包含0侧(客户端)和1侧(服务端)的tag
+type Key struct {
+	IsEdge           bool
+	L3EpcID0         int32
+	AutoServiceType0 uint8
+	AutoServiceID0   uint32
+	L3EpcID1         int32
+	AutoServiceType1 uint8
+	AutoServiceID1   uint32
+	ServerPort       uint16
+}
+
+type keyBaselines struct {
+	baselines [METRIC_MAX]*Baseline
+	lastSeen  uint32
+}
+
+type source struct {
+	table  string
+	isEdge bool
+	isApp  bool
+}
+
+var sources = []source{
+	{"vtap_app_port.1m", false, true},
+	{"vtap_app_edge_port.1m", true, true},
+	{"vtap_flow_port.1m", false, false},
+	{"vtap_flow_edge_port.1m", true, false},
+}
+
+type Counter struct {
+	KeyCount     int64 `statsd:"key-count"`
+	SampleCount  int64 `statsd:"sample-count"`
+	AnomalyCount int64 `statsd:"anomaly-count"`
+	QueryFailed  int64 `statsd:"query-failed"`
+}
+
+// Detector 周期性地从flow_metrics的分钟表读取服务/服务路径的RED及TCP重传指标,
+// 学习各季节性时段的基线, 指标偏离基线时写入event.anomaly_event
+type Detector struct {
+	config      *config.AnomalyConfig
+	isMaster    func() bool
+	addrs       []string
+	username    string
+	password    string
+	conn        *sql.DB
+	writer      *ckwriter.CKWriter
+	bucketCount int
+
+	keys     map[Key]*keyBaselines
+	keyCount int64
+	lastEnd  uint32
+	warmedUp bool
+	buffer   []float64
+
+	counter *Counter
+	utils.Closable
+}
+
+// isMaster为nil时总是检测, 否则只在master deepflow-server上检测, 避免多个ingester重复写入异常事件
+func NewDetector(cfg *config.Config, isMaster func() bool) (*Detector, error) {
+	writer, err := dbwriter.NewAnomalyEventWriter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	d := &Detector{
+		config:      &cfg.Anomaly,
+		isMaster:    isMaster,
+		addrs:       cfg.Base.CKDB.ActualAddrs,
+		username:    cfg.Base.CKDBAuth.Username,
+		password:    cfg.Base.CKDBAuth.Password,
+		writer:      writer,
+		bucketCount: SeasonalBucketCount(cfg.Anomaly.Seasonality),
+		keys:        make(map[Key]*keyBaselines),
+		buffer:      make([]float64, 0, cfg.Anomaly.HistorySize),
+		counter:     &Counter{},
+	}
+	common.RegisterCountableForIngester("anomaly_detector", d)
+	return d, nil
+}
+
+func (d *Detector) GetCounter() interface{} {
+	counter := &Counter{}
+	counter, d.counter = d.counter, counter
+	counter.KeyCount = atomic.LoadInt64(&d.keyCount)
+	return counter
+}
+
+func (d *Detector) Start() {
+	go d.run()
+}
+
+func (d *Detector) Close() error {
+	d.Closable.Close()
+	d.writer.Close()
+	return nil
+}
+
+func (d *Detector) run() {
+	ticker := time.NewTicker(CHECK_INTERVAL)
+	defer ticker.Stop()
+	for !d.Closed() {
+		<-ticker.C
+		now := uint32(time.Now().Unix())
+		end := (now - uint32(d.config.Delay)) / 60 * 60
+		if end < d.lastEnd+uint32(d.config.Interval) {
+			continue
+		}
+		if d.isMaster != nil && !d.isMaster() {
+			// 成为master前的基线已过期, 再次成为master时重新从clickhouse学习
+			if d.warmedUp {
+				log.Info("not master deepflow-server, stop anomaly detection")
+				d.reset()
+			}
+			d.lastEnd = end
+			continue
+		}
+		if err := d.updateConnection(); err != nil {
+			log.Warning(err)
+			continue
+		}
+		if !d.warmedUp {
+			d.warmup(end - uint32(d.config.Interval))
+			d.evict(end)
+			d.warmedUp = true
+		}
+		d.detect(end-uint32(d.config.Interval), end)
+		d.lastEnd = end
+		d.evict(end)
+	}
+}
+
+func (d *Detector) reset() {
+	d.keys = make(map[Key]*keyBaselines)
+	atomic.StoreInt64(&d.keyCount, 0)
+	d.warmedUp = false
+}
+
+// 如果clickhouse重启等，需要自动更新连接
+func (d *Detector) updateConnection() error {
+	if d.conn != nil {
+		if d.conn.Ping() == nil {
+			return nil
+		}
+		d.conn.Close()
+		d.conn = nil
+	}
+	var err error
+	for _, addr := range d.addrs {
+		if d.conn, err = common.NewCKConnection(addr, d.username, d.password); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("connect to clickhouse %v failed: %s", d.addrs, err)
+}
+
+func (d *Detector) detect(start, end uint32) {
+	bucket := SeasonalBucket(time.Unix(int64(start), 0), d.config.Seasonality)
+	for _, s := range sources {
+		if err := d.detectSource(s, bucket, start, end); err != nil {
+			atomic.AddInt64(&d.counter.QueryFailed, 1)
+			log.Warningf("detect anomaly of %s.%s failed: %s", FLOW_METRICS_DB, s.table, err)
+		}
+	}
+	atomic.StoreInt64(&d.keyCount, int64(len(d.keys)))
+}
+
+func keyColumns(isEdge bool) []string {
+	if isEdge {
+		return []string{"l3_epc_id_0", "auto_service_type_0", "auto_service_id_0", "l3_epc_id_1", "auto_service_type_1", "auto_service_id_1", "server_port"}
+	}
+	return []string{"l3_epc_id", "auto_service_type", "auto_service_id"}
+}
+
+// 返回source的key列、过滤条件及指标列
+func sourceColumns(s source, start, end uint32) (keys, conditions, values []string) {
+	keys = keyColumns(s.isEdge)
+	conditions = []string{fmt.Sprintf("time>=%d", start), fmt.Sprintf("time<%d", end)}
+	// 服务端为IP地址或Internet时不计算基线
+	if s.isEdge {
+		conditions = append(conditions, "auto_service_type_1 NOT IN (0,255)")
+	} else {
+		conditions = append(conditions, "auto_service_type NOT IN (0,255)", "direction='s2c'")
+	}
+	if s.isApp {
+		values = []string{"toFloat64(sum(request))", "toFloat64(sum(response))", "toFloat64(sum(error))", "toFloat64(sum(rrt_sum))", "toFloat64(sum(rrt_count))"}
+	} else {
+		conditions = append(conditions, "protocol=6")
+		values = []string{"toFloat64(sum(packet))", "toFloat64(sum(retrans))"}
+	}
+	return
+}
+
+func querySQL(s source, start, end uint32, limit int) string {
+	keys, conditions, values := sourceColumns(s, start, end)
+	return fmt.Sprintf("SELECT %s, %s FROM %s.`%s` WHERE %s GROUP BY %s ORDER BY %s DESC LIMIT %d",
+		strings.Join(keys, ", "), strings.Join(values, ", "), FLOW_METRICS_DB, s.table,
+		strings.Join(conditions, " AND "), strings.Join(keys, ", "), values[0], limit)
+}
+
+// 按小时聚合历史数据, 每个小时保留limit个最繁忙的服务/服务路径
+func warmupSQL(s source, start, end uint32, limit int) string {
+	keys, conditions, values := sourceColumns(s, start, end)
+	return fmt.Sprintf("SELECT %s, toUInt32(toStartOfHour(time)) AS hour, %s FROM %s.`%s` WHERE %s GROUP BY %s, hour ORDER BY hour, %s DESC LIMIT %d BY hour",
+		strings.Join(keys, ", "), strings.Join(values, ", "), FLOW_METRICS_DB, s.table,
+		strings.Join(conditions, " AND "), strings.Join(keys, ", "), values[0], limit)
+}
+
+// 返回扫描一行查询结果的目标, hour不为nil时在key列之后扫描小时
+func scanTargets(s source, key *Key, hour *uint32, values *[5]float64) []interface{} {
+	targets := []interface{}{&key.L3EpcID1, &key.AutoServiceType1, &key.AutoServiceID1}
+	if s.isEdge {
+		targets = []interface{}{&key.L3EpcID0, &key.AutoServiceType0, &key.AutoServiceID0, &key.L3EpcID1, &key.AutoServiceType1, &key.AutoServiceID1, &key.ServerPort}
+	}
+	if hour != nil {
+		targets = append(targets, hour)
+	}
+	valueCount := 2
+	if s.isApp {
+		valueCount = 5
+	}
+	for i := 0; i < valueCount; i++ {
+		targets = append(targets, &values[i])
+	}
+	return targets
+}
+
+// 根据seconds秒内的聚合结果计算各指标, 请求数/包数的下限按seconds内的检测窗口数放大
+func (d *Detector) calculate(s source, values *[5]float64, seconds uint32, f func(Metric, float64)) {
+	windows := float64(seconds / uint32(d.config.Interval))
+	if windows < 1 {
+		windows = 1
+	}
+	if s.isApp {
+		request, response, errorCount, rrtSum, rrtCount := values[0], values[1], values[2], values[3], values[4]
+		f(REQUEST_RATE, request/float64(seconds))
+		if response >= float64(d.config.MinRequests)*windows {
+			f(ERROR_RATIO, errorCount/response)
+			if rrtCount > 0 {
+				f(RRT, rrtSum/rrtCount)
+			}
+		}
+	} else {
+		packet, retrans := values[0], values[1]
+		if packet >= float64(d.config.MinPackets)*windows {
+			f(RETRANS_RATIO, retrans/packet)
+		}
+	}
+}
+
+// warmup 从clickhouse中读取end之前的历史数据学习基线, 避免重启或切换master后需要重新积累样本.
+// 每个时段每个周期一个样本, 为该小时的聚合值, end所在的小时由之后的检测窗口计入
+func (d *Detector) warmup(end uint32) {
+	period := uint32(d.bucketCount) * 3600
+	end = end / 3600 * 3600
+	start := uint32(0)
+	if history := period * uint32(d.config.HistorySize); end > history {
+		start = end - history
+	}
+	for _, s := range sources {
+		if err := d.warmupSource(s, start, end); err != nil {
+			atomic.AddInt64(&d.counter.QueryFailed, 1)
+			log.Warningf("warm up anomaly baselines from %s.%s failed: %s", FLOW_METRICS_DB, s.table, err)
+		}
+	}
+	atomic.StoreInt64(&d.keyCount, int64(len(d.keys)))
+	log.Infof("anomaly baselines of %d services and service paths warmed up from %d to %d", len(d.keys), start, end)
+}
+
+func (d *Detector) warmupSource(s source, start, end uint32) error {
+	rows, err := d.conn.Query(warmupSQL(s, start, end, d.config.MaxKeys))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var (
+		key    Key
+		hour   uint32
+		values [5]float64
+	)
+	key.IsEdge = s.isEdge
+	targets := scanTargets(s, &key, &hour, &values)
+	for rows.Next() {
+		if err := rows.Scan(targets...); err != nil {
+			return err
+		}
+		kb := d.getKeyBaselines(key)
+		kb.lastSeen = hour + 3600
+		bucket := SeasonalBucket(time.Unix(int64(hour), 0), d.config.Seasonality)
+		d.calculate(s, &values, 3600, func(metric Metric, value float64) {
+			if kb.baselines[metric] == nil {
+				kb.baselines[metric] = NewBaseline(d.bucketCount)
+			}
+			kb.baselines[metric].Learn(bucket, value, d.config.HistorySize)
+		})
+	}
+	return rows.Err()
+}
+
+func (d *Detector) detectSource(s source, bucket int, start, end uint32) error {
+	rows, err := d.conn.Query(querySQL(s, start, end, d.config.MaxKeys))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var (
+		key    Key
+		values [5]float64
+		seen   = make(map[Key]bool)
+	)
+	key.IsEdge = s.isEdge
+	targets := scanTargets(s, &key, nil, &values)
+	for rows.Next() {
+		if err := rows.Scan(targets...); err != nil {
+			return err
+		}
+		seen[key] = true
+		d.getKeyBaselines(key).lastSeen = end
+		d.calculate(s, &values, end-start, func(metric Metric, value float64) {
+			d.observe(key, metric, bucket, start, end, value)
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// 结果未被截断时, 已学习过请求速率但本周期无数据的服务/服务路径视为请求速率降为0
+	if s.isApp && len(seen) < d.config.MaxKeys {
+		for k, kb := range d.keys {
+			if k.IsEdge == s.isEdge && !seen[k] && kb.baselines[REQUEST_RATE] != nil {
+				d.observe(k, REQUEST_RATE, bucket, start, end, 0)
+			}
+		}
+	}
+	return nil
+}
+
+func (d *Detector) getKeyBaselines(key Key) *keyBaselines {
+	kb, ok := d.keys[key]
+	if !ok {
+		kb = &keyBaselines{}
+		d.keys[key] = kb
+	}
+	return kb
+}
+
+func (d *Detector) observe(key Key, metric Metric, bucket int, start, end uint32, value float64) {
+	kb := d.getKeyBaselines(key)
+	if kb.baselines[metric] == nil {
+		kb.baselines[metric] = NewBaseline(d.bucketCount)
+	}
+	atomic.AddInt64(&d.counter.SampleCount, 1)
+	result, ok := kb.baselines[metric].Observe(bucket, start, value, metricMinDeviations[metric], d.config.HistorySize, d.config.MinSamples, d.buffer)
+	if !ok || !metric.isAnomaly(result.Score, d.config.Threshold) {
+		return
+	}
+	atomic.AddInt64(&d.counter.AnomalyCount, 1)
+	d.writer.Put(newAnomalyEvent(key, metric, start, end, value, &result))
+}
+
+func newAnomalyEvent(key Key, metric Metric, start, end uint32, value float64, result *Result) *dbwriter.AnomalyEventStore {
+	e := dbwriter.AcquireAnomalyEventStore()
+	e.Time = end
+	e.StartTime = int64(start) * int64(time.Second/time.Microsecond)
+	e.EndTime = int64(end) * int64(time.Second/time.Microsecond)
+	e.Metric = metric.String()
+	e.IsEdge = key.IsEdge
+	e.EventDescription = fmt.Sprintf("%s %.4g deviates from baseline %.4g by %.2f times the deviation %.4g",
+		metric, value, result.Baseline, result.Score, result.Deviation)
+	e.Value = value
+	e.Baseline = result.Baseline
+	e.Deviation = result.Deviation
+	e.Score = result.Score
+	e.SampleCount = uint32(result.SampleCount)
+	e.L3EpcID0 = key.L3EpcID0
+	e.AutoServiceType0 = key.AutoServiceType0
+	e.AutoServiceID0 = key.AutoServiceID0
+	e.L3EpcID1 = key.L3EpcID1
+	e.AutoServiceType1 = key.AutoServiceType1
+	e.AutoServiceID1 = key.AutoServiceID1
+	e.ServerPort = key.ServerPort
+	return e
+}
+
+// 一个完整季节周期内都没有数据的服务/服务路径不再保留基线
+func (d *Detector) evict(now uint32) {
+	period := uint32(d.bucketCount) * 3600
+	if period < 24*3600 {
+		period = 24 * 3600
+	}
+	for k, kb := range d.keys {
+		if kb.lastSeen+period < now {
+			delete(d.keys, k)
+		}
+	}
+}
@@ -17,6 +17,7 @@
 package config
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 
@@ -35,8 +36,41 @@ const (
 	DefaultPerfDecoderQueueSize  = 100000
 	DefaultEventTTL              = 720 // hour
 	DefaultPerfEventTTL          = 168 // hour
+
+	DefaultAnomalyInterval    = 60 // s
+	DefaultAnomalyDelay       = 120
+	DefaultAnomalySeasonality = SEASONALITY_DAY
+	DefaultAnomalyHistorySize = 30
+	DefaultAnomalyMinSamples  = 5
+	DefaultAnomalyThreshold   = 5.0
+	DefaultAnomalyMinRequests = 100
+	DefaultAnomalyMinPackets  = 1000
+	DefaultAnomalyMaxKeys     = 5000
+	DefaultAnomalyEventTTL    = 720 // hour
 )
 
+const (
+	SEASONALITY_NONE = "none"
+	SEASONALITY_DAY  = "day"
+	SEASONALITY_WEEK = "week"
+)
+
+// 基于flow_metrics学习服务/服务路径的周期性基线, 偏离基线时写入event.anomaly_event
+type AnomalyConfig struct {
+	Enabled        bool                  `yaml:"enabled"`
+	Interval       int                   `yaml:"interval"`     // s, 检测周期
+	Delay          int                   `yaml:"delay"`        // s, 等待flow_metrics数据写入完成的时间
+	Seasonality    string                `yaml:"seasonality"`  // none: 不区分时段, day: 按一天中的小时, week: 按一周中的小时
+	HistorySize    int                   `yaml:"history-size"` // 每个时段保留的周期数, 每个周期一个样本(该小时各检测窗口的均值)
+	MinSamples     int                   `yaml:"min-samples"`  // 时段内样本数不足时只学习不检测
+	Threshold      float64               `yaml:"threshold"`    // 偏离基线的鲁棒z-score阈值
+	MinRequests    int                   `yaml:"min-requests"` // 响应数低于此值时不检测异常比例和时延
+	MinPackets     int                   `yaml:"min-packets"`  // 包数低于此值时不检测重传比例
+	MaxKeys        int                   `yaml:"max-keys"`     // 每个周期参与检测的服务/服务路径的最大数量
+	TTL            int                   `yaml:"ttl-hour"`
+	CKWriterConfig config.CKWriterConfig `yaml:"ck-writer"`
+}
+
 type Config struct {
 	Base                  *config.Config
 	CKWriterConfig        config.CKWriterConfig `yaml:"event-ck-writer"`
@@ -47,6 +81,7 @@ type Config struct {
 	PerfDecoderQueueCount int                   `yaml:"perf-event-decoder-queue-count"`
 	PerfDecoderQueueSize  int                   `yaml:"perf-event-decoder-queue-size"`
 	PerfTTL               int                   `yaml:"perf-event-ttl"`
+	Anomaly               AnomalyConfig         `yaml:"anomaly-detection"`
 }
 
 type EventConfig struct {
@@ -73,6 +108,41 @@ func (c *Config) Validate() error {
 		c.TTL = DefaultPerfEventTTL
 	}
 
+	return c.Anomaly.Validate()
+}
+
+func (c *AnomalyConfig) Validate() error {
+	if c.Interval < 60 {
+		c.Interval = DefaultAnomalyInterval
+	}
+	// flow_metrics按分钟聚合, 检测周期需为整分钟
+	c.Interval -= c.Interval % 60
+	if c.Delay <= 0 {
+		c.Delay = DefaultAnomalyDelay
+	}
+	switch c.Seasonality {
+	case SEASONALITY_NONE, SEASONALITY_DAY, SEASONALITY_WEEK:
+	default:
+		return fmt.Errorf("anomaly-detection seasonality(%s) invalid, should be one of %s/%s/%s", c.Seasonality, SEASONALITY_NONE, SEASONALITY_DAY, SEASONALITY_WEEK)
+	}
+	if c.HistorySize <= 0 {
+		c.HistorySize = DefaultAnomalyHistorySize
+	}
+	if c.MinSamples <= 0 || c.MinSamples > c.HistorySize {
+		c.MinSamples = c.HistorySize
+		if DefaultAnomalyMinSamples < c.MinSamples {
+			c.MinSamples = DefaultAnomalyMinSamples
+		}
+	}
+	if c.Threshold <= 0 {
+		c.Threshold = DefaultAnomalyThreshold
+	}
+	if c.MaxKeys <= 0 {
+		c.MaxKeys = DefaultAnomalyMaxKeys
+	}
+	if c.TTL <= 0 {
+		c.TTL = DefaultAnomalyEventTTL
+	}
 	return nil
 }
 
@@ -89,6 +159,20 @@ func Load(base *config.Config, path string) *Config {
 			PerfDecoderQueueCount: DefaultPerfDecoderQueueCount,
 			PerfDecoderQueueSize:  DefaultPerfDecoderQueueSize,
 			PerfTTL:               DefaultPerfEventTTL,
+
+			Anomaly: AnomalyConfig{
+				Interval:       DefaultAnomalyInterval,
+				Delay:          DefaultAnomalyDelay,
+				Seasonality:    DefaultAnomalySeasonality,
+				HistorySize:    DefaultAnomalyHistorySize,
+				MinSamples:     DefaultAnomalyMinSamples,
+				Threshold:      DefaultAnomalyThreshold,
+				MinRequests:    DefaultAnomalyMinRequests,
+				MinPackets:     DefaultAnomalyMinPackets,
+				MaxKeys:        DefaultAnomalyMaxKeys,
+				TTL:            DefaultAnomalyEventTTL,
+				CKWriterConfig: config.CKWriterConfig{QueueCount: 1, QueueSize: 10000, BatchSize: 4096, FlushTimeout: 5},
+			},
 		},
 	}
 	if _, err := os.Stat(path); os.IsNotExist(err) {
@@ -0,0 +1,173 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dbwriter
+
+import (
+	basecommon "github.com/deepflowio/deepflow/server/ingester/common"
+	"github.com/deepflowio/deepflow/server/ingester/event/config"
+	"github.com/deepflowio/deepflow/server/ingester/pkg/ckwriter"
+	"github.com/deepflowio/deepflow/server/libs/ckdb"
+	"github.com/deepflowio/deepflow/server/libs/pool"
+)
+
+// AnomalyEventStore 服务(仅1侧)或服务路径(0侧->1侧)的指标偏离基线的事件
+type AnomalyEventStore struct {
+	Time uint32 // s
+
+	StartTime int64
+	EndTime   int64
+
+	Metric           string
+	IsEdge           bool
+	EventDescription string
+
+	Value       float64
+	Baseline    float64
+	Deviation   float64
+	Score       float64
+	SampleCount uint32
+
+	L3EpcID0         int32
+	L3EpcID1         int32
+	AutoServiceID0   uint32
+	AutoServiceType0 uint8
+	AutoServiceID1   uint32
+	AutoServiceType1 uint8
+	ServerPort       uint16
+}
+
+func (e *AnomalyEventStore) WriteBlock(block *ckdb.Block) {
+	block.WriteDateTime(e.Time)
+	block.Write(
+		e.StartTime,
+		e.EndTime,
+
+		e.Metric)
+	block.WriteBool(e.IsEdge)
+	block.Write(
+		e.EventDescription,
+
+		e.Value,
+		e.Baseline,
+		e.Deviation,
+		e.Score,
+		e.SampleCount,
+
+		e.L3EpcID0,
+		e.L3EpcID1,
+		e.AutoServiceID0,
+		e.AutoServiceType0,
+		e.AutoServiceID1,
+		e.AutoServiceType1,
+		e.ServerPort)
+
+	// 仅用于资源Tag翻译时引用的IP列, 服务不对应IP地址
+	block.WriteBool(true)
+	block.WriteIPv4(0)
+	block.WriteIPv4(0)
+	block.WriteIPv6(nil)
+	block.WriteIPv6(nil)
+	block.Write(uint16(0), uint16(0))
+}
+
+func (e *AnomalyEventStore) Release() {
+	ReleaseAnomalyEventStore(e)
+}
+
+func AnomalyEventColumns() []*ckdb.Column {
+	return []*ckdb.Column{
+		ckdb.NewColumn("time", ckdb.DateTime),
+		ckdb.NewColumn("start_time", ckdb.DateTime64us).SetComment("检测窗口开始时间, 精度: 微秒"),
+		ckdb.NewColumn("end_time", ckdb.DateTime64us).SetComment("检测窗口结束时间, 精度: 微秒"),
+
+		ckdb.NewColumn("metric", ckdb.LowCardinalityString).SetComment("偏离基线的指标: request_rate, error_ratio, rrt, retrans_ratio"),
+		ckdb.NewColumn("is_edge", ckdb.UInt8).SetComment("0: 服务, 1: 服务路径"),
+		ckdb.NewColumn("event_desc", ckdb.String).SetComment("事件信息"),
+
+		ckdb.NewColumn("value", ckdb.Float64).SetComment("检测窗口内的指标值"),
+		ckdb.NewColumn("baseline", ckdb.Float64).SetComment("同时段历史样本的中位数"),
+		ckdb.NewColumn("deviation", ckdb.Float64).SetComment("同时段历史样本的鲁棒标准差"),
+		ckdb.NewColumn("score", ckdb.Float64).SetComment("鲁棒z-score, 正数表示高于基线"),
+		ckdb.NewColumn("sample_count", ckdb.UInt32).SetComment("计算基线的样本数"),
+
+		ckdb.NewColumn("l3_epc_id_0", ckdb.Int32),
+		ckdb.NewColumn("l3_epc_id_1", ckdb.Int32),
+		ckdb.NewColumn("auto_service_id_0", ckdb.UInt32),
+		ckdb.NewColumn("auto_service_type_0", ckdb.UInt8),
+		ckdb.NewColumn("auto_service_id_1", ckdb.UInt32),
+		ckdb.NewColumn("auto_service_type_1", ckdb.UInt8),
+		ckdb.NewColumn("server_port", ckdb.UInt16).SetComment("服务路径的服务端端口, 服务为0"),
+
+		ckdb.NewColumn("is_ipv4", ckdb.UInt8),
+		ckdb.NewColumn("ip4_0", ckdb.IPv4),
+		ckdb.NewColumn("ip4_1", ckdb.IPv4),
+		ckdb.NewColumn("ip6_0", ckdb.IPv6),
+		ckdb.NewColumn("ip6_1", ckdb.IPv6),
+		ckdb.NewColumn("subnet_id_0", ckdb.UInt16),
+		ckdb.NewColumn("subnet_id_1", ckdb.UInt16),
+	}
+}
+
+func GenAnomalyEventCKTable(cluster, storagePolicy string, ttl int, coldStorage *ckdb.ColdStorage) *ckdb.Table {
+	orderKeys := []string{"metric", "auto_service_type_1", "auto_service_id_1", "auto_service_type_0", "auto_service_id_0"}
+	return &ckdb.Table{
+		Version:         basecommon.CK_VERSION,
+		Database:        EVENT_DB,
+		LocalName:       ANOMALY_EVENT_TABLE + ckdb.LOCAL_SUBFFIX,
+		GlobalName:      ANOMALY_EVENT_TABLE,
+		Columns:         AnomalyEventColumns(),
+		TimeKey:         "time",
+		TTL:             ttl,
+		PartitionFunc:   DefaultPartition,
+		Engine:          ckdb.MergeTree,
+		Cluster:         cluster,
+		StoragePolicy:   storagePolicy,
+		ColdStorage:     *coldStorage,
+		OrderKeys:       orderKeys,
+		PrimaryKeyCount: len(orderKeys),
+	}
+}
+
+func NewAnomalyEventWriter(config *config.Config) (*ckwriter.CKWriter, error) {
+	base := config.Base
+	writerConfig := &config.Anomaly.CKWriterConfig
+	ckTable := GenAnomalyEventCKTable(base.CKDB.ClusterName, base.CKDB.StoragePolicy, config.Anomaly.TTL,
+		ckdb.GetColdStorage(base.GetCKDBColdStorages(), EVENT_DB, ANOMALY_EVENT_TABLE))
+	w, err := ckwriter.NewCKWriter(base.CKDB.ActualAddrs, base.CKDBAuth.Username, base.CKDBAuth.Password,
+		ANOMALY_EVENT_TABLE, base.CKDB.TimeZone, ckTable, writerConfig.QueueCount, writerConfig.QueueSize, writerConfig.BatchSize, writerConfig.FlushTimeout)
+	if err != nil {
+		return nil, err
+	}
+	w.Run()
+	return w, nil
+}
+
+var anomalyEventPool = pool.NewLockFreePool(func() interface{} {
+	return &AnomalyEventStore{}
+})
+
+func AcquireAnomalyEventStore() *AnomalyEventStore {
+	return anomalyEventPool.Get().(*AnomalyEventStore)
+}
+
+func ReleaseAnomalyEventStore(e *AnomalyEventStore) {
+	if e == nil {
+		return
+	}
+	*e = AnomalyEventStore{}
+	anomalyEventPool.Put(e)
+}
@@ -37,6 +37,8 @@ const (
 	SIGNAL_SOURCE_UNKNOWN SignalSource = iota
 	SIGNAL_SOURCE_RESOURCE
 	SIGNAL_SOURCE_IO
+	SIGNAL_SOURCE_K8S_EVENT
+	SIGNAL_SOURCE_CHANGE_EVENT
 )
 
 type EventStore struct {
@@ -47,7 +49,7 @@ type EventStore struct {
 
 	Tagged uint8
 
-	SignalSource     uint8 // Resource / File IO
+	SignalSource     uint8 // Resource / File IO / Kubernetes Event / Change Event
 	EventType        string
 	EventDescription string
 
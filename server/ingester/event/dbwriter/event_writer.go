@@ -33,6 +33,8 @@ const (
 	EVENT_DB         = "event"
 	EVENT_TABLE      = "event"
 	PERF_EVENT_TABLE = "perf_event"
+
+	ANOMALY_EVENT_TABLE = "anomaly_event"
 )
 
 type ClusterNode struct {
@@ -89,6 +91,7 @@ func NewEventWriter(table string, decoderIndex int, config *config.Config) (*Eve
 	w.flowTagWriter = flowTagWriter
 
 	ckTable := GenEventCKTable(w.ckdbCluster, w.ckdbStoragePolicy, table, w.ttl, ckdb.GetColdStorage(w.ckdbColdStorages, EVENT_DB, table))
+	ckTable.TTLRules = ckdb.GetTTLRules(config.Base.GetCKDBTTLRules(), EVENT_DB, table)
 
 	ckwriter, err := ckwriter.NewCKWriter(w.ckdbAddrs, w.ckdbUsername, w.ckdbPassword,
 		EVENT_TABLE, config.Base.CKDB.TimeZone, ckTable, w.writerConfig.QueueCount, w.writerConfig.QueueSize, w.writerConfig.BatchSize, w.writerConfig.FlushTimeout)
@@ -226,11 +226,21 @@ func (d *Decoder) handleResourceEvent(event *eventapi.ResourceEvent) {
 	eventStore.EndTime = eventStore.StartTime
 
 	eventStore.SignalSource = uint8(dbwriter.SIGNAL_SOURCE_RESOURCE)
+	if event.KubernetesType != "" {
+		eventStore.SignalSource = uint8(dbwriter.SIGNAL_SOURCE_K8S_EVENT)
+		eventStore.AttributeNames = append(eventStore.AttributeNames, "k8s_event_type")
+		eventStore.AttributeValues = append(eventStore.AttributeValues, event.KubernetesType)
+	} else if event.IsChangeEvent {
+		eventStore.SignalSource = uint8(dbwriter.SIGNAL_SOURCE_CHANGE_EVENT)
+	}
 	eventStore.EventType = event.Type
 	eventStore.EventDescription = event.Description
 
 	eventStore.GProcessID = event.GProcessID
 
+	eventStore.AttributeNames = append(eventStore.AttributeNames, event.AttributeNames...)
+	eventStore.AttributeValues = append(eventStore.AttributeValues, event.AttributeValues...)
+
 	if len(event.AttributeSubnetIDs) > 0 {
 		eventStore.AttributeNames = append(eventStore.AttributeNames, "subnet_ids")
 		eventStore.AttributeValues = append(eventStore.AttributeValues,
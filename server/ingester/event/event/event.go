@@ -24,6 +24,7 @@ import (
 	_ "google.golang.org/grpc"
 
 	dropletqueue "github.com/deepflowio/deepflow/server/ingester/droplet/queue"
+	"github.com/deepflowio/deepflow/server/ingester/event/anomaly"
 	"github.com/deepflowio/deepflow/server/ingester/event/common"
 	"github.com/deepflowio/deepflow/server/ingester/event/config"
 	"github.com/deepflowio/deepflow/server/ingester/event/dbwriter"
@@ -40,6 +41,7 @@ type Event struct {
 	Config          *config.Config
 	ResourceEventor *Eventor
 	PerfEventor     *Eventor
+	AnomalyDetector *anomaly.Detector
 }
 
 type Eventor struct {
@@ -48,7 +50,7 @@ type Eventor struct {
 	PlatformDatas []*grpc.PlatformInfoTable
 }
 
-func NewEvent(config *config.Config, resourceEventQueue *queue.OverwriteQueue, recv *receiver.Receiver, platformDataManager *grpc.PlatformDataManager) (*Event, error) {
+func NewEvent(config *config.Config, resourceEventQueue *queue.OverwriteQueue, recv *receiver.Receiver, platformDataManager *grpc.PlatformDataManager, isMaster func() bool) (*Event, error) {
 	manager := dropletqueue.NewManager(ingesterctl.INGESTERCTL_EVENT_QUEUE)
 	resourceEventor, err := NewResouceEventor(resourceEventQueue, common.RESOURCE_EVENT, config)
 	if err != nil {
@@ -59,10 +61,18 @@ func NewEvent(config *config.Config, resourceEventQueue *queue.OverwriteQueue, r
 	if err != nil {
 		return nil, err
 	}
+	var anomalyDetector *anomaly.Detector
+	if config.Anomaly.Enabled {
+		anomalyDetector, err = anomaly.NewDetector(config, isMaster)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return &Event{
 		Config:          config,
 		ResourceEventor: resourceEventor,
 		PerfEventor:     perfEventor,
+		AnomalyDetector: anomalyDetector,
 	}, nil
 }
 
@@ -143,10 +153,16 @@ func (e *Eventor) Close() {
 func (e *Event) Start() {
 	e.ResourceEventor.Start()
 	e.PerfEventor.Start()
+	if e.AnomalyDetector != nil {
+		e.AnomalyDetector.Start()
+	}
 }
 
 func (e *Event) Close() error {
 	e.ResourceEventor.Start()
 	e.PerfEventor.Close()
+	if e.AnomalyDetector != nil {
+		e.AnomalyDetector.Close()
+	}
 	return nil
 }
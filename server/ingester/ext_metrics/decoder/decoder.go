@@ -28,6 +28,7 @@ import (
 	"github.com/deepflowio/deepflow/server/ingester/common"
 	"github.com/deepflowio/deepflow/server/ingester/ext_metrics/config"
 	"github.com/deepflowio/deepflow/server/ingester/ext_metrics/dbwriter"
+	"github.com/deepflowio/deepflow/server/ingester/wasm"
 	"github.com/deepflowio/deepflow/server/libs/codec"
 	"github.com/deepflowio/deepflow/server/libs/datatype"
 	"github.com/deepflowio/deepflow/server/libs/grpc"
@@ -53,6 +54,7 @@ type Counter struct {
 	ErrorCount             int64 `statsd:"err-count"`
 	ErrMetrics             int64 `statsd:"err-metrics"`
 	DropUnsupportedMetrics int64 `statsd:"drop-unsupported-metrics"`
+	PluginDrop             int64 `statsd:"plugin-drop"`
 }
 
 type Decoder struct {
@@ -61,6 +63,7 @@ type Decoder struct {
 	platformData     *grpc.PlatformInfoTable
 	inQueue          queue.QueueReader
 	extMetricsWriter *dbwriter.ExtMetricsWriter
+	pluginRunner     *wasm.Runner
 	debugEnabled     bool
 	config           *config.Config
 
@@ -79,6 +82,7 @@ func NewDecoder(
 	platformData *grpc.PlatformInfoTable,
 	inQueue queue.QueueReader,
 	extMetricsWriter *dbwriter.ExtMetricsWriter,
+	pluginRunner *wasm.Runner,
 	config *config.Config,
 ) *Decoder {
 	return &Decoder{
@@ -88,6 +92,7 @@ func NewDecoder(
 		inQueue:          inQueue,
 		debugEnabled:     log.IsEnabledFor(logging.DEBUG),
 		extMetricsWriter: extMetricsWriter,
+		pluginRunner:     pluginRunner,
 		config:           config,
 		counter:          &Counter{},
 	}
@@ -176,10 +181,31 @@ func (d *Decoder) sendTelegraf(vtapID uint16, point models.Point) {
 		d.counter.ErrMetrics++
 		return
 	}
+	if d.dropByPlugins(extMetrics, string(point.Name())) {
+		dbwriter.ReleaseExtMetrics(extMetrics)
+		return
+	}
 	d.extMetricsWriter.Write(extMetrics)
 	d.counter.OutCount++
 }
 
+// 依次执行服务端WASM插件，插件可增加或修改tag，返回true表示插件要求丢弃该数据
+func (d *Decoder) dropByPlugins(m *dbwriter.ExtMetrics, measurement string) bool {
+	if !d.pluginRunner.HasMetricSamplePlugins() {
+		return false
+	}
+	in := wasm.NewMetricSample(wasm.SOURCE_TELEGRAF, measurement, m.Timestamp, m.TagNames, m.TagValues)
+	drop, changed := d.pluginRunner.OnMetricSample(in)
+	if drop {
+		d.counter.PluginDrop++
+		return true
+	}
+	if changed {
+		m.TagNames, m.TagValues = wasm.MergeAttributes(m.TagNames, m.TagValues, in.Labels)
+	}
+	return false
+}
+
 func (d *Decoder) handleDeepflowStats(vtapID uint16, decoder *codec.SimpleDecoder) {
 	for !decoder.IsEnd() {
 		pbStats := &pb.Stats{}
@@ -33,6 +33,7 @@ import (
 	"github.com/deepflowio/deepflow/server/ingester/flow_log/log_data"
 	"github.com/deepflowio/deepflow/server/ingester/flow_log/throttler"
 	"github.com/deepflowio/deepflow/server/ingester/flow_tag"
+	"github.com/deepflowio/deepflow/server/ingester/wasm"
 	"github.com/deepflowio/deepflow/server/libs/codec"
 	"github.com/deepflowio/deepflow/server/libs/datatype"
 	"github.com/deepflowio/deepflow/server/libs/datatype/pb"
@@ -67,6 +68,7 @@ type Counter struct {
 	ErrorCount       int64 `statsd:"err-count"`
 	Count            int64 `statsd:"count"`
 	DropCount        int64 `statsd:"drop-count"`
+	PluginDropCount  int64 `statsd:"plugin-drop-count"`
 
 	TotalTime int64 `statsd:"total-time"`
 	AvgTime   int64 `statsd:"avg-time"`
@@ -80,6 +82,7 @@ type Decoder struct {
 	throttler     *throttler.ThrottlingQueue
 	flowTagWriter *flow_tag.FlowTagWriter
 	otlpExporter  *exporter.OtlpExporter
+	pluginRunner  *wasm.Runner
 	debugEnabled  bool
 
 	fieldsBuf      []interface{}
@@ -95,6 +98,7 @@ func NewDecoder(
 	throttler *throttler.ThrottlingQueue,
 	flowTagWriter *flow_tag.FlowTagWriter,
 	otlpExporter *exporter.OtlpExporter,
+	pluginRunner *wasm.Runner,
 ) *Decoder {
 	return &Decoder{
 		index:          index,
@@ -104,6 +108,7 @@ func NewDecoder(
 		throttler:      throttler,
 		flowTagWriter:  flowTagWriter,
 		otlpExporter:   otlpExporter,
+		pluginRunner:   pluginRunner,
 		debugEnabled:   log.IsEnabledFor(logging.DEBUG),
 		fieldsBuf:      make([]interface{}, 0, 64),
 		fieldValuesBuf: make([]interface{}, 0, 64),
@@ -152,6 +157,10 @@ func (d *Decoder) Run() {
 				d.handleOpenTelemetry(recvBytes.VtapID, decoder, pbTracesData, false)
 			case datatype.MESSAGE_TYPE_OPENTELEMETRY_COMPRESSED:
 				d.handleOpenTelemetry(recvBytes.VtapID, decoder, pbTracesData, true)
+			case datatype.MESSAGE_TYPE_ZIPKIN:
+				d.handleZipkin(recvBytes.VtapID, decoder)
+			case datatype.MESSAGE_TYPE_SKYWALKING:
+				d.handleSkyWalking(recvBytes.VtapID, decoder)
 			case datatype.MESSAGE_TYPE_PACKETSEQUENCE:
 				d.handleL4Packet(recvBytes.VtapID, decoder)
 			default:
@@ -236,8 +245,69 @@ func (d *Decoder) sendOpenMetetry(vtapID uint16, tracesData *v1.TracesData) {
 		log.Debugf("decoder %d vtap %d recv otel: %s", d.index, vtapID, tracesData)
 	}
 	d.counter.Count++
-	ls := log_data.OTelTracesDataToL7FlowLogs(vtapID, tracesData, d.platformData)
+	d.sendL7FlowLogs(log_data.OTelTracesDataToL7FlowLogs(vtapID, tracesData, d.platformData))
+}
+
+func (d *Decoder) handleZipkin(vtapID uint16, decoder *codec.SimpleDecoder) {
+	for !decoder.IsEnd() {
+		bytes := decoder.ReadBytes()
+		var spans []log_data.ZipkinSpan
+		var err error
+		if len(bytes) > 0 {
+			spans, err = log_data.DecodeZipkinSpans(bytes)
+		}
+		if decoder.Failed() || err != nil {
+			if d.counter.ErrorCount == 0 {
+				log.Errorf("Zipkin span decode failed, offset=%d len=%d err: %s", decoder.Offset(), len(decoder.Bytes()), err)
+			}
+			d.counter.ErrorCount++
+			return
+		}
+		if d.debugEnabled {
+			log.Debugf("decoder %d vtap %d recv zipkin: %+v", d.index, vtapID, spans)
+		}
+		d.counter.Count++
+		d.sendL7FlowLogs(log_data.ZipkinSpansToL7FlowLogs(vtapID, spans, d.platformData))
+	}
+}
+
+func (d *Decoder) handleSkyWalking(vtapID uint16, decoder *codec.SimpleDecoder) {
+	for !decoder.IsEnd() {
+		bytes := decoder.ReadBytes()
+		var segments []*log_data.SkyWalkingSegment
+		var err error
+		if len(bytes) > 0 {
+			segments, err = log_data.DecodeSkyWalkingSegments(bytes)
+		}
+		if decoder.Failed() || err != nil {
+			if d.counter.ErrorCount == 0 {
+				log.Errorf("SkyWalking segment decode failed, offset=%d len=%d err: %s", decoder.Offset(), len(decoder.Bytes()), err)
+			}
+			d.counter.ErrorCount++
+			return
+		}
+		for _, segment := range segments {
+			// 单个segment无效不影响同一批次中的其他segment
+			if !segment.IsValid() {
+				d.counter.ErrorCount++
+				continue
+			}
+			if d.debugEnabled {
+				log.Debugf("decoder %d vtap %d recv skywalking: %+v", d.index, vtapID, segment)
+			}
+			d.counter.Count++
+			d.sendL7FlowLogs(log_data.SkyWalkingSegmentToL7FlowLogs(vtapID, segment, d.platformData))
+		}
+	}
+}
+
+// 应用Span(OTel, Zipkin, SkyWalking)转换为L7FlowLog后的公共发送流程
+func (d *Decoder) sendL7FlowLogs(ls []*log_data.L7FlowLog) {
 	for _, l := range ls {
+		if d.dropByPlugins(l) {
+			l.Release()
+			continue
+		}
 		l.AddReferenceCount()
 		if !d.throttler.SendWithThrottling(l) {
 			d.counter.DropCount++
@@ -301,6 +371,11 @@ func (d *Decoder) sendProto(proto *pb.AppProtoLogsData) {
 
 	dropped := false
 	l := log_data.ProtoLogToL7FlowLog(proto, d.platformData)
+	if d.dropByPlugins(l) {
+		l.Release()
+		proto.Release()
+		return
+	}
 	l.AddReferenceCount()
 	if d.throttler.SendWithThrottling(l) {
 		d.fieldsBuf, d.fieldValuesBuf = d.fieldsBuf[:0], d.fieldValuesBuf[:0]
@@ -316,6 +391,41 @@ func (d *Decoder) sendProto(proto *pb.AppProtoLogsData) {
 
 }
 
+// 依次执行服务端WASM插件，插件可增加属性、改写endpoint，返回true表示插件要求丢弃该日志
+func (d *Decoder) dropByPlugins(l *log_data.L7FlowLog) bool {
+	if !d.pluginRunner.HasL7FlowLogPlugins() {
+		return false
+	}
+	in := wasm.NewL7FlowLog(l.AttributeNames, l.AttributeValues)
+	in.Time = uint32(l.L7Base.EndTime / log_data.US_TO_S_DEVISOR)
+	in.L7Protocol = l.L7ProtocolStr
+	in.Version = l.Version
+	in.Type = l.Type
+	in.RequestType = l.RequestType
+	in.RequestDomain = l.RequestDomain
+	in.RequestResource = l.RequestResource
+	in.Endpoint = l.Endpoint
+	in.ResponseStatus = l.ResponseStatus
+	in.ResponseCode = l.ResponseCode
+	in.ResponseException = l.ResponseException
+	in.ResponseDuration = l.ResponseDuration
+	in.TraceId = l.TraceId
+	in.SpanId = l.SpanId
+	in.AppService = l.AppService
+	in.AppInstance = l.AppInstance
+
+	drop, changed := d.pluginRunner.OnL7FlowLog(in)
+	if drop {
+		d.counter.PluginDropCount++
+		return true
+	}
+	if changed {
+		l.Endpoint = in.Endpoint
+		l.AttributeNames, l.AttributeValues = wasm.MergeAttributes(l.AttributeNames, l.AttributeValues, in.Attributes)
+	}
+	return false
+}
+
 func (d *Decoder) updateCounter(l7Protocol datatype.L7Protocol, dropped bool) {
 	d.counter.Count++
 	drop := int64(0)
@@ -202,9 +202,16 @@ func NewOtlpExporter(config *config.Config) *OtlpExporter {
 	return exporter
 }
 
+// OTel, Zipkin, SkyWalking等应用Span由应用自身上报
+func isAppSpan(signalSource datatype.SignalSource) bool {
+	return signalSource == datatype.SIGNAL_SOURCE_OTEL ||
+		signalSource == datatype.SIGNAL_SOURCE_ZIPKIN ||
+		signalSource == datatype.SIGNAL_SOURCE_SKYWALKING
+}
+
 func (e *OtlpExporter) IsExportData(signalSource datatype.SignalSource) bool {
-	// always not export data from OTel
-	if signalSource == datatype.SIGNAL_SOURCE_OTEL {
+	// always not export app spans
+	if isAppSpan(signalSource) {
 		return false
 	}
 	return (1<<uint32(signalSource))&e.exportDataBits != 0
@@ -145,7 +145,7 @@ func L7FlowLogToExportRequest(l7 *log_data.L7FlowLog, universalTagsManager *Univ
 		putStrWithoutEmpty(spanAttrs, "df.span.native.span_id", l7.SpanId)
 
 		span.SetTraceID(getTraceID(l7.TraceId, l7.ID()))
-		if l7.SignalSource == uint16(datatype.SIGNAL_SOURCE_OTEL) {
+		if isAppSpan(datatype.SignalSource(l7.SignalSource)) {
 			span.SetSpanID(getSpanID(l7.SpanId, l7.ID()))
 			if l7.ParentSpanId == "" {
 				span.SetParentSpanID(pcommon.NewSpanIDEmpty())
@@ -33,6 +33,7 @@ import (
 	"github.com/deepflowio/deepflow/server/ingester/flow_log/throttler"
 	"github.com/deepflowio/deepflow/server/ingester/flow_tag"
 	"github.com/deepflowio/deepflow/server/ingester/ingesterctl"
+	"github.com/deepflowio/deepflow/server/ingester/wasm"
 	"github.com/deepflowio/deepflow/server/libs/datatype"
 	"github.com/deepflowio/deepflow/server/libs/debug"
 	"github.com/deepflowio/deepflow/server/libs/grpc"
@@ -50,6 +51,8 @@ type FlowLog struct {
 	L7FlowLogger         *Logger
 	OtelLogger           *Logger
 	OtelCompressedLogger *Logger
+	ZipkinLogger         *Logger
+	SkyWalkingLogger     *Logger
 	L4PacketLogger       *Logger
 	OtlpExporter         *exporter.OtlpExporter
 }
@@ -61,33 +64,41 @@ type Logger struct {
 	FlowLogWriter *dbwriter.FlowLogWriter
 }
 
-func NewFlowLog(config *config.Config, recv *receiver.Receiver, platformDataManager *grpc.PlatformDataManager) (*FlowLog, error) {
+func NewFlowLog(config *config.Config, recv *receiver.Receiver, platformDataManager *grpc.PlatformDataManager, pluginManager *wasm.Manager) (*FlowLog, error) {
 	manager := dropletqueue.NewManager(ingesterctl.INGESTERCTL_FLOW_LOG_QUEUE)
 	geo.NewGeoTree()
 
 	flowLogWriter, err := dbwriter.NewFlowLogWriter(
 		config.Base.CKDB.ActualAddrs, config.Base.CKDBAuth.Username, config.Base.CKDBAuth.Password,
 		config.Base.CKDB.ClusterName, config.Base.CKDB.StoragePolicy, config.Base.CKDB.TimeZone,
-		config.CKWriterConfig, config.FlowLogTTL, config.Base.GetCKDBColdStorages())
+		config.CKWriterConfig, config.FlowLogTTL, config.Base.GetCKDBColdStorages(), config.Base.GetCKDBTTLRules())
 	if err != nil {
 		return nil, err
 	}
 	l4FlowLogger := NewL4FlowLogger(config, platformDataManager, manager, recv, flowLogWriter)
 
 	otlpExporter := exporter.NewOtlpExporter(config)
-	l7FlowLogger, err := NewL7FlowLogger(config, platformDataManager, manager, recv, flowLogWriter, otlpExporter)
+	l7FlowLogger, err := NewL7FlowLogger(config, platformDataManager, manager, recv, flowLogWriter, otlpExporter, pluginManager)
 	if err != nil {
 		return nil, err
 	}
-	otelLogger, err := NewLogger(datatype.MESSAGE_TYPE_OPENTELEMETRY, config, platformDataManager, manager, recv, flowLogWriter, common.L7_FLOW_ID, otlpExporter)
+	otelLogger, err := NewLogger(datatype.MESSAGE_TYPE_OPENTELEMETRY, config, platformDataManager, manager, recv, flowLogWriter, common.L7_FLOW_ID, otlpExporter, pluginManager)
 	if err != nil {
 		return nil, err
 	}
-	otelCompressedLogger, err := NewLogger(datatype.MESSAGE_TYPE_OPENTELEMETRY_COMPRESSED, config, platformDataManager, manager, recv, flowLogWriter, common.L7_FLOW_ID, otlpExporter)
+	otelCompressedLogger, err := NewLogger(datatype.MESSAGE_TYPE_OPENTELEMETRY_COMPRESSED, config, platformDataManager, manager, recv, flowLogWriter, common.L7_FLOW_ID, otlpExporter, pluginManager)
 	if err != nil {
 		return nil, err
 	}
-	l4PacketLogger, err := NewLogger(datatype.MESSAGE_TYPE_PACKETSEQUENCE, config, nil, manager, recv, flowLogWriter, common.L4_PACKET_ID, nil)
+	zipkinLogger, err := NewLogger(datatype.MESSAGE_TYPE_ZIPKIN, config, platformDataManager, manager, recv, flowLogWriter, common.L7_FLOW_ID, otlpExporter, pluginManager)
+	if err != nil {
+		return nil, err
+	}
+	skyWalkingLogger, err := NewLogger(datatype.MESSAGE_TYPE_SKYWALKING, config, platformDataManager, manager, recv, flowLogWriter, common.L7_FLOW_ID, otlpExporter, pluginManager)
+	if err != nil {
+		return nil, err
+	}
+	l4PacketLogger, err := NewLogger(datatype.MESSAGE_TYPE_PACKETSEQUENCE, config, nil, manager, recv, flowLogWriter, common.L4_PACKET_ID, nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -97,12 +108,14 @@ func NewFlowLog(config *config.Config, recv *receiver.Receiver, platformDataMana
 		L7FlowLogger:         l7FlowLogger,
 		OtelLogger:           otelLogger,
 		OtelCompressedLogger: otelCompressedLogger,
+		ZipkinLogger:         zipkinLogger,
+		SkyWalkingLogger:     skyWalkingLogger,
 		L4PacketLogger:       l4PacketLogger,
 		OtlpExporter:         otlpExporter,
 	}, nil
 }
 
-func NewLogger(msgType datatype.MessageType, config *config.Config, platformDataManager *grpc.PlatformDataManager, manager *dropletqueue.Manager, recv *receiver.Receiver, flowLogWriter *dbwriter.FlowLogWriter, flowLogId common.FlowLogID, otlpExporter *exporter.OtlpExporter) (*Logger, error) {
+func NewLogger(msgType datatype.MessageType, config *config.Config, platformDataManager *grpc.PlatformDataManager, manager *dropletqueue.Manager, recv *receiver.Receiver, flowLogWriter *dbwriter.FlowLogWriter, flowLogId common.FlowLogID, otlpExporter *exporter.OtlpExporter, pluginManager *wasm.Manager) (*Logger, error) {
 	queueCount := config.DecoderQueueCount
 	decodeQueues := manager.NewQueues(
 		"1-receive-to-decode-"+datatype.MessageTypeString[msgType],
@@ -141,6 +154,7 @@ func NewLogger(msgType datatype.MessageType, config *config.Config, platformData
 			throttlers[i],
 			flowTagWriter,
 			otlpExporter,
+			pluginManager.NewRunner(),
 		)
 	}
 	return &Logger{
@@ -192,6 +206,7 @@ func NewL4FlowLogger(config *config.Config, platformDataManager *grpc.PlatformDa
 			throttlers[i],
 			nil,
 			nil,
+			nil,
 		)
 	}
 	return &Logger{
@@ -202,7 +217,7 @@ func NewL4FlowLogger(config *config.Config, platformDataManager *grpc.PlatformDa
 	}
 }
 
-func NewL7FlowLogger(config *config.Config, platformDataManager *grpc.PlatformDataManager, manager *dropletqueue.Manager, recv *receiver.Receiver, flowLogWriter *dbwriter.FlowLogWriter, otlpExporter *exporter.OtlpExporter) (*Logger, error) {
+func NewL7FlowLogger(config *config.Config, platformDataManager *grpc.PlatformDataManager, manager *dropletqueue.Manager, recv *receiver.Receiver, flowLogWriter *dbwriter.FlowLogWriter, otlpExporter *exporter.OtlpExporter, pluginManager *wasm.Manager) (*Logger, error) {
 	queueSuffix := "-l7"
 	queueCount := config.DecoderQueueCount
 	msgType := datatype.MESSAGE_TYPE_PROTOCOLLOG
@@ -245,6 +260,7 @@ func NewL7FlowLogger(config *config.Config, platformDataManager *grpc.PlatformDa
 			throttlers[i],
 			flowTagWriter,
 			otlpExporter,
+			pluginManager.NewRunner(),
 		)
 	}
 
@@ -281,6 +297,8 @@ func (s *FlowLog) Start() {
 	s.L4PacketLogger.Start()
 	s.OtelLogger.Start()
 	s.OtelCompressedLogger.Start()
+	s.ZipkinLogger.Start()
+	s.SkyWalkingLogger.Start()
 	if s.OtlpExporter != nil {
 		s.OtlpExporter.Start()
 	}
@@ -292,5 +310,7 @@ func (s *FlowLog) Close() error {
 	s.L4PacketLogger.Close()
 	s.OtelLogger.Close()
 	s.OtelCompressedLogger.Close()
+	s.ZipkinLogger.Close()
+	s.SkyWalkingLogger.Close()
 	return nil
 }
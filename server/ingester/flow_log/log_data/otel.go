@@ -279,6 +279,16 @@ func (h *L7FlowLog) fillAttributes(spanAttributes, resAttributes []*v11.KeyValue
 		}
 	}
 
+	h.fillL7Protocol()
+
+	h.AttributeNames = attributeNames
+	h.AttributeValues = attributeValues
+	h.MetricsNames = metricsNames
+	h.MetricsValues = metricsValues
+}
+
+// 根据L7ProtocolStr识别应用协议, 无法识别时设置为Other
+func (h *L7FlowLog) fillL7Protocol() {
 	if len(h.L7ProtocolStr) > 0 {
 		if strings.Contains(strings.ToLower(h.L7ProtocolStr), "http") {
 			if strings.HasPrefix(h.Version, "2") {
@@ -308,20 +318,57 @@ func (h *L7FlowLog) fillAttributes(spanAttributes, resAttributes []*v11.KeyValue
 	if h.L7Protocol == uint8(datatype.L7_PROTOCOL_UNKNOWN) {
 		h.L7Protocol = uint8(datatype.L7_PROTOCOL_OTHER)
 	}
-
-	h.AttributeNames = attributeNames
-	h.AttributeValues = attributeValues
-	h.MetricsNames = metricsNames
-	h.MetricsValues = metricsValues
 }
 
-func (h *L7FlowLog) FillOTel(l *v1.Span, resAttributes []*v11.KeyValue, platformData *grpc.PlatformInfoTable) {
+// OTel, Zipkin, SkyWalking等应用Span的公共字段
+func (h *L7FlowLog) fillAppSpanBase(signalSource datatype.SignalSource) {
 	// OTel data net protocol always set to TCP
 	h.Protocol = uint8(layers.IPProtocolTCP)
 	h.TapType = uint8(datatype.TAP_CLOUD)
 	h.Type = uint8(datatype.MSG_T_SESSION)
 	h.TapPortType = datatype.TAPPORT_FROM_OTEL
-	h.SignalSource = uint16(datatype.SIGNAL_SOURCE_OTEL)
+	h.SignalSource = uint16(signalSource)
+}
+
+// 设置Span自身(local)或对端的IP, 客户端Span自身为0侧, 其他Span自身为1侧
+func (h *L7FlowLog) setAppSpanIP(ip net.IP, local bool) {
+	side0 := local == (h.TapSide == zerodoc.ClientApp.String())
+	if ip4 := ip.To4(); ip4 != nil {
+		if side0 {
+			h.IP40 = utils.IpToUint32(ip4)
+		} else {
+			h.IP41 = utils.IpToUint32(ip4)
+		}
+	} else if len(ip) == net.IPv6len {
+		h.IsIPv4 = false
+		if side0 {
+			h.IP60 = ip
+		} else {
+			h.IP61 = ip
+		}
+	}
+}
+
+// 优先使用HTTP响应码, 其次使用Span的异常信息
+func (h *L7FlowLog) fillAppSpanStatus(isError bool, exception string) {
+	if h.ResponseCode != nil && *h.ResponseCode != 0 {
+		h.ResponseStatus = uint8(httpCodeToResponseStatus(*h.ResponseCode))
+		if h.ResponseStatus == uint8(datatype.STATUS_CLIENT_ERROR) ||
+			h.ResponseStatus == uint8(datatype.STATUS_SERVER_ERROR) {
+			h.ResponseException = GetHTTPExceptionDesc(uint16(*h.ResponseCode))
+		}
+	} else if isError {
+		h.ResponseStatus = uint8(datatype.STATUS_SERVER_ERROR)
+	} else {
+		h.ResponseStatus = uint8(datatype.STATUS_OK)
+	}
+	if isError && exception != "" {
+		h.ResponseException = exception
+	}
+}
+
+func (h *L7FlowLog) FillOTel(l *v1.Span, resAttributes []*v11.KeyValue, platformData *grpc.PlatformInfoTable) {
+	h.fillAppSpanBase(datatype.SIGNAL_SOURCE_OTEL)
 	h.TraceId = hex.EncodeToString(l.TraceId)
 	h.SpanId = hex.EncodeToString(l.SpanId)
 	h.ParentSpanId = hex.EncodeToString(l.ParentSpanId)
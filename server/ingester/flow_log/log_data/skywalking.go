@@ -0,0 +1,313 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log_data
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/deepflowio/deepflow/server/libs/datatype"
+	"github.com/deepflowio/deepflow/server/libs/grpc"
+	"github.com/deepflowio/deepflow/server/libs/zerodoc"
+
+	v1 "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// SkyWalking SegmentObject的proto3 JSON格式, 由SkyWalking HTTP接口/v3/segment(s)上报
+// 参考: https://github.com/apache/skywalking-data-collect-protocol/blob/master/language-agent/Tracing.proto
+type SkyWalkingSegment struct {
+	TraceID         string           `json:"traceId"`
+	TraceSegmentID  string           `json:"traceSegmentId"`
+	Spans           []SkyWalkingSpan `json:"spans"`
+	Service         string           `json:"service"`
+	ServiceInstance string           `json:"serviceInstance"`
+}
+
+type SkyWalkingSpanRef struct {
+	RefType              SkyWalkingRefType `json:"refType"`
+	TraceID              string            `json:"traceId"`
+	ParentTraceSegmentID string            `json:"parentTraceSegmentId"`
+	ParentSpanID         int32             `json:"parentSpanId"`
+}
+
+type SkyWalkingKeyValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type SkyWalkingLog struct {
+	Time skyWalkingInt64      `json:"time"`
+	Data []SkyWalkingKeyValue `json:"data"`
+}
+
+type SkyWalkingSpan struct {
+	SpanID        int32                `json:"spanId"`
+	ParentSpanID  int32                `json:"parentSpanId"` // 同一segment中的父span, -1表示没有
+	StartTime     skyWalkingInt64      `json:"startTime"`    // ms
+	EndTime       skyWalkingInt64      `json:"endTime"`      // ms
+	Refs          []SkyWalkingSpanRef  `json:"refs"`
+	OperationName string               `json:"operationName"`
+	Peer          string               `json:"peer"`
+	SpanType      SkyWalkingSpanType   `json:"spanType"`
+	SpanLayer     SkyWalkingSpanLayer  `json:"spanLayer"`
+	ComponentID   int32                `json:"componentId"`
+	IsError       bool                 `json:"isError"`
+	Tags          []SkyWalkingKeyValue `json:"tags"`
+	Logs          []SkyWalkingLog      `json:"logs"`
+}
+
+type SkyWalkingSpanType int32
+
+const (
+	SKYWALKING_SPAN_TYPE_ENTRY SkyWalkingSpanType = iota
+	SKYWALKING_SPAN_TYPE_EXIT
+	SKYWALKING_SPAN_TYPE_LOCAL
+)
+
+var skyWalkingSpanTypeNames = []string{"Entry", "Exit", "Local"}
+
+func (t *SkyWalkingSpanType) UnmarshalJSON(data []byte) error {
+	v, err := unmarshalSkyWalkingEnum(data, skyWalkingSpanTypeNames)
+	*t = SkyWalkingSpanType(v)
+	return err
+}
+
+type SkyWalkingSpanLayer int32
+
+const (
+	SKYWALKING_SPAN_LAYER_UNKNOWN SkyWalkingSpanLayer = iota
+	SKYWALKING_SPAN_LAYER_DATABASE
+	SKYWALKING_SPAN_LAYER_RPC_FRAMEWORK
+	SKYWALKING_SPAN_LAYER_HTTP
+	SKYWALKING_SPAN_LAYER_MQ
+	SKYWALKING_SPAN_LAYER_CACHE
+	SKYWALKING_SPAN_LAYER_FAAS
+)
+
+var skyWalkingSpanLayerNames = []string{"Unknown", "Database", "RPCFramework", "Http", "MQ", "Cache", "FAAS"}
+
+func (l *SkyWalkingSpanLayer) UnmarshalJSON(data []byte) error {
+	v, err := unmarshalSkyWalkingEnum(data, skyWalkingSpanLayerNames)
+	*l = SkyWalkingSpanLayer(v)
+	return err
+}
+
+type SkyWalkingRefType int32
+
+const (
+	SKYWALKING_REF_TYPE_CROSS_PROCESS SkyWalkingRefType = iota
+	SKYWALKING_REF_TYPE_CROSS_THREAD
+)
+
+var skyWalkingRefTypeNames = []string{"CrossProcess", "CrossThread"}
+
+func (t *SkyWalkingRefType) UnmarshalJSON(data []byte) error {
+	v, err := unmarshalSkyWalkingEnum(data, skyWalkingRefTypeNames)
+	*t = SkyWalkingRefType(v)
+	return err
+}
+
+// proto3 JSON中枚举为名称, 也可以是数值, 未知的名称按默认值处理
+func unmarshalSkyWalkingEnum(data []byte, names []string) (int32, error) {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		var v int32
+		err = json.Unmarshal(data, &v)
+		return v, err
+	}
+	for i, n := range names {
+		if n == name {
+			return int32(i), nil
+		}
+	}
+	return 0, nil
+}
+
+// proto3 JSON中int64编码为字符串, 也可以是数值
+type skyWalkingInt64 int64
+
+func (i *skyWalkingInt64) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		v, err := strconv.ParseInt(s, 10, 64)
+		*i = skyWalkingInt64(v)
+		return err
+	}
+	var v int64
+	err := json.Unmarshal(data, &v)
+	*i = skyWalkingInt64(v)
+	return err
+}
+
+// DecodeSkyWalkingSegments 解析SkyWalking HTTP接口上报的JSON, /v3/segments为SegmentObject数组, /v3/segment为单个SegmentObject
+func DecodeSkyWalkingSegments(data []byte) ([]*SkyWalkingSegment, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		segment := &SkyWalkingSegment{}
+		if err := json.Unmarshal(data, segment); err != nil {
+			return nil, err
+		}
+		return []*SkyWalkingSegment{segment}, nil
+	}
+	var segments []*SkyWalkingSegment
+	if err := json.Unmarshal(data, &segments); err != nil {
+		return nil, err
+	}
+	return segments, nil
+}
+
+// IsValid 缺少traceId或traceSegmentId的segment无法关联调用链
+func (s *SkyWalkingSegment) IsValid() bool {
+	return s != nil && s.TraceID != "" && s.TraceSegmentID != ""
+}
+
+func SkyWalkingSegmentToL7FlowLogs(vtapID uint16, segment *SkyWalkingSegment, platformData *grpc.PlatformInfoTable) []*L7FlowLog {
+	ret := make([]*L7FlowLog, 0, len(segment.Spans))
+	for i := range segment.Spans {
+		h := AcquireL7FlowLog()
+		h._id = genID(uint32(segment.Spans[i].EndTime/1000), &L7FlowLogCounter, vtapID)
+		h.VtapID = vtapID
+		h.FillSkyWalking(segment, &segment.Spans[i], platformData)
+		ret = append(ret, h)
+	}
+	return ret
+}
+
+func skyWalkingSpanID(segmentID string, spanID int32) string {
+	return segmentID + "-" + strconv.Itoa(int(spanID))
+}
+
+func skyWalkingSpanKind(span *SkyWalkingSpan) v1.Span_SpanKind {
+	switch span.SpanType {
+	case SKYWALKING_SPAN_TYPE_ENTRY:
+		if span.SpanLayer == SKYWALKING_SPAN_LAYER_MQ {
+			return v1.Span_SPAN_KIND_CONSUMER
+		}
+		return v1.Span_SPAN_KIND_SERVER
+	case SKYWALKING_SPAN_TYPE_EXIT:
+		if span.SpanLayer == SKYWALKING_SPAN_LAYER_MQ {
+			return v1.Span_SPAN_KIND_PRODUCER
+		}
+		return v1.Span_SPAN_KIND_CLIENT
+	default:
+		return v1.Span_SPAN_KIND_INTERNAL
+	}
+}
+
+// peer格式为host:port, host可能是IP或域名
+func splitSkyWalkingPeer(peer string) (string, uint16) {
+	host, portStr, err := net.SplitHostPort(peer)
+	if err != nil {
+		return peer, 0
+	}
+	port, _ := strconv.ParseUint(portStr, 10, 16)
+	return host, uint16(port)
+}
+
+func (h *L7FlowLog) FillSkyWalking(segment *SkyWalkingSegment, span *SkyWalkingSpan, platformData *grpc.PlatformInfoTable) {
+	h.fillAppSpanBase(datatype.SIGNAL_SOURCE_SKYWALKING)
+	h.IsIPv4 = true
+	h.TraceId = segment.TraceID
+	h.SpanId = skyWalkingSpanID(segment.TraceSegmentID, span.SpanID)
+	if span.ParentSpanID >= 0 {
+		h.ParentSpanId = skyWalkingSpanID(segment.TraceSegmentID, span.ParentSpanID)
+	} else if len(span.Refs) > 0 {
+		// 跨进程/跨线程调用时, 父Span在其他Segment中
+		h.ParentSpanId = skyWalkingSpanID(span.Refs[0].ParentTraceSegmentID, span.Refs[0].ParentSpanID)
+	}
+	spanKind := skyWalkingSpanKind(span)
+	h.TapSide = spanKindToTapSide(spanKind)
+	h.SpanKind = uint8(spanKind)
+	h.spanKind = &h.SpanKind
+	h.Endpoint = span.OperationName
+	h.AppService = segment.Service
+	h.AppInstance = segment.ServiceInstance
+	h.L7Base.StartTime = int64(span.StartTime) * 1000
+	h.L7Base.EndTime = int64(span.EndTime) * 1000
+	if span.EndTime > span.StartTime {
+		h.ResponseDuration = uint64(span.EndTime-span.StartTime) * 1000
+	}
+
+	if span.Peer != "" {
+		host, port := splitSkyWalkingPeer(span.Peer)
+		if ip := net.ParseIP(host); ip != nil {
+			h.setAppSpanIP(ip, false)
+		} else {
+			h.RequestDomain = host
+		}
+		if h.TapSide == zerodoc.ClientApp.String() {
+			h.ServerPort = port
+		}
+	}
+	// only show data for services as 'server side'
+	if h.TapSide == zerodoc.ServerApp.String() && h.ServerPort == 0 {
+		h.ServerPort = 65535
+	}
+
+	switch span.SpanLayer {
+	case SKYWALKING_SPAN_LAYER_HTTP:
+		h.L7ProtocolStr = "http"
+	case SKYWALKING_SPAN_LAYER_RPC_FRAMEWORK:
+		h.L7ProtocolStr = "grpc"
+	}
+	attributeNames := make([]string, 0, len(span.Tags)+1)
+	attributeValues := make([]string, 0, len(span.Tags)+1)
+	for _, tag := range span.Tags {
+		key, value := tag.Key, tag.Value
+		switch key {
+		case "url":
+			h.RequestResource = value
+		case "http.method":
+			h.RequestType = value
+		case "http.status_code", "http.status.code", "status_code":
+			v, _ := strconv.Atoi(value)
+			h.responseCode = int32(v)
+			h.ResponseCode = &h.responseCode
+		case "db.type":
+			h.L7ProtocolStr = value
+		case "db.statement":
+			h.RequestResource = value
+		case "mq.topic", "mq.queue":
+			h.RequestResource = value
+		case "mq.broker":
+			h.RequestDomain = value
+		}
+		attributeNames = append(attributeNames, key)
+		attributeValues = append(attributeValues, value)
+	}
+	attributeNames = append(attributeNames, "sw.component_id")
+	attributeValues = append(attributeValues, strconv.Itoa(int(span.ComponentID)))
+	h.AttributeNames, h.AttributeValues = attributeNames, attributeValues
+
+	exception := ""
+	for _, spanLog := range span.Logs {
+		for _, kv := range spanLog.Data {
+			if kv.Key == "message" || (kv.Key == "error.kind" && exception == "") {
+				exception = strings.TrimSpace(kv.Value)
+			}
+		}
+	}
+	h.fillL7Protocol()
+	h.fillAppSpanStatus(span.IsError, exception)
+	h.L7Base.KnowledgeGraph.FillOTel(h, platformData)
+}
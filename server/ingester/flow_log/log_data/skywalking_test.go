@@ -0,0 +1,155 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log_data
+
+import (
+	"testing"
+)
+
+// SkyWalking HTTP接口/v3/segments的示例数据
+// 参考: https://skywalking.apache.org/docs/main/latest/en/api/trace-data-protocol-v3/#http-api
+const skyWalkingSegmentsJSON = `[{
+	"traceId": "a12ff60b-5807-463b-a1f8-fb1c8608219e",
+	"serviceInstance": "User_Service_Instance_Name",
+	"spans": [{
+		"operationName": "/ingress",
+		"startTime": 1588664577013,
+		"endTime": 1588664577028,
+		"spanType": "Exit",
+		"spanId": 1,
+		"isError": false,
+		"parentSpanId": 0,
+		"componentId": 6000,
+		"peer": "upstream service",
+		"spanLayer": "Http"
+	}, {
+		"operationName": "/ingress",
+		"startTime": 1588664577013,
+		"tags": [{
+			"key": "http.method",
+			"value": "GET"
+		}, {
+			"key": "http.params",
+			"value": "http://localhost/ingress"
+		}],
+		"endTime": 1588664577028,
+		"spanType": "Entry",
+		"spanId": 0,
+		"parentSpanId": -1,
+		"isError": false,
+		"spanLayer": "Http",
+		"componentId": 6000
+	}],
+	"service": "User_Service_Name",
+	"traceSegmentId": "a12ff60b-5807-463b-a1f8-fb1c8608219e"
+}]`
+
+// proto3 JSON格式的单个SegmentObject, int64编码为字符串, 省略默认值
+const skyWalkingSegmentJSON = `{
+	"traceId": "trace-1",
+	"traceSegmentId": "seg-1",
+	"spans": [{
+		"spanId": 1,
+		"startTime": "1588664577100",
+		"endTime": "1588664577150",
+		"refs": [{
+			"refType": "CrossProcess",
+			"traceId": "trace-1",
+			"parentTraceSegmentId": "parent-seg",
+			"parentSpanId": 2,
+			"parentService": "gateway"
+		}],
+		"operationName": "/order",
+		"peer": "10.1.1.1:8080",
+		"spanType": "Exit",
+		"spanLayer": 3,
+		"componentId": 2,
+		"isError": true,
+		"tags": [{"key": "http.method", "value": "POST"}],
+		"logs": [{"time": "1588664577120", "data": [{"key": "error.kind", "value": "java.lang.NullPointerException"}, {"key": "message", "value": "NullPointerException"}]}],
+		"skipAnalysis": false
+	}],
+	"service": "order-service",
+	"serviceInstance": "instance-1",
+	"isSizeLimited": false
+}`
+
+func TestDecodeSkyWalkingSegments(t *testing.T) {
+	segments, err := DecodeSkyWalkingSegments([]byte(skyWalkingSegmentsJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 1 || !segments[0].IsValid() {
+		t.Fatalf("unexpected segments %+v", segments)
+	}
+	s := segments[0]
+	if s.Service != "User_Service_Name" || s.ServiceInstance != "User_Service_Instance_Name" || len(s.Spans) != 2 {
+		t.Fatalf("unexpected segment %+v", s)
+	}
+	exit, entry := &s.Spans[0], &s.Spans[1]
+	if exit.SpanType != SKYWALKING_SPAN_TYPE_EXIT || exit.SpanLayer != SKYWALKING_SPAN_LAYER_HTTP || exit.ParentSpanID != 0 || exit.ComponentID != 6000 {
+		t.Errorf("unexpected exit span %+v", exit)
+	}
+	if skyWalkingSpanKind(exit).String() != "SPAN_KIND_CLIENT" {
+		t.Errorf("unexpected span kind %s", skyWalkingSpanKind(exit))
+	}
+	if host, port := splitSkyWalkingPeer(exit.Peer); host != "upstream service" || port != 0 {
+		t.Errorf("unexpected peer %s %d", host, port)
+	}
+	if entry.ParentSpanID != -1 || entry.StartTime != 1588664577013 || entry.EndTime != 1588664577028 {
+		t.Errorf("unexpected entry span %+v", entry)
+	}
+	if len(entry.Tags) != 2 || entry.Tags[0] != (SkyWalkingKeyValue{"http.method", "GET"}) {
+		t.Errorf("unexpected tags %v", entry.Tags)
+	}
+	if skyWalkingSpanKind(entry).String() != "SPAN_KIND_SERVER" {
+		t.Errorf("unexpected span kind %s", skyWalkingSpanKind(entry))
+	}
+
+	segments, err = DecodeSkyWalkingSegments([]byte(skyWalkingSegmentJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 1 || segments[0].TraceSegmentID != "seg-1" || len(segments[0].Spans) != 1 {
+		t.Fatalf("unexpected segments %+v", segments)
+	}
+	sp := &segments[0].Spans[0]
+	if sp.ParentSpanID != 0 || sp.StartTime != 1588664577100 || sp.EndTime != 1588664577150 || !sp.IsError {
+		t.Errorf("unexpected span %+v", sp)
+	}
+	if sp.SpanLayer != SKYWALKING_SPAN_LAYER_HTTP {
+		t.Errorf("unexpected span layer %d", sp.SpanLayer)
+	}
+	if len(sp.Refs) != 1 || sp.Refs[0].RefType != SKYWALKING_REF_TYPE_CROSS_PROCESS ||
+		skyWalkingSpanID(sp.Refs[0].ParentTraceSegmentID, sp.Refs[0].ParentSpanID) != "parent-seg-2" {
+		t.Errorf("unexpected refs %+v", sp.Refs)
+	}
+	if len(sp.Logs) != 1 || sp.Logs[0].Time != 1588664577120 || len(sp.Logs[0].Data) != 2 {
+		t.Errorf("unexpected logs %+v", sp.Logs)
+	}
+	if host, port := splitSkyWalkingPeer(sp.Peer); host != "10.1.1.1" || port != 8080 {
+		t.Errorf("unexpected peer %s %d", host, port)
+	}
+
+	segments, err = DecodeSkyWalkingSegments([]byte(`[{"spans": []}]`))
+	if err != nil || len(segments) != 1 || segments[0].IsValid() {
+		t.Errorf("segment without ids should be decoded as invalid, got %+v, %v", segments, err)
+	}
+	if _, err := DecodeSkyWalkingSegments([]byte(skyWalkingSegmentsJSON[:50])); err == nil {
+		t.Error("expect error for truncated data")
+	}
+}
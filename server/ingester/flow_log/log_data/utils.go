@@ -16,8 +16,54 @@
 
 package log_data
 
-import "net"
+import (
+	"net"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
 
 func IPIntToString(ipInt uint32) string {
 	return net.IPv4(byte(ipInt>>24), byte(ipInt>>16), byte(ipInt>>8), byte(ipInt)).String()
 }
+
+type protoField struct {
+	num   protowire.Number
+	typ   protowire.Type
+	value uint64 // VarintType, Fixed32Type, Fixed64Type
+	bytes []byte // BytesType
+}
+
+// rangeProtoFields 按protobuf编码逐个解析消息的字段, 用于无需引入完整定义的外部协议
+func rangeProtoFields(b []byte, fn func(f *protoField) error) error {
+	var f protoField
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		f = protoField{num: num, typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			f.value, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(b)
+			f.value = uint64(v)
+		case protowire.Fixed64Type:
+			f.value, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if err := fn(&f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
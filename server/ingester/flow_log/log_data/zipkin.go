@@ -0,0 +1,293 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log_data
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/deepflowio/deepflow/server/libs/datatype"
+	"github.com/deepflowio/deepflow/server/libs/grpc"
+	"github.com/deepflowio/deepflow/server/libs/zerodoc"
+
+	v1 "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Zipkin v2 Span, 参考: https://github.com/openzipkin/zipkin-api/blob/master/zipkin2-api.yaml
+type ZipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+	IPv4        string `json:"ipv4"`
+	IPv6        string `json:"ipv6"`
+	Port        uint16 `json:"port"`
+}
+
+type ZipkinSpan struct {
+	TraceID        string            `json:"traceId"`
+	ID             string            `json:"id"`
+	ParentID       string            `json:"parentId"`
+	Kind           string            `json:"kind"`
+	Name           string            `json:"name"`
+	Timestamp      uint64            `json:"timestamp"` // us
+	Duration       uint64            `json:"duration"`  // us
+	LocalEndpoint  *ZipkinEndpoint   `json:"localEndpoint"`
+	RemoteEndpoint *ZipkinEndpoint   `json:"remoteEndpoint"`
+	Tags           map[string]string `json:"tags"`
+}
+
+const (
+	ZIPKIN_KIND_CLIENT   = "CLIENT"
+	ZIPKIN_KIND_SERVER   = "SERVER"
+	ZIPKIN_KIND_PRODUCER = "PRODUCER"
+	ZIPKIN_KIND_CONSUMER = "CONSUMER"
+)
+
+// zipkin.proto中Span.Kind的枚举值
+var zipkinProtoKinds = []string{"", ZIPKIN_KIND_CLIENT, ZIPKIN_KIND_SERVER, ZIPKIN_KIND_PRODUCER, ZIPKIN_KIND_CONSUMER}
+
+// DecodeZipkinSpans 解析Zipkin v2的JSON数组或proto3的ListOfSpans
+func DecodeZipkinSpans(data []byte) ([]ZipkinSpan, error) {
+	// proto编码首字节为'\n'(字段1的tag), 与JSON前的空白无法区分, JSON解析失败时再按proto解析
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var spans []ZipkinSpan
+		err := json.Unmarshal(trimmed, &spans)
+		if err == nil || data[0] != '\n' {
+			return spans, err
+		}
+	}
+	return decodeZipkinProtoSpans(data)
+}
+
+func decodeZipkinProtoSpans(data []byte) ([]ZipkinSpan, error) {
+	spans := []ZipkinSpan{}
+	err := rangeProtoFields(data, func(f *protoField) error {
+		if f.num != 1 || f.typ != protowire.BytesType {
+			return nil
+		}
+		span, err := decodeZipkinProtoSpan(f.bytes)
+		if err != nil {
+			return err
+		}
+		spans = append(spans, span)
+		return nil
+	})
+	return spans, err
+}
+
+func decodeZipkinProtoSpan(data []byte) (ZipkinSpan, error) {
+	span := ZipkinSpan{}
+	err := rangeProtoFields(data, func(f *protoField) error {
+		switch f.num {
+		case 1:
+			span.TraceID = hex.EncodeToString(f.bytes)
+		case 2:
+			span.ParentID = hex.EncodeToString(f.bytes)
+		case 3:
+			span.ID = hex.EncodeToString(f.bytes)
+		case 4:
+			if f.value < uint64(len(zipkinProtoKinds)) {
+				span.Kind = zipkinProtoKinds[f.value]
+			}
+		case 5:
+			span.Name = string(f.bytes)
+		case 6:
+			span.Timestamp = f.value
+		case 7:
+			span.Duration = f.value
+		case 8, 9:
+			endpoint, err := decodeZipkinProtoEndpoint(f.bytes)
+			if err != nil {
+				return err
+			}
+			if f.num == 8 {
+				span.LocalEndpoint = endpoint
+			} else {
+				span.RemoteEndpoint = endpoint
+			}
+		case 11:
+			var key, value string
+			if err := rangeProtoFields(f.bytes, func(entry *protoField) error {
+				if entry.num == 1 {
+					key = string(entry.bytes)
+				} else if entry.num == 2 {
+					value = string(entry.bytes)
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			if span.Tags == nil {
+				span.Tags = make(map[string]string)
+			}
+			span.Tags[key] = value
+		}
+		return nil
+	})
+	return span, err
+}
+
+func decodeZipkinProtoEndpoint(data []byte) (*ZipkinEndpoint, error) {
+	endpoint := &ZipkinEndpoint{}
+	err := rangeProtoFields(data, func(f *protoField) error {
+		switch f.num {
+		case 1:
+			endpoint.ServiceName = string(f.bytes)
+		case 2:
+			if len(f.bytes) != net.IPv4len {
+				return fmt.Errorf("invalid zipkin endpoint ipv4 length %d", len(f.bytes))
+			}
+			endpoint.IPv4 = net.IP(f.bytes).String()
+		case 3:
+			if len(f.bytes) != net.IPv6len {
+				return fmt.Errorf("invalid zipkin endpoint ipv6 length %d", len(f.bytes))
+			}
+			endpoint.IPv6 = net.IP(f.bytes).String()
+		case 4:
+			endpoint.Port = uint16(f.value)
+		}
+		return nil
+	})
+	return endpoint, err
+}
+
+func ZipkinSpansToL7FlowLogs(vtapID uint16, spans []ZipkinSpan, platformData *grpc.PlatformInfoTable) []*L7FlowLog {
+	ret := make([]*L7FlowLog, 0, len(spans))
+	for i := range spans {
+		h := AcquireL7FlowLog()
+		h._id = genID(uint32((spans[i].Timestamp+spans[i].Duration)/1000000), &L7FlowLogCounter, vtapID)
+		h.VtapID = vtapID
+		h.FillZipkin(&spans[i], platformData)
+		ret = append(ret, h)
+	}
+	return ret
+}
+
+func zipkinKindToSpanKind(kind string) v1.Span_SpanKind {
+	// 部分SDK上报的kind为小写
+	switch strings.ToUpper(kind) {
+	case ZIPKIN_KIND_CLIENT:
+		return v1.Span_SPAN_KIND_CLIENT
+	case ZIPKIN_KIND_SERVER:
+		return v1.Span_SPAN_KIND_SERVER
+	case ZIPKIN_KIND_PRODUCER:
+		return v1.Span_SPAN_KIND_PRODUCER
+	case ZIPKIN_KIND_CONSUMER:
+		return v1.Span_SPAN_KIND_CONSUMER
+	default:
+		return v1.Span_SPAN_KIND_UNSPECIFIED
+	}
+}
+
+func (e *ZipkinEndpoint) ip() net.IP {
+	if e.IPv4 != "" {
+		return net.ParseIP(e.IPv4)
+	}
+	return net.ParseIP(e.IPv6)
+}
+
+func (h *L7FlowLog) FillZipkin(span *ZipkinSpan, platformData *grpc.PlatformInfoTable) {
+	h.fillAppSpanBase(datatype.SIGNAL_SOURCE_ZIPKIN)
+	h.IsIPv4 = true
+	h.TraceId = span.TraceID
+	h.SpanId = span.ID
+	h.ParentSpanId = span.ParentID
+	spanKind := zipkinKindToSpanKind(span.Kind)
+	h.TapSide = spanKindToTapSide(spanKind)
+	h.Endpoint = span.Name
+	h.SpanKind = uint8(spanKind)
+	h.spanKind = &h.SpanKind
+	h.L7Base.StartTime = int64(span.Timestamp)
+	h.L7Base.EndTime = int64(span.Timestamp + span.Duration)
+	h.ResponseDuration = span.Duration
+
+	if local := span.LocalEndpoint; local != nil {
+		h.AppService = local.ServiceName
+		if ip := local.ip(); ip != nil {
+			h.AppInstance = ip.String()
+			h.setAppSpanIP(ip, true)
+		}
+		if h.TapSide == zerodoc.ServerApp.String() {
+			h.ServerPort = local.Port
+		}
+	}
+	if remote := span.RemoteEndpoint; remote != nil {
+		if ip := remote.ip(); ip != nil {
+			h.setAppSpanIP(ip, false)
+		}
+		if h.TapSide == zerodoc.ClientApp.String() {
+			h.ServerPort = remote.Port
+		}
+	}
+	// only show data for services as 'server side'
+	if h.TapSide == zerodoc.ServerApp.String() && h.ServerPort == 0 {
+		h.ServerPort = 65535
+	}
+
+	errorMessage, isError := "", false
+	// map遍历顺序随机, 按key排序使attribute顺序及重复tag的取值稳定
+	keys := make([]string, 0, len(span.Tags))
+	for key := range span.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	attributeNames, attributeValues := make([]string, 0, len(span.Tags)), make([]string, 0, len(span.Tags))
+	for _, key := range keys {
+		value := span.Tags[key]
+		switch key {
+		case "http.method":
+			h.RequestType = value
+			if h.L7ProtocolStr == "" {
+				h.L7ProtocolStr = "http"
+			}
+		case "http.path":
+			h.RequestResource = value
+		case "http.host":
+			h.RequestDomain = value
+		case "http.status_code":
+			v, _ := strconv.Atoi(value)
+			h.responseCode = int32(v)
+			h.ResponseCode = &h.responseCode
+		case "sql.query", "db.statement":
+			h.RequestResource = value
+		case "rpc.method":
+			h.RequestType = value
+		case "rpc.service":
+			h.RequestResource = value
+		case "db.system", "rpc.system", "messaging.system":
+			h.L7ProtocolStr = value
+		case "error":
+			isError, errorMessage = true, value
+		}
+		attributeNames = append(attributeNames, key)
+		attributeValues = append(attributeValues, value)
+	}
+	if h.RequestResource == "" {
+		if u, ok := span.Tags["http.url"]; ok {
+			h.RequestResource = u
+		}
+	}
+	h.AttributeNames, h.AttributeValues = attributeNames, attributeValues
+	h.fillL7Protocol()
+	h.fillAppSpanStatus(isError, errorMessage)
+	h.L7Base.KnowledgeGraph.FillOTel(h, platformData)
+}
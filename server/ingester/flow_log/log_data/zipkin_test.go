@@ -0,0 +1,107 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log_data
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestDecodeZipkinJSON(t *testing.T) {
+	data := []byte(` [{"traceId":"5af7183fb1d4cf5f","id":"352bff9a74ca9ad2","parentId":"6b221d5bc9e6496c","kind":"SERVER","name":"get /api","timestamp":1556604172355737,"duration":1431,"localEndpoint":{"serviceName":"backend","ipv4":"192.168.99.1","port":8080},"tags":{"http.method":"GET","http.path":"/api","http.status_code":"500"}}]`)
+	spans, err := DecodeZipkinSpans(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spans) != 1 {
+		t.Fatalf("expect 1 span, got %d", len(spans))
+	}
+	s := spans[0]
+	if s.TraceID != "5af7183fb1d4cf5f" || s.ParentID != "6b221d5bc9e6496c" || s.Kind != ZIPKIN_KIND_SERVER {
+		t.Errorf("unexpected span %+v", s)
+	}
+	if s.LocalEndpoint == nil || s.LocalEndpoint.Port != 8080 || s.LocalEndpoint.ip().String() != "192.168.99.1" {
+		t.Errorf("unexpected local endpoint %+v", s.LocalEndpoint)
+	}
+	if s.Tags["http.status_code"] != "500" {
+		t.Errorf("unexpected tags %v", s.Tags)
+	}
+}
+
+func TestDecodeZipkinProto(t *testing.T) {
+	var endpoint []byte
+	endpoint = protowire.AppendTag(endpoint, 1, protowire.BytesType)
+	endpoint = protowire.AppendString(endpoint, "frontend")
+	endpoint = protowire.AppendTag(endpoint, 2, protowire.BytesType)
+	endpoint = protowire.AppendBytes(endpoint, []byte{10, 0, 0, 1})
+	endpoint = protowire.AppendTag(endpoint, 4, protowire.VarintType)
+	endpoint = protowire.AppendVarint(endpoint, 80)
+
+	var tag []byte
+	tag = protowire.AppendTag(tag, 1, protowire.BytesType)
+	tag = protowire.AppendString(tag, "error")
+	tag = protowire.AppendTag(tag, 2, protowire.BytesType)
+	tag = protowire.AppendString(tag, "timeout")
+
+	var span []byte
+	span = protowire.AppendTag(span, 1, protowire.BytesType)
+	span = protowire.AppendBytes(span, []byte{0x01, 0x02})
+	span = protowire.AppendTag(span, 3, protowire.BytesType)
+	span = protowire.AppendBytes(span, []byte{0x0a, 0x0b})
+	span = protowire.AppendTag(span, 4, protowire.VarintType)
+	span = protowire.AppendVarint(span, 1)
+	span = protowire.AppendTag(span, 5, protowire.BytesType)
+	span = protowire.AppendString(span, "get")
+	span = protowire.AppendTag(span, 6, protowire.Fixed64Type)
+	span = protowire.AppendFixed64(span, 1000000)
+	span = protowire.AppendTag(span, 7, protowire.VarintType)
+	span = protowire.AppendVarint(span, 200)
+	span = protowire.AppendTag(span, 8, protowire.BytesType)
+	span = protowire.AppendBytes(span, endpoint)
+	span = protowire.AppendTag(span, 11, protowire.BytesType)
+	span = protowire.AppendBytes(span, tag)
+
+	var list []byte
+	list = protowire.AppendTag(list, 1, protowire.BytesType)
+	list = protowire.AppendBytes(list, span)
+
+	spans, err := DecodeZipkinSpans(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spans) != 1 {
+		t.Fatalf("expect 1 span, got %d", len(spans))
+	}
+	s := spans[0]
+	if s.TraceID != "0102" || s.ID != "0a0b" || s.Kind != ZIPKIN_KIND_CLIENT || s.Name != "get" {
+		t.Errorf("unexpected span %+v", s)
+	}
+	if s.Timestamp != 1000000 || s.Duration != 200 {
+		t.Errorf("unexpected time %d %d", s.Timestamp, s.Duration)
+	}
+	if s.LocalEndpoint == nil || s.LocalEndpoint.ServiceName != "frontend" || s.LocalEndpoint.IPv4 != "10.0.0.1" || s.LocalEndpoint.Port != 80 {
+		t.Errorf("unexpected local endpoint %+v", s.LocalEndpoint)
+	}
+	if s.Tags["error"] != "timeout" {
+		t.Errorf("unexpected tags %v", s.Tags)
+	}
+
+	if _, err := DecodeZipkinSpans(list[:len(list)-3]); err == nil {
+		t.Error("expect error for truncated data")
+	}
+}
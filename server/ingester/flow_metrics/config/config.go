@@ -36,6 +36,7 @@ const (
 	DefaultCKReadTimeout        = 300
 	DefaultFlowMetrics1MTTL     = 168 // hour
 	DefaultFlowMetrics1STTL     = 24  // hour
+	DefaultBackfillHorizon      = 24  // hour
 )
 
 type PCapConfig struct {
@@ -49,6 +50,13 @@ type FlowMetricsTTL struct {
 	VtapApp1S  int `yaml:"vtap-app-1s"`
 }
 
+// BackfillConfig 延迟到达(超过5分钟)的数据, 在回填时限内标记后写入独立的ck writer, 超过时限则不做标记
+type BackfillConfig struct {
+	Enabled        bool                  `yaml:"enabled"`
+	HorizonHour    int                   `yaml:"horizon-hour"`
+	CKWriterConfig config.CKWriterConfig `yaml:"ck-writer"`
+}
+
 type Config struct {
 	Base                 *config.Config
 	CKReadTimeout        int                   `yaml:"ck-read-timeout"`
@@ -59,6 +67,7 @@ type Config struct {
 	UnmarshallQueueSize  int                   `yaml:"unmarshall-queue-size"`
 	ReceiverWindowSize   uint64                `yaml:"receiver-window-size"`
 	FlowMetricsTTL       FlowMetricsTTL        `yaml:"flow-metrics-ttl-hour"`
+	Backfill             BackfillConfig        `yaml:"metrics-backfill"`
 }
 
 type FlowMetricsConfig struct {
@@ -86,6 +95,10 @@ func (c *Config) Validate() error {
 		c.FlowMetricsTTL.VtapApp1S = DefaultFlowMetrics1STTL
 	}
 
+	if c.Backfill.HorizonHour <= 0 {
+		c.Backfill.HorizonHour = DefaultBackfillHorizon
+	}
+
 	return nil
 }
 
@@ -99,6 +112,10 @@ func Load(base *config.Config, path string) *Config {
 			UnmarshallQueueSize:  DefaultUnmarshallQueueSize,
 			ReceiverWindowSize:   DefaultReceiverWindowSize,
 			FlowMetricsTTL:       FlowMetricsTTL{DefaultFlowMetrics1MTTL, DefaultFlowMetrics1STTL, DefaultFlowMetrics1MTTL, DefaultFlowMetrics1STTL},
+			Backfill: BackfillConfig{
+				HorizonHour:    DefaultBackfillHorizon,
+				CKWriterConfig: config.CKWriterConfig{QueueCount: 1, QueueSize: 100000, BatchSize: 51200, FlushTimeout: 10},
+			},
 
 			Pcap: PCapConfig{common.DEFAULT_PCAP_DATA_PATH},
 		},
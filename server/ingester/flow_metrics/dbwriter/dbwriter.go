@@ -39,6 +39,15 @@ type DbWriter struct {
 }
 
 func NewDbWriter(addrs []string, user, password, clusterName, storagePolicy, timeZone string, ckWriterCfg config.CKWriterConfig, flowMetricsTtl flowmetricsconfig.FlowMetricsTTL, coldStorages map[string]*ckdb.ColdStorage) (*DbWriter, error) {
+	return newDbWriter(addrs, user, password, clusterName, storagePolicy, timeZone, ckWriterCfg, flowMetricsTtl, coldStorages, "")
+}
+
+// NewBackfillDbWriter 回填数据使用独立的ck writer写入相同的表, 避免影响实时数据的写入
+func NewBackfillDbWriter(addrs []string, user, password, clusterName, storagePolicy, timeZone string, ckWriterCfg config.CKWriterConfig, flowMetricsTtl flowmetricsconfig.FlowMetricsTTL, coldStorages map[string]*ckdb.ColdStorage) (*DbWriter, error) {
+	return newDbWriter(addrs, user, password, clusterName, storagePolicy, timeZone, ckWriterCfg, flowMetricsTtl, coldStorages, "_backfill")
+}
+
+func newDbWriter(addrs []string, user, password, clusterName, storagePolicy, timeZone string, ckWriterCfg config.CKWriterConfig, flowMetricsTtl flowmetricsconfig.FlowMetricsTTL, coldStorages map[string]*ckdb.ColdStorage, counterSuffix string) (*DbWriter, error) {
 	ckwriters := []*ckwriter.CKWriter{}
 	tables := zerodoc.GetMetricsTables(ckdb.MergeTree, common.CK_VERSION, clusterName, storagePolicy, flowMetricsTtl.VtapFlow1M, flowMetricsTtl.VtapFlow1S, flowMetricsTtl.VtapApp1M, flowMetricsTtl.VtapApp1S, coldStorages)
 	for _, table := range tables {
@@ -50,7 +59,7 @@ func NewDbWriter(addrs []string, user, password, clusterName, storagePolicy, tim
 		} else if table.ID >= uint8(zerodoc.VTAP_APP_PORT_1M) && table.ID <= uint8(zerodoc.VTAP_APP_EDGE_PORT_1M) {
 			counterName = "app_1m"
 		}
-		ckwriter, err := ckwriter.NewCKWriter(addrs, user, password, counterName, timeZone, table,
+		ckwriter, err := ckwriter.NewCKWriter(addrs, user, password, counterName+counterSuffix, timeZone, table,
 			ckWriterCfg.QueueCount, ckWriterCfg.QueueSize, ckWriterCfg.BatchSize, ckWriterCfg.FlushTimeout)
 		if err != nil {
 			log.Error(err)
@@ -41,6 +41,8 @@ type FlowMetrics struct {
 	unmarshallers []*unmarshaller.Unmarshaller
 	platformDatas []*grpc.PlatformInfoTable
 	dbwriter      *dbwriter.DbWriter
+	// 开启回填时有效
+	backfillDbwriter *dbwriter.DbWriter
 }
 
 func NewFlowMetrics(cfg *config.Config, recv *receiver.Receiver, platformDataManager *grpc.PlatformDataManager) (*FlowMetrics, error) {
@@ -64,6 +66,17 @@ func NewFlowMetrics(cfg *config.Config, recv *receiver.Receiver, platformDataMan
 		return nil, err
 	}
 
+	var backfill *unmarshaller.Backfill
+	if cfg.Backfill.Enabled {
+		flowMetrics.backfillDbwriter, err = dbwriter.NewBackfillDbWriter(cfg.Base.CKDB.ActualAddrs, cfg.Base.CKDBAuth.Username, cfg.Base.CKDBAuth.Password, cfg.Base.CKDB.ClusterName, cfg.Base.CKDB.StoragePolicy, cfg.Base.CKDB.TimeZone,
+			cfg.Backfill.CKWriterConfig, cfg.FlowMetricsTTL, cfg.Base.GetCKDBColdStorages())
+		if err != nil {
+			log.Error(err)
+			return nil, err
+		}
+		backfill = unmarshaller.NewBackfill(cfg, flowMetrics.backfillDbwriter)
+	}
+
 	flowMetrics.unmarshallers = make([]*unmarshaller.Unmarshaller, unmarshallQueueCount)
 	flowMetrics.platformDatas = make([]*grpc.PlatformInfoTable, unmarshallQueueCount)
 	for i := 0; i < unmarshallQueueCount; i++ {
@@ -77,7 +90,7 @@ func NewFlowMetrics(cfg *config.Config, recv *receiver.Receiver, platformDataMan
 		if err != nil {
 			return nil, err
 		}
-		flowMetrics.unmarshallers[i] = unmarshaller.NewUnmarshaller(i, flowMetrics.platformDatas[i], cfg.DisableSecondWrite, libqueue.QueueReader(unmarshallQueues.FixedMultiQueue[i]), flowMetrics.dbwriter)
+		flowMetrics.unmarshallers[i] = unmarshaller.NewUnmarshaller(i, flowMetrics.platformDatas[i], cfg.DisableSecondWrite, libqueue.QueueReader(unmarshallQueues.FixedMultiQueue[i]), flowMetrics.dbwriter, backfill)
 	}
 
 	return &flowMetrics, nil
@@ -95,5 +108,8 @@ func (r *FlowMetrics) Close() error {
 		r.platformDatas[i].ClosePlatformInfoTable()
 	}
 	r.dbwriter.Close()
+	if r.backfillDbwriter != nil {
+		r.backfillDbwriter.Close()
+	}
 	return nil
 }
@@ -0,0 +1,62 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package unmarshaller
+
+import (
+	"github.com/deepflowio/deepflow/server/ingester/flow_metrics/config"
+	"github.com/deepflowio/deepflow/server/ingester/flow_metrics/dbwriter"
+	"github.com/deepflowio/deepflow/server/libs/zerodoc"
+)
+
+const VTAP_ACL_TTL = 7 // hour, 与zerodoc.GetMetricsTables中vtap_acl的ttl一致
+
+// Backfill 延迟超过DOC_TIME_EXCEED的数据, 在回填时限内标记_backfill后通过独立的dbwriter写入
+type Backfill struct {
+	horizons [zerodoc.VTAP_TABLE_ID_MAX]int64 // 每个表允许的最大延迟(秒), 不超过表的TTL
+	dbwriter *dbwriter.DbWriter
+}
+
+func NewBackfill(cfg *config.Config, dbwriter *dbwriter.DbWriter) *Backfill {
+	b := &Backfill{dbwriter: dbwriter}
+	ttls := [zerodoc.VTAP_TABLE_ID_MAX]int{
+		zerodoc.VTAP_FLOW_PORT_1M:      cfg.FlowMetricsTTL.VtapFlow1M,
+		zerodoc.VTAP_FLOW_EDGE_PORT_1M: cfg.FlowMetricsTTL.VtapFlow1M,
+		zerodoc.VTAP_APP_PORT_1M:       cfg.FlowMetricsTTL.VtapApp1M,
+		zerodoc.VTAP_APP_EDGE_PORT_1M:  cfg.FlowMetricsTTL.VtapApp1M,
+		zerodoc.VTAP_ACL_1M:            VTAP_ACL_TTL,
+		zerodoc.VTAP_FLOW_PORT_1S:      cfg.FlowMetricsTTL.VtapFlow1S,
+		zerodoc.VTAP_FLOW_EDGE_PORT_1S: cfg.FlowMetricsTTL.VtapFlow1S,
+		zerodoc.VTAP_APP_PORT_1S:       cfg.FlowMetricsTTL.VtapApp1S,
+		zerodoc.VTAP_APP_EDGE_PORT_1S:  cfg.FlowMetricsTTL.VtapApp1S,
+	}
+	for i, ttl := range ttls {
+		horizon := cfg.Backfill.HorizonHour
+		if ttl > 0 && ttl < horizon {
+			horizon = ttl
+		}
+		b.horizons[i] = int64(horizon) * 3600
+	}
+	return b
+}
+
+// Accept 判断延迟的数据是否在表的回填时限内
+func (b *Backfill) Accept(tableID uint8, delay int64) bool {
+	if int(tableID) >= len(b.horizons) {
+		return false
+	}
+	return delay <= b.horizons[tableID]
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package unmarshaller
+
+import (
+	"testing"
+
+	"github.com/deepflowio/deepflow/server/ingester/flow_metrics/config"
+	"github.com/deepflowio/deepflow/server/libs/zerodoc"
+)
+
+func TestBackfillAccept(t *testing.T) {
+	cfg := &config.Config{
+		FlowMetricsTTL: config.FlowMetricsTTL{VtapFlow1M: 168, VtapFlow1S: 4, VtapApp1M: 168, VtapApp1S: 24},
+		Backfill:       config.BackfillConfig{Enabled: true, HorizonHour: 12},
+	}
+	b := NewBackfill(cfg, nil)
+
+	cases := []struct {
+		tableID zerodoc.MetricsTableID
+		delay   int64
+		accept  bool
+	}{
+		{zerodoc.VTAP_FLOW_PORT_1M, 600, true},
+		{zerodoc.VTAP_FLOW_PORT_1M, 12 * 3600, true},
+		{zerodoc.VTAP_FLOW_PORT_1M, 12*3600 + 1, false},
+		// 秒级表的TTL小于回填时限
+		{zerodoc.VTAP_FLOW_EDGE_PORT_1S, 4 * 3600, true},
+		{zerodoc.VTAP_FLOW_EDGE_PORT_1S, 5 * 3600, false},
+		{zerodoc.VTAP_APP_PORT_1S, 12 * 3600, true},
+		{zerodoc.VTAP_ACL_1M, 8 * 3600, false},
+		{zerodoc.VTAP_TABLE_ID_MAX, 600, false},
+	}
+	for _, c := range cases {
+		if got := b.Accept(uint8(c.tableID), c.delay); got != c.accept {
+			t.Errorf("Accept(%d, %d) = %v, expected %v", c.tableID, c.delay, got, c.accept)
+		}
+	}
+}
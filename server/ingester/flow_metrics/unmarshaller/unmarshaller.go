@@ -63,6 +63,9 @@ type Counter struct {
 	TotalTime       int64 `statsd:"total-time"`
 	AvgTime         int64 `statsd:"avg-time"`
 
+	BackfillDocCount    int64 `statsd:"backfill-doc-count"`
+	BackfillExceedCount int64 `statsd:"backfill-exceed-doc-count"`
+
 	FlowPortCount       int64 `statsd:"vtap-flow-port"`
 	FlowPort1sCount     int64 `statsd:"vtap-flow-port-1s"`
 	FlowEdgePortCount   int64 `statsd:"vtap-flow-edge-port"`
@@ -78,12 +81,14 @@ type Unmarshaller struct {
 	unmarshallQueue    queue.QueueReader
 	dbwriter           *dbwriter.DbWriter
 	queueBatchCache    QueueCache
+	backfill           *Backfill
+	backfillBatchCache QueueCache
 	counter            *Counter
 	tableCounter       [zerodoc.VTAP_TABLE_ID_MAX + 1]int64
 	utils.Closable
 }
 
-func NewUnmarshaller(index int, platformData *grpc.PlatformInfoTable, disableSecondWrite bool, unmarshallQueue queue.QueueReader, dbwriter *dbwriter.DbWriter) *Unmarshaller {
+func NewUnmarshaller(index int, platformData *grpc.PlatformInfoTable, disableSecondWrite bool, unmarshallQueue queue.QueueReader, dbwriter *dbwriter.DbWriter, backfill *Backfill) *Unmarshaller {
 	return &Unmarshaller{
 		index:              index,
 		platformData:       platformData,
@@ -91,6 +96,7 @@ func NewUnmarshaller(index int, platformData *grpc.PlatformInfoTable, disableSec
 		unmarshallQueue:    unmarshallQueue,
 		counter:            &Counter{MaxDelay: -3600, MinDelay: 3600},
 		dbwriter:           dbwriter,
+		backfill:           backfill,
 	}
 }
 
@@ -108,8 +114,7 @@ func min(a, b int64) int64 {
 	return b
 }
 
-func (u *Unmarshaller) isGoodDocument(docTime int64) bool {
-	delay := time.Now().Unix() - docTime
+func (u *Unmarshaller) isGoodDocument(delay int64) bool {
 	u.counter.DocCount++
 	u.counter.AverageDelay += delay
 	u.counter.MaxDelay = max(u.counter.MaxDelay, delay)
@@ -157,12 +162,28 @@ func (u *Unmarshaller) putStoreQueue(doc *app.Document) {
 	}
 }
 
+func (u *Unmarshaller) putBackfillQueue(doc *app.Document) {
+	queueCache := &u.backfillBatchCache
+	queueCache.values = append(queueCache.values, doc)
+
+	if len(queueCache.values) >= QUEUE_BATCH_SIZE {
+		u.backfill.dbwriter.Put(queueCache.values...)
+		queueCache.values = queueCache.values[:0]
+	}
+}
+
 func (u *Unmarshaller) flushStoreQueue() {
 	queueCache := &u.queueBatchCache
 	if len(queueCache.values) > 0 {
 		u.dbwriter.Put(queueCache.values...)
 		queueCache.values = queueCache.values[:0]
 	}
+
+	queueCache = &u.backfillBatchCache
+	if len(queueCache.values) > 0 {
+		u.backfill.dbwriter.Put(queueCache.values...)
+		queueCache.values = queueCache.values[:0]
+	}
 }
 
 func DecodeForQueueMonitor(item interface{}) (interface{}, error) {
@@ -227,7 +248,8 @@ func (u *Unmarshaller) QueueProcess() {
 						log.Warningf("Decode failed, bytes len=%d err=%s", len([]byte(bytes)), err)
 						break
 					}
-					u.isGoodDocument(int64(doc.Timestamp))
+					delay := time.Now().Unix() - int64(doc.Timestamp)
+					isGood := u.isGoodDocument(delay)
 
 					// 秒级数据是否写入
 					if u.disableSecondWrite &&
@@ -250,6 +272,21 @@ func (u *Unmarshaller) QueueProcess() {
 						app.ReleaseDocument(doc)
 						continue
 					}
+
+					// 开启回填时, 回填时限内延迟的数据标记后写入独立的dbwriter, 超过时限的仍按实时数据写入, 不做标记
+					if !isGood && delay > 0 && u.backfill != nil {
+						if !u.backfill.Accept(tableID, delay) {
+							u.counter.BackfillExceedCount++
+							u.tableCounter[tableID]++
+							u.putStoreQueue(doc)
+							continue
+						}
+						u.tableCounter[tableID]++
+						u.counter.BackfillDocCount++
+						doc.Flags |= app.FLAG_BACKFILL
+						u.putBackfillQueue(doc)
+						continue
+					}
 					u.tableCounter[tableID]++
 
 					u.putStoreQueue(doc)
@@ -59,6 +59,7 @@ import (
 	"github.com/deepflowio/deepflow/server/ingester/profile/profile"
 	prometheuscfg "github.com/deepflowio/deepflow/server/ingester/prometheus/config"
 	"github.com/deepflowio/deepflow/server/ingester/prometheus/prometheus"
+	"github.com/deepflowio/deepflow/server/ingester/wasm"
 )
 
 var log = logging.MustGetLogger("ingester")
@@ -175,6 +176,11 @@ func Start(configPath string, shared *servercommon.ControllerIngesterShared) []i
 			cfg.NodeIP,
 			receiver)
 
+		// 服务端WASM插件，在写入l7_flow_log、prometheus及telegraf数据前执行
+		pluginManager := wasm.NewManager(controllers, int(cfg.ControllerPort), cfg.GrpcBufferSize, cfg.WasmPlugins)
+		pluginManager.Start()
+		closers = append(closers, pluginManager)
+
 		// 写遥测数据
 		flowMetrics, err := flowmetrics.NewFlowMetrics(flowMetricsConfig, receiver, platformDataManager)
 		checkError(err)
@@ -182,19 +188,19 @@ func Start(configPath string, shared *servercommon.ControllerIngesterShared) []i
 		closers = append(closers, flowMetrics)
 
 		// 写流日志数据
-		flowLog, err := flowlog.NewFlowLog(flowLogConfig, receiver, platformDataManager)
+		flowLog, err := flowlog.NewFlowLog(flowLogConfig, receiver, platformDataManager, pluginManager)
 		checkError(err)
 		flowLog.Start()
 		closers = append(closers, flowLog)
 
 		// 写ext_metrics数据
-		extMetrics, err := ext_metrics.NewExtMetrics(extMetricsConfig, receiver, platformDataManager)
+		extMetrics, err := ext_metrics.NewExtMetrics(extMetricsConfig, receiver, platformDataManager, pluginManager)
 		checkError(err)
 		extMetrics.Start()
 		closers = append(closers, extMetrics)
 
 		// write event data
-		event, err := event.NewEvent(eventConfig, shared.ResourceEventQueue, receiver, platformDataManager)
+		event, err := event.NewEvent(eventConfig, shared.ResourceEventQueue, receiver, platformDataManager, shared.IsMaster)
 		checkError(err)
 		event.Start()
 		closers = append(closers, event)
@@ -212,7 +218,7 @@ func Start(configPath string, shared *servercommon.ControllerIngesterShared) []i
 		closers = append(closers, profile)
 
 		// write prometheus data
-		prometheus, err := prometheus.NewPrometheusHandler(prometheusConfig, receiver, platformDataManager)
+		prometheus, err := prometheus.NewPrometheusHandler(prometheusConfig, receiver, platformDataManager, pluginManager)
 		checkError(err)
 		prometheus.Start()
 		closers = append(closers, prometheus)
@@ -227,6 +233,10 @@ func Start(configPath string, shared *servercommon.ControllerIngesterShared) []i
 		time.Sleep(time.Second)
 		err = issu.Start()
 		checkError(err)
+		// 已存在的表应用配置的TTL规则，失败时不影响启动
+		if err := issu.RunTTLRules(ds); err != nil {
+			log.Warningf("run ttl rules failed: %s", err)
+		}
 		closers = append(closers, issu)
 	}
 	// receiver后启动，防止启动后收到数据无法处理，而上报异常日志
@@ -24,6 +24,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/deepflowio/deepflow/server/ingester/ckmonitor"
 	"github.com/deepflowio/deepflow/server/ingester/droplet/adapter"
 	"github.com/deepflowio/deepflow/server/ingester/droplet/labeler"
 	"github.com/deepflowio/deepflow/server/ingester/droplet/profiler"
@@ -65,6 +66,7 @@ func RegisterIngesterCommand(root *cobra.Command) {
 	ingesterCmd.AddCommand(profiler.RegisterProfilerCommand())
 	ingesterCmd.AddCommand(debug.RegisterLogLevelCommand())
 	ingesterCmd.AddCommand(RegisterTimeConvertCommand())
+	ingesterCmd.AddCommand(ckmonitor.RegisterArchiveCommand())
 
 	dropletCmd.AddCommand(queue.RegisterCommand(ingesterctl.INGESTERCTL_QUEUE, []string{
 		"1-receiver-to-statsd",
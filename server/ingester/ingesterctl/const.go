@@ -47,6 +47,7 @@ const (
 	CMD_PLATFORMDATA_EXT_METRICS
 	CMD_PLATFORMDATA_PROMETHEUS
 	CMD_PROMETHEUS_LABEL
+	CMD_CK_ARCHIVE
 )
 
 const (
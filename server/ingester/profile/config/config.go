@@ -17,6 +17,7 @@
 package config
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 
@@ -33,6 +34,19 @@ type Config struct {
 	ProfileTTL        int                   `yaml:"profile-ttl-hour"`
 	DecoderQueueCount int                   `yaml:"profile-decoder-queue-count"`
 	DecoderQueueSize  int                   `yaml:"profile-decoder-queue-size"`
+	HTTPIngest        HTTPIngestConfig      `yaml:"profile-http-ingest"`
+}
+
+// HTTPIngestConfig 兼容Pyroscope的/ingest接口, 用于无法部署agent的环境直接上报profile
+type HTTPIngestConfig struct {
+	Enabled    bool     `yaml:"enabled"`
+	ListenPort int      `yaml:"listen-port"`
+	AuthTokens []string `yaml:"auth-tokens"`
+	// 每个应用每秒允许的请求数, 0表示不限制
+	AppRateLimit  float64            `yaml:"app-rate-limit"`
+	AppRateBurst  int                `yaml:"app-rate-burst"`
+	AppRateLimits map[string]float64 `yaml:"app-rate-limits"`
+	MaxBodySize   int64              `yaml:"max-body-size"`
 }
 
 type ProfileConfig struct {
@@ -43,8 +57,33 @@ const (
 	DefaultProfileTTL        = 72 // hour
 	DefaultDecoderQueueCount = 2
 	DefaultDecoderQueueSize  = 1 << 14
+
+	DefaultHTTPIngestListenPort  = 4040
+	DefaultHTTPIngestRateBurst   = 10
+	DefaultHTTPIngestMaxBodySize = 32 << 20
 )
 
+func (c *HTTPIngestConfig) Validate() error {
+	if c.ListenPort <= 0 || c.ListenPort > 65535 {
+		c.ListenPort = DefaultHTTPIngestListenPort
+	}
+	if c.AppRateLimit < 0 {
+		return fmt.Errorf("profile-http-ingest app-rate-limit(%f) must not be negative", c.AppRateLimit)
+	}
+	for app, limit := range c.AppRateLimits {
+		if limit < 0 {
+			return fmt.Errorf("profile-http-ingest app-rate-limits of %s(%f) must not be negative", app, limit)
+		}
+	}
+	if c.AppRateBurst <= 0 {
+		c.AppRateBurst = DefaultHTTPIngestRateBurst
+	}
+	if c.MaxBodySize <= 0 {
+		c.MaxBodySize = DefaultHTTPIngestMaxBodySize
+	}
+	return nil
+}
+
 func (c *Config) Validate() error {
 	if c.ProfileTTL <= 0 {
 		c.ProfileTTL = DefaultProfileTTL
@@ -58,7 +97,7 @@ func (c *Config) Validate() error {
 		c.DecoderQueueSize = DefaultDecoderQueueSize
 	}
 
-	return nil
+	return c.HTTPIngest.Validate()
 }
 
 func Load(base *config.Config, path string) *Config {
@@ -69,6 +108,11 @@ func Load(base *config.Config, path string) *Config {
 			ProfileTTL:        DefaultProfileTTL,
 			DecoderQueueCount: DefaultDecoderQueueCount,
 			DecoderQueueSize:  DefaultDecoderQueueSize,
+			HTTPIngest: HTTPIngestConfig{
+				ListenPort:   DefaultHTTPIngestListenPort,
+				AppRateBurst: DefaultHTTPIngestRateBurst,
+				MaxBodySize:  DefaultHTTPIngestMaxBodySize,
+			},
 		},
 	}
 	if _, err := os.Stat(path); os.IsNotExist(err) {
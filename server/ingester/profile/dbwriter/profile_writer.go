@@ -78,6 +78,7 @@ func NewProfileWriter(msgType datatype.MessageType, config *config.Config) (*Pro
 		writerConfig:      config.CKWriterConfig,
 	}
 	table := GenProfileCKTable(writer.ckdbCluster, PROFILE_DB, PROFILE_TABLE, writer.ckdbStoragePolicy, writer.ttl, ckdb.GetColdStorage(writer.ckdbColdStorages, PROFILE_DB, PROFILE_TABLE))
+	table.TTLRules = ckdb.GetTTLRules(config.Base.GetCKDBTTLRules(), PROFILE_DB, PROFILE_TABLE)
 	ckwriter, err := ckwriter.NewCKWriter(
 		writer.ckdbAddrs, writer.ckdbUsername, writer.ckdbPassword,
 		PROFILE_TABLE, config.Base.CKDB.TimeZone, table,
@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package decoder
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/deepflowio/deepflow/server/libs/zerodoc/pb"
+	"github.com/pyroscope-io/pyroscope/pkg/ingestion"
+	"github.com/pyroscope-io/pyroscope/pkg/storage"
+	"github.com/pyroscope-io/pyroscope/pkg/storage/tree"
+)
+
+const MAX_COLLAPSED_LINE_SIZE = 1 << 20
+
+// parseCollapsed 解析collapsed/folded格式, 每行为"a;b;c value"; lines格式每行只有调用栈, 值固定为1
+// parse collapsed stacks, callback with each stack and its value
+func parseCollapsed(data []byte, withValue bool, callback func(stack []byte, value uint64)) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), MAX_COLLAPSED_LINE_SIZE)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if !withValue {
+			callback(line, 1)
+			continue
+		}
+		index := bytes.LastIndexByte(line, ' ')
+		if index <= 0 {
+			return fmt.Errorf("line %d: missing value", lineNo)
+		}
+		value, err := strconv.ParseUint(string(line[index+1:]), 10, 64)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid value: %s", lineNo, err)
+		}
+		stack := bytes.TrimSpace(line[:index])
+		if len(stack) == 0 || value == 0 {
+			continue
+		}
+		callback(stack, value)
+	}
+	return scanner.Err()
+}
+
+func (d *Decoder) sendCollapsedData(profile *pb.Profile, parser *Parser, metadata ingestion.Metadata) error {
+	t := tree.New()
+	err := parseCollapsed(profile.Data, profile.Format != FORMAT_LINES, func(stack []byte, value uint64) {
+		t.Insert(stack, value)
+	})
+	if err != nil {
+		return err
+	}
+	return parser.Put(context.TODO(), &storage.PutInput{
+		StartTime:       metadata.StartTime,
+		EndTime:         metadata.EndTime,
+		Key:             metadata.Key,
+		Val:             t,
+		SpyName:         metadata.SpyName,
+		SampleRate:      metadata.SampleRate,
+		Units:           metadata.Units,
+		AggregationType: metadata.AggregationType,
+	})
+}
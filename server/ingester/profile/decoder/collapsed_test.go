@@ -0,0 +1,63 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package decoder
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCollapsed(t *testing.T) {
+	data := []byte("main;foo;bar 10\n\nmain;foo 3\nmain;with space;baz 2\nmain;zero 0\n")
+	stacks := map[string]uint64{}
+	err := parseCollapsed(data, true, func(stack []byte, value uint64) {
+		stacks[string(stack)] += value
+	})
+	if err != nil {
+		t.Fatalf("parseCollapsed failed: %s", err)
+	}
+	expected := map[string]uint64{
+		"main;foo;bar":        10,
+		"main;foo":            3,
+		"main;with space;baz": 2,
+	}
+	if !reflect.DeepEqual(stacks, expected) {
+		t.Errorf("expected %v, got %v", expected, stacks)
+	}
+
+	if err := parseCollapsed([]byte("main;foo\n"), true, func([]byte, uint64) {}); err == nil {
+		t.Error("expected error for line without value")
+	}
+	if err := parseCollapsed([]byte("main;foo abc\n"), true, func([]byte, uint64) {}); err == nil {
+		t.Error("expected error for invalid value")
+	}
+}
+
+func TestParseLines(t *testing.T) {
+	data := []byte("main;foo\nmain;foo\nmain;bar\n")
+	stacks := map[string]uint64{}
+	err := parseCollapsed(data, false, func(stack []byte, value uint64) {
+		stacks[string(stack)] += value
+	})
+	if err != nil {
+		t.Fatalf("parseCollapsed failed: %s", err)
+	}
+	expected := map[string]uint64{"main;foo": 2, "main;bar": 1}
+	if !reflect.DeepEqual(stacks, expected) {
+		t.Errorf("expected %v, got %v", expected, stacks)
+	}
+}
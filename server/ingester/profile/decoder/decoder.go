@@ -18,6 +18,7 @@ package decoder
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
 	"strconv"
@@ -50,6 +51,17 @@ const (
 	BUFFER_SIZE = 1024
 )
 
+// collapsed stack格式, 每行为"a;b;c value", lines格式每行为一个调用栈且值为1
+const (
+	FORMAT_COLLAPSED = "collapsed"
+	FORMAT_FOLDED    = "folded"
+	FORMAT_GROUPS    = "groups"
+	FORMAT_LINES     = "lines"
+)
+
+// ErrUnsupportedFormat 表示profile格式暂不支持解析
+var ErrUnsupportedFormat = errors.New("unsupported profile format")
+
 var InProcessCounter uint32
 
 type Counter struct {
@@ -57,6 +69,8 @@ type Counter struct {
 	JavaProfileCount   int64 `statsd:"java-profile-count"`
 	GolangProfileCount int64 `statsd:"golang-profile-count"`
 
+	CollapsedProfileCount int64 `statsd:"collapsed-profile-count"`
+
 	TotalTime int64 `statsd:"total-time"`
 	AvgTime   int64 `statsd:"avg-time"`
 }
@@ -142,65 +156,69 @@ func (d *Decoder) handleProfileData(vtapID uint16, decoder *codec.SimpleDecoder)
 			log.Errorf("profile data decode failed, offset=%d len=%d", decoder.Offset(), len(decoder.Bytes()))
 			return
 		}
-
-		parser := &Parser{
-			vtapID:       vtapID,
-			inTimestamp:  time.Now(),
-			callBack:     d.profileWriter.Write,
-			platformData: d.platformData,
-			IP:           make([]byte, len(profile.Ip)),
+		if err := d.HandleProfile(vtapID, profile); err != nil {
+			if errors.Is(err, ErrUnsupportedFormat) {
+				// 跳过不支持的格式, 继续处理后续数据
+				log.Debugf("skip profile of %s: %s", profile.Name, err)
+				continue
+			}
+			log.Errorf("decode %s profile data failed, offset=%d len=%d: %s", profile.Format, decoder.Offset(), len(decoder.Bytes()), err)
+			return
 		}
-		copy(parser.IP, profile.Ip[:len(profile.Ip)])
+	}
+}
 
-		switch profile.Format {
-		case "jfr":
-			atomic.AddInt64(&d.counter.JavaProfileCount, 1)
-			metadata := d.buildMetaData(profile)
-			parser.profileName = metadata.Key.AppName()
-			err := d.sendProfileData(&jfr.RawProfile{
-				FormDataContentType: string(profile.ContentType),
-				RawData:             profile.Data,
-			}, profile.Format, parser, metadata)
+// HandleProfile 解析单个profile并写入, agent转发的数据和HTTP直接上报的数据共用
+func (d *Decoder) HandleProfile(vtapID uint16, profile *pb.Profile) error {
+	parser := &Parser{
+		vtapID:       vtapID,
+		inTimestamp:  time.Now(),
+		callBack:     d.profileWriter.Write,
+		platformData: d.platformData,
+		IP:           make([]byte, len(profile.Ip)),
+	}
+	copy(parser.IP, profile.Ip[:len(profile.Ip)])
 
-			if err != nil {
-				log.Errorf("decode java profile data failed, offset=%d len=%d", decoder.Offset(), len(decoder.Bytes()))
-				return
-			}
-		case "pprof":
+	switch profile.Format {
+	case "jfr":
+		atomic.AddInt64(&d.counter.JavaProfileCount, 1)
+		metadata := d.buildMetaData(profile)
+		parser.profileName = metadata.Key.AppName()
+		return d.sendProfileData(&jfr.RawProfile{
+			FormDataContentType: string(profile.ContentType),
+			RawData:             profile.Data,
+		}, profile.Format, parser, metadata)
+	case "pprof":
+		atomic.AddInt64(&d.counter.GolangProfileCount, 1)
+		metadata := d.buildMetaData(profile)
+		parser.profileName = metadata.Key.AppName()
+		return d.sendProfileData(&pprof.RawProfile{
+			FormDataContentType: string(profile.ContentType),
+			RawData:             profile.Data,
+		}, profile.Format, parser, metadata)
+	case "":
+		// 如果 format == "" && contentType 有 "multipart/form-data"，默认当作 pprof 来解析，且 StreamingParser&PoolStreamingParser = true
+		// if format == "" && contentType has "multipart/form-data", using pprof parser as default, StreamingParser&PoolStreamingParser = true
+		if strings.Contains(string(profile.ContentType), "multipart/form-data") {
 			atomic.AddInt64(&d.counter.GolangProfileCount, 1)
 			metadata := d.buildMetaData(profile)
 			parser.profileName = metadata.Key.AppName()
-			err := d.sendProfileData(&pprof.RawProfile{
+			return d.sendProfileData(&pprof.RawProfile{
 				FormDataContentType: string(profile.ContentType),
 				RawData:             profile.Data,
+				StreamingParser:     true,
+				PoolStreamingParser: true,
 			}, profile.Format, parser, metadata)
-			if err != nil {
-				log.Errorf("decode golang profile data failed, offset=%d len=%d", decoder.Offset(), len(decoder.Bytes()))
-				return
-			}
-		case "":
-			// 如果 format == "" && contentType 有 "multipart/form-data"，默认当作 pprof 来解析，且 StreamingParser&PoolStreamingParser = true
-			// if format == "" && contentType has "multipart/form-data", using pprof parser as default, StreamingParser&PoolStreamingParser = true
-			if strings.Contains(string(profile.ContentType), "multipart/form-data") {
-				atomic.AddInt64(&d.counter.GolangProfileCount, 1)
-				metadata := d.buildMetaData(profile)
-				parser.profileName = metadata.Key.AppName()
-				err := d.sendProfileData(&pprof.RawProfile{
-					FormDataContentType: string(profile.ContentType),
-					RawData:             profile.Data,
-					StreamingParser:     true,
-					PoolStreamingParser: true,
-				}, profile.Format, parser, metadata)
-				if err != nil {
-					log.Errorf("decode golang profile data failed, offset=%d len=%d", decoder.Offset(), len(decoder.Bytes()))
-					return
-				}
-			}
-		case "speedscope", "tree", "trie", "lines":
-			// not implemented
-			continue
 		}
+		return fmt.Errorf("%w: empty format with content type %q", ErrUnsupportedFormat, profile.ContentType)
+	case FORMAT_COLLAPSED, FORMAT_FOLDED, FORMAT_GROUPS, FORMAT_LINES:
+		atomic.AddInt64(&d.counter.CollapsedProfileCount, 1)
+		metadata := d.buildMetaData(profile)
+		parser.profileName = metadata.Key.AppName()
+		return d.sendCollapsedData(profile, parser, metadata)
 	}
+	// speedscope, tree, trie等格式暂未实现
+	return fmt.Errorf("%w: %s", ErrUnsupportedFormat, profile.Format)
 }
 
 func (d *Decoder) buildMetaData(profile *pb.Profile) ingestion.Metadata {
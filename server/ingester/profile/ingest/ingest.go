@@ -0,0 +1,380 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ingest
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	logging "github.com/op/go-logging"
+	"github.com/pyroscope-io/pyroscope/pkg/storage/segment"
+	"golang.org/x/time/rate"
+
+	"github.com/deepflowio/deepflow/server/ingester/common"
+	"github.com/deepflowio/deepflow/server/ingester/profile/config"
+	"github.com/deepflowio/deepflow/server/ingester/profile/decoder"
+	"github.com/deepflowio/deepflow/server/libs/stats"
+	"github.com/deepflowio/deepflow/server/libs/utils"
+	"github.com/deepflowio/deepflow/server/libs/zerodoc/pb"
+)
+
+var log = logging.MustGetLogger("profile.ingest")
+
+const (
+	DEFAULT_SAMPLE_RATE      = 100
+	DEFAULT_SPY_NAME         = "unknown"
+	DEFAULT_UNITS            = "samples"
+	DEFAULT_AGGREGATION_TYPE = "sum"
+	DEFAULT_TIME_RANGE       = 10 // second
+
+	// 应用名由客户端上报, 限流器数量需要有上限, 超过上限时淘汰最久未使用的
+	MAX_APP_LIMITERS     = 4096
+	APP_LIMITER_IDLE_TTL = 10 * time.Minute
+)
+
+type Counter struct {
+	RequestCount     int64 `statsd:"request-count"`
+	AuthFailedCount  int64 `statsd:"auth-failed-count"`
+	RateLimitedCount int64 `statsd:"rate-limited-count"`
+	ErrorCount       int64 `statsd:"error-count"`
+	BodyBytes        int64 `statsd:"body-bytes"`
+}
+
+type appLimiter struct {
+	*rate.Limiter
+	lastSeen time.Time
+}
+
+// Ingester 兼容Pyroscope的HTTP /ingest接口, 数据与agent上报的profile共用decoder写入
+type Ingester struct {
+	cfg     *config.HTTPIngestConfig
+	decoder *decoder.Decoder
+	// decoder内的platformData不支持并发访问
+	decoderLock sync.Mutex
+
+	limitersLock sync.Mutex
+	limiters     map[string]*appLimiter
+
+	counter *Counter
+	server  *http.Server
+	utils.Closable
+}
+
+func NewIngester(cfg *config.HTTPIngestConfig, decoder *decoder.Decoder) *Ingester {
+	return &Ingester{
+		cfg:      cfg,
+		decoder:  decoder,
+		counter:  &Counter{},
+		limiters: make(map[string]*appLimiter),
+		server: &http.Server{
+			Addr:    ":" + strconv.Itoa(cfg.ListenPort),
+			Handler: mux.NewRouter(),
+		},
+	}
+}
+
+func (i *Ingester) GetCounter() interface{} {
+	// 请求处理中会并发累加各字段, 逐个原子地取值并清零
+	return &Counter{
+		RequestCount:     atomic.SwapInt64(&i.counter.RequestCount, 0),
+		AuthFailedCount:  atomic.SwapInt64(&i.counter.AuthFailedCount, 0),
+		RateLimitedCount: atomic.SwapInt64(&i.counter.RateLimitedCount, 0),
+		ErrorCount:       atomic.SwapInt64(&i.counter.ErrorCount, 0),
+		BodyBytes:        atomic.SwapInt64(&i.counter.BodyBytes, 0),
+	}
+}
+
+type JsonResp struct {
+	OptStatus   string `json:"OPT_STATUS"`
+	Description string `json:"DESCRIPTION,omitempty"`
+}
+
+func respSuccess(w http.ResponseWriter) {
+	resp, _ := json.Marshal(JsonResp{
+		OptStatus: "SUCCESS",
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+func respFailed(w http.ResponseWriter, status int, desc string) {
+	resp, _ := json.Marshal(JsonResp{
+		OptStatus:   "FAILED",
+		Description: desc,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(resp)
+}
+
+func (i *Ingester) ingest(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&i.counter.RequestCount, 1)
+	if !i.authenticate(r) {
+		atomic.AddInt64(&i.counter.AuthFailedCount, 1)
+		respFailed(w, http.StatusUnauthorized, "invalid auth token")
+		return
+	}
+
+	profile, err := parseProfileArgs(r.URL.Query(), time.Now())
+	if err != nil {
+		atomic.AddInt64(&i.counter.ErrorCount, 1)
+		respFailed(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !i.allow(profile.Name) {
+		atomic.AddInt64(&i.counter.RateLimitedCount, 1)
+		respFailed(w, http.StatusTooManyRequests, fmt.Sprintf("rate limit exceeded for %s", profile.Name))
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, i.cfg.MaxBodySize))
+	if err != nil {
+		atomic.AddInt64(&i.counter.ErrorCount, 1)
+		respFailed(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+	atomic.AddInt64(&i.counter.BodyBytes, int64(len(body)))
+	contentType := r.Header.Get("Content-Type")
+	if profile.Format == "" && !strings.Contains(contentType, "multipart/form-data") {
+		profile.Format = decoder.FORMAT_COLLAPSED
+	}
+	profile.ContentType = []byte(contentType)
+	profile.Data = body
+	profile.Ip = remoteIP(r)
+	// decoder中会对name做QueryUnescape
+	profile.Name = url.QueryEscape(profile.Name)
+
+	i.decoderLock.Lock()
+	err = i.decoder.HandleProfile(0, profile)
+	i.decoderLock.Unlock()
+	if err != nil {
+		atomic.AddInt64(&i.counter.ErrorCount, 1)
+		log.Warningf("ingest %s profile of %s failed: %s", profile.Format, profile.Name, err)
+		if errors.Is(err, decoder.ErrUnsupportedFormat) {
+			respFailed(w, http.StatusUnsupportedMediaType, err.Error())
+		} else {
+			respFailed(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+	respSuccess(w)
+}
+
+// authenticate 需携带"Authorization: Bearer <token>", 未配置auth-tokens时拒绝所有请求
+func (i *Ingester) authenticate(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	token := []byte(strings.TrimSpace(strings.TrimPrefix(auth, "Bearer ")))
+	for _, t := range i.cfg.AuthTokens {
+		if subtle.ConstantTimeCompare(token, []byte(t)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// allow 按应用名限流, app-rate-limits中配置的应用优先, 限速为0表示不限制
+func (i *Ingester) allow(name string) bool {
+	app := appName(name)
+	limit := i.cfg.AppRateLimit
+	if l, ok := i.cfg.AppRateLimits[app]; ok {
+		limit = l
+	}
+	if limit <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	i.limitersLock.Lock()
+	defer i.limitersLock.Unlock()
+	l, ok := i.limiters[app]
+	if !ok {
+		if len(i.limiters) >= MAX_APP_LIMITERS {
+			i.evictLimiters(now)
+		}
+		l = &appLimiter{Limiter: rate.NewLimiter(rate.Limit(limit), i.cfg.AppRateBurst)}
+		i.limiters[app] = l
+	}
+	l.lastSeen = now
+	return l.AllowN(now, 1)
+}
+
+// evictLimiters 删除空闲超过APP_LIMITER_IDLE_TTL的限流器, 若都不空闲则删除最久未使用的一个
+func (i *Ingester) evictLimiters(now time.Time) {
+	var oldestApp string
+	var oldest *appLimiter
+	for app, l := range i.limiters {
+		if now.Sub(l.lastSeen) > APP_LIMITER_IDLE_TTL {
+			delete(i.limiters, app)
+			continue
+		}
+		if oldest == nil || l.lastSeen.Before(oldest.lastSeen) {
+			oldestApp, oldest = app, l
+		}
+	}
+	if len(i.limiters) >= MAX_APP_LIMITERS && oldest != nil {
+		delete(i.limiters, oldestApp)
+	}
+}
+
+func appName(name string) string {
+	key, err := segment.ParseKey(name)
+	if err != nil {
+		return name
+	}
+	return key.AppName()
+}
+
+func remoteIP(r *http.Request) []byte {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	return ip
+}
+
+// parseProfileArgs 解析Pyroscope /ingest的查询参数
+func parseProfileArgs(query url.Values, now time.Time) (*pb.Profile, error) {
+	name := query.Get("name")
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+	profile := &pb.Profile{
+		Name:            name,
+		Format:          query.Get("format"),
+		SpyName:         query.Get("spyName"),
+		Units:           query.Get("units"),
+		AggregationType: query.Get("aggregationType"),
+		SampleRate:      DEFAULT_SAMPLE_RATE,
+	}
+	if profile.SpyName == "" {
+		profile.SpyName = DEFAULT_SPY_NAME
+	}
+	if profile.Units == "" {
+		profile.Units = DEFAULT_UNITS
+	}
+	if profile.AggregationType == "" {
+		profile.AggregationType = DEFAULT_AGGREGATION_TYPE
+	}
+	if v := query.Get("sampleRate"); v != "" {
+		sampleRate, err := strconv.ParseUint(v, 10, 32)
+		if err != nil || sampleRate == 0 {
+			return nil, fmt.Errorf("invalid sampleRate: %s", v)
+		}
+		profile.SampleRate = uint32(sampleRate)
+	}
+
+	until := now
+	if v := query.Get("until"); v != "" {
+		t, err := parseTime(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid until: %s", v)
+		}
+		until = t
+	}
+	from := until.Add(-DEFAULT_TIME_RANGE * time.Second)
+	if v := query.Get("from"); v != "" {
+		t, err := parseTime(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from: %s", v)
+		}
+		from = t
+	}
+	if from.After(until) {
+		return nil, fmt.Errorf("from(%d) is after until(%d)", from.Unix(), until.Unix())
+	}
+	profile.From = uint32(from.Unix())
+	profile.Until = uint32(until.Unix())
+	return profile, nil
+}
+
+// parseTime 与Pyroscope一致, 根据数值大小识别秒/毫秒/微秒/纳秒时间戳
+func parseTime(v string) (time.Time, error) {
+	ts, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || ts < 0 {
+		return time.Time{}, fmt.Errorf("invalid timestamp %s", v)
+	}
+	switch {
+	case ts > 1e18:
+		return time.Unix(0, ts), nil
+	case ts > 1e15:
+		return time.Unix(0, ts*int64(time.Microsecond)), nil
+	case ts > 1e12:
+		return time.Unix(0, ts*int64(time.Millisecond)), nil
+	default:
+		return time.Unix(ts, 0), nil
+	}
+}
+
+func (i *Ingester) RegisterHandlers() {
+	router := i.server.Handler.(*mux.Router)
+	router.HandleFunc("/ingest", i.ingest).Methods("POST")
+}
+
+func (i *Ingester) Start() {
+	i.RegisterHandlers()
+	if len(i.cfg.AuthTokens) == 0 {
+		log.Warning("profile http ingest has no auth-tokens configured, all requests will be rejected")
+	}
+	common.RegisterCountableForIngester("profile-http-ingest", i, stats.OptionStatTags{})
+
+	go func() {
+		if err := i.server.ListenAndServe(); err != http.ErrServerClosed {
+			log.Fatalf("ListenAndServe() failed: %v", err)
+		}
+	}()
+	log.Infof("profile http ingest started, listen port: %d", i.cfg.ListenPort)
+}
+
+func (i *Ingester) Close() error {
+	i.Closable.Close()
+	if i.server == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+
+	if err := i.server.Shutdown(ctx); err != nil {
+		log.Errorf("Shutdown() failed: %v", err)
+		return err
+	}
+
+	log.Info("profile http ingest stopped")
+	return nil
+}
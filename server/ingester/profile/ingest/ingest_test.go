@@ -0,0 +1,176 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ingest
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/deepflowio/deepflow/server/ingester/profile/config"
+)
+
+func TestParseProfileArgs(t *testing.T) {
+	now := time.Unix(1680000000, 0)
+	query := url.Values{}
+	if _, err := parseProfileArgs(query, now); err == nil {
+		t.Error("expected error without name")
+	}
+
+	query.Set("name", "my-app.cpu{env=prod}")
+	profile, err := parseProfileArgs(query, now)
+	if err != nil {
+		t.Fatalf("parseProfileArgs failed: %s", err)
+	}
+	if profile.Until != 1680000000 || profile.From != 1680000000-DEFAULT_TIME_RANGE {
+		t.Errorf("unexpected default time range: %d-%d", profile.From, profile.Until)
+	}
+	if profile.SampleRate != DEFAULT_SAMPLE_RATE || profile.SpyName != DEFAULT_SPY_NAME ||
+		profile.Units != DEFAULT_UNITS || profile.AggregationType != DEFAULT_AGGREGATION_TYPE {
+		t.Errorf("unexpected defaults: %+v", profile)
+	}
+
+	query.Set("from", "1679999990000")
+	query.Set("until", "1679999995")
+	query.Set("sampleRate", "99")
+	query.Set("spyName", "gospy")
+	query.Set("format", "pprof")
+	profile, err = parseProfileArgs(query, now)
+	if err != nil {
+		t.Fatalf("parseProfileArgs failed: %s", err)
+	}
+	if profile.From != 1679999990 || profile.Until != 1679999995 {
+		t.Errorf("unexpected time range: %d-%d", profile.From, profile.Until)
+	}
+	if profile.SampleRate != 99 || profile.SpyName != "gospy" || profile.Format != "pprof" {
+		t.Errorf("unexpected profile: %+v", profile)
+	}
+
+	query.Set("from", "1680000001")
+	if _, err := parseProfileArgs(query, now); err == nil {
+		t.Error("expected error when from is after until")
+	}
+	query.Set("from", "1679999990")
+	query.Set("sampleRate", "0")
+	if _, err := parseProfileArgs(query, now); err == nil {
+		t.Error("expected error for zero sampleRate")
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	for _, v := range []string{"1680000000", "1680000000000", "1680000000000000", "1680000000000000000"} {
+		ts, err := parseTime(v)
+		if err != nil {
+			t.Fatalf("parseTime(%s) failed: %s", v, err)
+		}
+		if ts.Unix() != 1680000000 {
+			t.Errorf("parseTime(%s) = %d", v, ts.Unix())
+		}
+	}
+	if _, err := parseTime("now-1h"); err == nil {
+		t.Error("expected error for non numeric time")
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	i := &Ingester{cfg: &config.HTTPIngestConfig{}}
+	r := httptest.NewRequest("POST", "/ingest?name=app", nil)
+	if i.authenticate(r) {
+		t.Error("expected auth failure when tokens are not configured")
+	}
+	r.Header.Set("Authorization", "Bearer ")
+	if i.authenticate(r) {
+		t.Error("expected auth failure with empty token when tokens are not configured")
+	}
+	r.Header.Del("Authorization")
+
+	i.cfg.AuthTokens = []string{"token-a", "token-b"}
+	if i.authenticate(r) {
+		t.Error("expected auth failure without token")
+	}
+	r.Header.Set("Authorization", "Bearer token-c")
+	if i.authenticate(r) {
+		t.Error("expected auth failure with wrong token")
+	}
+	r.Header.Set("Authorization", "Bearer token-b")
+	if !i.authenticate(r) {
+		t.Error("expected auth success")
+	}
+}
+
+func TestAllow(t *testing.T) {
+	i := NewIngester(&config.HTTPIngestConfig{
+		AppRateLimit:  1,
+		AppRateBurst:  2,
+		AppRateLimits: map[string]float64{"unlimited-app": 0},
+	}, nil)
+	for n := 0; n < 2; n++ {
+		if !i.allow("my-app.cpu{env=prod}") {
+			t.Fatalf("request %d should be allowed by burst", n)
+		}
+	}
+	if i.allow("my-app.cpu{env=test}") {
+		t.Error("requests of the same app should share a limiter")
+	}
+	if !i.allow("other-app.cpu") {
+		t.Error("other app should have its own limiter")
+	}
+	for n := 0; n < 10; n++ {
+		if !i.allow("unlimited-app{}") {
+			t.Fatal("app with zero limit should not be limited")
+		}
+	}
+}
+
+func TestEvictLimiters(t *testing.T) {
+	i := NewIngester(&config.HTTPIngestConfig{AppRateLimit: 1, AppRateBurst: 1}, nil)
+	now := time.Now()
+	for n := 0; n < MAX_APP_LIMITERS; n++ {
+		i.limiters[strconv.Itoa(n)] = &appLimiter{lastSeen: now.Add(time.Duration(n) * time.Second)}
+	}
+	i.allow("new-app")
+	if len(i.limiters) != MAX_APP_LIMITERS {
+		t.Errorf("limiters should be bounded to %d, got %d", MAX_APP_LIMITERS, len(i.limiters))
+	}
+	if _, ok := i.limiters["0"]; ok {
+		t.Error("least recently used limiter should be evicted")
+	}
+
+	for app, l := range i.limiters {
+		if app != "new-app" {
+			l.lastSeen = now.Add(-2 * APP_LIMITER_IDLE_TTL)
+		}
+	}
+	i.evictLimiters(time.Now())
+	if len(i.limiters) != 1 || i.limiters["new-app"] == nil {
+		t.Errorf("idle limiters should be evicted, %d left", len(i.limiters))
+	}
+}
+
+func TestGetCounter(t *testing.T) {
+	i := NewIngester(&config.HTTPIngestConfig{}, nil)
+	i.counter.RequestCount, i.counter.BodyBytes = 3, 100
+	c := i.GetCounter().(*Counter)
+	if c.RequestCount != 3 || c.BodyBytes != 100 {
+		t.Errorf("unexpected counter %+v", c)
+	}
+	if i.counter.RequestCount != 0 || i.counter.BodyBytes != 0 {
+		t.Errorf("counter should be reset, got %+v", i.counter)
+	}
+}
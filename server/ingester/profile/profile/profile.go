@@ -20,20 +20,31 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/deepflowio/deepflow/server/ingester/common"
 	dropletqueue "github.com/deepflowio/deepflow/server/ingester/droplet/queue"
 	"github.com/deepflowio/deepflow/server/ingester/ingesterctl"
 	"github.com/deepflowio/deepflow/server/ingester/profile/config"
 	"github.com/deepflowio/deepflow/server/ingester/profile/dbwriter"
 	"github.com/deepflowio/deepflow/server/ingester/profile/decoder"
+	"github.com/deepflowio/deepflow/server/ingester/profile/ingest"
 	"github.com/deepflowio/deepflow/server/libs/datatype"
 	"github.com/deepflowio/deepflow/server/libs/grpc"
 	"github.com/deepflowio/deepflow/server/libs/queue"
 	libqueue "github.com/deepflowio/deepflow/server/libs/queue"
 	"github.com/deepflowio/deepflow/server/libs/receiver"
+	"github.com/deepflowio/deepflow/server/libs/stats"
 )
 
 type Profile struct {
 	Profiler *Profiler
+	Ingester *HTTPIngester
+}
+
+// HTTPIngester 通过HTTP直接接收Pyroscope格式的profile, 使用独立的decoder和platformData
+type HTTPIngester struct {
+	PlatformData *grpc.PlatformInfoTable
+	Decoder      *decoder.Decoder
+	Ingester     *ingest.Ingester
 }
 
 type Profiler struct {
@@ -52,11 +63,53 @@ func NewProfile(config *config.Config, recv *receiver.Receiver, platformDataMana
 	if err != nil {
 		return nil, err
 	}
+	var ingester *HTTPIngester
+	if config.HTTPIngest.Enabled {
+		ingester, err = NewHTTPIngester(datatype.MESSAGE_TYPE_PROFILE, config, platformDataManager, profileWriter)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return &Profile{
 		Profiler: profiler,
+		Ingester: ingester,
+	}, nil
+}
+
+func NewHTTPIngester(msgType datatype.MessageType, config *config.Config, platformDataManager *grpc.PlatformDataManager, profileWriter *dbwriter.ProfileWriter) (*HTTPIngester, error) {
+	var platformData *grpc.PlatformInfoTable
+	if platformDataManager != nil {
+		var err error
+		platformData, err = platformDataManager.NewPlatformInfoTable(false, "profile-"+msgType.String()+"-http")
+		if err != nil {
+			return nil, err
+		}
+	}
+	d := decoder.NewDecoder(config.DecoderQueueCount, msgType, platformData, nil, profileWriter)
+	return &HTTPIngester{
+		PlatformData: platformData,
+		Decoder:      d,
+		Ingester:     ingest.NewIngester(&config.HTTPIngest, d),
 	}, nil
 }
 
+func (i *HTTPIngester) Start() {
+	if i.PlatformData != nil {
+		i.PlatformData.Start()
+	}
+	common.RegisterCountableForIngester("decoder", i.Decoder, stats.OptionStatTags{
+		"thread":   "http",
+		"msg_type": datatype.MESSAGE_TYPE_PROFILE.String()})
+	i.Ingester.Start()
+}
+
+func (i *HTTPIngester) Close() {
+	i.Ingester.Close()
+	if i.PlatformData != nil {
+		i.PlatformData.ClosePlatformInfoTable()
+	}
+}
+
 func NewProfiler(msgType datatype.MessageType, config *config.Config, platformDataManager *grpc.PlatformDataManager, manager *dropletqueue.Manager, recv *receiver.Receiver, profileWriter *dbwriter.ProfileWriter) (*Profiler, error) {
 	decodeQueues := manager.NewQueues(
 		"1-receive-to-decode-"+msgType.String(),
@@ -118,9 +171,15 @@ func (p *Profiler) Close() {
 
 func (p *Profile) Start() {
 	p.Profiler.Start()
+	if p.Ingester != nil {
+		p.Ingester.Start()
+	}
 }
 
 func (p *Profile) Close() error {
+	if p.Ingester != nil {
+		p.Ingester.Close()
+	}
 	p.Profiler.Close()
 	return nil
 }
@@ -30,6 +30,7 @@ import (
 	"github.com/deepflowio/deepflow/server/ingester/common"
 	"github.com/deepflowio/deepflow/server/ingester/prometheus/config"
 	"github.com/deepflowio/deepflow/server/ingester/prometheus/dbwriter"
+	"github.com/deepflowio/deepflow/server/ingester/wasm"
 	"github.com/deepflowio/deepflow/server/libs/codec"
 	"github.com/deepflowio/deepflow/server/libs/datatype"
 	"github.com/deepflowio/deepflow/server/libs/datatype/prompb"
@@ -58,6 +59,7 @@ type Counter struct {
 	TimeSeriesErr  int64 `statsd:"time-series-err"`
 	TimeSeriesSlow int64 `statsd:"time-series-slow"`
 	TimeSeriesOut  int64 `statsd:"time-series-out"` // count the number of TimeSeries (not Samples)
+	PluginDrop     int64 `statsd:"plugin-drop"`
 }
 
 type BuilderCounter struct {
@@ -126,6 +128,7 @@ type Decoder struct {
 	inQueue          queue.QueueReader
 	slowDecodeQueue  queue.QueueWriter
 	prometheusWriter *dbwriter.PrometheusWriter
+	pluginRunner     *wasm.Runner
 	debugEnabled     bool
 	config           *config.Config
 
@@ -142,6 +145,7 @@ func NewDecoder(
 	inQueue queue.QueueReader,
 	slowDecodeQueue queue.QueueWriter,
 	prometheusWriter *dbwriter.PrometheusWriter,
+	pluginRunner *wasm.Runner,
 	config *config.Config,
 ) *Decoder {
 	return &Decoder{
@@ -151,6 +155,7 @@ func NewDecoder(
 		slowDecodeQueue:  slowDecodeQueue,
 		debugEnabled:     log.IsEnabledFor(logging.DEBUG),
 		prometheusWriter: prometheusWriter,
+		pluginRunner:     pluginRunner,
 		config:           config,
 		counter:          &Counter{},
 	}
@@ -236,6 +241,9 @@ func (d *Decoder) sendPrometheus(vtapID uint16, ts *prompb.TimeSeries) {
 	if d.debugEnabled {
 		log.Debugf("decoder %d vtap %d recv promtheus timeseries: %v", d.index, vtapID, ts)
 	}
+	if d.dropByPlugins(ts) {
+		return
+	}
 	isSlowItem, err := d.samplesBuilder.TimeSeriesToStore(vtapID, ts)
 	if err != nil {
 		if d.counter.TimeSeriesErr == 0 {
@@ -255,6 +263,43 @@ func (d *Decoder) sendPrometheus(vtapID uint16, ts *prompb.TimeSeries) {
 	d.counter.TimeSeriesOut++
 }
 
+// 依次执行服务端WASM插件，插件可增加或修改标签，返回true表示插件要求丢弃该TimeSeries
+func (d *Decoder) dropByPlugins(ts *prompb.TimeSeries) bool {
+	if !d.pluginRunner.HasMetricSamplePlugins() {
+		return false
+	}
+	names := make([]string, len(ts.Labels))
+	values := make([]string, len(ts.Labels))
+	metricName := ""
+	for i, l := range ts.Labels {
+		names[i], values[i] = l.Name, l.Value
+		if l.Name == model.MetricNameLabel {
+			metricName = l.Value
+		}
+	}
+	var timestamp uint32
+	if len(ts.Samples) > 0 {
+		timestamp = uint32(ts.Samples[0].Timestamp / 1000)
+	}
+	in := wasm.NewMetricSample(wasm.SOURCE_PROMETHEUS, metricName, timestamp, names, values)
+	drop, changed := d.pluginRunner.OnMetricSample(in)
+	if drop {
+		d.counter.PluginDrop++
+		return true
+	}
+	if changed {
+		names, values = wasm.MergeAttributes(names, values, in.Labels)
+		for i := range names {
+			if i < len(ts.Labels) {
+				ts.Labels[i].Value = values[i]
+			} else {
+				ts.Labels = append(ts.Labels, prompb.Label{Name: names[i], Value: values[i]})
+			}
+		}
+	}
+	return false
+}
+
 func (b *PrometheusSamplesBuilder) TimeSeriesToStore(vtapID uint16, ts *prompb.TimeSeries) (bool, error) {
 	if len(ts.Samples) == 0 {
 		b.counter.TimeSeriesInvaild++
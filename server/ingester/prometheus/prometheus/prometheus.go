@@ -28,6 +28,7 @@ import (
 	"github.com/deepflowio/deepflow/server/ingester/prometheus/config"
 	"github.com/deepflowio/deepflow/server/ingester/prometheus/dbwriter"
 	"github.com/deepflowio/deepflow/server/ingester/prometheus/decoder"
+	"github.com/deepflowio/deepflow/server/ingester/wasm"
 	"github.com/deepflowio/deepflow/server/libs/datatype"
 	"github.com/deepflowio/deepflow/server/libs/debug"
 	"github.com/deepflowio/deepflow/server/libs/grpc"
@@ -45,7 +46,7 @@ type PrometheusHandler struct {
 	prometheusLabelTable *decoder.PrometheusLabelTable
 }
 
-func NewPrometheusHandler(config *config.Config, recv *receiver.Receiver, platformDataManager *grpc.PlatformDataManager) (*PrometheusHandler, error) {
+func NewPrometheusHandler(config *config.Config, recv *receiver.Receiver, platformDataManager *grpc.PlatformDataManager, pluginManager *wasm.Manager) (*PrometheusHandler, error) {
 	manager := dropletqueue.NewManager(ingesterctl.INGESTERCTL_PROMETHEUS_QUEUE)
 	queueCount := config.DecoderQueueCount
 	msgType := datatype.MESSAGE_TYPE_PROMETHEUS
@@ -92,6 +93,7 @@ func NewPrometheusHandler(config *config.Config, recv *receiver.Receiver, platfo
 			queue.QueueReader(decodeQueues.FixedMultiQueue[i]),
 			queue.QueueWriter(slowDecodeQueues.FixedMultiQueue[i]),
 			metricsWriter,
+			pluginManager.NewRunner(),
 			config,
 		)
 		slowMetricsWriter, err := dbwriter.NewPrometheusWriter(i, "slow-prometheus", dbwriter.PROMETHEUS_DB, config)
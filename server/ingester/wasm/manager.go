@@ -0,0 +1,242 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wasm
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	logging "github.com/op/go-logging"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+
+	"github.com/deepflowio/deepflow/message/trident"
+	"github.com/deepflowio/deepflow/server/ingester/common"
+	"github.com/deepflowio/deepflow/server/ingester/config"
+	"github.com/deepflowio/deepflow/server/libs/grpc"
+	"github.com/deepflowio/deepflow/server/libs/stats"
+)
+
+var log = logging.MustGetLogger("wasm")
+
+// Manager 定期从controller同步配置的插件，插件内容变化时重新编译，decoder通过各自的Runner执行插件
+type Manager struct {
+	ctlIP       string
+	GrpcSession *grpc.GrpcSession
+	version     uint64
+
+	ctx      context.Context
+	runtime  wazero.Runtime
+	configs  []config.WasmPlugin
+	names    []string
+	budgets  map[string]*Budget
+	counters map[string]*pluginCounter
+
+	current atomic.Value // *pluginSet
+}
+
+// NewManager 未配置插件时返回nil，nil的Manager创建的Runner不执行任何插件
+func NewManager(ips []net.IP, port, rpcMaxMsgSize int, configs []config.WasmPlugin) *Manager {
+	if len(configs) == 0 {
+		return nil
+	}
+	ctx := context.Background()
+	// 调用超时时wazero会中止插件的执行，避免死循环的插件阻塞decoder
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	wasi_snapshot_preview1.MustInstantiate(ctx, runtime)
+
+	m := &Manager{
+		GrpcSession: &grpc.GrpcSession{},
+		ctx:         ctx,
+		runtime:     runtime,
+		budgets:     make(map[string]*Budget),
+		counters:    make(map[string]*pluginCounter),
+	}
+	for _, c := range configs {
+		if _, ok := m.counters[c.Name]; ok {
+			continue
+		}
+		m.configs = append(m.configs, c)
+		m.names = append(m.names, c.Name)
+		m.budgets[c.Name] = NewBudget(time.Duration(c.CPUBudget) * time.Millisecond)
+		m.counters[c.Name] = &pluginCounter{}
+		common.RegisterCountableForIngester("wasm_plugin", m.counters[c.Name], stats.OptionStatTags{"name": c.Name})
+	}
+	m.current.Store(&pluginSet{})
+
+	runOnce := func() {
+		if err := m.Reload(); err != nil {
+			log.Warning(err)
+		}
+	}
+	m.GrpcSession.Init(ips, uint16(port), grpc.DEFAULT_SYNC_INTERVAL, rpcMaxMsgSize, runOnce)
+	if err := m.Reload(); err != nil {
+		log.Warning(err)
+	}
+	log.Infof("New wasm plugin manager ips:%v port:%d plugins:%v", ips, port, m.names)
+	return m
+}
+
+func (m *Manager) Start() {
+	if m == nil {
+		return
+	}
+	m.GrpcSession.Start()
+}
+
+func (m *Manager) Close() error {
+	if m == nil {
+		return nil
+	}
+	m.GrpcSession.Close()
+	for _, counter := range m.counters {
+		counter.Close()
+	}
+	return m.runtime.Close(m.ctx)
+}
+
+func (m *Manager) Reload() error {
+	var response *trident.ServerPluginSyncResponse
+	err := m.GrpcSession.Request(func(ctx context.Context, remote net.IP) error {
+		var err error
+		if m.ctlIP == "" {
+			var local net.IP
+			// 根据remote ip获取本端ip
+			if local, err = grpc.Lookup(remote); err != nil {
+				return err
+			}
+			m.ctlIP = local.String()
+		}
+
+		request := trident.ServerPluginSyncRequest{
+			CtrlIp:      proto.String(m.ctlIP),
+			PluginType:  trident.PluginType_WASM.Enum(),
+			PluginNames: m.names,
+			Version:     proto.Uint64(m.version),
+		}
+		c := m.GrpcSession.GetClient()
+		if c == nil {
+			return fmt.Errorf("can't get grpc client to %s", remote)
+		}
+		client := trident.NewSynchronizerClient(c)
+		response, err = client.ServerPluginSync(ctx, &request)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if status := response.GetStatus(); status != trident.Status_SUCCESS {
+		return fmt.Errorf("grpc server plugin response failed. responseStatus is %v", status)
+	}
+
+	newVersion := response.GetVersion()
+	if newVersion == m.version {
+		return nil
+	}
+	m.update(response.GetPlugins())
+	log.Infof("wasm plugin update rpc version %d -> %d", m.version, newVersion)
+	m.version = newVersion
+	return nil
+}
+
+// 按配置的顺序生成新的插件集合，内容未变化的插件直接复用，编译失败时继续使用旧的插件
+func (m *Manager) update(serverPlugins []*trident.ServerPlugin) {
+	nameToServerPlugin := make(map[string]*trident.ServerPlugin, len(serverPlugins))
+	for _, sp := range serverPlugins {
+		nameToServerPlugin[sp.GetName()] = sp
+	}
+	old := m.current.Load().(*pluginSet)
+	nameToOldPlugin := make(map[string]*Plugin, len(old.plugins))
+	for _, p := range old.plugins {
+		nameToOldPlugin[p.name] = p
+	}
+
+	set := &pluginSet{}
+	kept := make(map[*Plugin]bool)
+	for _, c := range m.configs {
+		sp, ok := nameToServerPlugin[c.Name]
+		if !ok {
+			log.Warningf("wasm plugin %s is not found in controller", c.Name)
+			continue
+		}
+		oldPlugin := nameToOldPlugin[c.Name]
+		if oldPlugin != nil && oldPlugin.digest == sp.GetDigest() {
+			set.add(oldPlugin)
+			kept[oldPlugin] = true
+			continue
+		}
+		p, err := m.compile(c, sp)
+		if err != nil {
+			log.Warningf("compile wasm plugin %s (digest %s) failed: %s", c.Name, sp.GetDigest(), err)
+			atomic.AddInt64(&m.counters[c.Name].ErrorCount, 1)
+			if oldPlugin != nil {
+				set.add(oldPlugin)
+				kept[oldPlugin] = true
+			}
+			continue
+		}
+		log.Infof("wasm plugin %s (digest %s) loaded, on_l7_flow_log: %t, on_metric_sample: %t", c.Name, p.digest, p.onL7FlowLog, p.onMetricSample)
+		set.add(p)
+	}
+	m.current.Store(set)
+
+	// decoder在下次执行插件时切换到新的集合，此后再释放旧插件的编译结果
+	for _, p := range old.plugins {
+		if kept[p] {
+			continue
+		}
+		compiled := p.compiled
+		time.AfterFunc(grpc.DEFAULT_SYNC_INTERVAL, func() {
+			compiled.Close(m.ctx)
+		})
+	}
+}
+
+func (m *Manager) compile(c config.WasmPlugin, sp *trident.ServerPlugin) (*Plugin, error) {
+	compiled, err := m.runtime.CompileModule(m.ctx, sp.GetContent())
+	if err != nil {
+		return nil, err
+	}
+	onL7FlowLog, onMetricSample, err := checkExports(compiled)
+	if err != nil {
+		compiled.Close(m.ctx)
+		return nil, err
+	}
+	return &Plugin{
+		name:           c.Name,
+		digest:         sp.GetDigest(),
+		compiled:       compiled,
+		onL7FlowLog:    onL7FlowLog,
+		onMetricSample: onMetricSample,
+		timeout:        time.Duration(c.Timeout) * time.Millisecond,
+		budget:         m.budgets[c.Name],
+		counter:        m.counters[c.Name],
+	}, nil
+}
+
+// NewRunner 为decoder协程创建插件执行器，Runner不能在多个协程中并发使用
+func (m *Manager) NewRunner() *Runner {
+	if m == nil {
+		return nil
+	}
+	return &Runner{manager: m}
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wasm
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+
+	"github.com/deepflowio/deepflow/server/libs/utils"
+)
+
+// 插件需要导出memory及以下函数:
+// - allocate(size i32) i32: 分配size字节的内存，返回地址
+// - deallocate(ptr i32, size i32): 可选，释放allocate分配的内存及插件返回的结果
+// - on_l7_flow_log(ptr i32, size i32) i64: 可选，输入为L7FlowLog的JSON
+// - on_metric_sample(ptr i32, size i32) i64: 可选，输入为MetricSample的JSON
+// on_*函数返回0表示不修改，-1表示丢弃，其它值的高32位为结果JSON的地址，低32位为长度
+const (
+	FUNCTION_ALLOCATE         = "allocate"
+	FUNCTION_DEALLOCATE       = "deallocate"
+	FUNCTION_ON_L7_FLOW_LOG   = "on_l7_flow_log"
+	FUNCTION_ON_METRIC_SAMPLE = "on_metric_sample"
+	// reactor模式的模块在实例化时执行_initialize，而不是_start
+	FUNCTION_INITIALIZE = "_initialize"
+
+	RESULT_KEEP = 0
+	RESULT_DROP = ^uint64(0) // i64 -1
+)
+
+type Counter struct {
+	CallCount    int64 `statsd:"call-count"`
+	DropCount    int64 `statsd:"drop-count"`
+	ErrorCount   int64 `statsd:"error-count"`
+	TimeoutCount int64 `statsd:"timeout-count"`
+	SkipCount    int64 `statsd:"skip-count"` // 超出CPU预算而跳过的次数
+	TotalTime    int64 `statsd:"total-time"` // us
+	AvgTime      int64 `statsd:"avg-time"`   // us
+}
+
+// 插件被所有decoder协程共享，计数需要原子操作
+type pluginCounter struct {
+	Counter
+	utils.Closable
+}
+
+func (c *pluginCounter) GetCounter() interface{} {
+	counter := &Counter{
+		CallCount:    atomic.SwapInt64(&c.CallCount, 0),
+		DropCount:    atomic.SwapInt64(&c.DropCount, 0),
+		ErrorCount:   atomic.SwapInt64(&c.ErrorCount, 0),
+		TimeoutCount: atomic.SwapInt64(&c.TimeoutCount, 0),
+		SkipCount:    atomic.SwapInt64(&c.SkipCount, 0),
+		TotalTime:    atomic.SwapInt64(&c.TotalTime, 0),
+	}
+	if counter.CallCount > 0 {
+		counter.AvgTime = counter.TotalTime / counter.CallCount
+	}
+	return counter
+}
+
+// Budget 限制插件每秒内的执行总时长，所有decoder协程共用
+type Budget struct {
+	limit  int64 // ns, 0表示不限制
+	second int64 // 当前统计周期, unix秒
+	used   int64 // ns
+}
+
+func NewBudget(limit time.Duration) *Budget {
+	return &Budget{limit: int64(limit)}
+}
+
+func (b *Budget) rotate(now time.Time) {
+	second := now.Unix()
+	if atomic.LoadInt64(&b.second) != second && atomic.SwapInt64(&b.second, second) != second {
+		atomic.StoreInt64(&b.used, 0)
+	}
+}
+
+// Exceeded 当前秒内的执行时长是否已超出预算
+func (b *Budget) Exceeded(now time.Time) bool {
+	if b.limit <= 0 {
+		return false
+	}
+	b.rotate(now)
+	return atomic.LoadInt64(&b.used) >= b.limit
+}
+
+func (b *Budget) Consume(now time.Time, cost time.Duration) {
+	if b.limit <= 0 {
+		return
+	}
+	b.rotate(now)
+	atomic.AddInt64(&b.used, int64(cost))
+}
+
+// Plugin 是编译后的插件，由Manager在插件内容变化时重新生成
+type Plugin struct {
+	name     string
+	digest   string
+	compiled wazero.CompiledModule
+
+	onL7FlowLog    bool
+	onMetricSample bool
+
+	timeout time.Duration
+	budget  *Budget
+	counter *pluginCounter
+}
+
+func checkSignature(functions map[string]api.FunctionDefinition, name string, params, results []api.ValueType) (bool, error) {
+	definition, ok := functions[name]
+	if !ok {
+		return false, nil
+	}
+	if !equalValueTypes(definition.ParamTypes(), params) || !equalValueTypes(definition.ResultTypes(), results) {
+		return false, fmt.Errorf("function %s signature mismatch, params %v results %v", name, definition.ParamTypes(), definition.ResultTypes())
+	}
+	return true, nil
+}
+
+func equalValueTypes(a, b []api.ValueType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// 检查插件导出的函数，返回插件支持的数据类型
+func checkExports(compiled wazero.CompiledModule) (onL7FlowLog, onMetricSample bool, err error) {
+	i32, i64 := api.ValueTypeI32, api.ValueTypeI64
+	functions := compiled.ExportedFunctions()
+	if ok, err := checkSignature(functions, FUNCTION_ALLOCATE, []api.ValueType{i32}, []api.ValueType{i32}); err != nil {
+		return false, false, err
+	} else if !ok {
+		return false, false, fmt.Errorf("function %s is not exported", FUNCTION_ALLOCATE)
+	}
+	if _, err := checkSignature(functions, FUNCTION_DEALLOCATE, []api.ValueType{i32, i32}, nil); err != nil {
+		return false, false, err
+	}
+	if onL7FlowLog, err = checkSignature(functions, FUNCTION_ON_L7_FLOW_LOG, []api.ValueType{i32, i32}, []api.ValueType{i64}); err != nil {
+		return false, false, err
+	}
+	if onMetricSample, err = checkSignature(functions, FUNCTION_ON_METRIC_SAMPLE, []api.ValueType{i32, i32}, []api.ValueType{i64}); err != nil {
+		return false, false, err
+	}
+	if !onL7FlowLog && !onMetricSample {
+		return false, false, fmt.Errorf("neither %s nor %s is exported", FUNCTION_ON_L7_FLOW_LOG, FUNCTION_ON_METRIC_SAMPLE)
+	}
+	return onL7FlowLog, onMetricSample, nil
+}
+
+type pluginSet struct {
+	plugins         []*Plugin
+	hasL7FlowLog    bool
+	hasMetricSample bool
+}
+
+func (s *pluginSet) add(p *Plugin) {
+	s.plugins = append(s.plugins, p)
+	s.hasL7FlowLog = s.hasL7FlowLog || p.onL7FlowLog
+	s.hasMetricSample = s.hasMetricSample || p.onMetricSample
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wasm
+
+import (
+	"sort"
+)
+
+const (
+	SOURCE_PROMETHEUS = "prometheus"
+	SOURCE_TELEGRAF   = "telegraf"
+)
+
+// L7FlowLog 是传递给插件on_l7_flow_log函数的JSON内容，插件执行后Endpoint和Attributes会被更新
+type L7FlowLog struct {
+	Time              uint32            `json:"time"` // s
+	L7Protocol        string            `json:"l7_protocol"`
+	Version           string            `json:"version"`
+	Type              uint8             `json:"type"`
+	RequestType       string            `json:"request_type"`
+	RequestDomain     string            `json:"request_domain"`
+	RequestResource   string            `json:"request_resource"`
+	Endpoint          string            `json:"endpoint"`
+	ResponseStatus    uint8             `json:"response_status"`
+	ResponseCode      *int32            `json:"response_code,omitempty"`
+	ResponseException string            `json:"response_exception"`
+	ResponseDuration  uint64            `json:"response_duration"` // us
+	TraceId           string            `json:"trace_id"`
+	SpanId            string            `json:"span_id"`
+	AppService        string            `json:"app_service"`
+	AppInstance       string            `json:"app_instance"`
+	Attributes        map[string]string `json:"attributes"`
+}
+
+// L7FlowLogResult 是插件on_l7_flow_log函数返回的JSON内容
+type L7FlowLogResult struct {
+	Drop       bool              `json:"drop"`
+	Endpoint   *string           `json:"endpoint,omitempty"`   // 非空时改写endpoint
+	Attributes map[string]string `json:"attributes,omitempty"` // 增加或修改的属性
+}
+
+// MetricSample 是传递给插件on_metric_sample函数的JSON内容，插件执行后Labels会被更新
+// prometheus的Name为指标名称，telegraf的Name为measurement
+type MetricSample struct {
+	Source string            `json:"source"`
+	Name   string            `json:"name"`
+	Time   uint32            `json:"time"` // s
+	Labels map[string]string `json:"labels"`
+}
+
+// MetricSampleResult 是插件on_metric_sample函数返回的JSON内容
+type MetricSampleResult struct {
+	Drop   bool              `json:"drop"`
+	Labels map[string]string `json:"labels,omitempty"` // 增加或修改的标签
+}
+
+// MergeAttributes 将插件执行后的属性合并到names/values中，已有的属性原地修改，新增的属性按名称排序后追加
+func MergeAttributes(names, values []string, attributes map[string]string) ([]string, []string) {
+	added := make([]string, 0, len(attributes))
+	for name, value := range attributes {
+		found := false
+		for i := range names {
+			if names[i] == name {
+				values[i] = value
+				found = true
+				break
+			}
+		}
+		if !found {
+			added = append(added, name)
+		}
+	}
+	sort.Strings(added)
+	for _, name := range added {
+		names = append(names, name)
+		values = append(values, attributes[name])
+	}
+	return names, values
+}
+
+func toAttributes(names, values []string) map[string]string {
+	attributes := make(map[string]string, len(names))
+	for i := range names {
+		if i < len(values) {
+			attributes[names[i]] = values[i]
+		}
+	}
+	return attributes
+}
+
+// NewL7FlowLog 根据属性名称和值构建插件的输入，其它字段由调用方填充
+func NewL7FlowLog(attributeNames, attributeValues []string) *L7FlowLog {
+	return &L7FlowLog{Attributes: toAttributes(attributeNames, attributeValues)}
+}
+
+// NewMetricSample 根据标签名称和值构建插件的输入
+func NewMetricSample(source, name string, time uint32, labelNames, labelValues []string) *MetricSample {
+	return &MetricSample{
+		Source: source,
+		Name:   name,
+		Time:   time,
+		Labels: toAttributes(labelNames, labelValues),
+	}
+}
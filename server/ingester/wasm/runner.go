@@ -0,0 +1,273 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wasm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// 插件在decoder协程中的实例，执行出错或超时后实例被关闭，下次调用时重新实例化
+type instance struct {
+	plugin *Plugin
+	module api.Module
+
+	allocate       api.Function
+	deallocate     api.Function
+	onL7FlowLog    api.Function
+	onMetricSample api.Function
+}
+
+func (i *instance) instantiate(ctx context.Context, runtime wazero.Runtime) error {
+	module, err := runtime.InstantiateModule(ctx, i.plugin.compiled,
+		wazero.NewModuleConfig().WithName("").WithStartFunctions(FUNCTION_INITIALIZE))
+	if err != nil {
+		return err
+	}
+	if module.Memory() == nil {
+		module.Close(ctx)
+		return fmt.Errorf("memory is not exported")
+	}
+	i.module = module
+	i.allocate = module.ExportedFunction(FUNCTION_ALLOCATE)
+	i.deallocate = module.ExportedFunction(FUNCTION_DEALLOCATE)
+	i.onL7FlowLog = module.ExportedFunction(FUNCTION_ON_L7_FLOW_LOG)
+	i.onMetricSample = module.ExportedFunction(FUNCTION_ON_METRIC_SAMPLE)
+	return nil
+}
+
+func (i *instance) close(ctx context.Context) {
+	if i.module != nil {
+		i.module.Close(ctx)
+		i.module = nil
+	}
+}
+
+func (i *instance) free(ctx context.Context, ptr, size uint32) error {
+	if i.deallocate == nil {
+		return nil
+	}
+	_, err := i.deallocate.Call(ctx, uint64(ptr), uint64(size))
+	return err
+}
+
+// 将input写入插件内存后调用function，插件返回结果时解析到result中
+func (i *instance) invoke(ctx context.Context, function api.Function, input []byte, result interface{}) (drop, changed bool, err error) {
+	ret, err := i.allocate.Call(ctx, uint64(len(input)))
+	if err != nil {
+		return false, false, err
+	}
+	ptr, size := uint32(ret[0]), uint32(len(input))
+	if !i.module.Memory().Write(ptr, input) {
+		return false, false, fmt.Errorf("write %d bytes at %d out of memory range", size, ptr)
+	}
+	ret, err = function.Call(ctx, uint64(ptr), uint64(size))
+	if err != nil {
+		return false, false, err
+	}
+	if err := i.free(ctx, ptr, size); err != nil {
+		return false, false, err
+	}
+
+	switch ret[0] {
+	case RESULT_KEEP:
+		return false, false, nil
+	case RESULT_DROP:
+		return true, false, nil
+	}
+	resultPtr, resultSize := uint32(ret[0]>>32), uint32(ret[0])
+	buffer, ok := i.module.Memory().Read(resultPtr, resultSize)
+	if !ok {
+		return false, false, fmt.Errorf("read %d bytes at %d out of memory range", resultSize, resultPtr)
+	}
+	// buffer引用的是插件内存，需在释放前完成解析
+	if err := json.Unmarshal(buffer, result); err != nil {
+		return false, false, fmt.Errorf("unmarshal result failed: %s", err)
+	}
+	if err := i.free(ctx, resultPtr, resultSize); err != nil {
+		return false, false, err
+	}
+	return false, true, nil
+}
+
+// Runner 在单个decoder协程中依次执行插件，前一个插件的修改对后一个插件可见
+// wazero的模块实例不能并发调用，因此每个decoder持有各自的Runner
+type Runner struct {
+	manager   *Manager
+	set       *pluginSet
+	instances []*instance
+}
+
+// 插件集合更新后，关闭旧插件的实例，新插件在首次调用时实例化
+func (r *Runner) refresh() {
+	set := r.manager.current.Load().(*pluginSet)
+	if set == r.set {
+		return
+	}
+	for _, i := range r.instances {
+		i.close(r.manager.ctx)
+	}
+	r.set = set
+	r.instances = make([]*instance, 0, len(set.plugins))
+	for _, p := range set.plugins {
+		r.instances = append(r.instances, &instance{plugin: p})
+	}
+}
+
+func (r *Runner) HasL7FlowLogPlugins() bool {
+	if r == nil {
+		return false
+	}
+	r.refresh()
+	return r.set.hasL7FlowLog
+}
+
+func (r *Runner) HasMetricSamplePlugins() bool {
+	if r == nil {
+		return false
+	}
+	r.refresh()
+	return r.set.hasMetricSample
+}
+
+// 执行插件的一次调用，超出CPU预算时跳过，出错或超时时不修改数据
+func (r *Runner) call(i *instance, function string, input, result interface{}) (drop, changed bool) {
+	p := i.plugin
+	start := time.Now()
+	if p.budget.Exceeded(start) {
+		atomic.AddInt64(&p.counter.SkipCount, 1)
+		return false, false
+	}
+	data, err := json.Marshal(input)
+	if err != nil {
+		r.onError(i, fmt.Errorf("marshal input failed: %s", err), false)
+		return false, false
+	}
+	if i.module == nil {
+		if err := i.instantiate(r.manager.ctx, r.manager.runtime); err != nil {
+			r.onError(i, fmt.Errorf("instantiate failed: %s", err), false)
+			return false, false
+		}
+	}
+
+	var fn api.Function
+	if function == FUNCTION_ON_L7_FLOW_LOG {
+		fn = i.onL7FlowLog
+	} else {
+		fn = i.onMetricSample
+	}
+	ctx, cancel := context.WithTimeout(r.manager.ctx, p.timeout)
+	drop, changed, err = i.invoke(ctx, fn, data, result)
+	timeout := ctx.Err() == context.DeadlineExceeded
+	cancel()
+
+	cost := time.Since(start)
+	p.budget.Consume(start, cost)
+	atomic.AddInt64(&p.counter.CallCount, 1)
+	atomic.AddInt64(&p.counter.TotalTime, int64(cost/time.Microsecond))
+	if err != nil {
+		// 插件内存状态可能已不一致，关闭实例，下次调用时重新实例化
+		i.close(r.manager.ctx)
+		r.onError(i, fmt.Errorf("call %s failed: %s", function, err), timeout)
+		return false, false
+	}
+	if drop {
+		atomic.AddInt64(&p.counter.DropCount, 1)
+	}
+	return drop, changed
+}
+
+func (r *Runner) onError(i *instance, err error, timeout bool) {
+	p := i.plugin
+	var count int64
+	if timeout {
+		count = atomic.AddInt64(&p.counter.TimeoutCount, 1)
+	} else {
+		count = atomic.AddInt64(&p.counter.ErrorCount, 1)
+	}
+	// 每个统计周期只打印第一次错误
+	if count == 1 {
+		log.Warningf("wasm plugin %s (digest %s) %s", p.name, p.digest, err)
+	}
+}
+
+// OnL7FlowLog 依次执行导出了on_l7_flow_log的插件，l的Endpoint和Attributes被原地修改
+// drop为true表示插件要求丢弃该日志，changed为true表示l被修改
+func (r *Runner) OnL7FlowLog(l *L7FlowLog) (drop, changed bool) {
+	if !r.HasL7FlowLogPlugins() {
+		return false, false
+	}
+	for _, i := range r.instances {
+		if !i.plugin.onL7FlowLog {
+			continue
+		}
+		result := L7FlowLogResult{}
+		d, c := r.call(i, FUNCTION_ON_L7_FLOW_LOG, l, &result)
+		if d || result.Drop {
+			if !d {
+				atomic.AddInt64(&i.plugin.counter.DropCount, 1)
+			}
+			return true, changed
+		}
+		if !c {
+			continue
+		}
+		if result.Endpoint != nil {
+			l.Endpoint = *result.Endpoint
+			changed = true
+		}
+		for name, value := range result.Attributes {
+			l.Attributes[name] = value
+			changed = true
+		}
+	}
+	return false, changed
+}
+
+// OnMetricSample 依次执行导出了on_metric_sample的插件，s的Labels被原地修改
+func (r *Runner) OnMetricSample(s *MetricSample) (drop, changed bool) {
+	if !r.HasMetricSamplePlugins() {
+		return false, false
+	}
+	for _, i := range r.instances {
+		if !i.plugin.onMetricSample {
+			continue
+		}
+		result := MetricSampleResult{}
+		d, c := r.call(i, FUNCTION_ON_METRIC_SAMPLE, s, &result)
+		if d || result.Drop {
+			if !d {
+				atomic.AddInt64(&i.plugin.counter.DropCount, 1)
+			}
+			return true, changed
+		}
+		if !c {
+			continue
+		}
+		for name, value := range result.Labels {
+			s.Labels[name] = value
+			changed = true
+		}
+	}
+	return false, changed
+}
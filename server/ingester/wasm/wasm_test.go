@@ -0,0 +1,203 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wasm
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+)
+
+const (
+	testResultOffset = 4096
+	testResult       = `{"endpoint":"/api","attributes":{"plugin":"test"}}`
+)
+
+func uleb128(v uint64) []byte {
+	var b []byte
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if v == 0 {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+func sleb128(v int64) []byte {
+	var b []byte
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && c&0x40 == 0) || (v == -1 && c&0x40 != 0) {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+func wasmSection(id byte, content ...[]byte) []byte {
+	var c []byte
+	for _, b := range content {
+		c = append(c, b...)
+	}
+	return append(append([]byte{id}, uleb128(uint64(len(c)))...), c...)
+}
+
+func wasmName(name string) []byte {
+	return append(uleb128(uint64(len(name))), name...)
+}
+
+func wasmBody(code ...byte) []byte {
+	// 无局部变量
+	return append(append(uleb128(uint64(len(code)+1)), 0), code...)
+}
+
+// 生成测试插件，等价于:
+// (module
+//
+//	(memory (export "memory") 1)
+//	(func (export "allocate") (param i32) (result i32) i32.const 0)
+//	(func (export "on_l7_flow_log") (param i32 i32) (result i64) i64.const (testResultOffset<<32 | len(testResult)))
+//	(func (export "on_metric_sample") (param i32 i32) (result i64) i64.const -1)
+//	(data (i32.const testResultOffset) testResult))
+func testPluginContent() []byte {
+	result := int64(testResultOffset<<32 | len(testResult))
+	module := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	module = append(module, wasmSection(1, []byte{
+		0x02,
+		0x60, 0x01, 0x7f, 0x01, 0x7f, // (i32) -> i32
+		0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7e, // (i32, i32) -> i64
+	})...)
+	module = append(module, wasmSection(3, []byte{0x03, 0x00, 0x01, 0x01})...)
+	module = append(module, wasmSection(5, []byte{0x01, 0x00, 0x01})...)
+	module = append(module, wasmSection(7,
+		[]byte{0x04},
+		wasmName("memory"), []byte{0x02, 0x00},
+		wasmName(FUNCTION_ALLOCATE), []byte{0x00, 0x00},
+		wasmName(FUNCTION_ON_L7_FLOW_LOG), []byte{0x00, 0x01},
+		wasmName(FUNCTION_ON_METRIC_SAMPLE), []byte{0x00, 0x02},
+	)...)
+	module = append(module, wasmSection(10,
+		[]byte{0x03},
+		wasmBody(0x41, 0x00, 0x0b),
+		wasmBody(append(append([]byte{0x42}, sleb128(result)...), 0x0b)...),
+		wasmBody(0x42, 0x7f, 0x0b),
+	)...)
+	module = append(module, wasmSection(11,
+		[]byte{0x01, 0x00, 0x41}, sleb128(testResultOffset), []byte{0x0b},
+		wasmName(testResult),
+	)...)
+	return module
+}
+
+func newTestRunner(t *testing.T, content []byte) (*Runner, *Plugin) {
+	ctx := context.Background()
+	runtime := wazero.NewRuntime(ctx)
+	t.Cleanup(func() { runtime.Close(ctx) })
+
+	compiled, err := runtime.CompileModule(ctx, content)
+	if err != nil {
+		t.Fatalf("compile failed: %s", err)
+	}
+	onL7FlowLog, onMetricSample, err := checkExports(compiled)
+	if err != nil {
+		t.Fatalf("check exports failed: %s", err)
+	}
+	p := &Plugin{
+		name:           "test",
+		compiled:       compiled,
+		onL7FlowLog:    onL7FlowLog,
+		onMetricSample: onMetricSample,
+		timeout:        time.Second,
+		budget:         NewBudget(0),
+		counter:        &pluginCounter{},
+	}
+	set := &pluginSet{}
+	set.add(p)
+	m := &Manager{ctx: ctx, runtime: runtime}
+	m.current.Store(set)
+	return m.NewRunner(), p
+}
+
+func TestRunner(t *testing.T) {
+	r, p := newTestRunner(t, testPluginContent())
+	if !r.HasL7FlowLogPlugins() || !r.HasMetricSamplePlugins() {
+		t.Fatal("plugin exports not detected")
+	}
+
+	l := NewL7FlowLog([]string{"a"}, []string{"1"})
+	l.Endpoint = "/api/v1/users/1"
+	drop, changed := r.OnL7FlowLog(l)
+	if drop || !changed {
+		t.Errorf("on_l7_flow_log drop %t changed %t, expected false true", drop, changed)
+	}
+	if l.Endpoint != "/api" {
+		t.Errorf("endpoint not rewritten: %s", l.Endpoint)
+	}
+	if l.Attributes["a"] != "1" || l.Attributes["plugin"] != "test" {
+		t.Errorf("attributes not merged: %v", l.Attributes)
+	}
+
+	s := NewMetricSample(SOURCE_PROMETHEUS, "up", 1000, []string{"job"}, []string{"node"})
+	if drop, _ := r.OnMetricSample(s); !drop {
+		t.Error("on_metric_sample should drop the sample")
+	}
+
+	counter := p.counter.GetCounter().(*Counter)
+	if counter.CallCount != 2 || counter.DropCount != 1 || counter.ErrorCount != 0 {
+		t.Errorf("unexpected counter %+v", counter)
+	}
+}
+
+func TestMergeAttributes(t *testing.T) {
+	names := []string{"a", "b"}
+	values := []string{"1", "2"}
+	names, values = MergeAttributes(names, values, map[string]string{"b": "3", "d": "5", "c": "4"})
+	if strings.Join(names, ",") != "a,b,c,d" {
+		t.Errorf("merge names failed: %v", names)
+	}
+	if strings.Join(values, ",") != "1,3,4,5" {
+		t.Errorf("merge values failed: %v", values)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := NewBudget(10 * time.Millisecond)
+	if b.Exceeded(now) {
+		t.Error("budget should not be exceeded before consumed")
+	}
+	b.Consume(now, 6*time.Millisecond)
+	b.Consume(now.Add(500*time.Millisecond), 6*time.Millisecond)
+	if !b.Exceeded(now.Add(600 * time.Millisecond)) {
+		t.Error("budget should be exceeded in the same second")
+	}
+	if b.Exceeded(now.Add(time.Second)) {
+		t.Error("budget should be reset in the next second")
+	}
+
+	unlimited := NewBudget(0)
+	unlimited.Consume(now, time.Hour)
+	if unlimited.Exceeded(now) {
+		t.Error("zero budget means no limit")
+	}
+}
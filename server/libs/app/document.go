@@ -23,6 +23,7 @@ import (
 	"github.com/deepflowio/deepflow/server/libs/ckdb"
 	"github.com/deepflowio/deepflow/server/libs/codec"
 	"github.com/deepflowio/deepflow/server/libs/pool"
+	"github.com/deepflowio/deepflow/server/libs/utils"
 	"github.com/deepflowio/deepflow/server/libs/zerodoc"
 	"github.com/deepflowio/deepflow/server/libs/zerodoc/pb"
 )
@@ -45,6 +46,8 @@ type Document struct {
 
 const (
 	FLAG_PER_SECOND_METRICS DocumentFlag = 1 << iota
+	// 由ingester设置, 表示延迟到达并通过回填写入的数据
+	FLAG_BACKFILL
 )
 
 func (d Document) String() string {
@@ -159,9 +162,11 @@ func (d *Document) WriteToPB(p *pb.Document) error {
 	return nil
 }
 
+// 顺序需要和zerodoc.GetMetricsTables的字段一致
 func (d *Document) WriteBlock(block *ckdb.Block) {
 	d.Tagger.(*zerodoc.Tag).WriteBlock(block, d.Timestamp)
 	d.Meter.WriteBlock(block)
+	block.Write(utils.Bool2UInt8(d.Flags&FLAG_BACKFILL != 0))
 }
 
 func (d *Document) TableID() (uint8, error) {
@@ -54,6 +54,7 @@ type Table struct {
 	TimeKey         string       // 时间字段名，用来设置partition和ttl
 	SummingKey      string       // When using SummingMergeEngine, this field is used for Summing aggregation
 	TTL             int          // 数据默认保留时长。 单位:小时
+	TTLRules        []TTLRule    // 按tag条件设置的保留时长, 优先于TTL
 	ColdStorage     ColdStorage  // 冷存储配置
 	PartitionFunc   TimeFuncType // partition函数作用于Time,
 	Cluster         string       // 对应的cluster
@@ -100,10 +101,12 @@ func (t *Table) MakeLocalTableCreateSQL() string {
 	}
 	ttl := ""
 	if t.TTL > 0 {
-		ttl = fmt.Sprintf("TTL %s +  toIntervalHour(%d)", t.TimeKey, t.TTL)
-		if t.ColdStorage.Enabled {
-			ttl += fmt.Sprintf(", %s + toIntervalHour(%d) TO %s '%s'", t.TimeKey, t.ColdStorage.TTLToMove, t.ColdStorage.Type, t.ColdStorage.Name)
+		ttlString, err := MakeTTLString(t.TimeKey, t.TTL, t.TTLRules, t.ColumnNames(), &t.ColdStorage)
+		if err != nil {
+			log.Warningf("table %s.%s ignore ttl rules: %s", t.Database, t.LocalName, err)
+			ttlString, _ = MakeTTLString(t.TimeKey, t.TTL, nil, nil, &t.ColdStorage)
 		}
+		ttl = "TTL " + ttlString
 	}
 
 	createTable := fmt.Sprintf(`
@@ -126,6 +129,14 @@ SETTINGS storage_policy = '%s'`,
 	return createTable
 }
 
+func (t *Table) ColumnNames() []string {
+	names := make([]string, 0, len(t.Columns))
+	for _, c := range t.Columns {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
 func (t *Table) MakeGlobalTableCreateSQL() string {
 	engine := fmt.Sprintf(Distributed.String(), t.Cluster, t.Database, t.LocalName)
 	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s.`%s` AS %s.`%s` ENGINE=%s",
@@ -0,0 +1,191 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ckdb
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	TTL_OP_EQ     = "="
+	TTL_OP_NE     = "!="
+	TTL_OP_GT     = ">"
+	TTL_OP_GE     = ">="
+	TTL_OP_LT     = "<"
+	TTL_OP_LE     = "<="
+	TTL_OP_IN     = "in"
+	TTL_OP_NOT_IN = "not in"
+)
+
+var ttlTagRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// TTLCondition 按tag过滤数据, Tag为表中存储的原始列名, 而不是查询时的tag名称,
+// 例如命名空间需要使用pod_ns_id及其ID值, 而不是pod_ns及名称.
+// 若表中不存在该列, 但存在Tag_0和Tag_1(客户端/服务端)两列时, 表示任意一侧满足条件
+type TTLCondition struct {
+	Tag    string   `yaml:"tag" json:"tag"`
+	Op     string   `yaml:"op" json:"op"` // =, !=, >, >=, <, <=, in, not in
+	Values []string `yaml:"values" json:"values"`
+}
+
+// TTLRule 满足所有Conditions的数据保留TTL小时. 多条规则按顺序匹配, 只有第一条匹配的规则生效,
+// 未匹配任何规则的数据使用表的默认TTL
+type TTLRule struct {
+	Conditions []TTLCondition `yaml:"where" json:"where"`
+	TTL        int            `yaml:"ttl-hour" json:"ttl-hour"`
+}
+
+func GetTTLRules(ttlRules map[string][]TTLRule, db, table string) []TTLRule {
+	if rules, ok := ttlRules[db+table]; ok {
+		return rules
+	}
+	return ttlRules[db]
+}
+
+func (c *TTLCondition) Validate() error {
+	if !ttlTagRegexp.MatchString(c.Tag) {
+		return fmt.Errorf("invalid tag '%s'", c.Tag)
+	}
+	switch strings.ToLower(c.Op) {
+	case TTL_OP_EQ, TTL_OP_NE, TTL_OP_GT, TTL_OP_GE, TTL_OP_LT, TTL_OP_LE:
+		if len(c.Values) != 1 {
+			return fmt.Errorf("op '%s' of tag '%s' requires exactly 1 value, got %d", c.Op, c.Tag, len(c.Values))
+		}
+	case TTL_OP_IN, TTL_OP_NOT_IN:
+		if len(c.Values) == 0 {
+			return fmt.Errorf("op '%s' of tag '%s' requires at least 1 value", c.Op, c.Tag)
+		}
+	default:
+		return fmt.Errorf("unsupported op '%s' of tag '%s'", c.Op, c.Tag)
+	}
+	return nil
+}
+
+func (r *TTLRule) Validate() error {
+	if r.TTL <= 0 {
+		return fmt.Errorf("ttl-hour is %d, should > 0", r.TTL)
+	}
+	if len(r.Conditions) == 0 {
+		return fmt.Errorf("conditions is empty")
+	}
+	for i := range r.Conditions {
+		if err := r.Conditions[i].Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 数字直接使用, 其他按字符串处理并转义
+func ttlValueString(value string) string {
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+func (c *TTLCondition) columnExpr(column string) string {
+	values := make([]string, 0, len(c.Values))
+	for _, v := range c.Values {
+		values = append(values, ttlValueString(v))
+	}
+	switch op := strings.ToLower(c.Op); op {
+	case TTL_OP_IN, TTL_OP_NOT_IN:
+		return fmt.Sprintf("%s %s (%s)", column, strings.ToUpper(op), strings.Join(values, ", "))
+	default:
+		return fmt.Sprintf("%s %s %s", column, op, values[0])
+	}
+}
+
+func (c *TTLCondition) whereString(columns map[string]bool) (string, error) {
+	if err := c.Validate(); err != nil {
+		return "", err
+	}
+	if columns[c.Tag] {
+		return c.columnExpr(c.Tag), nil
+	}
+	side0, side1 := c.Tag+"_0", c.Tag+"_1"
+	if !columns[side0] || !columns[side1] {
+		return "", fmt.Errorf("unknown tag '%s'", c.Tag)
+	}
+
+	// 任意一侧: 否定的条件取反后再判断任意一侧满足, 例如 pod_ns_id != 1 表示两侧都不为1
+	negative := *c
+	switch strings.ToLower(c.Op) {
+	case TTL_OP_NE:
+		negative.Op = TTL_OP_EQ
+	case TTL_OP_NOT_IN:
+		negative.Op = TTL_OP_IN
+	default:
+		return fmt.Sprintf("%s OR %s", c.columnExpr(side0), c.columnExpr(side1)), nil
+	}
+	return fmt.Sprintf("NOT (%s OR %s)", negative.columnExpr(side0), negative.columnExpr(side1)), nil
+}
+
+// WhereString 生成规则对应的ClickHouse过滤条件, columns为表的所有列名
+func (r *TTLRule) WhereString(columns []string) (string, error) {
+	if err := r.Validate(); err != nil {
+		return "", err
+	}
+	columnSet := make(map[string]bool, len(columns))
+	for _, c := range columns {
+		columnSet[c] = true
+	}
+	wheres := make([]string, 0, len(r.Conditions))
+	for i := range r.Conditions {
+		where, err := r.Conditions[i].whereString(columnSet)
+		if err != nil {
+			return "", err
+		}
+		wheres = append(wheres, "("+where+")")
+	}
+	return strings.Join(wheres, " AND "), nil
+}
+
+// MakeTTLString 生成 'TTL' 之后的表达式. 默认TTL总是在第一个, 用于修改TTL时从建表语句中解析出默认保留时长.
+// 例如: time + toIntervalHour(72) DELETE WHERE NOT ((a)), time + toIntervalHour(720) DELETE WHERE (a)
+func MakeTTLString(timeKey string, ttl int, rules []TTLRule, columns []string, coldStorage *ColdStorage) (string, error) {
+	ttlString := fmt.Sprintf("%s + toIntervalHour(%d)", timeKey, ttl)
+	wheres := make([]string, 0, len(rules))
+	for i := range rules {
+		where, err := rules[i].WhereString(columns)
+		if err != nil {
+			return "", fmt.Errorf("ttl rule %d: %s", i, err)
+		}
+		wheres = append(wheres, "("+where+")")
+	}
+	if len(wheres) > 0 {
+		ttlString += fmt.Sprintf(" DELETE WHERE NOT (%s)", strings.Join(wheres, " OR "))
+	}
+	for i, rule := range rules {
+		where := wheres[i]
+		// 前面的规则优先
+		if i > 0 {
+			where = fmt.Sprintf("%s AND NOT (%s)", wheres[i], strings.Join(wheres[:i], " OR "))
+		}
+		ttlString += fmt.Sprintf(", %s + toIntervalHour(%d) DELETE WHERE %s", timeKey, rule.TTL, where)
+	}
+	if coldStorage != nil && coldStorage.Enabled {
+		ttlString += fmt.Sprintf(", %s + toIntervalHour(%d) TO %s '%s'", timeKey, coldStorage.TTLToMove, coldStorage.Type, coldStorage.Name)
+	}
+	return ttlString, nil
+}
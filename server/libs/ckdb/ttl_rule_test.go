@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ckdb
+
+import (
+	"testing"
+)
+
+func TestMakeTTLString(t *testing.T) {
+	columns := []string{"time", "pod_ns_id_0", "pod_ns_id_1", "response_status", "request_domain"}
+	rules := []TTLRule{
+		{
+			Conditions: []TTLCondition{{Tag: "pod_ns_id", Op: "in", Values: []string{"3", "5"}}},
+			TTL:        720,
+		},
+		{
+			Conditions: []TTLCondition{
+				{Tag: "response_status", Op: "IN", Values: []string{"3", "4"}},
+				{Tag: "request_domain", Op: "!=", Values: []string{"it's"}},
+			},
+			TTL: 336,
+		},
+	}
+
+	ttl, err := MakeTTLString("time", 72, rules, columns, &ColdStorage{Enabled: true, Type: Volume, Name: "cold", TTLToMove: 24})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r0 := "((pod_ns_id_0 IN (3, 5) OR pod_ns_id_1 IN (3, 5)))"
+	r1 := "((response_status IN (3, 4)) AND (request_domain != 'it\\'s'))"
+	expect := "time + toIntervalHour(72) DELETE WHERE NOT (" + r0 + " OR " + r1 + "), " +
+		"time + toIntervalHour(720) DELETE WHERE " + r0 + ", " +
+		"time + toIntervalHour(336) DELETE WHERE " + r1 + " AND NOT (" + r0 + "), " +
+		"time + toIntervalHour(24) TO VOLUME 'cold'"
+	if ttl != expect {
+		t.Errorf("MakeTTLString() =\n%s\nwant\n%s", ttl, expect)
+	}
+
+	ttl, err = MakeTTLString("time", 72, nil, nil, &ColdStorage{})
+	if err != nil || ttl != "time + toIntervalHour(72)" {
+		t.Errorf("MakeTTLString() = %s, %v", ttl, err)
+	}
+}
+
+func TestTTLConditionNegativeEitherSide(t *testing.T) {
+	columns := map[string]bool{"pod_ns_id_0": true, "pod_ns_id_1": true}
+	c := TTLCondition{Tag: "pod_ns_id", Op: "not in", Values: []string{"1"}}
+	where, err := c.whereString(columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "NOT (pod_ns_id_0 IN (1) OR pod_ns_id_1 IN (1))"; where != expect {
+		t.Errorf("whereString() = %s, want %s", where, expect)
+	}
+}
+
+func TestTTLRuleInvalid(t *testing.T) {
+	columns := []string{"time", "l7_protocol"}
+	invalids := []TTLRule{
+		{TTL: 24},
+		{Conditions: []TTLCondition{{Tag: "l7_protocol", Op: "=", Values: []string{"1"}}}},
+		{Conditions: []TTLCondition{{Tag: "l7_protocol", Op: "like", Values: []string{"1"}}}, TTL: 24},
+		{Conditions: []TTLCondition{{Tag: "l7_protocol", Op: "=", Values: []string{"1", "2"}}}, TTL: 24},
+		{Conditions: []TTLCondition{{Tag: "l7_protocol)", Op: "=", Values: []string{"1"}}}, TTL: 24},
+		{Conditions: []TTLCondition{{Tag: "pod_ns_id", Op: "=", Values: []string{"1"}}}, TTL: 24},
+	}
+	for i, rule := range invalids {
+		if _, err := rule.WhereString(columns); err == nil {
+			t.Errorf("rule %d should be invalid", i)
+		}
+	}
+}
@@ -49,6 +49,8 @@ const (
 	MESSAGE_TYPE_RAW_PCAP
 	MESSAGE_TYPE_PROFILE
 	MESSAGE_TYPE_PROC_EVENT
+	MESSAGE_TYPE_ZIPKIN
+	MESSAGE_TYPE_SKYWALKING
 	MESSAGE_TYPE_MAX
 )
 
@@ -70,6 +72,8 @@ var MessageTypeString = [MESSAGE_TYPE_MAX]string{
 	MESSAGE_TYPE_RAW_PCAP:                 "raw_pcap",
 	MESSAGE_TYPE_PROFILE:                  "profile",
 	MESSAGE_TYPE_PROC_EVENT:               "proc_event",
+	MESSAGE_TYPE_ZIPKIN:                   "zipkin",
+	MESSAGE_TYPE_SKYWALKING:               "skywalking",
 }
 
 func (m MessageType) String() string {
@@ -109,6 +113,8 @@ var MessageHeaderTypes = [MESSAGE_TYPE_MAX]MessageHeaderType{
 	MESSAGE_TYPE_RAW_PCAP:                 HEADER_TYPE_LT_VTAP,
 	MESSAGE_TYPE_PROFILE:                  HEADER_TYPE_LT_VTAP,
 	MESSAGE_TYPE_PROC_EVENT:               HEADER_TYPE_LT_VTAP,
+	MESSAGE_TYPE_ZIPKIN:                   HEADER_TYPE_LT_VTAP,
+	MESSAGE_TYPE_SKYWALKING:               HEADER_TYPE_LT_VTAP,
 }
 
 func (m MessageType) HeaderType() MessageHeaderType {
@@ -104,6 +104,8 @@ const (
 	_
 	SIGNAL_SOURCE_EBPF
 	SIGNAL_SOURCE_OTEL
+	SIGNAL_SOURCE_ZIPKIN
+	SIGNAL_SOURCE_SKYWALKING
 )
 
 type TcpPerfCountsPeer struct {
@@ -325,6 +327,10 @@ func (t SignalSource) String() string {
 		return "eBPF"
 	case SIGNAL_SOURCE_OTEL:
 		return "OTel"
+	case SIGNAL_SOURCE_ZIPKIN:
+		return "Zipkin"
+	case SIGNAL_SOURCE_SKYWALKING:
+		return "SkyWalking"
 	default:
 		return "unknown"
 	}
@@ -19,13 +19,28 @@ package eventapi
 import "github.com/deepflowio/deepflow/server/libs/pool"
 
 const (
-	RESOURCE_EVENT_TYPE_CREATE       = "create"
-	RESOURCE_EVENT_TYPE_DELETE       = "delete"
-	RESOURCE_EVENT_TYPE_UPDATE_STATE = "update-state"
-	RESOURCE_EVENT_TYPE_MIGRATE      = "migrate"
-	RESOURCE_EVENT_TYPE_RECREATE     = "recreate"
-	RESOURCE_EVENT_TYPE_ADD_IP       = "add-ip"
-	RESOURCE_EVENT_TYPE_REMOVE_IP    = "remove-ip"
+	RESOURCE_EVENT_TYPE_CREATE        = "create"
+	RESOURCE_EVENT_TYPE_DELETE        = "delete"
+	RESOURCE_EVENT_TYPE_UPDATE_STATE  = "update-state"
+	RESOURCE_EVENT_TYPE_MIGRATE       = "migrate"
+	RESOURCE_EVENT_TYPE_RECREATE      = "recreate"
+	RESOURCE_EVENT_TYPE_ADD_IP        = "add-ip"
+	RESOURCE_EVENT_TYPE_REMOVE_IP     = "remove-ip"
+	RESOURCE_EVENT_TYPE_UPDATE_CONFIG = "update-config"
+)
+
+// Kubernetes 事件的 Type 为事件的 reason，如 BackOff、OOMKilling、FailedScheduling
+const (
+	KUBERNETES_EVENT_TYPE_NORMAL  = "Normal"
+	KUBERNETES_EVENT_TYPE_WARNING = "Warning"
+)
+
+// 外部变更事件的 Type，也可由调用方自定义
+const (
+	CHANGE_EVENT_TYPE_DEPLOY       = "deploy"
+	CHANGE_EVENT_TYPE_CONFIG       = "config-change"
+	CHANGE_EVENT_TYPE_FEATURE_FLAG = "feature-flag"
+	CHANGE_EVENT_TYPE_ROLLBACK     = "rollback"
 )
 
 type ResourceEvent struct {
@@ -40,6 +55,10 @@ type ResourceEvent struct {
 	Description        string
 	GProcessID         uint32 // if this value is set, InstanceType and InstanceID are empty
 	GProcessName       string // if this value is set, InstanceName is empty
+	AttributeNames     []string
+	AttributeValues    []string
+	KubernetesType     string // if this value is set, it is a kubernetes event rather than a resource change event
+	IsChangeEvent      bool   // if this value is set, it is a change event pushed by external systems rather than a resource change event
 
 	IfNeedTagged bool // if need ingester set tag
 	RegionID     uint32
@@ -72,6 +91,13 @@ func TagAttributeIPs(ips []string) TagFieldOption {
 	}
 }
 
+func TagAttribute(name, value string) TagFieldOption {
+	return func(r *ResourceEvent) {
+		r.AttributeNames = append(r.AttributeNames, name)
+		r.AttributeValues = append(r.AttributeValues, value)
+	}
+}
+
 func TagDescription(description string) TagFieldOption {
 	return func(r *ResourceEvent) {
 		r.Description = description
@@ -150,6 +150,9 @@ type PlatformInfoTable struct {
 
 	versionGroups uint64
 	*ServiceTable
+	// 当前版本的服务和对等连接列表，用于应用增量
+	services             []*trident.ServiceInfo
+	peerConnectionProtos []*trident.PeerConnection
 
 	podNameInfos map[string][]*PodInfo
 	vtapIdInfos  map[uint32]*VtapInfo
@@ -866,6 +869,7 @@ func Lookup(host net.IP) (net.IP, error) {
 }
 
 func (t *PlatformInfoTable) updateServices(groupsData *trident.Groups) {
+	t.services = groupsData.GetSvcs()
 	t.ServiceTable = NewServiceTable(groupsData.GetSvcs())
 	t.counter.UpdateServicesCount += int64(len(groupsData.GetSvcs()))
 }
@@ -884,8 +888,22 @@ func (t *PlatformInfoTable) updatePlatformData(platformData *trident.PlatformDat
 	for _, cidr := range platformData.GetCidrs() {
 		updateCidrInfos(newEpcIDIPV4CidrInfos, newEpcIDIPV6CidrInfos, newEpcIDBaseInfos, cidr)
 	}
+	for _, cidrs := range newEpcIDIPV4CidrInfos {
+		sortCidrInfos(cidrs)
+	}
+	for _, cidrs := range newEpcIDIPV6CidrInfos {
+		sortCidrInfos(cidrs)
+	}
 	t.updatePeerConnections(platformData.GetPeerConnections())
 
+	t.replacePlatformData(newEpcIDIPV4Infos, newEpcIDIPV6Infos, newEpcIDIPV4CidrInfos, newEpcIDIPV6CidrInfos, newMacInfos, newEpcIDBaseInfos)
+}
+
+// 查询不加锁，需要整体替换各个表，同时清空缓存的查询结果
+func (t *PlatformInfoTable) replacePlatformData(
+	newEpcIDIPV4Infos map[uint64]*Info, newEpcIDIPV6Infos map[[EpcIDIPV6_LEN]byte]*Info,
+	newEpcIDIPV4CidrInfos, newEpcIDIPV6CidrInfos map[int32][]*CidrInfo,
+	newMacInfos map[uint64]*Info, newEpcIDBaseInfos map[int32]*BaseInfo) {
 	t.epcIDIPV4Infos = newEpcIDIPV4Infos
 	t.epcIDIPV4CidrInfos = newEpcIDIPV4CidrInfos
 	t.epcIDIPV4Lru.NoStats()
@@ -990,6 +1008,7 @@ func (t *PlatformInfoTable) ReloadMaster() error {
 			BootTime:            proto.Uint32(t.bootTime),
 			VersionPlatformData: proto.Uint64(t.versionPlatformData),
 			VersionGroups:       proto.Uint64(t.versionGroups),
+			SupportDelta:        proto.Bool(true),
 			CtrlIp:              proto.String(t.ctlIP),
 			ProcessName:         proto.String(t.moduleName),
 			Host:                proto.String(hostname),
@@ -1016,7 +1035,18 @@ func (t *PlatformInfoTable) ReloadMaster() error {
 	}
 
 	newGroupsVersion := response.GetVersionGroups()
-	if newGroupsVersion != t.versionGroups {
+	if compressed := response.GetGroupsDelta(); newGroupsVersion != t.versionGroups && compressed != nil {
+		groupsDelta := trident.GroupsDelta{}
+		if err := groupsDelta.Unmarshal(compressed); err != nil {
+			log.Warningf("unmarshal grpc groups delta failed as %v", err)
+		} else if groupsDelta.GetBaseVersion() != t.versionGroups {
+			log.Warningf("ignore rpc groups delta, base version %d != current version %d", groupsDelta.GetBaseVersion(), t.versionGroups)
+		} else {
+			log.Infof("Update rpc groups version %d -> %d by delta", t.versionGroups, newGroupsVersion)
+			t.applyGroupsDelta(&groupsDelta)
+			t.versionGroups = newGroupsVersion
+		}
+	} else if newGroupsVersion != t.versionGroups {
 		log.Infof("Update rpc groups version %d -> %d ", t.versionGroups, newGroupsVersion)
 		groupsData := trident.Groups{}
 		if compressed := response.GetGroups(); compressed != nil {
@@ -1034,7 +1064,19 @@ func (t *PlatformInfoTable) ReloadMaster() error {
 	t.counter.UpdateServiceTime += serviceTime
 
 	newVersion := response.GetVersionPlatformData()
-	if newVersion != t.versionPlatformData {
+	if compressed := response.GetPlatformDataDelta(); newVersion != t.versionPlatformData && compressed != nil {
+		platformDataDelta := trident.PlatformDataDelta{}
+		if err := platformDataDelta.Unmarshal(compressed); err != nil {
+			log.Warningf("unmarshal grpc platformData delta failed as %v", err)
+		} else if platformDataDelta.GetBaseVersion() != t.versionPlatformData {
+			log.Warningf("ignore rpc platformdata delta, base version %d != current version %d", platformDataDelta.GetBaseVersion(), t.versionPlatformData)
+		} else {
+			log.Infof("Update rpc platformdata version %d -> %d by delta regionID=%d", t.versionPlatformData, newVersion, t.regionID)
+			t.applyPlatformDataDelta(&platformDataDelta)
+			t.versionPlatformData = newVersion
+			t.otherRegionCount = 0
+		}
+	} else if newVersion != t.versionPlatformData {
 		platformData := trident.PlatformData{}
 		isUnmarshalSuccess := false
 		if plarformCompressed := response.GetPlatformData(); plarformCompressed != nil {
@@ -1097,12 +1139,10 @@ func isIPV4(ipStr string) bool {
 	return false
 }
 
-func updateCidrInfos(IPV4CidrInfos, IPV6CidrInfos map[int32][]*CidrInfo, epcIDBaseInfos map[int32]*BaseInfo, tridentCidr *trident.Cidr) {
-	prefix := tridentCidr.GetPrefix()
-	_, cidr, err := net.ParseCIDR(prefix)
+func newCidrInfo(tridentCidr *trident.Cidr) (*CidrInfo, error) {
+	_, cidr, err := net.ParseCIDR(tridentCidr.GetPrefix())
 	if err != nil {
-		log.Warningf("parse cidr(%s) failed. err=%s", err)
-		return
+		return nil, err
 	}
 
 	epcID := tridentCidr.GetEpcId()
@@ -1110,16 +1150,26 @@ func updateCidrInfos(IPV4CidrInfos, IPV6CidrInfos map[int32][]*CidrInfo, epcIDBa
 	if epcID == 0 {
 		epcID = datatype.EPC_FROM_INTERNET
 	}
-	isWan := tridentCidr.GetType() == trident.CidrType_WAN
-	cidrInfo := &CidrInfo{
+	return &CidrInfo{
 		Cidr:     cidr,
 		EpcID:    epcID,
 		AZID:     tridentCidr.GetAzId(),
 		RegionID: tridentCidr.GetRegionId(),
 		SubnetID: tridentCidr.GetSubnetId(),
-		IsWan:    isWan,
+		IsWan:    tridentCidr.GetType() == trident.CidrType_WAN,
 		HitCount: new(uint64),
+	}, nil
+}
+
+// updateCidrInfos 添加cidr，添加完成后需调用sortCidrInfos排序
+func updateCidrInfos(IPV4CidrInfos, IPV6CidrInfos map[int32][]*CidrInfo, epcIDBaseInfos map[int32]*BaseInfo, tridentCidr *trident.Cidr) {
+	prefix := tridentCidr.GetPrefix()
+	cidrInfo, err := newCidrInfo(tridentCidr)
+	if err != nil {
+		log.Warningf("parse cidr(%s) failed. err=%s", prefix, err)
+		return
 	}
+	epcID, isWan := cidrInfo.EpcID, cidrInfo.IsWan
 	if _, exist := epcIDBaseInfos[epcID]; !exist {
 		epcIDBaseInfos[epcID] = &BaseInfo{
 			RegionID: tridentCidr.GetRegionId(),
@@ -1135,7 +1185,7 @@ func updateCidrInfos(IPV4CidrInfos, IPV6CidrInfos map[int32][]*CidrInfo, epcIDBa
 			if _, ok := IPV4CidrInfos[0]; !ok {
 				IPV4CidrInfos[0] = make([]*CidrInfo, 0, 128)
 			}
-			IPV4CidrInfos[0] = append(IPV4CidrInfos[epcID], cidrInfo)
+			IPV4CidrInfos[0] = append(IPV4CidrInfos[0], cidrInfo)
 		}
 	} else {
 		if _, ok := IPV6CidrInfos[epcID]; !ok {
@@ -1150,23 +1200,16 @@ func updateCidrInfos(IPV4CidrInfos, IPV6CidrInfos map[int32][]*CidrInfo, epcIDBa
 			IPV6CidrInfos[0] = append(IPV6CidrInfos[0], cidrInfo)
 		}
 	}
+}
 
-	// 对结果排序，如果存在相同的网络，保证先匹配到小网段，再匹配大网段
-	// 例如, 优先匹配 192.168.0.0/24 再匹配 192.168.0.0/16
-	for _, cidrs := range IPV4CidrInfos {
-		sort.Slice(cidrs, func(i, j int) bool {
-			ci, _ := cidrs[i].Cidr.Mask.Size()
-			cj, _ := cidrs[j].Cidr.Mask.Size()
-			return ci > cj
-		})
-	}
-	for _, cidrs := range IPV6CidrInfos {
-		sort.Slice(cidrs, func(i, j int) bool {
-			ci, _ := cidrs[i].Cidr.Mask.Size()
-			cj, _ := cidrs[j].Cidr.Mask.Size()
-			return ci > cj
-		})
-	}
+// 对结果排序，如果存在相同的网络，保证先匹配到小网段，再匹配大网段
+// 例如, 优先匹配 192.168.0.0/24 再匹配 192.168.0.0/16
+func sortCidrInfos(cidrs []*CidrInfo) {
+	sort.Slice(cidrs, func(i, j int) bool {
+		ci, _ := cidrs[i].Cidr.Mask.Size()
+		cj, _ := cidrs[j].Cidr.Mask.Size()
+		return ci > cj
+	})
 }
 
 func updateInterfaceInfos(epcIDIPV4Infos map[uint64]*Info, epcIDIPV6Infos map[[EpcIDIPV6_LEN]byte]*Info, macInfos map[uint64]*Info, epcIDBaseInfos map[int32]*BaseInfo, intf *trident.Interface) {
@@ -1463,6 +1506,7 @@ func (t *PlatformInfoTable) updatePeerConnections(connections []*trident.PeerCon
 		}
 	}
 
+	t.peerConnectionProtos = connections
 	t.peerConnections = peerConnections
 }
 
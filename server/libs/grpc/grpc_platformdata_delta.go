@@ -0,0 +1,224 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"bytes"
+
+	"github.com/deepflowio/deepflow/message/trident"
+)
+
+type marshaler interface {
+	Marshal() ([]byte, error)
+}
+
+// applyItemsDelta 按key删除dels中的条目，同key有多个条目时优先删除内容相同的，再追加adds，
+// 返回新的列表，不修改items
+func applyItemsDelta[T marshaler, K comparable](items, adds, dels []T, key func(T) K) []T {
+	removed := make([]bool, len(items))
+	if len(dels) > 0 {
+		keyIndexes := make(map[K][]int, len(items))
+		for i, item := range items {
+			k := key(item)
+			keyIndexes[k] = append(keyIndexes[k], i)
+		}
+		for _, item := range dels {
+			k := key(item)
+			indexes := keyIndexes[k]
+			if len(indexes) == 0 {
+				continue
+			}
+			pos := 0
+			if len(indexes) > 1 {
+				pos = indexOfSameContent(items, indexes, item)
+			}
+			removed[indexes[pos]] = true
+			keyIndexes[k] = append(indexes[:pos], indexes[pos+1:]...)
+		}
+	}
+	newItems := make([]T, 0, len(items)+len(adds))
+	for i, item := range items {
+		if !removed[i] {
+			newItems = append(newItems, item)
+		}
+	}
+	return append(newItems, adds...)
+}
+
+// indexOfSameContent 返回indexes中与item序列化内容相同的位置，没有则返回0
+func indexOfSameContent[T marshaler](items []T, indexes []int, item T) int {
+	b, err := item.Marshal()
+	if err != nil {
+		return 0
+	}
+	for pos, index := range indexes {
+		if ib, err := items[index].Marshal(); err == nil && bytes.Equal(ib, b) {
+			return pos
+		}
+	}
+	return 0
+}
+
+type peerConnectionKey struct {
+	id, localEpcID, remoteEpcID uint32
+}
+
+// CEN生成的对等连接id为0，需要用两端的epc区分
+func getPeerConnectionKey(p *trident.PeerConnection) peerConnectionKey {
+	return peerConnectionKey{p.GetId(), p.GetLocalEpcId(), p.GetRemoteEpcId()}
+}
+
+type serviceKey struct {
+	serviceType                         trident.ServiceType
+	id, podClusterID, podGroupID, epcID uint32
+	protocol                            trident.ServiceProtocol
+}
+
+func getServiceKey(s *trident.ServiceInfo) serviceKey {
+	return serviceKey{s.GetType(), s.GetId(), s.GetPodClusterId(), s.GetPodGroupId(), s.GetEpcId(), s.GetProtocol()}
+}
+
+func cloneMap[K comparable, V any](m map[K]V) map[K]V {
+	newMap := make(map[K]V, len(m))
+	for k, v := range m {
+		newMap[k] = v
+	}
+	return newMap
+}
+
+// 仅删除属于该接口的条目，避免误删其他接口覆盖后的数据
+func isSameInterfaceInfo(current, deleted *Info) bool {
+	return current != nil && current.EpcID == deleted.EpcID && current.DeviceType == deleted.DeviceType &&
+		current.DeviceID == deleted.DeviceID && current.Mac == deleted.Mac
+}
+
+func removeCidrInfo(cidrs []*CidrInfo, deleted *CidrInfo) []*CidrInfo {
+	for i, cidr := range cidrs {
+		if cidr.EpcID == deleted.EpcID && cidr.SubnetID == deleted.SubnetID && cidr.IsWan == deleted.IsWan &&
+			cidr.Cidr.String() == deleted.Cidr.String() {
+			return append(cidrs[:i], cidrs[i+1:]...)
+		}
+	}
+	return cidrs
+}
+
+// applyPlatformDataDelta 在当前平台数据上应用增量。由于查询不加锁，在副本上修改后整体替换，
+// 且cidr列表只复制和重新排序受影响的epc
+func (t *PlatformInfoTable) applyPlatformDataDelta(delta *trident.PlatformDataDelta) {
+	newEpcIDIPV4Infos := cloneMap(t.epcIDIPV4Infos)
+	newEpcIDIPV6Infos := cloneMap(t.epcIDIPV6Infos)
+	newMacInfos := cloneMap(t.macInfos)
+	newEpcIDBaseInfos := cloneMap(t.epcIDBaseInfos)
+	newEpcIDIPV4CidrInfos := cloneMap(t.epcIDIPV4CidrInfos)
+	newEpcIDIPV6CidrInfos := cloneMap(t.epcIDIPV6CidrInfos)
+
+	for _, intf := range delta.GetDelInterfaces() {
+		delIPV4Infos := make(map[uint64]*Info)
+		delIPV6Infos := make(map[[EpcIDIPV6_LEN]byte]*Info)
+		delMacInfos := make(map[uint64]*Info)
+		updateInterfaceInfos(delIPV4Infos, delIPV6Infos, delMacInfos, make(map[int32]*BaseInfo), intf)
+		for key, info := range delIPV4Infos {
+			if isSameInterfaceInfo(newEpcIDIPV4Infos[key], info) {
+				delete(newEpcIDIPV4Infos, key)
+			}
+		}
+		for key, info := range delIPV6Infos {
+			if isSameInterfaceInfo(newEpcIDIPV6Infos[key], info) {
+				delete(newEpcIDIPV6Infos, key)
+			}
+		}
+		for key, info := range delMacInfos {
+			if isSameInterfaceInfo(newMacInfos[key], info) {
+				delete(newMacInfos, key)
+			}
+		}
+	}
+	for _, intf := range delta.GetAddInterfaces() {
+		updateInterfaceInfos(newEpcIDIPV4Infos, newEpcIDIPV6Infos, newMacInfos, newEpcIDBaseInfos, intf)
+	}
+
+	// 受影响的cidr列表先复制，避免修改正在被查询的列表
+	changedIPV4Epcs, changedIPV6Epcs := make(map[int32]struct{}), make(map[int32]struct{})
+	copyCidrInfos := func(tridentCidr *trident.Cidr) *CidrInfo {
+		cidrInfo, err := newCidrInfo(tridentCidr)
+		if err != nil {
+			log.Warningf("parse cidr(%s) failed. err=%s", tridentCidr.GetPrefix(), err)
+			return nil
+		}
+		cidrInfos, changedEpcs := newEpcIDIPV6CidrInfos, changedIPV6Epcs
+		if isIPV4(tridentCidr.GetPrefix()) {
+			cidrInfos, changedEpcs = newEpcIDIPV4CidrInfos, changedIPV4Epcs
+		}
+		epcIDs := []int32{cidrInfo.EpcID}
+		if cidrInfo.IsWan {
+			epcIDs = append(epcIDs, 0)
+		}
+		for _, epcID := range epcIDs {
+			if _, ok := changedEpcs[epcID]; ok {
+				continue
+			}
+			changedEpcs[epcID] = struct{}{}
+			if cidrs, ok := cidrInfos[epcID]; ok {
+				cidrInfos[epcID] = append(make([]*CidrInfo, 0, len(cidrs)+1), cidrs...)
+			}
+		}
+		return cidrInfo
+	}
+	for _, tridentCidr := range delta.GetDelCidrs() {
+		cidrInfo := copyCidrInfos(tridentCidr)
+		if cidrInfo == nil {
+			continue
+		}
+		cidrInfos := newEpcIDIPV6CidrInfos
+		if isIPV4(tridentCidr.GetPrefix()) {
+			cidrInfos = newEpcIDIPV4CidrInfos
+		}
+		cidrInfos[cidrInfo.EpcID] = removeCidrInfo(cidrInfos[cidrInfo.EpcID], cidrInfo)
+		if cidrInfo.IsWan {
+			cidrInfos[0] = removeCidrInfo(cidrInfos[0], cidrInfo)
+		}
+	}
+	for _, tridentCidr := range delta.GetAddCidrs() {
+		if copyCidrInfos(tridentCidr) == nil {
+			continue
+		}
+		updateCidrInfos(newEpcIDIPV4CidrInfos, newEpcIDIPV6CidrInfos, newEpcIDBaseInfos, tridentCidr)
+	}
+	for epcID := range changedIPV4Epcs {
+		sortCidrInfos(newEpcIDIPV4CidrInfos[epcID])
+	}
+	for epcID := range changedIPV6Epcs {
+		sortCidrInfos(newEpcIDIPV6CidrInfos[epcID])
+	}
+
+	if len(delta.GetAddPeerConnections()) > 0 || len(delta.GetDelPeerConnections()) > 0 {
+		t.updatePeerConnections(applyItemsDelta(t.peerConnectionProtos, delta.GetAddPeerConnections(), delta.GetDelPeerConnections(), getPeerConnectionKey))
+	}
+
+	t.replacePlatformData(newEpcIDIPV4Infos, newEpcIDIPV6Infos, newEpcIDIPV4CidrInfos, newEpcIDIPV6CidrInfos, newMacInfos, newEpcIDBaseInfos)
+	log.Infof("apply platformdata delta: add interfaces %d, del interfaces %d, add cidrs %d, del cidrs %d, add peer connections %d, del peer connections %d",
+		len(delta.GetAddInterfaces()), len(delta.GetDelInterfaces()), len(delta.GetAddCidrs()), len(delta.GetDelCidrs()),
+		len(delta.GetAddPeerConnections()), len(delta.GetDelPeerConnections()))
+}
+
+// applyGroupsDelta 数据节点只使用groups中的服务信息
+func (t *PlatformInfoTable) applyGroupsDelta(delta *trident.GroupsDelta) {
+	if len(delta.GetAddSvcs()) == 0 && len(delta.GetDelSvcs()) == 0 {
+		return
+	}
+	t.updateServices(&trident.Groups{Svcs: applyItemsDelta(t.services, delta.GetAddSvcs(), delta.GetDelSvcs(), getServiceKey)})
+}
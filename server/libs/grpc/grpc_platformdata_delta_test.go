@@ -0,0 +1,150 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/deepflowio/deepflow/message/trident"
+	"github.com/deepflowio/deepflow/server/libs/hmap/lru"
+	"github.com/deepflowio/deepflow/server/libs/utils"
+)
+
+// testItem 格式为 key:content
+type testItem string
+
+func (i testItem) Marshal() ([]byte, error) {
+	return []byte(i), nil
+}
+
+func testItemKey(i testItem) string {
+	return strings.SplitN(string(i), ":", 2)[0]
+}
+
+func TestApplyItemsDelta(t *testing.T) {
+	items := []testItem{"a:1", "b:1", "b:2", "c:1"}
+	// c的内容已变化也要按key删除，b优先删除内容相同的条目
+	got := applyItemsDelta(items, []testItem{"d:1"}, []testItem{"b:2", "c:2", "e:1"}, testItemKey)
+	want := []testItem{"a:1", "b:1", "d:1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("applyItemsDelta() = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(items, []testItem{"a:1", "b:1", "b:2", "c:1"}) {
+		t.Errorf("applyItemsDelta() modified items: %v", items)
+	}
+
+	got = applyItemsDelta(items, nil, []testItem{"b:3", "b:3", "b:3"}, testItemKey)
+	want = []testItem{"a:1", "c:1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("applyItemsDelta() = %v, want %v", got, want)
+	}
+}
+
+func newTestInterface(id, epcID uint32, mac uint64, ip string) *trident.Interface {
+	return &trident.Interface{
+		Id:          proto.Uint32(id),
+		DeviceType:  proto.Uint32(1),
+		DeviceId:    proto.Uint32(id),
+		IfType:      proto.Uint32(4),
+		EpcId:       proto.Uint32(epcID),
+		Mac:         proto.Uint64(mac),
+		IpResources: []*trident.IpResource{{Ip: proto.String(ip), SubnetId: proto.Uint32(10)}},
+	}
+}
+
+func newTestCidr(prefix string, epcID int32, subnetID uint32) *trident.Cidr {
+	return &trident.Cidr{
+		Prefix:   proto.String(prefix),
+		Type:     trident.CidrType_LAN.Enum(),
+		EpcId:    proto.Int32(epcID),
+		SubnetId: proto.Uint32(subnetID),
+	}
+}
+
+func newTestPeerConnection(id, localEpcID, remoteEpcID uint32) *trident.PeerConnection {
+	return &trident.PeerConnection{
+		Id:          proto.Uint32(id),
+		LocalEpcId:  proto.Uint32(localEpcID),
+		RemoteEpcId: proto.Uint32(remoteEpcID),
+	}
+}
+
+func TestApplyPlatformDataDelta(t *testing.T) {
+	table := &PlatformInfoTable{
+		moduleName:   "test",
+		epcIDIPV4Lru: lru.NewU64LRUNoStats("epcIDIPV4_test", LruSlotSize, LruCap),
+		epcIDIPV6Lru: lru.NewU160LRUNoStats("epcIDIPV6_test", LruSlotSize, LruCap),
+		counter:      &Counter{},
+	}
+	table.updatePlatformData(&trident.PlatformData{
+		Interfaces: []*trident.Interface{
+			newTestInterface(1, 1, 0x1, "10.0.0.1"),
+			newTestInterface(2, 1, 0x2, "10.0.0.2"),
+		},
+		Cidrs: []*trident.Cidr{
+			newTestCidr("10.0.0.0/24", 1, 10),
+			newTestCidr("10.0.1.0/24", 1, 11),
+		},
+		PeerConnections: []*trident.PeerConnection{
+			newTestPeerConnection(1, 1, 2),
+			newTestPeerConnection(0, 1, 3),
+			newTestPeerConnection(0, 1, 4),
+		},
+	})
+	epcIDIPV4Infos, epcIDIPV4CidrInfos := table.epcIDIPV4Infos, table.epcIDIPV4CidrInfos
+
+	table.applyPlatformDataDelta(&trident.PlatformDataDelta{
+		// 接口1的ip由10.0.0.1变为10.0.1.1
+		DelInterfaces:      []*trident.Interface{newTestInterface(1, 1, 0x1, "10.0.0.1")},
+		AddInterfaces:      []*trident.Interface{newTestInterface(1, 1, 0x1, "10.0.1.1")},
+		DelCidrs:           []*trident.Cidr{newTestCidr("10.0.0.0/24", 1, 10)},
+		AddCidrs:           []*trident.Cidr{newTestCidr("10.0.2.0/24", 1, 12)},
+		DelPeerConnections: []*trident.PeerConnection{newTestPeerConnection(0, 1, 3)},
+	})
+
+	ip := func(s string) uint32 { return utils.IpToUint32(utils.ParserStringIpV4(s)) }
+	if info := table.queryIPV4Infos(1, ip("10.0.0.1")); info != nil {
+		t.Errorf("deleted ip 10.0.0.1 found: %+v", info)
+	}
+	if info := table.queryIPV4Infos(1, ip("10.0.1.1")); info == nil || info.Mac != 0x1 {
+		t.Errorf("added ip 10.0.1.1 = %+v, want mac 0x1", info)
+	}
+	if info := table.queryIPV4Infos(1, ip("10.0.0.2")); info == nil || info.Mac != 0x2 {
+		t.Errorf("unchanged ip 10.0.0.2 = %+v, want mac 0x2", info)
+	}
+	if info := table.queryIPV4Infos(1, ip("10.0.2.9")); info == nil || info.SubnetID != 12 {
+		t.Errorf("ip 10.0.2.9 in added cidr = %+v, want subnet 12", info)
+	}
+	if info := table.queryIPV4Infos(1, ip("10.0.1.9")); info == nil || info.SubnetID != 11 {
+		t.Errorf("ip 10.0.1.9 in unchanged cidr = %+v, want subnet 11", info)
+	}
+	if got := table.queryPeerConnections(1); !reflect.DeepEqual(got, []int32{2, 4}) {
+		t.Errorf("peer connections of epc 1 = %v, want [2 4]", got)
+	}
+
+	// 增量在副本上应用，原来的表不变
+	if _, ok := epcIDIPV4Infos[uint64(1)<<32|uint64(ip("10.0.0.1"))]; !ok {
+		t.Error("applyPlatformDataDelta() modified the previous ip table")
+	}
+	if len(epcIDIPV4CidrInfos[1]) != 2 {
+		t.Errorf("applyPlatformDataDelta() modified the previous cidr list: %v", epcIDIPV4CidrInfos[1])
+	}
+}
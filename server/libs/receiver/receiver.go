@@ -24,6 +24,7 @@ import (
 	"net"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -53,7 +54,8 @@ const (
 	DROP_DETECT_WINDOW_SIZE   = 1024
 	QUEUE_BATCH_NUM           = 16
 	LOG_INTERVAL              = 10
-	RECORD_STATUS_TIMEOUT     = 30 // 每30秒记录下trident的活跃信息，platformData模块每分钟会上报trisolaris
+	RECORD_STATUS_TIMEOUT     = 30  // 每30秒记录下trident的活跃信息，platformData模块每分钟会上报trisolaris
+	VTAP_COUNTER_EXPIRE       = 600 // 采集器超过10分钟无数据, 删除其统计, 避免采集器迁移后统计项一直累积
 	SOCKET_READ_ERROR         = "maybe trident restart."
 	ONE_HOUR                  = 3600
 )
@@ -389,6 +391,9 @@ type Receiver struct {
 	counter *ReceiverCounter
 
 	status *AdapterStatus
+
+	vtapCountersLock sync.RWMutex
+	vtapCounters     map[uint16]*VTapCounter
 }
 
 type ReceiverCounter struct {
@@ -402,6 +407,26 @@ type ReceiverCounter struct {
 	NewBufferCount  uint64 `statsd:"new_buffer_count"`  // If the received data is large, you need to alloc memory, record the times.
 }
 
+// 按采集器统计接收的数据量，控制器根据rx_bytes按流量均衡采集器的数据节点
+type VTapCounter struct {
+	RxPackets uint64 `statsd:"rx_packets"`
+	RxBytes   uint64 `statsd:"rx_bytes"`
+
+	lastActive int64 // 最后一次收到数据的时间, unix秒
+	closed     int32 // stats 模块与过期清理并发访问, 原子读写
+}
+
+func (c *VTapCounter) GetCounter() interface{} {
+	return &VTapCounter{
+		RxPackets: atomic.SwapUint64(&c.RxPackets, 0),
+		RxBytes:   atomic.SwapUint64(&c.RxBytes, 0),
+	}
+}
+
+func (c *VTapCounter) Closed() bool {
+	return atomic.LoadInt32(&c.closed) == 1
+}
+
 func NewReceiver(
 	listenPort, UDPReadBuffer, TCPReadBuffer, TCPReaderBuffer int, // 监听端口，默认同时监听tcp和upd的端口
 ) *Receiver {
@@ -416,6 +441,7 @@ func NewReceiver(
 		timeNow:         time.Now().Unix(),
 		counter:         &ReceiverCounter{},
 		status:          &AdapterStatus{},
+		vtapCounters:    make(map[uint16]*VTapCounter),
 	}
 	receiver.status.init()
 
@@ -459,6 +485,41 @@ func (r *Receiver) HandleSimpleCommand(op uint16, arg string) string {
 	return ret
 }
 
+func (r *Receiver) updateVTapCounter(vtapID uint16, size int) {
+	if vtapID == 0 {
+		return
+	}
+	r.vtapCountersLock.RLock()
+	counter, ok := r.vtapCounters[vtapID]
+	r.vtapCountersLock.RUnlock()
+	if !ok {
+		r.vtapCountersLock.Lock()
+		if counter, ok = r.vtapCounters[vtapID]; !ok {
+			counter = &VTapCounter{lastActive: r.timeNow}
+			r.vtapCounters[vtapID] = counter
+			stats.RegisterCountableWithModulePrefix("ingester.", "receiver_vtap", counter, stats.OptionStatTags{"vtap_id": strconv.Itoa(int(vtapID))})
+		}
+		r.vtapCountersLock.Unlock()
+	}
+	atomic.AddUint64(&counter.RxPackets, 1)
+	atomic.AddUint64(&counter.RxBytes, uint64(size))
+	if atomic.LoadInt64(&counter.lastActive) != r.timeNow {
+		atomic.StoreInt64(&counter.lastActive, r.timeNow)
+	}
+}
+
+// 删除长时间没有数据的采集器统计, closed后stats模块会注销该统计项
+func (r *Receiver) expireVTapCounters() {
+	r.vtapCountersLock.Lock()
+	for vtapID, counter := range r.vtapCounters {
+		if r.timeNow-atomic.LoadInt64(&counter.lastActive) > VTAP_COUNTER_EXPIRE {
+			atomic.StoreInt32(&counter.closed, 1)
+			delete(r.vtapCounters, vtapID)
+		}
+	}
+	r.vtapCountersLock.Unlock()
+}
+
 func (r *Receiver) SetServerType(serverType ServerType) {
 	r.serverType = serverType
 }
@@ -488,12 +549,17 @@ func (r *Receiver) timeNowAndFlushTicker() {
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
 
+	lastExpire := time.Now().Unix()
 	for range ticker.C {
 		if r.exit {
 			return
 		}
 		r.timeNow = time.Now().Unix()
 		r.flushPutTCPQueues()
+		if r.timeNow-lastExpire >= VTAP_COUNTER_EXPIRE {
+			lastExpire = r.timeNow
+			r.expireVTapCounters()
+		}
 	}
 }
 
@@ -721,6 +787,7 @@ func (r *Receiver) ProcessUDPServer() {
 			}
 		}
 		r.status.Update(uint32(r.timeNow), baseHeader.Type, vtapID, remoteAddr.IP, sequence, metricsTimestamp, UDP)
+		r.updateVTapCounter(vtapID, size)
 
 		recvBuffer.Begin = headerLen
 		recvBuffer.End = size // syslog,statsd数据的FrameSize长度是0,需要以实际长度为准
@@ -891,6 +958,7 @@ func (r *Receiver) handleTCPConnection(conn net.Conn) {
 			r.updateCounter(metricsTimestamp)
 		}
 		r.status.Update(uint32(r.timeNow), baseHeader.Type, vtapID, ip, sequence, metricsTimestamp, TCP)
+		r.updateVTapCounter(vtapID, int(baseHeader.FrameSize))
 		atomic.AddUint64(&r.counter.RxPackets, 1)
 
 		recvBuffer.Begin = 0
@@ -926,6 +994,11 @@ func (r *Receiver) Close() error {
 	r.exit = true
 	log.Info("Stopped receiver")
 	r.closed = true
+	r.vtapCountersLock.Lock()
+	for _, counter := range r.vtapCounters {
+		atomic.StoreInt32(&counter.closed, 1)
+	}
+	r.vtapCountersLock.Unlock()
 	return nil
 }
 
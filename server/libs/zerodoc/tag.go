@@ -315,6 +315,11 @@ type Field struct {
 	TagValue uint16
 }
 
+// BackfillColumn 标记延迟到达的回填数据, 以_开头不会被聚合表和MV使用
+func BackfillColumn() *ckdb.Column {
+	return ckdb.NewColumn("_backfill", ckdb.UInt8).SetComment("是否为延迟到达的回填数据").SetIndex(ckdb.IndexNone)
+}
+
 func newMetricsMinuteTable(id MetricsTableID, engine ckdb.EngineType, version, cluster, storagePolicy string, ttl int, coldStorage *ckdb.ColdStorage) *ckdb.Table {
 	timeKey := "time"
 
@@ -348,7 +353,7 @@ func newMetricsMinuteTable(id MetricsTableID, engine ckdb.EngineType, version, c
 		Database:        ckdb.METRICS_DB,
 		LocalName:       id.TableName() + ckdb.LOCAL_SUBFFIX,
 		GlobalName:      id.TableName(),
-		Columns:         append(GenTagColumns(metricsTableCodes[id]), meterColumns...),
+		Columns:         append(append(GenTagColumns(metricsTableCodes[id]), meterColumns...), BackfillColumn()),
 		TimeKey:         timeKey,
 		TTL:             ttl,
 		PartitionFunc:   ckdb.TimeFuncTwelveHour,
@@ -27,6 +27,7 @@ const (
 	SERVER_ERROR                    = "SERVER_ERROR"
 	RESOURCE_NUM_EXCEEDED           = "RESOURCE_NUM_EXCEEDED"
 	SELECTED_RESOURCES_NUM_EXCEEDED = "SELECTED_RESOURCES_NUM_EXCEEDED"
+	QUERY_LIMIT_EXCEEDED            = "QUERY_LIMIT_EXCEEDED"
 )
 
 const (
@@ -27,9 +27,19 @@ type QuerierParams struct {
 	DB         string
 	Sql        string
 	DataSource string
+	Caller     *Caller
+	Writer     ResultWriter // if set, the result is streamed to Writer instead of returned
+	TagHistory bool         // resolve resource names and labels as of the time of each row
 	Context    context.Context
 }
 
+// Caller identifies who issued a query, only an authenticated caller
+// is given the limits configured for its name.
+type Caller struct {
+	Name          string
+	Authenticated bool
+}
+
 type TempoParams struct {
 	TraceId     string
 	StartTime   string
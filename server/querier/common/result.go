@@ -39,6 +39,16 @@ func (r *Result) ToJson() map[string]interface{} {
 	}
 }
 
+// ResultWriter receives the result of a streamed query in batches, every batch
+// carries the same Columns and Schemas and only the rows read since the last one.
+type ResultWriter interface {
+	WriteBatch(result *Result) error
+	Rows() int
+	// Started returns whether any batch or header has been written
+	Started() bool
+	Close() error
+}
+
 type ColumnSchema struct {
 	Name      string
 	Unit      string
@@ -48,8 +48,11 @@ type QuerierConfig struct {
 	Language                      string                `default:"en" yaml:"language"`
 	OtelEndpoint                  string                `default:"http://${K8S_NODE_IP_FOR_DEEPFLOW}:38086/api/v1/otel/trace" yaml:"otel-endpoint"`
 	Limit                         string                `default:"10000" yaml:"limit"`
+	StreamLimit                   string                `default:"1000000" yaml:"stream-limit"`
 	TimeFillLimit                 int                   `default:"20" yaml:"time-fill-limit"`
 	PrometheusCacheUpdateInterval int                   `default:"60" yaml:"prometheus-cache-update-interval"`
+	TagHistory                    bool                  `default:"false" yaml:"tag-history"`
+	Governance                    Governance            `yaml:"governance"`
 }
 
 type DeepflowApp struct {
@@ -58,13 +61,56 @@ type DeepflowApp struct {
 }
 
 type Clickhouse struct {
-	User           string `default:"default" yaml:"user-name"`
-	Password       string `default:"" yaml:"user-password"`
-	Host           string `default:"clickhouse" yaml:"host"`
-	Port           int    `default:"9000" yaml:"port"`
-	Timeout        int    `default:"60" yaml:"timeout"`
-	ConnectTimeout int    `default:"2" yaml:"connect-timeout"`
-	MaxConnection  int    `default:"20" yaml:"max-connection"`
+	User                string   `default:"default" yaml:"user-name"`
+	Password            string   `default:"" yaml:"user-password"`
+	Host                string   `default:"clickhouse" yaml:"host"`
+	Port                int      `default:"9000" yaml:"port"`
+	Timeout             int      `default:"60" yaml:"timeout"`
+	ConnectTimeout      int      `default:"2" yaml:"connect-timeout"`
+	MaxConnection       int      `default:"20" yaml:"max-connection"`
+	Endpoints           []string `yaml:"endpoints"` // host:port of each replica, `host` and `port` are used if empty
+	Cluster             string   `default:"" yaml:"cluster"`
+	LoadBalance         string   `default:"round-robin" yaml:"load-balance"`
+	HealthCheckInterval int      `default:"10" yaml:"health-check-interval"`
+	MaxRetries          int      `default:"1" yaml:"max-retries"`
+}
+
+type Governance struct {
+	Enabled      bool          `default:"false" yaml:"enabled"`
+	CallerHeader string        `default:"X-DeepFlow-Caller" yaml:"caller-header"`
+	TokenHeader  string        `default:"X-DeepFlow-Token" yaml:"token-header"`
+	Limits       QueryLimits   `yaml:"limits"`
+	Callers      []QueryCaller `yaml:"callers"`
+	QueryLog     QueryLog      `yaml:"query-log"`
+}
+
+// QueryLimits are translated into ClickHouse settings, 0 or negative means no limit.
+// For a configured caller, 0 means inheriting the value from `governance.limits`,
+// use a negative value to remove the inherited limit.
+type QueryLimits struct {
+	MaxExecutionTime     int `default:"60" yaml:"max-execution-time"` // seconds
+	MaxRowsToRead        int `default:"0" yaml:"max-rows-to-read"`
+	MaxBytesToRead       int `default:"0" yaml:"max-bytes-to-read"`
+	MaxMemoryUsage       int `default:"0" yaml:"max-memory-usage"` // bytes
+	MaxConcurrentQueries int `default:"10" yaml:"max-concurrent-queries"`
+	MaxQueuedQueries     int `default:"50" yaml:"max-queued-queries"`
+	QueueTimeout         int `default:"30" yaml:"queue-timeout"` // seconds
+}
+
+type QueryCaller struct {
+	Name   string      `yaml:"name"`
+	Token  string      `yaml:"token"`
+	Limits QueryLimits `yaml:"limits"`
+}
+
+type QueryLog struct {
+	Enabled       bool   `default:"true" yaml:"enabled"`
+	Database      string `default:"deepflow_querier" yaml:"database"`
+	Cluster       string `default:"df_cluster" yaml:"cluster"`
+	StoragePolicy string `default:"df_storage" yaml:"storage-policy"`
+	TTL           int    `default:"168" yaml:"ttl"` // hours
+	QueueSize     int    `default:"10000" yaml:"queue-size"`
+	FlushInterval int    `default:"5" yaml:"flush-interval"` // seconds
 }
 
 func (c *Config) expendEnv() {
@@ -0,0 +1,37 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package common
+
+const (
+	DATABASE_FLOW_LOG   = "flow_log"
+	DATABASE_EVENT      = "event"
+	DATABASE_PROFILE    = "profile"
+	DATABASE_PROMETHEUS = "prometheus"
+
+	TABLE_L4_FLOW_LOG        = "l4_flow_log"
+	TABLE_L7_FLOW_LOG        = "l7_flow_log"
+	TABLE_EVENT              = "event"
+	TABLE_PERF_EVENT         = "perf_event"
+	TABLE_PROFILE            = "in_process"
+	TABLE_PROMETHEUS_SAMPLES = "samples"
+)
+
+const (
+	DEFAULT_LIMIT = 100
+	MAX_LIMIT     = 1000
+	// 以trace为锚点时, 时间窗口向两侧扩展的秒数, 用于覆盖span前后的流和事件
+	TRACE_WINDOW_MARGIN = 60
+)
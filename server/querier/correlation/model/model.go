@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package model
+
+import (
+	"context"
+
+	"github.com/deepflowio/deepflow/server/querier/common"
+)
+
+// Correlation 以trace_id/span_id, pod_id或gprocess_id为锚点, 查询时间窗口内共享同一全景标签的各类信号
+type Correlation struct {
+	TraceID     string   `json:"trace_id"`
+	SpanID      string   `json:"span_id"`
+	PodIDs      []uint32 `json:"pod_ids"`
+	GProcessIDs []uint32 `json:"gprocess_ids"`
+	TimeStart   int      `json:"time_start" binding:"required"`
+	TimeEnd     int      `json:"time_end" binding:"required"`
+	Limit       int      `json:"limit"`
+	Debug       bool     `json:"debug"`
+
+	// 由router填充, 用于查询的取消和限流
+	Context context.Context `json:"-"`
+	Caller  *common.Caller  `json:"-"`
+}
+
+// Anchor 解析后的锚点, trace_id指定时会补充span所在的pod和进程, 并收敛时间窗口
+type Anchor struct {
+	TraceID     string   `json:"trace_id"`
+	SpanID      string   `json:"span_id"`
+	PodIDs      []uint32 `json:"pod_ids"`
+	GProcessIDs []uint32 `json:"gprocess_ids"`
+	TimeStart   int      `json:"time_start"`
+	TimeEnd     int      `json:"time_end"`
+}
+
+type CorrelationResult struct {
+	Anchor           *Anchor                  `json:"anchor"`
+	L4Flows          []map[string]interface{} `json:"l4_flows"`
+	L7Spans          []map[string]interface{} `json:"l7_spans"`
+	Profiles         []map[string]interface{} `json:"profiles"`
+	Events           []map[string]interface{} `json:"events"`
+	PrometheusSeries []*PrometheusSeries      `json:"prometheus_series"`
+}
+
+type PrometheusSeries struct {
+	Metric      string `json:"metric"`
+	TargetID    int    `json:"target_id"`
+	PodID       int    `json:"pod_id"`
+	GProcessID  int    `json:"gprocess_id"`
+	SampleCount int    `json:"sample_count"`
+}
+
+type Debug struct {
+	IP        string `json:"ip"`
+	Sql       string `json:"sql"`
+	SqlCH     string `json:"sql_CH"`
+	QueryTime string `json:"query_time"`
+	QueryUUID string `json:"query_uuid"`
+	Error     string `json:"error"`
+}
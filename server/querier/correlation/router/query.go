@@ -0,0 +1,53 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+
+	"github.com/deepflowio/deepflow/server/querier/common"
+	"github.com/deepflowio/deepflow/server/querier/config"
+	"github.com/deepflowio/deepflow/server/querier/correlation/model"
+	"github.com/deepflowio/deepflow/server/querier/correlation/service"
+	"github.com/deepflowio/deepflow/server/querier/governance"
+	"github.com/deepflowio/deepflow/server/querier/router"
+)
+
+func CorrelationRouter(e *gin.Engine, cfg *config.QuerierConfig) {
+	e.POST("/v1/correlation/Correlate", correlate())
+}
+
+func correlate() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		var correlation model.Correlation
+
+		// 参数校验
+		err := c.ShouldBindBodyWith(&correlation, binding.JSON)
+		if err != nil {
+			router.BadRequestResponse(c, common.INVALID_POST_DATA, err.Error())
+			return
+		}
+		correlation.Context = c.Request.Context()
+		correlation.Caller = governance.Identify(c.Request.Header, c.ClientIP())
+		result, debug, err := service.Correlate(correlation)
+		if !correlation.Debug {
+			debug = nil
+		}
+		router.JsonResponse(c, result, debug, err)
+	})
+}
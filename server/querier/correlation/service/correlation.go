@@ -0,0 +1,371 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	logging "github.com/op/go-logging"
+
+	querier_common "github.com/deepflowio/deepflow/server/querier/common"
+	"github.com/deepflowio/deepflow/server/querier/config"
+	"github.com/deepflowio/deepflow/server/querier/correlation/common"
+	"github.com/deepflowio/deepflow/server/querier/correlation/model"
+	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse"
+	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse/client"
+	querier_service "github.com/deepflowio/deepflow/server/querier/service"
+)
+
+var log = logging.MustGetLogger("correlation")
+
+// trace_id/span_id直接拼接到SQL中, 只允许常见的ID字符
+var idRegexp = regexp.MustCompile(`^[0-9A-Za-z_.:\-]+$`)
+
+// 视为on-cpu的profile事件类型
+var onCPUProfileEventTypes = []string{"cpu", "on-cpu", "itimer"}
+
+const (
+	L4_FLOW_FIELDS = "time, _id, flow_id, start_time, end_time, pod_id_0, pod_id_1, pod_0, pod_1, gprocess_id_0, gprocess_id_1, ip_0, ip_1, client_port, server_port, protocol, l7_protocol, close_type, byte_tx, byte_rx, rtt"
+	L7_SPAN_FIELDS = "time, _id, flow_id, start_time, end_time, trace_id, span_id, parent_span_id, tap_side, app_service, app_instance, endpoint, pod_id_0, pod_id_1, pod_0, pod_1, gprocess_id_0, gprocess_id_1, l7_protocol_str, request_type, request_resource, response_status, response_code, response_duration"
+	EVENT_FIELDS   = "time, start_time, end_time, signal_source, event_type, event_desc, pod_id, pod, gprocess_id"
+)
+
+func Correlate(args model.Correlation) (result *model.CorrelationResult, debug []model.Debug, err error) {
+	if err = checkArgs(&args); err != nil {
+		return
+	}
+	c := &correlator{
+		args: &args,
+		anchor: &model.Anchor{
+			TraceID:     args.TraceID,
+			SpanID:      args.SpanID,
+			PodIDs:      args.PodIDs,
+			GProcessIDs: args.GProcessIDs,
+			TimeStart:   args.TimeStart,
+			TimeEnd:     args.TimeEnd,
+		},
+	}
+	result = &model.CorrelationResult{Anchor: c.anchor}
+	if args.TraceID != "" {
+		if result.L7Spans, err = c.traceSpans(); err != nil {
+			return nil, c.debugs, err
+		}
+		c.resolveAnchor(result.L7Spans)
+	} else if result.L7Spans, err = c.query(common.DATABASE_FLOW_LOG, fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY time DESC LIMIT %d",
+		L7_SPAN_FIELDS, common.TABLE_L7_FLOW_LOG, c.edgeFilter(), args.Limit)); err != nil {
+		return nil, c.debugs, err
+	}
+	if !c.hasTags() {
+		// trace中的span均未关联到pod或进程, 无法继续关联其他信号
+		return result, c.debugs, nil
+	}
+
+	if result.L4Flows, err = c.query(common.DATABASE_FLOW_LOG, fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY time DESC LIMIT %d",
+		L4_FLOW_FIELDS, common.TABLE_L4_FLOW_LOG, c.edgeFilter(), args.Limit)); err != nil {
+		return nil, c.debugs, err
+	}
+	if result.Profiles, err = c.profileSummaries(result.L7Spans, result.L4Flows); err != nil {
+		return nil, c.debugs, err
+	}
+	if result.Events, err = c.events(); err != nil {
+		return nil, c.debugs, err
+	}
+	if result.PrometheusSeries, err = c.prometheusSeries(); err != nil {
+		return nil, c.debugs, err
+	}
+	return result, c.debugs, nil
+}
+
+func checkArgs(args *model.Correlation) error {
+	if args.TraceID == "" && len(args.PodIDs) == 0 && len(args.GProcessIDs) == 0 {
+		return querier_service.NewError(querier_common.INVALID_PARAMETERS, "one of trace_id, pod_ids or gprocess_ids is required")
+	}
+	if args.SpanID != "" && args.TraceID == "" {
+		return querier_service.NewError(querier_common.INVALID_PARAMETERS, "span_id must be used with trace_id")
+	}
+	for _, id := range []string{args.TraceID, args.SpanID} {
+		if id != "" && !idRegexp.MatchString(id) {
+			return querier_service.NewError(querier_common.INVALID_PARAMETERS, fmt.Sprintf("invalid id %s", id))
+		}
+	}
+	if args.TimeStart > args.TimeEnd {
+		return querier_service.NewError(querier_common.INVALID_PARAMETERS, "time_start must not be greater than time_end")
+	}
+	if args.Limit <= 0 {
+		args.Limit = common.DEFAULT_LIMIT
+	} else if args.Limit > common.MAX_LIMIT {
+		args.Limit = common.MAX_LIMIT
+	}
+	return nil
+}
+
+type correlator struct {
+	args   *model.Correlation
+	anchor *model.Anchor
+	// trace中span所在的flow_id, 用于精确关联l4流
+	flowIDs []uint64
+	debugs  []model.Debug
+}
+
+func (c *correlator) traceSpans() ([]map[string]interface{}, error) {
+	sql := fmt.Sprintf("SELECT %s FROM %s WHERE %s AND trace_id='%s' ORDER BY start_time LIMIT %d",
+		L7_SPAN_FIELDS, common.TABLE_L7_FLOW_LOG, c.timeFilter(), c.args.TraceID, c.args.Limit)
+	return c.query(common.DATABASE_FLOW_LOG, sql)
+}
+
+// resolveAnchor 从trace的span中获取pod和进程, 并将时间窗口收敛到trace前后
+func (c *correlator) resolveAnchor(spans []map[string]interface{}) {
+	var minStart, maxEnd int64
+	podIDs, gprocessIDs := newIDSet(c.anchor.PodIDs), newIDSet(c.anchor.GProcessIDs)
+	flowIDs := map[uint64]struct{}{}
+	for _, span := range spans {
+		if c.args.SpanID != "" && toString(span["span_id"]) != c.args.SpanID {
+			continue
+		}
+		podIDs.add(toUint32(span["pod_id_0"]), toUint32(span["pod_id_1"]))
+		gprocessIDs.add(toUint32(span["gprocess_id_0"]), toUint32(span["gprocess_id_1"]))
+		if flowID := toUint64(span["flow_id"]); flowID > 0 {
+			flowIDs[flowID] = struct{}{}
+		}
+		// start_time/end_time精度为微秒
+		if start := toInt64(span["start_time"]); start > 0 && (minStart == 0 || start < minStart) {
+			minStart = start
+		}
+		if end := toInt64(span["end_time"]); end > maxEnd {
+			maxEnd = end
+		}
+	}
+	c.anchor.PodIDs, c.anchor.GProcessIDs = podIDs.list(), gprocessIDs.list()
+	for flowID := range flowIDs {
+		c.flowIDs = append(c.flowIDs, flowID)
+	}
+	sort.Slice(c.flowIDs, func(i, j int) bool { return c.flowIDs[i] < c.flowIDs[j] })
+	if minStart > 0 {
+		if start := int(minStart/1000000) - common.TRACE_WINDOW_MARGIN; start > c.anchor.TimeStart {
+			c.anchor.TimeStart = start
+		}
+	}
+	if maxEnd > 0 {
+		if end := int(maxEnd/1000000) + common.TRACE_WINDOW_MARGIN; end < c.anchor.TimeEnd {
+			c.anchor.TimeEnd = end
+		}
+	}
+}
+
+func (c *correlator) hasTags() bool {
+	return len(c.anchor.PodIDs) > 0 || len(c.anchor.GProcessIDs) > 0 || len(c.flowIDs) > 0
+}
+
+func (c *correlator) timeFilter() string {
+	return fmt.Sprintf("time>=%d AND time<=%d", c.anchor.TimeStart, c.anchor.TimeEnd)
+}
+
+// edgeFilter 用于l4_flow_log/l7_flow_log等区分客户端和服务端的表
+func (c *correlator) edgeFilter() string {
+	conditions := []string{}
+	if len(c.anchor.PodIDs) > 0 {
+		ids := joinIDs(c.anchor.PodIDs)
+		conditions = append(conditions, fmt.Sprintf("pod_id_0 IN (%s) OR pod_id_1 IN (%s)", ids, ids))
+	}
+	if len(c.anchor.GProcessIDs) > 0 {
+		ids := joinIDs(c.anchor.GProcessIDs)
+		conditions = append(conditions, fmt.Sprintf("gprocess_id_0 IN (%s) OR gprocess_id_1 IN (%s)", ids, ids))
+	}
+	if len(c.flowIDs) > 0 {
+		conditions = append(conditions, fmt.Sprintf("flow_id IN (%s)", joinIDs(c.flowIDs)))
+	}
+	return fmt.Sprintf("%s AND (%s)", c.timeFilter(), strings.Join(conditions, " OR "))
+}
+
+// singleFilter 用于event/profile/prometheus等只有单侧全景标签的表, 无可用条件时返回空
+func (c *correlator) singleFilter(withGProcess bool) string {
+	conditions := []string{}
+	if len(c.anchor.PodIDs) > 0 {
+		conditions = append(conditions, fmt.Sprintf("pod_id IN (%s)", joinIDs(c.anchor.PodIDs)))
+	}
+	if withGProcess && len(c.anchor.GProcessIDs) > 0 {
+		conditions = append(conditions, fmt.Sprintf("gprocess_id IN (%s)", joinIDs(c.anchor.GProcessIDs)))
+	}
+	if len(conditions) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s AND (%s)", c.timeFilter(), strings.Join(conditions, " OR "))
+}
+
+// gprocessPodIDs 从已查询的流日志中获取锚点进程所在的pod
+func (c *correlator) gprocessPodIDs(rowsList ...[]map[string]interface{}) []uint32 {
+	gprocessIDs := newIDSet(c.anchor.GProcessIDs)
+	podIDs := newIDSet(nil)
+	for _, rows := range rowsList {
+		for _, row := range rows {
+			for _, side := range []string{"_0", "_1"} {
+				if _, ok := gprocessIDs[toUint32(row["gprocess_id"+side])]; ok {
+					podIDs.add(toUint32(row["pod_id"+side]))
+				}
+			}
+		}
+	}
+	return podIDs.list()
+}
+
+// profileSummaries profile表没有进程标签, 仅以进程为锚点时通过流日志找到进程所在的pod
+func (c *correlator) profileSummaries(rowsList ...[]map[string]interface{}) ([]map[string]interface{}, error) {
+	conditions := []string{}
+	podIDs := c.anchor.PodIDs
+	if len(podIDs) == 0 && len(c.anchor.GProcessIDs) > 0 {
+		podIDs = c.gprocessPodIDs(rowsList...)
+	}
+	if len(podIDs) > 0 {
+		conditions = append(conditions, fmt.Sprintf("pod_id IN (%s)", joinIDs(podIDs)))
+	}
+	if c.anchor.TraceID != "" {
+		conditions = append(conditions, fmt.Sprintf("trace_id='%s'", c.anchor.TraceID))
+	}
+	if len(conditions) == 0 {
+		return []map[string]interface{}{}, nil
+	}
+	eventTypes := make([]string, 0, len(onCPUProfileEventTypes))
+	for _, t := range onCPUProfileEventTypes {
+		eventTypes = append(eventTypes, "'"+t+"'")
+	}
+	sql := fmt.Sprintf(
+		"SELECT app_service, profile_event_type, profile_language_type, Sum(profile_value) AS total_value FROM %s WHERE %s AND profile_event_type IN (%s) AND (%s) GROUP BY app_service, profile_event_type, profile_language_type ORDER BY total_value DESC LIMIT %d",
+		common.TABLE_PROFILE, c.timeFilter(), strings.Join(eventTypes, ","), strings.Join(conditions, " OR "), c.args.Limit,
+	)
+	return c.query(common.DATABASE_PROFILE, sql)
+}
+
+// events 资源事件按pod关联, 进程IO事件(perf_event)按pod和进程关联
+func (c *correlator) events() ([]map[string]interface{}, error) {
+	events := []map[string]interface{}{}
+	for _, table := range []string{common.TABLE_EVENT, common.TABLE_PERF_EVENT} {
+		filter := c.singleFilter(table == common.TABLE_PERF_EVENT)
+		if filter == "" {
+			continue
+		}
+		rows, err := c.query(common.DATABASE_EVENT, fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY time DESC LIMIT %d",
+			EVENT_FIELDS, table, filter, c.args.Limit))
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			row["table"] = table
+		}
+		events = append(events, rows...)
+	}
+	return events, nil
+}
+
+// prometheusSeries prometheus的表名为指标名, 无法通过单条DeepFlow SQL跨指标查询, 直接查询samples表
+func (c *correlator) prometheusSeries() ([]*model.PrometheusSeries, error) {
+	series := []*model.PrometheusSeries{}
+	filter := c.singleFilter(true)
+	if filter == "" {
+		return series, nil
+	}
+	sql := fmt.Sprintf(
+		"SELECT metric_id, target_id, pod_id, gprocess_id, count(1) AS sample_count FROM %s.%s WHERE %s GROUP BY metric_id, target_id, pod_id, gprocess_id ORDER BY sample_count DESC LIMIT %d",
+		common.DATABASE_PROMETHEUS, common.TABLE_PROMETHEUS_SAMPLES, filter, c.args.Limit,
+	)
+	// client按clickhouse.endpoints在各副本间路由和重试
+	chClient := client.Client{
+		Host:     config.Cfg.Clickhouse.Host,
+		Port:     config.Cfg.Clickhouse.Port,
+		UserName: config.Cfg.Clickhouse.User,
+		Password: config.Cfg.Clickhouse.Password,
+		DB:       common.DATABASE_PROMETHEUS,
+		Context:  c.args.Context,
+	}
+	rst, err := chClient.DoQuery(&client.QueryParams{Sql: sql})
+	c.debugs = append(c.debugs, toDebug(sql, chClient.Debug.Get()))
+	if err != nil {
+		log.Errorf("query prometheus samples failed: %s, %s", err.Error(), sql)
+		return nil, err
+	}
+	metricIDToName := map[int]string{}
+	for name, id := range clickhouse.Prometheus.MetricNameToID {
+		metricIDToName[id] = name
+	}
+	for _, value := range rst.Values {
+		row := value.([]interface{})
+		metricID := int(toInt64(row[0]))
+		name, ok := metricIDToName[metricID]
+		if !ok {
+			// 指标名缓存尚未更新, 保留ID便于排查
+			name = fmt.Sprintf("metric_id_%d", metricID)
+		}
+		series = append(series, &model.PrometheusSeries{
+			Metric:      name,
+			TargetID:    int(toInt64(row[1])),
+			PodID:       int(toInt64(row[2])),
+			GProcessID:  int(toInt64(row[3])),
+			SampleCount: int(toInt64(row[4])),
+		})
+	}
+	return series, nil
+}
+
+// query 直接通过查询引擎执行, 以便复用标签翻译
+func (c *correlator) query(db, sql string) ([]map[string]interface{}, error) {
+	params := &querier_common.QuerierParams{
+		DB:      db,
+		Sql:     sql,
+		Debug:   "true",
+		Caller:  c.args.Caller,
+		Context: c.args.Context,
+	}
+	result, debug, err := querier_service.Execute(params)
+	c.debugs = append(c.debugs, toDebug(sql, debug))
+	if err != nil {
+		log.Errorf("query %s failed: %s, %s", db, err.Error(), sql)
+		return nil, err
+	}
+	columns, _ := result["columns"].([]interface{})
+	values, _ := result["values"].([]interface{})
+	return toRows(columns, values), nil
+}
+
+func toDebug(sql string, debug map[string]interface{}) model.Debug {
+	queryDebug := model.Debug{Sql: sql}
+	if debug != nil {
+		queryDebug.IP, _ = debug["ip"].(string)
+		queryDebug.SqlCH, _ = debug["sql"].(string)
+		queryDebug.QueryTime, _ = debug["query_time"].(string)
+		queryDebug.QueryUUID, _ = debug["query_uuid"].(string)
+		queryDebug.Error, _ = debug["error"].(string)
+	}
+	return queryDebug
+}
+
+func toRows(columns, values []interface{}) []map[string]interface{} {
+	rows := []map[string]interface{}{}
+	for _, value := range values {
+		v, ok := value.([]interface{})
+		if !ok || len(v) != len(columns) {
+			continue
+		}
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			row[fmt.Sprint(column)] = v[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
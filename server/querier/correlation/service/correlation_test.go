@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/deepflowio/deepflow/server/querier/correlation/common"
+	"github.com/deepflowio/deepflow/server/querier/correlation/model"
+)
+
+func TestCheckArgs(t *testing.T) {
+	cases := []struct {
+		args  model.Correlation
+		valid bool
+	}{
+		{model.Correlation{TimeStart: 1, TimeEnd: 2}, false},
+		{model.Correlation{SpanID: "a", PodIDs: []uint32{1}, TimeStart: 1, TimeEnd: 2}, false},
+		{model.Correlation{TraceID: "a' OR 1=1", TimeStart: 1, TimeEnd: 2}, false},
+		{model.Correlation{PodIDs: []uint32{1}, TimeStart: 3, TimeEnd: 2}, false},
+		{model.Correlation{TraceID: "5af7183fb1d4cf5f", SpanID: "seg-1.2", TimeStart: 1, TimeEnd: 2}, true},
+		{model.Correlation{GProcessIDs: []uint32{1}, TimeStart: 1, TimeEnd: 2, Limit: 100000}, true},
+	}
+	for i, c := range cases {
+		err := checkArgs(&c.args)
+		if (err == nil) != c.valid {
+			t.Errorf("case %d: expect valid %v, got err %v", i, c.valid, err)
+		}
+		if err == nil && (c.args.Limit <= 0 || c.args.Limit > common.MAX_LIMIT) {
+			t.Errorf("case %d: limit %d not normalized", i, c.args.Limit)
+		}
+	}
+}
+
+func TestResolveAnchor(t *testing.T) {
+	args := &model.Correlation{TraceID: "t1", SpanID: "s2", TimeStart: 1000, TimeEnd: 5000}
+	c := &correlator{
+		args:   args,
+		anchor: &model.Anchor{TraceID: args.TraceID, SpanID: args.SpanID, TimeStart: args.TimeStart, TimeEnd: args.TimeEnd},
+	}
+	c.resolveAnchor([]map[string]interface{}{
+		{"span_id": "s1", "pod_id_0": json.Number("7"), "pod_id_1": json.Number("8"), "start_time": json.Number("1500000000")},
+		{"span_id": "s2", "pod_id_0": json.Number("0"), "pod_id_1": json.Number("9"), "gprocess_id_1": json.Number("3"),
+			"flow_id": json.Number("12345678901234"), "start_time": json.Number("2000000000"), "end_time": json.Number("2001000000")},
+	})
+	if !reflect.DeepEqual(c.anchor.PodIDs, []uint32{9}) || !reflect.DeepEqual(c.anchor.GProcessIDs, []uint32{3}) {
+		t.Errorf("unexpected anchor tags %v %v", c.anchor.PodIDs, c.anchor.GProcessIDs)
+	}
+	if !reflect.DeepEqual(c.flowIDs, []uint64{12345678901234}) {
+		t.Errorf("unexpected flow ids %v", c.flowIDs)
+	}
+	if c.anchor.TimeStart != 2000-common.TRACE_WINDOW_MARGIN || c.anchor.TimeEnd != 2001+common.TRACE_WINDOW_MARGIN {
+		t.Errorf("unexpected window %d-%d", c.anchor.TimeStart, c.anchor.TimeEnd)
+	}
+
+	expected := "time>=1940 AND time<=2061 AND (pod_id_0 IN (9) OR pod_id_1 IN (9) OR gprocess_id_0 IN (3) OR gprocess_id_1 IN (3) OR flow_id IN (12345678901234))"
+	if filter := c.edgeFilter(); filter != expected {
+		t.Errorf("unexpected edge filter %s", filter)
+	}
+	if filter := c.singleFilter(false); filter != "time>=1940 AND time<=2061 AND (pod_id IN (9))" {
+		t.Errorf("unexpected single filter %s", filter)
+	}
+}
+
+func TestGProcessPodIDs(t *testing.T) {
+	c := &correlator{
+		args:   &model.Correlation{GProcessIDs: []uint32{3}},
+		anchor: &model.Anchor{GProcessIDs: []uint32{3}},
+	}
+	podIDs := c.gprocessPodIDs(
+		[]map[string]interface{}{
+			{"gprocess_id_0": uint32(3), "pod_id_0": uint32(7), "gprocess_id_1": uint32(4), "pod_id_1": uint32(8)},
+		},
+		[]map[string]interface{}{
+			{"gprocess_id_0": uint32(0), "pod_id_0": uint32(9), "gprocess_id_1": uint32(3), "pod_id_1": uint32(10)},
+			{"gprocess_id_0": uint32(3), "pod_id_0": uint32(0)},
+		},
+	)
+	if !reflect.DeepEqual(podIDs, []uint32{7, 10}) {
+		t.Errorf("unexpected pod ids %v", podIDs)
+	}
+
+	rows := toRows([]interface{}{"pod_id", "pod"}, []interface{}{[]interface{}{uint32(7), "a"}, []interface{}{uint32(8)}})
+	if len(rows) != 1 || rows[0]["pod"] != "a" || toUint32(rows[0]["pod_id"]) != 7 {
+		t.Errorf("unexpected rows %v", rows)
+	}
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type idSet map[uint32]struct{}
+
+func newIDSet(ids []uint32) idSet {
+	s := idSet{}
+	s.add(ids...)
+	return s
+}
+
+// add 忽略为0的ID(未关联到资源)
+func (s idSet) add(ids ...uint32) {
+	for _, id := range ids {
+		if id != 0 {
+			s[id] = struct{}{}
+		}
+	}
+}
+
+func (s idSet) list() []uint32 {
+	ids := make([]uint32, 0, len(s))
+	for id := range s {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func joinIDs[T uint32 | uint64](ids []T) string {
+	strs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		strs = append(strs, strconv.FormatUint(uint64(id), 10))
+	}
+	return strings.Join(strs, ",")
+}
+
+// 查询引擎返回各类整型, 经过json解析后可能是json.Number, float64或字符串
+func toInt64(v interface{}) int64 {
+	switch n := v.(type) {
+	case json.Number:
+		i, err := n.Int64()
+		if err != nil {
+			f, _ := n.Float64()
+			return int64(f)
+		}
+		return i
+	case float64:
+		return int64(n)
+	case int64:
+		return n
+	case nil:
+		return 0
+	default:
+		i, _ := strconv.ParseInt(fmt.Sprint(n), 10, 64)
+		return i
+	}
+}
+
+func toUint64(v interface{}) uint64 {
+	if u, ok := v.(uint64); ok {
+		return u
+	}
+	if s, ok := v.(json.Number); ok {
+		if u, err := strconv.ParseUint(s.String(), 10, 64); err == nil {
+			return u
+		}
+	}
+	if s, ok := v.(string); ok {
+		u, _ := strconv.ParseUint(s, 10, 64)
+		return u
+	}
+	return uint64(toInt64(v))
+}
+
+func toUint32(v interface{}) uint32 {
+	return uint32(toInt64(v))
+}
+
+func toString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
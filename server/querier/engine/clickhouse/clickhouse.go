@@ -51,6 +51,14 @@ type CHEngine struct {
 	ColumnSchemas []*common.ColumnSchema
 	View          *view.View
 	Context       context.Context
+	// 资源名称和K8s标签按数据行的时间翻译
+	TagHistory bool
+	// union和join的翻译结果, 不包含LIMIT
+	compositeSql string
+	// 使用了查询数据表的子查询
+	hasSubquery bool
+	// where中子查询的翻译结果, having会解析两次where, 避免重复翻译
+	whereSubqueries map[*sqlparser.Subquery]string
 }
 
 func (e *CHEngine) ExecuteQuery(args *common.QuerierParams) (*common.Result, map[string]interface{}, error) {
@@ -61,6 +69,14 @@ func (e *CHEngine) ExecuteQuery(args *common.QuerierParams) (*common.Result, map
 	sql := args.Sql
 	query_uuid := args.QueryUUID // FIXME: should be queryUUID
 	log.Debugf("query_uuid: %s | raw sql: %s", query_uuid, sql)
+	if e.Context == nil {
+		e.Context = args.Context
+	}
+	e.TagHistory = args.TagHistory && e.DB != "flow_tag"
+	// make the query cancelable by query_uuid
+	ctx, done := client.RunningQueries.Register(e.Context, query_uuid, e.DB, sql)
+	defer done()
+	e.Context = ctx
 	// Parse slimitSql
 	slimitResult, slimitDebug, err := e.ParseSlimitSql(sql, args)
 	if err != nil {
@@ -141,6 +157,7 @@ func (e *CHEngine) ExecuteQuery(args *common.QuerierParams) (*common.Result, map
 	for _, stmt := range e.Statements {
 		stmt.Format(e.Model)
 	}
+	FormatStreamLimit(e.Model, args)
 	FormatModel(e.Model)
 	// 使用Model生成View
 	e.View = view.NewView(e.Model)
@@ -165,6 +182,7 @@ func (e *CHEngine) ExecuteQuery(args *common.QuerierParams) (*common.Result, map
 		Callbacks:       callbacks,
 		QueryUUID:       query_uuid,
 		ColumnSchemaMap: ColumnSchemaMap,
+		Writer:          args.Writer,
 	}
 	rst, err := chClient.DoQuery(params)
 	if err != nil {
@@ -437,7 +455,7 @@ func (e *CHEngine) ParseSlimitSql(sql string, args *common.QuerierParams) (*comm
 				}
 			}
 		}
-		innerEngine := &CHEngine{DB: e.DB, DataSource: e.DataSource, Context: e.Context}
+		innerEngine := &CHEngine{DB: e.DB, DataSource: e.DataSource, Context: e.Context, TagHistory: e.TagHistory}
 		innerEngine.Init()
 		innerParser := parse.Parser{Engine: innerEngine}
 		err = innerParser.ParseSQL(innerSql)
@@ -453,7 +471,7 @@ func (e *CHEngine) ParseSlimitSql(sql string, args *common.QuerierParams) (*comm
 		innerEngine.View = view.NewView(innerEngine.Model)
 		innerTransSql = innerEngine.ToSQLString()
 	}
-	outerEngine := &CHEngine{DB: e.DB, DataSource: e.DataSource, Context: e.Context}
+	outerEngine := &CHEngine{DB: e.DB, DataSource: e.DataSource, Context: e.Context, TagHistory: e.TagHistory}
 	outerEngine.Init()
 	outerParser := parse.Parser{Engine: outerEngine}
 	err = outerParser.ParseSQL(newSql)
@@ -464,6 +482,7 @@ func (e *CHEngine) ParseSlimitSql(sql string, args *common.QuerierParams) (*comm
 	for _, stmt := range outerEngine.Statements {
 		stmt.Format(outerEngine.Model)
 	}
+	FormatStreamLimit(outerEngine.Model, args)
 	FormatModel(outerEngine.Model)
 	// 使用Model生成View
 	outerEngine.View = view.NewView(outerEngine.Model)
@@ -523,6 +542,7 @@ func (e *CHEngine) ParseSlimitSql(sql string, args *common.QuerierParams) (*comm
 		Callbacks:       callbacks,
 		QueryUUID:       query_uuid,
 		ColumnSchemaMap: ColumnSchemaMap,
+		Writer:          args.Writer,
 	}
 	rst, err := chClient.DoQuery(params)
 	if err != nil {
@@ -608,7 +628,7 @@ func (e *CHEngine) TransSelect(tags sqlparser.SelectExprs) error {
 
 func (e *CHEngine) TransWhere(node *sqlparser.Where) error {
 	// 生成where的statement
-	whereStmt := Where{time: e.Model.Time}
+	whereStmt := Where{time: e.Model.Time, history: e.TagHistory}
 	// 解析ast树并生成view.Node结构
 	expr, err := e.parseWhere(node.Expr, &whereStmt, false)
 	filter := view.Filters{Expr: expr}
@@ -619,7 +639,7 @@ func (e *CHEngine) TransWhere(node *sqlparser.Where) error {
 
 func (e *CHEngine) TransHaving(node *sqlparser.Where) error {
 	// 生成having的statement
-	havingStmt := Having{Where{}}
+	havingStmt := Having{Where{history: e.TagHistory}}
 	// 解析ast树并生成view.Node结构
 	// having中的metric需要在trans之前确定是否分层，所以需要提前遍历
 	_, err := e.parseWhere(node.Expr, &havingStmt.Where, true)
@@ -681,6 +701,17 @@ func (e *CHEngine) TransFrom(froms sqlparser.TableExprs) error {
 	return nil
 }
 
+// flow_metrics的_backfill列只存在于1m和1s的原始表, 聚合生成的数据源中没有该列
+func (e *CHEngine) checkMetricDataSource(args []string) error {
+	if e.DB != "flow_metrics" || len(args) == 0 || strings.Trim(args[0], "`") != "backfill" {
+		return nil
+	}
+	if e.DataSource != "" && e.DataSource != "1m" && e.DataSource != "1s" {
+		return errors.New(fmt.Sprintf("metric backfill is not available in data source %s", e.DataSource))
+	}
+	return nil
+}
+
 func (e *CHEngine) TransGroupBy(groups sqlparser.GroupBy) error {
 	groupSlice := []string{}
 	for _, group := range groups {
@@ -738,6 +769,19 @@ func (e *CHEngine) TransLimit(limit *sqlparser.Limit) error {
 
 // 原始sql转为clickhouse-sql
 func (e *CHEngine) ToSQLString() string {
+	chSql := e.toSQLString()
+	if e.hasSubquery {
+		// 子查询中的表为分布式表, 需要在查询发起的节点执行子查询
+		chSql += " SETTINGS distributed_product_mode = 'global'"
+	}
+	return chSql
+}
+
+func (e *CHEngine) toSQLString() string {
+	if e.compositeSql != "" {
+		FormatLimit(e.Model)
+		return e.compositeSql + e.Model.Limit.ToString()
+	}
 	if e.View == nil {
 		for _, stmt := range e.Statements {
 			stmt.Format(e.Model)
@@ -780,8 +824,8 @@ func (e *CHEngine) parseGroupBy(group sqlparser.Expr) error {
 	// 普通字符串
 	case *sqlparser.ColName, *sqlparser.SQLVal:
 		groupTag := chCommon.ParseAlias(expr)
-		// pod_ingress/lb_listener is not supported by group
-		if strings.HasPrefix(groupTag, "pod_ingress") || strings.HasPrefix(groupTag, "lb_listener") {
+		// pod_ingress/lb_listener is not supported by group, except pod_ingress_type
+		if (strings.HasPrefix(groupTag, "pod_ingress") && !strings.HasPrefix(groupTag, "pod_ingress_type")) || strings.HasPrefix(groupTag, "lb_listener") {
 			errStr := fmt.Sprintf("%s is not supported by group", groupTag)
 			return errors.New(errStr)
 		}
@@ -796,8 +840,8 @@ func (e *CHEngine) parseGroupBy(group sqlparser.Expr) error {
 				return err
 			}
 		} else {
-			// pod_ingress/lb_listener is not supported by group
-			if strings.HasPrefix(preAsGroup, "pod_ingress") || strings.HasPrefix(preAsGroup, "lb_listener") {
+			// pod_ingress/lb_listener is not supported by group, except pod_ingress_type
+			if (strings.HasPrefix(preAsGroup, "pod_ingress") && !strings.HasPrefix(preAsGroup, "pod_ingress_type")) || strings.HasPrefix(preAsGroup, "lb_listener") {
 				errStr := fmt.Sprintf("%s is not supported by group", groupTag)
 				return errors.New(errStr)
 			}
@@ -921,6 +965,9 @@ func (e *CHEngine) parseSelectAlias(item *sqlparser.AliasedExpr) error {
 		if as == "" {
 			functionAs = strings.ReplaceAll(chCommon.ParseAlias(item.Expr), "`", "")
 		}
+		if err := e.checkMetricDataSource(args); err != nil {
+			return err
+		}
 		function, levelFlag, unit, err := GetAggFunc(name, args, functionAs, e.DB, e.Table, e.Context)
 		if err != nil {
 			return err
@@ -1014,6 +1061,9 @@ func (e *CHEngine) parseSelectBinaryExpr(node sqlparser.Expr) (binary Function,
 		if err != nil {
 			return nil, err
 		}
+		if err := e.checkMetricDataSource(args); err != nil {
+			return nil, err
+		}
 		aggfunction, levelFlag, unit, err := GetAggFunc(name, args, "", e.DB, e.Table, e.Context)
 		if err != nil {
 			return nil, err
@@ -1081,7 +1131,7 @@ func (e *CHEngine) AddTable(table string) {
 }
 
 func (e *CHEngine) AddTag(tag string, alias string) (string, error) {
-	stmt, labelType, err := GetTagTranslator(tag, alias, e.DB, e.Table)
+	stmt, labelType, err := GetTagTranslator(tag, alias, e.DB, e.Table, e.TagHistory)
 	if err != nil {
 		return labelType, err
 	}
@@ -1164,6 +1214,13 @@ func (e *CHEngine) parseWhere(node sqlparser.Expr, w *Where, isCheck bool) (view
 				}
 			}
 			whereValue := sqlparser.String(node.Right)
+			if subquery, ok := node.Right.(*sqlparser.Subquery); ok {
+				var err error
+				whereValue, err = e.transWhereSubquery(node.Operator, subquery)
+				if err != nil {
+					return nil, err
+				}
+			}
 			stmt := GetWhere(whereTag, whereValue)
 			return stmt.Trans(node, w, e.asTagMap, e.DB, e.Table)
 		case *sqlparser.FuncExpr, *sqlparser.BinaryExpr:
@@ -1246,6 +1303,14 @@ func FormatModel(m *view.Model) {
 	FormatLimit(m)
 }
 
+// FormatStreamLimit applies stream-limit instead of the default limit to a
+// streamed query without LIMIT
+func FormatStreamLimit(m *view.Model, args *common.QuerierParams) {
+	if m.Limit.Limit == "" && args != nil && args.Writer != nil && config.Cfg != nil {
+		m.Limit.Limit = config.Cfg.StreamLimit
+	}
+}
+
 func FormatLimit(m *view.Model) {
 	if m.Limit.Limit == "" {
 		defaultLimit := DEFAULT_LIMIT
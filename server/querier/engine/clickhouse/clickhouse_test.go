@@ -22,9 +22,13 @@ import (
 	"github.com/deepflowio/deepflow/server/querier/parse"
 
 	//"github.com/deepflowio/deepflow/server/querier/querier"
+	"strings"
 	"testing"
 
+	"github.com/xwb1989/sqlparser"
+
 	"github.com/deepflowio/deepflow/server/querier/config"
+	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse/view"
 )
 
 /* var (
@@ -118,6 +122,9 @@ var (
 	}, {
 		input:  "select region_id_0 from l7_flow_log where pod_ingress_0 !='xx' group by region_id_0",
 		output: "SELECT region_id_0 FROM flow_log.`l7_flow_log` PREWHERE (not(((if(is_ipv4=1,IPv4NumToString(ip4_0),IPv6NumToString(ip6_0)),toUInt64(l3_epc_id_0)) IN (SELECT ip,l3_epc_id FROM flow_tag.ip_relation_map WHERE pod_ingress_name = 'xx')) OR (toUInt64(service_id_0) IN (SELECT pod_service_id FROM flow_tag.ip_relation_map WHERE pod_ingress_name = 'xx')))) AND (region_id_0!=0) GROUP BY `region_id_0` LIMIT 10000",
+	}, {
+		input:  "select pod_ingress_type_0 from l7_flow_log group by pod_ingress_type_0",
+		output: "SELECT dictGet(flow_tag.pod_ingress_map, 'pod_ingress_type', dictGet(flow_tag.ip_relation_map, 'pod_ingress_id', (toUInt64(l3_epc_id_0),if(is_ipv4=1,IPv4NumToString(ip4_0),IPv6NumToString(ip6_0))))) AS `pod_ingress_type_0` FROM flow_log.`l7_flow_log` PREWHERE (dictGet(flow_tag.ip_relation_map, 'pod_ingress_id', (toUInt64(l3_epc_id_0),if(is_ipv4=1,IPv4NumToString(ip4_0),IPv6NumToString(ip6_0))))!=0) GROUP BY dictGet(flow_tag.pod_ingress_map, 'pod_ingress_type', dictGet(flow_tag.ip_relation_map, 'pod_ingress_id', (toUInt64(l3_epc_id_0),if(is_ipv4=1,IPv4NumToString(ip4_0),IPv6NumToString(ip6_0))))) AS `pod_ingress_type_0` LIMIT 10000",
 	}, {
 		input:  "select node_type(region_0) as `node_type_0`,mask(ip_0,33) as `mask_ip_0` from l7_flow_log group by `mask_ip_0`,`node_type_0`",
 		output: "WITH if(is_ipv4, IPv4NumToString(bitAnd(ip4_0, 4294967295)), IPv6NumToString(bitAnd(ip6_0, toFixedString(unhex('ffffffff800000000000000000000000'), 16)))) AS `mask_ip_0` SELECT 'region' AS `node_type_0`, `mask_ip_0` FROM flow_log.`l7_flow_log` GROUP BY `mask_ip_0`, `node_type_0` LIMIT 10000",
@@ -251,6 +258,15 @@ var (
 	}, {
 		input:  "SELECT Sum(log_count) as sum_log_count FROM l7_flow_log  WHERE `会话长度`>=893689408 ",
 		output: "SELECT SUM(1) AS `sum_log_count` FROM flow_log.`l7_flow_log` PREWHERE `会话长度` >= 893689408 LIMIT 10000",
+	}, {
+		input:  "select Sum(log_count) as sum_log_count from l4_flow_log where flow_id in (select flow_id from l7_flow_log where response_duration > 1000)",
+		output: "SELECT SUM(1) AS `sum_log_count` FROM flow_log.`l4_flow_log` PREWHERE flow_id in (SELECT flow_id FROM flow_log.`l7_flow_log` PREWHERE response_duration > 1000) LIMIT 10000 SETTINGS distributed_product_mode = 'global'",
+	}, {
+		input:  "select flow_id from l4_flow_log where flow_id > 1 union all select flow_id from l7_flow_log order by flow_id limit 5",
+		output: "SELECT * FROM ((SELECT flow_id FROM flow_log.`l4_flow_log` PREWHERE flow_id > 1) UNION ALL (SELECT flow_id FROM flow_log.`l7_flow_log`)) ORDER BY flow_id asc LIMIT 5",
+	}, {
+		input:  "select a.flow_id, b.trace_id as t from (select flow_id from l4_flow_log) as a join (select flow_id, trace_id from l7_flow_log) as b on a.flow_id = b.flow_id where b.trace_id != '' order by t desc limit 10",
+		output: "SELECT a.flow_id AS `flow_id`, b.trace_id AS `t` FROM (SELECT flow_id FROM flow_log.`l4_flow_log`) AS `a` INNER JOIN (SELECT flow_id, trace_id FROM flow_log.`l7_flow_log`) AS `b` ON a.flow_id = b.flow_id WHERE b.trace_id != '' ORDER BY t desc LIMIT 10",
 	}}
 )
 
@@ -275,6 +291,166 @@ func TestGetSql(t *testing.T) {
 	}
 }
 
+var tagHistorySQL = []struct {
+	input  string
+	output string
+}{{
+	input:  "select `k8s.label_0` from l7_flow_log",
+	output: "SELECT if(dictGetOrDefault(flow_tag.pod_service_k8s_labels_map, 'labels', toUInt64(service_id_0),'{}')!='{}', dictGetOrDefault(flow_tag.pod_service_k8s_labels_map, 'labels', toUInt64(service_id_0),'{}'), dictGetOrDefault(flow_tag.pod_k8s_labels_history_map, 'labels', toUInt64(pod_id_0), time, dictGetOrDefault(flow_tag.pod_k8s_labels_map, 'labels', toUInt64(pod_id_0), '{}')))  AS `k8s.label_0` FROM flow_log.`l7_flow_log` LIMIT 10000",
+}, {
+	input:  "select pod_service_0 from l7_flow_log where pod_service_0 !='xx' group by pod_service_0",
+	output: "SELECT dictGetOrDefault(flow_tag.device_history_map, 'name', (toUInt64(11),toUInt64(service_id_0)), time, dictGetOrDefault(flow_tag.device_map, 'name', (toUInt64(11),toUInt64(service_id_0)), '')) AS `pod_service_0` FROM flow_log.`l7_flow_log` PREWHERE (not(dictGetOrDefault(flow_tag.device_history_map, 'name', (toUInt64(11),toUInt64(service_id_0)), time, dictGetOrDefault(flow_tag.device_map, 'name', (toUInt64(11),toUInt64(service_id_0)), '')) = 'xx')) AND (service_id_0!=0) GROUP BY dictGetOrDefault(flow_tag.device_history_map, 'name', (toUInt64(11),toUInt64(service_id_0)), time, dictGetOrDefault(flow_tag.device_map, 'name', (toUInt64(11),toUInt64(service_id_0)), '')) AS `pod_service_0` LIMIT 10000",
+}}
+
+func TestTagHistory(t *testing.T) {
+	Load()
+	for _, pcase := range tagHistorySQL {
+		e := CHEngine{DB: "flow_log", TagHistory: true}
+		e.Init()
+		parser := parse.Parser{Engine: &e}
+		parser.ParseSQL(pcase.input)
+		out := parser.Engine.ToSQLString()
+		if out != pcase.output {
+			t.Errorf("Parse \n\t%q \n get: \n\t %q \n want: \n\t %q", pcase.input, out, pcase.output)
+		}
+	}
+}
+
+func TestCheckMetricDataSource(t *testing.T) {
+	cases := []struct {
+		db         string
+		dataSource string
+		args       []string
+		valid      bool
+	}{
+		{"flow_metrics", "1m", []string{"backfill"}, true},
+		{"flow_metrics", "", []string{"`backfill`"}, true},
+		{"flow_metrics", "1h", []string{"backfill"}, false},
+		{"flow_metrics", "1h", []string{"byte"}, true},
+		{"flow_log", "1h", []string{"backfill"}, true},
+	}
+	for _, c := range cases {
+		e := CHEngine{DB: c.db, DataSource: c.dataSource}
+		if err := e.checkMetricDataSource(c.args); (err == nil) != c.valid {
+			t.Errorf("checkMetricDataSource(%s, %s, %v) = %v, expected valid %v", c.db, c.dataSource, c.args, err, c.valid)
+		}
+	}
+}
+
+func TestSubqueryError(t *testing.T) {
+	Load()
+	for _, sql := range []string{
+		// 只支持union all
+		"select flow_id from l4_flow_log union select flow_id from l7_flow_log",
+		// 只支持等值join
+		"select a.flow_id from (select flow_id from l4_flow_log) as a join (select flow_id from l7_flow_log) as b on a.flow_id > b.flow_id",
+		"select a.flow_id from (select flow_id from l4_flow_log) as a join (select flow_id from l7_flow_log) as b on a.flow_id = b.trace_id",
+		// 重复的表别名
+		"select a.flow_id from (select flow_id from l4_flow_log) as a join (select flow_id from l7_flow_log) as a on a.flow_id = a.flow_id",
+		// join的外层不支持group by
+		"select a.flow_id from (select flow_id from l4_flow_log) as a join (select flow_id from l7_flow_log) as b on a.flow_id = b.flow_id group by a.flow_id",
+	} {
+		e := CHEngine{DB: "flow_log"}
+		e.Init()
+		parser := parse.Parser{Engine: &e}
+		if err := parser.ParseSQL(sql); err == nil {
+			t.Errorf("Parse %q expected error, get: %q", sql, e.ToSQLString())
+		}
+	}
+}
+
+func TestSubqueryTagHistory(t *testing.T) {
+	Load()
+	e := CHEngine{DB: "flow_log", TagHistory: true}
+	e.Init()
+	subEngine, err := e.TransSubSelect(&sqlparser.Select{
+		SelectExprs: sqlparser.SelectExprs{&sqlparser.AliasedExpr{Expr: &sqlparser.ColName{Name: sqlparser.NewColIdent("flow_id")}}},
+		From:        sqlparser.TableExprs{&sqlparser.AliasedTableExpr{Expr: sqlparser.TableName{Name: sqlparser.NewTableIdent("l7_flow_log")}}},
+	})
+	if err != nil || !subEngine.TagHistory {
+		t.Errorf("TransSubSelect() tag history = %v, %v, want true", subEngine, err)
+	}
+}
+
+func TestJoinTagTranslation(t *testing.T) {
+	Load()
+	sql := "select a.pod_service_0, b.pod_service_1 from (select pod_service_0 from l7_flow_log) as a join (select pod_service_1 from l7_flow_log) as b on a.pod_service_0 = b.pod_service_1"
+	for _, c := range []struct {
+		tagHistory bool
+		contains   []string
+	}{{
+		tagHistory: false,
+		contains: []string{
+			"(SELECT dictGet(flow_tag.device_map, 'name', (toUInt64(11),toUInt64(service_id_0))) AS `pod_service_0` FROM flow_log.`l7_flow_log`",
+			"(SELECT dictGet(flow_tag.device_map, 'name', (toUInt64(11),toUInt64(service_id_1))) AS `pod_service_1` FROM flow_log.`l7_flow_log`",
+			"ON a.pod_service_0 = b.pod_service_1",
+		},
+	}, {
+		tagHistory: true,
+		contains: []string{
+			"dictGetOrDefault(flow_tag.device_history_map, 'name', (toUInt64(11),toUInt64(service_id_0)), time, ",
+			"dictGetOrDefault(flow_tag.device_history_map, 'name', (toUInt64(11),toUInt64(service_id_1)), time, ",
+		},
+	}} {
+		e := CHEngine{DB: "flow_log", TagHistory: c.tagHistory}
+		e.Init()
+		parser := parse.Parser{Engine: &e}
+		if err := parser.ParseSQL(sql); err != nil {
+			t.Fatalf("Parse %q failed: %s", sql, err)
+		}
+		out := e.ToSQLString()
+		// join两侧的子查询分别翻译tag
+		for _, s := range c.contains {
+			if !strings.Contains(out, s) {
+				t.Errorf("Parse %q with tag history %v \n get: \n\t %q \n should contain: \n\t %q", sql, c.tagHistory, out, s)
+			}
+		}
+	}
+}
+
+func TestWhereSubqueryCache(t *testing.T) {
+	Load()
+	e := CHEngine{DB: "flow_log"}
+	e.Init()
+	stmt, err := sqlparser.Parse("select flow_id from l4_flow_log where flow_id in (select flow_id from l7_flow_log where response_duration > 1000)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	comparison := stmt.(*sqlparser.Select).Where.Expr.(*sqlparser.ComparisonExpr)
+	subquery := comparison.Right.(*sqlparser.Subquery)
+	first, err := e.transWhereSubquery(comparison.Operator, subquery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// having会解析两次where, 子查询只翻译一次
+	second, err := e.transWhereSubquery(comparison.Operator, subquery)
+	if err != nil || second != first || len(e.whereSubqueries) != 1 {
+		t.Errorf("transWhereSubquery() again = %q, %v, want %q", second, err, first)
+	}
+}
+
+func TestMergeCallbacks(t *testing.T) {
+	subEngine := CHEngine{DB: "flow_log"}
+	subEngine.Init()
+	subEngine.Model.AddCallback("mac_0", MacTranslate([]interface{}{"mac_0", "mac_0"}))
+	subEngine.View = view.NewView(subEngine.Model)
+
+	e := CHEngine{DB: "flow_log"}
+	e.Init()
+	// 外层没有保留同名的列
+	if err := e.mergeCallbacks(&subEngine, func(string) bool { return false }); err == nil {
+		t.Error("mergeCallbacks() expected error when the column is renamed")
+	}
+	if err := e.mergeCallbacks(&subEngine, func(column string) bool { return column == "mac_0" }); err != nil || e.Model.Callbacks["mac_0"] == nil {
+		t.Errorf("mergeCallbacks() = %v, callbacks %v", err, e.Model.Callbacks)
+	}
+	// 补点不支持在union和join中使用
+	subEngine.Model.AddCallback("time", TimeFill([]interface{}{subEngine.Model}))
+	if err := e.mergeCallbacks(&subEngine, func(string) bool { return true }); err == nil {
+		t.Error("mergeCallbacks() expected error with time fill")
+	}
+}
+
 /* func TestGetSqltest(t *testing.T) {
 	for _, pcase := range parsetest {
 		e := CHEngine{DB: "flow_log"}
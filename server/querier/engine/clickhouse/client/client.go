@@ -19,6 +19,7 @@ package client
 import (
 	"context"
 	"reflect"
+	"sync"
 
 	//"database/sql"
 	"fmt"
@@ -30,6 +31,7 @@ import (
 
 	"github.com/deepflowio/deepflow/server/querier/common"
 	"github.com/deepflowio/deepflow/server/querier/config"
+	"github.com/deepflowio/deepflow/server/querier/governance"
 	"github.com/deepflowio/deepflow/server/querier/statsd"
 	"github.com/google/uuid"
 	logging "github.com/op/go-logging"
@@ -42,10 +44,15 @@ type QueryParams struct {
 	Callbacks       map[string]func(result *common.Result) error
 	QueryUUID       string
 	ColumnSchemaMap map[string]*common.ColumnSchema
+	Writer          common.ResultWriter
 }
 
-// All ClickHouse Client share one connection
-var connection clickhouse.Conn
+// Rows of a streamed query are written in batches of STREAM_BATCH_SIZE
+const STREAM_BATCH_SIZE = 8192
+
+// All ClickHouse Client share one connection for each endpoint
+var connections = make(map[string]clickhouse.Conn)
+var connectionsLock sync.RWMutex
 
 type Client struct {
 	Host       string
@@ -58,7 +65,7 @@ type Client struct {
 	Debug      *Debug
 }
 
-func (c *Client) init(query_uuid string) error {
+func (c *Client) init(query_uuid string) {
 	if query_uuid == "" {
 		query_uuid = uuid.NewString()
 	}
@@ -68,34 +75,60 @@ func (c *Client) init(query_uuid string) error {
 			IP:        c.Host,
 		}
 	}
-	if connection == nil { // FIXME: add a RWLock
-		conn, err := clickhouse.Open(&clickhouse.Options{
-			Addr: []string{fmt.Sprintf("%s:%d", c.Host, c.Port)},
-			Auth: clickhouse.Auth{
-				Database: "default",
-				Username: c.UserName,
-				Password: c.Password,
-			},
-			// Default MaxOpenConns = MaxIdleConns + 5
-			//     Ref: https://clickhouse.com/docs/en/integrations/go/clickhouse-go/clickhouse-api#connection-settings
-			// In ClickHouse SDK, when returning a connection, if the current number of idle connections is equal to
-			// `MaxIdleConns`, the connection to be returned will be closed directly. Therefore, when `MaxOpenConns`
-			// is greater than `MaxIdleConns`, it is very easy for the connection to be actively closed, and it is
-			// easy to cause a lot of short connections during high-concurrency queries, so set the two to the same
-			// value here.
-			//     Ref: https://github.com/ClickHouse/clickhouse-go/blob/main/clickhouse.go#L296
-			MaxOpenConns: config.Cfg.Clickhouse.MaxConnection,
-			MaxIdleConns: config.Cfg.Clickhouse.MaxConnection,
-			DialTimeout:  time.Duration(config.Cfg.Clickhouse.Timeout) * time.Second,
-		})
-		if err != nil {
-			log.Errorf("connect clickhouse failed: %s, url: %s:%s@%s:%d", err, c.UserName, c.Password, c.Host, c.Port)
-			return err
-		}
-		connection = conn
+}
+
+func getConnection(endpoint string) (clickhouse.Conn, error) {
+	connectionsLock.RLock()
+	conn, ok := connections[endpoint]
+	connectionsLock.RUnlock()
+	if ok {
+		return conn, nil
+	}
+
+	connectionsLock.Lock()
+	defer connectionsLock.Unlock()
+	if conn, ok := connections[endpoint]; ok {
+		return conn, nil
+	}
+	conn, err := clickhouse.Open(&clickhouse.Options{
+		Addr: []string{endpoint},
+		Auth: clickhouse.Auth{
+			Database: "default",
+			Username: config.Cfg.Clickhouse.User,
+			Password: config.Cfg.Clickhouse.Password,
+		},
+		// Default MaxOpenConns = MaxIdleConns + 5
+		//     Ref: https://clickhouse.com/docs/en/integrations/go/clickhouse-go/clickhouse-api#connection-settings
+		// In ClickHouse SDK, when returning a connection, if the current number of idle connections is equal to
+		// `MaxIdleConns`, the connection to be returned will be closed directly. Therefore, when `MaxOpenConns`
+		// is greater than `MaxIdleConns`, it is very easy for the connection to be actively closed, and it is
+		// easy to cause a lot of short connections during high-concurrency queries, so set the two to the same
+		// value here.
+		//     Ref: https://github.com/ClickHouse/clickhouse-go/blob/main/clickhouse.go#L296
+		MaxOpenConns: config.Cfg.Clickhouse.MaxConnection,
+		MaxIdleConns: config.Cfg.Clickhouse.MaxConnection,
+		DialTimeout:  time.Duration(config.Cfg.Clickhouse.Timeout) * time.Second,
+	})
+	if err != nil {
+		log.Errorf("connect clickhouse failed: %s, url: %s:%s@%s", err, config.Cfg.Clickhouse.User, config.Cfg.Clickhouse.Password, endpoint)
+		return nil, err
+	}
+	connections[endpoint] = conn
+	return conn, nil
+}
+
+// closeConnection closes the shared connection of an endpoint that is no longer used
+func closeConnection(endpoint string) {
+	connectionsLock.Lock()
+	conn, ok := connections[endpoint]
+	delete(connections, endpoint)
+	connectionsLock.Unlock()
+	if !ok {
+		return
+	}
+	if err := conn.Close(); err != nil {
+		log.Warningf("close connection of clickhouse endpoint %s failed: %s", endpoint, err)
 	}
-	c.connection = connection
-	return nil
 }
 
 func (c *Client) Close() error {
@@ -104,10 +137,7 @@ func (c *Client) Close() error {
 
 func (c *Client) DoQuery(params *QueryParams) (result *common.Result, err error) {
 	sqlstr, callbacks, query_uuid, columnSchemaMap := params.Sql, params.Callbacks, params.QueryUUID, params.ColumnSchemaMap
-	err = c.init(query_uuid)
-	if err != nil {
-		return nil, err
-	}
+	c.init(query_uuid)
 	defer c.Close()
 
 	start := time.Now()
@@ -115,20 +145,120 @@ func (c *Client) DoQuery(params *QueryParams) (result *common.Result, err error)
 	if c.Context == nil {
 		ctx = context.Background()
 	}
-	rows, err := c.connection.Query(ctx, sqlstr)
 	c.Debug.Sql = sqlstr
+	if query := governance.FromContext(ctx); query != nil {
+		query.AddGeneratedSql(sqlstr)
+	}
+
+	batchSize := 0
+	onBatch := func(batch *common.Result) error {
+		result = batch
+		return nil
+	}
+	if params.Writer != nil {
+		batchSize = STREAM_BATCH_SIZE
+		onBatch = func(batch *common.Result) error {
+			if result == nil {
+				result = &common.Result{Columns: batch.Columns, Schemas: batch.Schemas}
+			}
+			// time fill needs the whole result, so it is not applied to streamed batches
+			for key, callback := range callbacks {
+				if key == "time" {
+					continue
+				}
+				if err := callback(batch); err != nil {
+					log.Error("Execute Callback %v Error: %v", callback, err)
+				}
+			}
+			return params.Writer.WriteBatch(batch)
+		}
+	}
+
+	// all queries are reads, so a query failed by a connection error is retried on another replica,
+	// unless part of its result has already been streamed
+	tried := make(map[string]bool)
+	resRows, resSize := 0, 0
+	for {
+		endpoint := getEndpointPool().Pick(tried)
+		if endpoint == nil {
+			break
+		}
+		tried[endpoint.Addr] = true
+		c.Debug.IP = endpoint.Addr
+		resRows, resSize, err = c.queryEndpoint(ctx, endpoint, sqlstr, columnSchemaMap, batchSize, onBatch)
+		if err == nil || !isRetryable(err) || result != nil || len(tried) > config.Cfg.Clickhouse.MaxRetries {
+			break
+		}
+		log.Warningf("query_uuid: %s, query clickhouse %s failed: %s, retry on another replica", c.Debug.QueryUUID, endpoint.Addr, err)
+	}
 	if err != nil {
 		log.Errorf("query clickhouse Error: %s, sql: %s, query_uuid: %s", err, sqlstr, c.Debug.QueryUUID)
 		c.Debug.Error = fmt.Sprintf("%s", err)
 		return nil, err
 	}
-	defer rows.Close()
-	columns := rows.ColumnTypes()
-	resColumns := len(columns)
-	if err != nil {
-		c.Debug.Error = fmt.Sprintf("%s", err)
+	if result == nil {
+		err = fmt.Errorf("no clickhouse endpoint available")
+		c.Debug.Error = err.Error()
 		return nil, err
 	}
+
+	queryTime := time.Since(start)
+	resColumns := len(result.Columns)
+	statsd.QuerierCounter.WriteCk(
+		&statsd.ClickhouseCounter{
+			ResponseSize: uint64(resSize),
+			RowCount:     uint64(resRows),
+			ColumnCount:  uint64(resColumns),
+			QueryTime:    uint64(queryTime),
+		},
+	)
+	c.Debug.QueryTime = int64(queryTime)
+	if params.Writer == nil {
+		for _, callback := range callbacks {
+			err := callback(result)
+			if err != nil {
+				log.Error("Execute Callback %v Error: %v", callback, err)
+			}
+		}
+	}
+	log.Debugf("sql: %s, query_uuid: %s", sqlstr, c.Debug.QueryUUID)
+	log.Infof("query_uuid: %s. query api statistics: %d rows, %d columns, %d bytes, cost %f ms", c.Debug.QueryUUID, resRows, resColumns, resSize, float64(queryTime.Milliseconds()))
+	return result, nil
+}
+
+// queryEndpoint reads the rows of sqlstr from endpoint and passes them to onBatch
+// every batchSize rows, batchSize 0 means all rows are passed in one batch.
+// onBatch is called at least once if the query succeeds.
+func (c *Client) queryEndpoint(ctx context.Context, endpoint *Endpoint, sqlstr string, columnSchemaMap map[string]*common.ColumnSchema,
+	batchSize int, onBatch func(*common.Result) error) (resRows int, resSize int, err error) {
+	conn, err := getConnection(endpoint.Addr)
+	if err != nil {
+		endpoint.setHealthy(false, err)
+		return 0, 0, err
+	}
+	c.connection = conn
+	ctx, done := queryContext(ctx, endpoint.Addr)
+	defer done()
+
+	// errors of Scan, TransType and onBatch are raised on this side, not by the endpoint
+	var localErr error
+	begin := endpoint.begin()
+	defer func() {
+		if localErr != nil {
+			endpoint.end(begin, nil)
+			return
+		}
+		endpoint.end(begin, err)
+		if isRetryable(err) {
+			endpoint.setHealthy(false, err)
+		}
+	}()
+	rows, err := c.connection.Query(ctx, sqlstr)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+	columns := rows.ColumnTypes()
 	columnNames := make([]interface{}, 0, len(columns))
 	var columnSchemas common.ColumnSchemas // FIXME: Slice growth should be avoided.
 	// 获取列名和列类型
@@ -141,59 +271,85 @@ func (c *Client) DoQuery(params *QueryParams) (result *common.Result, err error)
 		}
 	}
 	var values []interface{}
+	batches := 0
+	flush := func() error {
+		batches++
+		batch := &common.Result{
+			Columns: columnNames,
+			Values:  values,
+			Schemas: columnSchemas,
+		}
+		values = nil
+		localErr = onBatch(batch)
+		return localErr
+	}
 	columnValues := make([]interface{}, len(columns))
 	for i := range columns {
 		columnValues[i] = reflect.New(columns[i].ScanType()).Interface()
 	}
-	resSize := 0
 	for rows.Next() {
-		if err := rows.Scan(columnValues...); err != nil {
-			c.Debug.Error = fmt.Sprintf("%s", err)
-			return nil, err
+		if localErr = rows.Scan(columnValues...); localErr != nil {
+			return resRows, resSize, localErr
 		}
 		record := make([]interface{}, 0, len(columns))
 		for i, rawValue := range columnValues {
-			value, valueType, err := TransType(rawValue, columns[i].Name(), columns[i].DatabaseTypeName())
-			if err != nil {
-				c.Debug.Error = fmt.Sprintf("%s", err)
-				return nil, err
+			value, valueType, transErr := TransType(rawValue, columns[i].Name(), columns[i].DatabaseTypeName())
+			if transErr != nil {
+				localErr = transErr
+				return resRows, resSize, localErr
 			}
 			resSize += int(unsafe.Sizeof(value))
 			record = append(record, value)
 			columnSchemas[i].ValueType = valueType
 		}
 		values = append(values, record)
+		resRows++
+		if batchSize > 0 && len(values) >= batchSize {
+			if err := flush(); err != nil {
+				return resRows, resSize, err
+			}
+		}
 	}
 	// Even if the query operation produces an error, it does not necessarily return an error in the'err 'parameter,
 	// so the return value of the'rows. Err () ' method must be checked to ensure that the query operation is successful
 	if err := rows.Err(); err != nil {
-		log.Errorf("query clickhouse Error: %s, sql: %s, query_uuid: %s", err, sqlstr, c.Debug.QueryUUID)
-		c.Debug.Error = fmt.Sprintf("%s", err)
-		return nil, err
+		return resRows, resSize, err
 	}
-	queryTime := time.Since(start)
-	resRows := len(values)
-	statsd.QuerierCounter.WriteCk(
-		&statsd.ClickhouseCounter{
-			ResponseSize: uint64(resSize),
-			RowCount:     uint64(resRows),
-			ColumnCount:  uint64(resColumns),
-			QueryTime:    uint64(queryTime),
-		},
-	)
-	c.Debug.QueryTime = int64(queryTime)
-	result = &common.Result{
-		Columns: columnNames,
-		Values:  values,
-		Schemas: columnSchemas,
-	}
-	for _, callback := range callbacks {
-		err := callback(result)
-		if err != nil {
-			log.Error("Execute Callback %v Error: %v", callback, err)
+	if len(values) > 0 || batches == 0 {
+		if err := flush(); err != nil {
+			return resRows, resSize, err
 		}
 	}
-	log.Debugf("sql: %s, query_uuid: %s", sqlstr, c.Debug.QueryUUID)
-	log.Infof("query_uuid: %s. query api statistics: %d rows, %d columns, %d bytes, cost %f ms", c.Debug.QueryUUID, resRows, resColumns, resSize, float64(queryTime.Milliseconds()))
-	return result, nil
+	return resRows, resSize, nil
+}
+
+// queryContext attaches the query settings, query_id and progress callbacks of
+// the governed and running query to ctx
+func queryContext(ctx context.Context, endpoint string) (context.Context, func()) {
+	var options []clickhouse.QueryOption
+	var onProgress []func(*clickhouse.Progress)
+	done := func() {}
+	if query := governance.FromContext(ctx); query != nil {
+		if len(query.Settings) > 0 {
+			options = append(options, clickhouse.WithSettings(query.Settings))
+		}
+		onProgress = append(onProgress, query.OnProgress)
+	}
+	if running := RunningQueryFromContext(ctx); running != nil {
+		queryID := running.addClickhouseQuery(endpoint)
+		done = func() { running.removeClickhouseQuery(queryID) }
+		options = append(options, clickhouse.WithQueryID(queryID))
+		onProgress = append(onProgress, running.OnProgress)
+	}
+	if len(onProgress) > 0 {
+		options = append(options, clickhouse.WithProgress(func(p *clickhouse.Progress) {
+			for _, fn := range onProgress {
+				fn(p)
+			}
+		}))
+	}
+	if len(options) == 0 {
+		return ctx, done
+	}
+	return clickhouse.Context(ctx, options...), done
 }
@@ -0,0 +1,323 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	clickhouse "github.com/ClickHouse/clickhouse-go/v2"
+
+	"github.com/deepflowio/deepflow/server/querier/config"
+)
+
+const (
+	LOAD_BALANCE_ROUND_ROBIN       = "round-robin"
+	LOAD_BALANCE_LEAST_CONNECTIONS = "least-connections"
+)
+
+// Endpoint is one ClickHouse replica and its query statistics
+type Endpoint struct {
+	Addr       string
+	discovered bool
+
+	healthy   int32
+	inflight  int32
+	queries   uint64
+	errors    uint64
+	latency   uint64 // nanoseconds, sum of all queries
+	lastError atomic.Value
+}
+
+func newEndpoint(addr string, discovered bool) *Endpoint {
+	return &Endpoint{Addr: addr, discovered: discovered, healthy: 1}
+}
+
+func (e *Endpoint) Healthy() bool {
+	return atomic.LoadInt32(&e.healthy) == 1
+}
+
+func (e *Endpoint) setHealthy(healthy bool, err error) {
+	if healthy {
+		atomic.StoreInt32(&e.healthy, 1)
+		return
+	}
+	if atomic.SwapInt32(&e.healthy, 0) == 1 {
+		log.Warningf("clickhouse endpoint %s is unhealthy: %s", e.Addr, err)
+	}
+	if err != nil {
+		e.lastError.Store(err.Error())
+	}
+}
+
+func (e *Endpoint) begin() time.Time {
+	atomic.AddInt32(&e.inflight, 1)
+	return time.Now()
+}
+
+func (e *Endpoint) end(start time.Time, err error) {
+	atomic.AddInt32(&e.inflight, -1)
+	atomic.AddUint64(&e.queries, 1)
+	atomic.AddUint64(&e.latency, uint64(time.Since(start)))
+	if err != nil {
+		atomic.AddUint64(&e.errors, 1)
+		e.lastError.Store(err.Error())
+	}
+}
+
+func (e *Endpoint) Stats() map[string]interface{} {
+	queries := atomic.LoadUint64(&e.queries)
+	avgLatency := 0.0
+	if queries > 0 {
+		avgLatency = float64(atomic.LoadUint64(&e.latency)) / float64(queries) / 1e6
+	}
+	lastError, _ := e.lastError.Load().(string)
+	return map[string]interface{}{
+		"endpoint":       e.Addr,
+		"discovered":     e.discovered,
+		"healthy":        e.Healthy(),
+		"inflight":       atomic.LoadInt32(&e.inflight),
+		"queries":        queries,
+		"errors":         atomic.LoadUint64(&e.errors),
+		"avg_latency_ms": avgLatency,
+		"last_error":     lastError,
+	}
+}
+
+// EndpointPool routes queries across ClickHouse replicas. Endpoints come from
+// `clickhouse.endpoints` (or `host:port`) and, if `clickhouse.cluster` is set,
+// from the replicas of the cluster in `system.clusters`.
+type EndpointPool struct {
+	sync.RWMutex
+	cfg       *config.Clickhouse
+	endpoints []*Endpoint
+	next      uint32
+}
+
+var endpointPool *EndpointPool
+var endpointPoolOnce sync.Once
+
+func getEndpointPool() *EndpointPool {
+	endpointPoolOnce.Do(func() {
+		endpointPool = newEndpointPool(&config.Cfg.Clickhouse)
+	})
+	return endpointPool
+}
+
+func newEndpointPool(cfg *config.Clickhouse) *EndpointPool {
+	p := &EndpointPool{cfg: cfg}
+	addrs := cfg.Endpoints
+	if len(addrs) == 0 {
+		addrs = []string{fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)}
+	}
+	for _, addr := range addrs {
+		p.endpoints = append(p.endpoints, newEndpoint(addr, false))
+	}
+	return p
+}
+
+// StartEndpointPool discovers cluster replicas and starts health checking
+func StartEndpointPool() {
+	p := getEndpointPool()
+	if p.cfg.HealthCheckInterval <= 0 {
+		return
+	}
+	p.check()
+	go func() {
+		ticker := time.NewTicker(time.Duration(p.cfg.HealthCheckInterval) * time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			p.check()
+		}
+	}()
+}
+
+func (p *EndpointPool) Endpoints() []*Endpoint {
+	p.RLock()
+	defer p.RUnlock()
+	return append([]*Endpoint{}, p.endpoints...)
+}
+
+// Pick returns a healthy endpoint not in tried, if all endpoints are unhealthy,
+// unhealthy ones are still tried rather than failing the query directly.
+func (p *EndpointPool) Pick(tried map[string]bool) *Endpoint {
+	endpoints := p.Endpoints()
+	var candidates []*Endpoint
+	for _, e := range endpoints {
+		if !tried[e.Addr] && e.Healthy() {
+			candidates = append(candidates, e)
+		}
+	}
+	if len(candidates) == 0 {
+		for _, e := range endpoints {
+			if !tried[e.Addr] {
+				candidates = append(candidates, e)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	if p.cfg.LoadBalance == LOAD_BALANCE_LEAST_CONNECTIONS {
+		picked := candidates[0]
+		for _, e := range candidates[1:] {
+			if atomic.LoadInt32(&e.inflight) < atomic.LoadInt32(&picked.inflight) {
+				picked = e
+			}
+		}
+		return picked
+	}
+	return candidates[int(atomic.AddUint32(&p.next, 1))%len(candidates)]
+}
+
+func (p *EndpointPool) check() {
+	if p.cfg.Cluster != "" {
+		if err := p.discover(); err != nil {
+			log.Warningf("discover clickhouse replicas of cluster %s failed: %s", p.cfg.Cluster, err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(p.cfg.ConnectTimeout)*time.Second)
+	defer cancel()
+	for _, e := range p.Endpoints() {
+		conn, err := getConnection(e.Addr)
+		if err == nil {
+			err = conn.Ping(ctx)
+		}
+		if err != nil {
+			e.setHealthy(false, err)
+		} else if !e.Healthy() {
+			log.Infof("clickhouse endpoint %s is healthy again", e.Addr)
+			e.setHealthy(true, nil)
+		}
+	}
+}
+
+func (p *EndpointPool) discover() error {
+	e := p.Pick(nil)
+	if e == nil {
+		return errors.New("no clickhouse endpoint")
+	}
+	conn, err := getConnection(e.Addr)
+	if err != nil {
+		return err
+	}
+	rows, err := conn.Query(context.Background(),
+		"SELECT host_address, port FROM system.clusters WHERE cluster = ?", p.cfg.Cluster)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var addrs []string
+	for rows.Next() {
+		var host string
+		var port uint16
+		if err := rows.Scan(&host, &port); err != nil {
+			return err
+		}
+		addrs = append(addrs, fmt.Sprintf("%s:%d", host, port))
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("cluster %s not found in system.clusters", p.cfg.Cluster)
+	}
+	for _, addr := range p.updateDiscovered(addrs) {
+		log.Infof("clickhouse endpoint %s is removed from cluster %s", addr, p.cfg.Cluster)
+		closeConnection(addr)
+	}
+	return nil
+}
+
+// updateDiscovered replaces the discovered endpoints with addrs, configured
+// endpoints and the statistics of still existing ones are kept, returns the
+// addresses of the discovered endpoints which are dropped.
+func (p *EndpointPool) updateDiscovered(addrs []string) []string {
+	sort.Strings(addrs)
+	p.Lock()
+	defer p.Unlock()
+	existing := make(map[string]*Endpoint, len(p.endpoints))
+	var endpoints []*Endpoint
+	for _, e := range p.endpoints {
+		existing[e.Addr] = e
+		if !e.discovered {
+			endpoints = append(endpoints, e)
+		}
+	}
+	kept := make(map[string]bool, len(addrs))
+	for _, addr := range addrs {
+		if e, ok := existing[addr]; ok {
+			if e.discovered && !kept[addr] {
+				kept[addr] = true
+				endpoints = append(endpoints, e)
+			}
+			continue
+		}
+		log.Infof("discover clickhouse endpoint %s of cluster %s", addr, p.cfg.Cluster)
+		e := newEndpoint(addr, true)
+		existing[addr] = e
+		kept[addr] = true
+		endpoints = append(endpoints, e)
+	}
+	var removed []string
+	for _, e := range p.endpoints {
+		if e.discovered && !kept[e.Addr] {
+			removed = append(removed, e.Addr)
+		}
+	}
+	p.endpoints = endpoints
+	return removed
+}
+
+// EndpointStats returns the statistics of all ClickHouse endpoints
+func EndpointStats() []map[string]interface{} {
+	endpoints := getEndpointPool().Endpoints()
+	stats := make([]map[string]interface{}, 0, len(endpoints))
+	for _, e := range endpoints {
+		stats = append(stats, e.Stats())
+	}
+	return stats
+}
+
+// isRetryable returns whether a failed read can be retried on another replica,
+// only network and connection errors are retried and mark the replica unhealthy,
+// exceptions raised by ClickHouse itself (syntax, limits ...) and cancellation are not.
+func isRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var exception *clickhouse.Exception
+	if errors.As(err, &exception) {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, clickhouse.ErrAcquireConnTimeout) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
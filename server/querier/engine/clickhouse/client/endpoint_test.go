@@ -0,0 +1,155 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"syscall"
+	"testing"
+
+	clickhouse "github.com/ClickHouse/clickhouse-go/v2"
+
+	"github.com/deepflowio/deepflow/server/querier/config"
+)
+
+func endpointAddrs(endpoints []*Endpoint) []string {
+	addrs := make([]string, 0, len(endpoints))
+	for _, e := range endpoints {
+		addrs = append(addrs, e.Addr)
+	}
+	return addrs
+}
+
+func TestEndpointPoolPick(t *testing.T) {
+	p := newEndpointPool(&config.Clickhouse{Endpoints: []string{"a:9000", "b:9000", "c:9000"}})
+
+	// round-robin 轮流选择
+	picked := map[string]int{}
+	for i := 0; i < 6; i++ {
+		picked[p.Pick(nil).Addr]++
+	}
+	if !reflect.DeepEqual(picked, map[string]int{"a:9000": 2, "b:9000": 2, "c:9000": 2}) {
+		t.Fatalf("unexpected round-robin result %v", picked)
+	}
+
+	// 跳过已尝试和不健康的 endpoint
+	p.endpoints[1].setHealthy(false, errors.New("down"))
+	for i := 0; i < 3; i++ {
+		if e := p.Pick(map[string]bool{"a:9000": true}); e.Addr != "c:9000" {
+			t.Fatalf("Expected c:9000 found %s", e.Addr)
+		}
+	}
+	// 全部不健康时仍然尝试未尝试过的
+	if e := p.Pick(map[string]bool{"a:9000": true, "c:9000": true}); e == nil || e.Addr != "b:9000" {
+		t.Fatalf("Expected b:9000 found %v", e)
+	}
+	if e := p.Pick(map[string]bool{"a:9000": true, "b:9000": true, "c:9000": true}); e != nil {
+		t.Fatalf("Expected nil found %s", e.Addr)
+	}
+
+	p.cfg.LoadBalance = LOAD_BALANCE_LEAST_CONNECTIONS
+	p.endpoints[1].setHealthy(true, nil)
+	p.endpoints[0].begin()
+	p.endpoints[0].begin()
+	p.endpoints[1].begin()
+	if e := p.Pick(nil); e.Addr != "c:9000" {
+		t.Fatalf("Expected c:9000 found %s", e.Addr)
+	}
+	p.endpoints[2].begin()
+	p.endpoints[2].begin()
+	if e := p.Pick(nil); e.Addr != "b:9000" {
+		t.Fatalf("Expected b:9000 found %s", e.Addr)
+	}
+}
+
+func TestEndpointPoolPickDefaultAddr(t *testing.T) {
+	p := newEndpointPool(&config.Clickhouse{Host: "clickhouse", Port: 9000})
+	if e := p.Pick(nil); e == nil || e.Addr != "clickhouse:9000" || e.discovered {
+		t.Fatalf("Expected configured clickhouse:9000 found %v", e)
+	}
+}
+
+func TestEndpointPoolUpdateDiscovered(t *testing.T) {
+	p := newEndpointPool(&config.Clickhouse{Endpoints: []string{"a:9000"}, Cluster: "default"})
+
+	removed := p.updateDiscovered([]string{"c:9000", "b:9000", "a:9000"})
+	if len(removed) != 0 {
+		t.Fatalf("Expected nothing removed found %v", removed)
+	}
+	// 配置的 endpoint 不会被当作发现的重复加入
+	if addrs := endpointAddrs(p.Endpoints()); !reflect.DeepEqual(addrs, []string{"a:9000", "b:9000", "c:9000"}) {
+		t.Fatalf("unexpected endpoints %v", addrs)
+	}
+	if p.endpoints[0].discovered || !p.endpoints[1].discovered || !p.endpoints[2].discovered {
+		t.Fatalf("unexpected discovered flags")
+	}
+	b := p.endpoints[1]
+	b.end(b.begin(), nil)
+
+	// c 被移出集群, 已有 endpoint 的统计保留
+	removed = p.updateDiscovered([]string{"b:9000", "d:9000", "b:9000"})
+	if !reflect.DeepEqual(removed, []string{"c:9000"}) {
+		t.Fatalf("Expected [c:9000] removed found %v", removed)
+	}
+	if addrs := endpointAddrs(p.Endpoints()); !reflect.DeepEqual(addrs, []string{"a:9000", "b:9000", "d:9000"}) {
+		t.Fatalf("unexpected endpoints %v", addrs)
+	}
+	if p.endpoints[1] != b || b.Stats()["queries"] != uint64(1) {
+		t.Fatalf("statistics of b:9000 not kept")
+	}
+
+	// 配置的 endpoint 不会被移除
+	removed = p.updateDiscovered([]string{"d:9000"})
+	if !reflect.DeepEqual(removed, []string{"b:9000"}) {
+		t.Fatalf("Expected [b:9000] removed found %v", removed)
+	}
+	if addrs := endpointAddrs(p.Endpoints()); !reflect.DeepEqual(addrs, []string{"a:9000", "d:9000"}) {
+		t.Fatalf("unexpected endpoints %v", addrs)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		err       error
+		retryable bool
+	}{
+		{nil, false},
+		{context.Canceled, false},
+		{fmt.Errorf("read: %w", context.DeadlineExceeded), false},
+		{&clickhouse.Exception{Code: 62, Message: "Syntax error"}, false},
+		{errors.New("unknown"), false},
+		{io.EOF, true},
+		{fmt.Errorf("read: %w", io.ErrUnexpectedEOF), true},
+		{driver.ErrBadConn, true},
+		{clickhouse.ErrAcquireConnTimeout, true},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, true},
+		{fmt.Errorf("write: %w", syscall.EPIPE), true},
+		{syscall.ECONNRESET, true},
+		{&net.DNSError{Err: "no such host", Name: "clickhouse"}, true},
+	}
+	for _, c := range cases {
+		if retryable := isRetryable(c.err); retryable != c.retryable {
+			t.Errorf("isRetryable(%v) Expected %v found %v", c.err, c.retryable, retryable)
+		}
+	}
+}
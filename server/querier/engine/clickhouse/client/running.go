@@ -0,0 +1,210 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	clickhouse "github.com/ClickHouse/clickhouse-go/v2"
+)
+
+var ErrQueryNotFound = errors.New("query not found")
+
+type ctxKeyRunningQuery struct{}
+
+// RunningQuery is a querier request in progress, one request may issue several
+// ClickHouse queries, each of them uses `<query_uuid>-<seq>` as its query_id.
+type RunningQuery struct {
+	QueryUUID string
+	DB        string
+	Sql       string
+	StartTime time.Time
+
+	cancel      context.CancelFunc
+	rowsRead    uint64
+	bytesRead   uint64
+	rowsToRead  uint64
+	chQueries   map[string]string // clickhouse query_id -> endpoint
+	chQuerySeq  int
+	chQueryLock sync.Mutex
+}
+
+func (q *RunningQuery) OnProgress(p *clickhouse.Progress) {
+	atomic.AddUint64(&q.rowsRead, p.Rows)
+	atomic.AddUint64(&q.bytesRead, p.Bytes)
+	atomic.AddUint64(&q.rowsToRead, p.TotalRows)
+}
+
+func (q *RunningQuery) addClickhouseQuery(endpoint string) string {
+	q.chQueryLock.Lock()
+	defer q.chQueryLock.Unlock()
+	q.chQuerySeq++
+	queryID := fmt.Sprintf("%s-%d", q.QueryUUID, q.chQuerySeq)
+	q.chQueries[queryID] = endpoint
+	return queryID
+}
+
+func (q *RunningQuery) removeClickhouseQuery(queryID string) {
+	q.chQueryLock.Lock()
+	delete(q.chQueries, queryID)
+	q.chQueryLock.Unlock()
+}
+
+func (q *RunningQuery) clickhouseQueries() map[string][]string {
+	q.chQueryLock.Lock()
+	defer q.chQueryLock.Unlock()
+	endpointQueries := make(map[string][]string)
+	for queryID, endpoint := range q.chQueries {
+		endpointQueries[endpoint] = append(endpointQueries[endpoint], queryID)
+	}
+	return endpointQueries
+}
+
+func (q *RunningQuery) Progress() map[string]interface{} {
+	endpointQueries := q.clickhouseQueries()
+	chQueries := []map[string]interface{}{}
+	for endpoint, queryIDs := range endpointQueries {
+		for _, queryID := range queryIDs {
+			chQueries = append(chQueries, map[string]interface{}{"query_id": queryID, "endpoint": endpoint})
+		}
+	}
+	return map[string]interface{}{
+		"query_uuid":         q.QueryUUID,
+		"db":                 q.DB,
+		"sql":                q.Sql,
+		"start_time":         q.StartTime.Unix(),
+		"elapsed":            time.Since(q.StartTime).Seconds(),
+		"rows_read":          atomic.LoadUint64(&q.rowsRead),
+		"bytes_read":         atomic.LoadUint64(&q.bytesRead),
+		"total_rows_to_read": atomic.LoadUint64(&q.rowsToRead),
+		"clickhouse_queries": chQueries,
+	}
+}
+
+func RunningQueryFromContext(ctx context.Context) *RunningQuery {
+	if ctx == nil {
+		return nil
+	}
+	q, _ := ctx.Value(ctxKeyRunningQuery{}).(*RunningQuery)
+	return q
+}
+
+type runningQueries struct {
+	sync.RWMutex
+	queries map[string]*RunningQuery
+}
+
+var RunningQueries = &runningQueries{queries: make(map[string]*RunningQuery)}
+
+// Register makes the query cancelable by its uuid until done is called.
+// Nested registrations of the same query share the outermost one.
+func (r *runningQueries) Register(ctx context.Context, queryUUID, db, sql string) (context.Context, func()) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if RunningQueryFromContext(ctx) != nil || queryUUID == "" {
+		return ctx, func() {}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	q := &RunningQuery{
+		QueryUUID: queryUUID,
+		DB:        db,
+		Sql:       sql,
+		StartTime: time.Now(),
+		cancel:    cancel,
+		chQueries: make(map[string]string),
+	}
+	r.Lock()
+	if _, ok := r.queries[queryUUID]; ok {
+		r.Unlock()
+		log.Warningf("query_uuid %s is already running, it can not be canceled by uuid", queryUUID)
+		return ctx, cancel
+	}
+	r.queries[queryUUID] = q
+	r.Unlock()
+	return context.WithValue(ctx, ctxKeyRunningQuery{}, q), func() {
+		r.Lock()
+		delete(r.queries, queryUUID)
+		r.Unlock()
+		cancel()
+	}
+}
+
+func (r *runningQueries) Get(queryUUID string) *RunningQuery {
+	r.RLock()
+	defer r.RUnlock()
+	return r.queries[queryUUID]
+}
+
+func (r *runningQueries) List() []map[string]interface{} {
+	r.RLock()
+	queries := make([]*RunningQuery, 0, len(r.queries))
+	for _, q := range r.queries {
+		queries = append(queries, q)
+	}
+	r.RUnlock()
+	list := make([]map[string]interface{}, 0, len(queries))
+	for _, q := range queries {
+		list = append(list, q.Progress())
+	}
+	return list
+}
+
+// Kill cancels the query context and kills its ClickHouse queries on the
+// endpoints they were sent to.
+func (r *runningQueries) Kill(queryUUID string) error {
+	q := r.Get(queryUUID)
+	if q == nil {
+		return ErrQueryNotFound
+	}
+	endpointQueries := q.clickhouseQueries()
+	q.cancel()
+	var errs []string
+	for endpoint, queryIDs := range endpointQueries {
+		if err := killClickhouseQueries(endpoint, queryIDs); err != nil {
+			log.Errorf("kill query_uuid: %s on %s failed: %s", queryUUID, endpoint, err)
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, ", "))
+	}
+	log.Infof("query_uuid: %s killed", queryUUID)
+	return nil
+}
+
+func killClickhouseQueries(endpoint string, queryIDs []string) error {
+	conn, err := getConnection(endpoint)
+	if err != nil {
+		return err
+	}
+	return conn.Exec(context.Background(), killQuerySql(queryIDs))
+}
+
+func killQuerySql(queryIDs []string) string {
+	ids := make([]string, 0, len(queryIDs))
+	for _, id := range queryIDs {
+		ids = append(ids, fmt.Sprintf("'%s'", strings.ReplaceAll(id, "'", "")))
+	}
+	return fmt.Sprintf("KILL QUERY WHERE query_id IN (%s) ASYNC", strings.Join(ids, ","))
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"sort"
+	"testing"
+)
+
+func TestRunningQueryRegister(t *testing.T) {
+	r := &runningQueries{queries: make(map[string]*RunningQuery)}
+
+	ctx, done := r.Register(context.Background(), "", "flow_log", "SELECT 1")
+	done()
+	if RunningQueryFromContext(ctx) != nil || len(r.queries) != 0 {
+		t.Fatalf("query without uuid should not be registered")
+	}
+
+	ctx, done = r.Register(context.Background(), "q1", "flow_log", "SELECT 1")
+	q := RunningQueryFromContext(ctx)
+	if q == nil || r.Get("q1") != q {
+		t.Fatalf("query q1 not registered")
+	}
+	// 嵌套注册复用最外层的 RunningQuery
+	nestedCtx, nestedDone := r.Register(ctx, "q2", "flow_log", "SELECT 2")
+	nestedDone()
+	if RunningQueryFromContext(nestedCtx) != q || r.Get("q2") != nil {
+		t.Fatalf("nested query should share the outermost one")
+	}
+	// 重复的 uuid 不覆盖已注册的查询
+	dupCtx, dupDone := r.Register(context.Background(), "q1", "flow_log", "SELECT 3")
+	if RunningQueryFromContext(dupCtx) != nil || r.Get("q1") != q {
+		t.Fatalf("duplicate query_uuid should not replace the running one")
+	}
+	dupDone()
+	if r.Get("q1") != q {
+		t.Fatalf("done of the duplicate query removed the running one")
+	}
+
+	done()
+	if r.Get("q1") != nil {
+		t.Fatalf("query q1 not removed after done")
+	}
+	if ctx.Err() != context.Canceled {
+		t.Fatalf("Expected context canceled found %v", ctx.Err())
+	}
+}
+
+func TestRunningQueryClickhouseQueries(t *testing.T) {
+	r := &runningQueries{queries: make(map[string]*RunningQuery)}
+	ctx, done := r.Register(context.Background(), "q1", "flow_log", "SELECT 1")
+	defer done()
+	q := RunningQueryFromContext(ctx)
+
+	id1 := q.addClickhouseQuery("10.0.0.1:9000")
+	id2 := q.addClickhouseQuery("10.0.0.2:9000")
+	id3 := q.addClickhouseQuery("10.0.0.1:9000")
+	if id1 != "q1-1" || id2 != "q1-2" || id3 != "q1-3" {
+		t.Fatalf("Expected q1-1 q1-2 q1-3 found %s %s %s", id1, id2, id3)
+	}
+	queries := q.clickhouseQueries()
+	sort.Strings(queries["10.0.0.1:9000"])
+	if len(queries) != 2 || len(queries["10.0.0.1:9000"]) != 2 || queries["10.0.0.1:9000"][1] != "q1-3" ||
+		len(queries["10.0.0.2:9000"]) != 1 {
+		t.Fatalf("unexpected clickhouse queries %v", queries)
+	}
+
+	q.removeClickhouseQuery(id1)
+	q.removeClickhouseQuery(id2)
+	queries = q.clickhouseQueries()
+	if len(queries) != 1 || len(queries["10.0.0.1:9000"]) != 1 || queries["10.0.0.1:9000"][0] != "q1-3" {
+		t.Fatalf("unexpected clickhouse queries after remove %v", queries)
+	}
+	// 序号不因删除而复用
+	if id := q.addClickhouseQuery("10.0.0.2:9000"); id != "q1-4" {
+		t.Fatalf("Expected q1-4 found %s", id)
+	}
+}
+
+func TestRunningQueryKill(t *testing.T) {
+	r := &runningQueries{queries: make(map[string]*RunningQuery)}
+	if err := r.Kill("not-exist"); err != ErrQueryNotFound {
+		t.Fatalf("Expected %v found %v", ErrQueryNotFound, err)
+	}
+
+	// 未下发 ClickHouse 查询时只取消 context
+	ctx, done := r.Register(context.Background(), "q1", "flow_log", "SELECT 1")
+	defer done()
+	if err := r.Kill("q1"); err != nil {
+		t.Fatalf("Expected nil found %v", err)
+	}
+	if ctx.Err() != context.Canceled {
+		t.Fatalf("Expected context canceled found %v", ctx.Err())
+	}
+}
+
+func TestKillQuerySql(t *testing.T) {
+	cases := []struct {
+		queryIDs []string
+		expected string
+	}{
+		{[]string{"q1-1"}, "KILL QUERY WHERE query_id IN ('q1-1') ASYNC"},
+		{[]string{"q1-1", "q1-2"}, "KILL QUERY WHERE query_id IN ('q1-1','q1-2') ASYNC"},
+		{[]string{"q1') OR 1=1 --"}, "KILL QUERY WHERE query_id IN ('q1) OR 1=1 --') ASYNC"},
+	}
+	for _, c := range cases {
+		if sql := killQuerySql(c.queryIDs); sql != c.expected {
+			t.Errorf("Expected %s found %s", c.expected, sql)
+		}
+	}
+}
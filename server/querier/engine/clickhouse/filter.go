@@ -36,9 +36,10 @@ import (
 )
 
 type Where struct {
-	filter *view.Filters
-	withs  []view.Node
-	time   *view.Time
+	filter  *view.Filters
+	withs   []view.Node
+	time    *view.Time
+	history bool // 资源名称和K8s标签按数据行的时间过滤
 }
 
 func (w *Where) Format(m *view.Model) {
@@ -140,7 +141,8 @@ type WhereTag struct {
 
 func (t *WhereTag) Trans(expr sqlparser.Expr, w *Where, asTagMap map[string]string, db, table string) (view.Node, error) {
 	op := expr.(*sqlparser.ComparisonExpr).Operator
-	tagItem, ok := tag.GetTag(strings.Trim(t.Tag, "`"), db, table, "default")
+	tagFunction := tag.GetTagFunction(w.history)
+	tagItem, ok := tag.GetTag(strings.Trim(t.Tag, "`"), db, table, tagFunction)
 	whereTag := t.Tag
 	if strings.ToLower(op) == "like" || strings.ToLower(op) == "not like" {
 		t.Value = strings.ReplaceAll(t.Value, "*", "%")
@@ -161,7 +163,7 @@ func (t *WhereTag) Trans(expr sqlparser.Expr, w *Where, asTagMap map[string]stri
 		preAsTag, ok := asTagMap[t.Tag]
 		if ok {
 			whereTag = preAsTag
-			tagItem, ok = tag.GetTag(strings.Trim(preAsTag, "`"), db, table, "default")
+			tagItem, ok = tag.GetTag(strings.Trim(preAsTag, "`"), db, table, tagFunction)
 			if !ok {
 				switch preAsTag {
 				case "mac_0", "mac_1", "tunnel_tx_mac_0", "tunnel_tx_mac_1", "tunnel_rx_mac_0", "tunnel_rx_mac_1":
@@ -225,11 +227,11 @@ func (t *WhereTag) Trans(expr sqlparser.Expr, w *Where, asTagMap map[string]stri
 					preAsTag = strings.Trim(preAsTag, "`")
 					if strings.HasPrefix(preAsTag, "k8s.label.") {
 						if strings.HasSuffix(preAsTag, "_0") {
-							tagItem, ok = tag.GetTag("k8s_label_0", db, table, "default")
+							tagItem, ok = tag.GetTag("k8s_label_0", db, table, tagFunction)
 						} else if strings.HasSuffix(preAsTag, "_1") {
-							tagItem, ok = tag.GetTag("k8s_label_1", db, table, "default")
+							tagItem, ok = tag.GetTag("k8s_label_1", db, table, tagFunction)
 						} else {
-							tagItem, ok = tag.GetTag("k8s_label", db, table, "default")
+							tagItem, ok = tag.GetTag("k8s_label", db, table, tagFunction)
 						}
 						if ok {
 							nameNoSuffix := strings.TrimSuffix(preAsTag, "_0")
@@ -414,11 +416,11 @@ func (t *WhereTag) Trans(expr sqlparser.Expr, w *Where, asTagMap map[string]stri
 				tagName := strings.Trim(t.Tag, "`")
 				if strings.HasPrefix(tagName, "k8s.label.") {
 					if strings.HasSuffix(tagName, "_0") {
-						tagItem, ok = tag.GetTag("k8s_label_0", db, table, "default")
+						tagItem, ok = tag.GetTag("k8s_label_0", db, table, tagFunction)
 					} else if strings.HasSuffix(tagName, "_1") {
-						tagItem, ok = tag.GetTag("k8s_label_1", db, table, "default")
+						tagItem, ok = tag.GetTag("k8s_label_1", db, table, tagFunction)
 					} else {
-						tagItem, ok = tag.GetTag("k8s_label", db, table, "default")
+						tagItem, ok = tag.GetTag("k8s_label", db, table, tagFunction)
 					}
 					if ok {
 						nameNoSuffix := strings.TrimSuffix(tagName, "_0")
@@ -723,7 +725,7 @@ func (t *WhereTag) Trans(expr sqlparser.Expr, w *Where, asTagMap map[string]stri
 				whereFilter = fmt.Sprintf(tagItem.WhereTranslator, op, t.Value)
 			}
 		case "pod_ingress_id", "pod_ingress_id_0", "pod_ingress_id_1", "pod_ingress", "pod_ingress_0", "pod_ingress_1",
-			"pod_service", "pod_service_0", "pod_service_1":
+			"pod_ingress_type", "pod_ingress_type_0", "pod_ingress_type_1", "pod_service", "pod_service_0", "pod_service_1":
 			switch strings.ToLower(op) {
 			case "not match":
 				whereFilter = "not(" + fmt.Sprintf(tagItem.WhereRegexpTranslator, "match", t.Value, "match", t.Value) + ")"
@@ -883,14 +885,18 @@ func (f *WhereFunction) Trans(expr sqlparser.Expr, w *Where, asTagMap map[string
 				}
 			}
 			enumFileName := strings.TrimSuffix(tagDescription.EnumFile, "."+config.Cfg.Language)
+			tagColumn := tagName
+			if enumColumn, ok := tag.TAG_ENUM_COLUMNS[tagName]; ok {
+				tagColumn = enumColumn
+			}
 			switch strings.ToLower(expr.(*sqlparser.ComparisonExpr).Operator) {
 			case "=":
 				//when enum function operator is '=' , add 'or tag = xxx'
 				if isIntEnum {
 					intValue, err := strconv.Atoi(strings.Trim(f.Value, "'"))
-					if err == nil {
+					if err == nil && tagColumn != "" {
 						// when value type is int, add toUInt64() function
-						whereFilter = fmt.Sprintf(tagItem.WhereTranslator, "=", f.Value, enumFileName) + " OR " + tagName + " = " + "toUInt64(" + strconv.Itoa(intValue) + ")"
+						whereFilter = fmt.Sprintf(tagItem.WhereTranslator, "=", f.Value, enumFileName) + " OR " + tagColumn + " = " + "toUInt64(" + strconv.Itoa(intValue) + ")"
 					} else {
 						whereFilter = fmt.Sprintf(tagItem.WhereTranslator, "=", f.Value, enumFileName)
 					}
@@ -901,9 +907,9 @@ func (f *WhereFunction) Trans(expr sqlparser.Expr, w *Where, asTagMap map[string
 				//when enum function operator is '!=', add 'and tag != xxx'
 				if isIntEnum {
 					intValue, err := strconv.Atoi(strings.Trim(f.Value, "'"))
-					if err == nil {
+					if err == nil && tagColumn != "" {
 						// when value type is int, add toUInt64() function
-						whereFilter = fmt.Sprintf(tagItem.WhereTranslator, opName, f.Value, enumFileName) + " AND " + tagName + " != " + "toUInt64(" + strconv.Itoa(intValue) + ")"
+						whereFilter = fmt.Sprintf(tagItem.WhereTranslator, opName, f.Value, enumFileName) + " AND " + tagColumn + " != " + "toUInt64(" + strconv.Itoa(intValue) + ")"
 					} else {
 						whereFilter = fmt.Sprintf(tagItem.WhereTranslator, opName, f.Value, enumFileName)
 					}
@@ -154,6 +154,8 @@ func GetMetricsByDBTableStatic(db string, table string, where string) (map[strin
 			return GetResourceEventMetrics(), err
 		case "perf_event":
 			return GetResourcePerfEventMetrics(), err
+		case "anomaly_event":
+			return GetAnomalyEventMetrics(), err
 		}
 	case ckcommon.DB_NAME_PROFILE:
 		switch table {
@@ -217,6 +219,8 @@ func GetMetricsByDBTable(db string, table string, where string, ctx context.Cont
 			return GetResourceEventMetrics(), err
 		case "perf_event":
 			return GetResourcePerfEventMetrics(), err
+		case "anomaly_event":
+			return GetAnomalyEventMetrics(), err
 		}
 	case ckcommon.DB_NAME_PROFILE:
 		switch table {
@@ -383,6 +387,9 @@ func MergeMetrics(db string, table string, loadMetrics map[string]*Metrics) erro
 		case "perf_event":
 			metrics = RESOURCE_PERF_EVENT_METRICS
 			replaceMetrics = RESOURCE_PERF_EVENT_METRICS_REPLACE
+		case "anomaly_event":
+			metrics = ANOMALY_EVENT_METRICS
+			replaceMetrics = ANOMALY_EVENT_METRICS_REPLACE
 		}
 	case ckcommon.DB_NAME_PROFILE:
 		switch table {
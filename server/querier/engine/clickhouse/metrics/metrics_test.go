@@ -88,6 +88,9 @@ func TestCheckDBField(t *testing.T) {
 					case "perf_event":
 						metrics = RESOURCE_PERF_EVENT_METRICS
 						replaceMetrics = RESOURCE_PERF_EVENT_METRICS_REPLACE
+					case "anomaly_event":
+						metrics = ANOMALY_EVENT_METRICS
+						replaceMetrics = ANOMALY_EVENT_METRICS_REPLACE
 					}
 				}
 				if metrics == nil {
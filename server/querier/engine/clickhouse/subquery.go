@@ -0,0 +1,431 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clickhouse
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/xwb1989/sqlparser"
+
+	"github.com/deepflowio/deepflow/server/querier/common"
+	chCommon "github.com/deepflowio/deepflow/server/querier/engine/clickhouse/common"
+	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse/view"
+	"github.com/deepflowio/deepflow/server/querier/parse"
+)
+
+/*
+子查询的翻译, 每个子查询使用独立的CHEngine翻译, 因此tag翻译分别作用于每个子查询:
+
+	WHERE tag IN (SELECT tag FROM table ...)
+	SELECT ... FROM table1 UNION ALL SELECT ... FROM table2
+	SELECT a.x, b.y FROM (SELECT ... FROM table1) AS a JOIN (SELECT ... FROM table2) AS b ON a.tag = b.tag
+
+子查询可以通过 db.table 查询其他数据库, flow_metrics的表可以通过 table.datasource 指定数据源,
+例如: flow_metrics.`vtap_flow_port.1m`
+*/
+
+var joinTypes = map[string]string{
+	sqlparser.JoinStr:      "INNER JOIN",
+	sqlparser.LeftJoinStr:  "LEFT JOIN",
+	sqlparser.RightJoinStr: "RIGHT JOIN",
+}
+
+func subSelect(stmt sqlparser.SelectStatement) (*sqlparser.Select, error) {
+	switch stmt := stmt.(type) {
+	case *sqlparser.Select:
+		return stmt, nil
+	case *sqlparser.ParenSelect:
+		return subSelect(stmt.Select)
+	}
+	return nil, errors.New(fmt.Sprintf("subquery: %s not support", sqlparser.String(stmt)))
+}
+
+// 解析子查询的From, 返回子查询的数据库和数据源, 并去掉表名中的数据库和数据源
+func (e *CHEngine) subSelectFrom(stmt *sqlparser.Select) (string, string, error) {
+	if len(stmt.From) != 1 {
+		return "", "", errors.New(fmt.Sprintf("subquery: %s should select from one table", sqlparser.String(stmt)))
+	}
+	from, ok := stmt.From[0].(*sqlparser.AliasedTableExpr)
+	if !ok {
+		return "", "", errors.New(fmt.Sprintf("subquery: %s should select from one table", sqlparser.String(stmt)))
+	}
+	tableName, ok := from.Expr.(sqlparser.TableName)
+	if !ok {
+		return "", "", errors.New(fmt.Sprintf("nested subquery: %s not support", sqlparser.String(from)))
+	}
+	db, dataSource := e.DB, e.DataSource
+	if qualifier := tableName.Qualifier.String(); qualifier != "" {
+		// ext_metrics的表名可能包含'.', 只有数据库存在时才作为数据库解析
+		if _, ok := chCommon.DB_TABLE_MAP[qualifier]; ok {
+			if qualifier != e.DB {
+				db, dataSource = qualifier, ""
+			}
+			tableName.Qualifier = sqlparser.NewTableIdent("")
+		}
+	}
+	if db == chCommon.DB_NAME_FLOW_METRICS {
+		if name := tableName.Name.String(); strings.Contains(name, ".") {
+			nameSplit := strings.SplitN(name, ".", 2)
+			tableName.Name = sqlparser.NewTableIdent(nameSplit[0])
+			dataSource = nameSplit[1]
+		}
+	}
+	from.Expr = tableName
+	from.As = sqlparser.NewTableIdent("")
+	return db, dataSource, nil
+}
+
+// TransSubSelect 使用独立的CHEngine翻译子查询, 子查询不使用默认的LIMIT
+func (e *CHEngine) TransSubSelect(stmt sqlparser.SelectStatement) (*CHEngine, error) {
+	pStmt, err := subSelect(stmt)
+	if err != nil {
+		return nil, err
+	}
+	db, dataSource, err := e.subSelectFrom(pStmt)
+	if err != nil {
+		return nil, err
+	}
+	subEngine := &CHEngine{DB: db, DataSource: dataSource, Context: e.Context, TagHistory: e.TagHistory && db != "flow_tag"}
+	subEngine.Init()
+	parser := parse.Parser{Engine: subEngine}
+	if err := parser.ParseSelect(pStmt); err != nil {
+		return nil, err
+	}
+	for _, stmt := range subEngine.Statements {
+		stmt.Format(subEngine.Model)
+	}
+	FormatInnerTime(subEngine.Model)
+	subEngine.View = view.NewView(subEngine.Model)
+	e.hasSubquery = e.hasSubquery || subEngine.hasSubquery
+	return subEngine, nil
+}
+
+// 翻译 tag IN (SELECT ...) 中的子查询, 作为过滤条件的值, tag由过滤条件正常翻译
+func (e *CHEngine) transWhereSubquery(op string, subquery *sqlparser.Subquery) (string, error) {
+	if op != sqlparser.InStr && op != sqlparser.NotInStr {
+		return "", errors.New(fmt.Sprintf("operator '%s' not support subquery, use 'in' or 'not in'", op))
+	}
+	if sql, ok := e.whereSubqueries[subquery]; ok {
+		return sql, nil
+	}
+	pStmt, err := subSelect(subquery.Select)
+	if err != nil {
+		return "", err
+	}
+	if len(pStmt.SelectExprs) != 1 {
+		return "", errors.New(fmt.Sprintf("subquery: %s should select exactly one column", sqlparser.String(pStmt)))
+	}
+	subEngine, err := e.TransSubSelect(pStmt)
+	if err != nil {
+		return "", err
+	}
+	e.hasSubquery = true
+	if e.whereSubqueries == nil {
+		e.whereSubqueries = map[*sqlparser.Subquery]string{}
+	}
+	e.whereSubqueries[subquery] = "(" + subEngine.toSQLString() + ")"
+	return e.whereSubqueries[subquery], nil
+}
+
+// union和join的外层只能引用子查询的列, 不能使用函数和子查询
+func checkOuterExpr(node sqlparser.SQLNode, tables map[string]*CHEngine) error {
+	return sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		switch node := node.(type) {
+		case *sqlparser.ColName:
+			if qualifier := node.Qualifier.Name.String(); qualifier != "" {
+				if _, ok := tables[qualifier]; !ok {
+					return false, errors.New(fmt.Sprintf("unknown table '%s' of column %s", qualifier, sqlparser.String(node)))
+				}
+			}
+		case *sqlparser.FuncExpr, *sqlparser.GroupConcatExpr, *sqlparser.Subquery, *sqlparser.CaseExpr,
+			*sqlparser.ConvertExpr, *sqlparser.ConvertUsingExpr, *sqlparser.SubstrExpr, *sqlparser.MatchExpr,
+			*sqlparser.IntervalExpr, *sqlparser.ValuesFuncExpr:
+			return false, errors.New(fmt.Sprintf("%s not support outside subquery, use it in subquery", sqlparser.String(node)))
+		}
+		return true, nil
+	}, node)
+}
+
+// 子查询的callback按列名处理查询结果, 只有外层结果中保留了同名的列时才合并到外层, 否则返回错误
+func (e *CHEngine) mergeCallbacks(subEngine *CHEngine, keep func(column string) bool) error {
+	for column, callback := range subEngine.View.GetCallbacks() {
+		if column == "time" {
+			return errors.New("time fill not support in union or join, use it without union or join")
+		}
+		if !keep(column) {
+			return errors.New(fmt.Sprintf("%s is translated after query, select it as `%s` in union or join", column, column))
+		}
+		e.Model.AddCallback(column, callback)
+	}
+	return nil
+}
+
+func orderString(orders sqlparser.OrderBy) string {
+	if len(orders) == 0 {
+		return ""
+	}
+	orderSlice := []string{}
+	for _, order := range orders {
+		orderSlice = append(orderSlice, sqlparser.String(order.Expr)+" "+order.Direction)
+	}
+	return " ORDER BY " + strings.Join(orderSlice, ", ")
+}
+
+func columnSchema(engine *CHEngine, name string) *common.ColumnSchema {
+	for _, schema := range engine.ColumnSchemas {
+		if schema.Name == name {
+			return schema
+		}
+	}
+	return nil
+}
+
+// TransUnion 翻译 UNION ALL, 每个select分别翻译, 结果的列使用第一个select的列
+func (e *CHEngine) TransUnion(union *sqlparser.Union) error {
+	if union.Type != sqlparser.UnionAllStr {
+		return errors.New(fmt.Sprintf("%s not support, use union all", union.Type))
+	}
+	left, err := e.transUnionSelects(union.Left)
+	if err != nil {
+		return err
+	}
+	right, err := e.transUnionSelects(union.Right)
+	if err != nil {
+		return err
+	}
+	if err := checkOuterExpr(union.OrderBy, nil); err != nil {
+		return err
+	}
+	selects := append(left, right...)
+	e.compositeSql = "SELECT * FROM (" + strings.Join(selects, " UNION ALL ") + ")" + orderString(union.OrderBy)
+	if union.Limit != nil {
+		return e.TransLimit(union.Limit)
+	}
+	return nil
+}
+
+func (e *CHEngine) transUnionSelects(stmt sqlparser.SelectStatement) ([]string, error) {
+	union, ok := stmt.(*sqlparser.Union)
+	if !ok {
+		subEngine, err := e.TransSubSelect(stmt)
+		if err != nil {
+			return nil, err
+		}
+		if e.ColumnSchemas == nil {
+			e.ColumnSchemas = subEngine.ColumnSchemas
+		}
+		// union的结果使用第一个select的列名, 相同位置的列名一致时才能处理
+		err = e.mergeCallbacks(subEngine, func(column string) bool {
+			for i, schema := range subEngine.ColumnSchemas {
+				if schema.Name == column {
+					return i < len(e.ColumnSchemas) && e.ColumnSchemas[i].Name == column
+				}
+			}
+			return false
+		})
+		if err != nil {
+			return nil, err
+		}
+		return []string{"(" + subEngine.toSQLString() + ")"}, nil
+	}
+	if union.Type != sqlparser.UnionAllStr {
+		return nil, errors.New(fmt.Sprintf("%s not support, use union all", union.Type))
+	}
+	// 只有最外层的union可以使用order by和limit
+	if len(union.OrderBy) > 0 || union.Limit != nil {
+		return nil, errors.New(fmt.Sprintf("order by or limit of union: %s not support, use them in select", sqlparser.String(union)))
+	}
+	left, err := e.transUnionSelects(union.Left)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.transUnionSelects(union.Right)
+	if err != nil {
+		return nil, err
+	}
+	return append(left, right...), nil
+}
+
+// TransJoin 翻译 join 或 From 为子查询的select, 子查询分别翻译,
+// 外层select/where/order by只能引用子查询的列, join只支持等值条件, 例如 a.pod_service_1 = b.pod_service
+func (e *CHEngine) TransJoin(stmt *sqlparser.Select) error {
+	if len(stmt.From) != 1 {
+		return errors.New(fmt.Sprintf("from: %s not support, use join", sqlparser.String(stmt.From)))
+	}
+	if len(stmt.GroupBy) > 0 || stmt.Having != nil {
+		return errors.New("group by and having not support with join, use them in subquery")
+	}
+	tables := map[string]*CHEngine{}
+	from, err := e.transJoinTable(stmt.From[0], tables)
+	if err != nil {
+		return err
+	}
+	selects := []string{}
+	// 外层按原列名选择的子查询列
+	selected := map[*CHEngine]map[string]bool{}
+	for _, expr := range stmt.SelectExprs {
+		if err := checkOuterExpr(expr, tables); err != nil {
+			return err
+		}
+		switch expr := expr.(type) {
+		case *sqlparser.StarExpr:
+			selects = append(selects, sqlparser.String(expr))
+		case *sqlparser.AliasedExpr:
+			as := expr.As.String()
+			var schema *common.ColumnSchema
+			if colName, ok := expr.Expr.(*sqlparser.ColName); ok {
+				name := colName.Name.String()
+				if as == "" {
+					as = name
+				}
+				var table *CHEngine
+				if qualifier := colName.Qualifier.Name.String(); qualifier != "" {
+					table = tables[qualifier]
+					schema = columnSchema(table, name)
+				} else {
+					for _, table = range tables {
+						if schema = columnSchema(table, name); schema != nil {
+							break
+						}
+					}
+				}
+				if schema != nil && as == name {
+					if selected[table] == nil {
+						selected[table] = map[string]bool{}
+					}
+					selected[table][name] = true
+				}
+			}
+			if as == "" {
+				as = strings.ReplaceAll(sqlparser.String(expr.Expr), "`", "")
+			}
+			if schema != nil {
+				outSchema := *schema
+				outSchema.Name = as
+				e.ColumnSchemas = append(e.ColumnSchemas, &outSchema)
+			} else {
+				e.ColumnSchemas = append(e.ColumnSchemas, common.NewColumnSchema(as, "", ""))
+			}
+			selects = append(selects, fmt.Sprintf("%s AS `%s`", sqlparser.String(expr.Expr), as))
+		}
+	}
+	for _, table := range tables {
+		if err := e.mergeCallbacks(table, func(column string) bool { return selected[table][column] }); err != nil {
+			return err
+		}
+	}
+	if err := checkOuterExpr(stmt.Where, tables); err != nil {
+		return err
+	}
+	if err := checkOuterExpr(stmt.OrderBy, tables); err != nil {
+		return err
+	}
+	e.compositeSql = "SELECT " + strings.Join(selects, ", ") + " FROM " + from
+	if stmt.Where != nil {
+		e.compositeSql += " WHERE " + sqlparser.String(stmt.Where.Expr)
+	}
+	e.compositeSql += orderString(stmt.OrderBy)
+	if stmt.Limit != nil {
+		return e.TransLimit(stmt.Limit)
+	}
+	return nil
+}
+
+func (e *CHEngine) transJoinTable(node sqlparser.TableExpr, tables map[string]*CHEngine) (string, error) {
+	switch node := node.(type) {
+	case *sqlparser.AliasedTableExpr:
+		subquery, ok := node.Expr.(*sqlparser.Subquery)
+		if !ok {
+			return "", errors.New(fmt.Sprintf("table %s in join should be subquery, e.g. (SELECT ... FROM %s) AS t", sqlparser.String(node), sqlparser.String(node.Expr)))
+		}
+		alias := node.As.String()
+		if _, ok := tables[alias]; ok {
+			return "", errors.New(fmt.Sprintf("duplicate table alias '%s'", alias))
+		}
+		subEngine, err := e.TransSubSelect(subquery.Select)
+		if err != nil {
+			return "", err
+		}
+		tables[alias] = subEngine
+		return fmt.Sprintf("(%s) AS `%s`", subEngine.toSQLString(), alias), nil
+	case *sqlparser.ParenTableExpr:
+		if len(node.Exprs) != 1 {
+			return "", errors.New(fmt.Sprintf("from: %s not support, use join", sqlparser.String(node)))
+		}
+		return e.transJoinTable(node.Exprs[0], tables)
+	case *sqlparser.JoinTableExpr:
+		joinType, ok := joinTypes[node.Join]
+		if !ok {
+			return "", errors.New(fmt.Sprintf("%s not support", node.Join))
+		}
+		left, err := e.transJoinTable(node.LeftExpr, tables)
+		if err != nil {
+			return "", err
+		}
+		right, err := e.transJoinTable(node.RightExpr, tables)
+		if err != nil {
+			return "", err
+		}
+		if node.Condition.On == nil && len(node.Condition.Using) == 0 {
+			return "", errors.New(fmt.Sprintf("join: %s should have 'on' or 'using' condition", sqlparser.String(node)))
+		}
+		if node.Condition.On != nil {
+			if err := checkJoinCondition(node.Condition.On, tables); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s %s %s ON %s", left, joinType, right, sqlparser.String(node.Condition.On)), nil
+		}
+		return fmt.Sprintf("%s %s %s USING %s", left, joinType, right, sqlparser.String(node.Condition.Using)), nil
+	}
+	return "", errors.New(fmt.Sprintf("from: %s(%T) not support", sqlparser.String(node), node))
+}
+
+// join条件只支持两个子查询的列相等, 多个条件使用AND连接
+func checkJoinCondition(node sqlparser.Expr, tables map[string]*CHEngine) error {
+	switch node := node.(type) {
+	case *sqlparser.AndExpr:
+		if err := checkJoinCondition(node.Left, tables); err != nil {
+			return err
+		}
+		return checkJoinCondition(node.Right, tables)
+	case *sqlparser.ParenExpr:
+		return checkJoinCondition(node.Expr, tables)
+	case *sqlparser.ComparisonExpr:
+		left, leftOK := node.Left.(*sqlparser.ColName)
+		right, rightOK := node.Right.(*sqlparser.ColName)
+		if node.Operator != sqlparser.EqualStr || !leftOK || !rightOK {
+			break
+		}
+		leftTable, rightTable := left.Qualifier.Name.String(), right.Qualifier.Name.String()
+		if leftTable == "" || rightTable == "" || leftTable == rightTable {
+			break
+		}
+		for _, column := range []*sqlparser.ColName{left, right} {
+			table, ok := tables[column.Qualifier.Name.String()]
+			if !ok {
+				return errors.New(fmt.Sprintf("unknown table of column %s", sqlparser.String(column)))
+			}
+			if columnSchema(table, column.Name.String()) == nil {
+				return errors.New(fmt.Sprintf("column %s is not selected in subquery", sqlparser.String(column)))
+			}
+		}
+		return nil
+	}
+	return errors.New(fmt.Sprintf("join condition: %s not support, only equi-join like 'a.tag = b.tag' is supported", sqlparser.String(node)))
+}
@@ -30,23 +30,25 @@ import (
 	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse/view"
 )
 
-func GetTagTranslator(name, alias, db, table string) (Statement, string, error) {
+// history为true时资源名称和K8s标签按数据行的时间翻译
+func GetTagTranslator(name, alias, db, table string, history bool) (Statement, string, error) {
 	var stmt Statement
 	selectTag := name
 	if alias != "" {
 		selectTag = alias
 	}
 	labelType := ""
-	tagItem, ok := tag.GetTag(strings.Trim(name, "`"), db, table, "default")
+	tagFunction := tag.GetTagFunction(history)
+	tagItem, ok := tag.GetTag(strings.Trim(name, "`"), db, table, tagFunction)
 	if !ok {
 		name := strings.Trim(name, "`")
 		if strings.HasPrefix(name, "k8s.label.") {
 			if strings.HasSuffix(name, "_0") {
-				tagItem, ok = tag.GetTag("k8s_label_0", db, table, "default")
+				tagItem, ok = tag.GetTag("k8s_label_0", db, table, tagFunction)
 			} else if strings.HasSuffix(name, "_1") {
-				tagItem, ok = tag.GetTag("k8s_label_1", db, table, "default")
+				tagItem, ok = tag.GetTag("k8s_label_1", db, table, tagFunction)
 			} else {
-				tagItem, ok = tag.GetTag("k8s_label", db, table, "default")
+				tagItem, ok = tag.GetTag("k8s_label", db, table, tagFunction)
 			}
 			nameNoSuffix := strings.TrimSuffix(name, "_0")
 			nameNoSuffix = strings.TrimSuffix(nameNoSuffix, "_1")
@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tag
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 历史资源字典由tagrecorder根据ch_device_history和ch_pod_k8s_labels_history生成，
+// 按数据行的time查询当时的资源名称和标签，查不到时退回到当前的资源字典
+const (
+	// 所有数据表的时间列均为time，历史翻译只出现在直接查询数据表的一层SQL中
+	HISTORY_TIME_COLUMN = "time"
+
+	DEVICE_HISTORY_MAP         = "flow_tag.device_history_map"
+	POD_K8S_LABELS_HISTORY_MAP = "flow_tag.pod_k8s_labels_history_map"
+
+	// tag_history开启时优先使用的翻译，不存在时使用default
+	FUNCTION_HISTORY = "history"
+)
+
+// 名称保存在ch_device中的资源，对应的资源字典及其devicetype
+var HISTORY_RESOURCE_DEVICE_TYPE = map[string]int{
+	"pod_node":  VIF_DEVICE_TYPE_POD_NODE,
+	"pod":       VIF_DEVICE_TYPE_POD,
+	"pod_group": VIF_DEVICE_TYPE_POD_GROUP,
+	"gprocess":  VIF_DEVICE_TYPE_GPROCESS,
+}
+
+var (
+	// dictGet(flow_tag.device_map, 'name', (toUInt64(6),toUInt64(host_id)))
+	deviceNameRegexp = regexp.MustCompile(`dictGet\(flow_tag\.device_map, 'name', (\(toUInt64\([^()]+\),toUInt64\([^()]+\)\))\)`)
+	// dictGet(flow_tag.pod_map, 'name', (toUInt64(pod_id)))
+	resourceNameRegexp = regexp.MustCompile(`dictGet\(flow_tag\.(pod_node|pod_group|pod|gprocess)_map, 'name', \(toUInt64\(([^()]+)\)\)\)`)
+	// dictGetOrDefault(flow_tag.pod_k8s_labels_map, 'labels', toUInt64(pod_id),'{}')
+	podK8sLabelsRegexp = regexp.MustCompile(`dictGetOrDefault\(flow_tag\.pod_k8s_labels_map, 'labels', (toUInt64\([^()]+\)),'\{\}'\)`)
+	// dictGet(flow_tag.pod_k8s_label_map, 'value', (toUInt64(pod_id),'app'))
+	podK8sLabelRegexp = regexp.MustCompile(`dictGet\(flow_tag\.pod_k8s_label_map, 'value', \((toUInt64\([^()]+\)),'([^']*)'\)\)`)
+)
+
+// GetTagFunction 返回取tag默认翻译时使用的function
+func GetTagFunction(history bool) string {
+	if history {
+		return FUNCTION_HISTORY
+	}
+	return "default"
+}
+
+// toHistory 将tag翻译中按当前状态查询资源名称和K8s标签的字典，改写为按数据行的time查询历史字典
+func toHistory(expr string) string {
+	expr = deviceNameRegexp.ReplaceAllString(expr, fmt.Sprintf(
+		"dictGetOrDefault(%s, 'name', $1, %s, dictGetOrDefault(flow_tag.device_map, 'name', $1, ''))",
+		DEVICE_HISTORY_MAP, HISTORY_TIME_COLUMN,
+	))
+	expr = resourceNameRegexp.ReplaceAllStringFunc(expr, func(s string) string {
+		match := resourceNameRegexp.FindStringSubmatch(s)
+		deviceType := HISTORY_RESOURCE_DEVICE_TYPE[match[1]]
+		return fmt.Sprintf(
+			"dictGetOrDefault(%s, 'name', (toUInt64(%d),toUInt64(%s)), %s, dictGetOrDefault(flow_tag.%s_map, 'name', (toUInt64(%s)), ''))",
+			DEVICE_HISTORY_MAP, deviceType, match[2], HISTORY_TIME_COLUMN, match[1], match[2],
+		)
+	})
+	expr = podK8sLabelsRegexp.ReplaceAllString(expr, fmt.Sprintf(
+		"dictGetOrDefault(%s, 'labels', $1, %s, dictGetOrDefault(flow_tag.pod_k8s_labels_map, 'labels', $1, '{}'))",
+		POD_K8S_LABELS_HISTORY_MAP, HISTORY_TIME_COLUMN,
+	))
+	expr = podK8sLabelRegexp.ReplaceAllString(expr, fmt.Sprintf(
+		"if(JSONHas(dictGetOrDefault(%[1]s, 'labels', $1, %[2]s, '{}'), '$2'), JSONExtractString(dictGetOrDefault(%[1]s, 'labels', $1, %[2]s, '{}'), '$2'), dictGetOrDefault(flow_tag.pod_k8s_label_map, 'value', ($1,'$2'), ''))",
+		POD_K8S_LABELS_HISTORY_MAP, HISTORY_TIME_COLUMN,
+	))
+	return expr
+}
+
+// generateHistoryTags 为查询资源名称和K8s标签的tag生成history翻译，过滤条件也按历史值匹配：
+// WhereTranslator的前两个参数固定为操作符和值，使用带序号的格式化参数以兼容各类tag的参数个数，
+// k8s_label的标签名是TagTranslator的参数及WhereTranslator的第三个参数
+func generateHistoryTags(tagResourceMap map[string]map[string]*Tag) {
+	for name, functions := range tagResourceMap {
+		tag, ok := functions["default"]
+		if !ok || tag.TagTranslator == "" {
+			continue
+		}
+		tagTranslator := tag.TagTranslator
+		isK8sLabel := strings.HasPrefix(name, "k8s_label")
+		if isK8sLabel {
+			tagTranslator = strings.ReplaceAll(tagTranslator, "%s", "%[1]s")
+		}
+		historyTranslator := toHistory(tagTranslator)
+		if historyTranslator == tagTranslator {
+			continue
+		}
+		historyTag := NewTag(historyTranslator, tag.NotNullFilter, "", "")
+		if tag.WhereTranslator != "" {
+			whereExpr := historyTranslator
+			if isK8sLabel {
+				whereExpr = strings.ReplaceAll(whereExpr, "%[1]s", "%[3]s")
+			}
+			historyTag.WhereTranslator = whereExpr + " %[1]s %[2]s"
+			historyTag.WhereRegexpTranslator = "%[1]s(" + whereExpr + ",%[2]s)"
+		}
+		functions[FUNCTION_HISTORY] = historyTag
+	}
+}
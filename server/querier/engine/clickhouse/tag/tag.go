@@ -34,5 +34,8 @@ func NewTag(tagTranslator, notNullFilter, whereTranslator, whereRegexpTranslator
 
 func GetTag(name, db, table, function string) (*Tag, bool) {
 	tag, ok := TagResoureMap[name][function]
+	if !ok && function == FUNCTION_HISTORY {
+		tag, ok = TagResoureMap[name]["default"]
+	}
 	return tag, ok
 }
@@ -24,6 +24,9 @@ import (
 	"github.com/deepflowio/deepflow/server/querier/common"
 )
 
+// TAG_ENUM_COLUMNS maps the int enum tags not stored as a column to the
+// expression of their value, empty if the tag can only be used in filters
+var TAG_ENUM_COLUMNS = map[string]string{}
 var TagResoureMap = GenerateTagResoureMap()
 var DEVICE_MAP = map[string]int{
 	"chost":       VIF_DEVICE_TYPE_VM,
@@ -529,6 +532,27 @@ func GenerateTagResoureMap() map[string]map[string]*Tag {
 						"((if(is_ipv4=1,IPv4NumToString("+ip4Suffix+"),IPv6NumToString("+ip6Suffix+")),toUInt64("+l3EPCIDSuffix+")) IN (SELECT ip,l3_epc_id FROM flow_tag.ip_relation_map WHERE %s("+relatedResourceName+",%s))) OR (toUInt64(service_id"+suffix+") IN (SELECT pod_service_id FROM flow_tag.ip_relation_map WHERE %s("+relatedResourceName+",%s)))",
 					),
 				}
+				// pod_ingress_type 为 flow_tag.pod_ingress_map 中的属性
+				// 一个service只会关联一个pod_ingress, 分组时通过 ip_relation_map 查找IP对应的pod_ingress
+				podIngressTypeSuffix := "pod_ingress_type" + suffix
+				podIngressIDsByType := "SELECT id FROM flow_tag.pod_ingress_map WHERE pod_ingress_type"
+				podIngressID := "dictGet(flow_tag.ip_relation_map, 'pod_ingress_id', (toUInt64(" + l3EPCIDSuffix + "),if(is_ipv4=1,IPv4NumToString(" + ip4Suffix + "),IPv6NumToString(" + ip6Suffix + "))))"
+				podIngressType := "dictGet(flow_tag.pod_ingress_map, 'pod_ingress_type', " + podIngressID + ")"
+				TAG_ENUM_COLUMNS[podIngressTypeSuffix] = podIngressType
+				tagResourceMap[podIngressTypeSuffix] = map[string]*Tag{
+					"default": NewTag(
+						podIngressType,
+						podIngressID+"!=0",
+						"((if(is_ipv4=1,IPv4NumToString("+ip4Suffix+"),IPv6NumToString("+ip6Suffix+")),toUInt64("+l3EPCIDSuffix+")) IN (SELECT ip,l3_epc_id FROM flow_tag.ip_relation_map WHERE "+relatedResourceID+" IN ("+podIngressIDsByType+" %s %s))) OR (toUInt64(service_id"+suffix+") IN (SELECT pod_service_id FROM flow_tag.ip_relation_map WHERE "+relatedResourceID+" IN ("+podIngressIDsByType+" %s %s)))",
+						"",
+					),
+					"enum": NewTag(
+						"dictGetOrDefault(flow_tag.int_enum_map, 'name', ('%s',"+podIngressType+"), "+podIngressType+")",
+						podIngressID+"!=0",
+						"toUInt64(service_id"+suffix+") IN (SELECT pod_service_id FROM flow_tag.ip_relation_map WHERE "+relatedResourceID+" IN ("+podIngressIDsByType+" IN (SELECT value FROM flow_tag.int_enum_map WHERE name %s %s and tag_name='%s')))",
+						"toUInt64(service_id"+suffix+") IN (SELECT pod_service_id FROM flow_tag.ip_relation_map WHERE "+relatedResourceID+" IN ("+podIngressIDsByType+" IN (SELECT value FROM flow_tag.int_enum_map WHERE %s(name,%s) and tag_name='%s')))",
+					),
+				}
 			} else {
 				tagResourceMap[relatedResourceIDSuffix] = map[string]*Tag{
 					"default": NewTag(
@@ -906,6 +930,28 @@ func GenerateTagResoureMap() map[string]map[string]*Tag {
 			}
 		}
 	}
+	// 工作负载类型
+	// pod_group_type is an attribute in flow_tag.pod_group_map
+	for _, suffix := range []string{"", "_0", "_1"} {
+		podGroupIDSuffix := "pod_group_id" + suffix
+		podGroupTypeSuffix := "pod_group_type" + suffix
+		podGroupType := "dictGet(flow_tag.pod_group_map, 'pod_group_type', (toUInt64(" + podGroupIDSuffix + ")))"
+		TAG_ENUM_COLUMNS[podGroupTypeSuffix] = podGroupType
+		tagResourceMap[podGroupTypeSuffix] = map[string]*Tag{
+			"default": NewTag(
+				podGroupType,
+				podGroupIDSuffix+"!=0",
+				"toUInt64("+podGroupIDSuffix+") IN (SELECT id FROM flow_tag.pod_group_map WHERE pod_group_type %s %s)",
+				"",
+			),
+			"enum": NewTag(
+				"dictGetOrDefault(flow_tag.int_enum_map, 'name', ('%s',"+podGroupType+"), "+podGroupType+")",
+				podGroupIDSuffix+"!=0",
+				"toUInt64("+podGroupIDSuffix+") IN (SELECT id FROM flow_tag.pod_group_map WHERE pod_group_type IN (SELECT value FROM flow_tag.int_enum_map WHERE name %s %s and tag_name='%s'))",
+				"toUInt64("+podGroupIDSuffix+") IN (SELECT id FROM flow_tag.pod_group_map WHERE pod_group_type IN (SELECT value FROM flow_tag.int_enum_map WHERE %s(name,%s) and tag_name='%s'))",
+			),
+		}
+	}
 	// span_kind
 	// nullable int_enum tag do not return default value
 	tagResourceMap["span_kind"] = map[string]*Tag{
@@ -915,7 +961,7 @@ func GenerateTagResoureMap() map[string]map[string]*Tag {
 			"toUInt64(span_kind) IN (SELECT value FROM flow_tag.int_enum_map WHERE name %s %s and tag_name='%s')",
 			"toUInt64(span_kind) IN (SELECT value FROM flow_tag.int_enum_map WHERE %s(name,%s) and tag_name='%s')",
 		)}
-	for _, enumName := range []string{"tap_side", "event_type", "profile_language_type"} {
+	for _, enumName := range []string{"tap_side", "event_type", "profile_language_type", "metric"} {
 		tagResourceMap[enumName] = map[string]*Tag{
 			"enum": NewTag(
 				"dictGetOrDefault(flow_tag.string_enum_map, 'name', ('%s',"+enumName+"), "+enumName+")",
@@ -967,5 +1013,7 @@ func GenerateTagResoureMap() map[string]map[string]*Tag {
 				"",
 			)}
 	}
+	// 按数据行的时间解析资源名称和标签
+	generateHistoryTags(tagResourceMap)
 	return tagResourceMap
 }
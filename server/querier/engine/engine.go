@@ -29,6 +29,8 @@ type Engine interface {
 	TransHaving(*sqlparser.Where) error
 	TransOrderBy(sqlparser.OrderBy) error
 	TransLimit(*sqlparser.Limit) error
+	TransUnion(*sqlparser.Union) error
+	TransJoin(*sqlparser.Select) error
 	ToSQLString() string
 	Init()
 	ExecuteQuery(*common.QuerierParams) (*common.Result, map[string]interface{}, error)
@@ -0,0 +1,205 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package governance
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	clickhouse "github.com/ClickHouse/clickhouse-go/v2"
+	logging "github.com/op/go-logging"
+
+	"github.com/deepflowio/deepflow/server/querier/common"
+	"github.com/deepflowio/deepflow/server/querier/config"
+)
+
+var log = logging.MustGetLogger("querier.governance")
+
+const DEFAULT_CALLER = "default"
+
+var governor *Governor
+
+// Governor applies per-caller limits to querier requests and records them
+// in the query log. Unauthenticated callers share the default limits.
+type Governor struct {
+	cfg      *config.Governance
+	callers  map[string]*config.QueryCaller // token -> caller
+	limits   map[string]*config.QueryLimits // caller name -> limits
+	limiters sync.Map                       // caller name -> *Limiter
+	queryLog *QueryLogWriter
+}
+
+func Start(cfg *config.QuerierConfig) {
+	if !cfg.Governance.Enabled {
+		return
+	}
+	g := &Governor{
+		cfg:     &cfg.Governance,
+		callers: make(map[string]*config.QueryCaller),
+		limits:  make(map[string]*config.QueryLimits),
+	}
+	g.limits[DEFAULT_CALLER] = &cfg.Governance.Limits
+	for i := range cfg.Governance.Callers {
+		caller := &cfg.Governance.Callers[i]
+		if caller.Name == "" || caller.Token == "" {
+			log.Warningf("ignore caller without name or token: %s", caller.Name)
+			continue
+		}
+		g.callers[caller.Token] = caller
+		g.limits[caller.Name] = mergeLimits(&cfg.Governance.Limits, &caller.Limits)
+	}
+	if cfg.Governance.QueryLog.Enabled {
+		g.queryLog = NewQueryLogWriter(&cfg.Clickhouse, &cfg.Governance.QueryLog)
+		g.queryLog.Start()
+	}
+	governor = g
+}
+
+func Enabled() bool {
+	return governor != nil
+}
+
+// Identify returns the caller of an http request, the caller is authenticated
+// only when the token header matches a configured caller.
+func Identify(header http.Header, clientIP string) *common.Caller {
+	if governor == nil {
+		return &common.Caller{Name: clientIP}
+	}
+	if token := header.Get(governor.cfg.TokenHeader); token != "" {
+		if caller, ok := governor.callers[token]; ok {
+			return &common.Caller{Name: caller.Name, Authenticated: true}
+		}
+	}
+	if name := header.Get(governor.cfg.CallerHeader); name != "" {
+		return &common.Caller{Name: name}
+	}
+	return &common.Caller{Name: clientIP}
+}
+
+// Begin waits for a query slot of the caller and returns a context carrying
+// the query record, End must be called with the returned query when done.
+func Begin(args *common.QuerierParams) (*Query, context.Context, error) {
+	ctx := args.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if governor == nil {
+		return nil, ctx, nil
+	}
+	return governor.begin(ctx, args)
+}
+
+func End(q *Query, resultRows int, err error) {
+	if governor == nil || q == nil {
+		return
+	}
+	governor.end(q, resultRows, err)
+}
+
+func (g *Governor) begin(ctx context.Context, args *common.QuerierParams) (*Query, context.Context, error) {
+	key := DEFAULT_CALLER
+	callerName := ""
+	if args.Caller != nil {
+		callerName = args.Caller.Name
+		if _, ok := g.limits[args.Caller.Name]; ok && args.Caller.Authenticated {
+			key = args.Caller.Name
+		}
+	}
+	limits := g.limits[key]
+	limiter := g.limiter(key, limits)
+	release, err := limiter.Acquire(ctx)
+	if err != nil {
+		log.Warningf("query_uuid: %s, caller: %s, rejected: %s", args.QueryUUID, callerName, err)
+		return nil, ctx, err
+	}
+	q := &Query{
+		UUID:      args.QueryUUID,
+		Caller:    callerName,
+		Sql:       args.Sql,
+		StartTime: time.Now(),
+		Settings:  Settings(limits),
+		release:   release,
+	}
+	return q, NewContext(ctx, q), nil
+}
+
+func (g *Governor) end(q *Query, resultRows int, err error) {
+	q.release()
+	q.finish(resultRows, err)
+	if g.queryLog != nil {
+		g.queryLog.Put(q)
+	}
+}
+
+func (g *Governor) limiter(key string, limits *config.QueryLimits) *Limiter {
+	if l, ok := g.limiters.Load(key); ok {
+		return l.(*Limiter)
+	}
+	l, _ := g.limiters.LoadOrStore(key, NewLimiter(
+		limits.MaxConcurrentQueries,
+		limits.MaxQueuedQueries,
+		time.Duration(limits.QueueTimeout)*time.Second,
+	))
+	return l.(*Limiter)
+}
+
+// Settings translates limits into ClickHouse query settings
+func Settings(limits *config.QueryLimits) clickhouse.Settings {
+	settings := clickhouse.Settings{}
+	if limits.MaxExecutionTime > 0 {
+		settings["max_execution_time"] = limits.MaxExecutionTime
+	}
+	if limits.MaxRowsToRead > 0 {
+		settings["max_rows_to_read"] = limits.MaxRowsToRead
+	}
+	if limits.MaxBytesToRead > 0 {
+		settings["max_bytes_to_read"] = limits.MaxBytesToRead
+	}
+	if limits.MaxMemoryUsage > 0 {
+		settings["max_memory_usage"] = limits.MaxMemoryUsage
+	}
+	return settings
+}
+
+// mergeLimits 0 表示继承base的值, 负数表示不限制
+func mergeLimits(base, override *config.QueryLimits) *config.QueryLimits {
+	limits := *base
+	if override.MaxExecutionTime != 0 {
+		limits.MaxExecutionTime = override.MaxExecutionTime
+	}
+	if override.MaxRowsToRead != 0 {
+		limits.MaxRowsToRead = override.MaxRowsToRead
+	}
+	if override.MaxBytesToRead != 0 {
+		limits.MaxBytesToRead = override.MaxBytesToRead
+	}
+	if override.MaxMemoryUsage != 0 {
+		limits.MaxMemoryUsage = override.MaxMemoryUsage
+	}
+	if override.MaxConcurrentQueries != 0 {
+		limits.MaxConcurrentQueries = override.MaxConcurrentQueries
+	}
+	if override.MaxQueuedQueries != 0 {
+		limits.MaxQueuedQueries = override.MaxQueuedQueries
+	}
+	if override.QueueTimeout != 0 {
+		limits.QueueTimeout = override.QueueTimeout
+	}
+	return &limits
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package governance
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrQueueFull    = errors.New("too many queued queries")
+	ErrQueueTimeout = errors.New("timeout waiting for a query slot")
+)
+
+// Limiter bounds the number of running queries, queries beyond the bound
+// wait in a queue of limited length until a slot is released, a negative
+// maxQueued means the queue is unbounded.
+type Limiter struct {
+	slots     chan struct{}
+	queued    int32
+	maxQueued int32
+	timeout   time.Duration
+}
+
+// NewLimiter returns nil if maxConcurrent is 0 or negative, a nil Limiter never blocks.
+func NewLimiter(maxConcurrent, maxQueued int, timeout time.Duration) *Limiter {
+	if maxConcurrent <= 0 {
+		return nil
+	}
+	return &Limiter{
+		slots:     make(chan struct{}, maxConcurrent),
+		maxQueued: int32(maxQueued),
+		timeout:   timeout,
+	}
+}
+
+func (l *Limiter) Acquire(ctx context.Context) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	release = func() { <-l.slots }
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	if atomic.AddInt32(&l.queued, 1) > l.maxQueued && l.maxQueued >= 0 {
+		atomic.AddInt32(&l.queued, -1)
+		return nil, ErrQueueFull
+	}
+	defer atomic.AddInt32(&l.queued, -1)
+
+	var timeout <-chan time.Time
+	if l.timeout > 0 {
+		timer := time.NewTimer(l.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	case <-timeout:
+		return nil, ErrQueueTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (l *Limiter) Running() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.slots)
+}
+
+func (l *Limiter) Queued() int {
+	if l == nil {
+		return 0
+	}
+	return int(atomic.LoadInt32(&l.queued))
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package governance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/deepflowio/deepflow/server/querier/config"
+)
+
+func TestLimiterQueue(t *testing.T) {
+	l := NewLimiter(1, 1, 50*time.Millisecond)
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Expected nil found %v", err)
+	}
+
+	done := make(chan error)
+	go func() {
+		r, err := l.Acquire(context.Background())
+		if err == nil {
+			r()
+		}
+		done <- err
+	}()
+	for l.Queued() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := l.Acquire(context.Background()); err != ErrQueueFull {
+		t.Errorf("Expected %v found %v", ErrQueueFull, err)
+	}
+	release()
+	if err := <-done; err != nil {
+		t.Errorf("Expected nil found %v", err)
+	}
+	if actual := l.Running(); actual != 0 {
+		t.Errorf("Expected 0 found %v", actual)
+	}
+}
+
+func TestLimiterTimeout(t *testing.T) {
+	l := NewLimiter(1, 10, 10*time.Millisecond)
+	if _, err := l.Acquire(context.Background()); err != nil {
+		t.Fatalf("Expected nil found %v", err)
+	}
+	if _, err := l.Acquire(context.Background()); err != ErrQueueTimeout {
+		t.Errorf("Expected %v found %v", ErrQueueTimeout, err)
+	}
+}
+
+func TestUnlimited(t *testing.T) {
+	var l *Limiter = NewLimiter(0, 0, 0)
+	for i := 0; i < 100; i++ {
+		if _, err := l.Acquire(context.Background()); err != nil {
+			t.Fatalf("Expected nil found %v", err)
+		}
+	}
+}
+
+func TestMergeLimits(t *testing.T) {
+	base := &config.QueryLimits{MaxExecutionTime: 60, MaxConcurrentQueries: 10}
+	limits := mergeLimits(base, &config.QueryLimits{MaxRowsToRead: 1000000})
+	settings := Settings(limits)
+	if actual := settings["max_execution_time"]; actual != 60 {
+		t.Errorf("Expected 60 found %v", actual)
+	}
+	if actual := settings["max_rows_to_read"]; actual != 1000000 {
+		t.Errorf("Expected 1000000 found %v", actual)
+	}
+	if _, ok := settings["max_memory_usage"]; ok {
+		t.Errorf("Expected no max_memory_usage setting")
+	}
+}
+
+func TestMergeLimitsUnlimited(t *testing.T) {
+	base := &config.QueryLimits{MaxExecutionTime: 60, MaxConcurrentQueries: 10, MaxQueuedQueries: 50}
+	limits := mergeLimits(base, &config.QueryLimits{MaxExecutionTime: -1, MaxConcurrentQueries: -1})
+	if _, ok := Settings(limits)["max_execution_time"]; ok {
+		t.Errorf("Expected no max_execution_time setting")
+	}
+	if l := NewLimiter(limits.MaxConcurrentQueries, limits.MaxQueuedQueries, 0); l != nil {
+		t.Errorf("Expected nil limiter")
+	}
+	if limits.MaxQueuedQueries != 50 {
+		t.Errorf("Expected 50 found %v", limits.MaxQueuedQueries)
+	}
+}
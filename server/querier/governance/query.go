@@ -0,0 +1,90 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package governance
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	clickhouse "github.com/ClickHouse/clickhouse-go/v2"
+)
+
+type ctxKeyQuery struct{}
+
+// Query records one governed querier request, the ClickHouse client
+// fills in the generated sql and the progress it receives.
+type Query struct {
+	UUID      string
+	Caller    string
+	Sql       string
+	StartTime time.Time
+	Settings  clickhouse.Settings
+
+	rowsRead  uint64
+	bytesRead uint64
+	release   func()
+
+	sync.Mutex
+	generatedSqls []string
+	resultRows    int
+	duration      time.Duration
+	err           string
+}
+
+func NewContext(ctx context.Context, q *Query) context.Context {
+	return context.WithValue(ctx, ctxKeyQuery{}, q)
+}
+
+func FromContext(ctx context.Context) *Query {
+	if ctx == nil {
+		return nil
+	}
+	q, _ := ctx.Value(ctxKeyQuery{}).(*Query)
+	return q
+}
+
+// OnProgress accumulates ClickHouse progress packets, which carry increments
+func (q *Query) OnProgress(p *clickhouse.Progress) {
+	atomic.AddUint64(&q.rowsRead, p.Rows)
+	atomic.AddUint64(&q.bytesRead, p.Bytes)
+}
+
+func (q *Query) AddGeneratedSql(sql string) {
+	q.Lock()
+	q.generatedSqls = append(q.generatedSqls, sql)
+	q.Unlock()
+}
+
+func (q *Query) RowsRead() uint64 {
+	return atomic.LoadUint64(&q.rowsRead)
+}
+
+func (q *Query) BytesRead() uint64 {
+	return atomic.LoadUint64(&q.bytesRead)
+}
+
+func (q *Query) finish(resultRows int, err error) {
+	q.Lock()
+	q.resultRows = resultRows
+	q.duration = time.Since(q.StartTime)
+	if err != nil {
+		q.err = err.Error()
+	}
+	q.Unlock()
+}
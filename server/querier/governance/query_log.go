@@ -0,0 +1,269 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package governance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	clickhouse "github.com/ClickHouse/clickhouse-go/v2"
+
+	"github.com/deepflowio/deepflow/server/querier/config"
+)
+
+const (
+	QUERY_LOG_TABLE       = "query_log"
+	QUERY_LOG_LOCAL_TABLE = "query_log_local"
+	QUERY_LOG_BATCH_SIZE  = 1024
+)
+
+var ErrQueryLogDisabled = errors.New("query log is disabled")
+
+// QueryLogWriter writes finished queries into ClickHouse in batches,
+// queries are dropped if the queue is full.
+type QueryLogWriter struct {
+	chCfg *config.Clickhouse
+	cfg   *config.QueryLog
+	queue chan *Query
+
+	connLock sync.Mutex
+	conn     clickhouse.Conn
+}
+
+func NewQueryLogWriter(chCfg *config.Clickhouse, cfg *config.QueryLog) *QueryLogWriter {
+	return &QueryLogWriter{
+		chCfg: chCfg,
+		cfg:   cfg,
+		queue: make(chan *Query, cfg.QueueSize),
+	}
+}
+
+func (w *QueryLogWriter) Start() {
+	go w.run()
+}
+
+func (w *QueryLogWriter) Put(q *Query) {
+	select {
+	case w.queue <- q:
+	default:
+		log.Warningf("query log queue is full, drop query_uuid: %s", q.UUID)
+	}
+}
+
+func (w *QueryLogWriter) connect() error {
+	w.connLock.Lock()
+	defer w.connLock.Unlock()
+	if w.conn != nil {
+		return nil
+	}
+	conn, err := clickhouse.Open(&clickhouse.Options{
+		Addr: []string{fmt.Sprintf("%s:%d", w.chCfg.Host, w.chCfg.Port)},
+		Auth: clickhouse.Auth{
+			Database: "default",
+			Username: w.chCfg.User,
+			Password: w.chCfg.Password,
+		},
+		DialTimeout: time.Duration(w.chCfg.ConnectTimeout) * time.Second,
+	})
+	if err != nil {
+		return err
+	}
+	for _, sql := range w.createSqls() {
+		if err := conn.Exec(context.Background(), sql); err != nil {
+			conn.Close()
+			return fmt.Errorf("%s, sql: %s", err, sql)
+		}
+	}
+	w.conn = conn
+	return nil
+}
+
+func (w *QueryLogWriter) createSqls() []string {
+	onCluster := ""
+	if w.cfg.Cluster != "" {
+		onCluster = fmt.Sprintf("ON CLUSTER %s", w.cfg.Cluster)
+	}
+	settings := ""
+	if w.cfg.StoragePolicy != "" {
+		settings = fmt.Sprintf("SETTINGS storage_policy = '%s'", w.cfg.StoragePolicy)
+	}
+	sqls := []string{
+		fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s %s", w.cfg.Database, onCluster),
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s.%s %s
+(
+    time DateTime,
+    query_uuid String,
+    caller LowCardinality(String),
+    sql String,
+    generated_sql String,
+    rows_read UInt64,
+    bytes_read UInt64,
+    result_rows UInt64,
+    duration UInt64 COMMENT 'microseconds',
+    error String
+)
+ENGINE = MergeTree
+PARTITION BY toStartOfDay(time)
+ORDER BY (caller, time)
+TTL time + toIntervalHour(%d)
+%s`, w.cfg.Database, QUERY_LOG_LOCAL_TABLE, onCluster, w.cfg.TTL, settings),
+	}
+	if w.cfg.Cluster != "" {
+		sqls = append(sqls, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s.%s %s AS %s.%s ENGINE = Distributed(%s, %s, %s, rand())",
+			w.cfg.Database, QUERY_LOG_TABLE, onCluster, w.cfg.Database, QUERY_LOG_LOCAL_TABLE,
+			w.cfg.Cluster, w.cfg.Database, QUERY_LOG_LOCAL_TABLE))
+	}
+	return sqls
+}
+
+func (w *QueryLogWriter) table() string {
+	if w.cfg.Cluster == "" {
+		return fmt.Sprintf("%s.%s", w.cfg.Database, QUERY_LOG_LOCAL_TABLE)
+	}
+	return fmt.Sprintf("%s.%s", w.cfg.Database, QUERY_LOG_TABLE)
+}
+
+func (w *QueryLogWriter) run() {
+	ticker := time.NewTicker(time.Duration(w.cfg.FlushInterval) * time.Second)
+	defer ticker.Stop()
+	batch := make([]*Query, 0, QUERY_LOG_BATCH_SIZE)
+	for {
+		select {
+		case q := <-w.queue:
+			batch = append(batch, q)
+			if len(batch) < QUERY_LOG_BATCH_SIZE {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+		if err := w.write(batch); err != nil {
+			log.Errorf("write %d query logs failed: %s", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+}
+
+func (w *QueryLogWriter) write(queries []*Query) error {
+	if err := w.connect(); err != nil {
+		return err
+	}
+	batch, err := w.conn.PrepareBatch(context.Background(), fmt.Sprintf("INSERT INTO %s", w.table()))
+	if err != nil {
+		return err
+	}
+	for _, q := range queries {
+		q.Lock()
+		err = batch.Append(
+			q.StartTime,
+			q.UUID,
+			q.Caller,
+			q.Sql,
+			strings.Join(q.generatedSqls, ";\n"),
+			q.RowsRead(),
+			q.BytesRead(),
+			uint64(q.resultRows),
+			uint64(q.duration.Microseconds()),
+			q.err,
+		)
+		q.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return batch.Send()
+}
+
+type QueryLogFilter struct {
+	Caller    string
+	QueryUUID string
+	StartTime int64
+	EndTime   int64
+	Limit     int
+}
+
+// QueryLogs reads the query log, newest first
+func QueryLogs(ctx context.Context, filter *QueryLogFilter) ([]map[string]interface{}, error) {
+	if governor == nil || governor.queryLog == nil {
+		return nil, ErrQueryLogDisabled
+	}
+	w := governor.queryLog
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	conditions := []string{"1=1"}
+	args := []interface{}{}
+	if filter.Caller != "" {
+		conditions = append(conditions, "caller = ?")
+		args = append(args, filter.Caller)
+	}
+	if filter.QueryUUID != "" {
+		conditions = append(conditions, "query_uuid = ?")
+		args = append(args, filter.QueryUUID)
+	}
+	if filter.StartTime > 0 {
+		conditions = append(conditions, "time >= ?")
+		args = append(args, time.Unix(filter.StartTime, 0))
+	}
+	if filter.EndTime > 0 {
+		conditions = append(conditions, "time <= ?")
+		args = append(args, time.Unix(filter.EndTime, 0))
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	sqlstr := fmt.Sprintf(
+		"SELECT time, query_uuid, caller, sql, generated_sql, rows_read, bytes_read, result_rows, duration, error FROM %s WHERE %s ORDER BY time DESC LIMIT %d",
+		w.table(), strings.Join(conditions, " AND "), limit,
+	)
+	rows, err := w.conn.Query(ctx, sqlstr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	logs := []map[string]interface{}{}
+	for rows.Next() {
+		var (
+			t                                   time.Time
+			queryUUID, caller, sql, genSql, e   string
+			rowsRead, bytesRead, result, costUs uint64
+		)
+		if err := rows.Scan(&t, &queryUUID, &caller, &sql, &genSql, &rowsRead, &bytesRead, &result, &costUs, &e); err != nil {
+			return nil, err
+		}
+		logs = append(logs, map[string]interface{}{
+			"time":          t.Unix(),
+			"query_uuid":    queryUUID,
+			"caller":        caller,
+			"sql":           sql,
+			"generated_sql": genSql,
+			"rows_read":     rowsRead,
+			"bytes_read":    bytesRead,
+			"result_rows":   result,
+			"duration":      costUs,
+			"error":         e,
+		})
+	}
+	return logs, rows.Err()
+}
@@ -17,6 +17,9 @@
 package parse
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/xwb1989/sqlparser"
 
 	"github.com/deepflowio/deepflow/server/querier/engine"
@@ -38,7 +41,22 @@ func (p *Parser) ParseSQL(sql string) error {
 		return err
 	}
 
-	pStmt := stmt.(*sqlparser.Select)
+	switch pStmt := stmt.(type) {
+	case *sqlparser.Union:
+		return p.Engine.TransUnion(pStmt)
+	case *sqlparser.Select:
+		return p.ParseSelect(pStmt)
+	}
+	return errors.New(fmt.Sprintf("sql: '%s' not support", sql))
+}
+
+// 解析单个select, 也用于子查询的解析
+func (p *Parser) ParseSelect(pStmt *sqlparser.Select) error {
+	// From为join或子查询时, 由Engine整体解析
+	if IsJoin(pStmt.From) {
+		return p.Engine.TransJoin(pStmt)
+	}
+
 	// From解析
 	if pStmt.From != nil {
 		fromErr := p.Engine.TransFrom(pStmt.From)
@@ -96,3 +114,21 @@ func (p *Parser) ParseSQL(sql string) error {
 	}
 	return nil
 }
+
+// IsJoin 判断From是否为join或子查询
+func IsJoin(froms sqlparser.TableExprs) bool {
+	if len(froms) > 1 {
+		return true
+	}
+	for _, from := range froms {
+		switch from := from.(type) {
+		case *sqlparser.AliasedTableExpr:
+			if _, ok := from.Expr.(sqlparser.TableName); !ok {
+				return true
+			}
+		default:
+			return true
+		}
+	}
+	return false
+}
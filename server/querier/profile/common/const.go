@@ -31,4 +31,11 @@ const (
 	PROFILE_NODE_ID        = "profile_node_id"
 	PROFILE_PARENT_NODE_ID = "profile_parent_node_id"
 	PROFILE_VALUE          = "profile_value"
+	PROFILE_VALUE_UNIT     = "profile_value_unit"
+)
+
+const (
+	FORMAT_PPROF      = "pprof"
+	FORMAT_SPEEDSCOPE = "speedscope"
+	FORMAT_COLLAPSED  = "collapsed"
 )
@@ -16,6 +16,12 @@
 
 package model
 
+import (
+	"context"
+
+	"github.com/deepflowio/deepflow/server/querier/common"
+)
+
 type ProfileTracing struct {
 	AppService          string `json:"app_service" binding:"required"`
 	ProfileEventType    string `json:"profile_event_type" binding:"required"`
@@ -25,6 +31,25 @@ type ProfileTracing struct {
 	TimeStart           int    `json:"time_start" binding:"required"`
 	TimeEnd             int    `json:"time_end" binding:"required"`
 	Debug               bool   `json:"debug"`
+
+	// 由router填充, 用于查询的取消和限流
+	Context context.Context `json:"-"`
+	Caller  *common.Caller  `json:"-"`
+}
+
+// ProfileTracingDiff 对比两个选择(不同时间段或不同的tag_filter)的火焰图
+type ProfileTracingDiff struct {
+	Baseline   ProfileTracing `json:"baseline" binding:"required"`
+	Comparison ProfileTracing `json:"comparison" binding:"required"`
+	// 按总值将comparison缩放到baseline的量级, 用于对比长度不同的时间段
+	Normalize bool `json:"normalize"`
+	Debug     bool `json:"debug"`
+}
+
+// ProfileExport 将选择的profile导出为指定格式: pprof, speedscope, collapsed
+type ProfileExport struct {
+	ProfileTracing
+	Format string `json:"format" binding:"required,oneof=pprof speedscope collapsed"`
 }
 
 type ProfileTreeNode struct {
@@ -37,6 +62,18 @@ type ProfileTreeNode struct {
 	TotalValue           int      `json:"total_value"`
 }
 
+type ProfileDiffNode struct {
+	ProfileLocationStr   string   `json:"profile_location_str"`
+	NodeID               string   `json:"node_id"`
+	ParentNodeIDS        []string `json:"parent_node_ids"`
+	BaselineSelfValue    int      `json:"baseline_self_value"`
+	BaselineTotalValue   int      `json:"baseline_total_value"`
+	ComparisonSelfValue  int      `json:"comparison_self_value"`
+	ComparisonTotalValue int      `json:"comparison_total_value"`
+	SelfDelta            int      `json:"self_delta"`
+	TotalDelta           int      `json:"total_delta"`
+}
+
 type Debug struct {
 	IP         string
 	Sql        string `json:"sql"`
@@ -17,10 +17,14 @@
 package router
 
 import (
+	"fmt"
+	"net/http"
+
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 
 	"github.com/deepflowio/deepflow/server/querier/config"
+	"github.com/deepflowio/deepflow/server/querier/governance"
 	"github.com/deepflowio/deepflow/server/querier/profile/common"
 	"github.com/deepflowio/deepflow/server/querier/profile/model"
 	"github.com/deepflowio/deepflow/server/querier/profile/service"
@@ -29,6 +33,8 @@ import (
 
 func ProfileRouter(e *gin.Engine, cfg *config.QuerierConfig) {
 	e.POST("/v1/profile/ProfileTracing", profileTracing(cfg))
+	e.POST("/v1/profile/ProfileTracingDiff", profileTracingDiff(cfg))
+	e.POST("/v1/profile/ProfileExport", profileExport(cfg))
 
 }
 
@@ -42,6 +48,8 @@ func profileTracing(cfg *config.QuerierConfig) gin.HandlerFunc {
 			router.BadRequestResponse(c, common.INVALID_POST_DATA, err.Error())
 			return
 		}
+		profileTracing.Context = c.Request.Context()
+		profileTracing.Caller = governance.Identify(c.Request.Header, c.ClientIP())
 		result, debug, err := service.Tracing(profileTracing, cfg)
 		if err == nil && !profileTracing.Debug {
 			debug = nil
@@ -49,3 +57,59 @@ func profileTracing(cfg *config.QuerierConfig) gin.HandlerFunc {
 		router.JsonResponse(c, result, debug, err)
 	})
 }
+
+func profileTracingDiff(cfg *config.QuerierConfig) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		var profileTracingDiff model.ProfileTracingDiff
+
+		// 参数校验
+		err := c.ShouldBindBodyWith(&profileTracingDiff, binding.JSON)
+		if err != nil {
+			router.BadRequestResponse(c, common.INVALID_POST_DATA, err.Error())
+			return
+		}
+		caller := governance.Identify(c.Request.Header, c.ClientIP())
+		for _, args := range []*model.ProfileTracing{&profileTracingDiff.Baseline, &profileTracingDiff.Comparison} {
+			args.Context = c.Request.Context()
+			args.Caller = caller
+		}
+		result, debug, err := service.TracingDiff(profileTracingDiff, cfg)
+		if err == nil && !profileTracingDiff.Debug {
+			debug = nil
+		}
+		router.JsonResponse(c, result, debug, err)
+	})
+}
+
+func profileExport(cfg *config.QuerierConfig) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		var profileExport model.ProfileExport
+
+		// 参数校验
+		err := c.ShouldBindBodyWith(&profileExport, binding.JSON)
+		if err != nil {
+			router.BadRequestResponse(c, common.INVALID_POST_DATA, err.Error())
+			return
+		}
+		profileExport.Context = c.Request.Context()
+		profileExport.Caller = governance.Identify(c.Request.Header, c.ClientIP())
+		data, contentType, debug, err := service.Export(profileExport, cfg)
+		if err != nil {
+			router.JsonResponse(c, nil, debug, err)
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", exportFileName(profileExport.Format)))
+		c.Data(http.StatusOK, contentType, data)
+	})
+}
+
+func exportFileName(format string) string {
+	switch format {
+	case common.FORMAT_PPROF:
+		return "profile.pb.gz"
+	case common.FORMAT_SPEEDSCOPE:
+		return "profile.speedscope.json"
+	default:
+		return "profile.collapsed.txt"
+	}
+}
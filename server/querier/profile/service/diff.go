@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/exp/slices"
+
+	"github.com/deepflowio/deepflow/server/querier/config"
+	"github.com/deepflowio/deepflow/server/querier/profile/model"
+)
+
+func TracingDiff(args model.ProfileTracingDiff, cfg *config.QuerierConfig) (result []*model.ProfileDiffNode, debug interface{}, err error) {
+	baselineRows, baselineDebug, err := queryProfileRows(args.Baseline, cfg)
+	if err != nil {
+		return
+	}
+	comparisonRows, comparisonDebug, err := queryProfileRows(args.Comparison, cfg)
+	if err != nil {
+		return
+	}
+	formatStartTime := time.Now()
+	result = diffProfileTree(buildProfileTree(baselineRows, false), buildProfileTree(comparisonRows, false), args.Normalize)
+	formatTime := fmt.Sprintf("%.9fs", float64(time.Since(formatStartTime))/1e9)
+	baselineDebug.FormatTime = formatTime
+	comparisonDebug.FormatTime = formatTime
+	debug = []*model.Debug{baselineDebug, comparisonDebug}
+	return
+}
+
+func treeSelfTotal(tree []*model.ProfileTreeNode) int {
+	total := 0
+	for _, node := range tree {
+		total += node.SelfValue
+	}
+	return total
+}
+
+// diffProfileTree 按node_id合并两棵树, delta为comparison减baseline
+func diffProfileTree(baseline, comparison []*model.ProfileTreeNode, normalize bool) []*model.ProfileDiffNode {
+	scale := 1.0
+	if normalize {
+		if baselineTotal, comparisonTotal := treeSelfTotal(baseline), treeSelfTotal(comparison); baselineTotal > 0 && comparisonTotal > 0 {
+			scale = float64(baselineTotal) / float64(comparisonTotal)
+		}
+	}
+	result := make([]*model.ProfileDiffNode, 0, len(baseline))
+	nodes := make(map[string]*model.ProfileDiffNode, len(baseline))
+	getNode := func(n *model.ProfileTreeNode) *model.ProfileDiffNode {
+		node, ok := nodes[n.NodeID]
+		if !ok {
+			node = &model.ProfileDiffNode{
+				ProfileLocationStr: n.ProfileLocationStr,
+				NodeID:             n.NodeID,
+			}
+			nodes[n.NodeID] = node
+			result = append(result, node)
+		}
+		for _, parentNodeID := range n.ParentNodeIDS {
+			if !slices.Contains[string](node.ParentNodeIDS, parentNodeID) {
+				node.ParentNodeIDS = append(node.ParentNodeIDS, parentNodeID)
+			}
+		}
+		return node
+	}
+	for _, n := range baseline {
+		node := getNode(n)
+		node.BaselineSelfValue = n.SelfValue
+		node.BaselineTotalValue = n.TotalValue
+	}
+	for _, n := range comparison {
+		node := getNode(n)
+		node.ComparisonSelfValue = int(float64(n.SelfValue) * scale)
+		node.ComparisonTotalValue = int(float64(n.TotalValue) * scale)
+	}
+	for _, node := range result {
+		// 节点在一侧为根, 另一侧不是根时, 去掉根标记
+		if len(node.ParentNodeIDS) > 1 {
+			if i := slices.Index[string](node.ParentNodeIDS, ""); i >= 0 {
+				node.ParentNodeIDS = slices.Delete(node.ParentNodeIDS, i, i+1)
+			}
+		}
+		node.SelfDelta = node.ComparisonSelfValue - node.BaselineSelfValue
+		node.TotalDelta = node.ComparisonTotalValue - node.BaselineTotalValue
+	}
+	return result
+}
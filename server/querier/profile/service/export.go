@@ -0,0 +1,315 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	querier_common "github.com/deepflowio/deepflow/server/querier/common"
+	"github.com/deepflowio/deepflow/server/querier/config"
+	"github.com/deepflowio/deepflow/server/querier/profile/common"
+	"github.com/deepflowio/deepflow/server/querier/profile/model"
+	querier_service "github.com/deepflowio/deepflow/server/querier/service"
+)
+
+// profileStack 从根到叶子的调用栈及叶子的值
+type profileStack struct {
+	Frames []string
+	Value  int
+}
+
+// Export 返回导出的文件内容及其Content-Type
+func Export(args model.ProfileExport, cfg *config.QuerierConfig) (data []byte, contentType string, debug interface{}, err error) {
+	rows, profileDebug, err := queryProfileRows(args.ProfileTracing, cfg)
+	debug = profileDebug
+	if err != nil {
+		return
+	}
+	stacks := buildProfileStacks(rows)
+	unit := ""
+	for _, row := range rows {
+		if row.ValueUnit != "" {
+			unit = row.ValueUnit
+			break
+		}
+	}
+	switch args.Format {
+	case common.FORMAT_PPROF:
+		data, err = exportPprof(args.ProfileTracing, unit, stacks)
+		contentType = "application/octet-stream"
+	case common.FORMAT_SPEEDSCOPE:
+		data, err = exportSpeedscope(args.ProfileTracing, unit, stacks)
+		contentType = "application/json"
+	case common.FORMAT_COLLAPSED:
+		data = exportCollapsed(stacks)
+		contentType = "text/plain; charset=utf-8"
+	default:
+		err = querier_service.NewError(querier_common.INVALID_PARAMETERS, fmt.Sprintf("unsupported format %s", args.Format))
+	}
+	return
+}
+
+// buildProfileStacks 每行为调用栈中的一个节点, 从有自身值的叶子节点沿parent还原完整调用栈
+func buildProfileStacks(rows []*profileRow) []*profileStack {
+	nodes := make(map[int]*profileRow, len(rows))
+	for _, row := range rows {
+		nodes[row.NodeID] = row
+	}
+	stackIndex := map[string]*profileStack{}
+	stacks := []*profileStack{}
+	for _, row := range rows {
+		if row.Value == 0 {
+			continue
+		}
+		frames := []string{}
+		// 限制深度防止parent成环
+		for node := row; node != nil && len(frames) <= len(rows); node = nodes[node.ParentNodeID] {
+			frames = append(frames, node.LocationStr)
+			if node.ParentNodeID == 0 {
+				break
+			}
+		}
+		for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+			frames[i], frames[j] = frames[j], frames[i]
+		}
+		key := strings.Join(frames, ";")
+		if stack, ok := stackIndex[key]; ok {
+			stack.Value += row.Value
+			continue
+		}
+		stack := &profileStack{Frames: frames, Value: row.Value}
+		stackIndex[key] = stack
+		stacks = append(stacks, stack)
+	}
+	sort.Slice(stacks, func(i, j int) bool {
+		return strings.Join(stacks[i].Frames, ";") < strings.Join(stacks[j].Frames, ";")
+	})
+	return stacks
+}
+
+// exportCollapsed 输出Brendan Gregg的collapsed stack格式: 每行为"a;b;c value"
+func exportCollapsed(stacks []*profileStack) []byte {
+	var buf bytes.Buffer
+	for _, stack := range stacks {
+		buf.WriteString(strings.Join(stack.Frames, ";"))
+		buf.WriteByte(' ')
+		buf.WriteString(fmt.Sprint(stack.Value))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+type speedscopeFrame struct {
+	Name string `json:"name"`
+}
+
+type speedscopeProfile struct {
+	Type       string  `json:"type"`
+	Name       string  `json:"name"`
+	Unit       string  `json:"unit"`
+	StartValue int     `json:"startValue"`
+	EndValue   int     `json:"endValue"`
+	Samples    [][]int `json:"samples"`
+	Weights    []int   `json:"weights"`
+}
+
+type speedscopeFile struct {
+	Schema string `json:"$schema"`
+	Shared struct {
+		Frames []speedscopeFrame `json:"frames"`
+	} `json:"shared"`
+	Profiles           []speedscopeProfile `json:"profiles"`
+	Name               string              `json:"name"`
+	ActiveProfileIndex int                 `json:"activeProfileIndex"`
+	Exporter           string              `json:"exporter"`
+}
+
+// speedscope仅支持以下单位
+func speedscopeUnit(unit string) string {
+	switch unit {
+	case "nanoseconds", "microseconds", "milliseconds", "seconds", "bytes":
+		return unit
+	default:
+		return "none"
+	}
+}
+
+// exportSpeedscope 参考: https://github.com/jlfwong/speedscope/blob/main/src/lib/file-format-spec.ts
+func exportSpeedscope(args model.ProfileTracing, unit string, stacks []*profileStack) ([]byte, error) {
+	name := fmt.Sprintf("%s %s", args.AppService, args.ProfileEventType)
+	file := speedscopeFile{
+		Schema:   "https://www.speedscope.app/file-format-schema.json",
+		Name:     name,
+		Exporter: "deepflow",
+	}
+	frameIndex := map[string]int{}
+	profile := speedscopeProfile{
+		Type:    "sampled",
+		Name:    name,
+		Unit:    speedscopeUnit(unit),
+		Samples: make([][]int, 0, len(stacks)),
+		Weights: make([]int, 0, len(stacks)),
+	}
+	for _, stack := range stacks {
+		sample := make([]int, 0, len(stack.Frames))
+		for _, frame := range stack.Frames {
+			index, ok := frameIndex[frame]
+			if !ok {
+				index = len(file.Shared.Frames)
+				frameIndex[frame] = index
+				file.Shared.Frames = append(file.Shared.Frames, speedscopeFrame{Name: frame})
+			}
+			sample = append(sample, index)
+		}
+		profile.Samples = append(profile.Samples, sample)
+		profile.Weights = append(profile.Weights, stack.Value)
+		profile.EndValue += stack.Value
+	}
+	if file.Shared.Frames == nil {
+		file.Shared.Frames = []speedscopeFrame{}
+	}
+	file.Profiles = []speedscopeProfile{profile}
+	return json.Marshal(file)
+}
+
+// pprof profile.proto的字段编号, 参考: https://github.com/google/pprof/blob/main/proto/profile.proto
+const (
+	PPROF_PROFILE_SAMPLE_TYPE    = 1
+	PPROF_PROFILE_SAMPLE         = 2
+	PPROF_PROFILE_LOCATION       = 4
+	PPROF_PROFILE_FUNCTION       = 5
+	PPROF_PROFILE_STRING_TABLE   = 6
+	PPROF_PROFILE_TIME_NANOS     = 9
+	PPROF_PROFILE_DURATION_NANOS = 10
+
+	PPROF_VALUE_TYPE_TYPE = 1
+	PPROF_VALUE_TYPE_UNIT = 2
+
+	PPROF_SAMPLE_LOCATION_ID = 1
+	PPROF_SAMPLE_VALUE       = 2
+
+	PPROF_LOCATION_ID   = 1
+	PPROF_LOCATION_LINE = 4
+
+	PPROF_LINE_FUNCTION_ID = 1
+
+	PPROF_FUNCTION_ID          = 1
+	PPROF_FUNCTION_NAME        = 2
+	PPROF_FUNCTION_SYSTEM_NAME = 3
+)
+
+type pprofStrings struct {
+	table []string
+	index map[string]uint64
+}
+
+func (s *pprofStrings) get(str string) uint64 {
+	if i, ok := s.index[str]; ok {
+		return i
+	}
+	i := uint64(len(s.table))
+	s.table = append(s.table, str)
+	s.index[str] = i
+	return i
+}
+
+func appendPprofMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+func appendPprofVarint(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+// exportPprof 输出gzip压缩的pprof protobuf, 每个函数对应一个location
+func exportPprof(args model.ProfileTracing, unit string, stacks []*profileStack) ([]byte, error) {
+	if unit == "" {
+		unit = "count"
+	}
+	strs := &pprofStrings{table: []string{""}, index: map[string]uint64{"": 0}}
+	var profile []byte
+
+	var valueType []byte
+	valueType = appendPprofVarint(valueType, PPROF_VALUE_TYPE_TYPE, strs.get(args.ProfileEventType))
+	valueType = appendPprofVarint(valueType, PPROF_VALUE_TYPE_UNIT, strs.get(unit))
+	profile = appendPprofMessage(profile, PPROF_PROFILE_SAMPLE_TYPE, valueType)
+
+	functionIDs := map[string]uint64{}
+	functionNames := []string{}
+	for _, stack := range stacks {
+		var locationIDs []byte
+		// pprof中location_id[0]为叶子
+		for i := len(stack.Frames) - 1; i >= 0; i-- {
+			frame := stack.Frames[i]
+			id, ok := functionIDs[frame]
+			if !ok {
+				id = uint64(len(functionNames) + 1)
+				functionIDs[frame] = id
+				functionNames = append(functionNames, frame)
+			}
+			locationIDs = protowire.AppendVarint(locationIDs, id)
+		}
+		var sample []byte
+		sample = appendPprofMessage(sample, PPROF_SAMPLE_LOCATION_ID, locationIDs)
+		sample = appendPprofMessage(sample, PPROF_SAMPLE_VALUE, protowire.AppendVarint(nil, uint64(int64(stack.Value))))
+		profile = appendPprofMessage(profile, PPROF_PROFILE_SAMPLE, sample)
+	}
+	for i := range functionNames {
+		id := uint64(i + 1)
+		var line []byte
+		line = appendPprofVarint(line, PPROF_LINE_FUNCTION_ID, id)
+		var location []byte
+		location = appendPprofVarint(location, PPROF_LOCATION_ID, id)
+		location = appendPprofMessage(location, PPROF_LOCATION_LINE, line)
+		profile = appendPprofMessage(profile, PPROF_PROFILE_LOCATION, location)
+	}
+	for i, name := range functionNames {
+		nameIndex := strs.get(name)
+		var function []byte
+		function = appendPprofVarint(function, PPROF_FUNCTION_ID, uint64(i+1))
+		function = appendPprofVarint(function, PPROF_FUNCTION_NAME, nameIndex)
+		function = appendPprofVarint(function, PPROF_FUNCTION_SYSTEM_NAME, nameIndex)
+		profile = appendPprofMessage(profile, PPROF_PROFILE_FUNCTION, function)
+	}
+	for _, str := range strs.table {
+		profile = protowire.AppendTag(profile, PPROF_PROFILE_STRING_TABLE, protowire.BytesType)
+		profile = protowire.AppendString(profile, str)
+	}
+	profile = appendPprofVarint(profile, PPROF_PROFILE_TIME_NANOS, uint64(int64(args.TimeStart)*1e9))
+	if args.TimeEnd > args.TimeStart {
+		profile = appendPprofVarint(profile, PPROF_PROFILE_DURATION_NANOS, uint64(int64(args.TimeEnd-args.TimeStart)*1e9))
+	}
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(profile); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,141 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/deepflowio/deepflow/server/querier/profile/model"
+)
+
+// main -> a -> b(3), main -> a(2), main -> c(5)
+var testProfileRows = []*profileRow{
+	{LocationStr: "main", NodeID: 1, ParentNodeID: 0, Value: 0},
+	{LocationStr: "a", NodeID: 2, ParentNodeID: 1, Value: 0},
+	{LocationStr: "b", NodeID: 3, ParentNodeID: 2, Value: 3},
+	{LocationStr: "main", NodeID: 4, ParentNodeID: 0, Value: 0},
+	{LocationStr: "a", NodeID: 5, ParentNodeID: 4, Value: 2},
+	{LocationStr: "main", NodeID: 6, ParentNodeID: 0, Value: 0},
+	{LocationStr: "c", NodeID: 7, ParentNodeID: 6, Value: 5},
+}
+
+func TestExportCollapsed(t *testing.T) {
+	expected := "main;a 2\nmain;a;b 3\nmain;c 5\n"
+	if data := string(exportCollapsed(buildProfileStacks(testProfileRows))); data != expected {
+		t.Errorf("unexpected collapsed stacks:\n%s", data)
+	}
+}
+
+func TestExportSpeedscope(t *testing.T) {
+	data, err := exportSpeedscope(model.ProfileTracing{AppService: "app", ProfileEventType: "cpu"}, "nanoseconds", buildProfileStacks(testProfileRows))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := speedscopeFile{}
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Shared.Frames) != 4 || len(file.Profiles) != 1 {
+		t.Fatalf("unexpected speedscope file %s", data)
+	}
+	profile := file.Profiles[0]
+	if profile.EndValue != 10 || profile.Unit != "nanoseconds" || len(profile.Samples) != 3 {
+		t.Errorf("unexpected speedscope profile %+v", profile)
+	}
+}
+
+func TestExportPprof(t *testing.T) {
+	data, err := exportPprof(model.ProfileTracing{ProfileEventType: "cpu", TimeStart: 10, TimeEnd: 20}, "samples", buildProfileStacks(testProfileRows))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[protowire.Number]int{}
+	strs := []string{}
+	for len(profile) > 0 {
+		num, typ, n := protowire.ConsumeTag(profile)
+		if n < 0 {
+			t.Fatal(protowire.ParseError(n))
+		}
+		profile = profile[n:]
+		if num == PPROF_PROFILE_STRING_TABLE {
+			str, _ := protowire.ConsumeString(profile)
+			strs = append(strs, str)
+		}
+		n = protowire.ConsumeFieldValue(num, typ, profile)
+		if n < 0 {
+			t.Fatal(protowire.ParseError(n))
+		}
+		profile = profile[n:]
+		counts[num]++
+	}
+	if counts[PPROF_PROFILE_SAMPLE] != 3 || counts[PPROF_PROFILE_LOCATION] != 4 || counts[PPROF_PROFILE_FUNCTION] != 4 {
+		t.Errorf("unexpected field counts %v", counts)
+	}
+	if len(strs) == 0 || strs[0] != "" || len(strs) != 7 {
+		t.Errorf("unexpected string table %v", strs)
+	}
+}
+
+func TestDiffProfileTree(t *testing.T) {
+	comparisonRows := []*profileRow{
+		{LocationStr: "main", NodeID: 11, ParentNodeID: 0, Value: 0},
+		{LocationStr: "a", NodeID: 12, ParentNodeID: 11, Value: 4},
+		{LocationStr: "main", NodeID: 13, ParentNodeID: 0, Value: 0},
+		{LocationStr: "d", NodeID: 14, ParentNodeID: 13, Value: 6},
+	}
+	result := diffProfileTree(buildProfileTree(testProfileRows, false), buildProfileTree(comparisonRows, false), false)
+	nodes := map[string]*model.ProfileDiffNode{}
+	for _, node := range result {
+		nodes[node.ProfileLocationStr] = node
+	}
+	if len(nodes) != 5 {
+		t.Fatalf("expect 5 nodes, got %d", len(nodes))
+	}
+	if n := nodes["main"]; n.BaselineTotalValue != 10 || n.ComparisonTotalValue != 10 || n.TotalDelta != 0 {
+		t.Errorf("unexpected main %+v", n)
+	}
+	if n := nodes["a"]; n.BaselineSelfValue != 2 || n.ComparisonSelfValue != 4 || n.SelfDelta != 2 {
+		t.Errorf("unexpected a %+v", n)
+	}
+	if n := nodes["c"]; n.SelfDelta != -5 {
+		t.Errorf("unexpected c %+v", n)
+	}
+	if n := nodes["d"]; n.SelfDelta != 6 || len(n.ParentNodeIDS) != 1 || n.ParentNodeIDS[0] != nodes["main"].NodeID {
+		t.Errorf("unexpected d %+v", n)
+	}
+
+	normalized := diffProfileTree(buildProfileTree(testProfileRows, false), buildProfileTree(comparisonRows[:2], false), true)
+	for _, node := range normalized {
+		if node.ProfileLocationStr == "main" && node.ComparisonTotalValue != 10 {
+			t.Errorf("unexpected normalized main %+v", node)
+		}
+	}
+}
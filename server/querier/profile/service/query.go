@@ -0,0 +1,163 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	querier_common "github.com/deepflowio/deepflow/server/querier/common"
+	"github.com/deepflowio/deepflow/server/querier/config"
+	"github.com/deepflowio/deepflow/server/querier/profile/common"
+	"github.com/deepflowio/deepflow/server/querier/profile/model"
+	querier_service "github.com/deepflowio/deepflow/server/querier/service"
+)
+
+type profileRow struct {
+	LocationStr  string
+	NodeID       int
+	ParentNodeID int
+	Value        int
+	ValueUnit    string
+}
+
+func profileSql(args model.ProfileTracing, limit int) string {
+	whereSlice := []string{}
+	whereSlice = append(whereSlice, fmt.Sprintf(" time>=%d", args.TimeStart))
+	whereSlice = append(whereSlice, fmt.Sprintf(" time<=%d", args.TimeEnd))
+	whereSlice = append(whereSlice, fmt.Sprintf(" app_service='%s'", args.AppService))
+	whereSlice = append(whereSlice, fmt.Sprintf(" profile_language_type='%s'", args.ProfileLanguageType))
+	whereSlice = append(whereSlice, fmt.Sprintf(" profile_event_type='%s'", args.ProfileEventType))
+	if args.TagFilter != "" {
+		whereSlice = append(whereSlice, " "+args.TagFilter)
+	}
+	whereSql := strings.Join(whereSlice, " AND")
+	return fmt.Sprintf(
+		"SELECT %s, %s, %s, %s, %s FROM %s WHERE %s LIMIT %d",
+		common.PROFILE_LOCATION_STR, common.PROFILE_NODE_ID, common.PROFILE_PARENT_NODE_ID, common.PROFILE_VALUE, common.PROFILE_VALUE_UNIT, common.TABLE_PROFILE, whereSql, limit,
+	)
+}
+
+// queryProfileRows 直接通过查询引擎执行, 不再经过本地/v1/query/接口
+func queryProfileRows(args model.ProfileTracing, cfg *config.QuerierConfig) (rows []*profileRow, profileDebug *model.Debug, err error) {
+	sql := profileSql(args, cfg.Profile.FlameQueryLimit)
+	profileDebug = &model.Debug{Sql: sql}
+	params := &querier_common.QuerierParams{
+		DB:      common.DATABASE_PROFILE,
+		Sql:     sql,
+		Debug:   "true",
+		Caller:  args.Caller,
+		Context: args.Context,
+	}
+	result, debug, err := querier_service.Execute(params)
+	if debug != nil {
+		profileDebug.IP, _ = debug["ip"].(string)
+		profileDebug.SqlCH, _ = debug["sql"].(string)
+		profileDebug.QueryTime, _ = debug["query_time"].(string)
+		profileDebug.QueryUUID, _ = debug["query_uuid"].(string)
+		profileDebug.Error, _ = debug["error"].(string)
+	}
+	if err != nil {
+		log.Errorf("query profile failed: %s, %s", err.Error(), sql)
+		return
+	}
+	columns, _ := result["columns"].([]interface{})
+	values, _ := result["values"].([]interface{})
+	if len(values) == 0 {
+		log.Warningf("no data in profile query: %s", sql)
+		return
+	}
+	rows, err = toProfileRows(columns, values)
+	return
+}
+
+func toProfileRows(columns, values []interface{}) ([]*profileRow, error) {
+	profileLocationStrIndex := -1
+	profileNodeIDIndex := -1
+	profileParentNodeIDIndex := -1
+	profileValueIndex := -1
+	profileValueUnitIndex := -1
+	for columnIndex, column := range columns {
+		switch column {
+		case common.PROFILE_LOCATION_STR:
+			profileLocationStrIndex = columnIndex
+		case common.PROFILE_NODE_ID:
+			profileNodeIDIndex = columnIndex
+		case common.PROFILE_PARENT_NODE_ID:
+			profileParentNodeIDIndex = columnIndex
+		case common.PROFILE_VALUE:
+			profileValueIndex = columnIndex
+		case common.PROFILE_VALUE_UNIT:
+			profileValueUnitIndex = columnIndex
+		}
+	}
+	for _, index := range []int{profileLocationStrIndex, profileNodeIDIndex, profileParentNodeIDIndex, profileValueIndex} {
+		if index == -1 {
+			log.Error("Not all fields found")
+			return nil, errors.New("Not all fields found")
+		}
+	}
+	rows := make([]*profileRow, 0, len(values))
+	for _, value := range values {
+		v, ok := value.([]interface{})
+		if !ok || len(v) != len(columns) {
+			continue
+		}
+		row := &profileRow{
+			LocationStr:  toString(v[profileLocationStrIndex]),
+			NodeID:       toInt(v[profileNodeIDIndex]),
+			ParentNodeID: toInt(v[profileParentNodeIDIndex]),
+			Value:        toInt(v[profileValueIndex]),
+		}
+		if profileValueUnitIndex != -1 {
+			row.ValueUnit = toString(v[profileValueUnitIndex])
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func toString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+func toInt(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	case nil:
+		return 0
+	default:
+		// profile_node_id为UInt64, 超出int64时按位转换, 仅用于比较
+		str := fmt.Sprint(n)
+		if u, err := strconv.ParseUint(str, 10, 64); err == nil {
+			return int(u)
+		}
+		i, _ := strconv.ParseInt(str, 10, 64)
+		return int(i)
+	}
+}
@@ -17,10 +17,7 @@
 package service
 
 import (
-	"errors"
 	"fmt"
-	"net"
-	"strings"
 	"time"
 
 	"golang.org/x/exp/slices"
@@ -29,83 +26,35 @@ import (
 
 	controller_common "github.com/deepflowio/deepflow/server/controller/common"
 	"github.com/deepflowio/deepflow/server/querier/config"
-	"github.com/deepflowio/deepflow/server/querier/profile/common"
 	"github.com/deepflowio/deepflow/server/querier/profile/model"
 )
 
 var log = logging.MustGetLogger("profile")
 
 func Tracing(args model.ProfileTracing, cfg *config.QuerierConfig) (result []*model.ProfileTreeNode, debug interface{}, err error) {
-	whereSlice := []string{}
-	whereSlice = append(whereSlice, fmt.Sprintf(" time>=%d", args.TimeStart))
-	whereSlice = append(whereSlice, fmt.Sprintf(" time<=%d", args.TimeEnd))
-	whereSlice = append(whereSlice, fmt.Sprintf(" app_service='%s'", args.AppService))
-	whereSlice = append(whereSlice, fmt.Sprintf(" profile_language_type='%s'", args.ProfileLanguageType))
-	whereSlice = append(whereSlice, fmt.Sprintf(" profile_event_type='%s'", args.ProfileEventType))
-	if args.TagFilter != "" {
-		whereSlice = append(whereSlice, " "+args.TagFilter)
-	}
-	whereSql := strings.Join(whereSlice, " AND")
-	limitSql := cfg.Profile.FlameQueryLimit
-	url := fmt.Sprintf("http://%s/v1/query/?debug=true", net.JoinHostPort("localhost", fmt.Sprintf("%d", cfg.ListenPort)))
-	body := map[string]interface{}{}
-	body["db"] = common.DATABASE_PROFILE
-	sql := fmt.Sprintf(
-		"SELECT %s, %s, %s, %s FROM %s WHERE %s LIMIT %d",
-		common.PROFILE_LOCATION_STR, common.PROFILE_NODE_ID, common.PROFILE_PARENT_NODE_ID, common.PROFILE_VALUE, common.TABLE_PROFILE, whereSql, limitSql,
-	)
-	body["sql"] = sql
-	profileDebug := model.Debug{}
-	profileDebug.Sql = sql
-	resp, err := controller_common.CURLPerform("POST", url, body)
+	rows, profileDebug, err := queryProfileRows(args, cfg)
 	if err != nil {
-		log.Errorf("call querier failed: %s, %s", err.Error(), url)
-		return
-	}
-	if len(resp.Get("result").MustMap()) == 0 {
-		log.Warningf("no data in curl response: %s", url)
 		return
 	}
-	profileDebug.IP = resp.Get("debug").Get("ip").MustString()
-	profileDebug.QueryUUID = resp.Get("debug").Get("query_uuid").MustString()
-	profileDebug.SqlCH = resp.Get("debug").Get("sql").MustString()
-	profileDebug.Error = resp.Get("debug").Get("error").MustString()
-	profileDebug.QueryTime = resp.Get("debug").Get("query_time").MustString()
 	formatStartTime := time.Now()
-	profileLocationStrIndex := -1
-	profileNodeIDIndex := -1
-	profileParentNodeIDIndex := -1
-	profileValueIndex := -1
+	result = buildProfileTree(rows, args.Debug)
+	formatEndTime := int64(time.Since(formatStartTime))
+	formatTime := fmt.Sprintf("%.9fs", float64(formatEndTime)/1e9)
+	profileDebug.FormatTime = formatTime
+	debug = profileDebug
+	return
+}
+
+func buildProfileTree(rows []*profileRow, debug bool) (result []*model.ProfileTreeNode) {
 	NodeIDToProfileTree := map[string]*model.ProfileTreeNode{}
 	profileNodeIDToNodeID := map[int]string{}
-	columns := resp.GetPath("result", "columns")
-	values := resp.GetPath("result", "values")
-	for columnIndex := range columns.MustArray() {
-		column := columns.GetIndex(columnIndex).MustString()
-		switch column {
-		case "profile_location_str":
-			profileLocationStrIndex = columnIndex
-		case "profile_node_id":
-			profileNodeIDIndex = columnIndex
-		case "profile_parent_node_id":
-			profileParentNodeIDIndex = columnIndex
-		case "profile_value":
-			profileValueIndex = columnIndex
-		}
-	}
-	indexOK := slices.Contains[int]([]int{profileLocationStrIndex, profileNodeIDIndex, profileParentNodeIDIndex, profileValueIndex}, -1)
-	if indexOK {
-		log.Error("Not all fields found")
-		err = errors.New("Not all fields found")
-		return
-	}
 	// merge profile_node_ids, profile_parent_node_ids, self_value
-	for valueIndex := range values.MustArray() {
-		profileLocationStr := values.GetIndex(valueIndex).GetIndex(profileLocationStrIndex).MustString()
+	for _, row := range rows {
+		profileLocationStr := row.LocationStr
 		nodeID := controller_common.GenerateUUID(profileLocationStr)
-		profileNodeID := values.GetIndex(valueIndex).GetIndex(profileNodeIDIndex).MustInt()
-		profileParentNodeID := values.GetIndex(valueIndex).GetIndex(profileParentNodeIDIndex).MustInt()
-		profileValue := values.GetIndex(valueIndex).GetIndex(profileValueIndex).MustInt()
+		profileNodeID := row.NodeID
+		profileParentNodeID := row.ParentNodeID
+		profileValue := row.Value
 		existNode, ok := NodeIDToProfileTree[nodeID]
 		if ok {
 			ok = slices.Contains[int](existNode.ProfileNodeIDS, profileNodeID)
@@ -126,15 +75,15 @@ func Tracing(args model.ProfileTracing, cfg *config.QuerierConfig) (result []*mo
 				node.ProfileParentNodeIDS = []int{profileParentNodeID}
 			}
 			NodeIDToProfileTree[nodeID] = node
-			profileNodeIDToNodeID[profileNodeID] = nodeID
 			result = append(result, node)
 		}
+		profileNodeIDToNodeID[profileNodeID] = nodeID
 	}
 	// update parent_node_ids
 	for _, node := range NodeIDToProfileTree {
 		for _, profileParentNodeID := range node.ProfileParentNodeIDS {
 			parentNodeID, ok := profileNodeIDToNodeID[profileParentNodeID]
-			if ok {
+			if ok && !slices.Contains[string](node.ParentNodeIDS, parentNodeID) {
 				node.ParentNodeIDS = append(node.ParentNodeIDS, parentNodeID)
 			}
 		}
@@ -147,12 +96,12 @@ func Tracing(args model.ProfileTracing, cfg *config.QuerierConfig) (result []*mo
 	}
 	var noZeroResult []*model.ProfileTreeNode
 	// format root node
-	for _, node := range NodeIDToProfileTree {
+	for _, node := range result {
 		if len(node.ParentNodeIDS) == 0 {
 			node.ParentNodeIDS = append(node.ParentNodeIDS, "")
 		}
 		// remove debug information
-		if !args.Debug {
+		if !debug {
 			node.ProfileNodeIDS = node.ProfileNodeIDS[:0]
 			node.ProfileParentNodeIDS = node.ProfileParentNodeIDS[:0]
 		}
@@ -160,12 +109,7 @@ func Tracing(args model.ProfileTracing, cfg *config.QuerierConfig) (result []*mo
 			noZeroResult = append(noZeroResult, node)
 		}
 	}
-	result = noZeroResult
-	formatEndTime := int64(time.Since(formatStartTime))
-	formatTime := fmt.Sprintf("%.9fs", float64(formatEndTime)/1e9)
-	profileDebug.FormatTime = formatTime
-	debug = profileDebug
-	return
+	return noZeroResult
 }
 
 func NewProfileTreeNode(profileLocationStr string, nodeID string, profileNodeID int, profileValue int) *model.ProfileTreeNode {
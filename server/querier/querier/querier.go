@@ -31,7 +31,10 @@ import (
 	prometheus_router "github.com/deepflowio/deepflow/server/querier/app/prometheus/router"
 	"github.com/deepflowio/deepflow/server/querier/common"
 	"github.com/deepflowio/deepflow/server/querier/config"
+	correlation_router "github.com/deepflowio/deepflow/server/querier/correlation/router"
 	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse"
+	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse/client"
+	"github.com/deepflowio/deepflow/server/querier/governance"
 	profile_router "github.com/deepflowio/deepflow/server/querier/profile/router"
 	"github.com/deepflowio/deepflow/server/querier/router"
 	"github.com/deepflowio/deepflow/server/querier/statsd"
@@ -49,6 +52,9 @@ func Start(configPath, serverLogFile string) {
 	log.Info("==================== Launching DeepFlow-Server-Querier ====================")
 	log.Infof("querier config:\n%s", string(bytes))
 
+	// clickhouse replicas health check
+	client.StartEndpointPool()
+
 	// engine加载数据库tag/metric等信息
 	err := Load()
 	if err != nil {
@@ -59,6 +65,9 @@ func Start(configPath, serverLogFile string) {
 	// prometheus dict cache
 	go clickhouse.GeneratePrometheusMap()
 
+	// per-caller query limits and query log
+	governance.Start(config.Cfg)
+
 	// statsd
 	statsd.QuerierCounter = statsd.NewCounter()
 	statsd.RegisterCountableForIngester("querier_count", statsd.QuerierCounter)
@@ -81,6 +90,7 @@ func Start(configPath, serverLogFile string) {
 	r.Use(ErrHandle())
 	router.QueryRouter(r)
 	profile_router.ProfileRouter(r, &cfg)
+	correlation_router.CorrelationRouter(r, &cfg)
 	prometheus_router.PrometheusRouter(r)
 	// TODO: 增加router
 	if err := r.Run(fmt.Sprintf(":%d", cfg.ListenPort)); err != nil {
@@ -17,15 +17,26 @@
 package router
 
 import (
+	"fmt"
+	"strconv"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
 	"github.com/deepflowio/deepflow/server/querier/common"
+	"github.com/deepflowio/deepflow/server/querier/config"
+	"github.com/deepflowio/deepflow/server/querier/governance"
 	"github.com/deepflowio/deepflow/server/querier/service"
+	"github.com/deepflowio/deepflow/server/querier/stream"
 )
 
 func QueryRouter(e *gin.Engine) {
 	e.POST("/v1/query/", executeQuery())
+	e.GET("/v1/query/log", queryLog())
+	e.GET("/v1/query/running", runningQueries())
+	e.GET("/v1/query/:query_uuid/progress", queryProgress())
+	e.DELETE("/v1/query/:query_uuid", cancelQuery())
+	e.GET("/v1/clickhouse/endpoints", clickhouseEndpoints())
 
 	// api router for tempo
 	e.GET("/api/traces/:traceId", tempoTraceReader())
@@ -48,11 +59,30 @@ func executeQuery() gin.HandlerFunc {
 		args.DB = c.PostForm("db")
 		args.Sql = c.PostForm("sql")
 		args.DataSource = c.PostForm("data_precision")
+		args.Caller = governance.Identify(c.Request.Header, c.ClientIP())
+		args.TagHistory = config.Cfg.TagHistory
+		if tagHistory := c.DefaultQuery("tag_history", c.PostForm("tag_history")); tagHistory != "" {
+			args.TagHistory, _ = strconv.ParseBool(tagHistory)
+		}
+		format := c.DefaultQuery("format", c.PostForm("format"))
 		if args.Sql == "" && args.DB == "" {
 			json := make(map[string]interface{})
 			c.BindJSON(&json)
 			args.DB, _ = json["db"].(string)
 			args.Sql, _ = json["sql"].(string)
+			if f, ok := json["format"].(string); ok && format == "" {
+				format = f
+			}
+			if tagHistory, ok := json["tag_history"].(bool); ok {
+				args.TagHistory = tagHistory
+			}
+		}
+		if stream.IsStream(format) {
+			executeStreamQuery(c, &args, format)
+			return
+		} else if format != "" && format != stream.FORMAT_JSON {
+			JsonResponse(c, nil, nil, service.NewError(common.INVALID_PARAMETERS, fmt.Sprintf("unsupported format %s", format)))
+			return
 		}
 		result, debug, err := service.Execute(&args)
 		if err == nil && args.Debug != "true" {
@@ -61,3 +91,60 @@ func executeQuery() gin.HandlerFunc {
 		JsonResponse(c, result, debug, err)
 	})
 }
+
+// executeStreamQuery writes rows to the response as they are read from clickhouse,
+// an error after the response started is sent in the X-DeepFlow-Error trailer.
+func executeStreamQuery(c *gin.Context, args *common.QuerierParams, format string) {
+	// format is checked by stream.IsStream
+	writer, _ := stream.NewWriter(format, c.Writer)
+	args.Writer = writer
+	_, debug, err := service.Execute(args)
+	if err != nil {
+		if !writer.Started() {
+			JsonResponse(c, nil, debug, err)
+			return
+		}
+		writer.Abort(err)
+	}
+	writer.Close()
+}
+
+func queryLog() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		args := governance.QueryLogFilter{
+			Caller:    c.Query("caller"),
+			QueryUUID: c.Query("query_uuid"),
+		}
+		args.StartTime, _ = strconv.ParseInt(c.Query("start_time"), 10, 64)
+		args.EndTime, _ = strconv.ParseInt(c.Query("end_time"), 10, 64)
+		args.Limit, _ = strconv.Atoi(c.Query("limit"))
+		result, err := service.QueryLog(&args, c.Request.Context())
+		JsonResponse(c, result, nil, err)
+	})
+}
+
+func runningQueries() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		JsonResponse(c, service.RunningQueries(), nil, nil)
+	})
+}
+
+func queryProgress() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		result, err := service.QueryProgress(c.Param("query_uuid"))
+		JsonResponse(c, result, nil, err)
+	})
+}
+
+func cancelQuery() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		err := service.CancelQuery(c.Param("query_uuid"))
+		JsonResponse(c, nil, nil, err)
+	})
+}
+
+func clickhouseEndpoints() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		JsonResponse(c, service.ClickhouseEndpoints(), nil, nil)
+	})
+}
@@ -71,8 +71,13 @@ func JsonResponse(c *gin.Context, data interface{}, debug interface{}, err error
 		case *service.ServiceError:
 			switch t.Status {
 			case common.RESOURCE_NOT_FOUND, common.INVALID_POST_DATA, common.RESOURCE_NUM_EXCEEDED,
-				common.SELECTED_RESOURCES_NUM_EXCEEDED:
+				common.SELECTED_RESOURCES_NUM_EXCEEDED, common.INVALID_PARAMETERS:
 				BadRequestResponse(c, t.Status, t.Message)
+			case common.QUERY_LIMIT_EXCEEDED:
+				c.JSON(http.StatusTooManyRequests, Response{
+					OptStatus:   t.Status,
+					Description: t.Message,
+				})
 			case common.SERVER_ERROR:
 				InternalErrorResponse(c, data, debug, t.Status, t.Message)
 			}
@@ -17,12 +17,27 @@
 package service
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/deepflowio/deepflow/server/querier/common"
 	"github.com/deepflowio/deepflow/server/querier/engine"
 	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse"
+	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse/client"
+	"github.com/deepflowio/deepflow/server/querier/governance"
 )
 
 func Execute(args *common.QuerierParams) (jsonData map[string]interface{}, debug map[string]interface{}, err error) {
+	query, ctx, err := governance.Begin(args)
+	if err != nil {
+		return nil, nil, NewError(common.QUERY_LIMIT_EXCEEDED, err.Error())
+	}
+	args.Context = ctx
+	resultRows := 0
+	defer func() {
+		governance.End(query, resultRows, err)
+	}()
+
 	db := getDbBy()
 	var engine engine.Engine
 	switch db {
@@ -31,12 +46,54 @@ func Execute(args *common.QuerierParams) (jsonData map[string]interface{}, debug
 		engine.Init()
 	}
 	result, debug, err := engine.ExecuteQuery(args)
+	if args.Writer != nil {
+		// results not read from clickhouse, such as show, are written at once,
+		// an empty result is written too so that its columns are sent
+		if err == nil && result != nil && !args.Writer.Started() {
+			err = args.Writer.WriteBatch(result)
+		}
+		resultRows = args.Writer.Rows()
+		return nil, debug, err
+	}
 	if result != nil {
 		jsonData = result.ToJson()
+		resultRows = len(result.Values)
 	}
 	return jsonData, debug, err
 }
 
+func QueryLog(args *governance.QueryLogFilter, ctx context.Context) ([]map[string]interface{}, error) {
+	logs, err := governance.QueryLogs(ctx, args)
+	if err == governance.ErrQueryLogDisabled {
+		return nil, NewError(common.INVALID_PARAMETERS, err.Error())
+	}
+	return logs, err
+}
+
+func RunningQueries() []map[string]interface{} {
+	return client.RunningQueries.List()
+}
+
+func QueryProgress(queryUUID string) (map[string]interface{}, error) {
+	query := client.RunningQueries.Get(queryUUID)
+	if query == nil {
+		return nil, NewError(common.RESOURCE_NOT_FOUND, fmt.Sprintf("query_uuid %s is not running", queryUUID))
+	}
+	return query.Progress(), nil
+}
+
+func CancelQuery(queryUUID string) error {
+	err := client.RunningQueries.Kill(queryUUID)
+	if err == client.ErrQueryNotFound {
+		return NewError(common.RESOURCE_NOT_FOUND, fmt.Sprintf("query_uuid %s is not running", queryUUID))
+	}
+	return err
+}
+
+func ClickhouseEndpoints() []map[string]interface{} {
+	return client.EndpointStats()
+}
+
 func getDbBy() string {
 	return "clickhouse"
 }
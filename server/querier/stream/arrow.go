@@ -0,0 +1,142 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package stream
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/apache/arrow/go/arrow/memory"
+
+	"github.com/deepflowio/deepflow/server/querier/common"
+)
+
+// arrowEncoder writes every batch as a record of an Arrow IPC stream. Column
+// types are decided by the values of the first batch after callbacks: int as
+// Int64, float64 as Float64, others as Utf8. A value not matching the column
+// type in later batches is converted, or written as null if it can not be.
+type arrowEncoder struct {
+	w       io.Writer
+	mem     memory.Allocator
+	schema  *arrow.Schema
+	builder *array.RecordBuilder
+	writer  *ipc.Writer
+}
+
+func newArrowEncoder(w io.Writer) *arrowEncoder {
+	return &arrowEncoder{w: w, mem: memory.NewGoAllocator()}
+}
+
+func (e *arrowEncoder) writeHeader(result *common.Result) error {
+	fields := make([]arrow.Field, len(result.Columns))
+	for i, column := range result.Columns {
+		fields[i] = arrow.Field{Name: fmt.Sprint(column), Type: arrow.BinaryTypes.String, Nullable: true}
+	}
+	// the first non-null value of each column decides its type
+	decided := make([]bool, len(fields))
+	for _, row := range result.Values {
+		for i, value := range rowValues(row) {
+			if i >= len(fields) || decided[i] || value == nil {
+				continue
+			}
+			decided[i] = true
+			switch value.(type) {
+			case int:
+				fields[i].Type = arrow.PrimitiveTypes.Int64
+			case float64:
+				fields[i].Type = arrow.PrimitiveTypes.Float64
+			}
+		}
+	}
+	e.schema = arrow.NewSchema(fields, nil)
+	e.builder = array.NewRecordBuilder(e.mem, e.schema)
+	e.writer = ipc.NewWriter(e.w, ipc.WithSchema(e.schema), ipc.WithAllocator(e.mem))
+	return nil
+}
+
+func (e *arrowEncoder) writeRows(result *common.Result) error {
+	fields := e.builder.Fields()
+	for _, row := range result.Values {
+		values := rowValues(row)
+		for i, field := range fields {
+			var value interface{}
+			if i < len(values) {
+				value = values[i]
+			}
+			appendValue(field, value)
+		}
+	}
+	record := e.builder.NewRecord()
+	defer record.Release()
+	return e.writer.Write(record)
+}
+
+func appendValue(builder array.Builder, value interface{}) {
+	if value == nil {
+		builder.AppendNull()
+		return
+	}
+	switch b := builder.(type) {
+	case *array.Int64Builder:
+		switch v := value.(type) {
+		case int:
+			b.Append(int64(v))
+		case float64:
+			b.Append(int64(v))
+		case string:
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+				b.Append(n)
+			} else {
+				b.AppendNull()
+			}
+		default:
+			b.AppendNull()
+		}
+	case *array.Float64Builder:
+		switch v := value.(type) {
+		case float64:
+			b.Append(v)
+		case int:
+			b.Append(float64(v))
+		case string:
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				b.Append(f)
+			} else {
+				b.AppendNull()
+			}
+		default:
+			b.AppendNull()
+		}
+	case *array.StringBuilder:
+		b.Append(formatValue(value))
+	default:
+		builder.AppendNull()
+	}
+}
+
+func (e *arrowEncoder) close() error {
+	if e.writer == nil {
+		return nil
+	}
+	err := e.writer.Close()
+	e.builder.Release()
+	return err
+}
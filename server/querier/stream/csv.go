@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package stream
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	"github.com/deepflowio/deepflow/server/querier/common"
+)
+
+// csvEncoder writes the column names as the first record
+type csvEncoder struct {
+	w      *csv.Writer
+	record []string
+}
+
+func newCsvEncoder(w io.Writer) *csvEncoder {
+	return &csvEncoder{w: csv.NewWriter(w)}
+}
+
+func (e *csvEncoder) writeHeader(result *common.Result) error {
+	e.record = make([]string, len(result.Columns))
+	for i, column := range result.Columns {
+		e.record[i] = fmt.Sprint(column)
+	}
+	return e.w.Write(e.record)
+}
+
+func (e *csvEncoder) writeRows(result *common.Result) error {
+	for _, row := range result.Values {
+		for i := range e.record {
+			e.record[i] = ""
+		}
+		for i, value := range rowValues(row) {
+			if i < len(e.record) {
+				e.record[i] = formatValue(value)
+			}
+		}
+		if err := e.w.Write(e.record); err != nil {
+			return err
+		}
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvEncoder) close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package stream
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/deepflowio/deepflow/server/querier/common"
+)
+
+// ndjsonEncoder writes each row as a json object keyed by column name, in the
+// order of the columns
+type ndjsonEncoder struct {
+	w    *bufio.Writer
+	keys [][]byte
+}
+
+func newNdjsonEncoder(w io.Writer) *ndjsonEncoder {
+	return &ndjsonEncoder{w: bufio.NewWriter(w)}
+}
+
+func (e *ndjsonEncoder) writeHeader(result *common.Result) error {
+	e.keys = make([][]byte, len(result.Columns))
+	for i, column := range result.Columns {
+		key, err := json.Marshal(fmt.Sprint(column))
+		if err != nil {
+			return err
+		}
+		e.keys[i] = key
+	}
+	return nil
+}
+
+func (e *ndjsonEncoder) writeRows(result *common.Result) error {
+	for _, row := range result.Values {
+		e.w.WriteByte('{')
+		for i, value := range rowValues(row) {
+			if i >= len(e.keys) {
+				break
+			}
+			if i > 0 {
+				e.w.WriteByte(',')
+			}
+			e.w.Write(e.keys[i])
+			e.w.WriteByte(':')
+			b, err := json.Marshal(value)
+			if err != nil {
+				b, _ = json.Marshal(formatValue(value))
+			}
+			e.w.Write(b)
+		}
+		if _, err := e.w.WriteString("}\n"); err != nil {
+			return err
+		}
+	}
+	return e.w.Flush()
+}
+
+func (e *ndjsonEncoder) close() error {
+	return e.w.Flush()
+}
@@ -0,0 +1,180 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	logging "github.com/op/go-logging"
+
+	"github.com/deepflowio/deepflow/server/querier/common"
+)
+
+var log = logging.MustGetLogger("querier.stream")
+
+const (
+	FORMAT_JSON   = "json"
+	FORMAT_NDJSON = "ndjson"
+	FORMAT_CSV    = "csv"
+	FORMAT_ARROW  = "arrow"
+
+	// an error occurred after the response started is sent in this trailer
+	ERROR_TRAILER = "X-DeepFlow-Error"
+)
+
+var contentTypes = map[string]string{
+	FORMAT_NDJSON: "application/x-ndjson",
+	FORMAT_CSV:    "text/csv; charset=utf-8",
+	FORMAT_ARROW:  "application/vnd.apache.arrow.stream",
+}
+
+// IsStream returns whether the result of the format is streamed, json is not.
+func IsStream(format string) bool {
+	_, ok := contentTypes[format]
+	return ok
+}
+
+type encoder interface {
+	// writeHeader is called with the first batch before it is written
+	writeHeader(result *common.Result) error
+	writeRows(result *common.Result) error
+	close() error
+}
+
+// Writer implements common.ResultWriter, nothing is sent to the response until
+// the first batch arrives, so errors before that can still be returned as json.
+type Writer struct {
+	w       http.ResponseWriter
+	format  string
+	encoder encoder
+	started bool
+	rows    int
+}
+
+func NewWriter(format string, w http.ResponseWriter) (*Writer, error) {
+	var e encoder
+	switch format {
+	case FORMAT_NDJSON:
+		e = newNdjsonEncoder(w)
+	case FORMAT_CSV:
+		e = newCsvEncoder(w)
+	case FORMAT_ARROW:
+		e = newArrowEncoder(w)
+	default:
+		return nil, fmt.Errorf("unsupported format %s", format)
+	}
+	return &Writer{w: w, format: format, encoder: e}, nil
+}
+
+func (w *Writer) start() {
+	w.started = true
+	header := w.w.Header()
+	header.Set("Content-Type", contentTypes[w.format])
+	header.Set("Trailer", ERROR_TRAILER)
+	w.w.WriteHeader(http.StatusOK)
+}
+
+func (w *Writer) WriteBatch(result *common.Result) error {
+	if !w.started {
+		w.start()
+		if err := w.encoder.writeHeader(result); err != nil {
+			return err
+		}
+	}
+	if err := w.encoder.writeRows(result); err != nil {
+		return err
+	}
+	w.rows += len(result.Values)
+	if flusher, ok := w.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+func (w *Writer) Rows() int {
+	return w.rows
+}
+
+// Started returns whether the response has been sent
+func (w *Writer) Started() bool {
+	return w.started
+}
+
+// Abort reports an error occurred after the response started
+func (w *Writer) Abort(err error) {
+	log.Errorf("streaming %s result aborted after %d rows: %s", w.format, w.rows, err)
+	w.w.Header().Set(ERROR_TRAILER, err.Error())
+}
+
+func (w *Writer) Close() error {
+	if !w.started {
+		// 空结果也要输出 csv 表头和 arrow schema
+		w.start()
+		if err := w.encoder.writeHeader(&common.Result{}); err != nil {
+			log.Errorf("write %s header failed: %s", w.format, err)
+			return err
+		}
+	}
+	err := w.encoder.close()
+	if err != nil {
+		log.Errorf("close %s result failed: %s", w.format, err)
+	}
+	return err
+}
+
+// rowValues returns the values of a row in Result.Values
+func rowValues(row interface{}) []interface{} {
+	switch r := row.(type) {
+	case []interface{}:
+		return r
+	case []string:
+		values := make([]interface{}, len(r))
+		for i := range r {
+			values[i] = r[i]
+		}
+		return values
+	}
+	return []interface{}{row}
+}
+
+// formatValue returns the text of a value, time is formatted as in json
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case net.IP:
+		return v.String()
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
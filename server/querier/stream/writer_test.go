@@ -0,0 +1,173 @@
+/*
+ * Copyright (c) 2023 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package stream
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/ipc"
+
+	"github.com/deepflowio/deepflow/server/querier/common"
+)
+
+func batches() []*common.Result {
+	columns := []interface{}{"ip", "byte", "rtt"}
+	return []*common.Result{
+		{Columns: columns, Values: []interface{}{
+			[]interface{}{"1.1.1.1", 10, 0.5},
+			[]interface{}{"1,2", 20, nil},
+		}},
+		{Columns: columns, Values: []interface{}{
+			[]interface{}{"3.3.3.3", 30, 1},
+		}},
+	}
+}
+
+func write(t *testing.T, format string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	w, err := NewWriter(format, recorder)
+	if err != nil {
+		t.Fatalf("Expected nil found %v", err)
+	}
+	for _, batch := range batches() {
+		if err := w.WriteBatch(batch); err != nil {
+			t.Fatalf("Expected nil found %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Expected nil found %v", err)
+	}
+	if w.Rows() != 3 {
+		t.Errorf("Expected 3 found %v", w.Rows())
+	}
+	if actual := recorder.Header().Get("Content-Type"); actual != contentTypes[format] {
+		t.Errorf("Expected %v found %v", contentTypes[format], actual)
+	}
+	return recorder
+}
+
+func TestNdjson(t *testing.T) {
+	expected := `{"ip":"1.1.1.1","byte":10,"rtt":0.5}
+{"ip":"1,2","byte":20,"rtt":null}
+{"ip":"3.3.3.3","byte":30,"rtt":1}
+`
+	if actual := write(t, FORMAT_NDJSON).Body.String(); actual != expected {
+		t.Errorf("Expected %v found %v", expected, actual)
+	}
+}
+
+func TestCsv(t *testing.T) {
+	expected := `ip,byte,rtt
+1.1.1.1,10,0.5
+"1,2",20,
+3.3.3.3,30,1
+`
+	if actual := write(t, FORMAT_CSV).Body.String(); actual != expected {
+		t.Errorf("Expected %v found %v", expected, actual)
+	}
+}
+
+func TestArrow(t *testing.T) {
+	reader, err := ipc.NewReader(write(t, FORMAT_ARROW).Body)
+	if err != nil {
+		t.Fatalf("Expected nil found %v", err)
+	}
+	defer reader.Release()
+	fields := reader.Schema().Fields()
+	types := []arrow.DataType{arrow.BinaryTypes.String, arrow.PrimitiveTypes.Int64, arrow.PrimitiveTypes.Float64}
+	for i, field := range fields {
+		if !arrow.TypeEqual(field.Type, types[i]) {
+			t.Errorf("Expected %v found %v", types[i], field.Type)
+		}
+	}
+	rows := 0
+	var rtt []float64
+	for reader.Next() {
+		record := reader.Record()
+		rows += int(record.NumRows())
+		column := record.Column(2).(*array.Float64)
+		for i := 0; i < column.Len(); i++ {
+			if column.IsValid(i) {
+				rtt = append(rtt, column.Value(i))
+			}
+		}
+	}
+	if rows != 3 {
+		t.Errorf("Expected 3 found %v", rows)
+	}
+	if len(rtt) != 2 || rtt[1] != 1 {
+		t.Errorf("Expected [0.5 1] found %v", rtt)
+	}
+}
+
+func TestUnsupportedFormat(t *testing.T) {
+	if _, err := NewWriter("xml", httptest.NewRecorder()); err == nil {
+		t.Errorf("Expected error found nil")
+	}
+	if IsStream(FORMAT_JSON) {
+		t.Errorf("Expected json is not streamed")
+	}
+}
+
+func TestEmptyResult(t *testing.T) {
+	empty := &common.Result{Columns: []interface{}{"ip", "byte"}}
+
+	recorder := httptest.NewRecorder()
+	w, _ := NewWriter(FORMAT_CSV, recorder)
+	w.WriteBatch(empty)
+	w.Close()
+	if actual := recorder.Body.String(); actual != "ip,byte\n" {
+		t.Errorf("Expected csv header found %v", actual)
+	}
+
+	recorder = httptest.NewRecorder()
+	w, _ = NewWriter(FORMAT_ARROW, recorder)
+	w.WriteBatch(empty)
+	w.Close()
+	reader, err := ipc.NewReader(recorder.Body)
+	if err != nil {
+		t.Fatalf("Expected nil found %v", err)
+	}
+	defer reader.Release()
+	if fields := reader.Schema().Fields(); len(fields) != 2 || fields[0].Name != "ip" {
+		t.Errorf("Expected schema of ip, byte found %v", fields)
+	}
+	for reader.Next() {
+		if rows := reader.Record().NumRows(); rows != 0 {
+			t.Errorf("Expected 0 found %v", rows)
+		}
+	}
+}
+
+func TestCloseWithoutBatch(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w, _ := NewWriter(FORMAT_ARROW, recorder)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Expected nil found %v", err)
+	}
+	if !w.Started() {
+		t.Errorf("Expected started after close")
+	}
+	reader, err := ipc.NewReader(recorder.Body)
+	if err != nil {
+		t.Fatalf("Expected valid arrow stream found %v", err)
+	}
+	reader.Release()
+}